@@ -2,18 +2,24 @@ package main
 
 import (
 	"context"
+	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"syscall"
 
+	"loverly/lib/broker"
 	"loverly/lib/i18n"
 	"loverly/lib/jwt"
 	"loverly/lib/log"
+	"loverly/lib/payment"
 	"loverly/lib/postgres"
 	"loverly/lib/redis"
 	"loverly/src/business/domain"
 	"loverly/src/business/usecase"
 	"loverly/src/config"
 	"loverly/src/handler"
+	"loverly/src/worker"
 
 	atomicSQLX "loverly/lib/atomic/sqlx"
 
@@ -21,7 +27,9 @@ import (
 )
 
 func main() {
-	ctx := context.Background()
+	// workers, websockets and the server stop on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	logger := log.Init(log.Config{Level: "Debug"})
 
@@ -79,7 +87,38 @@ func main() {
 
 	atomicSessionProvider := atomicSQLX.NewSqlxAtomicSessionProvider(leader, tracer, logger)
 
-	uc := usecase.Init(logger, *cfg, *jwt, *dom, atomicSessionProvider, tracer)
+	eventBroker, err := broker.Init(ctx, logger, broker.BrokerConfig{
+		Kind:         cfg.Event.Broker,
+		StreamPrefix: cfg.Event.StreamPrefix,
+		StreamMaxLen: cfg.Event.StreamMaxLen,
+	}, rds)
+	if err != nil {
+		panic(err)
+	}
+
+	paymentGateway, err := payment.Init(ctx, logger, payment.PaymentConfig{
+		Kind:          cfg.Payment.Provider,
+		BaseURL:       cfg.Payment.BaseURL,
+		WebhookSecret: cfg.Payment.WebhookSecret,
+		NotifyURL:     cfg.Payment.NotifyURL,
+		ReturnURL:     cfg.Payment.ReturnURL,
+		Timeout:       cfg.Payment.Timeout,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	charger, err := payment.InitCharger(ctx, logger, payment.ChargerConfig{
+		Kind:    cfg.Payment.Charger,
+		Decline: cfg.Payment.FakeDecline,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	uc := usecase.Init(logger, *cfg, *jwt, *dom, atomicSessionProvider, eventBroker, paymentGateway, charger, tracer)
+
+	worker.Init(ctx, logger, *cfg, uc)
 
 	handler.Init(ctx, logger, *cfg, uc, jwt)
 }
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"loverly/lib/log"
+	"loverly/lib/payment"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/spf13/viper"
+)
+
+// mockpay is a stand-in payment provider for local end-to-end testing, it keeps checkouts in memory and
+// delivers signed webhooks the way a real provider would. It listens where PAYMENT_BASE_URL of the service points
+func main() {
+	ctx := context.Background()
+
+	// the secret and URL are shared with the service, read them from the same env file
+	viper.SetConfigType("env")
+	viper.SetConfigFile(env("ENV_FILE", ".env"))
+	viper.ReadInConfig()
+	viper.AutomaticEnv()
+	viper.SetDefault("PAYMENT_BASE_URL", "http://127.0.0.1:4010")
+
+	addr := flag.String("addr", env("MOCKPAY_ADDRESS", ":4010"), "address to listen on")
+	url := flag.String("url", viper.GetString("PAYMENT_BASE_URL"), "base URL the checkout pages are linked from")
+	secret := flag.String("secret", viper.GetString("PAYMENT_WEBHOOK_SECRET"), "secret the webhooks are signed with")
+	flag.Parse()
+
+	logger := log.Init(log.Config{Level: "Debug"})
+
+	s := &server{
+		log:       logger,
+		url:       strings.TrimRight(*url, "/"),
+		secret:    *secret,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		checkouts: map[string]*checkout{},
+		refunds:   map[string]string{},
+	}
+
+	r := chi.NewRouter()
+	r.Post("/checkouts", s.create)
+	r.Get("/checkouts/{id}", s.page)
+	r.Post("/checkouts/{id}/pay", s.settle(payment.EventPaid))
+	r.Post("/checkouts/{id}/fail", s.settle(payment.EventFailed))
+	// sends the last event again with the same id, to exercise idempotent handling
+	r.Post("/checkouts/{id}/redeliver", s.redeliver)
+	r.Post("/checkouts/{id}/refunds", s.refund)
+
+	logger.Info(ctx, fmt.Sprintf("mockpay listening on %s", *addr))
+	if err := http.ListenAndServe(*addr, r); err != nil {
+		panic(err)
+	}
+}
+
+type checkout struct {
+	payment.MockpayCheckoutRequest
+	ID        string
+	LastEvent *payment.MockpayEvent
+}
+
+type server struct {
+	log       log.Interface
+	url       string
+	secret    string
+	client    *http.Client
+	mu        sync.Mutex
+	seq       int
+	checkouts map[string]*checkout
+	refunds   map[string]string // reference to refund id, a reference is refunded once
+}
+
+func (s *server) create(w http.ResponseWriter, r *http.Request) {
+	var req payment.MockpayCheckoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Reference == "" || req.Amount < 0 || req.NotifyURL == "" {
+		http.Error(w, "invalid checkout", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.seq++
+	c := &checkout{MockpayCheckoutRequest: req, ID: fmt.Sprintf("chk_%d_%d", time.Now().Unix(), s.seq)}
+	s.checkouts[c.ID] = c
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(payment.MockpayCheckoutResponse{ID: c.ID, CheckoutURL: s.url + "/checkouts/" + c.ID})
+}
+
+var pageTemplate = template.Must(template.New("checkout").Parse(`<!DOCTYPE html>
+<html>
+<body>
+<h1>mockpay</h1>
+<p>{{.Description}}</p>
+<p>{{.Amount}} {{.Currency}}</p>
+{{if .SaveMethod}}<p>the card is verified and kept on file</p>{{end}}
+{{if .LastEvent}}<p>last event: {{.LastEvent.Type}} ({{.LastEvent.ID}})</p>{{end}}
+<form method="post" action="/checkouts/{{.ID}}/pay"><button>Pay</button></form>
+<form method="post" action="/checkouts/{{.ID}}/fail"><button>Fail</button></form>
+<form method="post" action="/checkouts/{{.ID}}/redeliver"><button>Redeliver last event</button></form>
+</body>
+</html>`))
+
+func (s *server) page(w http.ResponseWriter, r *http.Request) {
+	c, ok := s.get(chi.URLParam(r, "id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	view := *c
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	pageTemplate.Execute(w, view)
+}
+
+func (s *server) settle(eventType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, ok := s.get(chi.URLParam(r, "id"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		s.mu.Lock()
+		s.seq++
+		event := &payment.MockpayEvent{
+			ID:         fmt.Sprintf("evt_%d_%d", time.Now().Unix(), s.seq),
+			Type:       eventType,
+			CheckoutId: c.ID,
+			Reference:  c.Reference,
+			Amount:     c.Amount,
+			Currency:   c.Currency,
+			CreatedAt:  time.Now(),
+		}
+		// the card is checked when it's saved, a failed checkout keeps nothing
+		if c.SaveMethod && eventType == payment.EventPaid {
+			event.PaymentMethod = "pm_" + c.ID
+		}
+		c.LastEvent = event
+		s.mu.Unlock()
+
+		s.deliver(w, r, c, *event)
+	}
+}
+
+func (s *server) redeliver(w http.ResponseWriter, r *http.Request) {
+	c, ok := s.get(chi.URLParam(r, "id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	event := c.LastEvent
+	s.mu.Unlock()
+
+	if event == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.deliver(w, r, c, *event)
+}
+
+func (s *server) refund(w http.ResponseWriter, r *http.Request) {
+	c, ok := s.get(chi.URLParam(r, "id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req payment.MockpayRefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Reference == "" || req.Amount < 0 {
+		http.Error(w, "invalid refund", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	if c.LastEvent == nil || c.LastEvent.Type != payment.EventPaid || req.Amount > c.Amount {
+		s.mu.Unlock()
+		http.Error(w, "nothing to refund", http.StatusConflict)
+		return
+	}
+	id, ok := s.refunds[req.Reference]
+	if !ok {
+		s.seq++
+		id = fmt.Sprintf("ref_%d_%d", time.Now().Unix(), s.seq)
+		s.refunds[req.Reference] = id
+	}
+	s.mu.Unlock()
+
+	s.log.Info(r.Context(), fmt.Sprintf("refunded %d %s of %s: %s", req.Amount, req.Currency, c.ID, req.Reason))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(payment.MockpayRefundResponse{ID: id})
+}
+
+// deliver posts the event to the service, a few attempts like a real provider would before giving up
+func (s *server) deliver(w http.ResponseWriter, r *http.Request, c *checkout, event payment.MockpayEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var status string
+	for attempt := 0; attempt < 3; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, c.NotifyURL, bytes.NewReader(body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(payment.MockpaySignatureHeader, payment.SignMockpay(s.secret, time.Now(), body))
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			status = err.Error()
+			continue
+		}
+		resp.Body.Close()
+
+		status = resp.Status
+		if resp.StatusCode < 300 {
+			break
+		}
+	}
+
+	s.log.Info(r.Context(), fmt.Sprintf("mockpay delivered %s %s of %s: %s", event.Type, event.ID, c.ID, status))
+
+	if c.ReturnURL != "" {
+		http.Redirect(w, r, c.ReturnURL, http.StatusSeeOther)
+		return
+	}
+
+	fmt.Fprintf(w, "%s %s delivered: %s\n", event.Type, event.ID, status)
+}
+
+func (s *server) get(id string) (*checkout, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.checkouts[id]
+	return c, ok
+}
+
+func env(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"loverly/lib/log"
+	"loverly/lib/postgres"
+	"loverly/lib/redis"
+	"loverly/src/business/domain"
+	"loverly/src/business/usecase/recommendation"
+	"loverly/src/config"
+
+	atomicSQLX "loverly/lib/atomic/sqlx"
+
+	"go.opentelemetry.io/otel"
+)
+
+// recommender rebuilds the per-user discovery recommendations from the swipe history, run it on a schedule
+func main() {
+	ctx := context.Background()
+
+	logger := log.Init(log.Config{Level: "Debug"})
+
+	cfg, err := config.InitConfig(ctx, logger)
+	if err != nil {
+		panic(err)
+	}
+
+	leader, err := postgres.InitSQLX(ctx, logger, postgres.PostgresConfig{
+		ConnectionUrl:      cfg.Postgres.ConnURI,
+		MaxPoolSize:        cfg.Postgres.MaxPoolSize,
+		MaxIdleConnections: cfg.Postgres.MaxIdleConnections,
+		ConnMaxIdleTime:    cfg.Postgres.MaxIdleTime,
+		ConnMaxLifeTime:    cfg.Postgres.MaxLifeTime,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	follower, err := postgres.InitSQLX(ctx, logger, postgres.PostgresConfig{
+		ConnectionUrl:      cfg.PostgresReader.ConnURI,
+		MaxPoolSize:        cfg.PostgresReader.MaxPoolSize,
+		MaxIdleConnections: cfg.PostgresReader.MaxIdleConnections,
+		ConnMaxIdleTime:    cfg.PostgresReader.MaxIdleTime,
+		ConnMaxLifeTime:    cfg.PostgresReader.MaxLifeTime,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	rds, err := redis.InitRedis(ctx, logger, cfg.Redis.Host, cfg.Redis.Password)
+	if err != nil {
+		panic(err)
+	}
+
+	dom := domain.Init(ctx, domain.InitParam{Log: logger, Cfg: cfg, LeaderDB: leader, FollowerDB: follower, Rds: rds})
+
+	tracer := otel.Tracer(cfg.ServiceName)
+
+	atomicSessionProvider := atomicSQLX.NewSqlxAtomicSessionProvider(leader, tracer, logger)
+
+	rec := recommendation.Init(logger, cfg.Recommender, dom.Swipe, dom.Recommendation, atomicSessionProvider)
+
+	written, err := rec.Build(ctx)
+	if err != nil {
+		panic(err)
+	}
+
+	logger.Info(ctx, fmt.Sprintf("recommendations built for %d users", written))
+}
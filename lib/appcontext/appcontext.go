@@ -17,9 +17,11 @@ const (
 	serviceVersion   contextKey = "ServiceVersion"
 	userAgent        contextKey = "UserAgent"
 	userId           contextKey = "UserId"
+	userRole         contextKey = "UserRole"
 	requestStartTime contextKey = "RequestStartTime"
 	appResponseCode  contextKey = "AppResponseCode"
 	deviceType       contextKey = "DeviceType"
+	deviceId         contextKey = "DeviceId"
 	appErrorMessage  contextKey = "AppErrorMessage"
 	cacheControl     contextKey = "CacheControl"
 	eventName        contextKey = "EventName"
@@ -96,6 +98,18 @@ func GetUserId(ctx context.Context) int {
 	return ui
 }
 
+func SetUserRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, userRole, role)
+}
+
+func GetUserRole(ctx context.Context) string {
+	role, ok := ctx.Value(userRole).(string)
+	if !ok {
+		return ""
+	}
+	return role
+}
+
 func SetRequestStartTime(ctx context.Context, t time.Time) context.Context {
 	return context.WithValue(ctx, requestStartTime, t)
 }
@@ -128,6 +142,19 @@ func GetDeviceType(ctx context.Context) string {
 	return platform
 }
 
+func SetDeviceId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, deviceId, id)
+}
+
+func GetDeviceId(ctx context.Context) string {
+	id, ok := ctx.Value(deviceId).(string)
+	if !ok {
+		return ""
+	}
+
+	return id
+}
+
 func SetAppErrorMessage(ctx context.Context, errMsg string) context.Context {
 	return context.WithValue(ctx, appErrorMessage, errMsg)
 }
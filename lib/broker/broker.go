@@ -0,0 +1,61 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"loverly/lib/log"
+	"loverly/lib/redis"
+	"strconv"
+	"time"
+)
+
+// Message is what leaves the service, Key lets consumers group messages of the same entity
+type Message struct {
+	ID         int64
+	Topic      string
+	Key        string
+	Payload    []byte
+	OccurredAt time.Time
+}
+
+type Interface interface {
+	Publish(ctx context.Context, msg Message) error
+}
+
+func Init(ctx context.Context, log log.Interface, cfg BrokerConfig, rds redis.Redis) (Interface, error) {
+	switch cfg.Kind {
+	case KindRedis:
+		return &streams{rds: rds, prefix: cfg.StreamPrefix, maxLen: cfg.StreamMaxLen}, nil
+	case KindNone, "":
+		return &noop{}, nil
+	}
+
+	err := fmt.Errorf("unknown broker %s", cfg.Kind)
+	log.Error(ctx, fmt.Sprintf("broker Init err: %v", err))
+	return nil, err
+}
+
+// streams publishes every topic to its own redis stream
+type streams struct {
+	rds    redis.Redis
+	prefix string
+	maxLen int64
+}
+
+func (s *streams) Publish(ctx context.Context, msg Message) error {
+	_, err := s.rds.XAdd(ctx, s.prefix+msg.Topic, s.maxLen, map[string]interface{}{
+		"id":          strconv.FormatInt(msg.ID, 10),
+		"key":         msg.Key,
+		"payload":     string(msg.Payload),
+		"occurred_at": msg.OccurredAt.Format(time.RFC3339Nano),
+	})
+
+	return err
+}
+
+// noop keeps events in-process only
+type noop struct{}
+
+func (n *noop) Publish(ctx context.Context, msg Message) error {
+	return nil
+}
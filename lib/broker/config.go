@@ -0,0 +1,12 @@
+package broker
+
+const (
+	KindRedis = "redis"
+	KindNone  = "none"
+)
+
+type BrokerConfig struct {
+	Kind         string
+	StreamPrefix string
+	StreamMaxLen int64
+}
@@ -0,0 +1,41 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Max is where a first page starts, after everything that could exist
+var Max = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// Encode packs the sort value and id of the last row of a page into an opaque token
+func Encode(at time.Time, id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d.%d", at.UnixNano(), id)))
+}
+
+func Decode(cursor string) (time.Time, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	at, id, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return time.Time{}, 0, fmt.Errorf("malformed cursor %q", raw)
+	}
+
+	nanos, err := strconv.ParseInt(at, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	rowId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	return time.Unix(0, nanos).UTC(), rowId, nil
+}
@@ -10,8 +10,15 @@ const (
 	KeyAcceptLanguage string = "accept-language"
 	KeyCacheControl   string = "cache-control"
 	KeyDeviceType     string = "x-device-type"
+	KeyDeviceId       string = "x-device-id"
 	KeyServiceName    string = "x-service-name"
 
+	// Rate limit, see https://datatracker.ietf.org/doc/draft-ietf-httpapi-ratelimit-headers/
+	KeyRateLimitLimit     string = "ratelimit-limit"
+	KeyRateLimitRemaining string = "ratelimit-remaining"
+	KeyRateLimitReset     string = "ratelimit-reset"
+	KeyRetryAfter         string = "retry-after"
+
 	// Content type. Specifying the payload in the request
 	ContentTypeJSON string = "application/json"
 	ContentTypeXML  string = "application/xml"
@@ -30,7 +30,8 @@ type (
 	}
 
 	AccessTokenClaimData struct {
-		UserId int64 `json:"user_id"`
+		UserId  int64 `json:"user_id"`
+		Version int   `json:"ver"`
 	}
 
 	RefreshToken struct {
@@ -41,6 +42,7 @@ type (
 	RefreshTokenClaimData struct {
 		AccessTokenId string `json:"token_id,omitempty"`
 		AccessType    string `json:"access_type"`
+		Version       int    `json:"ver"`
 	}
 )
 
@@ -71,14 +73,15 @@ func Init(ctx context.Context, cfg *Configuration, log log.Interface) *TokenProv
 }
 
 /*
-Create new accessToken for given user and identity
+Create new accessToken for given user and identity, roles is carried as is in the roles claim,
+version is the user's token version, both tokens stop being accepted once it moves on
 */
-func (t TokenProvider) NewAccessToken(ctx context.Context, userId int64, audiences []string, accessType string) (*oauth2.Token, error) {
+func (t TokenProvider) NewAccessToken(ctx context.Context, userId int64, version int, roles string, audiences []string, accessType string) (*oauth2.Token, error) {
 	if accessType != AccessTypeOffline && accessType != AccessTypeOnline {
 		return nil, fmt.Errorf("invalid_access_type")
 	}
 
-	accessToken, err := t.newAccessToken(ctx, userId, audiences)
+	accessToken, err := t.newAccessToken(ctx, userId, version, roles, audiences)
 	if err != nil {
 		return nil, err
 	}
@@ -108,7 +111,7 @@ func (t TokenProvider) NewAccessToken(ctx context.Context, userId int64, audienc
 	return &token, nil
 }
 
-func (t TokenProvider) newAccessToken(ctx context.Context, userId int64, audiences []string) (*AccessToken, error) {
+func (t TokenProvider) newAccessToken(ctx context.Context, userId int64, version int, roles string, audiences []string) (*AccessToken, error) {
 	accessToken := AccessToken{
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        uuid.New().String(),
@@ -119,9 +122,10 @@ func (t TokenProvider) newAccessToken(ctx context.Context, userId int64, audienc
 			Subject:   fmt.Sprintf("%d", userId),
 		},
 		Scopes: "*",
-		Roles:  "user",
+		Roles:  roles,
 		Data: AccessTokenClaimData{
-			UserId: userId,
+			UserId:  userId,
+			Version: version,
 		},
 	}
 	return &accessToken, nil
@@ -143,6 +147,7 @@ func (t TokenProvider) newRefreshToken(ctx context.Context, accessToken AccessTo
 		Data: RefreshTokenClaimData{
 			AccessTokenId: accessToken.ID,
 			AccessType:    AccessTypeOnline,
+			Version:       accessToken.Data.Version,
 		},
 	}
 	if accessType == AccessTypeOffline {
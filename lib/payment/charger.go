@@ -0,0 +1,65 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"loverly/lib/log"
+	"sync"
+)
+
+var ErrChargeDeclined = errors.New("charge_declined")
+
+// Charge bills the payment method the user has on file, Reference is ours and the provider charges it once
+// however many times it's sent
+type Charge struct {
+	Reference   string
+	UserId      int64
+	Amount      int64 // in the smallest unit of Currency
+	Currency    string
+	Description string
+}
+
+// Charger bills without the user around, e.g. to renew a subscription
+type Charger interface {
+	// Charge returns the provider reference of the charge
+	Charge(ctx context.Context, charge Charge) (string, error)
+}
+
+func InitCharger(ctx context.Context, log log.Interface, cfg ChargerConfig) (Charger, error) {
+	switch cfg.Kind {
+	case KindFake:
+		return &fakeCharger{decline: cfg.Decline, charged: map[string]string{}}, nil
+	}
+
+	err := fmt.Errorf("unknown charger %s", cfg.Kind)
+	log.Error(ctx, fmt.Sprintf("payment InitCharger err: %v", err))
+	return nil, err
+}
+
+// fakeCharger stands in for a provider locally, it accepts or declines every charge and like a provider
+// answers a reference it already charged with that charge
+type fakeCharger struct {
+	decline bool
+
+	mu      sync.Mutex
+	charged map[string]string
+}
+
+func (f *fakeCharger) Charge(ctx context.Context, charge Charge) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if ref, ok := f.charged[charge.Reference]; ok {
+		return ref, nil
+	}
+
+	if f.decline {
+		return "", ErrChargeDeclined
+	}
+
+	ref := fmt.Sprintf("fake_%d_%s", len(f.charged)+1, charge.Reference)
+	f.charged[charge.Reference] = ref
+
+	return ref, nil
+}
@@ -0,0 +1,22 @@
+package payment
+
+import "time"
+
+const (
+	KindMock = "mock"
+	KindFake = "fake"
+)
+
+type PaymentConfig struct {
+	Kind          string
+	BaseURL       string        // where the provider API is reached
+	WebhookSecret string        // shared with the provider to sign webhooks
+	NotifyURL     string        // where the provider delivers webhooks
+	ReturnURL     string        // where the user lands after checkout
+	Timeout       time.Duration // of a single provider API call
+}
+
+type ChargerConfig struct {
+	Kind    string
+	Decline bool // the fake declines every charge, to walk through failed renewals
+}
@@ -0,0 +1,55 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: charger.go
+//
+// Generated by this command:
+//
+//	mockgen -source=charger.go -destination=mock/charger.go
+//
+// Package mock_payment is a generated GoMock package.
+package mock_payment
+
+import (
+	context "context"
+	payment "loverly/lib/payment"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockCharger is a mock of Charger interface.
+type MockCharger struct {
+	ctrl     *gomock.Controller
+	recorder *MockChargerMockRecorder
+}
+
+// MockChargerMockRecorder is the mock recorder for MockCharger.
+type MockChargerMockRecorder struct {
+	mock *MockCharger
+}
+
+// NewMockCharger creates a new mock instance.
+func NewMockCharger(ctrl *gomock.Controller) *MockCharger {
+	mock := &MockCharger{ctrl: ctrl}
+	mock.recorder = &MockChargerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCharger) EXPECT() *MockChargerMockRecorder {
+	return m.recorder
+}
+
+// Charge mocks base method.
+func (m *MockCharger) Charge(ctx context.Context, charge payment.Charge) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Charge", ctx, charge)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Charge indicates an expected call of Charge.
+func (mr *MockChargerMockRecorder) Charge(ctx, charge any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Charge", reflect.TypeOf((*MockCharger)(nil).Charge), ctx, charge)
+}
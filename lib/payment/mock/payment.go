@@ -0,0 +1,100 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: payment.go
+//
+// Generated by this command:
+//
+//	mockgen -source=payment.go -destination=mock/payment.go
+//
+// Package mock_payment is a generated GoMock package.
+package mock_payment
+
+import (
+	context "context"
+	payment "loverly/lib/payment"
+	http "net/http"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInterface is a mock of Interface interface.
+type MockInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockInterfaceMockRecorder
+}
+
+// MockInterfaceMockRecorder is the mock recorder for MockInterface.
+type MockInterfaceMockRecorder struct {
+	mock *MockInterface
+}
+
+// NewMockInterface creates a new mock instance.
+func NewMockInterface(ctrl *gomock.Controller) *MockInterface {
+	mock := &MockInterface{ctrl: ctrl}
+	mock.recorder = &MockInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInterface) EXPECT() *MockInterfaceMockRecorder {
+	return m.recorder
+}
+
+// CreateCheckout mocks base method.
+func (m *MockInterface) CreateCheckout(ctx context.Context, checkout payment.Checkout) (payment.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCheckout", ctx, checkout)
+	ret0, _ := ret[0].(payment.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateCheckout indicates an expected call of CreateCheckout.
+func (mr *MockInterfaceMockRecorder) CreateCheckout(ctx, checkout any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCheckout", reflect.TypeOf((*MockInterface)(nil).CreateCheckout), ctx, checkout)
+}
+
+// Name mocks base method.
+func (m *MockInterface) Name() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Name")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Name indicates an expected call of Name.
+func (mr *MockInterfaceMockRecorder) Name() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Name", reflect.TypeOf((*MockInterface)(nil).Name))
+}
+
+// ParseWebhook mocks base method.
+func (m *MockInterface) ParseWebhook(header http.Header, body []byte) (payment.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseWebhook", header, body)
+	ret0, _ := ret[0].(payment.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseWebhook indicates an expected call of ParseWebhook.
+func (mr *MockInterfaceMockRecorder) ParseWebhook(header, body any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseWebhook", reflect.TypeOf((*MockInterface)(nil).ParseWebhook), header, body)
+}
+
+// Refund mocks base method.
+func (m *MockInterface) Refund(ctx context.Context, refund payment.Refund) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Refund", ctx, refund)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Refund indicates an expected call of Refund.
+func (mr *MockInterfaceMockRecorder) Refund(ctx, refund any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refund", reflect.TypeOf((*MockInterface)(nil).Refund), ctx, refund)
+}
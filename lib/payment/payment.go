@@ -0,0 +1,77 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"loverly/lib/log"
+	"net/http"
+	"time"
+)
+
+const (
+	EventPaid   = "payment.paid"
+	EventFailed = "payment.failed"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid_signature")
+	ErrInvalidEvent     = errors.New("invalid_event")
+)
+
+// Checkout is what the provider charges, Reference is ours and comes back in every event about it
+type Checkout struct {
+	Reference   string
+	Amount      int64 // in the smallest unit of Currency
+	Currency    string
+	Description string
+	SaveMethod  bool // the provider verifies the payment method and keeps it on file for Charger, Amount may be 0
+}
+
+// Session is the provider side of a checkout, the user pays at CheckoutURL
+type Session struct {
+	ProviderRef string
+	CheckoutURL string
+}
+
+// Event is a verified webhook, ID is unique per provider and repeats when the provider redelivers it
+type Event struct {
+	ID            string
+	Type          string
+	ProviderRef   string
+	Reference     string
+	Amount        int64
+	Currency      string
+	OccurredAt    time.Time
+	PaymentMethod string // verified and kept on file by the provider, only for a paid checkout with SaveMethod
+}
+
+// Refund gives back Amount of the checkout at ProviderRef, Reference is ours and the provider refunds it once
+// however many times it's sent
+type Refund struct {
+	ProviderRef string
+	Reference   string
+	Amount      int64
+	Currency    string
+	Reason      string
+}
+
+type Interface interface {
+	Name() string
+	CreateCheckout(ctx context.Context, checkout Checkout) (Session, error)
+	// ParseWebhook only returns events whose signature checks out
+	ParseWebhook(header http.Header, body []byte) (Event, error)
+	// Refund returns the provider reference of the refund
+	Refund(ctx context.Context, refund Refund) (string, error)
+}
+
+func Init(ctx context.Context, log log.Interface, cfg PaymentConfig) (Interface, error) {
+	switch cfg.Kind {
+	case KindMock:
+		return &mockpay{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}, now: time.Now}, nil
+	}
+
+	err := fmt.Errorf("unknown payment provider %s", cfg.Kind)
+	log.Error(ctx, fmt.Sprintf("payment Init err: %v", err))
+	return nil, err
+}
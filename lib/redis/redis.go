@@ -22,7 +22,23 @@ type Redis interface {
 	DelWithPattern(ctx context.Context, pattern string) error
 	Get(ctx context.Context, key string) (string, error)
 	Set(ctx context.Context, key string, value string, duration time.Duration) error
+	SetNX(ctx context.Context, key string, value string, duration time.Duration) (bool, error)
+	GetDel(ctx context.Context, key string) (string, error)
 	Del(ctx context.Context, key string) error
+	Exists(ctx context.Context, key string) (bool, error)
+	Incr(ctx context.Context, key string) (int64, error)
+	IncrWindow(ctx context.Context, key, previousKey string, duration time.Duration) (int64, int64, error)
+	ZAdd(ctx context.Context, key string, score float64, member string) error
+	ZRangeByScore(ctx context.Context, key string, min, max string) ([]string, error)
+	ZRemRangeByScore(ctx context.Context, key string, min, max string) error
+	ReplaceList(ctx context.Context, key string, values []string, duration time.Duration) error
+	LPopCount(ctx context.Context, key string, count int) ([]string, error)
+	LLen(ctx context.Context, key string) (int64, error)
+	SAdd(ctx context.Context, key string, member string) error
+	SPopN(ctx context.Context, key string, count int64) ([]string, error)
+	XAdd(ctx context.Context, stream string, maxLen int64, values map[string]interface{}) (string, error)
+	Publish(ctx context.Context, channel string, message string) error
+	Subscribe(ctx context.Context, channel string) (<-chan string, error)
 }
 
 func Init(ctx context.Context, log log.Interface, addr, password string) (*redis.Client, error) {
@@ -127,6 +143,30 @@ func (rds *RedisCfg) Set(ctx context.Context, key string, value string, duration
 	return nil
 }
 
+// SetNX sets the key only when it doesn't exist yet and tells whether it did
+func (rds *RedisCfg) SetNX(ctx context.Context, key string, value string, duration time.Duration) (bool, error) {
+	ok, err := rds.Conn.SetNX(ctx, key, value, duration).Result()
+	if err != nil {
+		rds.log.Error(ctx, fmt.Sprintf("error when setnx data redis:  %v", err))
+		return false, err
+	}
+
+	return ok, nil
+}
+
+// GetDel reads the key and deletes it in one step, a missing key is redis.Nil like Get
+func (rds *RedisCfg) GetDel(ctx context.Context, key string) (string, error) {
+	val, err := rds.Conn.GetDel(ctx, key).Result()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			rds.log.Error(ctx, fmt.Sprintf("error when getdel data redis:  %v", err))
+		}
+		return "", err
+	}
+
+	return val, nil
+}
+
 func (rds *RedisCfg) Del(ctx context.Context, key string) error {
 	err := rds.Conn.Del(ctx, key).Err()
 	if err != nil {
@@ -168,3 +208,206 @@ func (rds *RedisCfg) DelWithPattern(ctx context.Context, pattern string) error {
 
 	return nil
 }
+
+func (rds *RedisCfg) Exists(ctx context.Context, key string) (bool, error) {
+	n, err := rds.Conn.Exists(ctx, key).Result()
+	if err != nil {
+		rds.log.Error(ctx, fmt.Sprintf("error when check key exists redis:  %v", err))
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func (rds *RedisCfg) Incr(ctx context.Context, key string) (int64, error) {
+	val, err := rds.Conn.Incr(ctx, key).Result()
+	if err != nil {
+		rds.log.Error(ctx, fmt.Sprintf("error when incr data redis:  %v", err))
+		return 0, err
+	}
+
+	return val, nil
+}
+
+// IncrWindow counts a hit in the window key and reads the previous window in one round trip, a missing previous window counts as 0
+func (rds *RedisCfg) IncrWindow(ctx context.Context, key, previousKey string, duration time.Duration) (int64, int64, error) {
+	var current *redis.IntCmd
+	var previous *redis.StringCmd
+	_, err := rds.Conn.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		current = pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, duration)
+		previous = pipe.Get(ctx, previousKey)
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		rds.log.Error(ctx, fmt.Sprintf("error when incr window redis:  %v", err))
+		return 0, 0, err
+	}
+
+	prev, _ := previous.Int64()
+
+	return current.Val(), prev, nil
+}
+
+func (rds *RedisCfg) ZAdd(ctx context.Context, key string, score float64, member string) error {
+	err := rds.Conn.ZAdd(ctx, key, redis.Z{Score: score, Member: member}).Err()
+	if err != nil {
+		rds.log.Error(ctx, fmt.Sprintf("error when zadd data redis:  %v", err))
+		return err
+	}
+
+	return nil
+}
+
+func (rds *RedisCfg) ZRangeByScore(ctx context.Context, key string, min, max string) ([]string, error) {
+	vals, err := rds.Conn.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: min, Max: max}).Result()
+	if err != nil {
+		rds.log.Error(ctx, fmt.Sprintf("error when zrangebyscore data redis:  %v", err))
+		return nil, err
+	}
+
+	return vals, nil
+}
+
+func (rds *RedisCfg) ZRemRangeByScore(ctx context.Context, key string, min, max string) error {
+	err := rds.Conn.ZRemRangeByScore(ctx, key, min, max).Err()
+	if err != nil {
+		rds.log.Error(ctx, fmt.Sprintf("error when zremrangebyscore data redis:  %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// ReplaceList swaps the whole list atomically so readers never see it half written
+func (rds *RedisCfg) ReplaceList(ctx context.Context, key string, values []string, duration time.Duration) error {
+	_, err := rds.Conn.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		if len(values) > 0 {
+			args := make([]interface{}, len(values))
+			for i, v := range values {
+				args[i] = v
+			}
+			pipe.RPush(ctx, key, args...)
+			pipe.Expire(ctx, key, duration)
+		}
+		return nil
+	})
+	if err != nil {
+		rds.log.Error(ctx, fmt.Sprintf("error when replace list redis:  %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// LPopCount returns an empty slice when the list doesn't exist
+func (rds *RedisCfg) LPopCount(ctx context.Context, key string, count int) ([]string, error) {
+	vals, err := rds.Conn.LPopCount(ctx, key, count).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		rds.log.Error(ctx, fmt.Sprintf("error when lpop data redis:  %v", err))
+		return nil, err
+	}
+
+	return vals, nil
+}
+
+func (rds *RedisCfg) LLen(ctx context.Context, key string) (int64, error) {
+	n, err := rds.Conn.LLen(ctx, key).Result()
+	if err != nil {
+		rds.log.Error(ctx, fmt.Sprintf("error when llen data redis:  %v", err))
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func (rds *RedisCfg) SAdd(ctx context.Context, key string, member string) error {
+	err := rds.Conn.SAdd(ctx, key, member).Err()
+	if err != nil {
+		rds.log.Error(ctx, fmt.Sprintf("error when sadd data redis:  %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// SPopN returns an empty slice when the set doesn't exist
+func (rds *RedisCfg) SPopN(ctx context.Context, key string, count int64) ([]string, error) {
+	vals, err := rds.Conn.SPopN(ctx, key, count).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		rds.log.Error(ctx, fmt.Sprintf("error when spop data redis:  %v", err))
+		return nil, err
+	}
+
+	return vals, nil
+}
+
+// XAdd appends to the stream and trims it to roughly maxLen entries, zero keeps everything
+func (rds *RedisCfg) XAdd(ctx context.Context, stream string, maxLen int64, values map[string]interface{}) (string, error) {
+	id, err := rds.Conn.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: maxLen,
+		Approx: maxLen > 0,
+		Values: values,
+	}).Result()
+	if err != nil {
+		rds.log.Error(ctx, fmt.Sprintf("error when xadd data redis:  %v", err))
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (rds *RedisCfg) Publish(ctx context.Context, channel string, message string) error {
+	err := rds.Conn.Publish(ctx, channel, message).Err()
+	if err != nil {
+		rds.log.Error(ctx, fmt.Sprintf("error when publish data redis:  %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// Subscribe streams the payloads published to the channel until ctx is done, the connection
+// is re-established by the client on its own when it drops
+func (rds *RedisCfg) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	pubsub := rds.Conn.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		rds.log.Error(ctx, fmt.Sprintf("error when subscribe redis:  %v", err))
+		pubsub.Close()
+		return nil, err
+	}
+
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				select {
+				case out <- msg.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Discovery looks up swipes from both sides of a pair and matches by either user
+CREATE INDEX IF NOT EXISTS idx_swipes_swiped_id ON swipes (swiped_id, swiper_id);
+CREATE INDEX IF NOT EXISTS idx_matchs_user_id_2 ON matchs (user_id_2, user_id_1);
+
+COMMIT;
@@ -0,0 +1,46 @@
+BEGIN;
+
+CREATE TYPE BOOST_SOURCE AS ENUM ('plan', 'consumable');
+
+-- Create the table boosts
+CREATE TABLE boosts(
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Utility columns
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+
+    user_id BIGINT NOT NULL,
+    source BOOST_SOURCE NOT NULL,
+    location VARCHAR NOT NULL DEFAULT '',
+    started_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    views BIGINT NOT NULL DEFAULT 0,
+    likes BIGINT NOT NULL DEFAULT 0,
+    summarized_at TIMESTAMPTZ
+);
+
+-- Create the table boost_credits, a ledger of consumable boosts (purchases are positive, usages negative)
+CREATE TABLE boost_credits(
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Utility columns
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+
+    user_id BIGINT NOT NULL,
+    amount INT NOT NULL,
+    reason VARCHAR NOT NULL
+);
+
+ALTER TABLE ONLY boosts
+    ADD CONSTRAINT user_id FOREIGN KEY (user_id) REFERENCES users(id) NOT VALID;
+
+ALTER TABLE ONLY boost_credits
+    ADD CONSTRAINT user_id FOREIGN KEY (user_id) REFERENCES users(id) NOT VALID;
+
+CREATE INDEX IF NOT EXISTS idx_boosts_user_id ON boosts (user_id, started_at);
+
+COMMIT;
@@ -0,0 +1,26 @@
+BEGIN;
+
+-- Create the table recommendations, candidate lists built offline by cmd/recommender.
+-- user_id 0 holds the popularity list used for users without any swipe history yet.
+CREATE TABLE recommendations(
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Utility columns
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+
+    user_id BIGINT NOT NULL,
+    candidate_id BIGINT NOT NULL,
+    score DOUBLE PRECISION NOT NULL
+);
+
+ALTER TABLE ONLY recommendations
+    ADD CONSTRAINT candidate_id FOREIGN KEY (candidate_id) REFERENCES users(id) NOT VALID;
+
+ALTER TABLE ONLY recommendations
+    ADD CONSTRAINT unique_recommendations_id UNIQUE (user_id, candidate_id);
+
+CREATE INDEX IF NOT EXISTS idx_swipes_updated_at ON swipes (updated_at);
+
+COMMIT;
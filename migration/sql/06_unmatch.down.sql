@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_matchs_user_id_2_active;
+DROP INDEX IF EXISTS idx_matchs_user_id_1;
+
+ALTER TABLE matchs DROP COLUMN IF EXISTS deleted_by;
+
+COMMIT;
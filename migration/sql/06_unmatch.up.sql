@@ -0,0 +1,10 @@
+BEGIN;
+
+-- who ended the match, matches are soft-deleted so the pair never resurfaces in discovery
+ALTER TABLE matchs ADD COLUMN deleted_by BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_matchs_user_id_1 ON matchs (user_id_1) WHERE deleted_at IS NULL;
+-- idx_matchs_user_id_2 is the pair index of 03_discovery_exclusion
+CREATE INDEX IF NOT EXISTS idx_matchs_user_id_2_active ON matchs (user_id_2) WHERE deleted_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+-- bumped whenever either side acts on the match, used to order the match list
+ALTER TABLE matchs ADD COLUMN last_activity_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE matchs SET last_activity_at = created_at;
+
+CREATE INDEX IF NOT EXISTS idx_matchs_created_at ON matchs (created_at DESC, id DESC) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_matchs_last_activity_at ON matchs (last_activity_at DESC, id DESC) WHERE deleted_at IS NULL;
+
+COMMIT;
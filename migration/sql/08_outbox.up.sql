@@ -0,0 +1,22 @@
+BEGIN;
+
+-- Create the table outbox, domain events written in the same transaction as the change they describe
+CREATE TABLE outbox(
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Utility columns
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+
+    type VARCHAR NOT NULL,
+    user_id BIGINT NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error VARCHAR,
+    published_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox (id) WHERE published_at IS NULL AND deleted_at IS NULL;
+
+COMMIT;
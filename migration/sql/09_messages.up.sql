@@ -0,0 +1,27 @@
+BEGIN;
+
+-- Create the table messages, only the two users of an active match can write to it
+CREATE TABLE messages(
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Utility columns
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+
+    match_id BIGINT NOT NULL,
+    sender_id BIGINT NOT NULL,
+    body VARCHAR NOT NULL,
+    read_at TIMESTAMPTZ
+);
+
+ALTER TABLE ONLY messages
+    ADD CONSTRAINT match_id FOREIGN KEY (match_id) REFERENCES matchs(id) NOT VALID;
+
+ALTER TABLE ONLY messages
+    ADD CONSTRAINT sender_id FOREIGN KEY (sender_id) REFERENCES users(id) NOT VALID;
+
+CREATE INDEX IF NOT EXISTS idx_messages_match_id ON messages (match_id, created_at DESC, id DESC) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_messages_unread ON messages (match_id, sender_id) WHERE read_at IS NULL AND deleted_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,18 @@
+BEGIN;
+
+-- a match nobody engages with (opens or acts on) expires at expires_at, or MATCH_EXPIRE_AFTER past created_at
+ALTER TABLE matchs ADD COLUMN engaged_at TIMESTAMPTZ;
+ALTER TABLE matchs ADD COLUMN first_message_at TIMESTAMPTZ;
+ALTER TABLE matchs ADD COLUMN expires_at TIMESTAMPTZ;
+ALTER TABLE matchs ADD COLUMN expiry_notified_at TIMESTAMPTZ;
+ALTER TABLE matchs ADD COLUMN extended_at TIMESTAMPTZ;
+
+-- existing matches predate the rule and never expire
+UPDATE matchs SET engaged_at = last_activity_at;
+UPDATE matchs m SET first_message_at = msg.created_at
+FROM (SELECT match_id, MIN(created_at) AS created_at FROM messages GROUP BY match_id) msg
+WHERE msg.match_id = m.id;
+
+CREATE INDEX IF NOT EXISTS idx_matchs_unengaged ON matchs (created_at) WHERE engaged_at IS NULL AND deleted_at IS NULL;
+
+COMMIT;
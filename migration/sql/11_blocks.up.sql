@@ -0,0 +1,28 @@
+BEGIN;
+
+-- Create the table blocks, a block hides both users from each other until it is lifted (soft-deleted)
+CREATE TABLE blocks(
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Utility columns
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+
+    blocker_id BIGINT NOT NULL,
+    blocked_id BIGINT NOT NULL
+);
+
+ALTER TABLE ONLY blocks
+    ADD CONSTRAINT unique_blocks_id UNIQUE (blocker_id, blocked_id);
+
+ALTER TABLE ONLY blocks
+    ADD CONSTRAINT blocker_id FOREIGN KEY (blocker_id) REFERENCES users(id) NOT VALID;
+
+ALTER TABLE ONLY blocks
+    ADD CONSTRAINT blocked_id FOREIGN KEY (blocked_id) REFERENCES users(id) NOT VALID;
+
+-- the unique constraint covers lookups by blocker, this one the other direction
+CREATE INDEX IF NOT EXISTS idx_blocks_blocked_id ON blocks (blocked_id, blocker_id) WHERE deleted_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,73 @@
+BEGIN;
+
+CREATE TYPE USER_ROLE AS ENUM ('user', 'moderator', 'admin');
+
+ALTER TABLE users ADD COLUMN role USER_ROLE NOT NULL DEFAULT 'user';
+
+CREATE TYPE REPORT_TARGET AS ENUM ('profile', 'photo', 'message');
+CREATE TYPE REPORT_REASON AS ENUM ('spam', 'harassment', 'inappropriate', 'fake', 'underage', 'other');
+CREATE TYPE REPORT_STATUS AS ENUM ('open', 'actioned', 'dismissed');
+CREATE TYPE MODERATION_ACTION AS ENUM ('assign', 'dismiss', 'warn', 'suspend', 'ban');
+
+-- Create the table reports, the moderation queue
+CREATE TABLE reports(
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Utility columns
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+
+    reporter_id BIGINT NOT NULL,
+    reported_id BIGINT NOT NULL,
+    target_type REPORT_TARGET NOT NULL,
+    -- the reported message, profiles and photos are identified by reported_id
+    target_id BIGINT,
+    reason REPORT_REASON NOT NULL,
+    details VARCHAR NOT NULL DEFAULT '',
+    status REPORT_STATUS NOT NULL DEFAULT 'open',
+    assignee_id BIGINT,
+    resolved_at TIMESTAMPTZ
+);
+
+-- Create the table moderation_actions, every step a moderator takes on a report or a user
+CREATE TABLE moderation_actions(
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Utility columns
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+
+    report_id BIGINT,
+    moderator_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    action MODERATION_ACTION NOT NULL,
+    note VARCHAR NOT NULL DEFAULT '',
+    -- end of a suspension
+    expires_at TIMESTAMPTZ
+);
+
+ALTER TABLE ONLY reports
+    ADD CONSTRAINT reporter_id FOREIGN KEY (reporter_id) REFERENCES users(id) NOT VALID;
+
+ALTER TABLE ONLY reports
+    ADD CONSTRAINT reported_id FOREIGN KEY (reported_id) REFERENCES users(id) NOT VALID;
+
+ALTER TABLE ONLY reports
+    ADD CONSTRAINT assignee_id FOREIGN KEY (assignee_id) REFERENCES users(id) NOT VALID;
+
+ALTER TABLE ONLY moderation_actions
+    ADD CONSTRAINT report_id FOREIGN KEY (report_id) REFERENCES reports(id) NOT VALID;
+
+ALTER TABLE ONLY moderation_actions
+    ADD CONSTRAINT moderator_id FOREIGN KEY (moderator_id) REFERENCES users(id) NOT VALID;
+
+ALTER TABLE ONLY moderation_actions
+    ADD CONSTRAINT user_id FOREIGN KEY (user_id) REFERENCES users(id) NOT VALID;
+
+CREATE INDEX IF NOT EXISTS idx_reports_queue ON reports (status, created_at, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_reports_reported_id ON reports (reported_id);
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_user_id ON moderation_actions (user_id, created_at);
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+CREATE TYPE USER_STATUS AS ENUM ('active', 'suspended', 'banned');
+
+-- a suspension ends by itself once suspended_until has passed, status is not reset
+ALTER TABLE users ADD COLUMN status USER_STATUS NOT NULL DEFAULT 'active';
+ALTER TABLE users ADD COLUMN suspended_until TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN status_reason VARCHAR NOT NULL DEFAULT '';
+
+CREATE INDEX idx_users_restricted ON users (id) WHERE status <> 'active';
+
+COMMIT;
@@ -0,0 +1,55 @@
+BEGIN;
+
+CREATE TYPE ABUSE_SIGNAL AS ENUM ('swipe_rate', 'like_ratio', 'shared_bio', 'shared_ip', 'shared_device');
+CREATE TYPE ABUSE_FLAG_STATUS AS ENUM ('open', 'confirmed', 'dismissed');
+
+-- set while the user has an open or confirmed flag, their likes don't count and they're left out of discovery
+ALTER TABLE users ADD COLUMN shadow_limited BOOLEAN NOT NULL DEFAULT false;
+
+-- Create the table user_fingerprints, the IPs and devices each account was seen from
+CREATE TABLE user_fingerprints(
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Utility columns
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+
+    user_id BIGINT NOT NULL,
+    ip VARCHAR NOT NULL DEFAULT '',
+    device_id VARCHAR NOT NULL DEFAULT '',
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT unique_user_fingerprints UNIQUE (user_id, ip, device_id),
+    CONSTRAINT fk_user_fingerprints_user_id FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX idx_user_fingerprints_ip ON user_fingerprints (ip, last_seen_at) WHERE ip <> '';
+CREATE INDEX idx_user_fingerprints_device_id ON user_fingerprints (device_id, last_seen_at) WHERE device_id <> '';
+
+-- Create the table abuse_flags, what the detector found and the admin review of it
+CREATE TABLE abuse_flags(
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Utility columns
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+
+    user_id BIGINT NOT NULL,
+    signal ABUSE_SIGNAL NOT NULL,
+    explanation VARCHAR NOT NULL,
+    status ABUSE_FLAG_STATUS NOT NULL DEFAULT 'open',
+    reviewer_id BIGINT,
+    note VARCHAR NOT NULL DEFAULT '',
+    reviewed_at TIMESTAMPTZ,
+
+    CONSTRAINT fk_abuse_flags_user_id FOREIGN KEY (user_id) REFERENCES users(id),
+    CONSTRAINT fk_abuse_flags_reviewer_id FOREIGN KEY (reviewer_id) REFERENCES users(id)
+);
+
+-- a signal is raised once per user, a dismissed one isn't raised again
+CREATE UNIQUE INDEX unique_abuse_flags_signal ON abuse_flags (user_id, signal) WHERE deleted_at IS NULL;
+CREATE INDEX idx_abuse_flags_status ON abuse_flags (status, created_at, id) WHERE deleted_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,40 @@
+BEGIN;
+
+-- Create the table audit_logs, append only: who did what to whom and from where
+CREATE TABLE audit_logs(
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Utility columns
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- no actor when nobody is logged in yet, e.g. a failed login
+    actor_id BIGINT,
+    action VARCHAR NOT NULL,
+    target_type VARCHAR NOT NULL,
+    target_id BIGINT,
+    -- what changed, free form per action
+    details JSONB NOT NULL DEFAULT '{}',
+    request_id VARCHAR NOT NULL DEFAULT '',
+    ip VARCHAR NOT NULL DEFAULT '',
+    user_agent VARCHAR NOT NULL DEFAULT ''
+);
+
+-- entries are never changed or removed, not even by the application
+CREATE OR REPLACE FUNCTION audit_logs_immutable() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_logs_immutable BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_immutable();
+
+CREATE TRIGGER audit_logs_no_truncate BEFORE TRUNCATE ON audit_logs
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_immutable();
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs (actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_target ON audit_logs (target_type, target_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs (action, created_at);
+
+COMMIT;
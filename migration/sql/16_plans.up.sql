@@ -0,0 +1,40 @@
+BEGIN;
+
+-- Create the table plans, the subscription catalog. Tiers are added or retired by inserting rows or
+-- switching active off, subscriptions keep the code of the plan they were bought with
+CREATE TABLE plans(
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Utility columns
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+
+    code VARCHAR NOT NULL,
+    -- language code to display name, e.g. {"en": "Unlimited", "id": "Tanpa Batas"}
+    name JSONB NOT NULL DEFAULT '{}',
+    -- in the smallest unit of the currency
+    price BIGINT NOT NULL CHECK (price >= 0),
+    currency CHAR(3) NOT NULL,
+    duration_days INT NOT NULL CHECK (duration_days > 0),
+    -- feature codes, see entity.Feature*
+    features JSONB NOT NULL DEFAULT '[]',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    sort_order INT NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_plans_code ON plans (code);
+
+INSERT INTO plans (code, name, price, currency, duration_days, features, sort_order) VALUES
+    ('unlimited', '{"en": "Unlimited", "id": "Tanpa Batas"}', 99000, 'IDR', 30, '["unlimited_swipes", "see_likes", "rewind", "boosts"]', 10),
+    ('verified', '{"en": "Verified", "id": "Terverifikasi"}', 49000, 'IDR', 30, '["verified_badge", "see_likes"]', 20);
+
+-- subscriptions point at the catalog instead of the fixed enum
+ALTER TABLE subscriptions ALTER COLUMN plan TYPE VARCHAR USING plan::VARCHAR;
+
+DROP TYPE PLAN;
+
+ALTER TABLE ONLY subscriptions
+    ADD CONSTRAINT plan FOREIGN KEY (plan) REFERENCES plans(code) NOT VALID;
+
+COMMIT;
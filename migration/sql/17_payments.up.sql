@@ -0,0 +1,56 @@
+BEGIN;
+
+CREATE TYPE PAYMENT_STATUS AS ENUM ('pending', 'paid', 'failed');
+
+-- Create the table payments, one row per checkout. The subscription is only created once the provider
+-- confirms the payment through a signed webhook
+CREATE TABLE payments(
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Utility columns
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+
+    user_id BIGINT NOT NULL,
+    plan VARCHAR NOT NULL,
+    -- copied from the plan at checkout, in the smallest unit of the currency
+    amount BIGINT NOT NULL CHECK (amount >= 0),
+    currency CHAR(3) NOT NULL,
+    provider VARCHAR NOT NULL,
+    -- set once the provider accepted the checkout
+    provider_ref VARCHAR,
+    checkout_url VARCHAR NOT NULL DEFAULT '',
+    status PAYMENT_STATUS NOT NULL DEFAULT 'pending',
+    subscription_id BIGINT,
+    paid_at TIMESTAMPTZ,
+
+    CONSTRAINT fk_payments_user_id FOREIGN KEY (user_id) REFERENCES users(id),
+    CONSTRAINT fk_payments_plan FOREIGN KEY (plan) REFERENCES plans(code),
+    CONSTRAINT fk_payments_subscription_id FOREIGN KEY (subscription_id) REFERENCES subscriptions(id)
+);
+
+CREATE UNIQUE INDEX unique_payments_provider_ref ON payments (provider, provider_ref) WHERE provider_ref IS NOT NULL;
+CREATE INDEX idx_payments_user_id ON payments (user_id, created_at);
+
+-- Create the table payment_events, every webhook accepted from a provider. A redelivered event hits the
+-- unique constraint and is acknowledged without being applied again
+CREATE TABLE payment_events(
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Utility columns
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+
+    provider VARCHAR NOT NULL,
+    event_id VARCHAR NOT NULL,
+    type VARCHAR NOT NULL,
+    payment_id BIGINT,
+    payload JSONB NOT NULL,
+
+    CONSTRAINT unique_payment_events_event_id UNIQUE (provider, event_id),
+    CONSTRAINT fk_payment_events_payment_id FOREIGN KEY (payment_id) REFERENCES payments(id)
+);
+
+COMMIT;
@@ -0,0 +1,25 @@
+BEGIN;
+
+-- active renews at end_date, cancelled runs until end_date and then expires, grace keeps the features while
+-- a failed renewal is retried until grace_until
+CREATE TYPE SUBSCRIPTION_STATUS AS ENUM ('active', 'cancelled', 'grace', 'expired');
+
+-- a period ends at the time it was bought, not at midnight
+ALTER TABLE subscriptions ALTER COLUMN start_date TYPE TIMESTAMPTZ USING start_date::TIMESTAMPTZ;
+ALTER TABLE subscriptions ALTER COLUMN end_date TYPE TIMESTAMPTZ USING end_date::TIMESTAMPTZ;
+
+ALTER TABLE subscriptions
+    ADD COLUMN status SUBSCRIPTION_STATUS NOT NULL DEFAULT 'active',
+    ADD COLUMN cancelled_at TIMESTAMPTZ,
+    ADD COLUMN grace_until TIMESTAMPTZ,
+    ADD COLUMN renewal_attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN next_renewal_at TIMESTAMPTZ,
+    ADD COLUMN reminder_sent_at TIMESTAMPTZ;
+
+UPDATE subscriptions SET status = 'expired' WHERE end_date <= now();
+
+-- what the lifecycle job scans, expired rows are history only
+CREATE INDEX idx_subscriptions_lifecycle ON subscriptions (end_date, id) WHERE status <> 'expired' AND deleted_at IS NULL;
+CREATE INDEX idx_subscriptions_user_id ON subscriptions (user_id, end_date) WHERE deleted_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,54 @@
+BEGIN;
+
+-- a user holds one subscription at a time, the overlapping rows bought before plans could be switched are
+-- folded into the one that runs the longest
+UPDATE subscriptions s SET status = 'expired', updated_at = now()
+WHERE s.status <> 'expired' AND s.deleted_at IS NULL AND EXISTS (
+    SELECT 1 FROM subscriptions o WHERE o.user_id = s.user_id AND o.status <> 'expired' AND o.deleted_at IS NULL
+    AND (o.end_date, o.id) > (s.end_date, s.id)
+);
+
+CREATE UNIQUE INDEX unique_subscriptions_user_id_current ON subscriptions (user_id) WHERE status <> 'expired' AND deleted_at IS NULL;
+
+-- a downgrade waits for the end of the period, the renewal switches to it
+ALTER TABLE subscriptions
+    ADD COLUMN pending_plan VARCHAR,
+    ADD CONSTRAINT fk_subscriptions_pending_plan FOREIGN KEY (pending_plan) REFERENCES plans(code);
+
+-- what was left of the current period, already taken off the amount
+ALTER TABLE payments ADD COLUMN credit BIGINT NOT NULL DEFAULT 0 CHECK (credit >= 0);
+
+-- start is a new subscription, extend a second payment for the plan it already has
+CREATE TYPE SUBSCRIPTION_CHANGE_KIND AS ENUM ('start', 'upgrade', 'downgrade', 'extend');
+
+-- Create the table subscription_changes, the plans a subscription went through. Rows are only appended, the
+-- latest one of a subscription is the plan it's on
+CREATE TABLE subscription_changes(
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Utility columns
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+
+    subscription_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    from_plan VARCHAR,
+    to_plan VARCHAR NOT NULL,
+    kind SUBSCRIPTION_CHANGE_KIND NOT NULL,
+    -- in the smallest unit of the currency, amount is what was charged for the change
+    credit BIGINT NOT NULL DEFAULT 0,
+    amount BIGINT NOT NULL DEFAULT 0,
+    currency CHAR(3) NOT NULL,
+    payment_id BIGINT,
+    effective_at TIMESTAMPTZ NOT NULL,
+
+    CONSTRAINT fk_subscription_changes_subscription_id FOREIGN KEY (subscription_id) REFERENCES subscriptions(id),
+    CONSTRAINT fk_subscription_changes_user_id FOREIGN KEY (user_id) REFERENCES users(id),
+    CONSTRAINT fk_subscription_changes_to_plan FOREIGN KEY (to_plan) REFERENCES plans(code),
+    CONSTRAINT fk_subscription_changes_payment_id FOREIGN KEY (payment_id) REFERENCES payments(id)
+);
+
+CREATE INDEX idx_subscription_changes_subscription_id ON subscription_changes (subscription_id, effective_at);
+
+COMMIT;
@@ -0,0 +1,72 @@
+BEGIN;
+
+CREATE TYPE COUPON_KIND AS ENUM ('percent', 'fixed', 'extra_days');
+
+-- Create the table coupons, the campaign codes. Codes are added or retired by inserting rows or switching
+-- active off, a coupon only applies to the first period of a subscription
+CREATE TABLE coupons(
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Utility columns
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+
+    -- users type it in any case, it's matched upper-cased
+    code VARCHAR NOT NULL CHECK (code = upper(code)),
+    kind COUPON_KIND NOT NULL,
+    -- percent off, amount off in the smallest unit of currency, or days added to the period
+    value BIGINT NOT NULL CHECK (value > 0),
+    -- of a fixed discount, it only applies to plans sold in it
+    currency CHAR(3),
+    -- plan codes it applies to, empty applies to every plan
+    plans JSONB NOT NULL DEFAULT '[]',
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    -- null is unlimited
+    max_redemptions INT CHECK (max_redemptions > 0),
+    max_per_user INT NOT NULL DEFAULT 1 CHECK (max_per_user > 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+
+    CONSTRAINT check_coupons_percent CHECK (kind <> 'percent' OR value <= 100),
+    CONSTRAINT check_coupons_currency CHECK (kind <> 'fixed' OR currency IS NOT NULL)
+);
+
+CREATE UNIQUE INDEX unique_coupons_code ON coupons (code) WHERE deleted_at IS NULL;
+
+-- reserved while the payment is pending, released when it fails
+CREATE TYPE COUPON_REDEMPTION_STATUS AS ENUM ('reserved', 'redeemed', 'released');
+
+-- Create the table coupon_redemptions, one row per checkout made with a coupon. They are counted against the
+-- limits of the coupon while its row is locked, so concurrent checkouts can't go past them
+CREATE TABLE coupon_redemptions(
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Utility columns
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+
+    coupon_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    payment_id BIGINT NOT NULL,
+    status COUPON_REDEMPTION_STATUS NOT NULL DEFAULT 'reserved',
+    discount BIGINT NOT NULL DEFAULT 0,
+    extra_days INT NOT NULL DEFAULT 0,
+
+    CONSTRAINT unique_coupon_redemptions_payment_id UNIQUE (payment_id),
+    CONSTRAINT fk_coupon_redemptions_coupon_id FOREIGN KEY (coupon_id) REFERENCES coupons(id),
+    CONSTRAINT fk_coupon_redemptions_user_id FOREIGN KEY (user_id) REFERENCES users(id),
+    CONSTRAINT fk_coupon_redemptions_payment_id FOREIGN KEY (payment_id) REFERENCES payments(id)
+);
+
+CREATE INDEX idx_coupon_redemptions_coupon_id ON coupon_redemptions (coupon_id, user_id) WHERE status <> 'released';
+
+-- amount is what's left to pay after the discount
+ALTER TABLE payments
+    ADD COLUMN coupon_id BIGINT,
+    ADD COLUMN discount BIGINT NOT NULL DEFAULT 0 CHECK (discount >= 0),
+    ADD COLUMN extra_days INT NOT NULL DEFAULT 0,
+    ADD CONSTRAINT fk_payments_coupon_id FOREIGN KEY (coupon_id) REFERENCES coupons(id);
+
+COMMIT;
@@ -0,0 +1,46 @@
+BEGIN;
+
+-- plans of a family are the same features sold for another price or duration, a user gets one trial per family
+ALTER TABLE plans
+    ADD COLUMN family VARCHAR,
+    ADD COLUMN trial_days INT NOT NULL DEFAULT 0 CHECK (trial_days >= 0);
+
+UPDATE plans SET family = code;
+
+ALTER TABLE plans ALTER COLUMN family SET NOT NULL;
+
+-- the first period of a subscription started as a trial ends at trial_ends_at, one with a card on file is
+-- charged then like any renewal and the other expires
+ALTER TABLE subscriptions
+    ADD COLUMN trial_ends_at TIMESTAMPTZ,
+    ADD COLUMN trial_converts BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- a checkout of nothing that puts a card on file, the subscription starts with a trial of trial_days once it's paid
+ALTER TABLE payments ADD COLUMN trial_days INT NOT NULL DEFAULT 0 CHECK (trial_days >= 0);
+
+-- Create the table trials, the trials users took. A row is kept whatever happens to the subscription, it's
+-- what stops a second trial of the family
+CREATE TABLE trials(
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Utility columns
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+
+    user_id BIGINT NOT NULL,
+    family VARCHAR NOT NULL,
+    plan VARCHAR NOT NULL,
+    -- of the card on file, none when the trial started without payment
+    payment_id BIGINT,
+    started_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+
+    CONSTRAINT fk_trials_user_id FOREIGN KEY (user_id) REFERENCES users(id),
+    CONSTRAINT fk_trials_plan FOREIGN KEY (plan) REFERENCES plans(code),
+    CONSTRAINT fk_trials_payment_id FOREIGN KEY (payment_id) REFERENCES payments(id)
+);
+
+CREATE UNIQUE INDEX unique_trials_user_id_family ON trials (user_id, family);
+
+COMMIT;
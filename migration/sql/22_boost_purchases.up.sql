@@ -0,0 +1,19 @@
+BEGIN;
+
+-- a payment buys either a plan or a pack of boosts, the boosts are credited once it's paid
+ALTER TABLE payments
+    ALTER COLUMN plan DROP NOT NULL,
+    ADD COLUMN boosts INT NOT NULL DEFAULT 0 CHECK (boosts >= 0),
+    ADD CONSTRAINT check_payments_item CHECK ((plan IS NULL) <> (boosts = 0));
+
+-- the credit of a purchase points at its payment, a payment is never credited twice
+ALTER TABLE boost_credits
+    ADD COLUMN payment_id BIGINT,
+    ADD CONSTRAINT fk_boost_credits_payment_id FOREIGN KEY (payment_id) REFERENCES payments(id);
+
+CREATE UNIQUE INDEX unique_boost_credits_payment_id ON boost_credits (payment_id) WHERE payment_id IS NOT NULL;
+
+-- boosts over and not summarized yet, see the boost worker
+CREATE INDEX idx_boosts_ends_at ON boosts (ends_at) WHERE summarized_at IS NULL AND deleted_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+-- the measure a signal was raised with, a dismissed flag is raised again once the measure is worse than that
+ALTER TABLE abuse_flags ADD COLUMN value INT NOT NULL DEFAULT 0;
+
+COMMIT;
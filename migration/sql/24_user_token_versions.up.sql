@@ -0,0 +1,6 @@
+BEGIN;
+
+-- bumped on every password change, tokens signed with an older version are rejected
+ALTER TABLE users ADD COLUMN token_version INT NOT NULL DEFAULT 0;
+
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+
+-- what was paid for the current period, an upgrade credits the unused part of it. The periods started before
+-- are taken at the price of their plan, a trial was free
+ALTER TABLE subscriptions ADD COLUMN period_amount BIGINT NOT NULL DEFAULT 0 CHECK (period_amount >= 0);
+
+UPDATE subscriptions s SET period_amount = p.price, updated_at = now() FROM plans p
+WHERE p.code = s.plan AND (s.trial_ends_at IS NULL OR s.end_date > s.trial_ends_at);
+
+-- the period an upgrade was priced on, it doesn't apply once the subscription moved on from it
+ALTER TABLE payments
+    ADD COLUMN from_plan VARCHAR,
+    ADD COLUMN period_end TIMESTAMPTZ;
+
+-- a new upgrade fails the checkout still pending, only one can be paid
+CREATE UNIQUE INDEX unique_payments_pending_change ON payments (subscription_id)
+WHERE status = 'pending' AND from_plan IS NOT NULL AND deleted_at IS NULL;
+
+COMMIT;
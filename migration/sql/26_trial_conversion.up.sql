@@ -0,0 +1,6 @@
+BEGIN;
+
+-- a trial paid for before its end, the paid period starts right away instead of after the trial
+ALTER TYPE SUBSCRIPTION_CHANGE_KIND ADD VALUE 'convert';
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+-- a payment the provider captured for something that can't be given anymore, e.g. an upgrade of a period that's
+-- over, is refunded by the worker
+ALTER TYPE PAYMENT_STATUS ADD VALUE 'refund_due';
+ALTER TYPE PAYMENT_STATUS ADD VALUE 'refunded';
+
+ALTER TABLE payments ADD COLUMN refund_ref VARCHAR;
+
+COMMIT;
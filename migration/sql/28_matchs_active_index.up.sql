@@ -0,0 +1,7 @@
+BEGIN;
+
+-- 06_unmatch reused the name of the pair index of 03_discovery_exclusion, so databases migrated before it was
+-- renamed never got the index on the active matches of user_id_2
+CREATE INDEX IF NOT EXISTS idx_matchs_user_id_2_active ON matchs (user_id_2) WHERE deleted_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+-- extending a match was open to every subscription, it's a feature of the plans now
+UPDATE plans SET features = features || '["extend_match"]'::jsonb, updated_at = now()
+WHERE NOT features @> '["extend_match"]'::jsonb;
+
+COMMIT;
@@ -0,0 +1,280 @@
+package abuse
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"loverly/lib/atomic"
+	"loverly/lib/log"
+	"loverly/lib/redis"
+	"loverly/src/business/entity"
+
+	atomicSqlx "loverly/lib/atomic/sqlx"
+	sqlxUtils "loverly/lib/sqlx"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Interface keeps what the bot detector measures and the flags it raises, admins review them fresh so they aren't cached
+type Interface interface {
+	Track(ctx context.Context, param entity.Fingerprint) error
+	GetSignals(ctx context.Context, param entity.AbuseSignalParam) (entity.AbuseSignals, error)
+	IsShadowLimited(ctx context.Context, userId int64) (bool, error)
+
+	GetPage(ctx context.Context, param entity.AbuseFlagPageParam) ([]entity.AbuseFlag, error)
+	GetForUpdate(ctx context.Context, id int64) (entity.AbuseFlag, error)
+	Flag(ctx context.Context, param entity.AbuseFlag) (int64, error)
+	Review(ctx context.Context, param entity.AbuseFlag) error
+	SyncShadowLimit(ctx context.Context, userId int64) error
+}
+
+type abuse struct {
+	log               log.Interface
+	leaderDB          *sqlx.DB
+	followerDB        *sqlx.DB
+	rds               redis.Redis
+	masterStmts       []*sqlx.Stmt
+	slaveStmts        []*sqlx.Stmt
+	masterNamedStmpts []*sqlx.NamedStmt
+}
+
+const (
+	AllFields = `id, user_id, signal, explanation, value, status, reviewer_id, note, reviewed_at, created_at, updated_at, deleted_at`
+
+	// ShadowLimited is true for users whose likes don't count, format it with the user id column
+	ShadowLimited = `EXISTS (SELECT 1 FROM users sl WHERE sl.id = %s AND sl.shadow_limited)`
+
+	GetSignals = iota
+	IsShadowLimited
+	GetPage
+	GetForUpdate
+	SyncShadowLimit
+
+	Track
+	Flag
+	Review
+)
+
+var (
+	masterQueries = []string{
+		GetForUpdate: fmt.Sprintf("SELECT %s FROM abuse_flags WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", AllFields),
+		// dismissing every flag of the user lifts the limit
+		SyncShadowLimit: `UPDATE users SET shadow_limited = EXISTS (SELECT 1 FROM abuse_flags f WHERE f.user_id = users.id
+			AND f.status IN ('open', 'confirmed') AND f.deleted_at IS NULL), updated_at = now() WHERE id = $1`,
+	}
+
+	masterNamedQueries = []string{
+		Track: `INSERT INTO user_fingerprints (user_id, ip, device_id, last_seen_at, created_at, updated_at)
+		VALUES (:user_id, :ip, :device_id, now(), now(), now())
+		ON CONFLICT (user_id, ip, device_id) DO UPDATE SET last_seen_at = now(), updated_at = now()`,
+		// a dismissed flag is opened again on worse evidence than it was dismissed with, an open or confirmed one is left as is
+		Flag: `INSERT INTO abuse_flags (user_id, signal, explanation, value, created_at, updated_at)
+		VALUES (:user_id, :signal, :explanation, :value, now(), now())
+		ON CONFLICT (user_id, signal) WHERE deleted_at IS NULL DO UPDATE SET status = 'open', explanation = EXCLUDED.explanation,
+			value = EXCLUDED.value, reviewer_id = NULL, note = '', reviewed_at = NULL, updated_at = now()
+		WHERE abuse_flags.status = 'dismissed' AND EXCLUDED.value > abuse_flags.value
+		RETURNING id`,
+		Review: `UPDATE abuse_flags SET status = :status, reviewer_id = :reviewer_id, note = :note, reviewed_at = now(), updated_at = now()
+		WHERE id = :id AND deleted_at IS NULL`,
+	}
+
+	slaveQueries = []string{
+		GetSignals: `SELECT
+			(SELECT COUNT(*) FROM swipes WHERE swiper_id = $1 AND updated_at >= $2 AND deleted_at IS NULL) AS recent_swipes,
+			(SELECT COUNT(*) FROM swipes WHERE swiper_id = $1 AND updated_at >= $3 AND deleted_at IS NULL) AS swipes,
+			(SELECT COUNT(*) FROM swipes WHERE swiper_id = $1 AND updated_at >= $3 AND direction = 'right' AND deleted_at IS NULL) AS likes,
+			(SELECT COUNT(*) FROM profiles p JOIN profiles o ON o.bio = p.bio AND o.user_id <> p.user_id AND o.deleted_at IS NULL
+				WHERE p.user_id = $1 AND p.bio <> '' AND p.deleted_at IS NULL) AS shared_bio,
+			(SELECT COUNT(DISTINCT o.user_id) FROM user_fingerprints f JOIN user_fingerprints o ON o.ip = f.ip AND o.user_id <> f.user_id
+				WHERE f.user_id = $1 AND f.ip <> '' AND f.last_seen_at >= $4 AND o.last_seen_at >= $4) AS shared_ip,
+			(SELECT COUNT(DISTINCT o.user_id) FROM user_fingerprints f JOIN user_fingerprints o ON o.device_id = f.device_id AND o.user_id <> f.user_id
+				WHERE f.user_id = $1 AND f.device_id <> '' AND f.last_seen_at >= $4 AND o.last_seen_at >= $4) AS shared_device,
+			COALESCE((SELECT shadow_limited FROM users WHERE id = $1), false) AS shadow_limited`,
+		IsShadowLimited: `SELECT COALESCE((SELECT shadow_limited FROM users WHERE id = $1), false)`,
+		// a user id of 0 lists the flags of everyone
+		GetPage: fmt.Sprintf(`SELECT %s FROM abuse_flags WHERE status = $1 AND ($2 = 0 OR user_id = $2) AND deleted_at IS NULL
+		AND (created_at, id) > ($3, $4) ORDER BY created_at, id LIMIT $5`, AllFields),
+	}
+)
+
+func Init(ctx context.Context, log log.Interface, leader *sqlx.DB, follower *sqlx.DB, rds redis.Redis) Interface {
+	stmpts, err := sqlxUtils.PrepareQueries(leader, masterQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	namedStmpts, err := sqlxUtils.PrepareNamedQueries(leader, masterNamedQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareNamedQueries err: %v", err))
+		return nil
+	}
+
+	slaveStmpts, err := sqlxUtils.PrepareQueries(follower, slaveQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	return &abuse{
+		log:               log,
+		leaderDB:          leader,
+		followerDB:        follower,
+		rds:               rds,
+		masterStmts:       stmpts,
+		slaveStmts:        slaveStmpts,
+		masterNamedStmpts: namedStmpts,
+	}
+}
+
+// Track records the IP and device the user was seen from
+func (a *abuse) Track(ctx context.Context, param entity.Fingerprint) error {
+	namedStmt, err := a.getNamedStatement(ctx, Track)
+	if err != nil {
+		a.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return err
+	}
+
+	if _, err = namedStmt.ExecContext(ctx, param); err != nil {
+		a.log.Error(ctx, fmt.Sprintf("Track err: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+func (a *abuse) GetSignals(ctx context.Context, param entity.AbuseSignalParam) (entity.AbuseSignals, error) {
+	var result entity.AbuseSignals
+
+	if err := a.slaveStmts[GetSignals].GetContext(ctx, &result, param.UserId, param.RateSince, param.RatioSince, param.FingerprintSince); err != nil {
+		a.log.Error(ctx, fmt.Sprintf("GetSignals err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
+
+// IsShadowLimited is the cheap part of GetSignals, for the swipe path
+func (a *abuse) IsShadowLimited(ctx context.Context, userId int64) (bool, error) {
+	var result bool
+
+	if err := a.slaveStmts[IsShadowLimited].GetContext(ctx, &result, userId); err != nil {
+		a.log.Error(ctx, fmt.Sprintf("IsShadowLimited err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (a *abuse) GetPage(ctx context.Context, param entity.AbuseFlagPageParam) ([]entity.AbuseFlag, error) {
+	var results []entity.AbuseFlag
+
+	if err := a.slaveStmts[GetPage].SelectContext(ctx, &results, param.Status, param.UserId, param.AfterAt, param.AfterId, param.Limit); err != nil {
+		a.log.Error(ctx, fmt.Sprintf("GetPage err: %v", err))
+		return results, err
+	}
+
+	return results, nil
+}
+
+// GetForUpdate locks the flag until the surrounding atomic session ends
+func (a *abuse) GetForUpdate(ctx context.Context, id int64) (entity.AbuseFlag, error) {
+	var result entity.AbuseFlag
+
+	stmt, err := a.getStatement(ctx, GetForUpdate)
+	if err != nil {
+		a.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return result, err
+	}
+
+	if err = stmt.GetContext(ctx, &result, id); err != nil {
+		a.log.Error(ctx, fmt.Sprintf("GetForUpdate err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Flag returns 0 when the signal was already raised for the user and isn't raised again
+func (a *abuse) Flag(ctx context.Context, param entity.AbuseFlag) (int64, error) {
+	var result entity.AbuseFlag
+
+	namedStmt, err := a.getNamedStatement(ctx, Flag)
+	if err != nil {
+		a.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return 0, err
+	}
+
+	if err = namedStmt.GetContext(ctx, &result, param); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		a.log.Error(ctx, fmt.Sprintf("Flag err: %v", err))
+		return 0, err
+	}
+
+	return result.ID, nil
+}
+
+func (a *abuse) Review(ctx context.Context, param entity.AbuseFlag) error {
+	namedStmt, err := a.getNamedStatement(ctx, Review)
+	if err != nil {
+		a.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return err
+	}
+
+	if _, err = namedStmt.ExecContext(ctx, param); err != nil {
+		a.log.Error(ctx, fmt.Sprintf("Review err: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// SyncShadowLimit limits the user while any flag is open or confirmed, the user then shows up or disappears
+// from everyone's discovery and likes. cached reads are left to swipe.InvalidateUser once it's committed
+func (a *abuse) SyncShadowLimit(ctx context.Context, userId int64) error {
+	stmt, err := a.getStatement(ctx, SyncShadowLimit)
+	if err != nil {
+		a.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return err
+	}
+
+	if _, err = stmt.ExecContext(ctx, userId); err != nil {
+		a.log.Error(ctx, fmt.Sprintf("SyncShadowLimit err: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+func (a *abuse) getStatement(ctx context.Context, queryId int) (*sqlx.Stmt, error) {
+	var err error
+	var statement *sqlx.Stmt
+	if atomicSessionCtx, ok := ctx.(*atomic.AtomicSessionContext); ok {
+		if atomicSession, ok := atomicSessionCtx.AtomicSession.(*atomicSqlx.SqlxAtomicSession); ok {
+			statement, err = atomicSession.Tx().PreparexContext(ctx, masterQueries[queryId])
+		} else {
+			err = atomic.InvalidAtomicSessionProvider
+		}
+	} else {
+		statement = a.masterStmts[queryId]
+	}
+	return statement, err
+}
+
+func (a *abuse) getNamedStatement(ctx context.Context, queryId int) (*sqlx.NamedStmt, error) {
+	var err error
+	var namedStmt *sqlx.NamedStmt
+	if atomicSessionCtx, ok := ctx.(*atomic.AtomicSessionContext); ok {
+		if atomicSession, ok := atomicSessionCtx.AtomicSession.(*atomicSqlx.SqlxAtomicSession); ok {
+			namedStmt, err = atomicSession.Tx().PrepareNamedContext(ctx, masterNamedQueries[queryId])
+		} else {
+			err = atomic.InvalidAtomicSessionProvider
+		}
+	} else {
+		namedStmt = a.masterNamedStmpts[queryId]
+	}
+	return namedStmt, err
+}
@@ -0,0 +1,147 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
+	"loverly/lib/log"
+	"loverly/lib/redis"
+	"loverly/src/business/entity"
+
+	atomicSqlx "loverly/lib/atomic/sqlx"
+	sqlxUtils "loverly/lib/sqlx"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Interface is the append-only audit log, entries are never updated nor deleted
+type Interface interface {
+	Record(ctx context.Context, param entity.AuditLog, details interface{}) (int64, error)
+	GetPage(ctx context.Context, param entity.AuditLogPageParam) ([]entity.AuditLog, error)
+}
+
+type audit struct {
+	log               log.Interface
+	leaderDB          *sqlx.DB
+	followerDB        *sqlx.DB
+	rds               redis.Redis
+	masterStmts       []*sqlx.Stmt
+	slaveStmts        []*sqlx.Stmt
+	masterNamedStmpts []*sqlx.NamedStmt
+}
+
+const (
+	AllFields = `id, actor_id, action, target_type, target_id, details, request_id, ip, user_agent, created_at`
+
+	GetPage = iota
+
+	Record
+)
+
+var (
+	masterQueries = []string{}
+
+	masterNamedQueries = []string{
+		Record: `INSERT INTO audit_logs (actor_id, action, target_type, target_id, details, request_id, ip, user_agent, created_at)
+		VALUES (:actor_id, :action, :target_type, :target_id, :details, :request_id, :ip, :user_agent, now()) RETURNING id`,
+	}
+
+	slaveQueries = []string{
+		// zero filters match every entry, a zero before id is the first page
+		GetPage: fmt.Sprintf(`SELECT %s FROM audit_logs WHERE ($1 = 0 OR actor_id = $1) AND ($2 = '' OR action = $2)
+		AND ($3 = '' OR target_type = $3) AND ($4 = 0 OR target_id = $4)
+		AND ($5::timestamptz IS NULL OR created_at >= $5) AND ($6::timestamptz IS NULL OR created_at < $6)
+		AND ($8 = 0 OR (created_at, id) < ($7, $8)) ORDER BY created_at DESC, id DESC LIMIT $9`, AllFields),
+	}
+)
+
+func Init(ctx context.Context, log log.Interface, leader *sqlx.DB, follower *sqlx.DB, rds redis.Redis) Interface {
+	stmpts, err := sqlxUtils.PrepareQueries(leader, masterQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	namedStmpts, err := sqlxUtils.PrepareNamedQueries(leader, masterNamedQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareNamedQueries err: %v", err))
+		return nil
+	}
+
+	slaveStmpts, err := sqlxUtils.PrepareQueries(follower, slaveQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	return &audit{
+		log:               log,
+		leaderDB:          leader,
+		followerDB:        follower,
+		rds:               rds,
+		masterStmts:       stmpts,
+		slaveStmts:        slaveStmpts,
+		masterNamedStmpts: namedStmpts,
+	}
+}
+
+// Record appends the entry stamped with the request it came from, inside the surrounding atomic session if any
+func (a *audit) Record(ctx context.Context, param entity.AuditLog, details interface{}) (int64, error) {
+	var result entity.AuditLog
+
+	param.Details = json.RawMessage(`{}`)
+	if details != nil {
+		raw, err := json.Marshal(details)
+		if err != nil {
+			a.log.Error(ctx, fmt.Sprintf("error when marshal audit details: %v", err))
+			return 0, err
+		}
+		param.Details = raw
+	}
+
+	param.RequestId = appcontext.GetRequestId(ctx)
+	param.IP = appcontext.GetRequestIP(ctx)
+	param.UserAgent = appcontext.GetUserAgent(ctx)
+
+	namedStmt, err := a.getNamedStatement(ctx, Record)
+	if err != nil {
+		a.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return 0, err
+	}
+
+	if err = namedStmt.GetContext(ctx, &result, param); err != nil {
+		a.log.Error(ctx, fmt.Sprintf("RecordAudit err: %v", err))
+		return 0, err
+	}
+
+	return result.ID, nil
+}
+
+func (a *audit) GetPage(ctx context.Context, param entity.AuditLogPageParam) ([]entity.AuditLog, error) {
+	var results []entity.AuditLog
+
+	if err := a.slaveStmts[GetPage].SelectContext(ctx, &results, param.ActorId, param.Action, param.TargetType, param.TargetId,
+		param.From, param.To, param.BeforeAt, param.BeforeId, param.Limit); err != nil {
+		a.log.Error(ctx, fmt.Sprintf("GetPage err: %v", err))
+		return results, err
+	}
+
+	return results, nil
+}
+
+func (a *audit) getNamedStatement(ctx context.Context, queryId int) (*sqlx.NamedStmt, error) {
+	var err error
+	var namedStmt *sqlx.NamedStmt
+	if atomicSessionCtx, ok := ctx.(*atomic.AtomicSessionContext); ok {
+		if atomicSession, ok := atomicSessionCtx.AtomicSession.(*atomicSqlx.SqlxAtomicSession); ok {
+			namedStmt, err = atomicSession.Tx().PrepareNamedContext(ctx, masterNamedQueries[queryId])
+		} else {
+			err = atomic.InvalidAtomicSessionProvider
+		}
+	} else {
+		namedStmt = a.masterNamedStmpts[queryId]
+	}
+	return namedStmt, err
+}
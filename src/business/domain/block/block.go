@@ -0,0 +1,216 @@
+package block
+
+import (
+	"context"
+	"fmt"
+	"loverly/lib/atomic"
+	"loverly/lib/log"
+	"loverly/lib/redis"
+	"loverly/src/business/entity"
+
+	atomicSqlx "loverly/lib/atomic/sqlx"
+	sqlxUtils "loverly/lib/sqlx"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Interface interface {
+	GetByBlockerId(ctx context.Context, blockerId int64) ([]entity.BlockedUser, error)
+	Create(ctx context.Context, param entity.Block) (int64, error)
+	Delete(ctx context.Context, param entity.Block) error
+	// Invalidate drops the cached reads of both users the block filter applies to, call it once the block or
+	// unblock is committed
+	Invalidate(ctx context.Context, param entity.Block)
+}
+
+type block struct {
+	log               log.Interface
+	leaderDB          *sqlx.DB
+	followerDB        *sqlx.DB
+	rds               redis.Redis
+	masterStmts       []*sqlx.Stmt
+	slaveStmts        []*sqlx.Stmt
+	masterNamedStmpts []*sqlx.NamedStmt
+}
+
+const (
+	AllFields = `id, blocker_id, blocked_id, created_at, updated_at, deleted_at`
+
+	// NotBlocked is the filter every read path between two users applies, format it with both user id
+	// columns or params, the order doesn't matter since a block hides both sides
+	NotBlocked = `NOT EXISTS (SELECT 1 FROM blocks b WHERE b.deleted_at IS NULL
+		AND ((b.blocker_id = %[1]s AND b.blocked_id = %[2]s) OR (b.blocker_id = %[2]s AND b.blocked_id = %[1]s)))`
+
+	GetByBlockerId = iota
+
+	Create
+	Delete
+
+	GetByBlockerIdKey = "blocks:getbyblockerid:%d"
+
+	// reads of other domains filtered by blocks, see NotBlocked
+	ProfileGetBySwipeKey   = "profiles:getbyswipe:%d:*"
+	ProfileGetByUserIdsKey = "profiles:getbyuserids:%d:*"
+	MatchGetByUserIdKey    = "matchs:getbyuserid:%d"
+	MatchGetPageKey        = "matchs:getpage:%d:*"
+	SwipeGetBySwipeIdKey   = "swipes:getbyswipeid:%d:%d"
+	SwipeReceivedLikesKey  = "swipes:getreceivedlikes:%d"
+)
+
+var (
+	masterQueries = []string{}
+
+	masterNamedQueries = []string{
+		// blocking again after an unblock starts a new block
+		Create: `INSERT INTO blocks (blocker_id, blocked_id, created_at, updated_at)
+		VALUES (:blocker_id, :blocked_id, now(), now())
+		ON CONFLICT ON CONSTRAINT unique_blocks_id DO UPDATE SET deleted_at = NULL, updated_at = now(),
+		created_at = CASE WHEN blocks.deleted_at IS NULL THEN blocks.created_at ELSE now() END
+		RETURNING id`,
+		Delete: `UPDATE blocks SET deleted_at = now(), updated_at = now()
+		WHERE blocker_id = :blocker_id AND blocked_id = :blocked_id AND deleted_at IS NULL`,
+	}
+
+	slaveQueries = []string{
+		GetByBlockerId: `SELECT b.blocked_id AS user_id, p.name, p.profile_picture, b.created_at AS blocked_at FROM blocks b
+		JOIN profiles p ON p.user_id = b.blocked_id AND p.deleted_at IS NULL
+		WHERE b.blocker_id = $1 AND b.deleted_at IS NULL ORDER BY b.created_at DESC, b.id DESC`,
+	}
+)
+
+func Init(ctx context.Context, log log.Interface, leader *sqlx.DB, follower *sqlx.DB, rds redis.Redis) Interface {
+	stmpts, err := sqlxUtils.PrepareQueries(leader, masterQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	namedStmpts, err := sqlxUtils.PrepareNamedQueries(leader, masterNamedQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf(")PrepareNamedQueries err: %v", err))
+		return nil
+	}
+
+	slaveStmpts, err := sqlxUtils.PrepareQueries(follower, slaveQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	return &block{
+		log:               log,
+		leaderDB:          leader,
+		followerDB:        follower,
+		rds:               rds,
+		masterStmts:       stmpts,
+		slaveStmts:        slaveStmpts,
+		masterNamedStmpts: namedStmpts,
+	}
+}
+
+func (b *block) GetByBlockerId(ctx context.Context, blockerId int64) ([]entity.BlockedUser, error) {
+	var blocks []entity.BlockedUser
+
+	err := b.rds.WithCache(ctx, fmt.Sprintf(GetByBlockerIdKey, blockerId), &blocks, func() (interface{}, error) {
+		if err := b.slaveStmts[GetByBlockerId].SelectContext(ctx, &blocks, blockerId); err != nil {
+			return blocks, err
+		}
+
+		return blocks, nil
+	})
+	if err != nil {
+		b.log.Error(ctx, fmt.Sprintf("GetByBlockerId err: %v", err))
+		return blocks, err
+	}
+
+	return blocks, nil
+}
+
+func (b *block) Create(ctx context.Context, param entity.Block) (int64, error) {
+	var result entity.Block
+
+	namedStmt, err := b.getNamedStatement(ctx, Create)
+	if err != nil {
+		b.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return 0, err
+	}
+
+	if err = namedStmt.GetContext(ctx, &result, param); err != nil {
+		b.log.Error(ctx, fmt.Sprintf("CreateBlocks err: %v", err))
+		return 0, err
+	}
+
+	return result.ID, nil
+}
+
+func (b *block) Delete(ctx context.Context, param entity.Block) error {
+	namedStmt, err := b.getNamedStatement(ctx, Delete)
+	if err != nil {
+		b.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return err
+	}
+
+	if _, err = namedStmt.ExecContext(ctx, param); err != nil {
+		b.log.Error(ctx, fmt.Sprintf("DeleteBlocks err: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// Invalidate isn't part of Create or Delete, a read racing the transaction would cache the pair as it was again
+func (b *block) Invalidate(ctx context.Context, param entity.Block) {
+	blockerId, blockedId := param.BlockerId, param.BlockedId
+	keys := []string{
+		fmt.Sprintf(GetByBlockerIdKey, blockerId),
+		fmt.Sprintf(SwipeGetBySwipeIdKey, blockerId, blockedId),
+		fmt.Sprintf(SwipeGetBySwipeIdKey, blockedId, blockerId),
+	}
+	var patterns []string
+	for _, userId := range []int64{blockerId, blockedId} {
+		keys = append(keys, fmt.Sprintf(MatchGetByUserIdKey, userId), fmt.Sprintf(SwipeReceivedLikesKey, userId))
+		patterns = append(patterns, fmt.Sprintf(ProfileGetBySwipeKey, userId), fmt.Sprintf(ProfileGetByUserIdsKey, userId), fmt.Sprintf(MatchGetPageKey, userId))
+	}
+
+	for _, key := range keys {
+		if redisErr := b.rds.Del(ctx, key); redisErr != nil {
+			b.log.Error(ctx, fmt.Sprintf("error when redis delete key: %s, %s", key, redisErr))
+		}
+	}
+
+	for _, pattern := range patterns {
+		if redisErr := b.rds.DelWithPattern(ctx, pattern); redisErr != nil {
+			b.log.Error(ctx, fmt.Sprintf("error when redis delete with pattern: %s, %s", pattern, redisErr))
+		}
+	}
+}
+
+func (b *block) getStatement(ctx context.Context, queryId int) (*sqlx.Stmt, error) {
+	var err error
+	var statement *sqlx.Stmt
+	if atomicSessionCtx, ok := ctx.(*atomic.AtomicSessionContext); ok {
+		if atomicSession, ok := atomicSessionCtx.AtomicSession.(*atomicSqlx.SqlxAtomicSession); ok {
+			statement, err = atomicSession.Tx().PreparexContext(ctx, masterQueries[queryId])
+		} else {
+			err = atomic.InvalidAtomicSessionProvider
+		}
+	} else {
+		statement = b.masterStmts[queryId]
+	}
+	return statement, err
+}
+
+func (b *block) getNamedStatement(ctx context.Context, queryId int) (*sqlx.NamedStmt, error) {
+	var err error
+	var namedStmt *sqlx.NamedStmt
+	if atomicSessionCtx, ok := ctx.(*atomic.AtomicSessionContext); ok {
+		if atomicSession, ok := atomicSessionCtx.AtomicSession.(*atomicSqlx.SqlxAtomicSession); ok {
+			namedStmt, err = atomicSession.Tx().PrepareNamedContext(ctx, masterNamedQueries[queryId])
+		} else {
+			err = atomic.InvalidAtomicSessionProvider
+		}
+	} else {
+		namedStmt = b.masterNamedStmpts[queryId]
+	}
+	return namedStmt, err
+}
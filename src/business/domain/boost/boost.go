@@ -0,0 +1,384 @@
+package boost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"loverly/lib/atomic"
+	"loverly/lib/log"
+	"loverly/lib/redis"
+	"loverly/src/business/entity"
+	"strconv"
+	"strings"
+	"time"
+
+	atomicSqlx "loverly/lib/atomic/sqlx"
+	sqlxUtils "loverly/lib/sqlx"
+
+	"github.com/jmoiron/sqlx"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+type Interface interface {
+	GetLatestByUserId(ctx context.Context, userId int64) (entity.Boost, error)
+	// LockUser makes activations of the user wait on each other until the atomic session ends, the reads below
+	// go to the leader so they see what the previous one wrote
+	LockUser(ctx context.Context, userId int64) error
+	GetLatestByUserIdForUpdate(ctx context.Context, userId int64) (entity.Boost, error)
+	CountBySource(ctx context.Context, userId int64, source string, since time.Time) (int64, error)
+	GetCreditBalance(ctx context.Context, userId int64) (int64, error)
+	// GetEnded locks boosts over and not summarized yet, skipping the ones another worker holds
+	GetEnded(ctx context.Context, limit int) ([]entity.Boost, error)
+	Create(ctx context.Context, param entity.Boost) (int64, error)
+	CreateCredit(ctx context.Context, param entity.BoostCredit) (int64, error)
+	UpdateStats(ctx context.Context, param entity.Boost) error
+
+	// active boosts are tracked in redis while they run
+	Activate(ctx context.Context, param entity.Boost) error
+	GetActiveUserIds(ctx context.Context, location string) ([]int64, error)
+	IsActive(ctx context.Context, userId int64) (bool, error)
+	Track(ctx context.Context, userId int64, stat string) error
+	GetStats(ctx context.Context, userId int64) (int64, int64, error)
+}
+
+type boost struct {
+	log               log.Interface
+	leaderDB          *sqlx.DB
+	followerDB        *sqlx.DB
+	rds               redis.Redis
+	masterStmts       []*sqlx.Stmt
+	slaveStmts        []*sqlx.Stmt
+	masterNamedStmpts []*sqlx.NamedStmt
+}
+
+const (
+	AllFields = `id, user_id, source, location, started_at, ends_at, views, likes, summarized_at, created_at, updated_at, deleted_at`
+
+	GetLatestByUserId = iota
+	LockUser
+	GetLatestByUserIdForUpdate
+	CountBySource
+	GetCreditBalance
+	GetEnded
+
+	Create
+	CreateCredit
+	UpdateStats
+
+	ActiveKey  = "boosts:active:%s"
+	CurrentKey = "boosts:current:%d"
+	StatKey    = "boosts:%s:%d"
+
+	// stats outlive the boost so the summary can be built after it ended
+	statRetention = 7 * 24 * time.Hour
+)
+
+var (
+	masterQueries = []string{
+		LockUser: "SELECT id FROM users WHERE id = $1 FOR UPDATE",
+		GetLatestByUserIdForUpdate: fmt.Sprintf(`SELECT %s FROM boosts WHERE user_id = $1 AND deleted_at IS NULL
+		ORDER BY started_at DESC LIMIT 1 FOR UPDATE`, AllFields),
+		CountBySource:    "SELECT COUNT(id) FROM boosts WHERE user_id = $1 AND source = $2 AND started_at >= $3 AND deleted_at IS NULL",
+		GetCreditBalance: "SELECT COALESCE(SUM(amount), 0) FROM boost_credits WHERE user_id = $1 AND deleted_at IS NULL",
+		GetEnded: fmt.Sprintf(`SELECT %s FROM boosts WHERE ends_at <= now() AND summarized_at IS NULL AND deleted_at IS NULL
+		ORDER BY ends_at, id LIMIT $1 FOR UPDATE SKIP LOCKED`, AllFields),
+	}
+
+	masterNamedQueries = []string{
+		Create: `INSERT INTO boosts (user_id, source, location, started_at, ends_at, created_at, updated_at) 
+		VALUES (:user_id, :source, :location, :started_at, :ends_at, now(), now()) RETURNING id`,
+		CreateCredit: `INSERT INTO boost_credits (user_id, amount, reason, payment_id, created_at, updated_at) 
+		VALUES (:user_id, :amount, :reason, :payment_id, now(), now()) RETURNING id`,
+		UpdateStats: `UPDATE boosts SET views = :views, likes = :likes, summarized_at = :summarized_at, updated_at = now() WHERE id = :id`,
+	}
+
+	slaveQueries = []string{
+		GetLatestByUserId: fmt.Sprintf("SELECT %s FROM boosts WHERE user_id = $1 AND deleted_at IS NULL ORDER BY started_at DESC LIMIT 1", AllFields),
+	}
+)
+
+func Init(ctx context.Context, log log.Interface, leader *sqlx.DB, follower *sqlx.DB, rds redis.Redis) Interface {
+	stmpts, err := sqlxUtils.PrepareQueries(leader, masterQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	namedStmpts, err := sqlxUtils.PrepareNamedQueries(leader, masterNamedQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf(")PrepareNamedQueries err: %v", err))
+		return nil
+	}
+
+	slaveStmpts, err := sqlxUtils.PrepareQueries(follower, slaveQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	return &boost{
+		log:               log,
+		leaderDB:          leader,
+		followerDB:        follower,
+		rds:               rds,
+		masterStmts:       stmpts,
+		slaveStmts:        slaveStmpts,
+		masterNamedStmpts: namedStmpts,
+	}
+}
+
+func (b *boost) GetLatestByUserId(ctx context.Context, userId int64) (entity.Boost, error) {
+	var result entity.Boost
+
+	if err := b.slaveStmts[GetLatestByUserId].GetContext(ctx, &result, userId); err != nil {
+		b.log.Error(ctx, fmt.Sprintf("GetLatestByUserId err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (b *boost) LockUser(ctx context.Context, userId int64) error {
+	var id int64
+
+	stmt, err := b.getStatement(ctx, LockUser)
+	if err != nil {
+		b.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return err
+	}
+
+	if err = stmt.GetContext(ctx, &id, userId); err != nil {
+		b.log.Error(ctx, fmt.Sprintf("LockUser err: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+func (b *boost) GetLatestByUserIdForUpdate(ctx context.Context, userId int64) (entity.Boost, error) {
+	var result entity.Boost
+
+	stmt, err := b.getStatement(ctx, GetLatestByUserIdForUpdate)
+	if err != nil {
+		b.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return result, err
+	}
+
+	if err = stmt.GetContext(ctx, &result, userId); err != nil {
+		b.log.Error(ctx, fmt.Sprintf("GetLatestByUserIdForUpdate err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (b *boost) CountBySource(ctx context.Context, userId int64, source string, since time.Time) (int64, error) {
+	var count int64
+
+	stmt, err := b.getStatement(ctx, CountBySource)
+	if err != nil {
+		b.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return count, err
+	}
+
+	if err = stmt.GetContext(ctx, &count, userId, source, since); err != nil {
+		b.log.Error(ctx, fmt.Sprintf("CountBySource err: %v", err))
+		return count, err
+	}
+
+	return count, nil
+}
+
+func (b *boost) GetCreditBalance(ctx context.Context, userId int64) (int64, error) {
+	var balance int64
+
+	stmt, err := b.getStatement(ctx, GetCreditBalance)
+	if err != nil {
+		b.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return balance, err
+	}
+
+	if err = stmt.GetContext(ctx, &balance, userId); err != nil {
+		b.log.Error(ctx, fmt.Sprintf("GetCreditBalance err: %v", err))
+		return balance, err
+	}
+
+	return balance, nil
+}
+
+func (b *boost) GetEnded(ctx context.Context, limit int) ([]entity.Boost, error) {
+	var results []entity.Boost
+
+	stmt, err := b.getStatement(ctx, GetEnded)
+	if err != nil {
+		b.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return results, err
+	}
+
+	if err = stmt.SelectContext(ctx, &results, limit); err != nil {
+		b.log.Error(ctx, fmt.Sprintf("GetEnded err: %v", err))
+		return results, err
+	}
+
+	return results, nil
+}
+
+func (b *boost) Create(ctx context.Context, param entity.Boost) (int64, error) {
+	var result entity.Boost
+
+	namedStmt, err := b.getNamedStatement(ctx, Create)
+	if err != nil {
+		b.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return 0, err
+	}
+
+	if err = namedStmt.GetContext(ctx, &result, param); err != nil {
+		b.log.Error(ctx, fmt.Sprintf("CreateBoost err: %v", err))
+		return 0, err
+	}
+
+	return result.ID, nil
+}
+
+func (b *boost) CreateCredit(ctx context.Context, param entity.BoostCredit) (int64, error) {
+	var result entity.BoostCredit
+
+	namedStmt, err := b.getNamedStatement(ctx, CreateCredit)
+	if err != nil {
+		b.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return 0, err
+	}
+
+	if err = namedStmt.GetContext(ctx, &result, param); err != nil {
+		b.log.Error(ctx, fmt.Sprintf("CreateBoostCredit err: %v", err))
+		return 0, err
+	}
+
+	return result.ID, nil
+}
+
+func (b *boost) UpdateStats(ctx context.Context, param entity.Boost) error {
+	namedStmt, err := b.getNamedStatement(ctx, UpdateStats)
+	if err != nil {
+		b.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return err
+	}
+
+	if _, err = namedStmt.ExecContext(ctx, param); err != nil {
+		b.log.Error(ctx, fmt.Sprintf("UpdateBoostStats err: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+func (b *boost) Activate(ctx context.Context, param entity.Boost) error {
+	ttl := time.Until(param.EndsAt)
+
+	if err := b.rds.Set(ctx, fmt.Sprintf(CurrentKey, param.UserId), strconv.FormatInt(param.ID, 10), ttl); err != nil {
+		return err
+	}
+
+	for _, stat := range []string{entity.BoostStatViews, entity.BoostStatLikes} {
+		if err := b.rds.Set(ctx, fmt.Sprintf(StatKey, stat, param.UserId), "0", ttl+statRetention); err != nil {
+			return err
+		}
+	}
+
+	return b.rds.ZAdd(ctx, fmt.Sprintf(ActiveKey, areaOf(param.Location)), float64(param.EndsAt.Unix()), strconv.FormatInt(param.UserId, 10))
+}
+
+func (b *boost) GetActiveUserIds(ctx context.Context, location string) ([]int64, error) {
+	var userIds []int64
+
+	key := fmt.Sprintf(ActiveKey, areaOf(location))
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+
+	// drop boosts that already ran out before reading the rest
+	if err := b.rds.ZRemRangeByScore(ctx, key, "-inf", now); err != nil {
+		return userIds, err
+	}
+
+	members, err := b.rds.ZRangeByScore(ctx, key, now, "+inf")
+	if err != nil {
+		return userIds, err
+	}
+
+	for _, m := range members {
+		id, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			b.log.Error(ctx, fmt.Sprintf("invalid boosted user id %s: %v", m, err))
+			continue
+		}
+		userIds = append(userIds, id)
+	}
+
+	return userIds, nil
+}
+
+func (b *boost) IsActive(ctx context.Context, userId int64) (bool, error) {
+	return b.rds.Exists(ctx, fmt.Sprintf(CurrentKey, userId))
+}
+
+func (b *boost) Track(ctx context.Context, userId int64, stat string) error {
+	active, err := b.IsActive(ctx, userId)
+	if err != nil || !active {
+		return err
+	}
+
+	_, err = b.rds.Incr(ctx, fmt.Sprintf(StatKey, stat, userId))
+	return err
+}
+
+func (b *boost) GetStats(ctx context.Context, userId int64) (int64, int64, error) {
+	var stats [2]int64
+
+	for i, stat := range []string{entity.BoostStatViews, entity.BoostStatLikes} {
+		val, err := b.rds.Get(ctx, fmt.Sprintf(StatKey, stat, userId))
+		if err != nil {
+			if errors.Is(err, goredis.Nil) {
+				continue
+			}
+			return 0, 0, err
+		}
+
+		stats[i], _ = strconv.ParseInt(val, 10, 64)
+	}
+
+	return stats[0], stats[1], nil
+}
+
+func (b *boost) getStatement(ctx context.Context, queryId int) (*sqlx.Stmt, error) {
+	var err error
+	var statement *sqlx.Stmt
+	if atomicSessionCtx, ok := ctx.(*atomic.AtomicSessionContext); ok {
+		if atomicSession, ok := atomicSessionCtx.AtomicSession.(*atomicSqlx.SqlxAtomicSession); ok {
+			statement, err = atomicSession.Tx().PreparexContext(ctx, masterQueries[queryId])
+		} else {
+			err = atomic.InvalidAtomicSessionProvider
+		}
+	} else {
+		statement = b.masterStmts[queryId]
+	}
+	return statement, err
+}
+
+func (b *boost) getNamedStatement(ctx context.Context, queryId int) (*sqlx.NamedStmt, error) {
+	var err error
+	var namedStmt *sqlx.NamedStmt
+	if atomicSessionCtx, ok := ctx.(*atomic.AtomicSessionContext); ok {
+		if atomicSession, ok := atomicSessionCtx.AtomicSession.(*atomicSqlx.SqlxAtomicSession); ok {
+			namedStmt, err = atomicSession.Tx().PrepareNamedContext(ctx, masterNamedQueries[queryId])
+		} else {
+			err = atomic.InvalidAtomicSessionProvider
+		}
+	} else {
+		namedStmt = b.masterNamedStmpts[queryId]
+	}
+	return namedStmt, err
+}
+
+// areaOf normalizes a profile location into the key boosts are grouped by
+func areaOf(location string) string {
+	return strings.ToLower(strings.TrimSpace(location))
+}
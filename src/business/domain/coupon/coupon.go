@@ -0,0 +1,233 @@
+package coupon
+
+import (
+	"context"
+	"fmt"
+	"loverly/lib/atomic"
+	"loverly/lib/log"
+	"loverly/lib/redis"
+	"loverly/src/business/entity"
+	"time"
+
+	atomicSqlx "loverly/lib/atomic/sqlx"
+	sqlxUtils "loverly/lib/sqlx"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Interface keeps the campaign codes and their redemptions, nothing is cached since the limits are counted
+// on every checkout
+type Interface interface {
+	// GetByCode returns sql.ErrNoRows for unknown and retired codes, whatever their case
+	GetByCode(ctx context.Context, code string) (entity.Coupon, error)
+	// GetByCodeForUpdate locks the coupon until the atomic session ends, checkouts with the same code are
+	// counted one after the other
+	GetByCodeForUpdate(ctx context.Context, code string) (entity.Coupon, error)
+	// GetByIdForUpdate locks the coupon a checkout was made with, retired since or not
+	GetByIdForUpdate(ctx context.Context, id int64) (entity.Coupon, error)
+	// GetUsage counts the redemptions held against the limits of the coupon, a reservation stops counting
+	// once it's older than hold, 0 holds it until the payment is settled
+	GetUsage(ctx context.Context, couponId int64, userId int64, hold time.Duration) (entity.CouponUsage, error)
+	CreateRedemption(ctx context.Context, param entity.CouponRedemption) (int64, error)
+	// SetRedemptionStatus moves the redemption of the payment along with it
+	SetRedemptionStatus(ctx context.Context, paymentId int64, status string) error
+}
+
+type coupon struct {
+	log               log.Interface
+	leaderDB          *sqlx.DB
+	followerDB        *sqlx.DB
+	rds               redis.Redis
+	masterStmts       []*sqlx.Stmt
+	slaveStmts        []*sqlx.Stmt
+	masterNamedStmpts []*sqlx.NamedStmt
+}
+
+const (
+	AllFields = `id, code, kind, value, currency, plans, starts_at, ends_at, max_redemptions, max_per_user, active, created_at,
+	updated_at, deleted_at`
+
+	GetByCode = iota
+	GetByCodeForUpdate
+	GetByIdForUpdate
+	GetUsage
+
+	CreateRedemption
+	SetRedemptionStatus
+)
+
+var (
+	masterQueries = []string{
+		GetByCodeForUpdate: fmt.Sprintf(`SELECT %s FROM coupons WHERE code = upper($1) AND active AND deleted_at IS NULL
+		FOR UPDATE`, AllFields),
+		GetByIdForUpdate: fmt.Sprintf(`SELECT %s FROM coupons WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, AllFields),
+		GetUsage: `SELECT COUNT(*) AS total, COUNT(*) FILTER (WHERE user_id = $2) AS by_user FROM coupon_redemptions
+		WHERE coupon_id = $1 AND deleted_at IS NULL AND (status = 'redeemed'
+		OR (status = 'reserved' AND ($3::float8 = 0 OR created_at > now() - make_interval(secs => $3::float8))))`,
+	}
+
+	masterNamedQueries = []string{
+		CreateRedemption: `INSERT INTO coupon_redemptions (coupon_id, user_id, payment_id, status, discount, extra_days, created_at, updated_at)
+		VALUES (:coupon_id, :user_id, :payment_id, :status, :discount, :extra_days, now(), now()) RETURNING id`,
+		SetRedemptionStatus: `UPDATE coupon_redemptions SET status = :status, updated_at = now()
+		WHERE payment_id = :payment_id AND deleted_at IS NULL`,
+	}
+
+	slaveQueries = []string{
+		GetByCode: fmt.Sprintf("SELECT %s FROM coupons WHERE code = upper($1) AND active AND deleted_at IS NULL", AllFields),
+	}
+)
+
+func Init(ctx context.Context, log log.Interface, leader *sqlx.DB, follower *sqlx.DB, rds redis.Redis) Interface {
+	stmpts, err := sqlxUtils.PrepareQueries(leader, masterQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	namedStmpts, err := sqlxUtils.PrepareNamedQueries(leader, masterNamedQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareNamedQueries err: %v", err))
+		return nil
+	}
+
+	slaveStmpts, err := sqlxUtils.PrepareQueries(follower, slaveQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	return &coupon{
+		log:               log,
+		leaderDB:          leader,
+		followerDB:        follower,
+		rds:               rds,
+		masterStmts:       stmpts,
+		slaveStmts:        slaveStmpts,
+		masterNamedStmpts: namedStmpts,
+	}
+}
+
+func (c *coupon) GetByCode(ctx context.Context, code string) (entity.Coupon, error) {
+	var result entity.Coupon
+
+	if err := c.slaveStmts[GetByCode].GetContext(ctx, &result, code); err != nil {
+		c.log.Error(ctx, fmt.Sprintf("GetByCode err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (c *coupon) GetByCodeForUpdate(ctx context.Context, code string) (entity.Coupon, error) {
+	var result entity.Coupon
+
+	stmt, err := c.getStatement(ctx, GetByCodeForUpdate)
+	if err != nil {
+		c.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return result, err
+	}
+
+	if err = stmt.GetContext(ctx, &result, code); err != nil {
+		c.log.Error(ctx, fmt.Sprintf("GetByCodeForUpdate err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (c *coupon) GetByIdForUpdate(ctx context.Context, id int64) (entity.Coupon, error) {
+	var result entity.Coupon
+
+	stmt, err := c.getStatement(ctx, GetByIdForUpdate)
+	if err != nil {
+		c.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return result, err
+	}
+
+	if err = stmt.GetContext(ctx, &result, id); err != nil {
+		c.log.Error(ctx, fmt.Sprintf("GetByIdForUpdate err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (c *coupon) GetUsage(ctx context.Context, couponId int64, userId int64, hold time.Duration) (entity.CouponUsage, error) {
+	var result entity.CouponUsage
+
+	stmt, err := c.getStatement(ctx, GetUsage)
+	if err != nil {
+		c.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return result, err
+	}
+
+	if err = stmt.GetContext(ctx, &result, couponId, userId, hold.Seconds()); err != nil {
+		c.log.Error(ctx, fmt.Sprintf("GetUsage err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (c *coupon) CreateRedemption(ctx context.Context, param entity.CouponRedemption) (int64, error) {
+	var result entity.CouponRedemption
+
+	namedStmt, err := c.getNamedStatement(ctx, CreateRedemption)
+	if err != nil {
+		c.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return 0, err
+	}
+
+	if err = namedStmt.GetContext(ctx, &result, param); err != nil {
+		c.log.Error(ctx, fmt.Sprintf("CreateRedemption err: %v", err))
+		return 0, err
+	}
+
+	return result.ID, nil
+}
+
+func (c *coupon) SetRedemptionStatus(ctx context.Context, paymentId int64, status string) error {
+	namedStmt, err := c.getNamedStatement(ctx, SetRedemptionStatus)
+	if err != nil {
+		c.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return err
+	}
+
+	if _, err = namedStmt.ExecContext(ctx, entity.CouponRedemption{PaymentId: paymentId, Status: status}); err != nil {
+		c.log.Error(ctx, fmt.Sprintf("SetRedemptionStatus err: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+func (c *coupon) getStatement(ctx context.Context, queryId int) (*sqlx.Stmt, error) {
+	var err error
+	var statement *sqlx.Stmt
+	if atomicSessionCtx, ok := ctx.(*atomic.AtomicSessionContext); ok {
+		if atomicSession, ok := atomicSessionCtx.AtomicSession.(*atomicSqlx.SqlxAtomicSession); ok {
+			statement, err = atomicSession.Tx().PreparexContext(ctx, masterQueries[queryId])
+		} else {
+			err = atomic.InvalidAtomicSessionProvider
+		}
+	} else {
+		statement = c.masterStmts[queryId]
+	}
+	return statement, err
+}
+
+func (c *coupon) getNamedStatement(ctx context.Context, queryId int) (*sqlx.NamedStmt, error) {
+	var err error
+	var namedStmt *sqlx.NamedStmt
+	if atomicSessionCtx, ok := ctx.(*atomic.AtomicSessionContext); ok {
+		if atomicSession, ok := atomicSessionCtx.AtomicSession.(*atomicSqlx.SqlxAtomicSession); ok {
+			namedStmt, err = atomicSession.Tx().PrepareNamedContext(ctx, masterNamedQueries[queryId])
+		} else {
+			err = atomic.InvalidAtomicSessionProvider
+		}
+	} else {
+		namedStmt = c.masterNamedStmpts[queryId]
+	}
+	return namedStmt, err
+}
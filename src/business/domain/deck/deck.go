@@ -0,0 +1,140 @@
+package deck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"loverly/lib/log"
+	"loverly/lib/redis"
+	"loverly/src/business/entity"
+	"strconv"
+	"time"
+)
+
+// Interface keeps the precomputed discovery decks, they only live in redis
+type Interface interface {
+	Pop(ctx context.Context, userId int64, count int) ([]entity.Profile, int64, error)
+	Replace(ctx context.Context, userId int64, profiles []entity.Profile, ttl time.Duration) error
+	Len(ctx context.Context, userId int64) (int64, error)
+	Delete(ctx context.Context, userId int64) error
+
+	// active users get their deck refilled by the worker
+	Touch(ctx context.Context, userId int64) error
+	GetActiveUserIds(ctx context.Context, since time.Time) ([]int64, error)
+	RequestRefill(ctx context.Context, userId int64) error
+	PopRefills(ctx context.Context, count int64) ([]int64, error)
+}
+
+type deck struct {
+	log log.Interface
+	rds redis.Redis
+}
+
+const (
+	DeckKey   = "decks:%d"
+	ActiveKey = "decks:active"
+	RefillKey = "decks:refill"
+)
+
+func Init(ctx context.Context, log log.Interface, rds redis.Redis) Interface {
+	return &deck{
+		log: log,
+		rds: rds,
+	}
+}
+
+// Pop takes the next profiles off the deck and returns how many are left behind
+func (d *deck) Pop(ctx context.Context, userId int64, count int) ([]entity.Profile, int64, error) {
+	var profiles []entity.Profile
+
+	key := fmt.Sprintf(DeckKey, userId)
+	vals, err := d.rds.LPopCount(ctx, key, count)
+	if err != nil {
+		return profiles, 0, err
+	}
+
+	for _, v := range vals {
+		var p entity.Profile
+		if err := json.Unmarshal([]byte(v), &p); err != nil {
+			d.log.Error(ctx, fmt.Sprintf("error when unmarshal deck profile: %v", err))
+			continue
+		}
+		profiles = append(profiles, p)
+	}
+
+	remaining, err := d.rds.LLen(ctx, key)
+	if err != nil {
+		return profiles, 0, err
+	}
+
+	return profiles, remaining, nil
+}
+
+func (d *deck) Replace(ctx context.Context, userId int64, profiles []entity.Profile, ttl time.Duration) error {
+	vals := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		val, err := json.Marshal(p)
+		if err != nil {
+			d.log.Error(ctx, fmt.Sprintf("error when marshal deck profile: %v", err))
+			return err
+		}
+		vals = append(vals, string(val))
+	}
+
+	return d.rds.ReplaceList(ctx, fmt.Sprintf(DeckKey, userId), vals, ttl)
+}
+
+func (d *deck) Len(ctx context.Context, userId int64) (int64, error) {
+	return d.rds.LLen(ctx, fmt.Sprintf(DeckKey, userId))
+}
+
+func (d *deck) Delete(ctx context.Context, userId int64) error {
+	return d.rds.Del(ctx, fmt.Sprintf(DeckKey, userId))
+}
+
+func (d *deck) Touch(ctx context.Context, userId int64) error {
+	return d.rds.ZAdd(ctx, ActiveKey, float64(time.Now().Unix()), strconv.FormatInt(userId, 10))
+}
+
+func (d *deck) GetActiveUserIds(ctx context.Context, since time.Time) ([]int64, error) {
+	min := strconv.FormatInt(since.Unix(), 10)
+
+	// forget users that haven't opened discovery within the window
+	if err := d.rds.ZRemRangeByScore(ctx, ActiveKey, "-inf", "("+min); err != nil {
+		return nil, err
+	}
+
+	members, err := d.rds.ZRangeByScore(ctx, ActiveKey, min, "+inf")
+	if err != nil {
+		return nil, err
+	}
+
+	return d.parseIds(ctx, members), nil
+}
+
+func (d *deck) RequestRefill(ctx context.Context, userId int64) error {
+	return d.rds.SAdd(ctx, RefillKey, strconv.FormatInt(userId, 10))
+}
+
+func (d *deck) PopRefills(ctx context.Context, count int64) ([]int64, error) {
+	members, err := d.rds.SPopN(ctx, RefillKey, count)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.parseIds(ctx, members), nil
+}
+
+func (d *deck) parseIds(ctx context.Context, members []string) []int64 {
+	var userIds []int64
+	for _, m := range members {
+		id, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			d.log.Error(ctx, fmt.Sprintf("invalid deck user id %s: %v", m, err))
+			continue
+		}
+		userIds = append(userIds, id)
+	}
+
+	return userIds
+}
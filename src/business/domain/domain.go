@@ -4,8 +4,22 @@ import (
 	"context"
 	"loverly/lib/log"
 	"loverly/lib/redis"
+	"loverly/src/business/domain/abuse"
+	"loverly/src/business/domain/audit"
+	"loverly/src/business/domain/block"
+	"loverly/src/business/domain/boost"
+	"loverly/src/business/domain/coupon"
+	"loverly/src/business/domain/deck"
 	match "loverly/src/business/domain/matchs"
+	"loverly/src/business/domain/message"
+	"loverly/src/business/domain/notification"
+	"loverly/src/business/domain/outbox"
+	"loverly/src/business/domain/payment"
+	"loverly/src/business/domain/plan"
 	"loverly/src/business/domain/profile"
+	"loverly/src/business/domain/ratelimit"
+	"loverly/src/business/domain/recommendation"
+	"loverly/src/business/domain/report"
 	"loverly/src/business/domain/subscription"
 	"loverly/src/business/domain/swipe"
 	"loverly/src/business/domain/user"
@@ -15,11 +29,25 @@ import (
 )
 
 type Domains struct {
-	User         user.Interface
-	Subscription subscription.Interface
-	Swipe        swipe.Interface
-	Profile      profile.Interface
-	Match        match.Interface
+	User           user.Interface
+	Subscription   subscription.Interface
+	Swipe          swipe.Interface
+	Profile        profile.Interface
+	Match          match.Interface
+	Boost          boost.Interface
+	Deck           deck.Interface
+	Recommendation recommendation.Interface
+	Outbox         outbox.Interface
+	Message        message.Interface
+	Notification   notification.Interface
+	Block          block.Interface
+	Report         report.Interface
+	RateLimit      ratelimit.Interface
+	Abuse          abuse.Interface
+	Audit          audit.Interface
+	Plan           plan.Interface
+	Payment        payment.Interface
+	Coupon         coupon.Interface
 }
 
 type InitParam struct {
@@ -32,10 +60,24 @@ type InitParam struct {
 
 func Init(ctx context.Context, params InitParam) *Domains {
 	return &Domains{
-		User:         user.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
-		Subscription: subscription.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
-		Swipe:        swipe.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
-		Profile:      profile.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
-		Match:        match.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
+		User:           user.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
+		Subscription:   subscription.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
+		Swipe:          swipe.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
+		Profile:        profile.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
+		Match:          match.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
+		Boost:          boost.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
+		Deck:           deck.Init(ctx, params.Log, params.Rds),
+		Recommendation: recommendation.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
+		Outbox:         outbox.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
+		Message:        message.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
+		Notification:   notification.Init(ctx, params.Log, params.Rds),
+		Block:          block.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
+		Report:         report.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
+		RateLimit:      ratelimit.Init(ctx, params.Log, params.Rds),
+		Abuse:          abuse.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
+		Audit:          audit.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
+		Plan:           plan.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
+		Payment:        payment.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
+		Coupon:         coupon.Init(ctx, params.Log, params.LeaderDB, params.FollowerDB, params.Rds),
 	}
 }
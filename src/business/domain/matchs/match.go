@@ -2,10 +2,14 @@ package match
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"loverly/lib/atomic"
 	"loverly/lib/log"
 	"loverly/lib/redis"
+	"loverly/src/business/domain/block"
+	"loverly/src/business/domain/user"
 	"loverly/src/business/entity"
 
 	atomicSqlx "loverly/lib/atomic/sqlx"
@@ -16,7 +20,18 @@ import (
 
 type Interface interface {
 	GetByUserId(ctx context.Context, userId int64) ([]entity.Match, error)
+	GetPage(ctx context.Context, param entity.MatchPageParam) ([]entity.Match, error)
+	GetForUpdate(ctx context.Context, id int64) (entity.Match, error)
+	GetByPairForUpdate(ctx context.Context, userId, otherId int64) ([]entity.Match, error)
+	// Create returns 0 when the pair already has a match either way round, an unmatched one included
 	Create(ctx context.Context, param entity.Match) (int64, error)
+	Delete(ctx context.Context, param entity.Match) error
+	Touch(ctx context.Context, param entity.Match) error
+	Engage(ctx context.Context, param entity.Match) error
+	Extend(ctx context.Context, param entity.Match) error
+	GetExpired(ctx context.Context, param entity.MatchExpiryParam) ([]entity.Match, error)
+	GetExpiring(ctx context.Context, param entity.MatchExpiryParam) ([]entity.Match, error)
+	MarkExpiryNotified(ctx context.Context, param entity.Match) error
 }
 
 type match struct {
@@ -30,26 +45,71 @@ type match struct {
 }
 
 const (
-	AllFields = `id, user_id_1, user_id_2, created_at, updated_at, deleted_at`
+	AllFields = `id, user_id_1, user_id_2, last_activity_at, engaged_at, first_message_at, expires_at, expiry_notified_at,
+	extended_at, created_at, updated_at, deleted_at, deleted_by`
+
+	// deadline of a match nobody has engaged with yet, an extension replaces the default one
+	deadline = `COALESCE(expires_at, created_at + make_interval(secs => $1))`
 
 	GetByUserId = iota
+	GetPageByNewest
+	GetPageByActivity
+	GetForUpdate
+	GetByPairForUpdate
+	GetExpired
+	GetExpiring
 
 	Create
+	Delete
+	Touch
+	Engage
+	Extend
+	MarkExpiryNotified
 
 	GetByUserIddKey = "matchs:getbyuserid:%d"
+	GetPageKey      = "matchs:getpage:%d:%s:%d:%d:%d"
+	GetPageUserKey  = "matchs:getpage:%d:*"
 	DeleteKey       = "matchs:*"
 )
 
 var (
-	masterQueries = []string{}
+	// blocking soft-deletes the match, the filter only guards against a match created concurrently
+	notBlocked = fmt.Sprintf(block.NotBlocked, "user_id_1", "user_id_2")
+	// match lists leave out suspended and banned users, the match itself stays
+	available = fmt.Sprintf("%s AND %s", fmt.Sprintf(user.Available, "user_id_1"), fmt.Sprintf(user.Available, "user_id_2"))
+
+	masterQueries = []string{
+		GetForUpdate: fmt.Sprintf("SELECT %s FROM matchs WHERE id = $1 AND deleted_at IS NULL AND %s FOR UPDATE", AllFields, notBlocked),
+		GetByPairForUpdate: fmt.Sprintf(`SELECT %s FROM matchs WHERE ((user_id_1 = $1 AND user_id_2 = $2) OR (user_id_1 = $2 AND user_id_2 = $1))
+		AND deleted_at IS NULL FOR UPDATE`, AllFields),
+		GetExpired: fmt.Sprintf(`SELECT %s FROM matchs WHERE engaged_at IS NULL AND deleted_at IS NULL AND %s <= now()
+		ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED`, AllFields, deadline),
+		GetExpiring: fmt.Sprintf(`SELECT %s FROM matchs WHERE engaged_at IS NULL AND deleted_at IS NULL AND expiry_notified_at IS NULL
+		AND %s <= now() + make_interval(secs => $3) ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED`, AllFields, deadline),
+	}
 
 	masterNamedQueries = []string{
-		Create: `INSERT INTO matchs (user_id_1, user_id_2, created_at, updated_at) 
-		VALUES (:user_id_1, :user_id_2, now(), now()) RETURNING id`,
+		// a like can be sent again, it never makes a second match nor brings back an unmatched one
+		Create: `INSERT INTO matchs (user_id_1, user_id_2, created_at, updated_at)
+		SELECT :user_id_1, :user_id_2, now(), now() WHERE NOT EXISTS (SELECT 1 FROM matchs
+			WHERE (user_id_1 = :user_id_1 AND user_id_2 = :user_id_2) OR (user_id_1 = :user_id_2 AND user_id_2 = :user_id_1))
+		ON CONFLICT ON CONSTRAINT unique_matchs_id DO NOTHING RETURNING id`,
+		Delete: `UPDATE matchs SET deleted_at = now(), deleted_by = :deleted_by, updated_at = now() WHERE id = :id AND deleted_at IS NULL`,
+		Touch: `UPDATE matchs SET last_activity_at = now(), engaged_at = COALESCE(engaged_at, now()),
+		first_message_at = COALESCE(first_message_at, now()), updated_at = now() WHERE id = :id AND deleted_at IS NULL`,
+		Engage: `UPDATE matchs SET engaged_at = now(), updated_at = now() WHERE id = :id AND engaged_at IS NULL AND deleted_at IS NULL`,
+		Extend: `UPDATE matchs SET expires_at = :expires_at, extended_at = now(), expiry_notified_at = NULL, updated_at = now()
+		WHERE id = :id AND deleted_at IS NULL`,
+		MarkExpiryNotified: `UPDATE matchs SET expiry_notified_at = now(), updated_at = now() WHERE id = :id`,
 	}
 
 	slaveQueries = []string{
-		GetByUserId: fmt.Sprintf("SELECT %s FROM matchs WHERE user_id_1 = $1 OR user_id_2 = $1 AND deleted_at IS NULL", AllFields),
+		GetByUserId: fmt.Sprintf("SELECT %s FROM matchs WHERE (user_id_1 = $1 OR user_id_2 = $1) AND deleted_at IS NULL AND %s AND %s",
+			AllFields, notBlocked, available),
+		GetPageByNewest: fmt.Sprintf(`SELECT %s FROM matchs WHERE (user_id_1 = $1 OR user_id_2 = $1) AND deleted_at IS NULL AND %s AND %s
+		AND (created_at, id) < ($2, $3) ORDER BY created_at DESC, id DESC LIMIT $4`, AllFields, notBlocked, available),
+		GetPageByActivity: fmt.Sprintf(`SELECT %s FROM matchs WHERE (user_id_1 = $1 OR user_id_2 = $1) AND deleted_at IS NULL AND %s AND %s
+		AND (last_activity_at, id) < ($2, $3) ORDER BY last_activity_at DESC, id DESC LIMIT $4`, AllFields, notBlocked, available),
 	}
 )
 
@@ -101,6 +161,30 @@ func (m *match) GetByUserId(ctx context.Context, userId int64) ([]entity.Match,
 	return matchs, nil
 }
 
+func (m *match) GetPage(ctx context.Context, param entity.MatchPageParam) ([]entity.Match, error) {
+	var matchs []entity.Match
+
+	queryId := GetPageByNewest
+	if param.Order == entity.MatchOrderActivity {
+		queryId = GetPageByActivity
+	}
+
+	key := fmt.Sprintf(GetPageKey, param.UserId, param.Order, param.BeforeAt.UnixNano(), param.BeforeId, param.Limit)
+	err := m.rds.WithCache(ctx, key, &matchs, func() (interface{}, error) {
+		if err := m.slaveStmts[queryId].SelectContext(ctx, &matchs, param.UserId, param.BeforeAt, param.BeforeId, param.Limit); err != nil {
+			return matchs, err
+		}
+
+		return matchs, nil
+	})
+	if err != nil {
+		m.log.Error(ctx, fmt.Sprintf("GetPage err: %v", err))
+		return matchs, err
+	}
+
+	return matchs, nil
+}
+
 func (m *match) Create(ctx context.Context, param entity.Match) (int64, error) {
 	var matchs entity.Match
 
@@ -111,18 +195,192 @@ func (m *match) Create(ctx context.Context, param entity.Match) (int64, error) {
 	}
 
 	if err = namedStmt.GetContext(ctx, &matchs, param); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
 		m.log.Error(ctx, fmt.Sprintf("CreateMatchs err: %v", err))
 		return 0, err
 	}
 
-	redisErr := m.rds.DelWithPattern(ctx, DeleteKey)
-	if redisErr != nil {
-		m.log.Error(ctx, fmt.Sprintf("error when redis delete with pattern: %s, %s", DeleteKey, redisErr))
-	}
+	m.invalidate(ctx, param.UserId1, param.UserId2)
 
 	return matchs.ID, nil
 }
 
+// GetForUpdate locks the match until the surrounding atomic session ends
+func (m *match) GetForUpdate(ctx context.Context, id int64) (entity.Match, error) {
+	var result entity.Match
+
+	stmt, err := m.getStatement(ctx, GetForUpdate)
+	if err != nil {
+		m.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return result, err
+	}
+
+	if err = stmt.GetContext(ctx, &result, id); err != nil {
+		m.log.Error(ctx, fmt.Sprintf("GetForUpdate err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
+
+// GetByPairForUpdate locks the active matches between the two users until the surrounding atomic session ends
+func (m *match) GetByPairForUpdate(ctx context.Context, userId, otherId int64) ([]entity.Match, error) {
+	var results []entity.Match
+
+	stmt, err := m.getStatement(ctx, GetByPairForUpdate)
+	if err != nil {
+		m.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return results, err
+	}
+
+	if err = stmt.SelectContext(ctx, &results, userId, otherId); err != nil {
+		m.log.Error(ctx, fmt.Sprintf("GetByPairForUpdate err: %v", err))
+		return results, err
+	}
+
+	return results, nil
+}
+
+func (m *match) Delete(ctx context.Context, param entity.Match) error {
+	namedStmt, err := m.getNamedStatement(ctx, Delete)
+	if err != nil {
+		m.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return err
+	}
+
+	if _, err = namedStmt.ExecContext(ctx, param); err != nil {
+		m.log.Error(ctx, fmt.Sprintf("DeleteMatchs err: %v", err))
+		return err
+	}
+
+	m.invalidate(ctx, param.UserId1, param.UserId2)
+
+	return nil
+}
+
+// Touch moves the match to the top of the activity order of both users
+func (m *match) Touch(ctx context.Context, param entity.Match) error {
+	namedStmt, err := m.getNamedStatement(ctx, Touch)
+	if err != nil {
+		m.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return err
+	}
+
+	if _, err = namedStmt.ExecContext(ctx, param); err != nil {
+		m.log.Error(ctx, fmt.Sprintf("TouchMatchs err: %v", err))
+		return err
+	}
+
+	m.invalidate(ctx, param.UserId1, param.UserId2)
+
+	return nil
+}
+
+// Engage stops the expiry clock of the match, only the first call has an effect
+func (m *match) Engage(ctx context.Context, param entity.Match) error {
+	namedStmt, err := m.getNamedStatement(ctx, Engage)
+	if err != nil {
+		m.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return err
+	}
+
+	if _, err = namedStmt.ExecContext(ctx, param); err != nil {
+		m.log.Error(ctx, fmt.Sprintf("EngageMatchs err: %v", err))
+		return err
+	}
+
+	m.invalidate(ctx, param.UserId1, param.UserId2)
+
+	return nil
+}
+
+// Extend moves the expiry deadline to param.ExpiresAt and rearms the expiry warning
+func (m *match) Extend(ctx context.Context, param entity.Match) error {
+	namedStmt, err := m.getNamedStatement(ctx, Extend)
+	if err != nil {
+		m.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return err
+	}
+
+	if _, err = namedStmt.ExecContext(ctx, param); err != nil {
+		m.log.Error(ctx, fmt.Sprintf("ExtendMatchs err: %v", err))
+		return err
+	}
+
+	m.invalidate(ctx, param.UserId1, param.UserId2)
+
+	return nil
+}
+
+// GetExpired locks unengaged matches past their deadline until the surrounding atomic session ends,
+// concurrent sweepers skip them instead of waiting
+func (m *match) GetExpired(ctx context.Context, param entity.MatchExpiryParam) ([]entity.Match, error) {
+	var results []entity.Match
+
+	stmt, err := m.getStatement(ctx, GetExpired)
+	if err != nil {
+		m.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return results, err
+	}
+
+	if err = stmt.SelectContext(ctx, &results, param.ExpireAfter.Seconds(), param.Limit); err != nil {
+		m.log.Error(ctx, fmt.Sprintf("GetExpired err: %v", err))
+		return results, err
+	}
+
+	return results, nil
+}
+
+// GetExpiring locks unengaged matches whose deadline falls within param.WarnBefore and that were not warned yet
+func (m *match) GetExpiring(ctx context.Context, param entity.MatchExpiryParam) ([]entity.Match, error) {
+	var results []entity.Match
+
+	stmt, err := m.getStatement(ctx, GetExpiring)
+	if err != nil {
+		m.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return results, err
+	}
+
+	if err = stmt.SelectContext(ctx, &results, param.ExpireAfter.Seconds(), param.Limit, param.WarnBefore.Seconds()); err != nil {
+		m.log.Error(ctx, fmt.Sprintf("GetExpiring err: %v", err))
+		return results, err
+	}
+
+	return results, nil
+}
+
+func (m *match) MarkExpiryNotified(ctx context.Context, param entity.Match) error {
+	namedStmt, err := m.getNamedStatement(ctx, MarkExpiryNotified)
+	if err != nil {
+		m.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return err
+	}
+
+	if _, err = namedStmt.ExecContext(ctx, param); err != nil {
+		m.log.Error(ctx, fmt.Sprintf("MarkExpiryNotified err: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// invalidate drops the cached match lists of both sides only
+func (m *match) invalidate(ctx context.Context, userIds ...int64) {
+	for _, userId := range userIds {
+		key := fmt.Sprintf(GetByUserIddKey, userId)
+		if redisErr := m.rds.Del(ctx, key); redisErr != nil {
+			m.log.Error(ctx, fmt.Sprintf("error when redis delete key: %s, %s", key, redisErr))
+		}
+
+		pattern := fmt.Sprintf(GetPageUserKey, userId)
+		if redisErr := m.rds.DelWithPattern(ctx, pattern); redisErr != nil {
+			m.log.Error(ctx, fmt.Sprintf("error when redis delete with pattern: %s, %s", pattern, redisErr))
+		}
+	}
+}
+
 func (m *match) getStatement(ctx context.Context, queryId int) (*sqlx.Stmt, error) {
 	var err error
 	var statement *sqlx.Stmt
@@ -0,0 +1,201 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"loverly/lib/atomic"
+	"loverly/lib/log"
+	"loverly/lib/redis"
+	"loverly/src/business/entity"
+
+	atomicSqlx "loverly/lib/atomic/sqlx"
+	sqlxUtils "loverly/lib/sqlx"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Interface keeps the chat history of matches, it changes too often to be worth caching
+type Interface interface {
+	GetPage(ctx context.Context, param entity.MessagePageParam) ([]entity.Message, error)
+	GetSummaries(ctx context.Context, userId int64, matchIds []int64) ([]entity.ConversationSummary, error)
+	GetForParticipant(ctx context.Context, id int64, userId int64) (entity.Message, error)
+	Create(ctx context.Context, param entity.Message) (entity.Message, error)
+	MarkRead(ctx context.Context, matchId int64, readerId int64) (int64, error)
+}
+
+type message struct {
+	log               log.Interface
+	leaderDB          *sqlx.DB
+	followerDB        *sqlx.DB
+	rds               redis.Redis
+	masterStmts       []*sqlx.Stmt
+	slaveStmts        []*sqlx.Stmt
+	masterNamedStmpts []*sqlx.NamedStmt
+}
+
+const (
+	AllFields = `id, match_id, sender_id, body, read_at, created_at, updated_at, deleted_at`
+
+	GetPage = iota
+	GetSummaries
+	GetForParticipant
+
+	Create
+	MarkRead
+)
+
+var (
+	masterQueries = []string{
+		MarkRead: `UPDATE messages SET read_at = now(), updated_at = now()
+		WHERE match_id = $1 AND sender_id <> $2 AND read_at IS NULL AND deleted_at IS NULL`,
+	}
+
+	masterNamedQueries = []string{
+		Create: fmt.Sprintf(`INSERT INTO messages (match_id, sender_id, body, created_at, updated_at)
+		VALUES (:match_id, :sender_id, :body, now(), now()) RETURNING %s`, AllFields),
+	}
+
+	slaveQueries = []string{
+		GetPage: fmt.Sprintf(`SELECT %s FROM messages WHERE match_id = $1 AND deleted_at IS NULL
+		AND (created_at, id) < ($2, $3) ORDER BY created_at DESC, id DESC LIMIT $4`, AllFields),
+		// the window count runs before DISTINCT ON keeps the newest row of every match
+		GetSummaries: `SELECT DISTINCT ON (match_id) match_id, id AS last_message_id, sender_id, body, created_at,
+		COUNT(*) FILTER (WHERE sender_id <> $1 AND read_at IS NULL) OVER (PARTITION BY match_id) AS unread
+		FROM messages WHERE match_id = ANY($2) AND deleted_at IS NULL
+		ORDER BY match_id, created_at DESC, id DESC`,
+		// the match may have ended since, its users can still report what was said
+		GetForParticipant: `SELECT msg.id, msg.match_id, msg.sender_id, msg.body, msg.read_at, msg.created_at, msg.updated_at, msg.deleted_at
+		FROM messages msg JOIN matchs m ON m.id = msg.match_id
+		WHERE msg.id = $1 AND (m.user_id_1 = $2 OR m.user_id_2 = $2)`,
+	}
+)
+
+func Init(ctx context.Context, log log.Interface, leader *sqlx.DB, follower *sqlx.DB, rds redis.Redis) Interface {
+	stmpts, err := sqlxUtils.PrepareQueries(leader, masterQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	namedStmpts, err := sqlxUtils.PrepareNamedQueries(leader, masterNamedQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareNamedQueries err: %v", err))
+		return nil
+	}
+
+	slaveStmpts, err := sqlxUtils.PrepareQueries(follower, slaveQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	return &message{
+		log:               log,
+		leaderDB:          leader,
+		followerDB:        follower,
+		rds:               rds,
+		masterStmts:       stmpts,
+		slaveStmts:        slaveStmpts,
+		masterNamedStmpts: namedStmpts,
+	}
+}
+
+func (m *message) GetPage(ctx context.Context, param entity.MessagePageParam) ([]entity.Message, error) {
+	var results []entity.Message
+
+	if err := m.slaveStmts[GetPage].SelectContext(ctx, &results, param.MatchId, param.BeforeAt, param.BeforeId, param.Limit); err != nil {
+		m.log.Error(ctx, fmt.Sprintf("GetPage err: %v", err))
+		return results, err
+	}
+
+	return results, nil
+}
+
+// GetSummaries returns one row per match that has messages, unread only counts what the other side sent
+func (m *message) GetSummaries(ctx context.Context, userId int64, matchIds []int64) ([]entity.ConversationSummary, error) {
+	var results []entity.ConversationSummary
+
+	if err := m.slaveStmts[GetSummaries].SelectContext(ctx, &results, userId, pq.Array(matchIds)); err != nil {
+		m.log.Error(ctx, fmt.Sprintf("GetSummaries err: %v", err))
+		return results, err
+	}
+
+	return results, nil
+}
+
+// GetForParticipant returns the message if the user is one of the two users of its match
+func (m *message) GetForParticipant(ctx context.Context, id int64, userId int64) (entity.Message, error) {
+	var result entity.Message
+
+	if err := m.slaveStmts[GetForParticipant].GetContext(ctx, &result, id, userId); err != nil {
+		m.log.Error(ctx, fmt.Sprintf("GetForParticipant err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (m *message) Create(ctx context.Context, param entity.Message) (entity.Message, error) {
+	var result entity.Message
+
+	namedStmt, err := m.getNamedStatement(ctx, Create)
+	if err != nil {
+		m.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return result, err
+	}
+
+	if err = namedStmt.GetContext(ctx, &result, param); err != nil {
+		m.log.Error(ctx, fmt.Sprintf("CreateMessage err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
+
+// MarkRead marks everything the other side sent in the match as read and returns how many messages changed
+func (m *message) MarkRead(ctx context.Context, matchId int64, readerId int64) (int64, error) {
+	stmt, err := m.getStatement(ctx, MarkRead)
+	if err != nil {
+		m.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return 0, err
+	}
+
+	res, err := stmt.ExecContext(ctx, matchId, readerId)
+	if err != nil {
+		m.log.Error(ctx, fmt.Sprintf("MarkRead err: %v", err))
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (m *message) getStatement(ctx context.Context, queryId int) (*sqlx.Stmt, error) {
+	var err error
+	var statement *sqlx.Stmt
+	if atomicSessionCtx, ok := ctx.(*atomic.AtomicSessionContext); ok {
+		if atomicSession, ok := atomicSessionCtx.AtomicSession.(*atomicSqlx.SqlxAtomicSession); ok {
+			statement, err = atomicSession.Tx().PreparexContext(ctx, masterQueries[queryId])
+		} else {
+			err = atomic.InvalidAtomicSessionProvider
+		}
+	} else {
+		statement = m.masterStmts[queryId]
+	}
+	return statement, err
+}
+
+func (m *message) getNamedStatement(ctx context.Context, queryId int) (*sqlx.NamedStmt, error) {
+	var err error
+	var namedStmt *sqlx.NamedStmt
+	if atomicSessionCtx, ok := ctx.(*atomic.AtomicSessionContext); ok {
+		if atomicSession, ok := atomicSessionCtx.AtomicSession.(*atomicSqlx.SqlxAtomicSession); ok {
+			namedStmt, err = atomicSession.Tx().PrepareNamedContext(ctx, masterNamedQueries[queryId])
+		} else {
+			err = atomic.InvalidAtomicSessionProvider
+		}
+	} else {
+		namedStmt = m.masterNamedStmpts[queryId]
+	}
+	return namedStmt, err
+}
@@ -0,0 +1,157 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: abuse/abuse.go
+//
+// Generated by this command:
+//
+//	mockgen -source=abuse/abuse.go -destination=mock/abuse/abuse.go
+//
+// Package mock_abuse is a generated GoMock package.
+package mock_abuse
+
+import (
+	context "context"
+	entity "loverly/src/business/entity"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInterface is a mock of Interface interface.
+type MockInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockInterfaceMockRecorder
+}
+
+// MockInterfaceMockRecorder is the mock recorder for MockInterface.
+type MockInterfaceMockRecorder struct {
+	mock *MockInterface
+}
+
+// NewMockInterface creates a new mock instance.
+func NewMockInterface(ctrl *gomock.Controller) *MockInterface {
+	mock := &MockInterface{ctrl: ctrl}
+	mock.recorder = &MockInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInterface) EXPECT() *MockInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Flag mocks base method.
+func (m *MockInterface) Flag(ctx context.Context, param entity.AbuseFlag) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Flag", ctx, param)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Flag indicates an expected call of Flag.
+func (mr *MockInterfaceMockRecorder) Flag(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Flag", reflect.TypeOf((*MockInterface)(nil).Flag), ctx, param)
+}
+
+// GetForUpdate mocks base method.
+func (m *MockInterface) GetForUpdate(ctx context.Context, id int64) (entity.AbuseFlag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetForUpdate", ctx, id)
+	ret0, _ := ret[0].(entity.AbuseFlag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetForUpdate indicates an expected call of GetForUpdate.
+func (mr *MockInterfaceMockRecorder) GetForUpdate(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetForUpdate", reflect.TypeOf((*MockInterface)(nil).GetForUpdate), ctx, id)
+}
+
+// GetPage mocks base method.
+func (m *MockInterface) GetPage(ctx context.Context, param entity.AbuseFlagPageParam) ([]entity.AbuseFlag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPage", ctx, param)
+	ret0, _ := ret[0].([]entity.AbuseFlag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPage indicates an expected call of GetPage.
+func (mr *MockInterfaceMockRecorder) GetPage(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPage", reflect.TypeOf((*MockInterface)(nil).GetPage), ctx, param)
+}
+
+// GetSignals mocks base method.
+func (m *MockInterface) GetSignals(ctx context.Context, param entity.AbuseSignalParam) (entity.AbuseSignals, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSignals", ctx, param)
+	ret0, _ := ret[0].(entity.AbuseSignals)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSignals indicates an expected call of GetSignals.
+func (mr *MockInterfaceMockRecorder) GetSignals(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSignals", reflect.TypeOf((*MockInterface)(nil).GetSignals), ctx, param)
+}
+
+// IsShadowLimited mocks base method.
+func (m *MockInterface) IsShadowLimited(ctx context.Context, userId int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsShadowLimited", ctx, userId)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsShadowLimited indicates an expected call of IsShadowLimited.
+func (mr *MockInterfaceMockRecorder) IsShadowLimited(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsShadowLimited", reflect.TypeOf((*MockInterface)(nil).IsShadowLimited), ctx, userId)
+}
+
+// Review mocks base method.
+func (m *MockInterface) Review(ctx context.Context, param entity.AbuseFlag) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Review", ctx, param)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Review indicates an expected call of Review.
+func (mr *MockInterfaceMockRecorder) Review(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Review", reflect.TypeOf((*MockInterface)(nil).Review), ctx, param)
+}
+
+// SyncShadowLimit mocks base method.
+func (m *MockInterface) SyncShadowLimit(ctx context.Context, userId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SyncShadowLimit", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SyncShadowLimit indicates an expected call of SyncShadowLimit.
+func (mr *MockInterfaceMockRecorder) SyncShadowLimit(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncShadowLimit", reflect.TypeOf((*MockInterface)(nil).SyncShadowLimit), ctx, userId)
+}
+
+// Track mocks base method.
+func (m *MockInterface) Track(ctx context.Context, param entity.Fingerprint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Track", ctx, param)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Track indicates an expected call of Track.
+func (mr *MockInterfaceMockRecorder) Track(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Track", reflect.TypeOf((*MockInterface)(nil).Track), ctx, param)
+}
@@ -0,0 +1,96 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: block/block.go
+//
+// Generated by this command:
+//
+//	mockgen -source=block/block.go -destination=mock/block/block.go
+//
+// Package mock_block is a generated GoMock package.
+package mock_block
+
+import (
+	context "context"
+	entity "loverly/src/business/entity"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInterface is a mock of Interface interface.
+type MockInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockInterfaceMockRecorder
+}
+
+// MockInterfaceMockRecorder is the mock recorder for MockInterface.
+type MockInterfaceMockRecorder struct {
+	mock *MockInterface
+}
+
+// NewMockInterface creates a new mock instance.
+func NewMockInterface(ctrl *gomock.Controller) *MockInterface {
+	mock := &MockInterface{ctrl: ctrl}
+	mock.recorder = &MockInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInterface) EXPECT() *MockInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockInterface) Create(ctx context.Context, param entity.Block) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, param)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockInterfaceMockRecorder) Create(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockInterface)(nil).Create), ctx, param)
+}
+
+// Delete mocks base method.
+func (m *MockInterface) Delete(ctx context.Context, param entity.Block) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, param)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockInterfaceMockRecorder) Delete(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockInterface)(nil).Delete), ctx, param)
+}
+
+// GetByBlockerId mocks base method.
+func (m *MockInterface) GetByBlockerId(ctx context.Context, blockerId int64) ([]entity.BlockedUser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByBlockerId", ctx, blockerId)
+	ret0, _ := ret[0].([]entity.BlockedUser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByBlockerId indicates an expected call of GetByBlockerId.
+func (mr *MockInterfaceMockRecorder) GetByBlockerId(ctx, blockerId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByBlockerId", reflect.TypeOf((*MockInterface)(nil).GetByBlockerId), ctx, blockerId)
+}
+
+// Invalidate mocks base method.
+func (m *MockInterface) Invalidate(ctx context.Context, param entity.Block) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Invalidate", ctx, param)
+}
+
+// Invalidate indicates an expected call of Invalidate.
+func (mr *MockInterfaceMockRecorder) Invalidate(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Invalidate", reflect.TypeOf((*MockInterface)(nil).Invalidate), ctx, param)
+}
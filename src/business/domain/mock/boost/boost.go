@@ -0,0 +1,248 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: boost/boost.go
+//
+// Generated by this command:
+//
+//	mockgen -source=boost/boost.go -destination=mock/boost/boost.go
+//
+// Package mock_boost is a generated GoMock package.
+package mock_boost
+
+import (
+	context "context"
+	entity "loverly/src/business/entity"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInterface is a mock of Interface interface.
+type MockInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockInterfaceMockRecorder
+}
+
+// MockInterfaceMockRecorder is the mock recorder for MockInterface.
+type MockInterfaceMockRecorder struct {
+	mock *MockInterface
+}
+
+// NewMockInterface creates a new mock instance.
+func NewMockInterface(ctrl *gomock.Controller) *MockInterface {
+	mock := &MockInterface{ctrl: ctrl}
+	mock.recorder = &MockInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInterface) EXPECT() *MockInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Activate mocks base method.
+func (m *MockInterface) Activate(ctx context.Context, param entity.Boost) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Activate", ctx, param)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Activate indicates an expected call of Activate.
+func (mr *MockInterfaceMockRecorder) Activate(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Activate", reflect.TypeOf((*MockInterface)(nil).Activate), ctx, param)
+}
+
+// CountBySource mocks base method.
+func (m *MockInterface) CountBySource(ctx context.Context, userId int64, source string, since time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountBySource", ctx, userId, source, since)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountBySource indicates an expected call of CountBySource.
+func (mr *MockInterfaceMockRecorder) CountBySource(ctx, userId, source, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountBySource", reflect.TypeOf((*MockInterface)(nil).CountBySource), ctx, userId, source, since)
+}
+
+// Create mocks base method.
+func (m *MockInterface) Create(ctx context.Context, param entity.Boost) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, param)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockInterfaceMockRecorder) Create(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockInterface)(nil).Create), ctx, param)
+}
+
+// CreateCredit mocks base method.
+func (m *MockInterface) CreateCredit(ctx context.Context, param entity.BoostCredit) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCredit", ctx, param)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateCredit indicates an expected call of CreateCredit.
+func (mr *MockInterfaceMockRecorder) CreateCredit(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCredit", reflect.TypeOf((*MockInterface)(nil).CreateCredit), ctx, param)
+}
+
+// GetActiveUserIds mocks base method.
+func (m *MockInterface) GetActiveUserIds(ctx context.Context, location string) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActiveUserIds", ctx, location)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActiveUserIds indicates an expected call of GetActiveUserIds.
+func (mr *MockInterfaceMockRecorder) GetActiveUserIds(ctx, location any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveUserIds", reflect.TypeOf((*MockInterface)(nil).GetActiveUserIds), ctx, location)
+}
+
+// GetCreditBalance mocks base method.
+func (m *MockInterface) GetCreditBalance(ctx context.Context, userId int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCreditBalance", ctx, userId)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCreditBalance indicates an expected call of GetCreditBalance.
+func (mr *MockInterfaceMockRecorder) GetCreditBalance(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCreditBalance", reflect.TypeOf((*MockInterface)(nil).GetCreditBalance), ctx, userId)
+}
+
+// GetEnded mocks base method.
+func (m *MockInterface) GetEnded(ctx context.Context, limit int) ([]entity.Boost, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEnded", ctx, limit)
+	ret0, _ := ret[0].([]entity.Boost)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEnded indicates an expected call of GetEnded.
+func (mr *MockInterfaceMockRecorder) GetEnded(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEnded", reflect.TypeOf((*MockInterface)(nil).GetEnded), ctx, limit)
+}
+
+// GetLatestByUserId mocks base method.
+func (m *MockInterface) GetLatestByUserId(ctx context.Context, userId int64) (entity.Boost, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatestByUserId", ctx, userId)
+	ret0, _ := ret[0].(entity.Boost)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLatestByUserId indicates an expected call of GetLatestByUserId.
+func (mr *MockInterfaceMockRecorder) GetLatestByUserId(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestByUserId", reflect.TypeOf((*MockInterface)(nil).GetLatestByUserId), ctx, userId)
+}
+
+// GetLatestByUserIdForUpdate mocks base method.
+func (m *MockInterface) GetLatestByUserIdForUpdate(ctx context.Context, userId int64) (entity.Boost, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatestByUserIdForUpdate", ctx, userId)
+	ret0, _ := ret[0].(entity.Boost)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLatestByUserIdForUpdate indicates an expected call of GetLatestByUserIdForUpdate.
+func (mr *MockInterfaceMockRecorder) GetLatestByUserIdForUpdate(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestByUserIdForUpdate", reflect.TypeOf((*MockInterface)(nil).GetLatestByUserIdForUpdate), ctx, userId)
+}
+
+// GetStats mocks base method.
+func (m *MockInterface) GetStats(ctx context.Context, userId int64) (int64, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStats", ctx, userId)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetStats indicates an expected call of GetStats.
+func (mr *MockInterfaceMockRecorder) GetStats(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStats", reflect.TypeOf((*MockInterface)(nil).GetStats), ctx, userId)
+}
+
+// IsActive mocks base method.
+func (m *MockInterface) IsActive(ctx context.Context, userId int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsActive", ctx, userId)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsActive indicates an expected call of IsActive.
+func (mr *MockInterfaceMockRecorder) IsActive(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsActive", reflect.TypeOf((*MockInterface)(nil).IsActive), ctx, userId)
+}
+
+// LockUser mocks base method.
+func (m *MockInterface) LockUser(ctx context.Context, userId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockUser", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockUser indicates an expected call of LockUser.
+func (mr *MockInterfaceMockRecorder) LockUser(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockUser", reflect.TypeOf((*MockInterface)(nil).LockUser), ctx, userId)
+}
+
+// Track mocks base method.
+func (m *MockInterface) Track(ctx context.Context, userId int64, stat string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Track", ctx, userId, stat)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Track indicates an expected call of Track.
+func (mr *MockInterfaceMockRecorder) Track(ctx, userId, stat any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Track", reflect.TypeOf((*MockInterface)(nil).Track), ctx, userId, stat)
+}
+
+// UpdateStats mocks base method.
+func (m *MockInterface) UpdateStats(ctx context.Context, param entity.Boost) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStats", ctx, param)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateStats indicates an expected call of UpdateStats.
+func (mr *MockInterfaceMockRecorder) UpdateStats(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStats", reflect.TypeOf((*MockInterface)(nil).UpdateStats), ctx, param)
+}
@@ -0,0 +1,130 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: coupon/coupon.go
+//
+// Generated by this command:
+//
+//	mockgen -source=coupon/coupon.go -destination=mock/coupon/coupon.go
+//
+// Package mock_coupon is a generated GoMock package.
+package mock_coupon
+
+import (
+	context "context"
+	entity "loverly/src/business/entity"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInterface is a mock of Interface interface.
+type MockInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockInterfaceMockRecorder
+}
+
+// MockInterfaceMockRecorder is the mock recorder for MockInterface.
+type MockInterfaceMockRecorder struct {
+	mock *MockInterface
+}
+
+// NewMockInterface creates a new mock instance.
+func NewMockInterface(ctrl *gomock.Controller) *MockInterface {
+	mock := &MockInterface{ctrl: ctrl}
+	mock.recorder = &MockInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInterface) EXPECT() *MockInterfaceMockRecorder {
+	return m.recorder
+}
+
+// CreateRedemption mocks base method.
+func (m *MockInterface) CreateRedemption(ctx context.Context, param entity.CouponRedemption) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRedemption", ctx, param)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRedemption indicates an expected call of CreateRedemption.
+func (mr *MockInterfaceMockRecorder) CreateRedemption(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRedemption", reflect.TypeOf((*MockInterface)(nil).CreateRedemption), ctx, param)
+}
+
+// GetByCode mocks base method.
+func (m *MockInterface) GetByCode(ctx context.Context, code string) (entity.Coupon, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByCode", ctx, code)
+	ret0, _ := ret[0].(entity.Coupon)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByCode indicates an expected call of GetByCode.
+func (mr *MockInterfaceMockRecorder) GetByCode(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByCode", reflect.TypeOf((*MockInterface)(nil).GetByCode), ctx, code)
+}
+
+// GetByCodeForUpdate mocks base method.
+func (m *MockInterface) GetByCodeForUpdate(ctx context.Context, code string) (entity.Coupon, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByCodeForUpdate", ctx, code)
+	ret0, _ := ret[0].(entity.Coupon)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByCodeForUpdate indicates an expected call of GetByCodeForUpdate.
+func (mr *MockInterfaceMockRecorder) GetByCodeForUpdate(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByCodeForUpdate", reflect.TypeOf((*MockInterface)(nil).GetByCodeForUpdate), ctx, code)
+}
+
+// GetByIdForUpdate mocks base method.
+func (m *MockInterface) GetByIdForUpdate(ctx context.Context, id int64) (entity.Coupon, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByIdForUpdate", ctx, id)
+	ret0, _ := ret[0].(entity.Coupon)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByIdForUpdate indicates an expected call of GetByIdForUpdate.
+func (mr *MockInterfaceMockRecorder) GetByIdForUpdate(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByIdForUpdate", reflect.TypeOf((*MockInterface)(nil).GetByIdForUpdate), ctx, id)
+}
+
+// GetUsage mocks base method.
+func (m *MockInterface) GetUsage(ctx context.Context, couponId, userId int64, hold time.Duration) (entity.CouponUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsage", ctx, couponId, userId, hold)
+	ret0, _ := ret[0].(entity.CouponUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsage indicates an expected call of GetUsage.
+func (mr *MockInterfaceMockRecorder) GetUsage(ctx, couponId, userId, hold any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsage", reflect.TypeOf((*MockInterface)(nil).GetUsage), ctx, couponId, userId, hold)
+}
+
+// SetRedemptionStatus mocks base method.
+func (m *MockInterface) SetRedemptionStatus(ctx context.Context, paymentId int64, status string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRedemptionStatus", ctx, paymentId, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRedemptionStatus indicates an expected call of SetRedemptionStatus.
+func (mr *MockInterfaceMockRecorder) SetRedemptionStatus(ctx, paymentId, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRedemptionStatus", reflect.TypeOf((*MockInterface)(nil).SetRedemptionStatus), ctx, paymentId, status)
+}
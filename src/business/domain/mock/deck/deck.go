@@ -0,0 +1,158 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: deck/deck.go
+//
+// Generated by this command:
+//
+//	mockgen -source=deck/deck.go -destination=mock/deck/deck.go
+//
+// Package mock_deck is a generated GoMock package.
+package mock_deck
+
+import (
+	context "context"
+	entity "loverly/src/business/entity"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInterface is a mock of Interface interface.
+type MockInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockInterfaceMockRecorder
+}
+
+// MockInterfaceMockRecorder is the mock recorder for MockInterface.
+type MockInterfaceMockRecorder struct {
+	mock *MockInterface
+}
+
+// NewMockInterface creates a new mock instance.
+func NewMockInterface(ctrl *gomock.Controller) *MockInterface {
+	mock := &MockInterface{ctrl: ctrl}
+	mock.recorder = &MockInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInterface) EXPECT() *MockInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockInterface) Delete(ctx context.Context, userId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockInterfaceMockRecorder) Delete(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockInterface)(nil).Delete), ctx, userId)
+}
+
+// GetActiveUserIds mocks base method.
+func (m *MockInterface) GetActiveUserIds(ctx context.Context, since time.Time) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActiveUserIds", ctx, since)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActiveUserIds indicates an expected call of GetActiveUserIds.
+func (mr *MockInterfaceMockRecorder) GetActiveUserIds(ctx, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveUserIds", reflect.TypeOf((*MockInterface)(nil).GetActiveUserIds), ctx, since)
+}
+
+// Len mocks base method.
+func (m *MockInterface) Len(ctx context.Context, userId int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Len", ctx, userId)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Len indicates an expected call of Len.
+func (mr *MockInterfaceMockRecorder) Len(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Len", reflect.TypeOf((*MockInterface)(nil).Len), ctx, userId)
+}
+
+// Pop mocks base method.
+func (m *MockInterface) Pop(ctx context.Context, userId int64, count int) ([]entity.Profile, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Pop", ctx, userId, count)
+	ret0, _ := ret[0].([]entity.Profile)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Pop indicates an expected call of Pop.
+func (mr *MockInterfaceMockRecorder) Pop(ctx, userId, count any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pop", reflect.TypeOf((*MockInterface)(nil).Pop), ctx, userId, count)
+}
+
+// PopRefills mocks base method.
+func (m *MockInterface) PopRefills(ctx context.Context, count int64) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PopRefills", ctx, count)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PopRefills indicates an expected call of PopRefills.
+func (mr *MockInterfaceMockRecorder) PopRefills(ctx, count any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PopRefills", reflect.TypeOf((*MockInterface)(nil).PopRefills), ctx, count)
+}
+
+// Replace mocks base method.
+func (m *MockInterface) Replace(ctx context.Context, userId int64, profiles []entity.Profile, ttl time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replace", ctx, userId, profiles, ttl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Replace indicates an expected call of Replace.
+func (mr *MockInterfaceMockRecorder) Replace(ctx, userId, profiles, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replace", reflect.TypeOf((*MockInterface)(nil).Replace), ctx, userId, profiles, ttl)
+}
+
+// RequestRefill mocks base method.
+func (m *MockInterface) RequestRefill(ctx context.Context, userId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestRefill", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RequestRefill indicates an expected call of RequestRefill.
+func (mr *MockInterfaceMockRecorder) RequestRefill(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestRefill", reflect.TypeOf((*MockInterface)(nil).RequestRefill), ctx, userId)
+}
+
+// Touch mocks base method.
+func (m *MockInterface) Touch(ctx context.Context, userId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Touch", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Touch indicates an expected call of Touch.
+func (mr *MockInterfaceMockRecorder) Touch(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Touch", reflect.TypeOf((*MockInterface)(nil).Touch), ctx, userId)
+}
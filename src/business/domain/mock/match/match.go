@@ -54,6 +54,63 @@ func (mr *MockInterfaceMockRecorder) Create(ctx, param any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockInterface)(nil).Create), ctx, param)
 }
 
+// Delete mocks base method.
+func (m *MockInterface) Delete(ctx context.Context, param entity.Match) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, param)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockInterfaceMockRecorder) Delete(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockInterface)(nil).Delete), ctx, param)
+}
+
+// Engage mocks base method.
+func (m *MockInterface) Engage(ctx context.Context, param entity.Match) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Engage", ctx, param)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Engage indicates an expected call of Engage.
+func (mr *MockInterfaceMockRecorder) Engage(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Engage", reflect.TypeOf((*MockInterface)(nil).Engage), ctx, param)
+}
+
+// Extend mocks base method.
+func (m *MockInterface) Extend(ctx context.Context, param entity.Match) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Extend", ctx, param)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Extend indicates an expected call of Extend.
+func (mr *MockInterfaceMockRecorder) Extend(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Extend", reflect.TypeOf((*MockInterface)(nil).Extend), ctx, param)
+}
+
+// GetByPairForUpdate mocks base method.
+func (m *MockInterface) GetByPairForUpdate(ctx context.Context, userId, otherId int64) ([]entity.Match, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByPairForUpdate", ctx, userId, otherId)
+	ret0, _ := ret[0].([]entity.Match)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByPairForUpdate indicates an expected call of GetByPairForUpdate.
+func (mr *MockInterfaceMockRecorder) GetByPairForUpdate(ctx, userId, otherId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByPairForUpdate", reflect.TypeOf((*MockInterface)(nil).GetByPairForUpdate), ctx, userId, otherId)
+}
+
 // GetByUserId mocks base method.
 func (m *MockInterface) GetByUserId(ctx context.Context, userId int64) ([]entity.Match, error) {
 	m.ctrl.T.Helper()
@@ -68,3 +125,91 @@ func (mr *MockInterfaceMockRecorder) GetByUserId(ctx, userId any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserId", reflect.TypeOf((*MockInterface)(nil).GetByUserId), ctx, userId)
 }
+
+// GetExpired mocks base method.
+func (m *MockInterface) GetExpired(ctx context.Context, param entity.MatchExpiryParam) ([]entity.Match, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpired", ctx, param)
+	ret0, _ := ret[0].([]entity.Match)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpired indicates an expected call of GetExpired.
+func (mr *MockInterfaceMockRecorder) GetExpired(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpired", reflect.TypeOf((*MockInterface)(nil).GetExpired), ctx, param)
+}
+
+// GetExpiring mocks base method.
+func (m *MockInterface) GetExpiring(ctx context.Context, param entity.MatchExpiryParam) ([]entity.Match, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpiring", ctx, param)
+	ret0, _ := ret[0].([]entity.Match)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpiring indicates an expected call of GetExpiring.
+func (mr *MockInterfaceMockRecorder) GetExpiring(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpiring", reflect.TypeOf((*MockInterface)(nil).GetExpiring), ctx, param)
+}
+
+// GetForUpdate mocks base method.
+func (m *MockInterface) GetForUpdate(ctx context.Context, id int64) (entity.Match, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetForUpdate", ctx, id)
+	ret0, _ := ret[0].(entity.Match)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetForUpdate indicates an expected call of GetForUpdate.
+func (mr *MockInterfaceMockRecorder) GetForUpdate(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetForUpdate", reflect.TypeOf((*MockInterface)(nil).GetForUpdate), ctx, id)
+}
+
+// GetPage mocks base method.
+func (m *MockInterface) GetPage(ctx context.Context, param entity.MatchPageParam) ([]entity.Match, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPage", ctx, param)
+	ret0, _ := ret[0].([]entity.Match)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPage indicates an expected call of GetPage.
+func (mr *MockInterfaceMockRecorder) GetPage(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPage", reflect.TypeOf((*MockInterface)(nil).GetPage), ctx, param)
+}
+
+// MarkExpiryNotified mocks base method.
+func (m *MockInterface) MarkExpiryNotified(ctx context.Context, param entity.Match) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkExpiryNotified", ctx, param)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkExpiryNotified indicates an expected call of MarkExpiryNotified.
+func (mr *MockInterfaceMockRecorder) MarkExpiryNotified(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkExpiryNotified", reflect.TypeOf((*MockInterface)(nil).MarkExpiryNotified), ctx, param)
+}
+
+// Touch mocks base method.
+func (m *MockInterface) Touch(ctx context.Context, param entity.Match) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Touch", ctx, param)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Touch indicates an expected call of Touch.
+func (mr *MockInterfaceMockRecorder) Touch(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Touch", reflect.TypeOf((*MockInterface)(nil).Touch), ctx, param)
+}
@@ -0,0 +1,158 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: payment/payment.go
+//
+// Generated by this command:
+//
+//	mockgen -source=payment/payment.go -destination=mock/payment/payment.go
+//
+// Package mock_payment is a generated GoMock package.
+package mock_payment
+
+import (
+	context "context"
+	entity "loverly/src/business/entity"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInterface is a mock of Interface interface.
+type MockInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockInterfaceMockRecorder
+}
+
+// MockInterfaceMockRecorder is the mock recorder for MockInterface.
+type MockInterfaceMockRecorder struct {
+	mock *MockInterface
+}
+
+// NewMockInterface creates a new mock instance.
+func NewMockInterface(ctrl *gomock.Controller) *MockInterface {
+	mock := &MockInterface{ctrl: ctrl}
+	mock.recorder = &MockInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInterface) EXPECT() *MockInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockInterface) Create(ctx context.Context, param entity.Payment) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, param)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockInterfaceMockRecorder) Create(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockInterface)(nil).Create), ctx, param)
+}
+
+// FailPendingChange mocks base method.
+func (m *MockInterface) FailPendingChange(ctx context.Context, subscriptionId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FailPendingChange", ctx, subscriptionId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FailPendingChange indicates an expected call of FailPendingChange.
+func (mr *MockInterfaceMockRecorder) FailPendingChange(ctx, subscriptionId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailPendingChange", reflect.TypeOf((*MockInterface)(nil).FailPendingChange), ctx, subscriptionId)
+}
+
+// Get mocks base method.
+func (m *MockInterface) Get(ctx context.Context, id, userId int64) (entity.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id, userId)
+	ret0, _ := ret[0].(entity.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockInterfaceMockRecorder) Get(ctx, id, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockInterface)(nil).Get), ctx, id, userId)
+}
+
+// GetByIdForUpdate mocks base method.
+func (m *MockInterface) GetByIdForUpdate(ctx context.Context, id int64) (entity.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByIdForUpdate", ctx, id)
+	ret0, _ := ret[0].(entity.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByIdForUpdate indicates an expected call of GetByIdForUpdate.
+func (mr *MockInterfaceMockRecorder) GetByIdForUpdate(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByIdForUpdate", reflect.TypeOf((*MockInterface)(nil).GetByIdForUpdate), ctx, id)
+}
+
+// GetByProviderRefForUpdate mocks base method.
+func (m *MockInterface) GetByProviderRefForUpdate(ctx context.Context, provider, providerRef string) (entity.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByProviderRefForUpdate", ctx, provider, providerRef)
+	ret0, _ := ret[0].(entity.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByProviderRefForUpdate indicates an expected call of GetByProviderRefForUpdate.
+func (mr *MockInterfaceMockRecorder) GetByProviderRefForUpdate(ctx, provider, providerRef any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByProviderRefForUpdate", reflect.TypeOf((*MockInterface)(nil).GetByProviderRefForUpdate), ctx, provider, providerRef)
+}
+
+// GetRefundDue mocks base method.
+func (m *MockInterface) GetRefundDue(ctx context.Context, limit int) ([]entity.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefundDue", ctx, limit)
+	ret0, _ := ret[0].([]entity.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefundDue indicates an expected call of GetRefundDue.
+func (mr *MockInterfaceMockRecorder) GetRefundDue(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefundDue", reflect.TypeOf((*MockInterface)(nil).GetRefundDue), ctx, limit)
+}
+
+// RecordEvent mocks base method.
+func (m *MockInterface) RecordEvent(ctx context.Context, param entity.PaymentEvent) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordEvent", ctx, param)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecordEvent indicates an expected call of RecordEvent.
+func (mr *MockInterfaceMockRecorder) RecordEvent(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordEvent", reflect.TypeOf((*MockInterface)(nil).RecordEvent), ctx, param)
+}
+
+// Update mocks base method.
+func (m *MockInterface) Update(ctx context.Context, param entity.Payment) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, param)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockInterfaceMockRecorder) Update(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockInterface)(nil).Update), ctx, param)
+}
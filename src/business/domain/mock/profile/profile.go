@@ -54,19 +54,34 @@ func (mr *MockInterfaceMockRecorder) Create(ctx, param any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockInterface)(nil).Create), ctx, param)
 }
 
+// GetAvailableIds mocks base method.
+func (m *MockInterface) GetAvailableIds(ctx context.Context, viewerId int64, userIds []int64) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAvailableIds", ctx, viewerId, userIds)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAvailableIds indicates an expected call of GetAvailableIds.
+func (mr *MockInterfaceMockRecorder) GetAvailableIds(ctx, viewerId, userIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAvailableIds", reflect.TypeOf((*MockInterface)(nil).GetAvailableIds), ctx, viewerId, userIds)
+}
+
 // GetBySwipe mocks base method.
-func (m *MockInterface) GetBySwipe(ctx context.Context, userId int64, gender string) ([]entity.Profile, error) {
+func (m *MockInterface) GetBySwipe(ctx context.Context, param entity.DiscoveryParam) ([]entity.Profile, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetBySwipe", ctx, userId, gender)
+	ret := m.ctrl.Call(m, "GetBySwipe", ctx, param)
 	ret0, _ := ret[0].([]entity.Profile)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetBySwipe indicates an expected call of GetBySwipe.
-func (mr *MockInterfaceMockRecorder) GetBySwipe(ctx, userId, gender any) *gomock.Call {
+func (mr *MockInterfaceMockRecorder) GetBySwipe(ctx, param any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBySwipe", reflect.TypeOf((*MockInterface)(nil).GetBySwipe), ctx, userId, gender)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBySwipe", reflect.TypeOf((*MockInterface)(nil).GetBySwipe), ctx, param)
 }
 
 // GetByUserId mocks base method.
@@ -85,16 +100,16 @@ func (mr *MockInterfaceMockRecorder) GetByUserId(ctx, userId any) *gomock.Call {
 }
 
 // GetByUserIds mocks base method.
-func (m *MockInterface) GetByUserIds(ctx context.Context, userId []string) ([]entity.Profile, error) {
+func (m *MockInterface) GetByUserIds(ctx context.Context, viewerId int64, userId []string) ([]entity.Profile, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByUserIds", ctx, userId)
+	ret := m.ctrl.Call(m, "GetByUserIds", ctx, viewerId, userId)
 	ret0, _ := ret[0].([]entity.Profile)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetByUserIds indicates an expected call of GetByUserIds.
-func (mr *MockInterfaceMockRecorder) GetByUserIds(ctx, userId any) *gomock.Call {
+func (mr *MockInterfaceMockRecorder) GetByUserIds(ctx, viewerId, userId any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserIds", reflect.TypeOf((*MockInterface)(nil).GetByUserIds), ctx, userId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserIds", reflect.TypeOf((*MockInterface)(nil).GetByUserIds), ctx, viewerId, userId)
 }
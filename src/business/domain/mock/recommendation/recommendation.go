@@ -0,0 +1,101 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: recommendation/recommendation.go
+//
+// Generated by this command:
+//
+//	mockgen -source=recommendation/recommendation.go -destination=mock/recommendation/recommendation.go
+//
+// Package mock_recommendation is a generated GoMock package.
+package mock_recommendation
+
+import (
+	context "context"
+	entity "loverly/src/business/entity"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInterface is a mock of Interface interface.
+type MockInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockInterfaceMockRecorder
+}
+
+// MockInterfaceMockRecorder is the mock recorder for MockInterface.
+type MockInterfaceMockRecorder struct {
+	mock *MockInterface
+}
+
+// NewMockInterface creates a new mock instance.
+func NewMockInterface(ctrl *gomock.Controller) *MockInterface {
+	mock := &MockInterface{ctrl: ctrl}
+	mock.recorder = &MockInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInterface) EXPECT() *MockInterfaceMockRecorder {
+	return m.recorder
+}
+
+// DeleteExcept mocks base method.
+func (m *MockInterface) DeleteExcept(ctx context.Context, userIds []int64) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExcept", ctx, userIds)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExcept indicates an expected call of DeleteExcept.
+func (mr *MockInterfaceMockRecorder) DeleteExcept(ctx, userIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExcept", reflect.TypeOf((*MockInterface)(nil).DeleteExcept), ctx, userIds)
+}
+
+// GetByUserId mocks base method.
+func (m *MockInterface) GetByUserId(ctx context.Context, userId int64) ([]entity.Recommendation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUserId", ctx, userId)
+	ret0, _ := ret[0].([]entity.Recommendation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUserId indicates an expected call of GetByUserId.
+func (mr *MockInterfaceMockRecorder) GetByUserId(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserId", reflect.TypeOf((*MockInterface)(nil).GetByUserId), ctx, userId)
+}
+
+// Invalidate mocks base method.
+func (m *MockInterface) Invalidate(ctx context.Context, userIds ...int64) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx}
+	for _, a := range userIds {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "Invalidate", varargs...)
+}
+
+// Invalidate indicates an expected call of Invalidate.
+func (mr *MockInterfaceMockRecorder) Invalidate(ctx any, userIds ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx}, userIds...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Invalidate", reflect.TypeOf((*MockInterface)(nil).Invalidate), varargs...)
+}
+
+// Replace mocks base method.
+func (m *MockInterface) Replace(ctx context.Context, userId int64, params []entity.Recommendation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replace", ctx, userId, params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Replace indicates an expected call of Replace.
+func (mr *MockInterfaceMockRecorder) Replace(ctx, userId, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replace", reflect.TypeOf((*MockInterface)(nil).Replace), ctx, userId, params)
+}
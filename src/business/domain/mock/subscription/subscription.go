@@ -12,6 +12,7 @@ import (
 	context "context"
 	entity "loverly/src/business/entity"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -39,6 +40,21 @@ func (m *MockInterface) EXPECT() *MockInterfaceMockRecorder {
 	return m.recorder
 }
 
+// ClaimTrial mocks base method.
+func (m *MockInterface) ClaimTrial(ctx context.Context, param entity.Trial) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimTrial", ctx, param)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimTrial indicates an expected call of ClaimTrial.
+func (mr *MockInterfaceMockRecorder) ClaimTrial(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimTrial", reflect.TypeOf((*MockInterface)(nil).ClaimTrial), ctx, param)
+}
+
 // Create mocks base method.
 func (m *MockInterface) Create(ctx context.Context, param entity.Subscription) (int64, error) {
 	m.ctrl.T.Helper()
@@ -54,19 +70,19 @@ func (mr *MockInterfaceMockRecorder) Create(ctx, param any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockInterface)(nil).Create), ctx, param)
 }
 
-// GetByPlan mocks base method.
-func (m *MockInterface) GetByPlan(ctx context.Context, userId int64, plan string) (entity.Subscription, error) {
+// GetByIdForUpdate mocks base method.
+func (m *MockInterface) GetByIdForUpdate(ctx context.Context, id int64) (entity.Subscription, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByPlan", ctx, userId, plan)
+	ret := m.ctrl.Call(m, "GetByIdForUpdate", ctx, id)
 	ret0, _ := ret[0].(entity.Subscription)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetByPlan indicates an expected call of GetByPlan.
-func (mr *MockInterfaceMockRecorder) GetByPlan(ctx, userId, plan any) *gomock.Call {
+// GetByIdForUpdate indicates an expected call of GetByIdForUpdate.
+func (mr *MockInterfaceMockRecorder) GetByIdForUpdate(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByPlan", reflect.TypeOf((*MockInterface)(nil).GetByPlan), ctx, userId, plan)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByIdForUpdate", reflect.TypeOf((*MockInterface)(nil).GetByIdForUpdate), ctx, id)
 }
 
 // GetByUserId mocks base method.
@@ -83,3 +99,122 @@ func (mr *MockInterfaceMockRecorder) GetByUserId(ctx, userId any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserId", reflect.TypeOf((*MockInterface)(nil).GetByUserId), ctx, userId)
 }
+
+// GetCurrentForUpdate mocks base method.
+func (m *MockInterface) GetCurrentForUpdate(ctx context.Context, userId int64) (entity.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCurrentForUpdate", ctx, userId)
+	ret0, _ := ret[0].(entity.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCurrentForUpdate indicates an expected call of GetCurrentForUpdate.
+func (mr *MockInterfaceMockRecorder) GetCurrentForUpdate(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCurrentForUpdate", reflect.TypeOf((*MockInterface)(nil).GetCurrentForUpdate), ctx, userId)
+}
+
+// GetDue mocks base method.
+func (m *MockInterface) GetDue(ctx context.Context, limit int) ([]entity.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDue", ctx, limit)
+	ret0, _ := ret[0].([]entity.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDue indicates an expected call of GetDue.
+func (mr *MockInterfaceMockRecorder) GetDue(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDue", reflect.TypeOf((*MockInterface)(nil).GetDue), ctx, limit)
+}
+
+// GetEntitled mocks base method.
+func (m *MockInterface) GetEntitled(ctx context.Context, feature string, userIds []int64) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEntitled", ctx, feature, userIds)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEntitled indicates an expected call of GetEntitled.
+func (mr *MockInterfaceMockRecorder) GetEntitled(ctx, feature, userIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEntitled", reflect.TypeOf((*MockInterface)(nil).GetEntitled), ctx, feature, userIds)
+}
+
+// GetExpiring mocks base method.
+func (m *MockInterface) GetExpiring(ctx context.Context, warnBefore time.Duration, limit int) ([]entity.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpiring", ctx, warnBefore, limit)
+	ret0, _ := ret[0].([]entity.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpiring indicates an expected call of GetExpiring.
+func (mr *MockInterfaceMockRecorder) GetExpiring(ctx, warnBefore, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpiring", reflect.TypeOf((*MockInterface)(nil).GetExpiring), ctx, warnBefore, limit)
+}
+
+// GetFeatures mocks base method.
+func (m *MockInterface) GetFeatures(ctx context.Context, userId int64) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFeatures", ctx, userId)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFeatures indicates an expected call of GetFeatures.
+func (mr *MockInterfaceMockRecorder) GetFeatures(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFeatures", reflect.TypeOf((*MockInterface)(nil).GetFeatures), ctx, userId)
+}
+
+// HasTrial mocks base method.
+func (m *MockInterface) HasTrial(ctx context.Context, userId int64, family string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasTrial", ctx, userId, family)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HasTrial indicates an expected call of HasTrial.
+func (mr *MockInterfaceMockRecorder) HasTrial(ctx, userId, family any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasTrial", reflect.TypeOf((*MockInterface)(nil).HasTrial), ctx, userId, family)
+}
+
+// RecordChange mocks base method.
+func (m *MockInterface) RecordChange(ctx context.Context, param entity.SubscriptionChange) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordChange", ctx, param)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecordChange indicates an expected call of RecordChange.
+func (mr *MockInterfaceMockRecorder) RecordChange(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordChange", reflect.TypeOf((*MockInterface)(nil).RecordChange), ctx, param)
+}
+
+// Update mocks base method.
+func (m *MockInterface) Update(ctx context.Context, param entity.Subscription) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, param)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockInterfaceMockRecorder) Update(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockInterface)(nil).Update), ctx, param)
+}
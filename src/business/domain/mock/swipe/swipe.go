@@ -12,6 +12,7 @@ import (
 	context "context"
 	entity "loverly/src/business/entity"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -39,6 +40,21 @@ func (m *MockInterface) EXPECT() *MockInterfaceMockRecorder {
 	return m.recorder
 }
 
+// BulkCreate mocks base method.
+func (m *MockInterface) BulkCreate(ctx context.Context, params []entity.Swipe) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BulkCreate", ctx, params)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BulkCreate indicates an expected call of BulkCreate.
+func (mr *MockInterfaceMockRecorder) BulkCreate(ctx, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkCreate", reflect.TypeOf((*MockInterface)(nil).BulkCreate), ctx, params)
+}
+
 // Create mocks base method.
 func (m *MockInterface) Create(ctx context.Context, param entity.Swipe) (int64, error) {
 	m.ctrl.T.Helper()
@@ -83,3 +99,107 @@ func (mr *MockInterfaceMockRecorder) GetBySwiperId(ctx, swiperId any) *gomock.Ca
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBySwiperId", reflect.TypeOf((*MockInterface)(nil).GetBySwiperId), ctx, swiperId)
 }
+
+// GetHistory mocks base method.
+func (m *MockInterface) GetHistory(ctx context.Context, since time.Time) ([]entity.Swipe, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHistory", ctx, since)
+	ret0, _ := ret[0].([]entity.Swipe)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHistory indicates an expected call of GetHistory.
+func (mr *MockInterfaceMockRecorder) GetHistory(ctx, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistory", reflect.TypeOf((*MockInterface)(nil).GetHistory), ctx, since)
+}
+
+// GetIncoming mocks base method.
+func (m *MockInterface) GetIncoming(ctx context.Context, swipedId int64, swiperIds []int64) ([]entity.Swipe, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIncoming", ctx, swipedId, swiperIds)
+	ret0, _ := ret[0].([]entity.Swipe)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIncoming indicates an expected call of GetIncoming.
+func (mr *MockInterfaceMockRecorder) GetIncoming(ctx, swipedId, swiperIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIncoming", reflect.TypeOf((*MockInterface)(nil).GetIncoming), ctx, swipedId, swiperIds)
+}
+
+// GetOutgoing mocks base method.
+func (m *MockInterface) GetOutgoing(ctx context.Context, swiperId int64, swipedIds []int64) ([]entity.Swipe, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOutgoing", ctx, swiperId, swipedIds)
+	ret0, _ := ret[0].([]entity.Swipe)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOutgoing indicates an expected call of GetOutgoing.
+func (mr *MockInterfaceMockRecorder) GetOutgoing(ctx, swiperId, swipedIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOutgoing", reflect.TypeOf((*MockInterface)(nil).GetOutgoing), ctx, swiperId, swipedIds)
+}
+
+// GetReceivedLike mocks base method.
+func (m *MockInterface) GetReceivedLike(ctx context.Context, id, swipedId int64) (entity.Swipe, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReceivedLike", ctx, id, swipedId)
+	ret0, _ := ret[0].(entity.Swipe)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReceivedLike indicates an expected call of GetReceivedLike.
+func (mr *MockInterfaceMockRecorder) GetReceivedLike(ctx, id, swipedId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReceivedLike", reflect.TypeOf((*MockInterface)(nil).GetReceivedLike), ctx, id, swipedId)
+}
+
+// GetReceivedLikes mocks base method.
+func (m *MockInterface) GetReceivedLikes(ctx context.Context, swipedId int64, passCoolOff time.Duration) ([]entity.Swipe, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReceivedLikes", ctx, swipedId, passCoolOff)
+	ret0, _ := ret[0].([]entity.Swipe)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReceivedLikes indicates an expected call of GetReceivedLikes.
+func (mr *MockInterfaceMockRecorder) GetReceivedLikes(ctx, swipedId, passCoolOff any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReceivedLikes", reflect.TypeOf((*MockInterface)(nil).GetReceivedLikes), ctx, swipedId, passCoolOff)
+}
+
+// InvalidateUser mocks base method.
+func (m *MockInterface) InvalidateUser(ctx context.Context, userId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InvalidateUser", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InvalidateUser indicates an expected call of InvalidateUser.
+func (mr *MockInterfaceMockRecorder) InvalidateUser(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateUser", reflect.TypeOf((*MockInterface)(nil).InvalidateUser), ctx, userId)
+}
+
+// Rewind mocks base method.
+func (m *MockInterface) Rewind(ctx context.Context, swiperId int64) (entity.Swipe, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rewind", ctx, swiperId)
+	ret0, _ := ret[0].(entity.Swipe)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Rewind indicates an expected call of Rewind.
+func (mr *MockInterfaceMockRecorder) Rewind(ctx, swiperId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rewind", reflect.TypeOf((*MockInterface)(nil).Rewind), ctx, swiperId)
+}
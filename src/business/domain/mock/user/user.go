@@ -68,3 +68,85 @@ func (mr *MockInterfaceMockRecorder) GetByEmail(ctx, email any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByEmail", reflect.TypeOf((*MockInterface)(nil).GetByEmail), ctx, email)
 }
+
+// GetById mocks base method.
+func (m *MockInterface) GetById(ctx context.Context, id int64) (entity.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetById", ctx, id)
+	ret0, _ := ret[0].(entity.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetById indicates an expected call of GetById.
+func (mr *MockInterfaceMockRecorder) GetById(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetById", reflect.TypeOf((*MockInterface)(nil).GetById), ctx, id)
+}
+
+// GetByIdForUpdate mocks base method.
+func (m *MockInterface) GetByIdForUpdate(ctx context.Context, id int64) (entity.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByIdForUpdate", ctx, id)
+	ret0, _ := ret[0].(entity.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByIdForUpdate indicates an expected call of GetByIdForUpdate.
+func (mr *MockInterfaceMockRecorder) GetByIdForUpdate(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByIdForUpdate", reflect.TypeOf((*MockInterface)(nil).GetByIdForUpdate), ctx, id)
+}
+
+// Invalidate mocks base method.
+func (m *MockInterface) Invalidate(ctx context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Invalidate", ctx)
+}
+
+// Invalidate indicates an expected call of Invalidate.
+func (mr *MockInterfaceMockRecorder) Invalidate(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Invalidate", reflect.TypeOf((*MockInterface)(nil).Invalidate), ctx)
+}
+
+// InvalidateStatus mocks base method.
+func (m *MockInterface) InvalidateStatus(ctx context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "InvalidateStatus", ctx)
+}
+
+// InvalidateStatus indicates an expected call of InvalidateStatus.
+func (mr *MockInterfaceMockRecorder) InvalidateStatus(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateStatus", reflect.TypeOf((*MockInterface)(nil).InvalidateStatus), ctx)
+}
+
+// UpdatePassword mocks base method.
+func (m *MockInterface) UpdatePassword(ctx context.Context, param entity.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePassword", ctx, param)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockInterfaceMockRecorder) UpdatePassword(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockInterface)(nil).UpdatePassword), ctx, param)
+}
+
+// UpdateStatus mocks base method.
+func (m *MockInterface) UpdateStatus(ctx context.Context, param entity.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStatus", ctx, param)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateStatus indicates an expected call of UpdateStatus.
+func (mr *MockInterfaceMockRecorder) UpdateStatus(ctx, param any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockInterface)(nil).UpdateStatus), ctx, param)
+}
@@ -0,0 +1,113 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"loverly/lib/log"
+	"loverly/lib/redis"
+	"loverly/src/business/entity"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Interface fans notifications out to every instance over redis pub/sub, nothing is stored
+// so users that aren't connected simply miss them
+type Interface interface {
+	Publish(ctx context.Context, param entity.Notification) error
+	Subscribe(ctx context.Context) (<-chan entity.Notification, error)
+
+	// Claim marks key as pushed for ttl and tells whether it wasn't already, Release undoes a claim whose push failed
+	Claim(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, key string) error
+
+	// CreateTicket lets userId open one websocket with ticket within ttl, RedeemTicket burns it and returns 0 when it's unknown or used
+	CreateTicket(ctx context.Context, ticket string, userId int64, ttl time.Duration) error
+	RedeemTicket(ctx context.Context, ticket string) (int64, error)
+}
+
+type notification struct {
+	log log.Interface
+	rds redis.Redis
+}
+
+const (
+	ChannelKey = "notifications"
+	ClaimKey   = "notifications:claims:%s"
+	TicketKey  = "notifications:tickets:%s"
+)
+
+func Init(ctx context.Context, log log.Interface, rds redis.Redis) Interface {
+	return &notification{
+		log: log,
+		rds: rds,
+	}
+}
+
+func (n *notification) Publish(ctx context.Context, param entity.Notification) error {
+	val, err := json.Marshal(param)
+	if err != nil {
+		n.log.Error(ctx, fmt.Sprintf("error when marshal notification: %v", err))
+		return err
+	}
+
+	return n.rds.Publish(ctx, ChannelKey, string(val))
+}
+
+// Subscribe receives what every instance publishes until ctx is done
+func (n *notification) Subscribe(ctx context.Context) (<-chan entity.Notification, error) {
+	payloads, err := n.rds.Subscribe(ctx, ChannelKey)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan entity.Notification)
+	go func() {
+		defer close(out)
+
+		for payload := range payloads {
+			var param entity.Notification
+			if err := json.Unmarshal([]byte(payload), &param); err != nil {
+				n.log.Error(ctx, fmt.Sprintf("error when unmarshal notification: %v", err))
+				continue
+			}
+
+			select {
+			case out <- param:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (n *notification) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return n.rds.SetNX(ctx, fmt.Sprintf(ClaimKey, key), "1", ttl)
+}
+
+func (n *notification) Release(ctx context.Context, key string) error {
+	return n.rds.Del(ctx, fmt.Sprintf(ClaimKey, key))
+}
+
+func (n *notification) CreateTicket(ctx context.Context, ticket string, userId int64, ttl time.Duration) error {
+	return n.rds.Set(ctx, fmt.Sprintf(TicketKey, ticket), strconv.FormatInt(userId, 10), ttl)
+}
+
+func (n *notification) RedeemTicket(ctx context.Context, ticket string) (int64, error) {
+	val, err := n.rds.GetDel(ctx, fmt.Sprintf(TicketKey, ticket))
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	userId, _ := strconv.ParseInt(val, 10, 64)
+
+	return userId, nil
+}
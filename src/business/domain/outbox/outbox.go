@@ -0,0 +1,197 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"loverly/lib/atomic"
+	"loverly/lib/log"
+	"loverly/lib/redis"
+	"loverly/src/business/entity"
+
+	atomicSqlx "loverly/lib/atomic/sqlx"
+	sqlxUtils "loverly/lib/sqlx"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Interface stores domain events next to the change they describe, emit them inside the same atomic session
+type Interface interface {
+	Emit(ctx context.Context, eventType string, userId int64, payload interface{}) (int64, error)
+	GetPending(ctx context.Context, limit int, maxAttempts int) ([]entity.Event, error)
+	MarkPublished(ctx context.Context, ids []int64) error
+	MarkFailed(ctx context.Context, id int64, reason string) error
+}
+
+type outbox struct {
+	log               log.Interface
+	leaderDB          *sqlx.DB
+	followerDB        *sqlx.DB
+	rds               redis.Redis
+	masterStmts       []*sqlx.Stmt
+	slaveStmts        []*sqlx.Stmt
+	masterNamedStmpts []*sqlx.NamedStmt
+}
+
+const (
+	AllFields = `id, type, user_id, payload, attempts, last_error, published_at, created_at, updated_at, deleted_at`
+
+	GetPending = iota
+
+	Create
+	MarkPublished
+	MarkFailed
+)
+
+var (
+	masterQueries = []string{
+		GetPending: fmt.Sprintf(`SELECT %s FROM outbox WHERE published_at IS NULL AND deleted_at IS NULL AND attempts < $2
+		ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`, AllFields),
+		MarkPublished: `UPDATE outbox SET published_at = now(), updated_at = now() WHERE id = ANY($1)`,
+		MarkFailed:    `UPDATE outbox SET attempts = attempts + 1, last_error = $2, updated_at = now() WHERE id = $1`,
+	}
+
+	masterNamedQueries = []string{
+		Create: `INSERT INTO outbox (type, user_id, payload, created_at, updated_at)
+		VALUES (:type, :user_id, :payload, now(), now()) RETURNING id`,
+	}
+
+	slaveQueries = []string{}
+)
+
+func Init(ctx context.Context, log log.Interface, leader *sqlx.DB, follower *sqlx.DB, rds redis.Redis) Interface {
+	stmpts, err := sqlxUtils.PrepareQueries(leader, masterQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	namedStmpts, err := sqlxUtils.PrepareNamedQueries(leader, masterNamedQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareNamedQueries err: %v", err))
+		return nil
+	}
+
+	slaveStmpts, err := sqlxUtils.PrepareQueries(follower, slaveQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	return &outbox{
+		log:               log,
+		leaderDB:          leader,
+		followerDB:        follower,
+		rds:               rds,
+		masterStmts:       stmpts,
+		slaveStmts:        slaveStmpts,
+		masterNamedStmpts: namedStmpts,
+	}
+}
+
+func (o *outbox) Emit(ctx context.Context, eventType string, userId int64, payload interface{}) (int64, error) {
+	var result entity.Event
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		o.log.Error(ctx, fmt.Sprintf("error when marshal event payload: %v", err))
+		return 0, err
+	}
+
+	namedStmt, err := o.getNamedStatement(ctx, Create)
+	if err != nil {
+		o.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return 0, err
+	}
+
+	if err = namedStmt.GetContext(ctx, &result, entity.Event{Type: eventType, UserId: userId, Payload: raw}); err != nil {
+		o.log.Error(ctx, fmt.Sprintf("CreateOutbox err: %v", err))
+		return 0, err
+	}
+
+	return result.ID, nil
+}
+
+// GetPending locks the oldest unpublished events until the surrounding atomic session ends,
+// concurrent relays skip them instead of waiting
+func (o *outbox) GetPending(ctx context.Context, limit int, maxAttempts int) ([]entity.Event, error) {
+	var results []entity.Event
+
+	stmt, err := o.getStatement(ctx, GetPending)
+	if err != nil {
+		o.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return results, err
+	}
+
+	if err = stmt.SelectContext(ctx, &results, limit, maxAttempts); err != nil {
+		o.log.Error(ctx, fmt.Sprintf("GetPending err: %v", err))
+		return results, err
+	}
+
+	return results, nil
+}
+
+func (o *outbox) MarkPublished(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	stmt, err := o.getStatement(ctx, MarkPublished)
+	if err != nil {
+		o.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return err
+	}
+
+	if _, err = stmt.ExecContext(ctx, pq.Array(ids)); err != nil {
+		o.log.Error(ctx, fmt.Sprintf("MarkPublished err: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+func (o *outbox) MarkFailed(ctx context.Context, id int64, reason string) error {
+	stmt, err := o.getStatement(ctx, MarkFailed)
+	if err != nil {
+		o.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return err
+	}
+
+	if _, err = stmt.ExecContext(ctx, id, reason); err != nil {
+		o.log.Error(ctx, fmt.Sprintf("MarkFailed err: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+func (o *outbox) getStatement(ctx context.Context, queryId int) (*sqlx.Stmt, error) {
+	var err error
+	var statement *sqlx.Stmt
+	if atomicSessionCtx, ok := ctx.(*atomic.AtomicSessionContext); ok {
+		if atomicSession, ok := atomicSessionCtx.AtomicSession.(*atomicSqlx.SqlxAtomicSession); ok {
+			statement, err = atomicSession.Tx().PreparexContext(ctx, masterQueries[queryId])
+		} else {
+			err = atomic.InvalidAtomicSessionProvider
+		}
+	} else {
+		statement = o.masterStmts[queryId]
+	}
+	return statement, err
+}
+
+func (o *outbox) getNamedStatement(ctx context.Context, queryId int) (*sqlx.NamedStmt, error) {
+	var err error
+	var namedStmt *sqlx.NamedStmt
+	if atomicSessionCtx, ok := ctx.(*atomic.AtomicSessionContext); ok {
+		if atomicSession, ok := atomicSessionCtx.AtomicSession.(*atomicSqlx.SqlxAtomicSession); ok {
+			namedStmt, err = atomicSession.Tx().PrepareNamedContext(ctx, masterNamedQueries[queryId])
+		} else {
+			err = atomic.InvalidAtomicSessionProvider
+		}
+	} else {
+		namedStmt = o.masterNamedStmpts[queryId]
+	}
+	return namedStmt, err
+}
@@ -0,0 +1,278 @@
+package payment
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"loverly/lib/atomic"
+	"loverly/lib/log"
+	"loverly/lib/redis"
+	"loverly/src/business/entity"
+
+	atomicSqlx "loverly/lib/atomic/sqlx"
+	sqlxUtils "loverly/lib/sqlx"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Interface keeps the checkouts and the provider webhooks applied to them, nothing is cached since the
+// status changes behind the user's back
+type Interface interface {
+	Get(ctx context.Context, id int64, userId int64) (entity.Payment, error)
+	GetByProviderRefForUpdate(ctx context.Context, provider string, providerRef string) (entity.Payment, error)
+	GetByIdForUpdate(ctx context.Context, id int64) (entity.Payment, error)
+	// GetRefundDue reads payments to refund without locking them, lock each with GetByIdForUpdate once refunded
+	GetRefundDue(ctx context.Context, limit int) ([]entity.Payment, error)
+	Create(ctx context.Context, param entity.Payment) (int64, error)
+	Update(ctx context.Context, param entity.Payment) error
+	RecordEvent(ctx context.Context, param entity.PaymentEvent) (int64, error)
+	// FailPendingChange fails the upgrade of the subscription still waiting to be paid, there is one at most
+	FailPendingChange(ctx context.Context, subscriptionId int64) error
+}
+
+type payment struct {
+	log               log.Interface
+	leaderDB          *sqlx.DB
+	followerDB        *sqlx.DB
+	rds               redis.Redis
+	masterStmts       []*sqlx.Stmt
+	slaveStmts        []*sqlx.Stmt
+	masterNamedStmpts []*sqlx.NamedStmt
+}
+
+const (
+	// a payment for boosts has no plan
+	AllFields = `id, user_id, COALESCE(plan, '') AS plan, boosts, amount, credit, coupon_id, discount, extra_days, trial_days,
+	currency, provider, provider_ref, checkout_url, status, subscription_id, from_plan, period_end, paid_at, refund_ref, created_at,
+	updated_at, deleted_at`
+
+	Get = iota
+	GetByProviderRefForUpdate
+	GetByIdForUpdate
+	GetRefundDue
+
+	Create
+	Update
+	RecordEvent
+	FailPendingChange
+)
+
+var (
+	masterQueries = []string{
+		GetByProviderRefForUpdate: fmt.Sprintf(`SELECT %s FROM payments WHERE provider = $1 AND provider_ref = $2
+		AND deleted_at IS NULL FOR UPDATE`, AllFields),
+		GetByIdForUpdate: fmt.Sprintf("SELECT %s FROM payments WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", AllFields),
+		// the refund is made before its payment is locked, reading from the leader spares a refund the replica already saw made
+		GetRefundDue: fmt.Sprintf(`SELECT %s FROM payments WHERE status = 'refund_due' AND deleted_at IS NULL
+		ORDER BY updated_at LIMIT $1`, AllFields),
+	}
+
+	masterNamedQueries = []string{
+		Create: `INSERT INTO payments (user_id, plan, boosts, amount, credit, coupon_id, discount, extra_days, trial_days, currency,
+		provider, subscription_id, from_plan, period_end, created_at, updated_at) VALUES (:user_id, NULLIF(:plan, ''), :boosts,
+		:amount, :credit, :coupon_id, :discount, :extra_days, :trial_days, :currency, :provider, :subscription_id, :from_plan,
+		:period_end, now(), now()) RETURNING id`,
+		FailPendingChange: `UPDATE payments SET status = 'failed', updated_at = now() WHERE subscription_id = :subscription_id
+		AND status = 'pending' AND from_plan IS NOT NULL AND deleted_at IS NULL`,
+		Update: `UPDATE payments SET provider_ref = :provider_ref, checkout_url = :checkout_url, status = :status,
+		subscription_id = :subscription_id, paid_at = :paid_at, refund_ref = :refund_ref, updated_at = now()
+		WHERE id = :id AND deleted_at IS NULL`,
+		RecordEvent: `INSERT INTO payment_events (provider, event_id, type, payment_id, payload, created_at, updated_at)
+		VALUES (:provider, :event_id, :type, :payment_id, :payload, now(), now())
+		ON CONFLICT ON CONSTRAINT unique_payment_events_event_id DO NOTHING RETURNING id`,
+	}
+
+	slaveQueries = []string{
+		Get: fmt.Sprintf("SELECT %s FROM payments WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL", AllFields),
+	}
+)
+
+func Init(ctx context.Context, log log.Interface, leader *sqlx.DB, follower *sqlx.DB, rds redis.Redis) Interface {
+	stmpts, err := sqlxUtils.PrepareQueries(leader, masterQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	namedStmpts, err := sqlxUtils.PrepareNamedQueries(leader, masterNamedQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareNamedQueries err: %v", err))
+		return nil
+	}
+
+	slaveStmpts, err := sqlxUtils.PrepareQueries(follower, slaveQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	return &payment{
+		log:               log,
+		leaderDB:          leader,
+		followerDB:        follower,
+		rds:               rds,
+		masterStmts:       stmpts,
+		slaveStmts:        slaveStmpts,
+		masterNamedStmpts: namedStmpts,
+	}
+}
+
+// Get only returns payments of userId
+func (p *payment) Get(ctx context.Context, id int64, userId int64) (entity.Payment, error) {
+	var result entity.Payment
+
+	if err := p.slaveStmts[Get].GetContext(ctx, &result, id, userId); err != nil {
+		p.log.Error(ctx, fmt.Sprintf("Get err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
+
+// GetByProviderRefForUpdate locks the payment until the atomic session ends, webhooks about the same
+// payment are applied one after the other
+func (p *payment) GetByProviderRefForUpdate(ctx context.Context, provider string, providerRef string) (entity.Payment, error) {
+	var result entity.Payment
+
+	stmt, err := p.getStatement(ctx, GetByProviderRefForUpdate)
+	if err != nil {
+		p.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return result, err
+	}
+
+	if err = stmt.GetContext(ctx, &result, provider, providerRef); err != nil {
+		p.log.Error(ctx, fmt.Sprintf("GetByProviderRefForUpdate err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
+
+// GetByIdForUpdate locks the payment until the atomic session ends
+func (p *payment) GetByIdForUpdate(ctx context.Context, id int64) (entity.Payment, error) {
+	var result entity.Payment
+
+	stmt, err := p.getStatement(ctx, GetByIdForUpdate)
+	if err != nil {
+		p.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return result, err
+	}
+
+	if err = stmt.GetContext(ctx, &result, id); err != nil {
+		p.log.Error(ctx, fmt.Sprintf("GetByIdForUpdate err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (p *payment) GetRefundDue(ctx context.Context, limit int) ([]entity.Payment, error) {
+	results := []entity.Payment{}
+
+	if err := p.masterStmts[GetRefundDue].SelectContext(ctx, &results, limit); err != nil {
+		p.log.Error(ctx, fmt.Sprintf("GetRefundDue err: %v", err))
+		return results, err
+	}
+
+	return results, nil
+}
+
+func (p *payment) Create(ctx context.Context, param entity.Payment) (int64, error) {
+	var result entity.Payment
+
+	namedStmt, err := p.getNamedStatement(ctx, Create)
+	if err != nil {
+		p.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return 0, err
+	}
+
+	if err = namedStmt.GetContext(ctx, &result, param); err != nil {
+		p.log.Error(ctx, fmt.Sprintf("Create err: %v", err))
+		return 0, err
+	}
+
+	return result.ID, nil
+}
+
+func (p *payment) Update(ctx context.Context, param entity.Payment) error {
+	namedStmt, err := p.getNamedStatement(ctx, Update)
+	if err != nil {
+		p.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return err
+	}
+
+	if _, err = namedStmt.ExecContext(ctx, param); err != nil {
+		p.log.Error(ctx, fmt.Sprintf("Update err: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// RecordEvent returns 0 when the provider already delivered the event
+func (p *payment) RecordEvent(ctx context.Context, param entity.PaymentEvent) (int64, error) {
+	var result entity.PaymentEvent
+
+	namedStmt, err := p.getNamedStatement(ctx, RecordEvent)
+	if err != nil {
+		p.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return 0, err
+	}
+
+	if err = namedStmt.GetContext(ctx, &result, param); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		p.log.Error(ctx, fmt.Sprintf("RecordEvent err: %v", err))
+		return 0, err
+	}
+
+	return result.ID, nil
+}
+
+func (p *payment) FailPendingChange(ctx context.Context, subscriptionId int64) error {
+	namedStmt, err := p.getNamedStatement(ctx, FailPendingChange)
+	if err != nil {
+		p.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return err
+	}
+
+	param := entity.Payment{SubscriptionId: sql.NullInt64{Int64: subscriptionId, Valid: true}}
+	if _, err = namedStmt.ExecContext(ctx, param); err != nil {
+		p.log.Error(ctx, fmt.Sprintf("FailPendingChange err: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+func (p *payment) getStatement(ctx context.Context, queryId int) (*sqlx.Stmt, error) {
+	var err error
+	var statement *sqlx.Stmt
+	if atomicSessionCtx, ok := ctx.(*atomic.AtomicSessionContext); ok {
+		if atomicSession, ok := atomicSessionCtx.AtomicSession.(*atomicSqlx.SqlxAtomicSession); ok {
+			statement, err = atomicSession.Tx().PreparexContext(ctx, masterQueries[queryId])
+		} else {
+			err = atomic.InvalidAtomicSessionProvider
+		}
+	} else {
+		statement = p.masterStmts[queryId]
+	}
+	return statement, err
+}
+
+func (p *payment) getNamedStatement(ctx context.Context, queryId int) (*sqlx.NamedStmt, error) {
+	var err error
+	var namedStmt *sqlx.NamedStmt
+	if atomicSessionCtx, ok := ctx.(*atomic.AtomicSessionContext); ok {
+		if atomicSession, ok := atomicSessionCtx.AtomicSession.(*atomicSqlx.SqlxAtomicSession); ok {
+			namedStmt, err = atomicSession.Tx().PrepareNamedContext(ctx, masterNamedQueries[queryId])
+		} else {
+			err = atomic.InvalidAtomicSessionProvider
+		}
+	} else {
+		namedStmt = p.masterNamedStmpts[queryId]
+	}
+	return namedStmt, err
+}
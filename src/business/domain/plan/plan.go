@@ -0,0 +1,102 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"loverly/lib/log"
+	"loverly/lib/redis"
+	"loverly/src/business/entity"
+
+	sqlxUtils "loverly/lib/sqlx"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Interface reads the plan catalog. Plans are edited straight in the table, so they are not cached
+type Interface interface {
+	GetList(ctx context.Context) ([]entity.Plan, error)
+	GetByCode(ctx context.Context, code string) (entity.Plan, error)
+}
+
+type plan struct {
+	log               log.Interface
+	leaderDB          *sqlx.DB
+	followerDB        *sqlx.DB
+	rds               redis.Redis
+	masterStmts       []*sqlx.Stmt
+	slaveStmts        []*sqlx.Stmt
+	masterNamedStmpts []*sqlx.NamedStmt
+}
+
+const (
+	AllFields = `id, code, name, price, currency, duration_days, features, family, trial_days, active, sort_order, created_at,
+	updated_at, deleted_at`
+
+	GetList = iota
+	GetByCode
+)
+
+var (
+	masterQueries = []string{}
+
+	masterNamedQueries = []string{}
+
+	slaveQueries = []string{
+		GetList:   fmt.Sprintf("SELECT %s FROM plans WHERE active AND deleted_at IS NULL ORDER BY sort_order, price, id", AllFields),
+		GetByCode: fmt.Sprintf("SELECT %s FROM plans WHERE code = $1 AND active AND deleted_at IS NULL", AllFields),
+	}
+)
+
+func Init(ctx context.Context, log log.Interface, leader *sqlx.DB, follower *sqlx.DB, rds redis.Redis) Interface {
+	stmpts, err := sqlxUtils.PrepareQueries(leader, masterQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	namedStmpts, err := sqlxUtils.PrepareNamedQueries(leader, masterNamedQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareNamedQueries err: %v", err))
+		return nil
+	}
+
+	slaveStmpts, err := sqlxUtils.PrepareQueries(follower, slaveQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	return &plan{
+		log:               log,
+		leaderDB:          leader,
+		followerDB:        follower,
+		rds:               rds,
+		masterStmts:       stmpts,
+		slaveStmts:        slaveStmpts,
+		masterNamedStmpts: namedStmpts,
+	}
+}
+
+// GetList returns the active plans in the order they are shown
+func (p *plan) GetList(ctx context.Context) ([]entity.Plan, error) {
+	var results []entity.Plan
+
+	if err := p.slaveStmts[GetList].SelectContext(ctx, &results); err != nil {
+		p.log.Error(ctx, fmt.Sprintf("GetList err: %v", err))
+		return results, err
+	}
+
+	return results, nil
+}
+
+// GetByCode returns sql.ErrNoRows for unknown and retired plans
+func (p *plan) GetByCode(ctx context.Context, code string) (entity.Plan, error) {
+	var result entity.Plan
+
+	if err := p.slaveStmts[GetByCode].GetContext(ctx, &result, code); err != nil {
+		p.log.Error(ctx, fmt.Sprintf("GetByCode err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
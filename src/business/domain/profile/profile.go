@@ -6,6 +6,9 @@ import (
 	"loverly/lib/atomic"
 	"loverly/lib/log"
 	"loverly/lib/redis"
+	"loverly/src/business/domain/abuse"
+	"loverly/src/business/domain/block"
+	"loverly/src/business/domain/user"
 	"loverly/src/business/entity"
 	"strconv"
 	"strings"
@@ -14,12 +17,15 @@ import (
 	sqlxUtils "loverly/lib/sqlx"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type Interface interface {
 	GetByUserId(ctx context.Context, userId int64) (entity.Profile, error)
-	GetByUserIds(ctx context.Context, userId []string) ([]entity.Profile, error)
-	GetBySwipe(ctx context.Context, userId int64, gender string) ([]entity.Profile, error)
+	GetByUserIds(ctx context.Context, viewerId int64, userId []string) ([]entity.Profile, error)
+	GetBySwipe(ctx context.Context, param entity.DiscoveryParam) ([]entity.Profile, error)
+	// GetAvailableIds are the users among userIds that can still be shown to the viewer, never cached
+	GetAvailableIds(ctx context.Context, viewerId int64, userIds []int64) ([]int64, error)
 	Create(ctx context.Context, param entity.Profile) (int64, error)
 }
 
@@ -39,12 +45,13 @@ const (
 	GetBySwipe = iota
 	GetByUserId
 	GetByUserIds
+	GetAvailableIds
 
 	Create
 
 	GetBySwipedKey  = "profiles:getbyswipe:%d:%s"
 	GetByUserIdKey  = "profiles:getbyuserid:%d"
-	GetByUserIdsKey = "profiles:getbyuserids:%s"
+	GetByUserIdsKey = "profiles:getbyuserids:%d:%s"
 	DeleteKey       = "profiles:*"
 )
 
@@ -57,9 +64,24 @@ var (
 	}
 
 	slaveQueries = []string{
-		GetBySwipe:   fmt.Sprintf("SELECT %s FROM profiles WHERE user_id NOT IN (SELECT swiped_id FROM swipes WHERE swiper_id = $1 and DATE(created_at) = CURRENT_DATE) AND gender = $2 AND deleted_at IS NULL", AllFields),
-		GetByUserId:  fmt.Sprintf("SELECT %s FROM profiles WHERE user_id = $1 AND deleted_at IS NULL", AllFields),
-		GetByUserIds: fmt.Sprintf("SELECT %s FROM profiles WHERE user_id = ANY($1) AND deleted_at IS NULL", AllFields),
+		// liked users and matches (including unmatched ones) stay excluded for good, passes in
+		// either direction only until the cool-off period has elapsed
+		GetBySwipe: fmt.Sprintf(`SELECT %s FROM profiles p WHERE p.user_id <> $1 AND p.gender = $2 AND p.deleted_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM swipes s WHERE s.swiper_id = $1 AND s.swiped_id = p.user_id AND s.deleted_at IS NULL
+			AND (s.direction = 'right' OR s.updated_at > now() - make_interval(secs => $3)))
+		AND NOT EXISTS (SELECT 1 FROM swipes s WHERE s.swiper_id = p.user_id AND s.swiped_id = $1 AND s.deleted_at IS NULL
+			AND s.direction = 'left' AND s.updated_at > now() - make_interval(secs => $3))
+		AND NOT EXISTS (SELECT 1 FROM matchs m WHERE (m.user_id_1 = $1 AND m.user_id_2 = p.user_id) OR (m.user_id_1 = p.user_id AND m.user_id_2 = $1))
+		AND %s AND %s AND NOT %s`, AllFields, fmt.Sprintf(block.NotBlocked, "$1", "p.user_id"), fmt.Sprintf(user.Available, "p.user_id"),
+			fmt.Sprintf(abuse.ShadowLimited, "p.user_id")),
+		// unfiltered, only used for the user's own profile and to check that a user exists
+		GetByUserId: fmt.Sprintf("SELECT %s FROM profiles WHERE user_id = $1 AND deleted_at IS NULL", AllFields),
+		// profiles as seen by the viewer, users blocked in either direction, suspended or banned are left out
+		GetByUserIds: fmt.Sprintf("SELECT %s FROM profiles p WHERE p.user_id = ANY($2) AND p.deleted_at IS NULL AND %s AND %s",
+			AllFields, fmt.Sprintf(block.NotBlocked, "$1", "p.user_id"), fmt.Sprintf(user.Available, "p.user_id")),
+		// decks are built ahead, their users may have been suspended, banned, shadow-limited or blocked since
+		GetAvailableIds: fmt.Sprintf("SELECT p.user_id FROM profiles p WHERE p.user_id = ANY($2) AND p.deleted_at IS NULL AND %s AND %s AND NOT %s",
+			fmt.Sprintf(block.NotBlocked, "$1", "p.user_id"), fmt.Sprintf(user.Available, "p.user_id"), fmt.Sprintf(abuse.ShadowLimited, "p.user_id")),
 	}
 )
 
@@ -111,12 +133,12 @@ func (p *profile) GetByUserId(ctx context.Context, userId int64) (entity.Profile
 	return profile, nil
 }
 
-func (p *profile) GetByUserIds(ctx context.Context, userId []string) ([]entity.Profile, error) {
+func (p *profile) GetByUserIds(ctx context.Context, viewerId int64, userId []string) ([]entity.Profile, error) {
 	var profiles []entity.Profile
 
 	userIds := fmt.Sprintf("{%s}", strings.Join(userId, ","))
-	err := p.rds.WithCache(ctx, fmt.Sprintf(GetByUserIdsKey, userIds), &profiles, func() (interface{}, error) {
-		if err := p.slaveStmts[GetByUserIds].SelectContext(ctx, &profiles, userIds); err != nil {
+	err := p.rds.WithCache(ctx, fmt.Sprintf(GetByUserIdsKey, viewerId, userIds), &profiles, func() (interface{}, error) {
+		if err := p.slaveStmts[GetByUserIds].SelectContext(ctx, &profiles, viewerId, userIds); err != nil {
 			return profiles, err
 		}
 
@@ -130,11 +152,11 @@ func (p *profile) GetByUserIds(ctx context.Context, userId []string) ([]entity.P
 	return profiles, nil
 }
 
-func (p *profile) GetBySwipe(ctx context.Context, userId int64, gender string) ([]entity.Profile, error) {
+func (p *profile) GetBySwipe(ctx context.Context, param entity.DiscoveryParam) ([]entity.Profile, error) {
 	var profiles []entity.Profile
 
-	err := p.rds.WithCache(ctx, fmt.Sprintf(GetBySwipedKey, userId, gender), &profiles, func() (interface{}, error) {
-		if err := p.slaveStmts[GetBySwipe].SelectContext(ctx, &profiles, userId, gender); err != nil {
+	err := p.rds.WithCache(ctx, fmt.Sprintf(GetBySwipedKey, param.UserId, param.Gender), &profiles, func() (interface{}, error) {
+		if err := p.slaveStmts[GetBySwipe].SelectContext(ctx, &profiles, param.UserId, param.Gender, param.PassCoolOff.Seconds()); err != nil {
 			return profiles, err
 		}
 
@@ -148,6 +170,17 @@ func (p *profile) GetBySwipe(ctx context.Context, userId int64, gender string) (
 	return profiles, nil
 }
 
+func (p *profile) GetAvailableIds(ctx context.Context, viewerId int64, userIds []int64) ([]int64, error) {
+	var ids []int64
+
+	if err := p.slaveStmts[GetAvailableIds].SelectContext(ctx, &ids, viewerId, pq.Array(userIds)); err != nil {
+		p.log.Error(ctx, fmt.Sprintf("GetAvailableIds err: %v", err))
+		return ids, err
+	}
+
+	return ids, nil
+}
+
 func (p *profile) Create(ctx context.Context, param entity.Profile) (int64, error) {
 	var profile entity.Profile
 
@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"loverly/lib/log"
+	"loverly/lib/redis"
+	"loverly/src/business/entity"
+	"time"
+)
+
+// Interface counts requests per policy and key in fixed windows, they only live in redis
+type Interface interface {
+	Hit(ctx context.Context, policy, key string, window time.Duration, now time.Time) (entity.RateLimitCount, error)
+}
+
+type ratelimit struct {
+	log log.Interface
+	rds redis.Redis
+}
+
+const (
+	CounterKey = "ratelimits:%s:%s:%d"
+)
+
+func Init(ctx context.Context, log log.Interface, rds redis.Redis) Interface {
+	return &ratelimit{
+		log: log,
+		rds: rds,
+	}
+}
+
+// Hit counts the request in the window containing now, a counter is kept for two windows so the next one can read it
+func (r *ratelimit) Hit(ctx context.Context, policy, key string, window time.Duration, now time.Time) (entity.RateLimitCount, error) {
+	start := now.Truncate(window)
+	current := fmt.Sprintf(CounterKey, policy, key, start.Unix())
+	previous := fmt.Sprintf(CounterKey, policy, key, start.Add(-window).Unix())
+
+	cur, prev, err := r.rds.IncrWindow(ctx, current, previous, 2*window)
+	if err != nil {
+		return entity.RateLimitCount{}, err
+	}
+
+	return entity.RateLimitCount{
+		Current:     cur,
+		Previous:    prev,
+		WindowStart: start,
+	}, nil
+}
@@ -0,0 +1,182 @@
+package recommendation
+
+import (
+	"context"
+	"fmt"
+	"loverly/lib/atomic"
+	"loverly/lib/log"
+	"loverly/lib/redis"
+	"loverly/src/business/entity"
+
+	atomicSqlx "loverly/lib/atomic/sqlx"
+	sqlxUtils "loverly/lib/sqlx"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Interface interface {
+	GetByUserId(ctx context.Context, userId int64) ([]entity.Recommendation, error)
+	// Replace swaps the whole candidate list of the user, run it inside an atomic session and call Invalidate once
+	// it is committed
+	Replace(ctx context.Context, userId int64, params []entity.Recommendation) error
+	// DeleteExcept drops the lists of everyone but userIds and returns whose were dropped, call Invalidate with them
+	DeleteExcept(ctx context.Context, userIds []int64) ([]int64, error)
+	// Invalidate isn't part of Replace, a read racing the transaction would cache the old list again
+	Invalidate(ctx context.Context, userIds ...int64)
+}
+
+type recommendation struct {
+	log               log.Interface
+	leaderDB          *sqlx.DB
+	followerDB        *sqlx.DB
+	rds               redis.Redis
+	masterStmts       []*sqlx.Stmt
+	slaveStmts        []*sqlx.Stmt
+	masterNamedStmpts []*sqlx.NamedStmt
+}
+
+const (
+	AllFields = `id, user_id, candidate_id, score, created_at, updated_at, deleted_at`
+
+	GetByUserId = iota
+
+	DeleteByUserId
+	BulkCreate
+	DeleteExcept
+
+	GetByUserIdKey = "recommendations:getbyuserid:%d"
+	DeleteKey      = "recommendations:*"
+)
+
+var (
+	masterQueries = []string{
+		DeleteByUserId: `DELETE FROM recommendations WHERE user_id = $1`,
+		BulkCreate: `INSERT INTO recommendations (user_id, candidate_id, score, created_at, updated_at)
+		SELECT $1, unnest($2::bigint[]), unnest($3::double precision[]), now(), now()`,
+		DeleteExcept: `WITH deleted AS (DELETE FROM recommendations WHERE user_id <> ALL($1) RETURNING user_id)
+		SELECT DISTINCT user_id FROM deleted`,
+	}
+
+	masterNamedQueries = []string{}
+
+	slaveQueries = []string{
+		GetByUserId: fmt.Sprintf("SELECT %s FROM recommendations WHERE user_id = $1 AND deleted_at IS NULL ORDER BY score DESC", AllFields),
+	}
+)
+
+func Init(ctx context.Context, log log.Interface, leader *sqlx.DB, follower *sqlx.DB, rds redis.Redis) Interface {
+	stmpts, err := sqlxUtils.PrepareQueries(leader, masterQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	namedStmpts, err := sqlxUtils.PrepareNamedQueries(leader, masterNamedQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf(")PrepareNamedQueries err: %v", err))
+		return nil
+	}
+
+	slaveStmpts, err := sqlxUtils.PrepareQueries(follower, slaveQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	return &recommendation{
+		log:               log,
+		leaderDB:          leader,
+		followerDB:        follower,
+		rds:               rds,
+		masterStmts:       stmpts,
+		slaveStmts:        slaveStmpts,
+		masterNamedStmpts: namedStmpts,
+	}
+}
+
+func (r *recommendation) GetByUserId(ctx context.Context, userId int64) ([]entity.Recommendation, error) {
+	var results []entity.Recommendation
+
+	err := r.rds.WithCache(ctx, fmt.Sprintf(GetByUserIdKey, userId), &results, func() (interface{}, error) {
+		if err := r.slaveStmts[GetByUserId].SelectContext(ctx, &results, userId); err != nil {
+			return results, err
+		}
+
+		return results, nil
+	})
+	if err != nil {
+		r.log.Error(ctx, fmt.Sprintf("GetByUserId err: %v", err))
+		return results, err
+	}
+
+	return results, nil
+}
+
+func (r *recommendation) Replace(ctx context.Context, userId int64, params []entity.Recommendation) error {
+	stmt, err := r.getStatement(ctx, DeleteByUserId)
+	if err != nil {
+		r.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return err
+	}
+
+	if _, err = stmt.ExecContext(ctx, userId); err != nil {
+		r.log.Error(ctx, fmt.Sprintf("DeleteRecommendations err: %v", err))
+		return err
+	}
+
+	if len(params) > 0 {
+		candidateIds, scores := make([]int64, len(params)), make([]float64, len(params))
+		for i, p := range params {
+			candidateIds[i], scores[i] = p.CandidateId, p.Score
+		}
+
+		stmt, err = r.getStatement(ctx, BulkCreate)
+		if err != nil {
+			r.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+			return err
+		}
+
+		if _, err = stmt.ExecContext(ctx, userId, pq.Array(candidateIds), pq.Array(scores)); err != nil {
+			r.log.Error(ctx, fmt.Sprintf("BulkCreateRecommendations err: %v", err))
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *recommendation) DeleteExcept(ctx context.Context, userIds []int64) ([]int64, error) {
+	var deleted []int64
+
+	if err := r.masterStmts[DeleteExcept].SelectContext(ctx, &deleted, pq.Array(userIds)); err != nil {
+		r.log.Error(ctx, fmt.Sprintf("DeleteExcept err: %v", err))
+		return deleted, err
+	}
+
+	return deleted, nil
+}
+
+func (r *recommendation) Invalidate(ctx context.Context, userIds ...int64) {
+	for _, userId := range userIds {
+		key := fmt.Sprintf(GetByUserIdKey, userId)
+		if redisErr := r.rds.Del(ctx, key); redisErr != nil {
+			r.log.Error(ctx, fmt.Sprintf("error when redis delete key: %s, %s", key, redisErr))
+		}
+	}
+}
+
+func (r *recommendation) getStatement(ctx context.Context, queryId int) (*sqlx.Stmt, error) {
+	var err error
+	var statement *sqlx.Stmt
+	if atomicSessionCtx, ok := ctx.(*atomic.AtomicSessionContext); ok {
+		if atomicSession, ok := atomicSessionCtx.AtomicSession.(*atomicSqlx.SqlxAtomicSession); ok {
+			statement, err = atomicSession.Tx().PreparexContext(ctx, masterQueries[queryId])
+		} else {
+			err = atomic.InvalidAtomicSessionProvider
+		}
+	} else {
+		statement = r.masterStmts[queryId]
+	}
+	return statement, err
+}
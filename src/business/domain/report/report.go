@@ -0,0 +1,224 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"loverly/lib/atomic"
+	"loverly/lib/log"
+	"loverly/lib/redis"
+	"loverly/src/business/entity"
+
+	atomicSqlx "loverly/lib/atomic/sqlx"
+	sqlxUtils "loverly/lib/sqlx"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Interface keeps the moderation queue and the actions taken on it, moderators need it fresh so it isn't cached
+type Interface interface {
+	GetPage(ctx context.Context, param entity.ReportPageParam) ([]entity.Report, error)
+	GetForUpdate(ctx context.Context, id int64) (entity.Report, error)
+	Create(ctx context.Context, param entity.Report) (int64, error)
+	Assign(ctx context.Context, param entity.Report) error
+	Resolve(ctx context.Context, param entity.Report) error
+	CreateAction(ctx context.Context, param entity.ModerationAction) (int64, error)
+}
+
+type report struct {
+	log               log.Interface
+	leaderDB          *sqlx.DB
+	followerDB        *sqlx.DB
+	rds               redis.Redis
+	masterStmts       []*sqlx.Stmt
+	slaveStmts        []*sqlx.Stmt
+	masterNamedStmpts []*sqlx.NamedStmt
+}
+
+const (
+	AllFields = `id, reporter_id, reported_id, target_type, target_id, reason, details, status, assignee_id, resolved_at,
+	created_at, updated_at, deleted_at`
+
+	GetPage = iota
+	GetForUpdate
+
+	Create
+	Assign
+	Resolve
+	CreateAction
+)
+
+var (
+	masterQueries = []string{
+		GetForUpdate: fmt.Sprintf("SELECT %s FROM reports WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", AllFields),
+	}
+
+	masterNamedQueries = []string{
+		Create: `INSERT INTO reports (reporter_id, reported_id, target_type, target_id, reason, details, created_at, updated_at)
+		VALUES (:reporter_id, :reported_id, :target_type, :target_id, :reason, :details, now(), now()) RETURNING id`,
+		Assign: `UPDATE reports SET assignee_id = :assignee_id, updated_at = now() WHERE id = :id AND deleted_at IS NULL`,
+		Resolve: `UPDATE reports SET status = :status, assignee_id = :assignee_id, resolved_at = now(), updated_at = now()
+		WHERE id = :id AND deleted_at IS NULL`,
+		CreateAction: `INSERT INTO moderation_actions (report_id, moderator_id, user_id, action, note, expires_at, created_at, updated_at)
+		VALUES (:report_id, :moderator_id, :user_id, :action, :note, :expires_at, now(), now()) RETURNING id`,
+	}
+
+	slaveQueries = []string{
+		// an assignee of 0 lists every report of the status
+		GetPage: fmt.Sprintf(`SELECT %s FROM reports WHERE status = $1 AND ($2 = 0 OR assignee_id = $2) AND deleted_at IS NULL
+		AND (created_at, id) > ($3, $4) ORDER BY created_at, id LIMIT $5`, AllFields),
+	}
+)
+
+func Init(ctx context.Context, log log.Interface, leader *sqlx.DB, follower *sqlx.DB, rds redis.Redis) Interface {
+	stmpts, err := sqlxUtils.PrepareQueries(leader, masterQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	namedStmpts, err := sqlxUtils.PrepareNamedQueries(leader, masterNamedQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareNamedQueries err: %v", err))
+		return nil
+	}
+
+	slaveStmpts, err := sqlxUtils.PrepareQueries(follower, slaveQueries)
+	if err != nil {
+		log.Error(ctx, fmt.Sprintf("PrepareQueries err: %v", err))
+		return nil
+	}
+
+	return &report{
+		log:               log,
+		leaderDB:          leader,
+		followerDB:        follower,
+		rds:               rds,
+		masterStmts:       stmpts,
+		slaveStmts:        slaveStmpts,
+		masterNamedStmpts: namedStmpts,
+	}
+}
+
+func (r *report) GetPage(ctx context.Context, param entity.ReportPageParam) ([]entity.Report, error) {
+	var results []entity.Report
+
+	if err := r.slaveStmts[GetPage].SelectContext(ctx, &results, param.Status, param.AssigneeId, param.AfterAt, param.AfterId, param.Limit); err != nil {
+		r.log.Error(ctx, fmt.Sprintf("GetPage err: %v", err))
+		return results, err
+	}
+
+	return results, nil
+}
+
+// GetForUpdate locks the report until the surrounding atomic session ends
+func (r *report) GetForUpdate(ctx context.Context, id int64) (entity.Report, error) {
+	var result entity.Report
+
+	stmt, err := r.getStatement(ctx, GetForUpdate)
+	if err != nil {
+		r.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return result, err
+	}
+
+	if err = stmt.GetContext(ctx, &result, id); err != nil {
+		r.log.Error(ctx, fmt.Sprintf("GetForUpdate err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *report) Create(ctx context.Context, param entity.Report) (int64, error) {
+	var result entity.Report
+
+	namedStmt, err := r.getNamedStatement(ctx, Create)
+	if err != nil {
+		r.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return 0, err
+	}
+
+	if err = namedStmt.GetContext(ctx, &result, param); err != nil {
+		r.log.Error(ctx, fmt.Sprintf("CreateReport err: %v", err))
+		return 0, err
+	}
+
+	return result.ID, nil
+}
+
+func (r *report) Assign(ctx context.Context, param entity.Report) error {
+	namedStmt, err := r.getNamedStatement(ctx, Assign)
+	if err != nil {
+		r.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return err
+	}
+
+	if _, err = namedStmt.ExecContext(ctx, param); err != nil {
+		r.log.Error(ctx, fmt.Sprintf("AssignReport err: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// Resolve closes the report with param.Status, param.AssigneeId is the moderator who resolved it
+func (r *report) Resolve(ctx context.Context, param entity.Report) error {
+	namedStmt, err := r.getNamedStatement(ctx, Resolve)
+	if err != nil {
+		r.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return err
+	}
+
+	if _, err = namedStmt.ExecContext(ctx, param); err != nil {
+		r.log.Error(ctx, fmt.Sprintf("ResolveReport err: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *report) CreateAction(ctx context.Context, param entity.ModerationAction) (int64, error) {
+	var result entity.ModerationAction
+
+	namedStmt, err := r.getNamedStatement(ctx, CreateAction)
+	if err != nil {
+		r.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return 0, err
+	}
+
+	if err = namedStmt.GetContext(ctx, &result, param); err != nil {
+		r.log.Error(ctx, fmt.Sprintf("CreateModerationAction err: %v", err))
+		return 0, err
+	}
+
+	return result.ID, nil
+}
+
+func (r *report) getStatement(ctx context.Context, queryId int) (*sqlx.Stmt, error) {
+	var err error
+	var statement *sqlx.Stmt
+	if atomicSessionCtx, ok := ctx.(*atomic.AtomicSessionContext); ok {
+		if atomicSession, ok := atomicSessionCtx.AtomicSession.(*atomicSqlx.SqlxAtomicSession); ok {
+			statement, err = atomicSession.Tx().PreparexContext(ctx, masterQueries[queryId])
+		} else {
+			err = atomic.InvalidAtomicSessionProvider
+		}
+	} else {
+		statement = r.masterStmts[queryId]
+	}
+	return statement, err
+}
+
+func (r *report) getNamedStatement(ctx context.Context, queryId int) (*sqlx.NamedStmt, error) {
+	var err error
+	var namedStmt *sqlx.NamedStmt
+	if atomicSessionCtx, ok := ctx.(*atomic.AtomicSessionContext); ok {
+		if atomicSession, ok := atomicSessionCtx.AtomicSession.(*atomicSqlx.SqlxAtomicSession); ok {
+			namedStmt, err = atomicSession.Tx().PrepareNamedContext(ctx, masterNamedQueries[queryId])
+		} else {
+			err = atomic.InvalidAtomicSessionProvider
+		}
+	} else {
+		namedStmt = r.masterNamedStmpts[queryId]
+	}
+	return namedStmt, err
+}
@@ -2,22 +2,41 @@ package subscription
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"loverly/lib/atomic"
 	"loverly/lib/log"
 	"loverly/lib/redis"
 	"loverly/src/business/entity"
+	"time"
 
 	atomicSqlx "loverly/lib/atomic/sqlx"
 	sqlxUtils "loverly/lib/sqlx"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type Interface interface {
+	// GetByUserId returns the current subscription of the user, the latest expired one when there is none
 	GetByUserId(ctx context.Context, userId int64) (entity.Subscription, error)
-	GetByPlan(ctx context.Context, userId int64, plan string) (entity.Subscription, error)
+	// GetFeatures returns the Feature* codes of the plan of the current subscription, none when there is none
+	GetFeatures(ctx context.Context, userId int64) ([]string, error)
+	// GetEntitled returns those of the users whose current plan has the feature
+	GetEntitled(ctx context.Context, feature string, userIds []int64) ([]int64, error)
+	GetCurrentForUpdate(ctx context.Context, userId int64) (entity.Subscription, error)
+	GetByIdForUpdate(ctx context.Context, id int64) (entity.Subscription, error)
+	// GetDue reads subscriptions to renew or expire without locking them, lock each with GetByIdForUpdate to change it
+	GetDue(ctx context.Context, limit int) ([]entity.Subscription, error)
+	GetExpiring(ctx context.Context, warnBefore time.Duration, limit int) ([]entity.Subscription, error)
 	Create(ctx context.Context, param entity.Subscription) (int64, error)
+	Update(ctx context.Context, param entity.Subscription) error
+	// RecordChange appends to the plan history of a subscription
+	RecordChange(ctx context.Context, param entity.SubscriptionChange) (int64, error)
+	HasTrial(ctx context.Context, userId int64, family string) (bool, error)
+	// ClaimTrial records the trial of the family for the user, it returns 0 when the user already took one
+	ClaimTrial(ctx context.Context, param entity.Trial) (int64, error)
 }
 
 type subs struct {
@@ -31,29 +50,70 @@ type subs struct {
 }
 
 const (
-	AllFields = `id, user_id, plan, start_date, end_date, created_at, updated_at, deleted_at`
+	AllFields = `id, user_id, plan, start_date, end_date, status, cancelled_at, grace_until, renewal_attempts, next_renewal_at,
+	reminder_sent_at, pending_plan, trial_ends_at, trial_converts, period_amount, created_at, updated_at, deleted_at`
 
 	GetByUserId = iota
-	GetByPlan
+	GetFeatures
+	GetEntitled
+	GetCurrentForUpdate
+	GetByIdForUpdate
+	GetDue
+	GetExpiring
+	HasTrial
 
 	Create
+	Update
+	RecordChange
+	ClaimTrial
 
 	GetByUserIdKey = "subscriptions:getbyuserid:%d"
-	GetByPlanKey   = "subscriptions:getbyplan:%d:%s"
 	DeleteKey      = "subscriptions:*"
 )
 
 var (
-	masterQueries = []string{}
+	masterQueries = []string{
+		GetCurrentForUpdate: fmt.Sprintf(`SELECT %s FROM subscriptions WHERE user_id = $1 AND status <> 'expired' AND deleted_at IS NULL
+		ORDER BY end_date DESC LIMIT 1 FOR UPDATE`, AllFields),
+		GetByIdForUpdate: fmt.Sprintf(`SELECT %s FROM subscriptions WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, AllFields),
+		// past their period, or in grace with a retry or the end of grace due
+		GetDue: fmt.Sprintf(`SELECT %s FROM subscriptions WHERE deleted_at IS NULL
+		AND ((status IN ('active', 'cancelled') AND end_date <= now())
+		OR (status = 'grace' AND (next_renewal_at <= now() OR grace_until <= now())))
+		ORDER BY end_date, id LIMIT $1`, AllFields),
+		GetExpiring: fmt.Sprintf(`SELECT %s FROM subscriptions WHERE deleted_at IS NULL AND status IN ('active', 'cancelled')
+		AND reminder_sent_at IS NULL AND end_date > now() AND end_date <= now() + make_interval(secs => $2)
+		ORDER BY end_date, id LIMIT $1 FOR UPDATE SKIP LOCKED`, AllFields),
+	}
 
 	masterNamedQueries = []string{
-		Create: `INSERT INTO subscriptions (user_id, plan, start_date, end_date, created_at, updated_at) 
-		VALUES (:user_id, :plan, :start_date, :end_date, now(), now()) RETURNING id`,
+		Create: `INSERT INTO subscriptions (user_id, plan, start_date, end_date, trial_ends_at, trial_converts, period_amount,
+		created_at, updated_at) VALUES (:user_id, :plan, :start_date, :end_date, :trial_ends_at, :trial_converts, :period_amount,
+		now(), now()) RETURNING id`,
+		Update: `UPDATE subscriptions SET plan = :plan, start_date = :start_date, end_date = :end_date, status = :status,
+		cancelled_at = :cancelled_at, grace_until = :grace_until, renewal_attempts = :renewal_attempts,
+		next_renewal_at = :next_renewal_at, reminder_sent_at = :reminder_sent_at, pending_plan = :pending_plan,
+		trial_ends_at = :trial_ends_at, trial_converts = :trial_converts, period_amount = :period_amount, updated_at = now()
+		WHERE id = :id AND deleted_at IS NULL`,
+		RecordChange: `INSERT INTO subscription_changes (subscription_id, user_id, from_plan, to_plan, kind, credit, amount, currency,
+		payment_id, effective_at, created_at, updated_at) VALUES (:subscription_id, :user_id, :from_plan, :to_plan, :kind, :credit,
+		:amount, :currency, :payment_id, :effective_at, now(), now()) RETURNING id`,
+		ClaimTrial: `INSERT INTO trials (user_id, family, plan, payment_id, started_at, ends_at, created_at, updated_at)
+		VALUES (:user_id, :family, :plan, :payment_id, :started_at, :ends_at, now(), now())
+		ON CONFLICT (user_id, family) DO NOTHING RETURNING id`,
 	}
 
 	slaveQueries = []string{
-		GetByUserId: fmt.Sprintf("SELECT %s FROM subscriptions WHERE user_id = $1 AND deleted_at IS NULL", AllFields),
-		GetByPlan:   fmt.Sprintf("SELECT %s FROM subscriptions WHERE user_id = $1 AND plan = $2 AND deleted_at IS NULL", AllFields),
+		GetByUserId: fmt.Sprintf(`SELECT %s FROM subscriptions WHERE user_id = $1 AND deleted_at IS NULL
+		ORDER BY status = 'expired', end_date DESC LIMIT 1`, AllFields),
+		// a retired plan keeps its features until the subscription ends, a period past its end only keeps them in grace,
+		// the sweep may not have expired it yet
+		GetFeatures: `SELECT jsonb_array_elements_text(p.features) FROM subscriptions s JOIN plans p ON p.code = s.plan
+		WHERE s.user_id = $1 AND s.status <> 'expired' AND (s.end_date > now() OR s.status = 'grace') AND s.deleted_at IS NULL`,
+		GetEntitled: `SELECT s.user_id FROM subscriptions s JOIN plans p ON p.code = s.plan
+		WHERE s.user_id = ANY($2) AND s.status <> 'expired' AND (s.end_date > now() OR s.status = 'grace') AND s.deleted_at IS NULL
+		AND p.features @> jsonb_build_array($1::text)`,
+		HasTrial: "SELECT EXISTS (SELECT 1 FROM trials WHERE user_id = $1 AND family = $2 AND deleted_at IS NULL)",
 	}
 )
 
@@ -105,22 +165,99 @@ func (s *subs) GetByUserId(ctx context.Context, userId int64) (entity.Subscripti
 	return subscription, nil
 }
 
-func (s *subs) GetByPlan(ctx context.Context, userId int64, plan string) (entity.Subscription, error) {
-	var subscription entity.Subscription
+// GetFeatures reads the plan every time, a cached copy would outlive plan edits and subscription changes
+func (s *subs) GetFeatures(ctx context.Context, userId int64) ([]string, error) {
+	features := []string{}
 
-	err := s.rds.WithCache(ctx, fmt.Sprintf(GetByPlanKey, userId, plan), &subscription, func() (interface{}, error) {
-		if err := s.slaveStmts[GetByPlan].GetContext(ctx, &subscription, userId, plan); err != nil {
-			return subscription, err
-		}
+	if err := s.slaveStmts[GetFeatures].SelectContext(ctx, &features, userId); err != nil {
+		s.log.Error(ctx, fmt.Sprintf("GetFeatures err: %v", err))
+		return features, err
+	}
 
-		return subscription, nil
-	})
+	return features, nil
+}
+
+func (s *subs) GetEntitled(ctx context.Context, feature string, userIds []int64) ([]int64, error) {
+	ids := []int64{}
+
+	if err := s.slaveStmts[GetEntitled].SelectContext(ctx, &ids, feature, pq.Array(userIds)); err != nil {
+		s.log.Error(ctx, fmt.Sprintf("GetEntitled err: %v", err))
+		return ids, err
+	}
+
+	return ids, nil
+}
+
+// GetCurrentForUpdate locks the subscription whose features are on until the surrounding atomic session ends
+func (s *subs) GetCurrentForUpdate(ctx context.Context, userId int64) (entity.Subscription, error) {
+	var result entity.Subscription
+
+	stmt, err := s.getStatement(ctx, GetCurrentForUpdate)
 	if err != nil {
-		s.log.Error(ctx, fmt.Sprintf("GetByPlan err: %v", err))
-		return subscription, err
+		s.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return result, err
 	}
 
-	return subscription, nil
+	if err = stmt.GetContext(ctx, &result, userId); err != nil {
+		s.log.Error(ctx, fmt.Sprintf("GetCurrentForUpdate err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
+
+// GetByIdForUpdate locks the subscription until the surrounding atomic session ends
+func (s *subs) GetByIdForUpdate(ctx context.Context, id int64) (entity.Subscription, error) {
+	var result entity.Subscription
+
+	stmt, err := s.getStatement(ctx, GetByIdForUpdate)
+	if err != nil {
+		s.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return result, err
+	}
+
+	if err = stmt.GetContext(ctx, &result, id); err != nil {
+		s.log.Error(ctx, fmt.Sprintf("GetByIdForUpdate err: %v", err))
+		return result, err
+	}
+
+	return result, nil
+}
+
+// GetDue reads subscriptions to renew or expire, the sweep locks them one by one
+func (s *subs) GetDue(ctx context.Context, limit int) ([]entity.Subscription, error) {
+	var results []entity.Subscription
+
+	stmt, err := s.getStatement(ctx, GetDue)
+	if err != nil {
+		s.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return results, err
+	}
+
+	if err = stmt.SelectContext(ctx, &results, limit); err != nil {
+		s.log.Error(ctx, fmt.Sprintf("GetDue err: %v", err))
+		return results, err
+	}
+
+	return results, nil
+}
+
+// GetExpiring locks subscriptions whose period ends within warnBefore and that were not reminded yet
+func (s *subs) GetExpiring(ctx context.Context, warnBefore time.Duration, limit int) ([]entity.Subscription, error) {
+	var results []entity.Subscription
+
+	stmt, err := s.getStatement(ctx, GetExpiring)
+	if err != nil {
+		s.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return results, err
+	}
+
+	if err = stmt.SelectContext(ctx, &results, limit, warnBefore.Seconds()); err != nil {
+		s.log.Error(ctx, fmt.Sprintf("GetExpiring err: %v", err))
+		return results, err
+	}
+
+	return results, nil
 }
 
 func (s *subs) Create(ctx context.Context, param entity.Subscription) (int64, error) {
@@ -145,6 +282,74 @@ func (s *subs) Create(ctx context.Context, param entity.Subscription) (int64, er
 	return user.ID, nil
 }
 
+func (s *subs) Update(ctx context.Context, param entity.Subscription) error {
+	namedStmt, err := s.getNamedStatement(ctx, Update)
+	if err != nil {
+		s.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return err
+	}
+
+	if _, err = namedStmt.ExecContext(ctx, param); err != nil {
+		s.log.Error(ctx, fmt.Sprintf("Update err: %v", err))
+		return err
+	}
+
+	redisErr := s.rds.DelWithPattern(ctx, DeleteKey)
+	if redisErr != nil {
+		s.log.Error(ctx, fmt.Sprintf("error when redis delete with pattern: %s, %s", DeleteKey, redisErr))
+	}
+
+	return nil
+}
+
+func (s *subs) RecordChange(ctx context.Context, param entity.SubscriptionChange) (int64, error) {
+	var result entity.SubscriptionChange
+
+	namedStmt, err := s.getNamedStatement(ctx, RecordChange)
+	if err != nil {
+		s.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return 0, err
+	}
+
+	if err = namedStmt.GetContext(ctx, &result, param); err != nil {
+		s.log.Error(ctx, fmt.Sprintf("RecordChange err: %v", err))
+		return 0, err
+	}
+
+	return result.ID, nil
+}
+
+func (s *subs) HasTrial(ctx context.Context, userId int64, family string) (bool, error) {
+	var exists bool
+
+	if err := s.slaveStmts[HasTrial].GetContext(ctx, &exists, userId, family); err != nil {
+		s.log.Error(ctx, fmt.Sprintf("HasTrial err: %v", err))
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (s *subs) ClaimTrial(ctx context.Context, param entity.Trial) (int64, error) {
+	var result entity.Trial
+
+	namedStmt, err := s.getNamedStatement(ctx, ClaimTrial)
+	if err != nil {
+		s.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return 0, err
+	}
+
+	if err = namedStmt.GetContext(ctx, &result, param); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		s.log.Error(ctx, fmt.Sprintf("ClaimTrial err: %v", err))
+		return 0, err
+	}
+
+	return result.ID, nil
+}
+
 func (s *subs) getStatement(ctx context.Context, queryId int) (*sqlx.Stmt, error) {
 	var err error
 	var statement *sqlx.Stmt
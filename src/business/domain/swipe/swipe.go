@@ -6,18 +6,34 @@ import (
 	"loverly/lib/atomic"
 	"loverly/lib/log"
 	"loverly/lib/redis"
+	"loverly/src/business/domain/abuse"
+	"loverly/src/business/domain/block"
 	"loverly/src/business/entity"
+	"time"
 
 	atomicSqlx "loverly/lib/atomic/sqlx"
 	sqlxUtils "loverly/lib/sqlx"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type Interface interface {
 	GetBySwiperId(ctx context.Context, swiperId int64) ([]entity.Swipe, error)
 	GetBySwipeId(ctx context.Context, swiperId, swipedId int64) (entity.Swipe, error)
+	GetOutgoing(ctx context.Context, swiperId int64, swipedIds []int64) ([]entity.Swipe, error)
+	GetIncoming(ctx context.Context, swipedId int64, swiperIds []int64) ([]entity.Swipe, error)
+	GetReceivedLikes(ctx context.Context, swipedId int64, passCoolOff time.Duration) ([]entity.Swipe, error)
+	// GetReceivedLike is a like the user was sent, by its id
+	GetReceivedLike(ctx context.Context, id, swipedId int64) (entity.Swipe, error)
+	GetHistory(ctx context.Context, since time.Time) ([]entity.Swipe, error)
 	Create(ctx context.Context, param entity.Swipe) (int64, error)
+	BulkCreate(ctx context.Context, params []entity.Swipe) ([]int64, error)
+	// Rewind deletes the latest swipe of the user when it's a pass, sql.ErrNoRows when it isn't or there is none
+	Rewind(ctx context.Context, swiperId int64) (entity.Swipe, error)
+
+	// InvalidateUser drops the cached reads of every swipe from or to the user, for changes to the user they filter by
+	InvalidateUser(ctx context.Context, userId int64) error
 }
 
 type swipe struct {
@@ -35,25 +51,65 @@ const (
 
 	GetBySwiperId = iota
 	GetBySwipeId
+	GetOutgoing
+	GetIncoming
+	GetReceivedLikes
+	GetReceivedLike
+	GetHistory
+	GetByUserId
 
 	Create
+	BulkCreate
+	Rewind
+
+	GetBySwipeIdKey     = "swipes:getbyswipeid:%d:%d"
+	GetBySwiperIdKey    = "swipes:getbyswiperid:%d"
+	GetReceivedLikesKey = "swipes:getreceivedlikes:%d"
+	DeleteKey           = "swipes:*"
 
-	GetBySwipeIdKey  = "swipes:getbyswipeid:%d:%d"
-	GetBySwiperIdKey = "swipes:getbyswiperid:%d"
-	DeleteKey        = "swipes:*"
+	// discovery candidates cached by the profile domain, see profile.GetBySwipedKey
+	ProfileGetBySwipeKey = "profiles:getbyswipe:%d:%s"
 )
 
 var (
-	masterQueries = []string{}
+	notShadowLimited = fmt.Sprintf("NOT %s AND NOT %s", fmt.Sprintf(abuse.ShadowLimited, "swiper_id"), fmt.Sprintf(abuse.ShadowLimited, "swiped_id"))
+
+	masterQueries = []string{
+		BulkCreate: `INSERT INTO swipes (swiper_id, swiped_id, direction, created_at, updated_at)
+		SELECT unnest($1::bigint[]), unnest($2::bigint[]), unnest($3::direction[]), now(), now()
+		ON CONFLICT ON CONSTRAINT unique_swipes_id DO UPDATE SET direction = EXCLUDED.direction, updated_at = now(), deleted_at = NULL
+		RETURNING id`,
+		// a like is never taken back, it may already have matched or been seen
+		Rewind: fmt.Sprintf(`UPDATE swipes SET deleted_at = now(), updated_at = now()
+		WHERE id = (SELECT id FROM swipes WHERE swiper_id = $1 AND deleted_at IS NULL ORDER BY updated_at DESC, id DESC LIMIT 1 FOR UPDATE)
+		AND direction = 'left' RETURNING %s`, AllFields),
+	}
 
 	masterNamedQueries = []string{
+		// swiping on someone again (e.g. after the pass cool-off) overwrites the previous decision
 		Create: `INSERT INTO swipes (swiper_id, swiped_id, direction, created_at, updated_at) 
-		VALUES (:swiper_id, :swiped_id, :direction, now(), now()) RETURNING id`,
+		VALUES (:swiper_id, :swiped_id, :direction, now(), now())
+		ON CONFLICT ON CONSTRAINT unique_swipes_id DO UPDATE SET direction = EXCLUDED.direction, updated_at = now(), deleted_at = NULL
+		RETURNING id`,
 	}
 
 	slaveQueries = []string{
-		GetBySwiperId: fmt.Sprintf("SELECT %s FROM swipes WHERE swiper_id = $1 AND DATE(created_at) = CURRENT_DATE AND deleted_at IS NULL", AllFields),
-		GetBySwipeId:  fmt.Sprintf("SELECT %s FROM swipes WHERE swiper_id = $1 AND swiped_id = $2 AND deleted_at IS NULL", AllFields),
+		GetBySwiperId: fmt.Sprintf("SELECT %s FROM swipes WHERE swiper_id = $1 AND DATE(updated_at) = CURRENT_DATE AND deleted_at IS NULL", AllFields),
+		// likes between blocked users, or with a shadow-limited one, never turn into a match
+		GetBySwipeId: fmt.Sprintf("SELECT %s FROM swipes WHERE swiper_id = $1 AND swiped_id = $2 AND deleted_at IS NULL AND %s AND %s",
+			AllFields, fmt.Sprintf(block.NotBlocked, "swiper_id", "swiped_id"), notShadowLimited),
+		GetOutgoing: fmt.Sprintf("SELECT %s FROM swipes WHERE swiper_id = $1 AND swiped_id = ANY($2) AND deleted_at IS NULL", AllFields),
+		GetIncoming: fmt.Sprintf("SELECT %s FROM swipes WHERE swiped_id = $1 AND swiper_id = ANY($2) AND deleted_at IS NULL AND %s AND %s",
+			AllFields, fmt.Sprintf(block.NotBlocked, "swiper_id", "swiped_id"), notShadowLimited),
+		GetReceivedLike: fmt.Sprintf("SELECT %s FROM swipes WHERE id = $1 AND swiped_id = $2 AND direction = 'right' AND deleted_at IS NULL", AllFields),
+		GetHistory:      fmt.Sprintf("SELECT %s FROM swipes WHERE updated_at >= $1 AND deleted_at IS NULL", AllFields),
+		GetByUserId:     fmt.Sprintf("SELECT %s FROM swipes WHERE (swiper_id = $1 OR swiped_id = $1) AND deleted_at IS NULL", AllFields),
+		// likes the user hasn't answered yet, a pass only hides the liker until the cool-off has elapsed
+		GetReceivedLikes: fmt.Sprintf(`SELECT %s FROM swipes s WHERE s.swiped_id = $1 AND s.direction = 'right' AND s.deleted_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM swipes o WHERE o.swiper_id = $1 AND o.swiped_id = s.swiper_id AND o.deleted_at IS NULL
+			AND (o.direction = 'right' OR o.updated_at > now() - make_interval(secs => $2)))
+		AND %s AND NOT %s
+		ORDER BY s.updated_at DESC`, AllFields, fmt.Sprintf(block.NotBlocked, "s.swiper_id", "s.swiped_id"), fmt.Sprintf(abuse.ShadowLimited, "s.swiper_id")),
 	}
 )
 
@@ -123,6 +179,69 @@ func (s *swipe) GetBySwipeId(ctx context.Context, swiperId, swipedId int64) (ent
 	return swipes, nil
 }
 
+func (s *swipe) GetOutgoing(ctx context.Context, swiperId int64, swipedIds []int64) ([]entity.Swipe, error) {
+	var swipes []entity.Swipe
+
+	if err := s.slaveStmts[GetOutgoing].SelectContext(ctx, &swipes, swiperId, pq.Array(swipedIds)); err != nil {
+		s.log.Error(ctx, fmt.Sprintf("GetOutgoing err: %v", err))
+		return swipes, err
+	}
+
+	return swipes, nil
+}
+
+func (s *swipe) GetIncoming(ctx context.Context, swipedId int64, swiperIds []int64) ([]entity.Swipe, error) {
+	var swipes []entity.Swipe
+
+	if err := s.slaveStmts[GetIncoming].SelectContext(ctx, &swipes, swipedId, pq.Array(swiperIds)); err != nil {
+		s.log.Error(ctx, fmt.Sprintf("GetIncoming err: %v", err))
+		return swipes, err
+	}
+
+	return swipes, nil
+}
+
+func (s *swipe) GetReceivedLikes(ctx context.Context, swipedId int64, passCoolOff time.Duration) ([]entity.Swipe, error) {
+	var swipes []entity.Swipe
+
+	err := s.rds.WithCache(ctx, fmt.Sprintf(GetReceivedLikesKey, swipedId), &swipes, func() (interface{}, error) {
+		if err := s.slaveStmts[GetReceivedLikes].SelectContext(ctx, &swipes, swipedId, passCoolOff.Seconds()); err != nil {
+			return swipes, err
+		}
+
+		return swipes, nil
+	})
+	if err != nil {
+		s.log.Error(ctx, fmt.Sprintf("GetReceivedLikes err: %v", err))
+		return swipes, err
+	}
+
+	return swipes, nil
+}
+
+func (s *swipe) GetReceivedLike(ctx context.Context, id, swipedId int64) (entity.Swipe, error) {
+	var swipe entity.Swipe
+
+	if err := s.slaveStmts[GetReceivedLike].GetContext(ctx, &swipe, id, swipedId); err != nil {
+		s.log.Error(ctx, fmt.Sprintf("GetReceivedLike err: %v", err))
+		return swipe, err
+	}
+
+	return swipe, nil
+}
+
+// GetHistory reads every swipe since the given time, meant for offline jobs so it isn't cached
+func (s *swipe) GetHistory(ctx context.Context, since time.Time) ([]entity.Swipe, error) {
+	var swipes []entity.Swipe
+
+	if err := s.slaveStmts[GetHistory].SelectContext(ctx, &swipes, since); err != nil {
+		s.log.Error(ctx, fmt.Sprintf("GetHistory err: %v", err))
+		return swipes, err
+	}
+
+	return swipes, nil
+}
+
 func (s *swipe) Create(ctx context.Context, param entity.Swipe) (int64, error) {
 	var swipes entity.Swipe
 
@@ -137,17 +256,91 @@ func (s *swipe) Create(ctx context.Context, param entity.Swipe) (int64, error) {
 		return 0, err
 	}
 
-	redisErr := s.rds.DelWithPattern(ctx, DeleteKey)
-	if redisErr != nil {
-		s.log.Error(ctx, fmt.Sprintf("error when redis delete with pattern: %s, %s", DeleteKey, redisErr))
+	s.invalidate(ctx, param)
+
+	return swipes.ID, nil
+}
+
+func (s *swipe) BulkCreate(ctx context.Context, params []entity.Swipe) ([]int64, error) {
+	var ids []int64
+
+	swiperIds, swipedIds, directions := make([]int64, len(params)), make([]int64, len(params)), make([]string, len(params))
+	for i, p := range params {
+		swiperIds[i], swipedIds[i], directions[i] = p.SwiperId, p.SwipedId, p.Direction
+	}
+
+	stmt, err := s.getStatement(ctx, BulkCreate)
+	if err != nil {
+		s.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return ids, err
 	}
 
-	redisErr = s.rds.DelWithPattern(ctx, "profiles:*")
-	if redisErr != nil {
-		s.log.Error(ctx, fmt.Sprintf("error when redis delete with pattern: %s, %s", DeleteKey, redisErr))
+	if err = stmt.SelectContext(ctx, &ids, pq.Array(swiperIds), pq.Array(swipedIds), pq.Array(directions)); err != nil {
+		s.log.Error(ctx, fmt.Sprintf("BulkCreateSwipes err: %v", err))
+		return ids, err
 	}
 
-	return swipes.ID, nil
+	s.invalidate(ctx, params...)
+
+	return ids, nil
+}
+
+func (s *swipe) Rewind(ctx context.Context, swiperId int64) (entity.Swipe, error) {
+	var swipe entity.Swipe
+
+	stmt, err := s.getStatement(ctx, Rewind)
+	if err != nil {
+		s.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return swipe, err
+	}
+
+	if err = stmt.GetContext(ctx, &swipe, swiperId); err != nil {
+		s.log.Error(ctx, fmt.Sprintf("Rewind err: %v", err))
+		return swipe, err
+	}
+
+	s.invalidate(ctx, swipe)
+
+	return swipe, nil
+}
+
+// InvalidateUser is for a shadow limit, the reads filter the swipes of a limited user out in both directions
+func (s *swipe) InvalidateUser(ctx context.Context, userId int64) error {
+	var swipes []entity.Swipe
+
+	if err := s.slaveStmts[GetByUserId].SelectContext(ctx, &swipes, userId); err != nil {
+		s.log.Error(ctx, fmt.Sprintf("GetByUserId err: %v", err))
+		return err
+	}
+
+	// whether the other one swiped back is cached too, with or without a swipe
+	for _, sw := range swipes {
+		swipes = append(swipes, entity.Swipe{SwiperId: sw.SwipedId, SwipedId: sw.SwiperId})
+	}
+
+	s.invalidate(ctx, swipes...)
+
+	return nil
+}
+
+// invalidate only drops the keys of the users involved, a swipe says nothing about anyone else
+func (s *swipe) invalidate(ctx context.Context, params ...entity.Swipe) {
+	keys := make(map[string]bool)
+	for _, p := range params {
+		keys[fmt.Sprintf(GetBySwipeIdKey, p.SwiperId, p.SwipedId)] = true
+		keys[fmt.Sprintf(GetBySwiperIdKey, p.SwiperId)] = true
+		for _, userId := range []int64{p.SwiperId, p.SwipedId} {
+			keys[fmt.Sprintf(GetReceivedLikesKey, userId)] = true
+			keys[fmt.Sprintf(ProfileGetBySwipeKey, userId, entity.Male)] = true
+			keys[fmt.Sprintf(ProfileGetBySwipeKey, userId, entity.Female)] = true
+		}
+	}
+
+	for key := range keys {
+		if err := s.rds.Del(ctx, key); err != nil {
+			s.log.Error(ctx, fmt.Sprintf("error when redis delete key: %s, %s", key, err))
+		}
+	}
 }
 
 func (s *swipe) getStatement(ctx context.Context, queryId int) (*sqlx.Stmt, error) {
@@ -16,9 +16,17 @@ import (
 
 type Interface interface {
 	// Get(ctx context.Context, params entity.user) (entity.user, error)
-	// GetById(ctx context.Context, id int64) (entity.user, error)
+	GetById(ctx context.Context, id int64) (entity.User, error)
 	GetByEmail(ctx context.Context, email string) (entity.User, error)
+	// GetByIdForUpdate locks the user until the atomic session ends, it's never cached
+	GetByIdForUpdate(ctx context.Context, id int64) (entity.User, error)
 	Create(ctx context.Context, param entity.User) (int64, error)
+	UpdateStatus(ctx context.Context, param entity.User) error
+	// InvalidateStatus drops every cached read filtered by account status, call it once the status change is committed
+	InvalidateStatus(ctx context.Context)
+	// UpdatePassword also revokes every token issued before, call Invalidate once it is committed
+	UpdatePassword(ctx context.Context, param entity.User) error
+	Invalidate(ctx context.Context)
 }
 
 type user struct {
@@ -32,31 +40,49 @@ type user struct {
 }
 
 const (
-	AllFields = `id, email, password, verified, created_at, updated_at, deleted_at`
+	AllFields = `id, email, password, verified, role, status, suspended_until, status_reason, token_version, created_at, updated_at, deleted_at`
+
+	// Available is the filter every read of other users applies, format it with the user id column
+	Available = `EXISTS (SELECT 1 FROM users acct WHERE acct.id = %s AND acct.deleted_at IS NULL
+		AND (acct.status = 'active' OR (acct.status = 'suspended' AND acct.suspended_until <= now())))`
 
 	Get = iota
-	// GetById
+	GetById
 	GetByEmail
+	GetByIdForUpdate
 
 	Create
+	UpdateStatus
+	UpdatePassword
 
 	// GetListKey    = "users:getlist"
-	// GetByIdKey    = "users:getbyid:%d"
+	GetByIdKey    = "users:getbyid:%d"
 	GetByEmailKey = "users:getbyemail:%s"
 	DeleteKey     = "users:*"
+
+	// reads of other domains filtered by Available, anyone may have the user in them
+	ProfileGetBySwipeKey   = "profiles:getbyswipe:*"
+	ProfileGetByUserIdsKey = "profiles:getbyuserids:*"
+	MatchGetByUserIdKey    = "matchs:getbyuserid:*"
+	MatchGetPageKey        = "matchs:getpage:*"
 )
 
 var (
-	masterQueries = []string{}
+	masterQueries = []string{
+		GetByIdForUpdate: fmt.Sprintf("SELECT %s FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", AllFields),
+	}
 
 	masterNamedQueries = []string{
 		Create: `INSERT INTO users (email, password, created_at, updated_at) 
 		VALUES (:email, :password, now(), now()) RETURNING id`,
+		UpdateStatus: `UPDATE users SET status = :status, suspended_until = :suspended_until, status_reason = :status_reason,
+		updated_at = now() WHERE id = :id AND deleted_at IS NULL`,
+		UpdatePassword: `UPDATE users SET password = :password, token_version = token_version + 1, updated_at = now() WHERE id = :id AND deleted_at IS NULL`,
 	}
 
 	slaveQueries = []string{
-		Get: fmt.Sprintf("SELECT %s FROM users WHERE deleted_at IS NULL", AllFields),
-		// GetById:    fmt.Sprintf("SELECT %s FROM users WHERE id = $1 AND deleted_at IS NULL", AllFields),
+		Get:        fmt.Sprintf("SELECT %s FROM users WHERE deleted_at IS NULL", AllFields),
+		GetById:    fmt.Sprintf("SELECT %s FROM users WHERE id = $1 AND deleted_at IS NULL", AllFields),
 		GetByEmail: fmt.Sprintf("SELECT %s FROM users WHERE email = $1 AND deleted_at IS NULL", AllFields),
 	}
 )
@@ -91,6 +117,41 @@ func Init(ctx context.Context, log log.Interface, leader *sqlx.DB, follower *sql
 	}
 }
 
+func (u *user) GetById(ctx context.Context, id int64) (entity.User, error) {
+	var user entity.User
+
+	err := u.rds.WithCache(ctx, fmt.Sprintf(GetByIdKey, id), &user, func() (interface{}, error) {
+		if err := u.slaveStmts[GetById].GetContext(ctx, &user, id); err != nil {
+			return user, err
+		}
+
+		return user, nil
+	})
+	if err != nil {
+		u.log.Error(ctx, fmt.Sprintf("GetById err: %v", err))
+		return user, err
+	}
+
+	return user, nil
+}
+
+func (u *user) GetByIdForUpdate(ctx context.Context, id int64) (entity.User, error) {
+	var user entity.User
+
+	stmt, err := u.getStatement(ctx, GetByIdForUpdate)
+	if err != nil {
+		u.log.Error(ctx, fmt.Sprintf("getStatement err: %v", err))
+		return user, err
+	}
+
+	if err = stmt.GetContext(ctx, &user, id); err != nil {
+		u.log.Error(ctx, fmt.Sprintf("GetByIdForUpdate err: %v", err))
+		return user, err
+	}
+
+	return user, nil
+}
+
 func (u *user) GetByEmail(ctx context.Context, email string) (entity.User, error) {
 	var user entity.User
 
@@ -131,6 +192,53 @@ func (u *user) Create(ctx context.Context, param entity.User) (int64, error) {
 	return user.ID, nil
 }
 
+// UpdateStatus restricts or restores the account, the user shows up again or disappears from everyone's reads
+func (u *user) UpdateStatus(ctx context.Context, param entity.User) error {
+	namedStmt, err := u.getNamedStatement(ctx, UpdateStatus)
+	if err != nil {
+		u.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return err
+	}
+
+	if _, err = namedStmt.ExecContext(ctx, param); err != nil {
+		u.log.Error(ctx, fmt.Sprintf("UpdateStatus err: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// InvalidateStatus isn't part of UpdateStatus, a read racing the transaction would cache the old status again
+func (u *user) InvalidateStatus(ctx context.Context) {
+	for _, pattern := range []string{DeleteKey, ProfileGetBySwipeKey, ProfileGetByUserIdsKey, MatchGetByUserIdKey, MatchGetPageKey} {
+		if redisErr := u.rds.DelWithPattern(ctx, pattern); redisErr != nil {
+			u.log.Error(ctx, fmt.Sprintf("error when redis delete with pattern: %s, %s", pattern, redisErr))
+		}
+	}
+}
+
+func (u *user) UpdatePassword(ctx context.Context, param entity.User) error {
+	namedStmt, err := u.getNamedStatement(ctx, UpdatePassword)
+	if err != nil {
+		u.log.Error(ctx, fmt.Sprintf("getNamedStatement err: %v", err))
+		return err
+	}
+
+	if _, err = namedStmt.ExecContext(ctx, param); err != nil {
+		u.log.Error(ctx, fmt.Sprintf("UpdatePassword err: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// Invalidate drops the cached users, a read racing the transaction would cache the old token version again
+func (u *user) Invalidate(ctx context.Context) {
+	if redisErr := u.rds.DelWithPattern(ctx, DeleteKey); redisErr != nil {
+		u.log.Error(ctx, fmt.Sprintf("error when redis delete with pattern: %s, %s", DeleteKey, redisErr))
+	}
+}
+
 func (r *user) getStatement(ctx context.Context, queryId int) (*sqlx.Stmt, error) {
 	var err error
 	var statement *sqlx.Stmt
@@ -0,0 +1,96 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	AbuseSwipeRate    = "swipe_rate"
+	AbuseLikeRatio    = "like_ratio"
+	AbuseSharedBio    = "shared_bio"
+	AbuseSharedIP     = "shared_ip"
+	AbuseSharedDevice = "shared_device"
+
+	AbuseFlagOpen      = "open"
+	AbuseFlagConfirmed = "confirmed"
+	AbuseFlagDismissed = "dismissed"
+)
+
+type Fingerprint struct {
+	UserId   int64  `db:"user_id"`
+	IP       string `db:"ip"`
+	DeviceId string `db:"device_id"`
+}
+
+type AbuseFlag struct {
+	ID          int64         `db:"id" json:"id"`
+	UserId      int64         `db:"user_id" json:"user_id"`
+	Signal      string        `db:"signal" json:"signal"`
+	Explanation string        `db:"explanation" json:"explanation"`
+	Value       int           `db:"value" json:"value"`
+	Status      string        `db:"status" json:"status"`
+	ReviewerId  sql.NullInt64 `db:"reviewer_id" json:"reviewer_id"`
+	Note        string        `db:"note" json:"note"`
+	ReviewedAt  sql.NullTime  `db:"reviewed_at" json:"reviewed_at"`
+	CreatedAt   sql.NullTime  `db:"created_at" json:"created_at"`
+	UpdatedAt   sql.NullTime  `db:"updated_at" json:"updated_at"`
+	DeletedAt   sql.NullTime  `db:"deleted_at" json:"deleted_at"`
+}
+
+// AbuseSignalParam sets how far back each kind of signal looks
+type AbuseSignalParam struct {
+	UserId           int64
+	RateSince        time.Time
+	RatioSince       time.Time
+	FingerprintSince time.Time
+}
+
+// AbuseSignals is what the detector measures for a user, the Shared* are other accounts
+type AbuseSignals struct {
+	RecentSwipes  int  `db:"recent_swipes"`
+	Swipes        int  `db:"swipes"`
+	Likes         int  `db:"likes"`
+	SharedBio     int  `db:"shared_bio"`
+	SharedIP      int  `db:"shared_ip"`
+	SharedDevice  int  `db:"shared_device"`
+	ShadowLimited bool `db:"shadow_limited"`
+}
+
+type AbuseFlagListParam struct {
+	Status string `validate:"omitempty,oneof=open confirmed dismissed"`
+	UserId int64  `validate:"omitempty,min=1"`
+	Cursor string `validate:"omitempty,base64rawurl"`
+	Limit  int    `validate:"omitempty,min=1,max=100"`
+}
+
+// AbuseFlagPageParam is a keyset page of the flags, strictly after (AfterAt, AfterId), oldest first
+type AbuseFlagPageParam struct {
+	Status  string
+	UserId  int64
+	AfterAt time.Time
+	AfterId int64
+	Limit   int
+}
+
+type AbuseFlagReviewParam struct {
+	Status string `json:"status" validate:"oneof=confirmed dismissed"`
+	Note   string `json:"note" validate:"max=2000"`
+}
+
+type AbuseFlagResponse struct {
+	ID          int64      `json:"id"`
+	UserId      int64      `json:"user_id"`
+	Signal      string     `json:"signal"`
+	Explanation string     `json:"explanation"`
+	Status      string     `json:"status"`
+	ReviewerId  int64      `json:"reviewer_id,omitempty"`
+	Note        string     `json:"note,omitempty"`
+	ReviewedAt  *time.Time `json:"reviewed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type AbuseFlagListResponse struct {
+	Flags      []AbuseFlagResponse `json:"flags"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
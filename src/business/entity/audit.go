@@ -0,0 +1,105 @@
+package entity
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const (
+	AuditLogin                 = "user.login"
+	AuditLoginFailed           = "user.login_failed"
+	AuditPasswordChanged       = "user.password_changed"
+	AuditSubscriptionCreated   = "subscription.created"
+	AuditSubscriptionRenewed   = "subscription.renewed"
+	AuditSubscriptionCancelled = "subscription.cancelled"
+	AuditSubscriptionChanged   = "subscription.changed"
+	AuditReportAssigned        = "report.assigned"
+	AuditReportResolved        = "report.resolved"
+	AuditAbuseFlagReviewed     = "abuse_flag.reviewed"
+
+	AuditTargetUser         = "user"
+	AuditTargetSubscription = "subscription"
+	AuditTargetReport       = "report"
+	AuditTargetAbuseFlag    = "abuse_flag"
+)
+
+// AuditLog is an append-only entry, ActorId is who acted and TargetId what was acted on
+type AuditLog struct {
+	ID         int64           `db:"id" json:"id"`
+	ActorId    sql.NullInt64   `db:"actor_id" json:"actor_id"`
+	Action     string          `db:"action" json:"action"`
+	TargetType string          `db:"target_type" json:"target_type"`
+	TargetId   sql.NullInt64   `db:"target_id" json:"target_id"`
+	Details    json.RawMessage `db:"details" json:"details"`
+	RequestId  string          `db:"request_id" json:"request_id"`
+	IP         string          `db:"ip" json:"ip"`
+	UserAgent  string          `db:"user_agent" json:"user_agent"`
+	CreatedAt  sql.NullTime    `db:"created_at" json:"created_at"`
+}
+
+type AuditLoginDetails struct {
+	Email  string `json:"email"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type AuditSubscriptionDetails struct {
+	Plan      string    `json:"plan"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	PaymentId int64     `json:"payment_id,omitempty"`
+	ChargeRef string    `json:"charge_ref,omitempty"` // of the renewal
+	FromPlan  string    `json:"from_plan,omitempty"`  // of a plan change
+	Trial     bool      `json:"trial,omitempty"`      // started as a trial
+}
+
+// AuditModerationDetails is what a moderator did to UserId, Action is the moderation action or review status
+type AuditModerationDetails struct {
+	UserId    int64      `json:"user_id"`
+	Action    string     `json:"action"`
+	Note      string     `json:"note,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type AuditLogListParam struct {
+	ActorId    int64     `validate:"omitempty,min=1"`
+	Action     string    `validate:"max=64"`
+	TargetType string    `validate:"omitempty,oneof=user subscription report abuse_flag"`
+	TargetId   int64     `validate:"omitempty,min=1"`
+	From       time.Time // inclusive, zero for no bound
+	To         time.Time `validate:"omitempty,gtfield=From"` // exclusive, zero for no bound
+	Cursor     string    `validate:"omitempty,base64rawurl"`
+	Limit      int       `validate:"omitempty,min=1,max=100"`
+}
+
+// AuditLogPageParam is a keyset page of the log, strictly before (BeforeAt, BeforeId), newest first.
+// Zero values leave a filter out
+type AuditLogPageParam struct {
+	ActorId    int64
+	Action     string
+	TargetType string
+	TargetId   int64
+	From       sql.NullTime
+	To         sql.NullTime
+	BeforeAt   time.Time
+	BeforeId   int64
+	Limit      int
+}
+
+type AuditLogResponse struct {
+	ID         int64           `json:"id"`
+	ActorId    int64           `json:"actor_id,omitempty"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetId   int64           `json:"target_id,omitempty"`
+	Details    json.RawMessage `json:"details"`
+	RequestId  string          `json:"request_id"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"user_agent"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type AuditLogListResponse struct {
+	Logs       []AuditLogResponse `json:"logs"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Block struct {
+	ID        int64        `db:"id" json:"id"`
+	BlockerId int64        `db:"blocker_id" json:"blocker_id"`
+	BlockedId int64        `db:"blocked_id" json:"blocked_id"`
+	CreatedAt sql.NullTime `db:"created_at" json:"created_at"`
+	UpdatedAt sql.NullTime `db:"updated_at" json:"updated_at"`
+	DeletedAt sql.NullTime `db:"deleted_at" json:"deleted_at"`
+}
+
+type BlockParam struct {
+	UserId int64 `json:"user_id" validate:"required,min=1"`
+}
+
+// BlockedUser is a block joined with the blocked user's profile, profile reads hide blocked users
+type BlockedUser struct {
+	UserId    int64          `db:"user_id"`
+	FullName  string         `db:"name"`
+	ProfPic   sql.NullString `db:"profile_picture"`
+	BlockedAt time.Time      `db:"blocked_at"`
+}
+
+type BlockResponse struct {
+	UserId    int64     `json:"user_id"`
+	FullName  string    `json:"fullname"`
+	ProfPic   string    `json:"profile_picture"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+type BlockListResponse struct {
+	Blocks []BlockResponse `json:"blocks"`
+}
@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	BoostSourcePlan       = "plan"
+	BoostSourceConsumable = "consumable"
+
+	BoostStatViews = "views"
+	BoostStatLikes = "likes"
+)
+
+type Boost struct {
+	ID           int64        `db:"id" json:"id"`
+	UserId       int64        `db:"user_id" json:"user_id"`
+	Source       string       `db:"source" json:"source"`
+	Location     string       `db:"location" json:"location"`
+	StartedAt    time.Time    `db:"started_at" json:"started_at"`
+	EndsAt       time.Time    `db:"ends_at" json:"ends_at"`
+	Views        int64        `db:"views" json:"views"`
+	Likes        int64        `db:"likes" json:"likes"`
+	SummarizedAt sql.NullTime `db:"summarized_at" json:"summarized_at"`
+	CreatedAt    sql.NullTime `db:"created_at" json:"created_at"`
+	UpdatedAt    sql.NullTime `db:"updated_at" json:"updated_at"`
+	DeletedAt    sql.NullTime `db:"deleted_at" json:"deleted_at"`
+}
+
+type BoostCredit struct {
+	ID        int64         `db:"id" json:"id"`
+	UserId    int64         `db:"user_id" json:"user_id"`
+	Amount    int64         `db:"amount" json:"amount"`
+	Reason    string        `db:"reason" json:"reason"`
+	PaymentId sql.NullInt64 `db:"payment_id" json:"payment_id"` // of a purchase
+	CreatedAt sql.NullTime  `db:"created_at" json:"created_at"`
+	UpdatedAt sql.NullTime  `db:"updated_at" json:"updated_at"`
+	DeletedAt sql.NullTime  `db:"deleted_at" json:"deleted_at"`
+}
+
+type BoostPurchaseParam struct {
+	Quantity int `json:"quantity" validate:"required,min=1,max=100"`
+}
+
+type BoostResponse struct {
+	ID        int64     `json:"id"`
+	Source    string    `json:"source"`
+	Active    bool      `json:"active"`
+	StartedAt time.Time `json:"started_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Views     int64     `json:"views"`
+	Likes     int64     `json:"likes"`
+}
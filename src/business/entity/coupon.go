@@ -0,0 +1,72 @@
+package entity
+
+import (
+	"database/sql"
+	"encoding/json"
+)
+
+const (
+	CouponPercent   = "percent"    // Value percent off the price
+	CouponFixed     = "fixed"      // Value off the price, in the smallest unit of Currency
+	CouponExtraDays = "extra_days" // Value days added to the period, at full price
+)
+
+const (
+	CouponRedemptionReserved = "reserved" // while the payment is pending
+	CouponRedemptionRedeemed = "redeemed"
+	CouponRedemptionReleased = "released" // the payment failed
+)
+
+// Coupon is a campaign code, it only applies to the first period of a subscription
+type Coupon struct {
+	ID             int64           `db:"id" json:"id"`
+	Code           string          `db:"code" json:"code"`
+	Kind           string          `db:"kind" json:"kind"`
+	Value          int64           `db:"value" json:"value"`
+	Currency       sql.NullString  `db:"currency" json:"currency"`
+	Plans          json.RawMessage `db:"plans" json:"plans"` // plan codes it applies to, empty is every plan
+	StartsAt       sql.NullTime    `db:"starts_at" json:"starts_at"`
+	EndsAt         sql.NullTime    `db:"ends_at" json:"ends_at"`
+	MaxRedemptions sql.NullInt64   `db:"max_redemptions" json:"max_redemptions"`
+	MaxPerUser     int             `db:"max_per_user" json:"max_per_user"`
+	Active         bool            `db:"active" json:"active"`
+	CreatedAt      sql.NullTime    `db:"created_at" json:"created_at"`
+	UpdatedAt      sql.NullTime    `db:"updated_at" json:"updated_at"`
+	DeletedAt      sql.NullTime    `db:"deleted_at" json:"deleted_at"`
+}
+
+type CouponRedemption struct {
+	ID        int64        `db:"id" json:"id"`
+	CouponId  int64        `db:"coupon_id" json:"coupon_id"`
+	UserId    int64        `db:"user_id" json:"user_id"`
+	PaymentId int64        `db:"payment_id" json:"payment_id"`
+	Status    string       `db:"status" json:"status"`
+	Discount  int64        `db:"discount" json:"discount"`
+	ExtraDays int          `db:"extra_days" json:"extra_days"`
+	CreatedAt sql.NullTime `db:"created_at" json:"created_at"`
+	UpdatedAt sql.NullTime `db:"updated_at" json:"updated_at"`
+	DeletedAt sql.NullTime `db:"deleted_at" json:"deleted_at"`
+}
+
+// CouponUsage counts the redemptions held against the limits of a coupon, overall and by one user
+type CouponUsage struct {
+	Total  int `db:"total"`
+	ByUser int `db:"by_user"`
+}
+
+type CouponParam struct {
+	Plan   string `json:"plan" validate:"required,max=64"`
+	Coupon string `json:"coupon" validate:"required,max=64"`
+}
+
+// QuoteResponse is what a checkout of Plan with Coupon would cost, Amount is Price less Discount
+type QuoteResponse struct {
+	Plan         string `json:"plan"`
+	Coupon       string `json:"coupon,omitempty"`
+	Price        int64  `json:"price"`
+	Discount     int64  `json:"discount"`
+	Amount       int64  `json:"amount"`
+	Currency     string `json:"currency"`
+	DurationDays int    `json:"duration_days"`
+	ExtraDays    int    `json:"extra_days"`
+}
@@ -1,5 +1,7 @@
 package entity
 
+import "time"
+
 const (
 	Like = "right"
 	Pass = "left"
@@ -14,4 +16,30 @@ type Discovery struct {
 	Location string   `json:"location"`
 	Interest string   `json:"interest"`
 	Photos   []string `json:"photos"`
+	Verified bool     `json:"verified"` // the plan of the user has FeatureVerifiedBadge
+}
+
+type DiscoveryParam struct {
+	UserId      int64
+	Gender      string
+	PassCoolOff time.Duration
+}
+
+type ReceivedLike struct {
+	LikeId   int64     `json:"like_id"` // swiped on in place of the user id, see SwipeParam
+	UserId   int64     `json:"user_id,omitempty"`
+	FullName string    `json:"fullname,omitempty"`
+	Age      int64     `json:"age"`
+	Gender   string    `json:"gender"`
+	Bio      string    `json:"bio,omitempty"`
+	Location string    `json:"location,omitempty"`
+	Interest string    `json:"interest,omitempty"`
+	ProfPic  string    `json:"profile_picture,omitempty"`
+	Blurred  bool      `json:"blurred"`
+	LikedAt  time.Time `json:"liked_at"`
+}
+
+type ReceivedLikesResponse struct {
+	Total int            `json:"total"`
+	Likes []ReceivedLike `json:"likes"`
 }
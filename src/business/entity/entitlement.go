@@ -0,0 +1,11 @@
+package entity
+
+import "time"
+
+// EntitlementResponse is what the subscription of the user turns on, clients show or hide features by it
+type EntitlementResponse struct {
+	Plan     string     `json:"plan,omitempty"`
+	Status   string     `json:"status,omitempty"`
+	EndDate  *time.Time `json:"end_date,omitempty"`
+	Features []string   `json:"features"` // Feature* codes, empty without a subscription
+}
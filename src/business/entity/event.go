@@ -0,0 +1,104 @@
+package entity
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventMatchCreated              = "match.created"
+	EventUserRegistered            = "user.registered"
+	EventSubscriptionStarted       = "subscription.started"
+	EventSubscriptionExpiring      = "subscription.expiring"
+	EventSubscriptionRenewed       = "subscription.renewed"
+	EventSubscriptionRenewalFailed = "subscription.renewal_failed"
+	EventSubscriptionCancelled     = "subscription.cancelled"
+	EventSubscriptionExpired       = "subscription.expired"
+	EventSubscriptionChanged       = "subscription.changed"
+	EventMessageSent               = "message.sent"
+	EventMatchExpiring             = "match.expiring"
+	EventMatchExpired              = "match.expired"
+	EventLikeReceived              = "like.received"
+	EventSwipeRecorded             = "swipe.recorded"
+)
+
+// Event is a domain event as stored in the outbox, UserId is who the event is about
+type Event struct {
+	ID          int64           `db:"id" json:"id"`
+	Type        string          `db:"type" json:"type"`
+	UserId      int64           `db:"user_id" json:"user_id"`
+	Payload     json.RawMessage `db:"payload" json:"payload"`
+	Attempts    int             `db:"attempts" json:"attempts"`
+	LastError   sql.NullString  `db:"last_error" json:"last_error"`
+	PublishedAt sql.NullTime    `db:"published_at" json:"published_at"`
+	CreatedAt   sql.NullTime    `db:"created_at" json:"created_at"`
+	UpdatedAt   sql.NullTime    `db:"updated_at" json:"updated_at"`
+	DeletedAt   sql.NullTime    `db:"deleted_at" json:"deleted_at"`
+}
+
+type MatchCreatedPayload struct {
+	MatchId int64 `json:"match_id"`
+	UserId1 int64 `json:"user_id_1"`
+	UserId2 int64 `json:"user_id_2"`
+}
+
+// LikeReceivedPayload doesn't carry the liker, that stays behind received likes
+type LikeReceivedPayload struct {
+	UserId int64 `json:"user_id"`
+}
+
+// SwipeRecordedPayload is where the swipes of the user came from, for the bot detector
+type SwipeRecordedPayload struct {
+	UserId   int64  `json:"user_id"`
+	IP       string `json:"ip"`
+	DeviceId string `json:"device_id"`
+}
+
+type MatchExpiryPayload struct {
+	MatchId   int64     `json:"match_id"`
+	UserId1   int64     `json:"user_id_1"`
+	UserId2   int64     `json:"user_id_2"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type UserRegisteredPayload struct {
+	UserId int64  `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+type SubscriptionStartedPayload struct {
+	SubscriptionId int64      `json:"subscription_id"`
+	UserId         int64      `json:"user_id"`
+	Plan           string     `json:"plan"`
+	StartDate      time.Time  `json:"start_date"`
+	EndDate        time.Time  `json:"end_date"`
+	TrialEndsAt    *time.Time `json:"trial_ends_at,omitempty"`
+}
+
+// SubscriptionLifecyclePayload is the state of the subscription after the change, GraceUntil is set while in grace
+type SubscriptionLifecyclePayload struct {
+	SubscriptionId int64      `json:"subscription_id"`
+	UserId         int64      `json:"user_id"`
+	Plan           string     `json:"plan"`
+	Status         string     `json:"status"`
+	EndDate        time.Time  `json:"end_date"`
+	GraceUntil     *time.Time `json:"grace_until,omitempty"`
+}
+
+// SubscriptionChangedPayload is a switch of plan once applied, Kind is one of SubscriptionChange*
+type SubscriptionChangedPayload struct {
+	SubscriptionId int64     `json:"subscription_id"`
+	UserId         int64     `json:"user_id"`
+	Kind           string    `json:"kind"`
+	FromPlan       string    `json:"from_plan"`
+	ToPlan         string    `json:"to_plan"`
+	EndDate        time.Time `json:"end_date"`
+}
+
+type MessageSentPayload struct {
+	MessageId  int64 `json:"message_id"`
+	MatchId    int64 `json:"match_id"`
+	SenderId   int64 `json:"sender_id"`
+	ReceiverId int64 `json:"receiver_id"`
+}
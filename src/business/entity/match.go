@@ -1,12 +1,75 @@
 package entity
 
-import "database/sql"
+import (
+	"database/sql"
+	"time"
+)
 
+const (
+	MatchOrderNewest   = "newest"
+	MatchOrderActivity = "activity"
+
+	// who may send the first message of a match
+	FirstMoveAnyone     = "anyone"
+	FirstMoveFemale     = Female
+	FirstMoveMale       = Male
+	FirstMoveFirstLiker = "first_liker"
+)
+
+// Match is created by the swipe that completes it, so UserId1 liked last and UserId2 liked first
 type Match struct {
-	ID        int64        `db:"id" json:"id"`
-	UserId1   int64        `db:"user_id_1" json:"user_id_1"`
-	UserId2   int64        `db:"user_id_2" json:"user_id_2"`
-	CreatedAt sql.NullTime `db:"created_at" json:"created_at"`
-	UpdatedAt sql.NullTime `db:"updated_at" json:"updated_at"`
-	DeletedAt sql.NullTime `db:"deleted_at" json:"deleted_at"`
+	ID               int64         `db:"id" json:"id"`
+	UserId1          int64         `db:"user_id_1" json:"user_id_1"`
+	UserId2          int64         `db:"user_id_2" json:"user_id_2"`
+	LastActivityAt   sql.NullTime  `db:"last_activity_at" json:"last_activity_at"`
+	EngagedAt        sql.NullTime  `db:"engaged_at" json:"engaged_at"`
+	FirstMessageAt   sql.NullTime  `db:"first_message_at" json:"first_message_at"`
+	ExpiresAt        sql.NullTime  `db:"expires_at" json:"expires_at"`
+	ExpiryNotifiedAt sql.NullTime  `db:"expiry_notified_at" json:"expiry_notified_at"`
+	ExtendedAt       sql.NullTime  `db:"extended_at" json:"extended_at"`
+	CreatedAt        sql.NullTime  `db:"created_at" json:"created_at"`
+	UpdatedAt        sql.NullTime  `db:"updated_at" json:"updated_at"`
+	DeletedAt        sql.NullTime  `db:"deleted_at" json:"deleted_at"`
+	DeletedBy        sql.NullInt64 `db:"deleted_by" json:"deleted_by"`
+}
+
+type MatchListParam struct {
+	Cursor string `validate:"omitempty,base64rawurl"`
+	Limit  int    `validate:"omitempty,min=1,max=100"`
+	Order  string `validate:"omitempty,oneof=newest activity"`
+}
+
+// MatchPageParam is a keyset page, matches strictly before (BeforeAt, BeforeId) in the given order
+type MatchPageParam struct {
+	UserId   int64
+	Order    string
+	BeforeAt time.Time
+	BeforeId int64
+	Limit    int
+}
+
+type MatchResponse struct {
+	ID             int64           `json:"id"`
+	UserId         int64           `json:"user_id"`
+	Profile        ProfileResponse `json:"profile"`
+	Photo          string          `json:"photo"`
+	MatchedAt      time.Time       `json:"matched_at"`
+	LastActivityAt time.Time       `json:"last_activity_at"`
+	ExpiresAt      *time.Time      `json:"expires_at"`
+}
+
+type MatchListResponse struct {
+	Matches    []MatchResponse `json:"matches"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+type MatchExpiryParam struct {
+	ExpireAfter time.Duration
+	WarnBefore  time.Duration
+	Limit       int
+}
+
+type MatchExtendResponse struct {
+	ID        int64     `json:"id"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
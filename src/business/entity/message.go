@@ -0,0 +1,73 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Message struct {
+	ID        int64        `db:"id" json:"id"`
+	MatchId   int64        `db:"match_id" json:"match_id"`
+	SenderId  int64        `db:"sender_id" json:"sender_id"`
+	Body      string       `db:"body" json:"body"`
+	ReadAt    sql.NullTime `db:"read_at" json:"read_at"`
+	CreatedAt sql.NullTime `db:"created_at" json:"created_at"`
+	UpdatedAt sql.NullTime `db:"updated_at" json:"updated_at"`
+	DeletedAt sql.NullTime `db:"deleted_at" json:"deleted_at"`
+}
+
+type MessageParam struct {
+	Body string `json:"body" validate:"required,max=2000"`
+}
+
+// MessageListParam pages both the history of a conversation and the conversation list
+type MessageListParam struct {
+	Cursor string `validate:"omitempty,base64rawurl"`
+	Limit  int    `validate:"omitempty,min=1,max=100"`
+}
+
+// MessagePageParam is a keyset page, messages strictly before (BeforeAt, BeforeId), newest first
+type MessagePageParam struct {
+	MatchId  int64
+	BeforeAt time.Time
+	BeforeId int64
+	Limit    int
+}
+
+// ConversationSummary is the last message and the unread count of a match, as seen by one of its users
+type ConversationSummary struct {
+	MatchId       int64     `db:"match_id"`
+	LastMessageId int64     `db:"last_message_id"`
+	SenderId      int64     `db:"sender_id"`
+	Body          string    `db:"body"`
+	CreatedAt     time.Time `db:"created_at"`
+	Unread        int64     `db:"unread"`
+}
+
+type MessageResponse struct {
+	ID        int64      `json:"id"`
+	MatchId   int64      `json:"match_id"`
+	SenderId  int64      `json:"sender_id"`
+	Body      string     `json:"body"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type MessageListResponse struct {
+	Messages   []MessageResponse `json:"messages"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+type ConversationResponse struct {
+	MatchId        int64            `json:"match_id"`
+	UserId         int64            `json:"user_id"`
+	Profile        ProfileResponse  `json:"profile"`
+	LastMessage    *MessageResponse `json:"last_message"`
+	Unread         int64            `json:"unread"`
+	LastActivityAt time.Time        `json:"last_activity_at"`
+}
+
+type ConversationListResponse struct {
+	Conversations []ConversationResponse `json:"conversations"`
+	NextCursor    string                 `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	NotificationMatchCreated          = "match.created"
+	NotificationMatchExpiring         = "match.expiring"
+	NotificationMatchExpired          = "match.expired"
+	NotificationLikeReceived          = "like.received"
+	NotificationMessageReceived       = "message.received"
+	NotificationSubscriptionActivated = "subscription.activated"
+	NotificationSubscriptionExpiring  = "subscription.expiring"
+	NotificationSubscriptionRenewed   = "subscription.renewed"
+	NotificationSubscriptionFailed    = "subscription.renewal_failed"
+	NotificationSubscriptionExpired   = "subscription.expired"
+	NotificationSubscriptionChanged   = "subscription.changed"
+	NotificationQuotaReset            = "quota.reset"
+
+	// BroadcastUserId addresses every connected user
+	BroadcastUserId = 0
+)
+
+// Notification is pushed in real time to the connected devices of UserId, the type is open so any feature can publish its own
+// Id is set when the same notification may be pushed again, clients drop the ones they've seen
+type Notification struct {
+	Id     string          `json:"id,omitempty"`
+	UserId int64           `json:"user_id"`
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	SentAt time.Time       `json:"sent_at"`
+}
+
+// RealtimeTicket opens one websocket before ExpiresAt, browsers can't send the access token on the handshake
+type RealtimeTicket struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,73 @@
+package entity
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const (
+	PaymentPending = "pending"
+	PaymentPaid    = "paid"
+	PaymentFailed  = "failed"
+	// captured by the provider for something that can't be given anymore, the worker refunds it
+	PaymentRefundDue = "refund_due"
+	PaymentRefunded  = "refunded"
+)
+
+// Payment is a checkout of a plan or of a pack of Boosts, Amount is what's left to pay of the price after Credit and Discount, all
+// in the smallest unit of Currency
+type Payment struct {
+	ID             int64          `db:"id" json:"id"`
+	UserId         int64          `db:"user_id" json:"user_id"`
+	Plan           string         `db:"plan" json:"plan"`
+	Boosts         int            `db:"boosts" json:"boosts"` // credited once it's paid, a payment for boosts has no plan
+	Amount         int64          `db:"amount" json:"amount"`
+	Credit         int64          `db:"credit" json:"credit"` // taken off the price of an upgrade
+	CouponId       sql.NullInt64  `db:"coupon_id" json:"coupon_id"`
+	Discount       int64          `db:"discount" json:"discount"` // of the coupon, taken off the price
+	ExtraDays      int            `db:"extra_days" json:"extra_days"`
+	TrialDays      int            `db:"trial_days" json:"trial_days"` // of the trial it puts a card on file for
+	Currency       string         `db:"currency" json:"currency"`
+	Provider       string         `db:"provider" json:"provider"`
+	ProviderRef    sql.NullString `db:"provider_ref" json:"provider_ref"`
+	CheckoutURL    string         `db:"checkout_url" json:"checkout_url"`
+	Status         string         `db:"status" json:"status"`
+	SubscriptionId sql.NullInt64  `db:"subscription_id" json:"subscription_id"`
+	FromPlan       sql.NullString `db:"from_plan" json:"from_plan"`   // of an upgrade, with PeriodEnd the period its credit was taken from
+	PeriodEnd      sql.NullTime   `db:"period_end" json:"period_end"` // of the subscription, SubscriptionId is set from the checkout on
+	PaidAt         sql.NullTime   `db:"paid_at" json:"paid_at"`
+	RefundRef      sql.NullString `db:"refund_ref" json:"refund_ref"` // the provider's, once refunded
+	CreatedAt      sql.NullTime   `db:"created_at" json:"created_at"`
+	UpdatedAt      sql.NullTime   `db:"updated_at" json:"updated_at"`
+	DeletedAt      sql.NullTime   `db:"deleted_at" json:"deleted_at"`
+}
+
+// PaymentEvent is a webhook accepted from a provider, EventId is the provider's
+type PaymentEvent struct {
+	ID        int64           `db:"id" json:"id"`
+	Provider  string          `db:"provider" json:"provider"`
+	EventId   string          `db:"event_id" json:"event_id"`
+	Type      string          `db:"type" json:"type"`
+	PaymentId sql.NullInt64   `db:"payment_id" json:"payment_id"`
+	Payload   json.RawMessage `db:"payload" json:"payload"`
+	CreatedAt sql.NullTime    `db:"created_at" json:"created_at"`
+	UpdatedAt sql.NullTime    `db:"updated_at" json:"updated_at"`
+	DeletedAt sql.NullTime    `db:"deleted_at" json:"deleted_at"`
+}
+
+type PaymentResponse struct {
+	ID             int64      `json:"id"`
+	Plan           string     `json:"plan,omitempty"`
+	Boosts         int        `json:"boosts,omitempty"`
+	Amount         int64      `json:"amount"`
+	Credit         int64      `json:"credit,omitempty"`
+	Discount       int64      `json:"discount,omitempty"`
+	ExtraDays      int        `json:"extra_days,omitempty"`
+	TrialDays      int        `json:"trial_days,omitempty"`
+	Currency       string     `json:"currency"`
+	Status         string     `json:"status"`
+	CheckoutURL    string     `json:"checkout_url,omitempty"`
+	SubscriptionId int64      `json:"subscription_id,omitempty"`
+	PaidAt         *time.Time `json:"paid_at,omitempty"`
+}
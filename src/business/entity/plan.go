@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"database/sql"
+	"encoding/json"
+)
+
+const (
+	FeatureUnlimitedSwipes = "unlimited_swipes"
+	FeatureSeeLikes        = "see_likes"
+	FeatureRewind          = "rewind"
+	FeatureBoosts          = "boosts"
+	FeatureVerifiedBadge   = "verified_badge"
+	FeatureExtendMatch     = "extend_match"
+)
+
+// Plan is a tier of the subscription catalog, Price is in the smallest unit of Currency
+type Plan struct {
+	ID           int64           `db:"id" json:"id"`
+	Code         string          `db:"code" json:"code"`
+	Name         json.RawMessage `db:"name" json:"name"` // language code to display name
+	Price        int64           `db:"price" json:"price"`
+	Currency     string          `db:"currency" json:"currency"`
+	DurationDays int             `db:"duration_days" json:"duration_days"`
+	Features     json.RawMessage `db:"features" json:"features"` // list of Feature* codes
+	Family       string          `db:"family" json:"family"`     // plans sharing it share one trial
+	TrialDays    int             `db:"trial_days" json:"trial_days"`
+	Active       bool            `db:"active" json:"active"`
+	SortOrder    int             `db:"sort_order" json:"sort_order"`
+	CreatedAt    sql.NullTime    `db:"created_at" json:"created_at"`
+	UpdatedAt    sql.NullTime    `db:"updated_at" json:"updated_at"`
+	DeletedAt    sql.NullTime    `db:"deleted_at" json:"deleted_at"`
+}
+
+type PlanResponse struct {
+	Code         string   `json:"code"`
+	Name         string   `json:"name"`
+	Price        int64    `json:"price"`
+	Currency     string   `json:"currency"`
+	DurationDays int      `json:"duration_days"`
+	TrialDays    int      `json:"trial_days,omitempty"`
+	Features     []string `json:"features"`
+}
@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+const (
+	RateLimitAuth    = "auth"    // login and register, by IP
+	RateLimitDefault = "default" // every authenticated route
+	RateLimitSwipe   = "swipe"
+	RateLimitMessage = "message"
+	RateLimitReport  = "report"
+)
+
+type RateLimitPolicy struct {
+	Limit  int
+	Window time.Duration
+}
+
+// RateLimitCount is what the counter saw for a key, the fixed window starting at WindowStart and the one before it
+type RateLimitCount struct {
+	Current     int64
+	Previous    int64
+	WindowStart time.Time
+}
+
+// RateLimitResult is a zero Limit when the policy is disabled
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
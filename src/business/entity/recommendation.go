@@ -0,0 +1,16 @@
+package entity
+
+import "database/sql"
+
+// ColdStartUserId owns the popularity list served to users without their own recommendations
+const ColdStartUserId = 0
+
+type Recommendation struct {
+	ID          int64        `db:"id" json:"id"`
+	UserId      int64        `db:"user_id" json:"user_id"`
+	CandidateId int64        `db:"candidate_id" json:"candidate_id"`
+	Score       float64      `db:"score" json:"score"`
+	CreatedAt   sql.NullTime `db:"created_at" json:"created_at"`
+	UpdatedAt   sql.NullTime `db:"updated_at" json:"updated_at"`
+	DeletedAt   sql.NullTime `db:"deleted_at" json:"deleted_at"`
+}
@@ -0,0 +1,105 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	ReportTargetProfile = "profile"
+	ReportTargetPhoto   = "photo"
+	ReportTargetMessage = "message"
+
+	ReportStatusOpen      = "open"
+	ReportStatusActioned  = "actioned"
+	ReportStatusDismissed = "dismissed"
+
+	ModerationAssign  = "assign"
+	ModerationDismiss = "dismiss"
+	ModerationWarn    = "warn"
+	ModerationSuspend = "suspend"
+	ModerationBan     = "ban"
+)
+
+type Report struct {
+	ID         int64         `db:"id" json:"id"`
+	ReporterId int64         `db:"reporter_id" json:"reporter_id"`
+	ReportedId int64         `db:"reported_id" json:"reported_id"`
+	TargetType string        `db:"target_type" json:"target_type"`
+	TargetId   sql.NullInt64 `db:"target_id" json:"target_id"`
+	Reason     string        `db:"reason" json:"reason"`
+	Details    string        `db:"details" json:"details"`
+	Status     string        `db:"status" json:"status"`
+	AssigneeId sql.NullInt64 `db:"assignee_id" json:"assignee_id"`
+	ResolvedAt sql.NullTime  `db:"resolved_at" json:"resolved_at"`
+	CreatedAt  sql.NullTime  `db:"created_at" json:"created_at"`
+	UpdatedAt  sql.NullTime  `db:"updated_at" json:"updated_at"`
+	DeletedAt  sql.NullTime  `db:"deleted_at" json:"deleted_at"`
+}
+
+type ModerationAction struct {
+	ID          int64         `db:"id" json:"id"`
+	ReportId    sql.NullInt64 `db:"report_id" json:"report_id"`
+	ModeratorId int64         `db:"moderator_id" json:"moderator_id"`
+	UserId      int64         `db:"user_id" json:"user_id"`
+	Action      string        `db:"action" json:"action"`
+	Note        string        `db:"note" json:"note"`
+	ExpiresAt   sql.NullTime  `db:"expires_at" json:"expires_at"`
+	CreatedAt   sql.NullTime  `db:"created_at" json:"created_at"`
+	UpdatedAt   sql.NullTime  `db:"updated_at" json:"updated_at"`
+	DeletedAt   sql.NullTime  `db:"deleted_at" json:"deleted_at"`
+}
+
+// ReportParam reports a profile or its photo by user id, a message by its id
+type ReportParam struct {
+	TargetType string `json:"target_type" validate:"oneof=profile photo message"`
+	UserId     int64  `json:"user_id" validate:"required_unless=TargetType message,omitempty,min=1"`
+	MessageId  int64  `json:"message_id" validate:"required_if=TargetType message,omitempty,min=1"`
+	Reason     string `json:"reason" validate:"oneof=spam harassment inappropriate fake underage other"`
+	Details    string `json:"details" validate:"max=2000"`
+}
+
+type ReportListParam struct {
+	Status     string `validate:"omitempty,oneof=open actioned dismissed"`
+	AssigneeId int64  `validate:"omitempty,min=1"`
+	Cursor     string `validate:"omitempty,base64rawurl"`
+	Limit      int    `validate:"omitempty,min=1,max=100"`
+}
+
+// ReportPageParam is a keyset page of the queue, reports strictly after (AfterAt, AfterId), oldest first
+type ReportPageParam struct {
+	Status     string
+	AssigneeId int64
+	AfterAt    time.Time
+	AfterId    int64
+	Limit      int
+}
+
+type ReportAssignParam struct {
+	AssigneeId int64 `json:"assignee_id" validate:"omitempty,min=1"` // defaults to the moderator making the request
+}
+
+type ReportResolveParam struct {
+	Action      string `json:"action" validate:"oneof=dismiss warn suspend ban"`
+	Note        string `json:"note" validate:"max=2000"`
+	SuspendDays int    `json:"suspend_days" validate:"required_if=Action suspend,omitempty,min=1,max=365"`
+}
+
+type ReportResponse struct {
+	ID         int64      `json:"id"`
+	ReporterId int64      `json:"reporter_id"`
+	ReportedId int64      `json:"reported_id"`
+	TargetType string     `json:"target_type"`
+	MessageId  int64      `json:"message_id,omitempty"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details"`
+	Status     string     `json:"status"`
+	AssigneeId int64      `json:"assignee_id,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type ReportListResponse struct {
+	Reports    []ReportResponse `json:"reports"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
@@ -5,22 +5,124 @@ import (
 	"time"
 )
 
+// plans every deployment ships with, the rest of the catalog lives in the plans table
 const (
 	UnlimitedPlan = "unlimited"
 	VerifiedPlan  = "verified"
 )
 
+// the features of a subscription stay on in every status but expired
+const (
+	SubscriptionActive    = "active"    // renews at EndDate
+	SubscriptionCancelled = "cancelled" // runs until EndDate, then expires
+	SubscriptionGrace     = "grace"     // renewal failed, retried until GraceUntil
+	SubscriptionExpired   = "expired"
+)
+
 type Subscription struct {
-	ID        int64        `db:"id" json:"id"`
-	UserId    int64        `db:"user_id" json:"user_id"`
-	Plan      string       `db:"plan" json:"plan"`
-	StartDate time.Time    `db:"start_date" json:"start_date"`
-	EndDate   time.Time    `db:"end_date" json:"end_date"`
-	CreatedAt sql.NullTime `db:"created_at" json:"created_at"`
-	UpdatedAt sql.NullTime `db:"updated_at" json:"updated_at"`
-	DeletedAt sql.NullTime `db:"deleted_at" json:"deleted_at"`
+	ID              int64          `db:"id" json:"id"`
+	UserId          int64          `db:"user_id" json:"user_id"`
+	Plan            string         `db:"plan" json:"plan"`
+	StartDate       time.Time      `db:"start_date" json:"start_date"`
+	EndDate         time.Time      `db:"end_date" json:"end_date"`
+	Status          string         `db:"status" json:"status"`
+	CancelledAt     sql.NullTime   `db:"cancelled_at" json:"cancelled_at"`
+	GraceUntil      sql.NullTime   `db:"grace_until" json:"grace_until"`
+	RenewalAttempts int            `db:"renewal_attempts" json:"renewal_attempts"`
+	NextRenewalAt   sql.NullTime   `db:"next_renewal_at" json:"next_renewal_at"`
+	ReminderSentAt  sql.NullTime   `db:"reminder_sent_at" json:"reminder_sent_at"`
+	PendingPlan     sql.NullString `db:"pending_plan" json:"pending_plan"`     // downgrade the renewal switches to
+	TrialEndsAt     sql.NullTime   `db:"trial_ends_at" json:"trial_ends_at"`   // of the trial it started with
+	TrialConverts   bool           `db:"trial_converts" json:"trial_converts"` // the trial is charged at its end, a card is on file
+	PeriodAmount    int64          `db:"period_amount" json:"period_amount"`   // paid for the current period, an upgrade credits what's left of it
+	CreatedAt       sql.NullTime   `db:"created_at" json:"created_at"`
+	UpdatedAt       sql.NullTime   `db:"updated_at" json:"updated_at"`
+	DeletedAt       sql.NullTime   `db:"deleted_at" json:"deleted_at"`
+}
+
+const (
+	SubscriptionChangeStart     = "start"
+	SubscriptionChangeUpgrade   = "upgrade"   // applied as soon as it's paid
+	SubscriptionChangeDowngrade = "downgrade" // applied at the end of the period
+	SubscriptionChangeExtend    = "extend"    // paid again for the plan it's on
+	SubscriptionChangeConvert   = "convert"   // paid for the plan during its trial, the paid period starts right away
+	SubscriptionChangeKeep      = "keep"      // a scheduled downgrade called off, never stored
+)
+
+// SubscriptionChange is an entry of the plan history of a subscription, Credit is what was left of the
+// previous plan and Amount what was charged for the change, both in the smallest unit of Currency
+type SubscriptionChange struct {
+	ID             int64          `db:"id" json:"id"`
+	SubscriptionId int64          `db:"subscription_id" json:"subscription_id"`
+	UserId         int64          `db:"user_id" json:"user_id"`
+	FromPlan       sql.NullString `db:"from_plan" json:"from_plan"`
+	ToPlan         string         `db:"to_plan" json:"to_plan"`
+	Kind           string         `db:"kind" json:"kind"`
+	Credit         int64          `db:"credit" json:"credit"`
+	Amount         int64          `db:"amount" json:"amount"`
+	Currency       string         `db:"currency" json:"currency"`
+	PaymentId      sql.NullInt64  `db:"payment_id" json:"payment_id"`
+	EffectiveAt    time.Time      `db:"effective_at" json:"effective_at"`
+	CreatedAt      sql.NullTime   `db:"created_at" json:"created_at"`
+	UpdatedAt      sql.NullTime   `db:"updated_at" json:"updated_at"`
+	DeletedAt      sql.NullTime   `db:"deleted_at" json:"deleted_at"`
+}
+
+const (
+	TrialActive    = "active"    // the features are on for free until the trial ends
+	TrialConverted = "converted" // a paid period followed
+	TrialEnded     = "ended"     // expired at its end without converting
+)
+
+// Trial is one taken by a user, a user gets one per plan family
+type Trial struct {
+	ID        int64         `db:"id" json:"id"`
+	UserId    int64         `db:"user_id" json:"user_id"`
+	Family    string        `db:"family" json:"family"`
+	Plan      string        `db:"plan" json:"plan"`
+	PaymentId sql.NullInt64 `db:"payment_id" json:"payment_id"` // of the card on file
+	StartedAt time.Time     `db:"started_at" json:"started_at"`
+	EndsAt    time.Time     `db:"ends_at" json:"ends_at"`
+	CreatedAt sql.NullTime  `db:"created_at" json:"created_at"`
+	UpdatedAt sql.NullTime  `db:"updated_at" json:"updated_at"`
+	DeletedAt sql.NullTime  `db:"deleted_at" json:"deleted_at"`
+}
+
+// SubscriptionResponse is the subscription with where the trial it started with stands
+type SubscriptionResponse struct {
+	Subscription
+	Trial *SubscriptionTrial `json:"trial,omitempty"`
+}
+
+type SubscriptionTrial struct {
+	Status        string    `json:"status"`
+	EndsAt        time.Time `json:"ends_at"`
+	RemainingDays int       `json:"remaining_days"`
+	Converts      bool      `json:"converts"` // charged at EndsAt, false once cancelled or without a card on file
+}
+
+// TrialResponse is a trial asked for, with a card on file it only starts once Payment is paid
+type TrialResponse struct {
+	Plan           string           `json:"plan"`
+	TrialDays      int              `json:"trial_days"`
+	SubscriptionId int64            `json:"subscription_id,omitempty"`
+	EndsAt         *time.Time       `json:"ends_at,omitempty"`
+	Payment        *PaymentResponse `json:"payment,omitempty"`
+}
+
+// PlanChangeResponse is a switch of plan, Payment is set when an upgrade is left to pay
+type PlanChangeResponse struct {
+	Kind        string           `json:"kind"`
+	FromPlan    string           `json:"from_plan"`
+	ToPlan      string           `json:"to_plan"`
+	Credit      int64            `json:"credit"`
+	Amount      int64            `json:"amount"`
+	Currency    string           `json:"currency"`
+	EffectiveAt time.Time        `json:"effective_at"`
+	Payment     *PaymentResponse `json:"payment,omitempty"`
 }
 
 type SubscriptionParam struct {
-	Plan string `json:"plan" validate:"oneof=unlimited verified"`
+	Plan   string `json:"plan" validate:"required,max=64"` // code of an active catalog plan
+	Coupon string `json:"coupon" validate:"omitempty,max=64"`
 }
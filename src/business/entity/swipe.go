@@ -1,6 +1,17 @@
 package entity
 
-import "database/sql"
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	SwipeApplied       = "applied"
+	SwipeDuplicate     = "duplicate"
+	SwipeSuperseded    = "superseded" // a later swipe on the same target in the batch replaced it
+	SwipeQuotaExceeded = "quota_exceeded"
+	SwipeFailed        = "failed"
+)
 
 type Swipe struct {
 	ID        int64        `db:"id" json:"id"`
@@ -13,7 +24,8 @@ type Swipe struct {
 }
 
 type SwipeParam struct {
-	SwipedId  int64  `json:"swiped_id" validate:"required"`
+	SwipedId  int64  `json:"swiped_id" validate:"required_without=LikeId"`
+	LikeId    int64  `json:"like_id"` // answers a blurred received like without knowing who sent it
 	Direction string `json:"direction" validate:"oneof=left right"`
 }
 
@@ -21,3 +33,22 @@ type SwipeResponse struct {
 	Match bool `json:"match,omitempty"`
 	Like  bool `json:"like,omitempty"`
 }
+
+type SwipeBatchParam struct {
+	Swipes []SwipeBatchItem `json:"swipes" validate:"required,min=1,max=100,dive"`
+}
+
+type SwipeBatchItem struct {
+	SwipedId  int64     `json:"swiped_id" validate:"required"`
+	Direction string    `json:"direction" validate:"oneof=left right"`
+	SwipedAt  time.Time `json:"swiped_at" validate:"required"` // client time the swipe was made, used to replay in order
+}
+
+type SwipeBatchResult struct {
+	Index    int    `json:"index"`
+	SwipedId int64  `json:"swiped_id"`
+	Status   string `json:"status"`
+	Match    bool   `json:"match,omitempty"`
+	Like     bool   `json:"like,omitempty"`
+	Err      error  `json:"-"` // translated by the handler, like the error of a single swipe
+}
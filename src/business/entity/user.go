@@ -9,16 +9,29 @@ import (
 const (
 	NextStateLogin  = "login"
 	NextStateVerify = "verify"
+
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusBanned    = "banned"
 )
 
 type User struct {
-	ID        int64        `db:"id"`
-	Email     string       `db:"email"`
-	Password  string       `db:"password"`
-	Verifed   bool         `db:"verified"`
-	CreatedAt sql.NullTime `db:"created_at"`
-	UpdatedAt sql.NullTime `db:"updated_at"`
-	DeletedAt sql.NullTime `db:"deleted_at"`
+	ID             int64        `db:"id"`
+	Email          string       `db:"email"`
+	Password       string       `db:"password"`
+	Verifed        bool         `db:"verified"`
+	Role           string       `db:"role"`
+	Status         string       `db:"status"`
+	SuspendedUntil sql.NullTime `db:"suspended_until"` // the suspension is over once passed, whatever Status says
+	StatusReason   string       `db:"status_reason"`
+	TokenVersion   int          `db:"token_version"` // carried in every token, bumping it revokes the tokens signed before
+	CreatedAt      sql.NullTime `db:"created_at"`
+	UpdatedAt      sql.NullTime `db:"updated_at"`
+	DeletedAt      sql.NullTime `db:"deleted_at"`
 }
 
 type SignInParam struct {
@@ -41,6 +54,12 @@ type SignUpParam struct {
 	ConfirmPassword string `json:"confirm_password" validate:"eqfield=Password"`
 }
 
+type ChangePasswordParam struct {
+	OldPassword     string `json:"old_password" validate:"required"`
+	Password        string `json:"password" validate:"required,min=6,nefield=OldPassword"`
+	ConfirmPassword string `json:"confirm_password" validate:"eqfield=Password"`
+}
+
 type SignUpResponse struct {
 	NextState string `json:"next_state"`
 }
@@ -0,0 +1,167 @@
+package abuse
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
+	"loverly/lib/cursor"
+	"loverly/lib/log"
+	"loverly/lib/operator"
+	"loverly/src/business/domain/abuse"
+	"loverly/src/business/domain/audit"
+	"loverly/src/business/domain/swipe"
+	"loverly/src/business/entity"
+	appErr "loverly/src/errors"
+	"time"
+)
+
+// Interface lets moderators review what the bot detector flagged, see dating for the detection itself
+type Interface interface {
+	GetList(ctx context.Context, param entity.AbuseFlagListParam) (entity.AbuseFlagListResponse, error)
+	Review(ctx context.Context, flagId int64, param entity.AbuseFlagReviewParam) (entity.AbuseFlagResponse, error)
+}
+
+const defaultLimit = 20
+
+var Now = time.Now
+
+type abuses struct {
+	log    log.Interface
+	abuse  abuse.Interface
+	swipe  swipe.Interface
+	audit  audit.Interface
+	atomic atomic.AtomicSessionProvider
+}
+
+func Init(log log.Interface, a abuse.Interface, sw swipe.Interface, au audit.Interface, at atomic.AtomicSessionProvider) Interface {
+	return &abuses{
+		log:    log,
+		abuse:  a,
+		swipe:  sw,
+		audit:  au,
+		atomic: at,
+	}
+}
+
+// GetList pages the flags oldest first, open flags unless another status is asked for
+func (a *abuses) GetList(ctx context.Context, param entity.AbuseFlagListParam) (entity.AbuseFlagListResponse, error) {
+	var result entity.AbuseFlagListResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	limit := operator.Ternary(param.Limit < 1, defaultLimit, param.Limit)
+	page := entity.AbuseFlagPageParam{
+		Status: operator.Ternary(param.Status == "", entity.AbuseFlagOpen, param.Status),
+		UserId: param.UserId,
+		Limit:  limit + 1, // one extra row tells whether there is a next page
+	}
+
+	if param.Cursor != "" {
+		var err error
+		page.AfterAt, page.AfterId, err = cursor.Decode(param.Cursor)
+		if err != nil {
+			return result, appErr.ErrInvalidCursor
+		}
+	}
+
+	flags, err := a.abuse.GetPage(ctx, page)
+	if err != nil {
+		return result, err
+	}
+
+	if len(flags) > limit {
+		flags = flags[:limit]
+		last := flags[len(flags)-1]
+		result.NextCursor = cursor.Encode(last.CreatedAt.Time, last.ID)
+	}
+
+	for _, flag := range flags {
+		result.Flags = append(result.Flags, toResponse(flag))
+	}
+
+	return result, nil
+}
+
+// Review confirms or dismisses an open flag, the user stays shadow-limited only while a flag is open or confirmed
+func (a *abuses) Review(ctx context.Context, flagId int64, param entity.AbuseFlagReviewParam) (entity.AbuseFlagResponse, error) {
+	var result entity.AbuseFlagResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	err := atomic.Atomic(ctx, a.atomic, a.log, func(ctx context.Context) error {
+		current, err := a.abuse.GetForUpdate(ctx, flagId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return appErr.ErrAbuseFlagNotFound
+			}
+			return err
+		}
+
+		if current.Status != entity.AbuseFlagOpen {
+			return appErr.ErrAbuseFlagAlreadyReviewed
+		}
+
+		current.Status = param.Status
+		current.Note = param.Note
+		current.ReviewerId = sql.NullInt64{Int64: int64(userId), Valid: true}
+		current.ReviewedAt = sql.NullTime{Time: Now(), Valid: true}
+		if err := a.abuse.Review(ctx, current); err != nil {
+			return err
+		}
+
+		if err := a.abuse.SyncShadowLimit(ctx, current.UserId); err != nil {
+			return err
+		}
+
+		if _, err := a.audit.Record(ctx, entity.AuditLog{
+			ActorId:    sql.NullInt64{Int64: int64(userId), Valid: true},
+			Action:     entity.AuditAbuseFlagReviewed,
+			TargetType: entity.AuditTargetAbuseFlag,
+			TargetId:   sql.NullInt64{Int64: current.ID, Valid: true},
+		}, entity.AuditModerationDetails{UserId: current.UserId, Action: param.Status, Note: param.Note}); err != nil {
+			return err
+		}
+
+		result = toResponse(current)
+
+		return nil
+	})
+	if err != nil {
+		return entity.AbuseFlagResponse{}, err
+	}
+
+	// the review is done, a failed invalidation only delays it
+	if err := a.swipe.InvalidateUser(ctx, result.UserId); err != nil {
+		a.log.Error(ctx, fmt.Sprintf("invalidate swipes of user %d err: %v", result.UserId, err))
+	}
+
+	return result, nil
+}
+
+func toResponse(flag entity.AbuseFlag) entity.AbuseFlagResponse {
+	resp := entity.AbuseFlagResponse{
+		ID:          flag.ID,
+		UserId:      flag.UserId,
+		Signal:      flag.Signal,
+		Explanation: flag.Explanation,
+		Status:      flag.Status,
+		ReviewerId:  flag.ReviewerId.Int64,
+		Note:        flag.Note,
+		CreatedAt:   flag.CreatedAt.Time,
+	}
+
+	if flag.ReviewedAt.Valid {
+		resp.ReviewedAt = &flag.ReviewedAt.Time
+	}
+
+	return resp
+}
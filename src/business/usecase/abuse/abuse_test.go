@@ -0,0 +1,254 @@
+package abuse
+
+import (
+	"context"
+	"database/sql"
+	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
+	mock_atomic "loverly/lib/atomic/mock"
+	"loverly/lib/cursor"
+	mock_log "loverly/lib/log/mock"
+	mock_abuse "loverly/src/business/domain/mock/abuse"
+	mock_audit "loverly/src/business/domain/mock/audit"
+	mock_swipe "loverly/src/business/domain/mock/swipe"
+	"loverly/src/business/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	appErr "loverly/src/errors"
+)
+
+func TestGetList(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	abuseMock := mock_abuse.NewMockInterface(ctrl)
+	swipeMock := mock_swipe.NewMockInterface(ctrl)
+	auditMock := mock_audit.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	type args struct {
+		ctx   context.Context
+		param entity.AbuseFlagListParam
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		want     entity.AbuseFlagListResponse
+		wantErr  error
+	}{
+		{
+			name: "err invalid user id",
+			args: args{
+				ctx: context.Background(),
+			},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "err invalid cursor",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.AbuseFlagListParam{Cursor: "bm9wZQ"},
+			},
+			wantErr:  appErr.ErrInvalidCursor,
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "err get page",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			wantErr: assert.AnError,
+			mockFunc: func(arg args) {
+				abuseMock.EXPECT().GetPage(arg.ctx, entity.AbuseFlagPageParam{Status: entity.AbuseFlagOpen, Limit: defaultLimit + 1}).Return(nil, assert.AnError)
+			},
+		},
+		{
+			name: "first page with more to come",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.AbuseFlagListParam{UserId: 5, Limit: 1},
+			},
+			want: entity.AbuseFlagListResponse{
+				Flags:      []entity.AbuseFlagResponse{{ID: 3, UserId: 5, Signal: entity.AbuseSwipeRate, Explanation: "too fast", Status: entity.AbuseFlagOpen, CreatedAt: createdAt}},
+				NextCursor: cursor.Encode(createdAt, 3),
+			},
+			mockFunc: func(arg args) {
+				abuseMock.EXPECT().GetPage(arg.ctx, entity.AbuseFlagPageParam{Status: entity.AbuseFlagOpen, UserId: 5, Limit: 2}).Return([]entity.AbuseFlag{
+					{ID: 3, UserId: 5, Signal: entity.AbuseSwipeRate, Explanation: "too fast", Status: entity.AbuseFlagOpen, CreatedAt: sql.NullTime{Time: createdAt, Valid: true}},
+					{ID: 4, UserId: 5, Signal: entity.AbuseSharedIP, Explanation: "same ip", Status: entity.AbuseFlagOpen, CreatedAt: sql.NullTime{Time: createdAt, Valid: true}},
+				}, nil)
+			},
+		},
+		{
+			name: "last page",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.AbuseFlagListParam{Status: entity.AbuseFlagConfirmed, Cursor: cursor.Encode(createdAt, 3)},
+			},
+			want: entity.AbuseFlagListResponse{
+				Flags: []entity.AbuseFlagResponse{{ID: 4, UserId: 5, Signal: entity.AbuseSharedIP, Status: entity.AbuseFlagConfirmed, ReviewerId: 1, ReviewedAt: &createdAt, CreatedAt: createdAt}},
+			},
+			mockFunc: func(arg args) {
+				abuseMock.EXPECT().GetPage(arg.ctx, entity.AbuseFlagPageParam{Status: entity.AbuseFlagConfirmed, AfterAt: createdAt, AfterId: 3, Limit: defaultLimit + 1}).Return([]entity.AbuseFlag{
+					{ID: 4, UserId: 5, Signal: entity.AbuseSharedIP, Status: entity.AbuseFlagConfirmed, ReviewerId: sql.NullInt64{Int64: 1, Valid: true}, ReviewedAt: sql.NullTime{Time: createdAt, Valid: true}, CreatedAt: sql.NullTime{Time: createdAt, Valid: true}},
+				}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			a := Init(log, abuseMock, swipeMock, auditMock, atomicMock)
+			got, err := a.GetList(tt.args.ctx, tt.args.param)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReview(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	abuseMock := mock_abuse.NewMockInterface(ctrl)
+	swipeMock := mock_swipe.NewMockInterface(ctrl)
+	auditMock := mock_audit.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
+
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	Now = func() time.Time { return now }
+	defer func() { Now = time.Now }()
+
+	open := entity.AbuseFlag{ID: 3, UserId: 5, Signal: entity.AbuseSwipeRate, Explanation: "too fast", Status: entity.AbuseFlagOpen}
+
+	type args struct {
+		ctx    context.Context
+		flagId int64
+		param  entity.AbuseFlagReviewParam
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		want     entity.AbuseFlagResponse
+		wantErr  error
+	}{
+		{
+			name: "err invalid user id",
+			args: args{
+				ctx:    context.Background(),
+				flagId: 3,
+				param:  entity.AbuseFlagReviewParam{Status: entity.AbuseFlagDismissed},
+			},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "err flag not found",
+			args: args{
+				ctx:    appcontext.SetUserId(context.Background(), 1),
+				flagId: 3,
+				param:  entity.AbuseFlagReviewParam{Status: entity.AbuseFlagDismissed},
+			},
+			wantErr: appErr.ErrAbuseFlagNotFound,
+			mockFunc: func(arg args) {
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				abuseMock.EXPECT().GetForUpdate(gomock.Any(), int64(3)).Return(entity.AbuseFlag{}, sql.ErrNoRows)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "err flag already reviewed",
+			args: args{
+				ctx:    appcontext.SetUserId(context.Background(), 1),
+				flagId: 3,
+				param:  entity.AbuseFlagReviewParam{Status: entity.AbuseFlagConfirmed},
+			},
+			wantErr: appErr.ErrAbuseFlagAlreadyReviewed,
+			mockFunc: func(arg args) {
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				abuseMock.EXPECT().GetForUpdate(gomock.Any(), int64(3)).Return(entity.AbuseFlag{ID: 3, Status: entity.AbuseFlagDismissed}, nil)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "err sync shadow limit",
+			args: args{
+				ctx:    appcontext.SetUserId(context.Background(), 1),
+				flagId: 3,
+				param:  entity.AbuseFlagReviewParam{Status: entity.AbuseFlagDismissed},
+			},
+			wantErr: assert.AnError,
+			mockFunc: func(arg args) {
+				reviewed := open
+				reviewed.Status = entity.AbuseFlagDismissed
+				reviewed.ReviewerId = sql.NullInt64{Int64: 1, Valid: true}
+				reviewed.ReviewedAt = sql.NullTime{Time: now, Valid: true}
+
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				abuseMock.EXPECT().GetForUpdate(gomock.Any(), int64(3)).Return(open, nil)
+				abuseMock.EXPECT().Review(gomock.Any(), reviewed).Return(nil)
+				abuseMock.EXPECT().SyncShadowLimit(gomock.Any(), int64(5)).Return(assert.AnError)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "confirm",
+			args: args{
+				ctx:    appcontext.SetUserId(context.Background(), 1),
+				flagId: 3,
+				param:  entity.AbuseFlagReviewParam{Status: entity.AbuseFlagConfirmed, Note: "bot"},
+			},
+			want: entity.AbuseFlagResponse{ID: 3, UserId: 5, Signal: entity.AbuseSwipeRate, Explanation: "too fast", Status: entity.AbuseFlagConfirmed, ReviewerId: 1, Note: "bot", ReviewedAt: &now},
+			mockFunc: func(arg args) {
+				reviewed := open
+				reviewed.Status = entity.AbuseFlagConfirmed
+				reviewed.Note = "bot"
+				reviewed.ReviewerId = sql.NullInt64{Int64: 1, Valid: true}
+				reviewed.ReviewedAt = sql.NullTime{Time: now, Valid: true}
+
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				abuseMock.EXPECT().GetForUpdate(gomock.Any(), int64(3)).Return(open, nil)
+				abuseMock.EXPECT().Review(gomock.Any(), reviewed).Return(nil)
+				abuseMock.EXPECT().SyncShadowLimit(gomock.Any(), int64(5)).Return(nil)
+				auditMock.EXPECT().Record(gomock.Any(), entity.AuditLog{
+					ActorId:    sql.NullInt64{Int64: 1, Valid: true},
+					Action:     entity.AuditAbuseFlagReviewed,
+					TargetType: entity.AuditTargetAbuseFlag,
+					TargetId:   sql.NullInt64{Int64: 3, Valid: true},
+				}, entity.AuditModerationDetails{UserId: 5, Action: entity.AbuseFlagConfirmed, Note: "bot"}).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+				swipeMock.EXPECT().InvalidateUser(arg.ctx, int64(5)).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			a := Init(log, abuseMock, swipeMock, auditMock, atomicMock)
+			got, err := a.Review(tt.args.ctx, tt.args.flagId, tt.args.param)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
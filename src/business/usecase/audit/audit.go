@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"loverly/lib/appcontext"
+	"loverly/lib/cursor"
+	"loverly/lib/log"
+	"loverly/lib/operator"
+	"loverly/src/business/domain/audit"
+	"loverly/src/business/entity"
+	appErr "loverly/src/errors"
+)
+
+// Interface lets admins search the audit log, entries are written by the usecases performing the actions
+type Interface interface {
+	GetList(ctx context.Context, param entity.AuditLogListParam) (entity.AuditLogListResponse, error)
+}
+
+const defaultLimit = 50
+
+type audits struct {
+	log   log.Interface
+	audit audit.Interface
+}
+
+func Init(log log.Interface, a audit.Interface) Interface {
+	return &audits{
+		log:   log,
+		audit: a,
+	}
+}
+
+// GetList pages the matching entries newest first
+func (a *audits) GetList(ctx context.Context, param entity.AuditLogListParam) (entity.AuditLogListResponse, error) {
+	var result entity.AuditLogListResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	limit := operator.Ternary(param.Limit < 1, defaultLimit, param.Limit)
+	page := entity.AuditLogPageParam{
+		ActorId:    param.ActorId,
+		Action:     param.Action,
+		TargetType: param.TargetType,
+		TargetId:   param.TargetId,
+		From:       sql.NullTime{Time: param.From, Valid: !param.From.IsZero()},
+		To:         sql.NullTime{Time: param.To, Valid: !param.To.IsZero()},
+		Limit:      limit + 1, // one extra row tells whether there is a next page
+	}
+
+	if param.Cursor != "" {
+		var err error
+		page.BeforeAt, page.BeforeId, err = cursor.Decode(param.Cursor)
+		if err != nil {
+			return result, appErr.ErrInvalidCursor
+		}
+	}
+
+	logs, err := a.audit.GetPage(ctx, page)
+	if err != nil {
+		return result, err
+	}
+
+	if len(logs) > limit {
+		logs = logs[:limit]
+		last := logs[len(logs)-1]
+		result.NextCursor = cursor.Encode(last.CreatedAt.Time, last.ID)
+	}
+
+	for _, entry := range logs {
+		result.Logs = append(result.Logs, toResponse(entry))
+	}
+
+	return result, nil
+}
+
+func toResponse(entry entity.AuditLog) entity.AuditLogResponse {
+	return entity.AuditLogResponse{
+		ID:         entry.ID,
+		ActorId:    entry.ActorId.Int64,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetId:   entry.TargetId.Int64,
+		Details:    entry.Details,
+		RequestId:  entry.RequestId,
+		IP:         entry.IP,
+		UserAgent:  entry.UserAgent,
+		CreatedAt:  entry.CreatedAt.Time,
+	}
+}
@@ -0,0 +1,125 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"loverly/lib/appcontext"
+	"loverly/lib/cursor"
+	mock_log "loverly/lib/log/mock"
+	mock_audit "loverly/src/business/domain/mock/audit"
+	"loverly/src/business/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	appErr "loverly/src/errors"
+)
+
+func TestGetList(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	auditMock := mock_audit.NewMockInterface(ctrl)
+
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	from := createdAt.AddDate(0, 0, -1)
+	details := json.RawMessage(`{"email":"test@mail.com"}`)
+
+	type args struct {
+		ctx   context.Context
+		param entity.AuditLogListParam
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		want     entity.AuditLogListResponse
+		wantErr  error
+	}{
+		{
+			name: "err invalid user id",
+			args: args{
+				ctx: context.Background(),
+			},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "err invalid cursor",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.AuditLogListParam{Cursor: "bm9wZQ"},
+			},
+			wantErr:  appErr.ErrInvalidCursor,
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "err get page",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			wantErr: assert.AnError,
+			mockFunc: func(arg args) {
+				auditMock.EXPECT().GetPage(arg.ctx, entity.AuditLogPageParam{Limit: defaultLimit + 1}).Return(nil, assert.AnError)
+			},
+		},
+		{
+			name: "first page with more to come",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.AuditLogListParam{TargetType: entity.AuditTargetUser, TargetId: 2, From: from, Limit: 1},
+			},
+			want: entity.AuditLogListResponse{
+				Logs: []entity.AuditLogResponse{{
+					ID: 9, ActorId: 2, Action: entity.AuditLogin, TargetType: entity.AuditTargetUser, TargetId: 2, Details: details,
+					RequestId: "rid", IP: "10.0.0.1", UserAgent: "ua", CreatedAt: createdAt,
+				}},
+				NextCursor: cursor.Encode(createdAt, 9),
+			},
+			mockFunc: func(arg args) {
+				auditMock.EXPECT().GetPage(arg.ctx, entity.AuditLogPageParam{
+					TargetType: entity.AuditTargetUser, TargetId: 2, From: sql.NullTime{Time: from, Valid: true}, Limit: 2,
+				}).Return([]entity.AuditLog{
+					{
+						ID: 9, ActorId: sql.NullInt64{Int64: 2, Valid: true}, Action: entity.AuditLogin, TargetType: entity.AuditTargetUser, TargetId: sql.NullInt64{Int64: 2, Valid: true},
+						Details: details, RequestId: "rid", IP: "10.0.0.1", UserAgent: "ua", CreatedAt: sql.NullTime{Time: createdAt, Valid: true},
+					},
+					{ID: 8, Action: entity.AuditLoginFailed, TargetType: entity.AuditTargetUser, TargetId: sql.NullInt64{Int64: 2, Valid: true}, CreatedAt: sql.NullTime{Time: createdAt, Valid: true}},
+				}, nil)
+			},
+		},
+		{
+			name: "last page",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.AuditLogListParam{ActorId: 1, Action: entity.AuditReportResolved, Cursor: cursor.Encode(createdAt, 9)},
+			},
+			want: entity.AuditLogListResponse{
+				Logs: []entity.AuditLogResponse{{ID: 7, ActorId: 1, Action: entity.AuditReportResolved, TargetType: entity.AuditTargetReport, TargetId: 3, CreatedAt: createdAt}},
+			},
+			mockFunc: func(arg args) {
+				auditMock.EXPECT().GetPage(arg.ctx, entity.AuditLogPageParam{
+					ActorId: 1, Action: entity.AuditReportResolved, BeforeAt: createdAt, BeforeId: 9, Limit: defaultLimit + 1,
+				}).Return([]entity.AuditLog{
+					{ID: 7, ActorId: sql.NullInt64{Int64: 1, Valid: true}, Action: entity.AuditReportResolved, TargetType: entity.AuditTargetReport, TargetId: sql.NullInt64{Int64: 3, Valid: true}, CreatedAt: sql.NullTime{Time: createdAt, Valid: true}},
+				}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			a := Init(log, auditMock)
+			got, err := a.GetList(tt.args.ctx, tt.args.param)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package block
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
+	"loverly/lib/log"
+	"loverly/src/business/domain/block"
+	"loverly/src/business/domain/deck"
+	match "loverly/src/business/domain/matchs"
+	"loverly/src/business/domain/profile"
+	"loverly/src/business/entity"
+	appErr "loverly/src/errors"
+)
+
+// Interface hides two users from each other, see block.NotBlocked for the reads it applies to
+type Interface interface {
+	Block(ctx context.Context, param entity.BlockParam) error
+	Unblock(ctx context.Context, blockedId int64) error
+	GetList(ctx context.Context) (entity.BlockListResponse, error)
+}
+
+type blocks struct {
+	log     log.Interface
+	block   block.Interface
+	match   match.Interface
+	profile profile.Interface
+	deck    deck.Interface
+	atomic  atomic.AtomicSessionProvider
+}
+
+func Init(log log.Interface, b block.Interface, m match.Interface, p profile.Interface, dk deck.Interface, a atomic.AtomicSessionProvider) Interface {
+	return &blocks{
+		log:     log,
+		block:   b,
+		match:   m,
+		profile: p,
+		deck:    dk,
+		atomic:  a,
+	}
+}
+
+// Block also ends the match between the two users for good, unblocking doesn't bring it back
+func (b *blocks) Block(ctx context.Context, param entity.BlockParam) error {
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return appErr.ErrInvalidUserId
+	}
+
+	if param.UserId == int64(userId) {
+		return appErr.ErrInvalidBlockTarget
+	}
+
+	if _, err := b.profile.GetByUserId(ctx, param.UserId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return appErr.ErrInvalidBlockTarget
+		}
+		return err
+	}
+
+	blk := entity.Block{
+		BlockerId: int64(userId),
+		BlockedId: param.UserId,
+	}
+
+	err := atomic.Atomic(ctx, b.atomic, b.log, func(ctx context.Context) error {
+		if _, err := b.block.Create(ctx, blk); err != nil {
+			return err
+		}
+
+		matchs, err := b.match.GetByPairForUpdate(ctx, int64(userId), param.UserId)
+		if err != nil {
+			return err
+		}
+
+		for _, mt := range matchs {
+			mt.DeletedBy = sql.NullInt64{Int64: int64(userId), Valid: true}
+			if err := b.match.Delete(ctx, mt); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// only now, a read before the commit would cache the pair and their match as they were
+	b.block.Invalidate(ctx, blk)
+
+	// precomputed decks may still hold the other user, they're rebuilt on the next discovery
+	for _, id := range []int64{int64(userId), param.UserId} {
+		if err := b.deck.Delete(ctx, id); err != nil {
+			b.log.Error(ctx, fmt.Sprintf("delete deck err: %v", err))
+		}
+	}
+
+	return nil
+}
+
+func (b *blocks) Unblock(ctx context.Context, blockedId int64) error {
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return appErr.ErrInvalidUserId
+	}
+
+	blk := entity.Block{
+		BlockerId: int64(userId),
+		BlockedId: blockedId,
+	}
+
+	if err := b.block.Delete(ctx, blk); err != nil {
+		return err
+	}
+
+	b.block.Invalidate(ctx, blk)
+
+	return nil
+}
+
+func (b *blocks) GetList(ctx context.Context) (entity.BlockListResponse, error) {
+	var result entity.BlockListResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	blocked, err := b.block.GetByBlockerId(ctx, int64(userId))
+	if err != nil {
+		return result, err
+	}
+
+	for _, u := range blocked {
+		result.Blocks = append(result.Blocks, entity.BlockResponse{
+			UserId:    u.UserId,
+			FullName:  u.FullName,
+			ProfPic:   u.ProfPic.String,
+			BlockedAt: u.BlockedAt,
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,285 @@
+package block
+
+import (
+	"context"
+	"database/sql"
+	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
+	mock_atomic "loverly/lib/atomic/mock"
+	mock_log "loverly/lib/log/mock"
+	mock_block "loverly/src/business/domain/mock/block"
+	mock_deck "loverly/src/business/domain/mock/deck"
+	mock_match "loverly/src/business/domain/mock/match"
+	mock_profile "loverly/src/business/domain/mock/profile"
+	"loverly/src/business/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	appErr "loverly/src/errors"
+)
+
+func TestBlock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	blockMock := mock_block.NewMockInterface(ctrl)
+	matchMock := mock_match.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	deckMock := mock_deck.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
+
+	type mockFields struct {
+		blockMock   *mock_block.MockInterface
+		matchMock   *mock_match.MockInterface
+		profileMock *mock_profile.MockInterface
+		deckMock    *mock_deck.MockInterface
+		atomicMock  *mock_atomic.MockAtomicSessionProvider
+		sessionMock *mock_atomic.MockAtomicSession
+	}
+
+	mocks := mockFields{
+		blockMock:   blockMock,
+		matchMock:   matchMock,
+		profileMock: profileMock,
+		deckMock:    deckMock,
+		atomicMock:  atomicMock,
+		sessionMock: sessionMock,
+	}
+
+	type args struct {
+		ctx   context.Context
+		param entity.BlockParam
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(mock mockFields, arg args)
+		args     args
+		wantErr  error
+	}{
+		{
+			name: "err invalid user id",
+			args: args{
+				ctx:   context.Background(),
+				param: entity.BlockParam{UserId: 2},
+			},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(mock mockFields, arg args) {},
+		},
+		{
+			name: "err block yourself",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.BlockParam{UserId: 1},
+			},
+			wantErr:  appErr.ErrInvalidBlockTarget,
+			mockFunc: func(mock mockFields, arg args) {},
+		},
+		{
+			name: "err unknown user",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.BlockParam{UserId: 2},
+			},
+			wantErr: appErr.ErrInvalidBlockTarget,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(2)).Return(entity.Profile{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name: "err delete match",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.BlockParam{UserId: 2},
+			},
+			wantErr: assert.AnError,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(2)).Return(entity.Profile{UserId: 2}, nil)
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.blockMock.EXPECT().Create(gomock.Any(), entity.Block{BlockerId: 1, BlockedId: 2}).Return(int64(3), nil)
+				mock.matchMock.EXPECT().GetByPairForUpdate(gomock.Any(), int64(1), int64(2)).Return([]entity.Match{{ID: 5, UserId1: 2, UserId2: 1}}, nil)
+				mock.matchMock.EXPECT().Delete(gomock.Any(), entity.Match{ID: 5, UserId1: 2, UserId2: 1, DeletedBy: sql.NullInt64{Int64: 1, Valid: true}}).Return(assert.AnError)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				mock.sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "block without match",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.BlockParam{UserId: 2},
+			},
+			wantErr: nil,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(2)).Return(entity.Profile{UserId: 2}, nil)
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.blockMock.EXPECT().Create(gomock.Any(), entity.Block{BlockerId: 1, BlockedId: 2}).Return(int64(3), nil)
+				mock.matchMock.EXPECT().GetByPairForUpdate(gomock.Any(), int64(1), int64(2)).Return(nil, nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+				mock.blockMock.EXPECT().Invalidate(arg.ctx, entity.Block{BlockerId: 1, BlockedId: 2})
+				mock.deckMock.EXPECT().Delete(arg.ctx, int64(1)).Return(nil)
+				mock.deckMock.EXPECT().Delete(arg.ctx, int64(2)).Return(nil)
+			},
+		},
+		{
+			name: "block removes the match",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.BlockParam{UserId: 2},
+			},
+			wantErr: nil,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(2)).Return(entity.Profile{UserId: 2}, nil)
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.blockMock.EXPECT().Create(gomock.Any(), entity.Block{BlockerId: 1, BlockedId: 2}).Return(int64(3), nil)
+				mock.matchMock.EXPECT().GetByPairForUpdate(gomock.Any(), int64(1), int64(2)).Return([]entity.Match{{ID: 5, UserId1: 2, UserId2: 1}}, nil)
+				mock.matchMock.EXPECT().Delete(gomock.Any(), entity.Match{ID: 5, UserId1: 2, UserId2: 1, DeletedBy: sql.NullInt64{Int64: 1, Valid: true}}).Return(nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+				mock.blockMock.EXPECT().Invalidate(arg.ctx, entity.Block{BlockerId: 1, BlockedId: 2})
+				mock.deckMock.EXPECT().Delete(arg.ctx, int64(1)).Return(nil)
+				mock.deckMock.EXPECT().Delete(arg.ctx, int64(2)).Return(assert.AnError)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks, tt.args)
+
+			b := Init(log, blockMock, matchMock, profileMock, deckMock, atomicMock)
+			err := b.Block(tt.args.ctx, tt.args.param)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUnblock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	blockMock := mock_block.NewMockInterface(ctrl)
+	matchMock := mock_match.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	deckMock := mock_deck.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+
+	type args struct {
+		ctx       context.Context
+		blockedId int64
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		wantErr  error
+	}{
+		{
+			name: "err invalid user id",
+			args: args{
+				ctx:       context.Background(),
+				blockedId: 2,
+			},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "all goods",
+			args: args{
+				ctx:       appcontext.SetUserId(context.Background(), 1),
+				blockedId: 2,
+			},
+			wantErr: nil,
+			mockFunc: func(arg args) {
+				blockMock.EXPECT().Delete(arg.ctx, entity.Block{BlockerId: 1, BlockedId: 2}).Return(nil)
+				blockMock.EXPECT().Invalidate(arg.ctx, entity.Block{BlockerId: 1, BlockedId: 2})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			b := Init(log, blockMock, matchMock, profileMock, deckMock, atomicMock)
+			err := b.Unblock(tt.args.ctx, tt.args.blockedId)
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestGetList(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	blockMock := mock_block.NewMockInterface(ctrl)
+	matchMock := mock_match.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	deckMock := mock_deck.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+
+	blockedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	type args struct {
+		ctx context.Context
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		want     entity.BlockListResponse
+		wantErr  bool
+	}{
+		{
+			name: "err get blocks",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    entity.BlockListResponse{},
+			wantErr: true,
+			mockFunc: func(arg args) {
+				blockMock.EXPECT().GetByBlockerId(arg.ctx, int64(1)).Return(nil, assert.AnError)
+			},
+		},
+		{
+			name: "all goods",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want: entity.BlockListResponse{
+				Blocks: []entity.BlockResponse{
+					{UserId: 2, FullName: "test", ProfPic: "pic.jpg", BlockedAt: blockedAt},
+				},
+			},
+			wantErr: false,
+			mockFunc: func(arg args) {
+				blockMock.EXPECT().GetByBlockerId(arg.ctx, int64(1)).Return([]entity.BlockedUser{
+					{UserId: 2, FullName: "test", ProfPic: sql.NullString{String: "pic.jpg", Valid: true}, BlockedAt: blockedAt},
+				}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			b := Init(log, blockMock, matchMock, profileMock, deckMock, atomicMock)
+			got, err := b.GetList(tt.args.ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetList error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package boost
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
+	"loverly/lib/log"
+	"loverly/src/business/domain/boost"
+	"loverly/src/business/domain/profile"
+	"loverly/src/business/domain/subscription"
+	"loverly/src/business/entity"
+	"loverly/src/config"
+	"slices"
+	"time"
+
+	appErr "loverly/src/errors"
+)
+
+var Now = time.Now
+
+type Interface interface {
+	Activate(ctx context.Context) (entity.BoostResponse, error)
+	// GetLatest only reads, the counters of a boost over are frozen by Summarize
+	GetLatest(ctx context.Context) (*entity.BoostResponse, error)
+	// Summarize freezes the counters of boosts over into their rows, see worker
+	Summarize(ctx context.Context, limit int) (int, error)
+}
+
+type boosts struct {
+	log          log.Interface
+	cfg          config.Boost
+	boost        boost.Interface
+	profile      profile.Interface
+	subscription subscription.Interface
+	atomic       atomic.AtomicSessionProvider
+}
+
+func Init(log log.Interface, cfg config.Boost, b boost.Interface, p profile.Interface, s subscription.Interface, a atomic.AtomicSessionProvider) Interface {
+	return &boosts{
+		log:          log,
+		cfg:          cfg,
+		boost:        b,
+		profile:      p,
+		subscription: s,
+		atomic:       a,
+	}
+}
+
+func (b *boosts) Activate(ctx context.Context) (entity.BoostResponse, error) {
+	var result entity.BoostResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	pf, err := b.profile.GetByUserId(ctx, int64(userId))
+	if err != nil {
+		return result, err
+	}
+
+	now := Now()
+	param := entity.Boost{
+		UserId:    int64(userId),
+		Location:  pf.Location.String,
+		StartedAt: now,
+		EndsAt:    now.Add(b.cfg.Duration),
+	}
+
+	err = atomic.Atomic(ctx, b.atomic, b.log, func(ctx context.Context) error {
+		// two activations at once would both pass the checks below and spend one credit twice
+		if err := b.boost.LockUser(ctx, int64(userId)); err != nil {
+			return err
+		}
+
+		latest, err := b.boost.GetLatestByUserIdForUpdate(ctx, int64(userId))
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		if latest.ID > 0 && latest.EndsAt.After(now) {
+			return appErr.ErrBoostActive
+		}
+
+		// the counters start over with the new boost, the last one keeps what it got
+		if latest.ID > 0 && !latest.SummarizedAt.Valid {
+			if err := b.summarize(ctx, latest); err != nil {
+				return err
+			}
+		}
+
+		param.Source, err = b.getSource(ctx, int64(userId))
+		if err != nil {
+			return err
+		}
+
+		param.ID, err = b.boost.Create(ctx, param)
+		if err != nil {
+			return err
+		}
+
+		if param.Source == entity.BoostSourceConsumable {
+			if _, err := b.boost.CreateCredit(ctx, entity.BoostCredit{
+				UserId: int64(userId),
+				Amount: -1,
+				Reason: fmt.Sprintf("boost:%d", param.ID),
+			}); err != nil {
+				return err
+			}
+		}
+
+		// last, a boost that never reached discovery rolls back with its row and the credit it spent
+		return b.boost.Activate(ctx, param)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return entity.BoostResponse{
+		ID:        param.ID,
+		Source:    param.Source,
+		Active:    true,
+		StartedAt: param.StartedAt,
+		EndsAt:    param.EndsAt,
+	}, nil
+}
+
+func (b *boosts) GetLatest(ctx context.Context) (*entity.BoostResponse, error) {
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return nil, appErr.ErrInvalidUserId
+	}
+
+	latest, err := b.boost.GetLatestByUserId(ctx, int64(userId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	result := &entity.BoostResponse{
+		ID:        latest.ID,
+		Source:    latest.Source,
+		Active:    latest.EndsAt.After(Now()),
+		StartedAt: latest.StartedAt,
+		EndsAt:    latest.EndsAt,
+		Views:     latest.Views,
+		Likes:     latest.Likes,
+	}
+
+	if latest.SummarizedAt.Valid {
+		return result, nil
+	}
+
+	result.Views, result.Likes, err = b.boost.GetStats(ctx, int64(userId))
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (b *boosts) Summarize(ctx context.Context, limit int) (int, error) {
+	summarized := 0
+	err := atomic.Atomic(ctx, b.atomic, b.log, func(ctx context.Context) error {
+		ended, err := b.boost.GetEnded(ctx, limit)
+		if err != nil {
+			return err
+		}
+
+		for _, boost := range ended {
+			if err := b.summarize(ctx, boost); err != nil {
+				return err
+			}
+		}
+
+		summarized = len(ended)
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return summarized, nil
+}
+
+// summarize freezes the counters of the boost into its row, they only stay in redis for a while after it ends
+func (b *boosts) summarize(ctx context.Context, boost entity.Boost) error {
+	views, likes, err := b.boost.GetStats(ctx, boost.UserId)
+	if err != nil {
+		return err
+	}
+
+	boost.Views, boost.Likes = views, likes
+	boost.SummarizedAt = sql.NullTime{Time: Now(), Valid: true}
+
+	return b.boost.UpdateStats(ctx, boost)
+}
+
+// getSource picks the plan allowance first and falls back to purchased boosts
+func (b *boosts) getSource(ctx context.Context, userId int64) (string, error) {
+	features, err := b.subscription.GetFeatures(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+
+	if slices.Contains(features, entity.FeatureBoosts) && b.cfg.PlanAllowance > 0 {
+		// the allowance is per period, a renewal starts a new one
+		sub, err := b.subscription.GetByUserId(ctx, userId)
+		if err != nil {
+			return "", err
+		}
+
+		used, err := b.boost.CountBySource(ctx, userId, entity.BoostSourcePlan, sub.StartDate)
+		if err != nil {
+			return "", err
+		}
+
+		if used < b.cfg.PlanAllowance {
+			return entity.BoostSourcePlan, nil
+		}
+	}
+
+	balance, err := b.boost.GetCreditBalance(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+
+	if balance < 1 {
+		return "", appErr.ErrNoBoostAvailable
+	}
+
+	return entity.BoostSourceConsumable, nil
+}
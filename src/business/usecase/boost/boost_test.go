@@ -0,0 +1,353 @@
+package boost
+
+import (
+	"context"
+	"database/sql"
+	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
+	mock_atomic "loverly/lib/atomic/mock"
+	mock_log "loverly/lib/log/mock"
+	mock_boost "loverly/src/business/domain/mock/boost"
+	mock_profile "loverly/src/business/domain/mock/profile"
+	mock_subscription "loverly/src/business/domain/mock/subscription"
+	"loverly/src/business/entity"
+	"loverly/src/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestActivate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	boostMock := mock_boost.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	subsMock := mock_subscription.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
+	cfg := config.Boost{Duration: 30 * time.Minute, PlanAllowance: 1}
+
+	mockTime := time.Now()
+	Now = func() time.Time {
+		return mockTime
+	}
+	defer func() {
+		Now = time.Now
+	}()
+
+	type mockFields struct {
+		boostMock   *mock_boost.MockInterface
+		profileMock *mock_profile.MockInterface
+		subsMock    *mock_subscription.MockInterface
+		atomicMock  *mock_atomic.MockAtomicSessionProvider
+		sessionMock *mock_atomic.MockAtomicSession
+	}
+
+	mocks := mockFields{
+		boostMock:   boostMock,
+		profileMock: profileMock,
+		subsMock:    subsMock,
+		atomicMock:  atomicMock,
+		sessionMock: sessionMock,
+	}
+
+	type args struct {
+		ctx context.Context
+	}
+
+	activeSub := entity.Subscription{ID: 1, Plan: entity.UnlimitedPlan, StartDate: mockTime.AddDate(0, 0, -1), EndDate: mockTime.AddDate(0, 0, 29)}
+	location := entity.Profile{Location: sql.NullString{String: "Jakarta", Valid: true}}
+
+	tests := []struct {
+		name     string
+		mockFunc func(mock mockFields, arg args)
+		args     args
+		want     entity.BoostResponse
+		wantErr  bool
+	}{
+		{
+			name: "err boost already active",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    entity.BoostResponse{},
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(location, nil)
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.boostMock.EXPECT().LockUser(gomock.Any(), int64(1)).Return(nil)
+				mock.boostMock.EXPECT().GetLatestByUserIdForUpdate(gomock.Any(), int64(1)).Return(entity.Boost{ID: 4, EndsAt: mockTime.Add(time.Minute)}, nil)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				mock.sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "err no boost available",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    entity.BoostResponse{},
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(location, nil)
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.boostMock.EXPECT().LockUser(gomock.Any(), int64(1)).Return(nil)
+				mock.boostMock.EXPECT().GetLatestByUserIdForUpdate(gomock.Any(), int64(1)).Return(entity.Boost{}, sql.ErrNoRows)
+				mock.subsMock.EXPECT().GetFeatures(gomock.Any(), int64(1)).Return([]string{}, nil)
+				mock.boostMock.EXPECT().GetCreditBalance(gomock.Any(), int64(1)).Return(int64(0), nil)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				mock.sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "plan allowance boost",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    entity.BoostResponse{ID: 5, Source: entity.BoostSourcePlan, Active: true, StartedAt: mockTime, EndsAt: mockTime.Add(cfg.Duration)},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(location, nil)
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.boostMock.EXPECT().LockUser(gomock.Any(), int64(1)).Return(nil)
+				mock.boostMock.EXPECT().GetLatestByUserIdForUpdate(gomock.Any(), int64(1)).Return(entity.Boost{}, sql.ErrNoRows)
+				mock.subsMock.EXPECT().GetFeatures(gomock.Any(), int64(1)).Return([]string{entity.FeatureBoosts}, nil)
+				mock.subsMock.EXPECT().GetByUserId(gomock.Any(), int64(1)).Return(activeSub, nil)
+				mock.boostMock.EXPECT().CountBySource(gomock.Any(), int64(1), entity.BoostSourcePlan, activeSub.StartDate).Return(int64(0), nil)
+				mock.boostMock.EXPECT().Create(gomock.Any(), entity.Boost{UserId: 1, Source: entity.BoostSourcePlan, Location: "Jakarta", StartedAt: mockTime, EndsAt: mockTime.Add(cfg.Duration)}).Return(int64(5), nil)
+				mock.boostMock.EXPECT().Activate(gomock.Any(), entity.Boost{ID: 5, UserId: 1, Source: entity.BoostSourcePlan, Location: "Jakarta", StartedAt: mockTime, EndsAt: mockTime.Add(cfg.Duration)}).Return(nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "consumable boost uses a credit and freezes the last boost",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    entity.BoostResponse{ID: 6, Source: entity.BoostSourceConsumable, Active: true, StartedAt: mockTime, EndsAt: mockTime.Add(cfg.Duration)},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				last := entity.Boost{ID: 4, UserId: 1, EndsAt: mockTime.Add(-time.Minute)}
+				summarized := last
+				summarized.Views, summarized.Likes = 12, 3
+				summarized.SummarizedAt = sql.NullTime{Time: mockTime, Valid: true}
+
+				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(location, nil)
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.boostMock.EXPECT().LockUser(gomock.Any(), int64(1)).Return(nil)
+				mock.boostMock.EXPECT().GetLatestByUserIdForUpdate(gomock.Any(), int64(1)).Return(last, nil)
+				mock.boostMock.EXPECT().GetStats(gomock.Any(), int64(1)).Return(int64(12), int64(3), nil)
+				mock.boostMock.EXPECT().UpdateStats(gomock.Any(), summarized).Return(nil)
+				mock.subsMock.EXPECT().GetFeatures(gomock.Any(), int64(1)).Return([]string{entity.FeatureBoosts}, nil)
+				mock.subsMock.EXPECT().GetByUserId(gomock.Any(), int64(1)).Return(activeSub, nil)
+				mock.boostMock.EXPECT().CountBySource(gomock.Any(), int64(1), entity.BoostSourcePlan, activeSub.StartDate).Return(int64(1), nil)
+				mock.boostMock.EXPECT().GetCreditBalance(gomock.Any(), int64(1)).Return(int64(2), nil)
+				mock.boostMock.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(6), nil)
+				mock.boostMock.EXPECT().CreateCredit(gomock.Any(), entity.BoostCredit{UserId: 1, Amount: -1, Reason: "boost:6"}).Return(int64(1), nil)
+				mock.boostMock.EXPECT().Activate(gomock.Any(), gomock.Any()).Return(nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "err activate rolls back the credit and the boost",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    entity.BoostResponse{},
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(location, nil)
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.boostMock.EXPECT().LockUser(gomock.Any(), int64(1)).Return(nil)
+				mock.boostMock.EXPECT().GetLatestByUserIdForUpdate(gomock.Any(), int64(1)).Return(entity.Boost{}, sql.ErrNoRows)
+				mock.subsMock.EXPECT().GetFeatures(gomock.Any(), int64(1)).Return([]string{}, nil)
+				mock.boostMock.EXPECT().GetCreditBalance(gomock.Any(), int64(1)).Return(int64(2), nil)
+				mock.boostMock.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(6), nil)
+				mock.boostMock.EXPECT().CreateCredit(gomock.Any(), entity.BoostCredit{UserId: 1, Amount: -1, Reason: "boost:6"}).Return(int64(1), nil)
+				mock.boostMock.EXPECT().Activate(gomock.Any(), gomock.Any()).Return(assert.AnError)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				mock.sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks, tt.args)
+
+			b := Init(log, cfg, boostMock, profileMock, subsMock, atomicMock)
+			got, err := b.Activate(tt.args.ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Activate error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetLatest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	boostMock := mock_boost.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	subsMock := mock_subscription.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	cfg := config.Boost{Duration: 30 * time.Minute}
+
+	mockTime := time.Now()
+	Now = func() time.Time {
+		return mockTime
+	}
+	defer func() {
+		Now = time.Now
+	}()
+
+	type args struct {
+		ctx context.Context
+	}
+
+	ended := entity.Boost{ID: 1, UserId: 1, Source: entity.BoostSourcePlan, StartedAt: mockTime.Add(-time.Hour), EndsAt: mockTime.Add(-30 * time.Minute)}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		want     *entity.BoostResponse
+		wantErr  bool
+	}{
+		{
+			name: "never boosted",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    nil,
+			wantErr: false,
+			mockFunc: func(arg args) {
+				boostMock.EXPECT().GetLatestByUserId(arg.ctx, int64(1)).Return(entity.Boost{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name: "ended boost not summarized yet reads the counters",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    &entity.BoostResponse{ID: 1, Source: entity.BoostSourcePlan, StartedAt: ended.StartedAt, EndsAt: ended.EndsAt, Views: 40, Likes: 7},
+			wantErr: false,
+			mockFunc: func(arg args) {
+				boostMock.EXPECT().GetLatestByUserId(arg.ctx, int64(1)).Return(ended, nil)
+				boostMock.EXPECT().GetStats(arg.ctx, int64(1)).Return(int64(40), int64(7), nil)
+			},
+		},
+		{
+			name: "summarized boost",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    &entity.BoostResponse{ID: 1, Source: entity.BoostSourcePlan, StartedAt: ended.StartedAt, EndsAt: ended.EndsAt, Views: 40, Likes: 7},
+			wantErr: false,
+			mockFunc: func(arg args) {
+				summarized := ended
+				summarized.Views, summarized.Likes = 40, 7
+				summarized.SummarizedAt = sql.NullTime{Time: mockTime, Valid: true}
+				boostMock.EXPECT().GetLatestByUserId(arg.ctx, int64(1)).Return(summarized, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			b := Init(log, cfg, boostMock, profileMock, subsMock, atomicMock)
+			got, err := b.GetLatest(tt.args.ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetLatest error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	boostMock := mock_boost.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	subsMock := mock_subscription.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
+	cfg := config.Boost{Duration: 30 * time.Minute}
+
+	mockTime := time.Now()
+	Now = func() time.Time {
+		return mockTime
+	}
+	defer func() {
+		Now = time.Now
+	}()
+
+	ctx := context.Background()
+	ended := entity.Boost{ID: 1, UserId: 2, Source: entity.BoostSourcePlan, StartedAt: mockTime.Add(-time.Hour), EndsAt: mockTime.Add(-30 * time.Minute)}
+
+	tests := []struct {
+		name     string
+		mockFunc func()
+		want     int
+		wantErr  bool
+	}{
+		{
+			name:    "err get ended boosts",
+			want:    0,
+			wantErr: true,
+			mockFunc: func() {
+				atomicMock.EXPECT().BeginSession(ctx).Return(atomic.NewAtomicSessionContext(ctx, sessionMock), nil)
+				boostMock.EXPECT().GetEnded(gomock.Any(), 10).Return(nil, assert.AnError)
+				log.EXPECT().Error(ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(ctx).Return(nil)
+			},
+		},
+		{
+			name:    "counters are frozen into the row",
+			want:    1,
+			wantErr: false,
+			mockFunc: func() {
+				summarized := ended
+				summarized.Views, summarized.Likes = 40, 7
+				summarized.SummarizedAt = sql.NullTime{Time: mockTime, Valid: true}
+
+				atomicMock.EXPECT().BeginSession(ctx).Return(atomic.NewAtomicSessionContext(ctx, sessionMock), nil)
+				boostMock.EXPECT().GetEnded(gomock.Any(), 10).Return([]entity.Boost{ended}, nil)
+				boostMock.EXPECT().GetStats(gomock.Any(), int64(2)).Return(int64(40), int64(7), nil)
+				boostMock.EXPECT().UpdateStats(gomock.Any(), summarized).Return(nil)
+				sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc()
+
+			b := Init(log, cfg, boostMock, profileMock, subsMock, atomicMock)
+			got, err := b.Summarize(ctx, 10)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Summarize error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
+	"loverly/lib/cursor"
+	"loverly/lib/log"
+	"loverly/lib/operator"
+	match "loverly/src/business/domain/matchs"
+	"loverly/src/business/domain/message"
+	"loverly/src/business/domain/outbox"
+	"loverly/src/business/domain/profile"
+	"loverly/src/business/entity"
+	"loverly/src/config"
+	appErr "loverly/src/errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Interface lets matched users talk, unmatched pairs no longer have an active match and lose access to the conversation
+type Interface interface {
+	Send(ctx context.Context, matchId int64, param entity.MessageParam) (entity.MessageResponse, error)
+	GetHistory(ctx context.Context, matchId int64, param entity.MessageListParam) (entity.MessageListResponse, error)
+	GetConversations(ctx context.Context, param entity.MessageListParam) (entity.ConversationListResponse, error)
+}
+
+const defaultLimit = 20
+
+type chat struct {
+	log     log.Interface
+	cfg     config.Match
+	match   match.Interface
+	message message.Interface
+	profile profile.Interface
+	outbox  outbox.Interface
+	atomic  atomic.AtomicSessionProvider
+}
+
+func Init(log log.Interface, cfg config.Match, m match.Interface, msg message.Interface, p profile.Interface, o outbox.Interface, a atomic.AtomicSessionProvider) Interface {
+	return &chat{
+		log:     log,
+		cfg:     cfg,
+		match:   m,
+		message: msg,
+		profile: p,
+		outbox:  o,
+		atomic:  a,
+	}
+}
+
+func (c *chat) Send(ctx context.Context, matchId int64, param entity.MessageParam) (entity.MessageResponse, error) {
+	var result entity.MessageResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	err := atomic.Atomic(ctx, c.atomic, c.log, func(ctx context.Context) error {
+		// the lock keeps an unmatch from slipping in between the check and the insert
+		current, err := c.match.GetForUpdate(ctx, matchId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return appErr.ErrMatchNotFound
+			}
+			return err
+		}
+
+		if current.UserId1 != int64(userId) && current.UserId2 != int64(userId) {
+			return appErr.ErrMatchNotFound
+		}
+
+		if !current.FirstMessageAt.Valid {
+			allowed, err := c.canMakeFirstMove(ctx, current, int64(userId))
+			if err != nil {
+				return err
+			}
+
+			if !allowed {
+				return appErr.ErrFirstMoveNotAllowed
+			}
+		}
+
+		msg, err := c.message.Create(ctx, entity.Message{
+			MatchId:  matchId,
+			SenderId: int64(userId),
+			Body:     param.Body,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := c.match.Touch(ctx, current); err != nil {
+			return err
+		}
+
+		receiverId := counterpart(current, int64(userId))
+		if _, err := c.outbox.Emit(ctx, entity.EventMessageSent, receiverId, entity.MessageSentPayload{
+			MessageId:  msg.ID,
+			MatchId:    matchId,
+			SenderId:   int64(userId),
+			ReceiverId: receiverId,
+		}); err != nil {
+			return err
+		}
+
+		result = toResponse(msg)
+
+		return nil
+	})
+	if err != nil {
+		return entity.MessageResponse{}, err
+	}
+
+	return result, nil
+}
+
+// GetHistory returns the conversation newest first and marks what the other side sent as read
+func (c *chat) GetHistory(ctx context.Context, matchId int64, param entity.MessageListParam) (entity.MessageListResponse, error) {
+	var result entity.MessageListResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	current, err := c.getActiveMatch(ctx, int64(userId), matchId)
+	if err != nil {
+		return result, err
+	}
+
+	limit := operator.Ternary(param.Limit < 1, defaultLimit, param.Limit)
+	page := entity.MessagePageParam{
+		MatchId:  matchId,
+		BeforeAt: cursor.Max,
+		BeforeId: math.MaxInt64,
+		Limit:    limit + 1, // one extra row tells whether there is a next page
+	}
+
+	if param.Cursor != "" {
+		var err error
+		page.BeforeAt, page.BeforeId, err = cursor.Decode(param.Cursor)
+		if err != nil {
+			return result, appErr.ErrInvalidCursor
+		}
+	}
+
+	messages, err := c.message.GetPage(ctx, page)
+	if err != nil {
+		return result, err
+	}
+
+	if len(messages) > limit {
+		messages = messages[:limit]
+		last := messages[len(messages)-1]
+		result.NextCursor = cursor.Encode(last.CreatedAt.Time, last.ID)
+	}
+
+	for _, msg := range messages {
+		result.Messages = append(result.Messages, toResponse(msg))
+	}
+
+	// opening the conversation is enough to keep the match from expiring
+	if !current.EngagedAt.Valid {
+		if err := c.match.Engage(ctx, current); err != nil {
+			return result, err
+		}
+	}
+
+	if _, err := c.message.MarkRead(ctx, matchId, int64(userId)); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// GetConversations lists the active matches by latest activity with their last message and unread count
+func (c *chat) GetConversations(ctx context.Context, param entity.MessageListParam) (entity.ConversationListResponse, error) {
+	var result entity.ConversationListResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	limit := operator.Ternary(param.Limit < 1, defaultLimit, param.Limit)
+	page := entity.MatchPageParam{
+		UserId:   int64(userId),
+		Order:    entity.MatchOrderActivity,
+		BeforeAt: cursor.Max,
+		BeforeId: math.MaxInt64,
+		Limit:    limit + 1,
+	}
+
+	if param.Cursor != "" {
+		var err error
+		page.BeforeAt, page.BeforeId, err = cursor.Decode(param.Cursor)
+		if err != nil {
+			return result, appErr.ErrInvalidCursor
+		}
+	}
+
+	matchs, err := c.match.GetPage(ctx, page)
+	if err != nil {
+		return result, err
+	}
+
+	if len(matchs) > limit {
+		matchs = matchs[:limit]
+		last := matchs[len(matchs)-1]
+		result.NextCursor = cursor.Encode(last.LastActivityAt.Time, last.ID)
+	}
+
+	if len(matchs) < 1 {
+		return result, nil
+	}
+
+	var userIds []string
+	var matchIds []int64
+	for _, mt := range matchs {
+		userIds = append(userIds, strconv.FormatInt(counterpart(mt, int64(userId)), 10))
+		matchIds = append(matchIds, mt.ID)
+	}
+
+	profiles, err := c.profile.GetByUserIds(ctx, int64(userId), userIds)
+	if err != nil {
+		return result, err
+	}
+
+	profileByUser := make(map[int64]entity.Profile, len(profiles))
+	for _, p := range profiles {
+		profileByUser[p.UserId] = p
+	}
+
+	summaries, err := c.message.GetSummaries(ctx, int64(userId), matchIds)
+	if err != nil {
+		return result, err
+	}
+
+	summaryByMatch := make(map[int64]entity.ConversationSummary, len(summaries))
+	for _, s := range summaries {
+		summaryByMatch[s.MatchId] = s
+	}
+
+	for _, mt := range matchs {
+		p, ok := profileByUser[counterpart(mt, int64(userId))]
+		if !ok {
+			continue
+		}
+
+		days := int(time.Now().Sub(p.BirthDay.Time).Hours() / 24)
+		conversation := entity.ConversationResponse{
+			MatchId: mt.ID,
+			UserId:  p.UserId,
+			Profile: entity.ProfileResponse{
+				FullName:  p.FullName,
+				Gender:    p.Gender,
+				Age:       int64(days / 365),
+				Location:  p.Location.String,
+				Bio:       p.Bio.String,
+				ProfPic:   p.ProfPic.String,
+				Interest:  p.Interest.String,
+				CreatedAt: p.CreatedAt.Time,
+			},
+			LastActivityAt: mt.LastActivityAt.Time,
+		}
+
+		if s, ok := summaryByMatch[mt.ID]; ok {
+			conversation.Unread = s.Unread
+			conversation.LastMessage = &entity.MessageResponse{
+				ID:        s.LastMessageId,
+				MatchId:   s.MatchId,
+				SenderId:  s.SenderId,
+				Body:      s.Body,
+				CreatedAt: s.CreatedAt,
+			}
+		}
+
+		result.Conversations = append(result.Conversations, conversation)
+	}
+
+	return result, nil
+}
+
+// canMakeFirstMove applies the configured first-move rule, a gender rule does not hold back same-gender pairs
+func (c *chat) canMakeFirstMove(ctx context.Context, current entity.Match, senderId int64) (bool, error) {
+	switch c.cfg.FirstMove {
+	case entity.FirstMoveFirstLiker:
+		return current.UserId2 == senderId, nil
+	case entity.FirstMoveFemale, entity.FirstMoveMale:
+		profiles, err := c.profile.GetByUserIds(ctx, senderId, []string{
+			strconv.FormatInt(current.UserId1, 10),
+			strconv.FormatInt(current.UserId2, 10),
+		})
+		if err != nil {
+			return false, err
+		}
+
+		var senderGender string
+		eligible := 0
+		for _, p := range profiles {
+			if p.UserId == senderId {
+				senderGender = p.Gender
+			}
+			if p.Gender == c.cfg.FirstMove {
+				eligible++
+			}
+		}
+
+		return senderGender == c.cfg.FirstMove || eligible != 1, nil
+	default:
+		return true, nil
+	}
+}
+
+// getActiveMatch looks the match up among the user's active ones, anything else is reported as not found
+func (c *chat) getActiveMatch(ctx context.Context, userId, matchId int64) (entity.Match, error) {
+	matchs, err := c.match.GetByUserId(ctx, userId)
+	if err != nil {
+		return entity.Match{}, err
+	}
+
+	for _, mt := range matchs {
+		if mt.ID == matchId {
+			return mt, nil
+		}
+	}
+
+	return entity.Match{}, appErr.ErrMatchNotFound
+}
+
+// counterpart returns the other user of the match
+func counterpart(m entity.Match, userId int64) int64 {
+	if m.UserId1 == userId {
+		return m.UserId2
+	}
+	return m.UserId1
+}
+
+func toResponse(msg entity.Message) entity.MessageResponse {
+	resp := entity.MessageResponse{
+		ID:        msg.ID,
+		MatchId:   msg.MatchId,
+		SenderId:  msg.SenderId,
+		Body:      msg.Body,
+		CreatedAt: msg.CreatedAt.Time,
+	}
+
+	if msg.ReadAt.Valid {
+		resp.ReadAt = &msg.ReadAt.Time
+	}
+
+	return resp
+}
@@ -0,0 +1,439 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
+	mock_atomic "loverly/lib/atomic/mock"
+	"loverly/lib/cursor"
+	mock_log "loverly/lib/log/mock"
+	mock_match "loverly/src/business/domain/mock/match"
+	mock_message "loverly/src/business/domain/mock/message"
+	mock_outbox "loverly/src/business/domain/mock/outbox"
+	mock_profile "loverly/src/business/domain/mock/profile"
+	"loverly/src/business/entity"
+	"loverly/src/config"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	appErr "loverly/src/errors"
+)
+
+func TestSend(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	matchMock := mock_match.NewMockInterface(ctrl)
+	messageMock := mock_message.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
+
+	type mockFields struct {
+		matchMock   *mock_match.MockInterface
+		messageMock *mock_message.MockInterface
+		profileMock *mock_profile.MockInterface
+		outboxMock  *mock_outbox.MockInterface
+		atomicMock  *mock_atomic.MockAtomicSessionProvider
+		sessionMock *mock_atomic.MockAtomicSession
+	}
+
+	mocks := mockFields{
+		matchMock:   matchMock,
+		messageMock: messageMock,
+		profileMock: profileMock,
+		outboxMock:  outboxMock,
+		atomicMock:  atomicMock,
+		sessionMock: sessionMock,
+	}
+
+	type args struct {
+		ctx     context.Context
+		matchId int64
+		param   entity.MessageParam
+	}
+
+	sentAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	match := entity.Match{ID: 7, UserId1: 2, UserId2: 1}
+	started := entity.Match{ID: 7, UserId1: 2, UserId2: 1, FirstMessageAt: sql.NullTime{Time: sentAt, Valid: true}}
+
+	tests := []struct {
+		name     string
+		cfg      config.Match
+		mockFunc func(mock mockFields, arg args)
+		args     args
+		want     entity.MessageResponse
+		wantErr  error
+	}{
+		{
+			name: "err invalid user id",
+			args: args{
+				ctx:     context.Background(),
+				matchId: 7,
+				param:   entity.MessageParam{Body: "hi"},
+			},
+			want:     entity.MessageResponse{},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(mock mockFields, arg args) {},
+		},
+		{
+			name: "err unmatched",
+			args: args{
+				ctx:     appcontext.SetUserId(context.Background(), 1),
+				matchId: 7,
+				param:   entity.MessageParam{Body: "hi"},
+			},
+			want:    entity.MessageResponse{},
+			wantErr: appErr.ErrMatchNotFound,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.matchMock.EXPECT().GetForUpdate(gomock.Any(), int64(7)).Return(entity.Match{}, sql.ErrNoRows)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				mock.sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "err not a participant",
+			args: args{
+				ctx:     appcontext.SetUserId(context.Background(), 3),
+				matchId: 7,
+				param:   entity.MessageParam{Body: "hi"},
+			},
+			want:    entity.MessageResponse{},
+			wantErr: appErr.ErrMatchNotFound,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.matchMock.EXPECT().GetForUpdate(gomock.Any(), int64(7)).Return(match, nil)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				mock.sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "err first move belongs to the first liker",
+			cfg:  config.Match{FirstMove: entity.FirstMoveFirstLiker},
+			args: args{
+				ctx:     appcontext.SetUserId(context.Background(), 2),
+				matchId: 7,
+				param:   entity.MessageParam{Body: "hi"},
+			},
+			want:    entity.MessageResponse{},
+			wantErr: appErr.ErrFirstMoveNotAllowed,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.matchMock.EXPECT().GetForUpdate(gomock.Any(), int64(7)).Return(match, nil)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				mock.sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "err first move belongs to the other gender",
+			cfg:  config.Match{FirstMove: entity.FirstMoveFemale},
+			args: args{
+				ctx:     appcontext.SetUserId(context.Background(), 1),
+				matchId: 7,
+				param:   entity.MessageParam{Body: "hi"},
+			},
+			want:    entity.MessageResponse{},
+			wantErr: appErr.ErrFirstMoveNotAllowed,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.matchMock.EXPECT().GetForUpdate(gomock.Any(), int64(7)).Return(match, nil)
+				mock.profileMock.EXPECT().GetByUserIds(gomock.Any(), int64(1), []string{"2", "1"}).Return([]entity.Profile{
+					{UserId: 2, Gender: entity.Female},
+					{UserId: 1, Gender: entity.Male},
+				}, nil)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				mock.sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "same gender pair skips the first move rule",
+			cfg:  config.Match{FirstMove: entity.FirstMoveFemale},
+			args: args{
+				ctx:     appcontext.SetUserId(context.Background(), 1),
+				matchId: 7,
+				param:   entity.MessageParam{Body: "hi"},
+			},
+			want:    entity.MessageResponse{ID: 10, MatchId: 7, SenderId: 1, Body: "hi", CreatedAt: sentAt},
+			wantErr: nil,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.matchMock.EXPECT().GetForUpdate(gomock.Any(), int64(7)).Return(match, nil)
+				mock.profileMock.EXPECT().GetByUserIds(gomock.Any(), int64(1), []string{"2", "1"}).Return([]entity.Profile{
+					{UserId: 2, Gender: entity.Male},
+					{UserId: 1, Gender: entity.Male},
+				}, nil)
+				mock.messageMock.EXPECT().Create(gomock.Any(), entity.Message{MatchId: 7, SenderId: 1, Body: "hi"}).Return(entity.Message{ID: 10, MatchId: 7, SenderId: 1, Body: "hi", CreatedAt: sql.NullTime{Time: sentAt, Valid: true}}, nil)
+				mock.matchMock.EXPECT().Touch(gomock.Any(), match).Return(nil)
+				mock.outboxMock.EXPECT().Emit(gomock.Any(), entity.EventMessageSent, int64(2), entity.MessageSentPayload{MessageId: 10, MatchId: 7, SenderId: 1, ReceiverId: 2}).Return(int64(1), nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "reply after the first move",
+			cfg:  config.Match{FirstMove: entity.FirstMoveFirstLiker},
+			args: args{
+				ctx:     appcontext.SetUserId(context.Background(), 2),
+				matchId: 7,
+				param:   entity.MessageParam{Body: "hey"},
+			},
+			want:    entity.MessageResponse{ID: 11, MatchId: 7, SenderId: 2, Body: "hey", CreatedAt: sentAt},
+			wantErr: nil,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.matchMock.EXPECT().GetForUpdate(gomock.Any(), int64(7)).Return(started, nil)
+				mock.messageMock.EXPECT().Create(gomock.Any(), entity.Message{MatchId: 7, SenderId: 2, Body: "hey"}).Return(entity.Message{ID: 11, MatchId: 7, SenderId: 2, Body: "hey", CreatedAt: sql.NullTime{Time: sentAt, Valid: true}}, nil)
+				mock.matchMock.EXPECT().Touch(gomock.Any(), started).Return(nil)
+				mock.outboxMock.EXPECT().Emit(gomock.Any(), entity.EventMessageSent, int64(1), entity.MessageSentPayload{MessageId: 11, MatchId: 7, SenderId: 2, ReceiverId: 1}).Return(int64(1), nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "all goods",
+			args: args{
+				ctx:     appcontext.SetUserId(context.Background(), 1),
+				matchId: 7,
+				param:   entity.MessageParam{Body: "hi"},
+			},
+			want:    entity.MessageResponse{ID: 10, MatchId: 7, SenderId: 1, Body: "hi", CreatedAt: sentAt},
+			wantErr: nil,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.matchMock.EXPECT().GetForUpdate(gomock.Any(), int64(7)).Return(match, nil)
+				mock.messageMock.EXPECT().Create(gomock.Any(), entity.Message{MatchId: 7, SenderId: 1, Body: "hi"}).Return(entity.Message{ID: 10, MatchId: 7, SenderId: 1, Body: "hi", CreatedAt: sql.NullTime{Time: sentAt, Valid: true}}, nil)
+				mock.matchMock.EXPECT().Touch(gomock.Any(), match).Return(nil)
+				mock.outboxMock.EXPECT().Emit(gomock.Any(), entity.EventMessageSent, int64(2), entity.MessageSentPayload{MessageId: 10, MatchId: 7, SenderId: 1, ReceiverId: 2}).Return(int64(1), nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks, tt.args)
+
+			c := Init(log, tt.cfg, matchMock, messageMock, profileMock, outboxMock, atomicMock)
+			got, err := c.Send(tt.args.ctx, tt.args.matchId, tt.args.param)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	matchMock := mock_match.NewMockInterface(ctrl)
+	messageMock := mock_message.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+
+	type mockFields struct {
+		matchMock   *mock_match.MockInterface
+		messageMock *mock_message.MockInterface
+	}
+
+	mocks := mockFields{
+		matchMock:   matchMock,
+		messageMock: messageMock,
+	}
+
+	type args struct {
+		ctx     context.Context
+		matchId int64
+		param   entity.MessageListParam
+	}
+
+	sentAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	readAt := sentAt.Add(time.Minute)
+	messages := []entity.Message{
+		{ID: 12, MatchId: 7, SenderId: 2, Body: "hey", CreatedAt: sql.NullTime{Time: sentAt.Add(time.Hour), Valid: true}},
+		{ID: 11, MatchId: 7, SenderId: 1, Body: "hi", CreatedAt: sql.NullTime{Time: sentAt, Valid: true}, ReadAt: sql.NullTime{Time: readAt, Valid: true}},
+		{ID: 10, MatchId: 7, SenderId: 2, Body: "hello", CreatedAt: sql.NullTime{Time: sentAt, Valid: true}},
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(mock mockFields, arg args)
+		args     args
+		want     entity.MessageListResponse
+		wantErr  error
+	}{
+		{
+			name: "err match not active",
+			args: args{
+				ctx:     appcontext.SetUserId(context.Background(), 1),
+				matchId: 7,
+			},
+			want:    entity.MessageListResponse{},
+			wantErr: appErr.ErrMatchNotFound,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.matchMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return([]entity.Match{{ID: 3, UserId1: 1, UserId2: 4}}, nil)
+			},
+		},
+		{
+			name: "err invalid cursor",
+			args: args{
+				ctx:     appcontext.SetUserId(context.Background(), 1),
+				matchId: 7,
+				param:   entity.MessageListParam{Cursor: "bm9wZQ"},
+			},
+			want:    entity.MessageListResponse{},
+			wantErr: appErr.ErrInvalidCursor,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.matchMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return([]entity.Match{{ID: 7, UserId1: 2, UserId2: 1}}, nil)
+			},
+		},
+		{
+			name: "page and mark read",
+			args: args{
+				ctx:     appcontext.SetUserId(context.Background(), 1),
+				matchId: 7,
+				param:   entity.MessageListParam{Limit: 2},
+			},
+			want: entity.MessageListResponse{
+				Messages: []entity.MessageResponse{
+					{ID: 12, MatchId: 7, SenderId: 2, Body: "hey", CreatedAt: sentAt.Add(time.Hour)},
+					{ID: 11, MatchId: 7, SenderId: 1, Body: "hi", CreatedAt: sentAt, ReadAt: &readAt},
+				},
+				NextCursor: cursor.Encode(sentAt, 11),
+			},
+			wantErr: nil,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.matchMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return([]entity.Match{{ID: 7, UserId1: 2, UserId2: 1}}, nil)
+				mock.messageMock.EXPECT().GetPage(arg.ctx, entity.MessagePageParam{MatchId: 7, BeforeAt: cursor.Max, BeforeId: math.MaxInt64, Limit: 3}).Return(messages, nil)
+				mock.matchMock.EXPECT().Engage(arg.ctx, entity.Match{ID: 7, UserId1: 2, UserId2: 1}).Return(nil)
+				mock.messageMock.EXPECT().MarkRead(arg.ctx, int64(7), int64(1)).Return(int64(2), nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks, tt.args)
+
+			c := Init(log, config.Match{}, matchMock, messageMock, profileMock, outboxMock, atomicMock)
+			got, err := c.GetHistory(tt.args.ctx, tt.args.matchId, tt.args.param)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetConversations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	matchMock := mock_match.NewMockInterface(ctrl)
+	messageMock := mock_message.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+
+	type mockFields struct {
+		matchMock   *mock_match.MockInterface
+		messageMock *mock_message.MockInterface
+		profileMock *mock_profile.MockInterface
+	}
+
+	mocks := mockFields{
+		matchMock:   matchMock,
+		messageMock: messageMock,
+		profileMock: profileMock,
+	}
+
+	type args struct {
+		ctx   context.Context
+		param entity.MessageListParam
+	}
+
+	activeAt := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	firstPage := entity.MatchPageParam{UserId: 1, Order: entity.MatchOrderActivity, BeforeAt: cursor.Max, BeforeId: math.MaxInt64, Limit: 21}
+	matchs := []entity.Match{
+		{ID: 7, UserId1: 2, UserId2: 1, LastActivityAt: sql.NullTime{Time: activeAt, Valid: true}},
+		{ID: 3, UserId1: 1, UserId2: 4, LastActivityAt: sql.NullTime{Time: activeAt.Add(-time.Hour), Valid: true}},
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(mock mockFields, arg args)
+		args     args
+		want     entity.ConversationListResponse
+		wantErr  bool
+	}{
+		{
+			name: "err get summaries",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    entity.ConversationListResponse{},
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.matchMock.EXPECT().GetPage(arg.ctx, firstPage).Return(matchs, nil)
+				mock.profileMock.EXPECT().GetByUserIds(arg.ctx, int64(1), []string{"2", "4"}).Return([]entity.Profile{{UserId: 2}, {UserId: 4}}, nil)
+				mock.messageMock.EXPECT().GetSummaries(arg.ctx, int64(1), []int64{7, 3}).Return(nil, assert.AnError)
+			},
+		},
+		{
+			name: "all goods",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want: entity.ConversationListResponse{
+				Conversations: []entity.ConversationResponse{
+					{
+						MatchId:        7,
+						UserId:         2,
+						Profile:        entity.ProfileResponse{FullName: "test", Gender: entity.Female, Age: 292},
+						LastMessage:    &entity.MessageResponse{ID: 12, MatchId: 7, SenderId: 2, Body: "hey", CreatedAt: activeAt},
+						Unread:         2,
+						LastActivityAt: activeAt,
+					},
+					{
+						MatchId:        3,
+						UserId:         4,
+						Profile:        entity.ProfileResponse{FullName: "other", Gender: entity.Female, Age: 292},
+						LastActivityAt: activeAt.Add(-time.Hour),
+					},
+				},
+			},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.matchMock.EXPECT().GetPage(arg.ctx, firstPage).Return(matchs, nil)
+				mock.profileMock.EXPECT().GetByUserIds(arg.ctx, int64(1), []string{"2", "4"}).Return([]entity.Profile{
+					{UserId: 2, FullName: "test", Gender: entity.Female},
+					{UserId: 4, FullName: "other", Gender: entity.Female},
+				}, nil)
+				mock.messageMock.EXPECT().GetSummaries(arg.ctx, int64(1), []int64{7, 3}).Return([]entity.ConversationSummary{
+					{MatchId: 7, LastMessageId: 12, SenderId: 2, Body: "hey", CreatedAt: activeAt, Unread: 2},
+				}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks, tt.args)
+
+			c := Init(log, config.Match{}, matchMock, messageMock, profileMock, outboxMock, atomicMock)
+			got, err := c.GetConversations(tt.args.ctx, tt.args.param)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetConversations error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
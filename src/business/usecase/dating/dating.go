@@ -6,12 +6,21 @@ import (
 	"errors"
 	"fmt"
 	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
 	"loverly/lib/log"
+	"loverly/src/business/domain/abuse"
+	"loverly/src/business/domain/boost"
+	"loverly/src/business/domain/deck"
 	match "loverly/src/business/domain/matchs"
+	"loverly/src/business/domain/outbox"
 	"loverly/src/business/domain/profile"
+	"loverly/src/business/domain/recommendation"
 	"loverly/src/business/domain/subscription"
 	"loverly/src/business/domain/swipe"
 	"loverly/src/business/entity"
+	"loverly/src/config"
+	"slices"
+	"strconv"
 	"time"
 
 	appErr "loverly/src/errors"
@@ -20,23 +29,50 @@ import (
 type Interface interface {
 	Discovery(ctx context.Context) ([]entity.Discovery, error)
 	Swipe(ctx context.Context, param entity.SwipeParam) (entity.SwipeResponse, error)
+	SwipeBatch(ctx context.Context, param entity.SwipeBatchParam) ([]entity.SwipeBatchResult, error)
+	ReceivedLikes(ctx context.Context) (entity.ReceivedLikesResponse, error)
+	// Rewind takes back the last pass of the user and hands the profile back, gated on FeatureRewind by the handler
+	Rewind(ctx context.Context) (entity.Discovery, error)
+
+	// decks are precomputed in the background, see worker
+	RefillDeck(ctx context.Context, userId int64) error
+	RefillDecks(ctx context.Context, batchSize int64) (int, error)
+
+	// Inspect feeds the swipes of the user to the bot detector, off the swipe path through EventSwipeRecorded
+	Inspect(ctx context.Context, param entity.SwipeRecordedPayload) error
 }
 
 type dating struct {
-	log          log.Interface
-	subscription subscription.Interface
-	profile      profile.Interface
-	swipe        swipe.Interface
-	match        match.Interface
+	log            log.Interface
+	cfg            config.Discovery
+	abuseCfg       config.Abuse
+	subscription   subscription.Interface
+	profile        profile.Interface
+	swipe          swipe.Interface
+	match          match.Interface
+	boost          boost.Interface
+	deck           deck.Interface
+	recommendation recommendation.Interface
+	abuse          abuse.Interface
+	outbox         outbox.Interface
+	atomic         atomic.AtomicSessionProvider
 }
 
-func Init(log log.Interface, subs subscription.Interface, pr profile.Interface, sw swipe.Interface, m match.Interface) Interface {
+func Init(log log.Interface, cfg config.Discovery, abuseCfg config.Abuse, subs subscription.Interface, pr profile.Interface, sw swipe.Interface, m match.Interface, b boost.Interface, dk deck.Interface, rc recommendation.Interface, ab abuse.Interface, o outbox.Interface, atomic atomic.AtomicSessionProvider) Interface {
 	return &dating{
-		log:          log,
-		subscription: subs,
-		profile:      pr,
-		swipe:        sw,
-		match:        m,
+		log:            log,
+		cfg:            cfg,
+		abuseCfg:       abuseCfg,
+		subscription:   subs,
+		profile:        pr,
+		swipe:          sw,
+		match:          m,
+		boost:          b,
+		deck:           dk,
+		recommendation: rc,
+		abuse:          ab,
+		outbox:         o,
+		atomic:         atomic,
 	}
 }
 
@@ -63,31 +99,284 @@ func (d *dating) Discovery(ctx context.Context) ([]entity.Discovery, error) {
 		return results, err
 	}
 
+	// boosted profiles in the same area go first, boosts are best effort
+	boosted, err := d.boost.GetActiveUserIds(ctx, uProfile.Location.String)
+	if err != nil {
+		d.log.Error(ctx, fmt.Sprintf("GetActiveUserIds err: %v", err))
+	}
+
+	if err := d.deck.Touch(ctx, int64(userId)); err != nil {
+		d.log.Error(ctx, fmt.Sprintf("touch deck err: %v", err))
+	}
+
+	profiles, err := d.popDeck(ctx, int64(userId))
+	if err != nil {
+		return results, err
+	}
+
+	// nothing precomputed yet, build the deck right away and keep what isn't shown for later
+	if len(profiles) < 1 {
+		candidates, err := d.buildDeck(ctx, uProfile, boosted)
+		if err != nil {
+			return results, err
+		}
+
+		profiles = candidates[:min(len(candidates), d.cfg.PageSize)]
+		if err := d.deck.Replace(ctx, int64(userId), candidates[len(profiles):], d.cfg.DeckTTL); err != nil {
+			d.log.Error(ctx, fmt.Sprintf("replace deck err: %v", err))
+		}
+	}
+
+	// boosts may have started after the deck was built
+	profiles = rankBoosted(profiles, boosted)
+
+	verified := d.verified(ctx, profiles)
+	for _, p := range profiles {
+		if slices.Contains(boosted, p.UserId) {
+			d.trackBoost(ctx, p.UserId, entity.BoostStatViews)
+		}
+
+		results = append(results, card(p, verified))
+	}
+
+	return results, nil
+}
+
+func (d *dating) Rewind(ctx context.Context) (entity.Discovery, error) {
+	var result entity.Discovery
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	swipe, err := d.swipe.Rewind(ctx, int64(userId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, appErr.ErrNothingToRewind
+		}
+		return result, err
+	}
+
+	p, err := d.profile.GetByUserId(ctx, swipe.SwipedId)
+	if err != nil {
+		return result, err
+	}
+
+	return card(p, d.verified(ctx, []entity.Profile{p})), nil
+}
+
+// verified returns the users of the profiles whose plan has the badge, failing to read it only hides the badge
+func (d *dating) verified(ctx context.Context, profiles []entity.Profile) []int64 {
+	if len(profiles) < 1 {
+		return nil
+	}
+
+	userIds := make([]int64, len(profiles))
+	for i, p := range profiles {
+		userIds[i] = p.UserId
+	}
+
+	verified, err := d.subscription.GetEntitled(ctx, entity.FeatureVerifiedBadge, userIds)
+	if err != nil {
+		d.log.Error(ctx, fmt.Sprintf("GetEntitled err: %v", err))
+	}
+
+	return verified
+}
+
+func card(p entity.Profile, verified []int64) entity.Discovery {
+	days := int(time.Now().Sub(p.BirthDay.Time).Hours() / 24)
+	return entity.Discovery{
+		ID:       p.ID,
+		FullName: p.FullName,
+		Age:      int64(days / 365),
+		Gender:   p.Gender,
+		Bio:      p.Bio.String,
+		Location: p.Location.String,
+		Interest: p.Interest.String,
+		Verified: slices.Contains(verified, p.UserId),
+	}
+}
+
+func (d *dating) RefillDeck(ctx context.Context, userId int64) error {
+	uProfile, err := d.profile.GetByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	boosted, err := d.boost.GetActiveUserIds(ctx, uProfile.Location.String)
+	if err != nil {
+		d.log.Error(ctx, fmt.Sprintf("GetActiveUserIds err: %v", err))
+	}
+
+	candidates, err := d.buildDeck(ctx, uProfile, boosted)
+	if err != nil {
+		return err
+	}
+
+	return d.deck.Replace(ctx, userId, candidates, d.cfg.DeckTTL)
+}
+
+func (d *dating) RefillDecks(ctx context.Context, batchSize int64) (int, error) {
+	userIds, err := d.deck.PopRefills(ctx, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	// active users whose deck ran low without asking, e.g. it expired
+	active, err := d.deck.GetActiveUserIds(ctx, time.Now().Add(-d.cfg.ActiveWindow))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range active {
+		if slices.Contains(userIds, id) {
+			continue
+		}
+
+		left, err := d.deck.Len(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+
+		if left < max(d.cfg.RefillThreshold, 1) {
+			userIds = append(userIds, id)
+		}
+	}
+
+	var refilled int
+	for _, id := range userIds {
+		if err := d.RefillDeck(ctx, id); err != nil {
+			d.log.Error(ctx, fmt.Sprintf("refill deck for user %d err: %v", id, err))
+			continue
+		}
+		refilled++
+	}
+
+	return refilled, nil
+}
+
+// popDeck returns the next page of the precomputed deck, skipping profiles swiped, suspended or banned since it was built
+func (d *dating) popDeck(ctx context.Context, userId int64) ([]entity.Profile, error) {
+	profiles, remaining, err := d.deck.Pop(ctx, userId, d.cfg.PageSize)
+	if err != nil {
+		// the deck is only an optimization, fall back to building it synchronously
+		d.log.Error(ctx, fmt.Sprintf("pop deck err: %v", err))
+		return nil, nil
+	}
+
+	if remaining < d.cfg.RefillThreshold {
+		if err := d.deck.RequestRefill(ctx, userId); err != nil {
+			d.log.Error(ctx, fmt.Sprintf("request deck refill err: %v", err))
+		}
+	}
+
+	if len(profiles) < 1 {
+		return profiles, nil
+	}
+
+	var userIds []int64
+	for _, p := range profiles {
+		userIds = append(userIds, p.UserId)
+	}
+
+	swiped, err := d.swipe.GetOutgoing(ctx, userId, userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	available, err := d.profile.GetAvailableIds(ctx, userId, userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(profiles, func(p entity.Profile) bool {
+		return !slices.Contains(available, p.UserId) ||
+			slices.ContainsFunc(swiped, func(s entity.Swipe) bool { return s.SwipedId == p.UserId })
+	}), nil
+}
+
+// buildDeck ranks the candidates for the user, boosted profiles in the same area first
+func (d *dating) buildDeck(ctx context.Context, uProfile entity.Profile, boosted []int64) ([]entity.Profile, error) {
 	gender := entity.Male
 	if uProfile.Gender == entity.Male {
 		gender = entity.Female
 	}
 
 	// get profile unique & with opposite gender
-	profiles, err := d.profile.GetBySwipe(ctx, int64(userId), gender)
+	profiles, err := d.profile.GetBySwipe(ctx, entity.DiscoveryParam{
+		UserId:      uProfile.UserId,
+		Gender:      gender,
+		PassCoolOff: d.cfg.PassCoolOff,
+	})
 	if err != nil {
-		return results, err
+		return nil, err
+	}
+
+	recs, err := d.getRecommendations(ctx, uProfile.UserId)
+	if err != nil {
+		// recommendations only reorder the deck, carry on without them
+		d.log.Error(ctx, fmt.Sprintf("get recommendations err: %v", err))
+	}
+
+	profiles = blendRecommended(profiles, recs, d.cfg.RecommendedShare)
+	profiles = rankBoosted(profiles, boosted)
+
+	return profiles[:min(len(profiles), d.cfg.DeckSize)], nil
+}
+
+// getRecommendations returns the candidates built by cmd/recommender, users without swipe history get the popular ones
+func (d *dating) getRecommendations(ctx context.Context, userId int64) ([]entity.Recommendation, error) {
+	recs, err := d.recommendation.GetByUserId(ctx, userId)
+	if err != nil || len(recs) > 0 {
+		return recs, err
+	}
+
+	return d.recommendation.GetByUserId(ctx, entity.ColdStartUserId)
+}
+
+// blendRecommended interleaves recommended profiles, best score first, with the rest of the candidates.
+// share is the percentage of slots given to recommendations, leftovers of either side are appended.
+func blendRecommended(profiles []entity.Profile, recs []entity.Recommendation, share int) []entity.Profile {
+	if len(recs) < 1 {
+		return profiles
 	}
 
+	rank := make(map[int64]int, len(recs))
+	for i, r := range recs {
+		rank[r.CandidateId] = i
+	}
+
+	var recommended, rest []entity.Profile
 	for _, p := range profiles {
-		days := int(time.Now().Sub(p.BirthDay.Time).Hours() / 24)
-		results = append(results, entity.Discovery{
-			ID:       p.ID,
-			FullName: p.FullName,
-			Age:      int64(days / 365),
-			Gender:   p.Gender,
-			Bio:      p.Bio.String,
-			Location: p.Location.String,
-			Interest: p.Interest.String,
-		})
+		if _, ok := rank[p.UserId]; ok {
+			recommended = append(recommended, p)
+			continue
+		}
+		rest = append(rest, p)
 	}
+	slices.SortStableFunc(recommended, func(a, b entity.Profile) int {
+		return rank[a.UserId] - rank[b.UserId]
+	})
 
-	return results, nil
+	blended := make([]entity.Profile, 0, len(profiles))
+	var taken int
+	for len(recommended) > 0 || len(rest) > 0 {
+		// by slot n, ceil(n * share / 100) recommendations should have been placed
+		due := ((len(blended)+1)*share + 99) / 100
+		if len(recommended) > 0 && (due > taken || len(rest) < 1) {
+			blended = append(blended, recommended[0])
+			recommended = recommended[1:]
+			taken++
+			continue
+		}
+
+		blended = append(blended, rest[0])
+		rest = rest[1:]
+	}
+
+	return blended
 }
 
 func (d *dating) Swipe(ctx context.Context, param entity.SwipeParam) (entity.SwipeResponse, error) {
@@ -98,6 +387,19 @@ func (d *dating) Swipe(ctx context.Context, param entity.SwipeParam) (entity.Swi
 		return result, appErr.ErrInvalidUserId
 	}
 
+	// liking back from a blurred received like, the liker stays hidden unless it's a match
+	if param.LikeId > 0 {
+		like, err := d.swipe.GetReceivedLike(ctx, param.LikeId, int64(userId))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return result, appErr.ErrLikeNotFound
+			}
+			return result, err
+		}
+
+		param.SwipedId = like.SwiperId
+	}
+
 	access, err := d.checkQuotaLimit(ctx, int64(userId))
 	if err != nil {
 		return result, err
@@ -117,6 +419,9 @@ func (d *dating) Swipe(ctx context.Context, param entity.SwipeParam) (entity.Swi
 		return result, err
 	}
 
+	// a shadow-limited user is told the like went through, it just never counts
+	shadowed := d.screen(ctx, int64(userId))
+
 	// check match or not
 	match, err := d.swipe.GetBySwipeId(ctx, param.SwipedId, int64(userId))
 	if err != nil {
@@ -125,49 +430,448 @@ func (d *dating) Swipe(ctx context.Context, param entity.SwipeParam) (entity.Swi
 		}
 	}
 
-	if match.ID > 0 && match.Direction == entity.Like && param.Direction == entity.Like {
-		_, err = d.match.Create(ctx, entity.Match{
-			UserId1: int64(userId),
-			UserId2: param.SwipedId,
-		})
+	likedBack := !shadowed && match.ID > 0 && match.Direction == entity.Like && param.Direction == entity.Like
+	if likedBack {
+		result.Match, err = d.createMatch(ctx, int64(userId), param.SwipedId)
 		if err != nil {
 			return result, err
 		}
-
-		result.Match = true
 	}
 
 	result.Like = false
 	if param.Direction == entity.Like {
 		result.Like = true
+		if shadowed {
+			return result, nil
+		}
+
+		d.trackBoost(ctx, param.SwipedId, entity.BoostStatLikes)
+
+		// a match is announced through its own event, a like on a pair matched before isn't news
+		if !likedBack {
+			d.notifyLike(ctx, param.SwipedId)
+		}
 	}
 
 	return result, nil
 }
 
-func (d *dating) checkQuotaLimit(ctx context.Context, userId int64) (bool, error) {
-	// check quota plan from subscription
-	sub, err := d.subscription.GetByPlan(ctx, int64(userId), entity.UnlimitedPlan)
+func (d *dating) SwipeBatch(ctx context.Context, param entity.SwipeBatchParam) ([]entity.SwipeBatchResult, error) {
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return nil, appErr.ErrInvalidUserId
+	}
+
+	remaining, limited, err := d.getQuota(ctx, int64(userId))
 	if err != nil {
-		if !errors.Is(err, sql.ErrNoRows) {
-			return false, err
+		return nil, err
+	}
+
+	// replay in the order the swipes were made on the device, keeping request order for ties
+	order := make([]int, len(param.Swipes))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return param.Swipes[a].SwipedAt.Compare(param.Swipes[b].SwipedAt)
+	})
+
+	var swipedIds []int64
+	for _, item := range param.Swipes {
+		swipedIds = append(swipedIds, item.SwipedId)
+	}
+
+	existing, err := d.swipe.GetOutgoing(ctx, int64(userId), swipedIds)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := make(map[int64]string, len(existing))
+	for _, sw := range existing {
+		previous[sw.SwipedId] = sw.Direction
+	}
+
+	results := make([]entity.SwipeBatchResult, len(param.Swipes))
+	pending := make(map[int64]int, len(param.Swipes))
+	var applied []int
+	var creates []entity.Swipe
+	for _, i := range order {
+		item := param.Swipes[i]
+		results[i] = entity.SwipeBatchResult{Index: i, SwipedId: item.SwipedId, Like: item.Direction == entity.Like}
+
+		switch {
+		case item.SwipedId == int64(userId):
+			results[i].Status, results[i].Err = entity.SwipeFailed, appErr.ErrInvalidSwipeTarget
+			continue
+		case previous[item.SwipedId] == item.Direction:
+			// already applied earlier in this batch or by a previous replay
+			results[i].Status = entity.SwipeDuplicate
+			continue
 		}
+
+		// the latest swipe on a target wins, as it would replayed one at a time. It replaces the earlier
+		// one of the batch without using more quota, a swipe on the same target is only counted once
+		if at, ok := pending[item.SwipedId]; ok {
+			results[applied[at]].Status = entity.SwipeSuperseded
+			applied[at] = i
+			creates[at].Direction = item.Direction
+			previous[item.SwipedId] = item.Direction
+			continue
+		}
+
+		if limited && remaining < 1 {
+			results[i].Status, results[i].Err = entity.SwipeQuotaExceeded, appErr.ErrSwipeQuotaExceeded
+			continue
+		}
+
+		remaining--
+		pending[item.SwipedId] = len(applied)
+		previous[item.SwipedId] = item.Direction
+		applied = append(applied, i)
+		creates = append(creates, entity.Swipe{SwiperId: int64(userId), SwipedId: item.SwipedId, Direction: item.Direction})
+	}
+
+	if len(creates) < 1 {
+		return results, nil
+	}
+
+	if _, err := d.swipe.BulkCreate(ctx, creates); err != nil {
+		return nil, err
+	}
+
+	shadowed := d.screen(ctx, int64(userId))
+
+	var likedIds []int64
+	for _, c := range creates {
+		if c.Direction == entity.Like {
+			likedIds = append(likedIds, c.SwipedId)
+		}
+	}
+
+	likedBack := make(map[int64]bool)
+	if len(likedIds) > 0 && !shadowed {
+		incoming, err := d.swipe.GetIncoming(ctx, int64(userId), likedIds)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, sw := range incoming {
+			likedBack[sw.SwiperId] = sw.Direction == entity.Like
+		}
+	}
+
+	for _, i := range applied {
+		results[i].Status = entity.SwipeApplied
+		if !results[i].Like || shadowed {
+			continue
+		}
+
+		d.trackBoost(ctx, results[i].SwipedId, entity.BoostStatLikes)
+		if !likedBack[results[i].SwipedId] {
+			d.notifyLike(ctx, results[i].SwipedId)
+			continue
+		}
+
+		matched, err := d.createMatch(ctx, int64(userId), results[i].SwipedId)
+		if err != nil {
+			results[i].Status, results[i].Err = entity.SwipeFailed, err
+			continue
+		}
+
+		results[i].Match = matched
+	}
+
+	return results, nil
+}
+
+func (d *dating) ReceivedLikes(ctx context.Context) (entity.ReceivedLikesResponse, error) {
+	var result entity.ReceivedLikesResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	likes, err := d.swipe.GetReceivedLikes(ctx, int64(userId), d.cfg.PassCoolOff)
+	if err != nil {
+		return result, err
+	}
+
+	result.Total = len(likes)
+	if len(likes) < 1 {
+		return result, nil
+	}
+
+	seeLikes, err := d.entitled(ctx, int64(userId), entity.FeatureSeeLikes)
+	if err != nil {
+		return result, err
+	}
+
+	var userIds []string
+	for _, l := range likes {
+		userIds = append(userIds, strconv.FormatInt(l.SwiperId, 10))
+	}
+
+	profiles, err := d.profile.GetByUserIds(ctx, int64(userId), userIds)
+	if err != nil {
+		return result, err
+	}
+
+	profileByUser := make(map[int64]entity.Profile, len(profiles))
+	for _, p := range profiles {
+		profileByUser[p.UserId] = p
+	}
+
+	for _, l := range likes {
+		p, ok := profileByUser[l.SwiperId]
+		if !ok {
+			continue
+		}
+
+		days := int(time.Now().Sub(p.BirthDay.Time).Hours() / 24)
+		like := entity.ReceivedLike{
+			LikeId:  l.ID,
+			Age:     int64(days / 365),
+			Gender:  p.Gender,
+			Blurred: true,
+			LikedAt: l.UpdatedAt.Time,
+		}
+
+		// without see likes the user only gets a teaser, liking back goes through Swipe with the like id
+		if seeLikes {
+			like.UserId = p.UserId
+			like.FullName = p.FullName
+			like.Bio = p.Bio.String
+			like.Location = p.Location.String
+			like.Interest = p.Interest.String
+			like.ProfPic = p.ProfPic.String
+			like.Blurred = false
+		}
+
+		result.Likes = append(result.Likes, like)
+	}
+
+	return result, nil
+}
+
+// entitled tells whether the plan of the user's current subscription has the feature
+func (d *dating) entitled(ctx context.Context, userId int64, feature string) (bool, error) {
+	features, err := d.subscription.GetFeatures(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.Contains(features, feature), nil
+}
+
+func (d *dating) trackBoost(ctx context.Context, userId int64, stat string) {
+	if err := d.boost.Track(ctx, userId, stat); err != nil {
+		d.log.Error(ctx, fmt.Sprintf("track boost %s err: %v", stat, err))
+	}
+}
+
+// notifyLike tells the liked user in real time without revealing who, that stays behind received likes
+func (d *dating) notifyLike(ctx context.Context, userId int64) {
+	_, err := d.outbox.Emit(ctx, entity.EventLikeReceived, userId, entity.LikeReceivedPayload{UserId: userId})
+	if err != nil {
+		d.log.Error(ctx, fmt.Sprintf("notify like err: %v", err))
+	}
+}
+
+// rankBoosted moves boosted profiles to the front, keeping the original order otherwise
+func rankBoosted(profiles []entity.Profile, boosted []int64) []entity.Profile {
+	if len(boosted) < 1 {
+		return profiles
+	}
+
+	ranked := make([]entity.Profile, 0, len(profiles))
+	var rest []entity.Profile
+	for _, p := range profiles {
+		if slices.Contains(boosted, p.UserId) {
+			ranked = append(ranked, p)
+			continue
+		}
+		rest = append(rest, p)
+	}
+
+	return append(ranked, rest...)
+}
+
+func (d *dating) checkQuotaLimit(ctx context.Context, userId int64) (bool, error) {
+	remaining, limited, err := d.getQuota(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+
+	if limited && remaining < 1 {
+		return false, fmt.Errorf("Your quotas exceeded!")
+	}
+
+	return true, nil
+}
+
+// getQuota returns how many swipes are left today and whether the user is limited at all
+func (d *dating) getQuota(ctx context.Context, userId int64) (int, bool, error) {
+	unlimited, err := d.entitled(ctx, userId, entity.FeatureUnlimitedSwipes)
+	if err != nil {
+		return 0, false, err
 	}
 
 	// get swipe today
 	swipes, err := d.swipe.GetBySwiperId(ctx, userId)
 	if err != nil {
 		if !errors.Is(err, sql.ErrNoRows) {
-			return false, err
+			return 0, false, err
 		}
 	}
 
-	quota := 10                                       // default quota
-	if sub.ID == 0 || sub.EndDate.After(time.Now()) { // if don't have a plan or expired plan
-		if len(swipes) >= quota {
-			return false, fmt.Errorf("Your quotas exceeded!")
+	quota := 10 // default quota
+	if !unlimited {
+		return quota - len(swipes), true, nil
+	}
+
+	return 0, false, nil
+}
+
+// screen tells whether the user is shadow-limited and has the swipe inspected later,
+// it's best-effort and never fails the swipe
+func (d *dating) screen(ctx context.Context, userId int64) bool {
+	cfg := d.abuseCfg
+	if cfg.MaxSwipes < 1 && cfg.MaxLikeRatio < 1 && cfg.MaxSharedBio < 1 && cfg.MaxSharedIP < 1 && cfg.MaxSharedDevice < 1 {
+		return false
+	}
+
+	_, err := d.outbox.Emit(ctx, entity.EventSwipeRecorded, userId, entity.SwipeRecordedPayload{
+		UserId:   userId,
+		IP:       appcontext.GetRequestIP(ctx),
+		DeviceId: appcontext.GetDeviceId(ctx),
+	})
+	if err != nil {
+		d.log.Error(ctx, fmt.Sprintf("emit swipe recorded err: %v", err))
+	}
+
+	limited, err := d.abuse.IsShadowLimited(ctx, userId)
+	if err != nil {
+		d.log.Error(ctx, fmt.Sprintf("get shadow limit err: %v", err))
+		return false
+	}
+
+	return limited
+}
+
+func (d *dating) Inspect(ctx context.Context, param entity.SwipeRecordedPayload) error {
+	cfg := d.abuseCfg
+
+	if param.IP != "" || param.DeviceId != "" {
+		if err := d.abuse.Track(ctx, entity.Fingerprint{UserId: param.UserId, IP: param.IP, DeviceId: param.DeviceId}); err != nil {
+			return err
 		}
 	}
 
-	return true, nil
+	now := time.Now()
+	signals, err := d.abuse.GetSignals(ctx, entity.AbuseSignalParam{
+		UserId:           param.UserId,
+		RateSince:        now.Add(-cfg.SwipeWindow),
+		RatioSince:       now.Add(-cfg.RatioWindow),
+		FingerprintSince: now.Add(-cfg.FingerprintWindow),
+	})
+	if err != nil {
+		return err
+	}
+
+	flags := detect(cfg, param.UserId, signals)
+	if len(flags) < 1 {
+		return nil
+	}
+
+	// a signal is raised once, or again on worse evidence after a dismissal, the limit is synced when one is
+	raised := false
+	err = atomic.Atomic(ctx, d.atomic, d.log, func(ctx context.Context) error {
+		for _, flag := range flags {
+			id, err := d.abuse.Flag(ctx, flag)
+			if err != nil {
+				return err
+			}
+			raised = raised || id > 0
+		}
+
+		if !raised {
+			return nil
+		}
+
+		return d.abuse.SyncShadowLimit(ctx, param.UserId)
+	})
+	if err != nil || !raised {
+		return err
+	}
+
+	// committed, the limit is there for good even if the invalidation fails
+	if err := d.swipe.InvalidateUser(ctx, param.UserId); err != nil {
+		d.log.Error(ctx, fmt.Sprintf("invalidate swipes of user %d err: %v", param.UserId, err))
+	}
+
+	return nil
+}
+
+// detect explains every signal above its threshold, a zero threshold is off
+func detect(cfg config.Abuse, userId int64, signals entity.AbuseSignals) []entity.AbuseFlag {
+	var flags []entity.AbuseFlag
+	raise := func(signal string, value int, format string, args ...any) {
+		flags = append(flags, entity.AbuseFlag{UserId: userId, Signal: signal, Explanation: fmt.Sprintf(format, args...), Value: value})
+	}
+
+	if cfg.MaxSwipes > 0 && signals.RecentSwipes > cfg.MaxSwipes {
+		raise(entity.AbuseSwipeRate, signals.RecentSwipes, "%d swipes in the last %s, more than %d", signals.RecentSwipes, cfg.SwipeWindow, cfg.MaxSwipes)
+	}
+
+	if cfg.MaxLikeRatio > 0 && signals.Swipes >= max(cfg.MinRatioSwipes, 1) && signals.Likes*100 > cfg.MaxLikeRatio*signals.Swipes {
+		raise(entity.AbuseLikeRatio, signals.Likes*100/signals.Swipes, "liked %d of %d swipes in the last %s, more than %d%%", signals.Likes, signals.Swipes, cfg.RatioWindow, cfg.MaxLikeRatio)
+	}
+
+	if cfg.MaxSharedBio > 0 && signals.SharedBio > cfg.MaxSharedBio {
+		raise(entity.AbuseSharedBio, signals.SharedBio, "same bio as %d other accounts, more than %d", signals.SharedBio, cfg.MaxSharedBio)
+	}
+
+	if cfg.MaxSharedIP > 0 && signals.SharedIP > cfg.MaxSharedIP {
+		raise(entity.AbuseSharedIP, signals.SharedIP, "%d other accounts seen from the same IP in the last %s, more than %d", signals.SharedIP, cfg.FingerprintWindow, cfg.MaxSharedIP)
+	}
+
+	if cfg.MaxSharedDevice > 0 && signals.SharedDevice > cfg.MaxSharedDevice {
+		raise(entity.AbuseSharedDevice, signals.SharedDevice, "%d other accounts seen from the same device in the last %s, more than %d", signals.SharedDevice, cfg.FingerprintWindow, cfg.MaxSharedDevice)
+	}
+
+	return flags
+}
+
+// createMatch stores the match together with its MatchCreated event, it tells false when the pair was matched before
+func (d *dating) createMatch(ctx context.Context, userId, otherId int64) (bool, error) {
+	var created bool
+	err := atomic.Atomic(ctx, d.atomic, d.log, func(ctx context.Context) error {
+		matchId, err := d.match.Create(ctx, entity.Match{
+			UserId1: userId,
+			UserId2: otherId,
+		})
+		if err != nil {
+			return err
+		}
+
+		// liked again while matched or after an unmatch
+		if matchId == 0 {
+			return nil
+		}
+		created = true
+
+		_, err = d.outbox.Emit(ctx, entity.EventMatchCreated, userId, entity.MatchCreatedPayload{
+			MatchId: matchId,
+			UserId1: userId,
+			UserId2: otherId,
+		})
+
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return created, nil
 }
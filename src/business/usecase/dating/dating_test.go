@@ -2,17 +2,29 @@ package dating
 
 import (
 	"context"
+	"database/sql"
 	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
+	mock_atomic "loverly/lib/atomic/mock"
 	mock_log "loverly/lib/log/mock"
+	mock_abuse "loverly/src/business/domain/mock/abuse"
+	mock_boost "loverly/src/business/domain/mock/boost"
+	mock_deck "loverly/src/business/domain/mock/deck"
 	mock_match "loverly/src/business/domain/mock/match"
+	mock_outbox "loverly/src/business/domain/mock/outbox"
 	mock_profile "loverly/src/business/domain/mock/profile"
+	mock_recommendation "loverly/src/business/domain/mock/recommendation"
 	mock_subscription "loverly/src/business/domain/mock/subscription"
 	mock_swipe "loverly/src/business/domain/mock/swipe"
 	"loverly/src/business/entity"
+	"loverly/src/config"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
+
+	appErr "loverly/src/errors"
 )
 
 func TestDiscovery(t *testing.T) {
@@ -24,12 +36,22 @@ func TestDiscovery(t *testing.T) {
 	profileMock := mock_profile.NewMockInterface(ctrl)
 	swipeMock := mock_swipe.NewMockInterface(ctrl)
 	matchMock := mock_match.NewMockInterface(ctrl)
+	boostMock := mock_boost.NewMockInterface(ctrl)
+	deckMock := mock_deck.NewMockInterface(ctrl)
+	recMock := mock_recommendation.NewMockInterface(ctrl)
+	abuseMock := mock_abuse.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	cfg := config.Discovery{PassCoolOff: 720 * time.Hour, PageSize: 10, DeckSize: 100, DeckTTL: time.Hour, RefillThreshold: 5, ActiveWindow: 24 * time.Hour, RecommendedShare: 50}
 
 	type mockFields struct {
 		subsMock    *mock_subscription.MockInterface
 		profileMock *mock_profile.MockInterface
 		swipeMock   *mock_swipe.MockInterface
 		matchMock   *mock_match.MockInterface
+		boostMock   *mock_boost.MockInterface
+		deckMock    *mock_deck.MockInterface
+		recMock     *mock_recommendation.MockInterface
 	}
 
 	mocks := mockFields{
@@ -37,6 +59,9 @@ func TestDiscovery(t *testing.T) {
 		profileMock: profileMock,
 		swipeMock:   swipeMock,
 		matchMock:   matchMock,
+		boostMock:   boostMock,
+		deckMock:    deckMock,
+		recMock:     recMock,
 	}
 
 	type args struct {
@@ -64,7 +89,7 @@ func TestDiscovery(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 			mockFunc: func(mock mockFields, arg args) {
-				mock.subsMock.EXPECT().GetByPlan(arg.ctx, int64(1), entity.UnlimitedPlan).Return(entity.Subscription{}, assert.AnError)
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return(nil, assert.AnError)
 			},
 		},
 		{
@@ -75,7 +100,7 @@ func TestDiscovery(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 			mockFunc: func(mock mockFields, arg args) {
-				mock.subsMock.EXPECT().GetByPlan(arg.ctx, int64(1), entity.UnlimitedPlan).Return(entity.Subscription{}, nil)
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
 				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return([]entity.Swipe{}, assert.AnError)
 			},
 		},
@@ -87,8 +112,21 @@ func TestDiscovery(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 			mockFunc: func(mock mockFields, arg args) {
-				mock.subsMock.EXPECT().GetByPlan(arg.ctx, int64(1), entity.UnlimitedPlan).Return(entity.Subscription{}, nil)
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
+				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMax, nil)
+			},
+		},
+		{
+			name: "unlimited swipes goes past the quota",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    nil,
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{entity.FeatureUnlimitedSwipes}, nil)
 				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMax, nil)
+				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Profile{}, assert.AnError)
 			},
 		},
 		{
@@ -99,7 +137,7 @@ func TestDiscovery(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 			mockFunc: func(mock mockFields, arg args) {
-				mock.subsMock.EXPECT().GetByPlan(arg.ctx, int64(1), entity.UnlimitedPlan).Return(entity.Subscription{}, nil)
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
 				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMin, nil)
 				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Profile{}, assert.AnError)
 			},
@@ -112,10 +150,14 @@ func TestDiscovery(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 			mockFunc: func(mock mockFields, arg args) {
-				mock.subsMock.EXPECT().GetByPlan(arg.ctx, int64(1), entity.UnlimitedPlan).Return(entity.Subscription{}, nil)
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
 				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMin, nil)
-				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Profile{Gender: entity.Male}, nil)
-				mock.profileMock.EXPECT().GetBySwipe(arg.ctx, int64(1), entity.Female).Return([]entity.Profile{}, assert.AnError)
+				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Profile{UserId: 1, Gender: entity.Male}, nil)
+				mock.boostMock.EXPECT().GetActiveUserIds(arg.ctx, "").Return(nil, nil)
+				mock.deckMock.EXPECT().Touch(arg.ctx, int64(1)).Return(nil)
+				mock.deckMock.EXPECT().Pop(arg.ctx, int64(1), cfg.PageSize).Return(nil, int64(0), nil)
+				mock.deckMock.EXPECT().RequestRefill(arg.ctx, int64(1)).Return(nil)
+				mock.profileMock.EXPECT().GetBySwipe(arg.ctx, entity.DiscoveryParam{UserId: 1, Gender: entity.Female, PassCoolOff: cfg.PassCoolOff}).Return([]entity.Profile{}, assert.AnError)
 			},
 		},
 		{
@@ -126,10 +168,101 @@ func TestDiscovery(t *testing.T) {
 			want:    allGoods,
 			wantErr: false,
 			mockFunc: func(mock mockFields, arg args) {
-				mock.subsMock.EXPECT().GetByPlan(arg.ctx, int64(1), entity.UnlimitedPlan).Return(entity.Subscription{}, nil)
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
+				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMin, nil)
+				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Profile{UserId: 1, Gender: entity.Male}, nil)
+				mock.boostMock.EXPECT().GetActiveUserIds(arg.ctx, "").Return(nil, nil)
+				mock.deckMock.EXPECT().Touch(arg.ctx, int64(1)).Return(nil)
+				mock.deckMock.EXPECT().Pop(arg.ctx, int64(1), cfg.PageSize).Return(nil, int64(0), nil)
+				mock.deckMock.EXPECT().RequestRefill(arg.ctx, int64(1)).Return(nil)
+				mock.profileMock.EXPECT().GetBySwipe(arg.ctx, entity.DiscoveryParam{UserId: 1, Gender: entity.Female, PassCoolOff: cfg.PassCoolOff}).Return([]entity.Profile{{FullName: "test", Gender: entity.Female}}, nil)
+				mock.recMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(nil, nil)
+				mock.recMock.EXPECT().GetByUserId(arg.ctx, int64(entity.ColdStartUserId)).Return(nil, nil)
+				mock.deckMock.EXPECT().Replace(arg.ctx, int64(1), []entity.Profile{}, cfg.DeckTTL).Return(nil)
+				mock.subsMock.EXPECT().GetEntitled(arg.ctx, entity.FeatureVerifiedBadge, []int64{0}).Return(nil, nil)
+			},
+		},
+		{
+			name: "recommended profiles are blended in",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    []entity.Discovery{{FullName: "recommended", Gender: entity.Female, Age: 292}, {FullName: "a", Gender: entity.Female, Age: 292}, {FullName: "b", Gender: entity.Female, Age: 292}},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
+				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMin, nil)
+				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Profile{UserId: 1, Gender: entity.Male}, nil)
+				mock.boostMock.EXPECT().GetActiveUserIds(arg.ctx, "").Return(nil, nil)
+				mock.deckMock.EXPECT().Touch(arg.ctx, int64(1)).Return(nil)
+				mock.deckMock.EXPECT().Pop(arg.ctx, int64(1), cfg.PageSize).Return(nil, int64(0), nil)
+				mock.deckMock.EXPECT().RequestRefill(arg.ctx, int64(1)).Return(nil)
+				mock.profileMock.EXPECT().GetBySwipe(arg.ctx, entity.DiscoveryParam{UserId: 1, Gender: entity.Female, PassCoolOff: cfg.PassCoolOff}).Return([]entity.Profile{{UserId: 2, FullName: "a", Gender: entity.Female}, {UserId: 3, FullName: "b", Gender: entity.Female}, {UserId: 4, FullName: "recommended", Gender: entity.Female}}, nil)
+				mock.recMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return([]entity.Recommendation{{UserId: 1, CandidateId: 4, Score: 0.8}}, nil)
+				mock.deckMock.EXPECT().Replace(arg.ctx, int64(1), []entity.Profile{}, cfg.DeckTTL).Return(nil)
+				mock.subsMock.EXPECT().GetEntitled(arg.ctx, entity.FeatureVerifiedBadge, []int64{4, 2, 3}).Return(nil, nil)
+			},
+		},
+		{
+			name: "page from precomputed deck",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    []entity.Discovery{{FullName: "test", Gender: entity.Female, Age: 292, Verified: true}},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
+				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMin, nil)
+				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Profile{UserId: 1, Gender: entity.Male}, nil)
+				mock.boostMock.EXPECT().GetActiveUserIds(arg.ctx, "").Return(nil, nil)
+				mock.deckMock.EXPECT().Touch(arg.ctx, int64(1)).Return(nil)
+				mock.deckMock.EXPECT().Pop(arg.ctx, int64(1), cfg.PageSize).Return([]entity.Profile{{UserId: 2, FullName: "test", Gender: entity.Female}, {UserId: 3, FullName: "swiped", Gender: entity.Female}}, int64(30), nil)
+				mock.swipeMock.EXPECT().GetOutgoing(arg.ctx, int64(1), []int64{2, 3}).Return([]entity.Swipe{{SwiperId: 1, SwipedId: 3}}, nil)
+				mock.profileMock.EXPECT().GetAvailableIds(arg.ctx, int64(1), []int64{2, 3}).Return([]int64{2, 3}, nil)
+				mock.subsMock.EXPECT().GetEntitled(arg.ctx, entity.FeatureVerifiedBadge, []int64{2}).Return([]int64{2}, nil)
+			},
+		},
+		{
+			name: "deck skips users banned or blocked since it was built",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    allGoods,
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
 				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMin, nil)
-				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Profile{Gender: entity.Male}, nil)
-				mock.profileMock.EXPECT().GetBySwipe(arg.ctx, int64(1), entity.Female).Return([]entity.Profile{{FullName: "test", Gender: entity.Female}}, nil)
+				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Profile{UserId: 1, Gender: entity.Male}, nil)
+				mock.boostMock.EXPECT().GetActiveUserIds(arg.ctx, "").Return(nil, nil)
+				mock.deckMock.EXPECT().Touch(arg.ctx, int64(1)).Return(nil)
+				mock.deckMock.EXPECT().Pop(arg.ctx, int64(1), cfg.PageSize).Return([]entity.Profile{{UserId: 2, FullName: "test", Gender: entity.Female}, {UserId: 3, FullName: "banned", Gender: entity.Female}}, int64(30), nil)
+				mock.swipeMock.EXPECT().GetOutgoing(arg.ctx, int64(1), []int64{2, 3}).Return(nil, nil)
+				mock.profileMock.EXPECT().GetAvailableIds(arg.ctx, int64(1), []int64{2, 3}).Return([]int64{2}, nil)
+				mock.subsMock.EXPECT().GetEntitled(arg.ctx, entity.FeatureVerifiedBadge, []int64{2}).Return(nil, assert.AnError)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+			},
+		},
+		{
+			name: "boosted profiles go first",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    []entity.Discovery{{FullName: "boosted", Gender: entity.Female, Age: 292}, {FullName: "test", Gender: entity.Female, Age: 292}},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
+				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMin, nil)
+				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Profile{UserId: 1, Gender: entity.Male}, nil)
+				mock.boostMock.EXPECT().GetActiveUserIds(arg.ctx, "").Return([]int64{3}, nil)
+				mock.deckMock.EXPECT().Touch(arg.ctx, int64(1)).Return(nil)
+				mock.deckMock.EXPECT().Pop(arg.ctx, int64(1), cfg.PageSize).Return(nil, int64(0), nil)
+				mock.deckMock.EXPECT().RequestRefill(arg.ctx, int64(1)).Return(nil)
+				mock.profileMock.EXPECT().GetBySwipe(arg.ctx, entity.DiscoveryParam{UserId: 1, Gender: entity.Female, PassCoolOff: cfg.PassCoolOff}).Return([]entity.Profile{{UserId: 2, FullName: "test", Gender: entity.Female}, {UserId: 3, FullName: "boosted", Gender: entity.Female}}, nil)
+				mock.recMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(nil, nil)
+				mock.recMock.EXPECT().GetByUserId(arg.ctx, int64(entity.ColdStartUserId)).Return(nil, nil)
+				mock.deckMock.EXPECT().Replace(arg.ctx, int64(1), []entity.Profile{}, cfg.DeckTTL).Return(nil)
+				mock.subsMock.EXPECT().GetEntitled(arg.ctx, entity.FeatureVerifiedBadge, []int64{3, 2}).Return(nil, nil)
+				mock.boostMock.EXPECT().Track(arg.ctx, int64(3), entity.BoostStatViews).Return(nil)
 			},
 		},
 	}
@@ -138,7 +271,7 @@ func TestDiscovery(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockFunc(mocks, tt.args)
 
-			d := Init(log, subsMock, profileMock, swipeMock, matchMock)
+			d := Init(log, cfg, config.Abuse{}, subsMock, profileMock, swipeMock, matchMock, boostMock, deckMock, recMock, abuseMock, outboxMock, atomicMock)
 			got, err := d.Discovery(tt.args.ctx)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Discover error = %v, wantErr %v", err, tt.wantErr)
@@ -150,6 +283,90 @@ func TestDiscovery(t *testing.T) {
 	}
 }
 
+func TestRefillDecks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	subsMock := mock_subscription.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	swipeMock := mock_swipe.NewMockInterface(ctrl)
+	matchMock := mock_match.NewMockInterface(ctrl)
+	boostMock := mock_boost.NewMockInterface(ctrl)
+	deckMock := mock_deck.NewMockInterface(ctrl)
+	recMock := mock_recommendation.NewMockInterface(ctrl)
+	abuseMock := mock_abuse.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	cfg := config.Discovery{PassCoolOff: 720 * time.Hour, PageSize: 10, DeckSize: 1, DeckTTL: time.Hour, RefillThreshold: 5, ActiveWindow: 24 * time.Hour, RecommendedShare: 100}
+
+	type args struct {
+		ctx       context.Context
+		batchSize int64
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		want     int
+		wantErr  bool
+	}{
+		{
+			name: "err pop refills",
+			args: args{
+				ctx:       context.Background(),
+				batchSize: 10,
+			},
+			want:    0,
+			wantErr: true,
+			mockFunc: func(arg args) {
+				deckMock.EXPECT().PopRefills(arg.ctx, int64(10)).Return(nil, assert.AnError)
+			},
+		},
+		{
+			name: "refill requested and low active decks",
+			args: args{
+				ctx:       context.Background(),
+				batchSize: 10,
+			},
+			want:    1,
+			wantErr: false,
+			mockFunc: func(arg args) {
+				deckMock.EXPECT().PopRefills(arg.ctx, int64(10)).Return([]int64{2}, nil)
+				deckMock.EXPECT().GetActiveUserIds(arg.ctx, gomock.Any()).Return([]int64{2, 3, 4}, nil)
+				deckMock.EXPECT().Len(arg.ctx, int64(3)).Return(int64(0), nil)
+				deckMock.EXPECT().Len(arg.ctx, int64(4)).Return(int64(50), nil)
+
+				// a failing user doesn't stop the others
+				profileMock.EXPECT().GetByUserId(arg.ctx, int64(2)).Return(entity.Profile{}, assert.AnError)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+
+				profileMock.EXPECT().GetByUserId(arg.ctx, int64(3)).Return(entity.Profile{UserId: 3, Gender: entity.Female}, nil)
+				boostMock.EXPECT().GetActiveUserIds(arg.ctx, "").Return(nil, nil)
+				profileMock.EXPECT().GetBySwipe(arg.ctx, entity.DiscoveryParam{UserId: 3, Gender: entity.Male, PassCoolOff: cfg.PassCoolOff}).Return([]entity.Profile{{UserId: 5}, {UserId: 6}}, nil)
+				recMock.EXPECT().GetByUserId(arg.ctx, int64(3)).Return([]entity.Recommendation{{UserId: 3, CandidateId: 6, Score: 1}}, nil)
+				deckMock.EXPECT().Replace(arg.ctx, int64(3), []entity.Profile{{UserId: 6}}, cfg.DeckTTL).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			d := Init(log, cfg, config.Abuse{}, subsMock, profileMock, swipeMock, matchMock, boostMock, deckMock, recMock, abuseMock, outboxMock, atomicMock)
+			got, err := d.RefillDecks(tt.args.ctx, tt.args.batchSize)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RefillDecks error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestSwipe(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -159,12 +376,27 @@ func TestSwipe(t *testing.T) {
 	profileMock := mock_profile.NewMockInterface(ctrl)
 	swipeMock := mock_swipe.NewMockInterface(ctrl)
 	matchMock := mock_match.NewMockInterface(ctrl)
+	boostMock := mock_boost.NewMockInterface(ctrl)
+	deckMock := mock_deck.NewMockInterface(ctrl)
+	recMock := mock_recommendation.NewMockInterface(ctrl)
+	abuseMock := mock_abuse.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
+	cfg := config.Discovery{PassCoolOff: 720 * time.Hour, PageSize: 10, DeckSize: 100, DeckTTL: time.Hour, RefillThreshold: 5, ActiveWindow: 24 * time.Hour}
 
 	type mockFields struct {
 		subsMock    *mock_subscription.MockInterface
 		profileMock *mock_profile.MockInterface
 		swipeMock   *mock_swipe.MockInterface
 		matchMock   *mock_match.MockInterface
+		boostMock   *mock_boost.MockInterface
+		deckMock    *mock_deck.MockInterface
+		recMock     *mock_recommendation.MockInterface
+		abuseMock   *mock_abuse.MockInterface
+		outboxMock  *mock_outbox.MockInterface
+		atomicMock  *mock_atomic.MockAtomicSessionProvider
+		sessionMock *mock_atomic.MockAtomicSession
 	}
 
 	mocks := mockFields{
@@ -172,6 +404,13 @@ func TestSwipe(t *testing.T) {
 		profileMock: profileMock,
 		swipeMock:   swipeMock,
 		matchMock:   matchMock,
+		boostMock:   boostMock,
+		deckMock:    deckMock,
+		recMock:     recMock,
+		abuseMock:   abuseMock,
+		outboxMock:  outboxMock,
+		atomicMock:  atomicMock,
+		sessionMock: sessionMock,
 	}
 
 	type args struct {
@@ -187,11 +426,13 @@ func TestSwipe(t *testing.T) {
 	}
 	resp := entity.SwipeResponse{}
 	paramMock := entity.SwipeParam{SwipedId: 2, Direction: entity.Like}
+	abuseCfg := config.Abuse{SwipeWindow: time.Minute, MaxSwipes: 30, FingerprintWindow: time.Hour, MaxSharedIP: 3}
 
 	tests := []struct {
 		name     string
 		mockFunc func(mock mockFields, arg args)
 		args     args
+		abuseCfg config.Abuse
 		want     entity.SwipeResponse
 		wantErr  bool
 	}{
@@ -204,7 +445,40 @@ func TestSwipe(t *testing.T) {
 			want:    resp,
 			wantErr: true,
 			mockFunc: func(mock mockFields, arg args) {
-				mock.subsMock.EXPECT().GetByPlan(arg.ctx, int64(1), entity.UnlimitedPlan).Return(entity.Subscription{}, assert.AnError)
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return(nil, assert.AnError)
+			},
+		},
+		{
+			name: "err like not found",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.SwipeParam{LikeId: 7, Direction: entity.Like},
+			},
+			want:    resp,
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.swipeMock.EXPECT().GetReceivedLike(arg.ctx, int64(7), int64(1)).Return(entity.Swipe{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name: "liking back a blurred like matches",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.SwipeParam{LikeId: 7, Direction: entity.Like},
+			},
+			want:    allGoods,
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.swipeMock.EXPECT().GetReceivedLike(arg.ctx, int64(7), int64(1)).Return(entity.Swipe{ID: 7, SwiperId: 2, SwipedId: 1, Direction: entity.Like}, nil)
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
+				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMin, nil)
+				mock.swipeMock.EXPECT().Create(arg.ctx, entity.Swipe{SwiperId: int64(1), SwipedId: int64(2), Direction: entity.Like}).Return(int64(1), nil)
+				mock.swipeMock.EXPECT().GetBySwipeId(arg.ctx, int64(2), int64(1)).Return(entity.Swipe{ID: 7, Direction: entity.Like}, nil)
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.matchMock.EXPECT().Create(gomock.Any(), entity.Match{UserId1: int64(1), UserId2: int64(2)}).Return(int64(1), nil)
+				mock.outboxMock.EXPECT().Emit(gomock.Any(), entity.EventMatchCreated, int64(1), entity.MatchCreatedPayload{MatchId: 1, UserId1: 1, UserId2: 2}).Return(int64(1), nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+				mock.boostMock.EXPECT().Track(arg.ctx, int64(2), entity.BoostStatLikes).Return(nil)
 			},
 		},
 		{
@@ -216,7 +490,7 @@ func TestSwipe(t *testing.T) {
 			want:    resp,
 			wantErr: true,
 			mockFunc: func(mock mockFields, arg args) {
-				mock.subsMock.EXPECT().GetByPlan(arg.ctx, int64(1), entity.UnlimitedPlan).Return(entity.Subscription{}, nil)
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
 				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return([]entity.Swipe{}, assert.AnError)
 			},
 		},
@@ -229,7 +503,7 @@ func TestSwipe(t *testing.T) {
 			want:    resp,
 			wantErr: true,
 			mockFunc: func(mock mockFields, arg args) {
-				mock.subsMock.EXPECT().GetByPlan(arg.ctx, int64(1), entity.UnlimitedPlan).Return(entity.Subscription{}, nil)
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
 				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMax, nil)
 			},
 		},
@@ -242,7 +516,7 @@ func TestSwipe(t *testing.T) {
 			want:    resp,
 			wantErr: true,
 			mockFunc: func(mock mockFields, arg args) {
-				mock.subsMock.EXPECT().GetByPlan(arg.ctx, int64(1), entity.UnlimitedPlan).Return(entity.Subscription{}, nil)
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
 				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMin, nil)
 				mock.swipeMock.EXPECT().Create(arg.ctx, entity.Swipe{SwiperId: int64(1), SwipedId: arg.param.SwipedId, Direction: arg.param.Direction}).Return(int64(9), assert.AnError)
 			},
@@ -256,7 +530,7 @@ func TestSwipe(t *testing.T) {
 			want:    resp,
 			wantErr: true,
 			mockFunc: func(mock mockFields, arg args) {
-				mock.subsMock.EXPECT().GetByPlan(arg.ctx, int64(1), entity.UnlimitedPlan).Return(entity.Subscription{}, nil)
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
 				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMin, nil)
 				mock.swipeMock.EXPECT().Create(arg.ctx, entity.Swipe{SwiperId: int64(1), SwipedId: arg.param.SwipedId, Direction: arg.param.Direction}).Return(int64(1), nil)
 				mock.swipeMock.EXPECT().GetBySwipeId(arg.ctx, arg.param.SwipedId, int64(1)).Return(entity.Swipe{}, assert.AnError)
@@ -271,11 +545,14 @@ func TestSwipe(t *testing.T) {
 			want:    resp,
 			wantErr: true,
 			mockFunc: func(mock mockFields, arg args) {
-				mock.subsMock.EXPECT().GetByPlan(arg.ctx, int64(1), entity.UnlimitedPlan).Return(entity.Subscription{}, nil)
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
 				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMin, nil)
 				mock.swipeMock.EXPECT().Create(arg.ctx, entity.Swipe{SwiperId: int64(1), SwipedId: arg.param.SwipedId, Direction: arg.param.Direction}).Return(int64(1), nil)
 				mock.swipeMock.EXPECT().GetBySwipeId(arg.ctx, arg.param.SwipedId, int64(1)).Return(entity.Swipe{ID: 2, Direction: entity.Like}, nil)
-				mock.matchMock.EXPECT().Create(arg.ctx, entity.Match{UserId1: int64(1), UserId2: int64(2)}).Return(int64(0), assert.AnError)
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.matchMock.EXPECT().Create(gomock.Any(), entity.Match{UserId1: int64(1), UserId2: int64(2)}).Return(int64(0), assert.AnError)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				mock.sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
 			},
 		},
 		{
@@ -287,11 +564,125 @@ func TestSwipe(t *testing.T) {
 			want:    allGoods,
 			wantErr: false,
 			mockFunc: func(mock mockFields, arg args) {
-				mock.subsMock.EXPECT().GetByPlan(arg.ctx, int64(1), entity.UnlimitedPlan).Return(entity.Subscription{}, nil)
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
+				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMin, nil)
+				mock.swipeMock.EXPECT().Create(arg.ctx, entity.Swipe{SwiperId: int64(1), SwipedId: arg.param.SwipedId, Direction: arg.param.Direction}).Return(int64(1), nil)
+				mock.swipeMock.EXPECT().GetBySwipeId(arg.ctx, arg.param.SwipedId, int64(1)).Return(entity.Swipe{ID: 2, Direction: entity.Like}, nil)
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.matchMock.EXPECT().Create(gomock.Any(), entity.Match{UserId1: int64(1), UserId2: int64(2)}).Return(int64(1), nil)
+				mock.outboxMock.EXPECT().Emit(gomock.Any(), entity.EventMatchCreated, int64(1), entity.MatchCreatedPayload{MatchId: 1, UserId1: 1, UserId2: 2}).Return(int64(1), nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+				mock.boostMock.EXPECT().Track(arg.ctx, int64(2), entity.BoostStatLikes).Return(nil)
+			},
+		},
+		{
+			name: "liking again while matched makes no second match",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: paramMock,
+			},
+			want:    entity.SwipeResponse{Like: true},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
+				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMin, nil)
+				mock.swipeMock.EXPECT().Create(arg.ctx, entity.Swipe{SwiperId: int64(1), SwipedId: arg.param.SwipedId, Direction: arg.param.Direction}).Return(int64(1), nil)
+				mock.swipeMock.EXPECT().GetBySwipeId(arg.ctx, arg.param.SwipedId, int64(1)).Return(entity.Swipe{ID: 2, Direction: entity.Like}, nil)
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.matchMock.EXPECT().Create(gomock.Any(), entity.Match{UserId1: int64(1), UserId2: int64(2)}).Return(int64(0), nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+				mock.boostMock.EXPECT().Track(arg.ctx, int64(2), entity.BoostStatLikes).Return(nil)
+			},
+		},
+		{
+			name: "liking again after an unmatch brings no match back",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: paramMock,
+			},
+			want:    entity.SwipeResponse{Like: true},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
+				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMin, nil)
+				mock.swipeMock.EXPECT().Create(arg.ctx, entity.Swipe{SwiperId: int64(1), SwipedId: arg.param.SwipedId, Direction: arg.param.Direction}).Return(int64(1), nil)
+				mock.swipeMock.EXPECT().GetBySwipeId(arg.ctx, arg.param.SwipedId, int64(1)).Return(entity.Swipe{ID: 2, Direction: entity.Like}, nil)
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.matchMock.EXPECT().Create(gomock.Any(), entity.Match{UserId1: int64(1), UserId2: int64(2)}).Return(int64(0), nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+				mock.boostMock.EXPECT().Track(arg.ctx, int64(2), entity.BoostStatLikes).Return(nil)
+			},
+		},
+		{
+			name: "like without match notifies",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: paramMock,
+			},
+			want:    entity.SwipeResponse{Like: true},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
+				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMin, nil)
+				mock.swipeMock.EXPECT().Create(arg.ctx, entity.Swipe{SwiperId: int64(1), SwipedId: arg.param.SwipedId, Direction: arg.param.Direction}).Return(int64(1), nil)
+				mock.swipeMock.EXPECT().GetBySwipeId(arg.ctx, arg.param.SwipedId, int64(1)).Return(entity.Swipe{}, nil)
+				mock.boostMock.EXPECT().Track(arg.ctx, int64(2), entity.BoostStatLikes).Return(nil)
+				mock.outboxMock.EXPECT().Emit(arg.ctx, entity.EventLikeReceived, int64(2), entity.LikeReceivedPayload{UserId: 2}).Return(int64(1), nil)
+			},
+		},
+		{
+			name: "shadow-limited like never matches",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: paramMock,
+			},
+			abuseCfg: abuseCfg,
+			want:     entity.SwipeResponse{Like: true},
+			wantErr:  false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
 				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMin, nil)
 				mock.swipeMock.EXPECT().Create(arg.ctx, entity.Swipe{SwiperId: int64(1), SwipedId: arg.param.SwipedId, Direction: arg.param.Direction}).Return(int64(1), nil)
+				mock.outboxMock.EXPECT().Emit(arg.ctx, entity.EventSwipeRecorded, int64(1), entity.SwipeRecordedPayload{UserId: 1}).Return(int64(1), nil)
+				mock.abuseMock.EXPECT().IsShadowLimited(arg.ctx, int64(1)).Return(true, nil)
 				mock.swipeMock.EXPECT().GetBySwipeId(arg.ctx, arg.param.SwipedId, int64(1)).Return(entity.Swipe{ID: 2, Direction: entity.Like}, nil)
-				mock.matchMock.EXPECT().Create(arg.ctx, entity.Match{UserId1: int64(1), UserId2: int64(2)}).Return(int64(1), nil)
+			},
+		},
+		{
+			name: "err get shadow limit does not fail the swipe",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.SwipeParam{SwipedId: 2, Direction: entity.Pass},
+			},
+			abuseCfg: abuseCfg,
+			want:     entity.SwipeResponse{},
+			wantErr:  false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
+				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMin, nil)
+				mock.swipeMock.EXPECT().Create(arg.ctx, entity.Swipe{SwiperId: int64(1), SwipedId: arg.param.SwipedId, Direction: arg.param.Direction}).Return(int64(1), nil)
+				mock.outboxMock.EXPECT().Emit(arg.ctx, entity.EventSwipeRecorded, int64(1), entity.SwipeRecordedPayload{UserId: 1}).Return(int64(1), nil)
+				mock.abuseMock.EXPECT().IsShadowLimited(arg.ctx, int64(1)).Return(false, assert.AnError)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				mock.swipeMock.EXPECT().GetBySwipeId(arg.ctx, arg.param.SwipedId, int64(1)).Return(entity.Swipe{}, nil)
+			},
+		},
+		{
+			name: "swipe is handed to the bot detector with its fingerprint",
+			args: args{
+				ctx:   appcontext.SetRequestIP(appcontext.SetUserId(context.Background(), 1), "10.0.0.1"),
+				param: entity.SwipeParam{SwipedId: 2, Direction: entity.Pass},
+			},
+			abuseCfg: abuseCfg,
+			want:     entity.SwipeResponse{},
+			wantErr:  false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
+				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipesMin, nil)
+				mock.swipeMock.EXPECT().Create(arg.ctx, entity.Swipe{SwiperId: int64(1), SwipedId: arg.param.SwipedId, Direction: arg.param.Direction}).Return(int64(1), nil)
+				mock.outboxMock.EXPECT().Emit(arg.ctx, entity.EventSwipeRecorded, int64(1), entity.SwipeRecordedPayload{UserId: 1, IP: "10.0.0.1"}).Return(int64(1), nil)
+				mock.abuseMock.EXPECT().IsShadowLimited(arg.ctx, int64(1)).Return(false, nil)
+				mock.swipeMock.EXPECT().GetBySwipeId(arg.ctx, arg.param.SwipedId, int64(1)).Return(entity.Swipe{}, nil)
 			},
 		},
 	}
@@ -300,7 +691,7 @@ func TestSwipe(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockFunc(mocks, tt.args)
 
-			d := Init(log, subsMock, profileMock, swipeMock, matchMock)
+			d := Init(log, cfg, tt.abuseCfg, subsMock, profileMock, swipeMock, matchMock, boostMock, deckMock, recMock, abuseMock, outboxMock, atomicMock)
 			got, err := d.Swipe(tt.args.ctx, tt.args.param)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Swipe error = %v, wantErr %v", err, tt.wantErr)
@@ -311,3 +702,530 @@ func TestSwipe(t *testing.T) {
 		})
 	}
 }
+
+func TestInspect(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	subsMock := mock_subscription.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	swipeMock := mock_swipe.NewMockInterface(ctrl)
+	matchMock := mock_match.NewMockInterface(ctrl)
+	boostMock := mock_boost.NewMockInterface(ctrl)
+	deckMock := mock_deck.NewMockInterface(ctrl)
+	recMock := mock_recommendation.NewMockInterface(ctrl)
+	abuseMock := mock_abuse.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
+	abuseCfg := config.Abuse{SwipeWindow: time.Minute, MaxSwipes: 30, FingerprintWindow: time.Hour, MaxSharedIP: 3}
+
+	type mockFields struct {
+		swipeMock   *mock_swipe.MockInterface
+		abuseMock   *mock_abuse.MockInterface
+		atomicMock  *mock_atomic.MockAtomicSessionProvider
+		sessionMock *mock_atomic.MockAtomicSession
+	}
+
+	mocks := mockFields{
+		swipeMock:   swipeMock,
+		abuseMock:   abuseMock,
+		atomicMock:  atomicMock,
+		sessionMock: sessionMock,
+	}
+
+	type args struct {
+		ctx   context.Context
+		param entity.SwipeRecordedPayload
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(mock mockFields, arg args)
+		args     args
+		wantErr  bool
+	}{
+		{
+			name: "err track fingerprint",
+			args: args{
+				ctx:   context.Background(),
+				param: entity.SwipeRecordedPayload{UserId: 1, IP: "10.0.0.1"},
+			},
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.abuseMock.EXPECT().Track(arg.ctx, entity.Fingerprint{UserId: 1, IP: "10.0.0.1"}).Return(assert.AnError)
+			},
+		},
+		{
+			name: "err get abuse signals",
+			args: args{
+				ctx:   context.Background(),
+				param: entity.SwipeRecordedPayload{UserId: 1},
+			},
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.abuseMock.EXPECT().GetSignals(arg.ctx, gomock.Any()).Return(entity.AbuseSignals{}, assert.AnError)
+			},
+		},
+		{
+			name: "nothing to flag",
+			args: args{
+				ctx:   context.Background(),
+				param: entity.SwipeRecordedPayload{UserId: 1},
+			},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.abuseMock.EXPECT().GetSignals(arg.ctx, gomock.Any()).Return(entity.AbuseSignals{RecentSwipes: 2}, nil)
+			},
+		},
+		{
+			name: "err flag",
+			args: args{
+				ctx:   context.Background(),
+				param: entity.SwipeRecordedPayload{UserId: 1},
+			},
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.abuseMock.EXPECT().GetSignals(arg.ctx, gomock.Any()).Return(entity.AbuseSignals{RecentSwipes: 31}, nil)
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.abuseMock.EXPECT().Flag(gomock.Any(), gomock.Any()).Return(int64(0), assert.AnError)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				mock.sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "swipe rate and shared ip raise flags",
+			args: args{
+				ctx:   context.Background(),
+				param: entity.SwipeRecordedPayload{UserId: 1, IP: "10.0.0.1"},
+			},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.abuseMock.EXPECT().Track(arg.ctx, entity.Fingerprint{UserId: 1, IP: "10.0.0.1"}).Return(nil)
+				mock.abuseMock.EXPECT().GetSignals(arg.ctx, gomock.Any()).Return(entity.AbuseSignals{RecentSwipes: 31, SharedIP: 4}, nil)
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.abuseMock.EXPECT().Flag(gomock.Any(), gomock.Cond(func(x any) bool {
+					f := x.(entity.AbuseFlag)
+					return f.Signal == entity.AbuseSwipeRate && f.Value == 31
+				})).Return(int64(0), nil)
+				mock.abuseMock.EXPECT().Flag(gomock.Any(), gomock.Cond(func(x any) bool {
+					f := x.(entity.AbuseFlag)
+					return f.Signal == entity.AbuseSharedIP && f.Value == 4
+				})).Return(int64(7), nil)
+				mock.abuseMock.EXPECT().SyncShadowLimit(gomock.Any(), int64(1)).Return(nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+				mock.swipeMock.EXPECT().InvalidateUser(arg.ctx, int64(1)).Return(nil)
+			},
+		},
+		{
+			name: "flags already raised leave the limit alone",
+			args: args{
+				ctx:   context.Background(),
+				param: entity.SwipeRecordedPayload{UserId: 1},
+			},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.abuseMock.EXPECT().GetSignals(arg.ctx, gomock.Any()).Return(entity.AbuseSignals{RecentSwipes: 31}, nil)
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.abuseMock.EXPECT().Flag(gomock.Any(), gomock.Any()).Return(int64(0), nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks, tt.args)
+
+			d := Init(log, config.Discovery{}, abuseCfg, subsMock, profileMock, swipeMock, matchMock, boostMock, deckMock, recMock, abuseMock, outboxMock, atomicMock)
+			err := d.Inspect(tt.args.ctx, tt.args.param)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Inspect error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSwipeBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	subsMock := mock_subscription.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	swipeMock := mock_swipe.NewMockInterface(ctrl)
+	matchMock := mock_match.NewMockInterface(ctrl)
+	boostMock := mock_boost.NewMockInterface(ctrl)
+	deckMock := mock_deck.NewMockInterface(ctrl)
+	recMock := mock_recommendation.NewMockInterface(ctrl)
+	abuseMock := mock_abuse.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
+	cfg := config.Discovery{PassCoolOff: 720 * time.Hour, PageSize: 10, DeckSize: 100, DeckTTL: time.Hour, RefillThreshold: 5, ActiveWindow: 24 * time.Hour}
+
+	type mockFields struct {
+		subsMock    *mock_subscription.MockInterface
+		profileMock *mock_profile.MockInterface
+		swipeMock   *mock_swipe.MockInterface
+		matchMock   *mock_match.MockInterface
+		boostMock   *mock_boost.MockInterface
+		deckMock    *mock_deck.MockInterface
+		recMock     *mock_recommendation.MockInterface
+		outboxMock  *mock_outbox.MockInterface
+		atomicMock  *mock_atomic.MockAtomicSessionProvider
+		sessionMock *mock_atomic.MockAtomicSession
+	}
+
+	mocks := mockFields{
+		subsMock:    subsMock,
+		profileMock: profileMock,
+		swipeMock:   swipeMock,
+		matchMock:   matchMock,
+		boostMock:   boostMock,
+		deckMock:    deckMock,
+		recMock:     recMock,
+		outboxMock:  outboxMock,
+		atomicMock:  atomicMock,
+		sessionMock: sessionMock,
+	}
+
+	type args struct {
+		ctx   context.Context
+		param entity.SwipeBatchParam
+	}
+
+	now := time.Now()
+	swipes9 := []entity.Swipe{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}, {ID: 6}, {ID: 7}, {ID: 8}, {ID: 9}}
+	paramMock := entity.SwipeBatchParam{Swipes: []entity.SwipeBatchItem{
+		{SwipedId: 4, Direction: entity.Like, SwipedAt: now.Add(time.Minute)},
+		{SwipedId: 2, Direction: entity.Like, SwipedAt: now},
+		{SwipedId: 2, Direction: entity.Like, SwipedAt: now.Add(2 * time.Minute)},
+		{SwipedId: 3, Direction: entity.Pass, SwipedAt: now.Add(3 * time.Minute)},
+	}}
+
+	tests := []struct {
+		name     string
+		mockFunc func(mock mockFields, arg args)
+		args     args
+		want     []entity.SwipeBatchResult
+		wantErr  bool
+	}{
+		{
+			name: "err get subscription",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: paramMock,
+			},
+			want:    nil,
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return(nil, assert.AnError)
+			},
+		},
+		{
+			name: "err bulk insert swipes",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: paramMock,
+			},
+			want:    nil,
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
+				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(nil, nil)
+				mock.swipeMock.EXPECT().GetOutgoing(arg.ctx, int64(1), []int64{4, 2, 2, 3}).Return(nil, nil)
+				mock.swipeMock.EXPECT().BulkCreate(arg.ctx, gomock.Any()).Return(nil, assert.AnError)
+			},
+		},
+		{
+			name: "duplicates and quota are resolved per item",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: paramMock,
+			},
+			want: []entity.SwipeBatchResult{
+				{Index: 0, SwipedId: 4, Like: true, Status: entity.SwipeDuplicate},
+				{Index: 1, SwipedId: 2, Like: true, Status: entity.SwipeApplied, Match: true},
+				{Index: 2, SwipedId: 2, Like: true, Status: entity.SwipeDuplicate},
+				{Index: 3, SwipedId: 3, Status: entity.SwipeQuotaExceeded, Err: appErr.ErrSwipeQuotaExceeded},
+			},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
+				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipes9, nil)
+				mock.swipeMock.EXPECT().GetOutgoing(arg.ctx, int64(1), []int64{4, 2, 2, 3}).Return([]entity.Swipe{{SwipedId: 4, Direction: entity.Like}}, nil)
+				mock.swipeMock.EXPECT().BulkCreate(arg.ctx, []entity.Swipe{{SwiperId: 1, SwipedId: 2, Direction: entity.Like}}).Return([]int64{10}, nil)
+				mock.swipeMock.EXPECT().GetIncoming(arg.ctx, int64(1), []int64{2}).Return([]entity.Swipe{{SwiperId: 2, SwipedId: 1, Direction: entity.Like}}, nil)
+				mock.boostMock.EXPECT().Track(arg.ctx, int64(2), entity.BoostStatLikes).Return(nil)
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.matchMock.EXPECT().Create(gomock.Any(), entity.Match{UserId1: 1, UserId2: 2}).Return(int64(1), nil)
+				mock.outboxMock.EXPECT().Emit(gomock.Any(), entity.EventMatchCreated, int64(1), entity.MatchCreatedPayload{MatchId: 1, UserId1: 1, UserId2: 2}).Return(int64(1), nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "liking again a pair matched before makes no match",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.SwipeBatchParam{Swipes: []entity.SwipeBatchItem{{SwipedId: 2, Direction: entity.Like, SwipedAt: now}}},
+			},
+			want: []entity.SwipeBatchResult{
+				{Index: 0, SwipedId: 2, Like: true, Status: entity.SwipeApplied},
+			},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
+				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipes9, nil)
+				mock.swipeMock.EXPECT().GetOutgoing(arg.ctx, int64(1), []int64{2}).Return(nil, nil)
+				mock.swipeMock.EXPECT().BulkCreate(arg.ctx, []entity.Swipe{{SwiperId: 1, SwipedId: 2, Direction: entity.Like}}).Return([]int64{10}, nil)
+				mock.swipeMock.EXPECT().GetIncoming(arg.ctx, int64(1), []int64{2}).Return([]entity.Swipe{{SwiperId: 2, SwipedId: 1, Direction: entity.Like}}, nil)
+				mock.boostMock.EXPECT().Track(arg.ctx, int64(2), entity.BoostStatLikes).Return(nil)
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.matchMock.EXPECT().Create(gomock.Any(), entity.Match{UserId1: 1, UserId2: 2}).Return(int64(0), nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "the latest swipe on a target wins without using more quota",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+				param: entity.SwipeBatchParam{Swipes: []entity.SwipeBatchItem{
+					{SwipedId: 2, Direction: entity.Pass, SwipedAt: now},
+					{SwipedId: 2, Direction: entity.Like, SwipedAt: now.Add(time.Minute)},
+					{SwipedId: 3, Direction: entity.Like, SwipedAt: now.Add(2 * time.Minute)},
+				}},
+			},
+			want: []entity.SwipeBatchResult{
+				{Index: 0, SwipedId: 2, Status: entity.SwipeSuperseded},
+				{Index: 1, SwipedId: 2, Like: true, Status: entity.SwipeApplied},
+				{Index: 2, SwipedId: 3, Like: true, Status: entity.SwipeQuotaExceeded, Err: appErr.ErrSwipeQuotaExceeded},
+			},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
+				mock.swipeMock.EXPECT().GetBySwiperId(arg.ctx, int64(1)).Return(swipes9, nil)
+				mock.swipeMock.EXPECT().GetOutgoing(arg.ctx, int64(1), []int64{2, 2, 3}).Return(nil, nil)
+				mock.swipeMock.EXPECT().BulkCreate(arg.ctx, []entity.Swipe{{SwiperId: 1, SwipedId: 2, Direction: entity.Like}}).Return([]int64{10}, nil)
+				mock.swipeMock.EXPECT().GetIncoming(arg.ctx, int64(1), []int64{2}).Return(nil, nil)
+				mock.boostMock.EXPECT().Track(arg.ctx, int64(2), entity.BoostStatLikes).Return(nil)
+				mock.outboxMock.EXPECT().Emit(arg.ctx, entity.EventLikeReceived, int64(2), entity.LikeReceivedPayload{UserId: 2}).Return(int64(1), nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks, tt.args)
+
+			d := Init(log, cfg, config.Abuse{}, subsMock, profileMock, swipeMock, matchMock, boostMock, deckMock, recMock, abuseMock, outboxMock, atomicMock)
+			got, err := d.SwipeBatch(tt.args.ctx, tt.args.param)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SwipeBatch error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReceivedLikes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	subsMock := mock_subscription.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	swipeMock := mock_swipe.NewMockInterface(ctrl)
+	matchMock := mock_match.NewMockInterface(ctrl)
+	boostMock := mock_boost.NewMockInterface(ctrl)
+	deckMock := mock_deck.NewMockInterface(ctrl)
+	recMock := mock_recommendation.NewMockInterface(ctrl)
+	abuseMock := mock_abuse.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	cfg := config.Discovery{PassCoolOff: 720 * time.Hour, PageSize: 10, DeckSize: 100, DeckTTL: time.Hour, RefillThreshold: 5, ActiveWindow: 24 * time.Hour}
+
+	type mockFields struct {
+		subsMock    *mock_subscription.MockInterface
+		profileMock *mock_profile.MockInterface
+		swipeMock   *mock_swipe.MockInterface
+	}
+
+	mocks := mockFields{
+		subsMock:    subsMock,
+		profileMock: profileMock,
+		swipeMock:   swipeMock,
+	}
+
+	type args struct {
+		ctx context.Context
+	}
+
+	likes := []entity.Swipe{{ID: 7, SwiperId: 2, SwipedId: 1, Direction: entity.Like}}
+	profiles := []entity.Profile{{UserId: 2, FullName: "test", Gender: entity.Female}}
+
+	tests := []struct {
+		name     string
+		mockFunc func(mock mockFields, arg args)
+		args     args
+		want     entity.ReceivedLikesResponse
+		wantErr  bool
+	}{
+		{
+			name: "err get received likes",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    entity.ReceivedLikesResponse{},
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.swipeMock.EXPECT().GetReceivedLikes(arg.ctx, int64(1), cfg.PassCoolOff).Return(nil, assert.AnError)
+			},
+		},
+		{
+			name: "free user gets blurred likes",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    entity.ReceivedLikesResponse{Total: 1, Likes: []entity.ReceivedLike{{LikeId: 7, Gender: entity.Female, Age: 292, Blurred: true}}},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.swipeMock.EXPECT().GetReceivedLikes(arg.ctx, int64(1), cfg.PassCoolOff).Return(likes, nil)
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{}, nil)
+				mock.profileMock.EXPECT().GetByUserIds(arg.ctx, int64(1), []string{"2"}).Return(profiles, nil)
+			},
+		},
+		{
+			name: "plan with see likes gets full profiles",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    entity.ReceivedLikesResponse{Total: 1, Likes: []entity.ReceivedLike{{LikeId: 7, UserId: 2, FullName: "test", Gender: entity.Female, Age: 292}}},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.swipeMock.EXPECT().GetReceivedLikes(arg.ctx, int64(1), cfg.PassCoolOff).Return(likes, nil)
+				mock.subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{entity.FeatureSeeLikes}, nil)
+				mock.profileMock.EXPECT().GetByUserIds(arg.ctx, int64(1), []string{"2"}).Return(profiles, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks, tt.args)
+
+			d := Init(log, cfg, config.Abuse{}, subsMock, profileMock, swipeMock, matchMock, boostMock, deckMock, recMock, abuseMock, outboxMock, atomicMock)
+			got, err := d.ReceivedLikes(tt.args.ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReceivedLikes error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRewind(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	subsMock := mock_subscription.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	swipeMock := mock_swipe.NewMockInterface(ctrl)
+	matchMock := mock_match.NewMockInterface(ctrl)
+	boostMock := mock_boost.NewMockInterface(ctrl)
+	deckMock := mock_deck.NewMockInterface(ctrl)
+	recMock := mock_recommendation.NewMockInterface(ctrl)
+	abuseMock := mock_abuse.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	cfg := config.Discovery{PassCoolOff: 720 * time.Hour, PageSize: 10, DeckSize: 100, DeckTTL: time.Hour, RefillThreshold: 5, ActiveWindow: 24 * time.Hour}
+
+	type mockFields struct {
+		subsMock    *mock_subscription.MockInterface
+		profileMock *mock_profile.MockInterface
+		swipeMock   *mock_swipe.MockInterface
+	}
+
+	mocks := mockFields{
+		subsMock:    subsMock,
+		profileMock: profileMock,
+		swipeMock:   swipeMock,
+	}
+
+	type args struct {
+		ctx context.Context
+	}
+
+	pass := entity.Swipe{ID: 7, SwiperId: 1, SwipedId: 2, Direction: entity.Pass}
+
+	tests := []struct {
+		name     string
+		mockFunc func(mock mockFields, arg args)
+		args     args
+		want     entity.Discovery
+		wantErr  error
+	}{
+		{
+			name: "err invalid user",
+			args: args{
+				ctx: context.Background(),
+			},
+			want:     entity.Discovery{},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(mock mockFields, arg args) {},
+		},
+		{
+			name: "last swipe isn't a pass",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    entity.Discovery{},
+			wantErr: appErr.ErrNothingToRewind,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.swipeMock.EXPECT().Rewind(arg.ctx, int64(1)).Return(entity.Swipe{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name: "err get profile",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    entity.Discovery{},
+			wantErr: assert.AnError,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.swipeMock.EXPECT().Rewind(arg.ctx, int64(1)).Return(pass, nil)
+				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(2)).Return(entity.Profile{}, assert.AnError)
+			},
+		},
+		{
+			name: "all goods",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    entity.Discovery{ID: 5, FullName: "test", Gender: entity.Female, Age: 292, Verified: true},
+			wantErr: nil,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.swipeMock.EXPECT().Rewind(arg.ctx, int64(1)).Return(pass, nil)
+				mock.profileMock.EXPECT().GetByUserId(arg.ctx, int64(2)).Return(entity.Profile{ID: 5, UserId: 2, FullName: "test", Gender: entity.Female}, nil)
+				mock.subsMock.EXPECT().GetEntitled(arg.ctx, entity.FeatureVerifiedBadge, []int64{2}).Return([]int64{2}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks, tt.args)
+
+			d := Init(log, cfg, config.Abuse{}, subsMock, profileMock, swipeMock, matchMock, boostMock, deckMock, recMock, abuseMock, outboxMock, atomicMock)
+			got, err := d.Rewind(tt.args.ctx)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
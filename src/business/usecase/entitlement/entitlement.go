@@ -0,0 +1,88 @@
+package entitlement
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"loverly/lib/appcontext"
+	"loverly/lib/log"
+	"loverly/src/business/domain/subscription"
+	"loverly/src/business/entity"
+	"slices"
+
+	appErr "loverly/src/errors"
+)
+
+// Interface resolves the features a user gets from the plan of their subscription, features are gated by
+// these instead of by plan codes so the catalog can change without touching the checks
+type Interface interface {
+	Get(ctx context.Context) (entity.EntitlementResponse, error)
+	// Has tells whether the user of the request has every one of the features
+	Has(ctx context.Context, features ...string) (bool, error)
+}
+
+type entitlements struct {
+	log          log.Interface
+	subscription subscription.Interface
+}
+
+func Init(log log.Interface, s subscription.Interface) Interface {
+	return &entitlements{
+		log:          log,
+		subscription: s,
+	}
+}
+
+// Get returns the current subscription with its features, a user without one gets no features
+func (e *entitlements) Get(ctx context.Context) (entity.EntitlementResponse, error) {
+	result := entity.EntitlementResponse{Features: []string{}}
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	sub, err := e.subscription.GetByUserId(ctx, int64(userId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, nil
+		}
+		return result, err
+	}
+
+	if sub.Status == entity.SubscriptionExpired {
+		return result, nil
+	}
+
+	features, err := e.subscription.GetFeatures(ctx, int64(userId))
+	if err != nil {
+		return result, err
+	}
+
+	result.Plan = sub.Plan
+	result.Status = sub.Status
+	result.EndDate = &sub.EndDate
+	result.Features = features
+
+	return result, nil
+}
+
+func (e *entitlements) Has(ctx context.Context, features ...string) (bool, error) {
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return false, appErr.ErrInvalidUserId
+	}
+
+	entitled, err := e.subscription.GetFeatures(ctx, int64(userId))
+	if err != nil {
+		return false, err
+	}
+
+	for _, f := range features {
+		if !slices.Contains(entitled, f) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
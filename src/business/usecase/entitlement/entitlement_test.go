@@ -0,0 +1,195 @@
+package entitlement
+
+import (
+	"context"
+	"database/sql"
+	"loverly/lib/appcontext"
+	mock_log "loverly/lib/log/mock"
+	mock_subscription "loverly/src/business/domain/mock/subscription"
+	"loverly/src/business/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	appErr "loverly/src/errors"
+)
+
+func TestGet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	subsMock := mock_subscription.NewMockInterface(ctrl)
+
+	endDate := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	sub := entity.Subscription{ID: 1, UserId: 1, Plan: entity.VerifiedPlan, Status: entity.SubscriptionCancelled, EndDate: endDate}
+
+	type args struct {
+		ctx context.Context
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		want     entity.EntitlementResponse
+		wantErr  error
+	}{
+		{
+			name: "err invalid user id",
+			args: args{
+				ctx: context.Background(),
+			},
+			want:     entity.EntitlementResponse{Features: []string{}},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "err get subscription",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    entity.EntitlementResponse{Features: []string{}},
+			wantErr: assert.AnError,
+			mockFunc: func(arg args) {
+				subsMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Subscription{}, assert.AnError)
+			},
+		},
+		{
+			name: "no subscription has no features",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want: entity.EntitlementResponse{Features: []string{}},
+			mockFunc: func(arg args) {
+				subsMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Subscription{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name: "expired subscription has no features",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want: entity.EntitlementResponse{Features: []string{}},
+			mockFunc: func(arg args) {
+				subsMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Subscription{ID: 1, Status: entity.SubscriptionExpired}, nil)
+			},
+		},
+		{
+			name: "err get features",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want:    entity.EntitlementResponse{Features: []string{}},
+			wantErr: assert.AnError,
+			mockFunc: func(arg args) {
+				subsMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(sub, nil)
+				subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return(nil, assert.AnError)
+			},
+		},
+		{
+			name: "cancelled subscription keeps its features until the end",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want: entity.EntitlementResponse{
+				Plan:     entity.VerifiedPlan,
+				Status:   entity.SubscriptionCancelled,
+				EndDate:  &endDate,
+				Features: []string{entity.FeatureVerifiedBadge, entity.FeatureSeeLikes},
+			},
+			mockFunc: func(arg args) {
+				subsMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(sub, nil)
+				subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{entity.FeatureVerifiedBadge, entity.FeatureSeeLikes}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			e := Init(log, subsMock)
+			got, err := e.Get(tt.args.ctx)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestHas(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	subsMock := mock_subscription.NewMockInterface(ctrl)
+
+	type args struct {
+		ctx      context.Context
+		features []string
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		want     bool
+		wantErr  error
+	}{
+		{
+			name: "err invalid user id",
+			args: args{
+				ctx:      context.Background(),
+				features: []string{entity.FeatureRewind},
+			},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "err get features",
+			args: args{
+				ctx:      appcontext.SetUserId(context.Background(), 1),
+				features: []string{entity.FeatureRewind},
+			},
+			wantErr: assert.AnError,
+			mockFunc: func(arg args) {
+				subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return(nil, assert.AnError)
+			},
+		},
+		{
+			name: "missing one of the features",
+			args: args{
+				ctx:      appcontext.SetUserId(context.Background(), 1),
+				features: []string{entity.FeatureSeeLikes, entity.FeatureRewind},
+			},
+			want: false,
+			mockFunc: func(arg args) {
+				subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{entity.FeatureVerifiedBadge, entity.FeatureSeeLikes}, nil)
+			},
+		},
+		{
+			name: "has every feature",
+			args: args{
+				ctx:      appcontext.SetUserId(context.Background(), 1),
+				features: []string{entity.FeatureSeeLikes, entity.FeatureRewind},
+			},
+			want: true,
+			mockFunc: func(arg args) {
+				subsMock.EXPECT().GetFeatures(arg.ctx, int64(1)).Return([]string{entity.FeatureUnlimitedSwipes, entity.FeatureSeeLikes, entity.FeatureRewind}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			e := Init(log, subsMock)
+			got, err := e.Has(tt.args.ctx, tt.args.features...)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"loverly/lib/atomic"
+	"loverly/lib/broker"
+	"loverly/lib/log"
+	"loverly/src/business/domain/outbox"
+	"loverly/src/business/entity"
+	"loverly/src/config"
+	"strconv"
+	"sync"
+)
+
+// Handler reacts to a domain event, delivery is at-least-once so it has to be idempotent
+type Handler func(ctx context.Context, event entity.Event) error
+
+type Interface interface {
+	Subscribe(eventType string, handler Handler)
+	Relay(ctx context.Context) (int, error)
+}
+
+type events struct {
+	log    log.Interface
+	cfg    config.Event
+	outbox outbox.Interface
+	broker broker.Interface
+	atomic atomic.AtomicSessionProvider
+
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func Init(log log.Interface, cfg config.Event, o outbox.Interface, b broker.Interface, atomic atomic.AtomicSessionProvider) Interface {
+	return &events{
+		log:      log,
+		cfg:      cfg,
+		outbox:   o,
+		broker:   b,
+		atomic:   atomic,
+		handlers: make(map[string][]Handler),
+	}
+}
+
+func (e *events) Subscribe(eventType string, handler Handler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.handlers[eventType] = append(e.handlers[eventType], handler)
+}
+
+// Relay delivers a batch of pending outbox events and returns how many got published,
+// a failed event is retried on the next run until it runs out of attempts
+func (e *events) Relay(ctx context.Context) (int, error) {
+	var published int
+
+	err := atomic.Atomic(ctx, e.atomic, e.log, func(txCtx context.Context) error {
+		pending, err := e.outbox.GetPending(txCtx, e.cfg.RelayBatchSize, e.cfg.MaxAttempts)
+		if err != nil {
+			return err
+		}
+
+		var ids []int64
+		for _, ev := range pending {
+			// handlers run outside the transaction, the rows stay locked until they are marked
+			if err := e.deliver(ctx, ev); err != nil {
+				e.log.Error(ctx, fmt.Sprintf("deliver event %d err: %v", ev.ID, err))
+				if err := e.outbox.MarkFailed(txCtx, ev.ID, err.Error()); err != nil {
+					return err
+				}
+				continue
+			}
+			ids = append(ids, ev.ID)
+		}
+
+		if err := e.outbox.MarkPublished(txCtx, ids); err != nil {
+			return err
+		}
+		published = len(ids)
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return published, nil
+}
+
+func (e *events) deliver(ctx context.Context, ev entity.Event) error {
+	e.mu.RLock()
+	handlers := e.handlers[ev.Type]
+	e.mu.RUnlock()
+
+	for _, h := range handlers {
+		if err := h(ctx, ev); err != nil {
+			return err
+		}
+	}
+
+	return e.broker.Publish(ctx, broker.Message{
+		ID:         ev.ID,
+		Topic:      ev.Type,
+		Key:        strconv.FormatInt(ev.UserId, 10),
+		Payload:    ev.Payload,
+		OccurredAt: ev.CreatedAt.Time,
+	})
+}
@@ -0,0 +1,116 @@
+package event
+
+import (
+	"context"
+	"loverly/lib/atomic"
+	mock_atomic "loverly/lib/atomic/mock"
+	"loverly/lib/broker"
+	mock_broker "loverly/lib/broker/mock"
+	mock_log "loverly/lib/log/mock"
+	mock_outbox "loverly/src/business/domain/mock/outbox"
+	"loverly/src/business/entity"
+	"loverly/src/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRelay(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	brokerMock := mock_broker.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
+	cfg := config.Event{RelayBatchSize: 10, MaxAttempts: 3}
+
+	type mockFields struct {
+		outboxMock  *mock_outbox.MockInterface
+		brokerMock  *mock_broker.MockInterface
+		atomicMock  *mock_atomic.MockAtomicSessionProvider
+		sessionMock *mock_atomic.MockAtomicSession
+	}
+
+	mocks := mockFields{
+		outboxMock:  outboxMock,
+		brokerMock:  brokerMock,
+		atomicMock:  atomicMock,
+		sessionMock: sessionMock,
+	}
+
+	type args struct {
+		ctx context.Context
+	}
+
+	pending := []entity.Event{
+		{ID: 1, Type: entity.EventUserRegistered, UserId: 1, Payload: []byte(`{"user_id":1}`)},
+		{ID: 2, Type: entity.EventUserRegistered, UserId: 2, Payload: []byte(`{"user_id":2}`)},
+		{ID: 3, Type: entity.EventMatchCreated, UserId: 3, Payload: []byte(`{"match_id":1}`)},
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(mock mockFields, arg args)
+		args     args
+		want     int
+		wantErr  bool
+	}{
+		{
+			name: "err get pending",
+			args: args{
+				ctx: context.Background(),
+			},
+			want:    0,
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.outboxMock.EXPECT().GetPending(gomock.Any(), 10, 3).Return(nil, assert.AnError)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				mock.sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "failed deliveries are marked and the rest published",
+			args: args{
+				ctx: context.Background(),
+			},
+			want:    2,
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.outboxMock.EXPECT().GetPending(gomock.Any(), 10, 3).Return(pending, nil)
+				mock.brokerMock.EXPECT().Publish(arg.ctx, broker.Message{ID: 1, Topic: entity.EventUserRegistered, Key: "1", Payload: pending[0].Payload}).Return(nil)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				mock.outboxMock.EXPECT().MarkFailed(gomock.Any(), int64(2), assert.AnError.Error()).Return(nil)
+				mock.brokerMock.EXPECT().Publish(arg.ctx, broker.Message{ID: 3, Topic: entity.EventMatchCreated, Key: "3", Payload: pending[2].Payload}).Return(nil)
+				mock.outboxMock.EXPECT().MarkPublished(gomock.Any(), []int64{1, 3}).Return(nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks, tt.args)
+
+			e := Init(log, cfg, outboxMock, brokerMock, atomicMock)
+			e.Subscribe(entity.EventUserRegistered, func(ctx context.Context, ev entity.Event) error {
+				if ev.UserId == 2 {
+					return assert.AnError
+				}
+				return nil
+			})
+
+			got, err := e.Relay(tt.args.ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Relay error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
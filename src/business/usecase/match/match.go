@@ -2,77 +2,335 @@ package match
 
 import (
 	"context"
-	"fmt"
+	"database/sql"
+	"errors"
 	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
+	"loverly/lib/cursor"
 	"loverly/lib/log"
+	"loverly/lib/operator"
 	match "loverly/src/business/domain/matchs"
+	"loverly/src/business/domain/outbox"
 	"loverly/src/business/domain/profile"
 	"loverly/src/business/entity"
+	"loverly/src/config"
 	appErr "loverly/src/errors"
-	"slices"
+	"math"
+	"strconv"
 	"time"
 )
 
 type Interface interface {
-	GetList(ctx context.Context) ([]entity.ProfileResponse, error)
+	GetList(ctx context.Context, param entity.MatchListParam) (entity.MatchListResponse, error)
+	Unmatch(ctx context.Context, matchId int64) error
+	Extend(ctx context.Context, matchId int64) (entity.MatchExtendResponse, error)
+	Sweep(ctx context.Context, limit int) (int, error)
 }
 
+const defaultLimit = 20
+
+var Now = time.Now
+
 type matchs struct {
 	log     log.Interface
+	cfg     config.Match
 	match   match.Interface
 	profile profile.Interface
+	outbox  outbox.Interface
+	atomic  atomic.AtomicSessionProvider
 }
 
-func Init(log log.Interface, m match.Interface, p profile.Interface) Interface {
+func Init(log log.Interface, cfg config.Match, m match.Interface, p profile.Interface, o outbox.Interface, a atomic.AtomicSessionProvider) Interface {
 	return &matchs{
 		log:     log,
+		cfg:     cfg,
 		match:   m,
 		profile: p,
+		outbox:  o,
+		atomic:  a,
 	}
 }
 
-func (m *matchs) GetList(ctx context.Context) ([]entity.ProfileResponse, error) {
-	var results []entity.ProfileResponse
+func (m *matchs) GetList(ctx context.Context, param entity.MatchListParam) (entity.MatchListResponse, error) {
+	var result entity.MatchListResponse
 
 	userId := appcontext.GetUserId(ctx)
 	if userId < 1 {
-		return results, appErr.ErrInvalidUserId
+		return result, appErr.ErrInvalidUserId
+	}
+
+	limit := operator.Ternary(param.Limit < 1, defaultLimit, param.Limit)
+	page := entity.MatchPageParam{
+		UserId:   int64(userId),
+		Order:    operator.Ternary(param.Order == "", entity.MatchOrderNewest, param.Order),
+		BeforeAt: cursor.Max,
+		BeforeId: math.MaxInt64,
+		Limit:    limit + 1, // one extra row tells whether there is a next page
+	}
+
+	if param.Cursor != "" {
+		var err error
+		page.BeforeAt, page.BeforeId, err = cursor.Decode(param.Cursor)
+		if err != nil {
+			return result, appErr.ErrInvalidCursor
+		}
 	}
 
-	matchs, err := m.match.GetByUserId(ctx, int64(userId))
+	matchs, err := m.match.GetPage(ctx, page)
 	if err != nil {
-		return results, err
+		return result, err
+	}
+
+	if len(matchs) > limit {
+		matchs = matchs[:limit]
+		last := matchs[len(matchs)-1]
+		result.NextCursor = cursor.Encode(operator.Ternary(page.Order == entity.MatchOrderActivity, last.LastActivityAt.Time, last.CreatedAt.Time), last.ID)
+	}
+
+	if len(matchs) < 1 {
+		return result, nil
 	}
 
 	var userIds []string
-	for _, m := range matchs {
-		userId1, userId2 := fmt.Sprintf("%d", m.UserId1), fmt.Sprintf("%d", m.UserId2)
-		if !slices.Contains(userIds, userId1) && m.UserId1 != int64(userId) {
-			userIds = append(userIds, userId1)
+	for _, mt := range matchs {
+		userIds = append(userIds, strconv.FormatInt(counterpart(mt, int64(userId)), 10))
+	}
+
+	profiles, err := m.profile.GetByUserIds(ctx, int64(userId), userIds)
+	if err != nil {
+		return result, err
+	}
+
+	profileByUser := make(map[int64]entity.Profile, len(profiles))
+	for _, p := range profiles {
+		profileByUser[p.UserId] = p
+	}
+
+	for _, mt := range matchs {
+		p, ok := profileByUser[counterpart(mt, int64(userId))]
+		if !ok {
+			continue
+		}
+
+		days := int(time.Now().Sub(p.BirthDay.Time).Hours() / 24)
+		resp := entity.MatchResponse{
+			ID:     mt.ID,
+			UserId: p.UserId,
+			Profile: entity.ProfileResponse{
+				FullName:  p.FullName,
+				Gender:    p.Gender,
+				Age:       int64(days / 365),
+				Location:  p.Location.String,
+				Bio:       p.Bio.String,
+				ProfPic:   p.ProfPic.String,
+				Interest:  p.Interest.String,
+				CreatedAt: p.CreatedAt.Time,
+			},
+			Photo:          p.ProfPic.String,
+			MatchedAt:      mt.CreatedAt.Time,
+			LastActivityAt: mt.LastActivityAt.Time,
+		}
+
+		if deadline, ok := m.deadline(mt); ok {
+			resp.ExpiresAt = &deadline
+		}
+
+		result.Matches = append(result.Matches, resp)
+	}
+
+	return result, nil
+}
+
+// Unmatch soft-deletes the match for both users, the pair stays out of each other's discovery for good
+func (m *matchs) Unmatch(ctx context.Context, matchId int64) error {
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return appErr.ErrInvalidUserId
+	}
+
+	return atomic.Atomic(ctx, m.atomic, m.log, func(ctx context.Context) error {
+		current, err := m.match.GetForUpdate(ctx, matchId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return appErr.ErrMatchNotFound
+			}
+			return err
 		}
 
-		if !slices.Contains(userIds, userId2) && m.UserId2 != int64(userId) {
-			userIds = append(userIds, userId2)
+		// someone else's match looks the same as a missing one
+		if current.UserId1 != int64(userId) && current.UserId2 != int64(userId) {
+			return appErr.ErrMatchNotFound
 		}
+
+		current.DeletedBy = sql.NullInt64{Int64: int64(userId), Valid: true}
+
+		return m.match.Delete(ctx, current)
+	})
+}
+
+// Extend gives one more ExtendBy on a match nobody has engaged with yet, the route lets only users with
+// FeatureExtendMatch through
+func (m *matchs) Extend(ctx context.Context, matchId int64) (entity.MatchExtendResponse, error) {
+	var result entity.MatchExtendResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
 	}
 
-	profiles, err := m.profile.GetByUserIds(ctx, userIds)
+	if m.cfg.ExpireAfter <= 0 {
+		return result, appErr.ErrMatchNotExpiring
+	}
+
+	err := atomic.Atomic(ctx, m.atomic, m.log, func(ctx context.Context) error {
+		current, err := m.match.GetForUpdate(ctx, matchId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return appErr.ErrMatchNotFound
+			}
+			return err
+		}
+
+		if current.UserId1 != int64(userId) && current.UserId2 != int64(userId) {
+			return appErr.ErrMatchNotFound
+		}
+
+		deadline, ok := m.deadline(current)
+		if !ok {
+			return appErr.ErrMatchNotExpiring
+		}
+
+		if current.ExtendedAt.Valid {
+			return appErr.ErrMatchAlreadyExtended
+		}
+
+		// a deadline the sweeper has not caught up with yet is extended from now
+		if now := Now(); deadline.Before(now) {
+			deadline = now
+		}
+
+		current.ExpiresAt = sql.NullTime{Time: deadline.Add(m.cfg.ExtendBy), Valid: true}
+		if err := m.match.Extend(ctx, current); err != nil {
+			return err
+		}
+
+		result = entity.MatchExtendResponse{
+			ID:        current.ID,
+			ExpiresAt: current.ExpiresAt.Time,
+		}
+
+		return nil
+	})
 	if err != nil {
-		return results, err
+		return entity.MatchExtendResponse{}, err
 	}
 
-	for _, p := range profiles {
-		days := int(time.Now().Sub(p.BirthDay.Time).Hours() / 24)
-		results = append(results, entity.ProfileResponse{
-			FullName:  p.FullName,
-			Gender:    p.Gender,
-			Age:       int64(days / 365),
-			Location:  p.Location.String,
-			Bio:       p.Bio.String,
-			Interest:  p.Interest.String,
-			CreatedAt: p.CreatedAt.Time,
-		})
-	}
-
-	return results, nil
+	return result, nil
+}
+
+// Sweep removes unengaged matches past their deadline and warns both users of those about to follow,
+// it returns how many matches were handled
+func (m *matchs) Sweep(ctx context.Context, limit int) (int, error) {
+	if m.cfg.ExpireAfter <= 0 {
+		return 0, nil
+	}
+
+	param := entity.MatchExpiryParam{
+		ExpireAfter: m.cfg.ExpireAfter,
+		WarnBefore:  m.cfg.ExpiryWarning,
+		Limit:       limit,
+	}
+
+	expired := 0
+	err := atomic.Atomic(ctx, m.atomic, m.log, func(ctx context.Context) error {
+		matchs, err := m.match.GetExpired(ctx, param)
+		if err != nil {
+			return err
+		}
+
+		for _, mt := range matchs {
+			deadline, _ := m.deadline(mt)
+
+			// deleted_by stays empty, nobody unmatched
+			if err := m.match.Delete(ctx, mt); err != nil {
+				return err
+			}
+
+			if err := m.emitExpiry(ctx, entity.EventMatchExpired, mt, deadline); err != nil {
+				return err
+			}
+		}
+
+		expired = len(matchs)
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if m.cfg.ExpiryWarning <= 0 {
+		return expired, nil
+	}
+
+	warned := 0
+	err = atomic.Atomic(ctx, m.atomic, m.log, func(ctx context.Context) error {
+		matchs, err := m.match.GetExpiring(ctx, param)
+		if err != nil {
+			return err
+		}
+
+		for _, mt := range matchs {
+			deadline, _ := m.deadline(mt)
+
+			if err := m.match.MarkExpiryNotified(ctx, mt); err != nil {
+				return err
+			}
+
+			if err := m.emitExpiry(ctx, entity.EventMatchExpiring, mt, deadline); err != nil {
+				return err
+			}
+		}
+
+		warned = len(matchs)
+
+		return nil
+	})
+	if err != nil {
+		return expired, err
+	}
+
+	return expired + warned, nil
+}
+
+func (m *matchs) emitExpiry(ctx context.Context, eventType string, mt entity.Match, deadline time.Time) error {
+	_, err := m.outbox.Emit(ctx, eventType, mt.UserId1, entity.MatchExpiryPayload{
+		MatchId:   mt.ID,
+		UserId1:   mt.UserId1,
+		UserId2:   mt.UserId2,
+		ExpiresAt: deadline,
+	})
+
+	return err
+}
+
+// deadline returns when the match expires, engaged matches and disabled expiry have none
+func (m *matchs) deadline(mt entity.Match) (time.Time, bool) {
+	if m.cfg.ExpireAfter <= 0 || mt.EngagedAt.Valid {
+		return time.Time{}, false
+	}
+
+	if mt.ExpiresAt.Valid {
+		return mt.ExpiresAt.Time, true
+	}
+
+	return mt.CreatedAt.Time.Add(m.cfg.ExpireAfter), true
+}
+
+// counterpart returns the other user of the match
+func counterpart(m entity.Match, userId int64) int64 {
+	if m.UserId1 == userId {
+		return m.UserId2
+	}
+	return m.UserId1
 }
@@ -2,15 +2,25 @@ package match
 
 import (
 	"context"
+	"database/sql"
 	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
+	mock_atomic "loverly/lib/atomic/mock"
+	"loverly/lib/cursor"
 	mock_log "loverly/lib/log/mock"
 	mock_match "loverly/src/business/domain/mock/match"
+	mock_outbox "loverly/src/business/domain/mock/outbox"
 	mock_profile "loverly/src/business/domain/mock/profile"
 	"loverly/src/business/entity"
+	"loverly/src/config"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
+
+	appErr "loverly/src/errors"
 )
 
 func TestGetList(t *testing.T) {
@@ -20,6 +30,8 @@ func TestGetList(t *testing.T) {
 	log := mock_log.NewMockInterface(ctrl)
 	profileMock := mock_profile.NewMockInterface(ctrl)
 	matchMock := mock_match.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
 
 	type mockFields struct {
 		profileMock *mock_profile.MockInterface
@@ -32,53 +44,90 @@ func TestGetList(t *testing.T) {
 	}
 
 	type args struct {
-		ctx context.Context
+		ctx   context.Context
+		param entity.MatchListParam
 	}
 
-	allGoods := []entity.ProfileResponse{
-		{FullName: "test", Gender: entity.Female, Age: 292},
+	matchedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	activeAt := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	firstPage := entity.MatchPageParam{UserId: 1, Order: entity.MatchOrderNewest, BeforeAt: cursor.Max, BeforeId: math.MaxInt64, Limit: 2}
+	match := entity.Match{ID: 7, UserId1: 2, UserId2: 1, CreatedAt: sql.NullTime{Time: matchedAt, Valid: true}, LastActivityAt: sql.NullTime{Time: activeAt, Valid: true}}
+
+	allGoods := entity.MatchListResponse{
+		Matches: []entity.MatchResponse{
+			{ID: 7, UserId: 2, Profile: entity.ProfileResponse{FullName: "test", Gender: entity.Female, Age: 292}, MatchedAt: matchedAt, LastActivityAt: activeAt},
+		},
 	}
 
 	tests := []struct {
 		name     string
 		mockFunc func(mock mockFields, arg args)
 		args     args
-		want     []entity.ProfileResponse
+		want     entity.MatchListResponse
 		wantErr  bool
 	}{
 		{
 			name: "err get match",
 			args: args{
-				ctx: appcontext.SetUserId(context.Background(), 1),
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.MatchListParam{Limit: 1},
 			},
-			want:    nil,
+			want:    entity.MatchListResponse{},
 			wantErr: true,
 			mockFunc: func(mock mockFields, arg args) {
-				mock.matchMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return([]entity.Match{}, assert.AnError)
+				mock.matchMock.EXPECT().GetPage(arg.ctx, firstPage).Return([]entity.Match{}, assert.AnError)
 			},
 		},
+		{
+			name: "err invalid cursor",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.MatchListParam{Cursor: "bm9wZQ"},
+			},
+			want:     entity.MatchListResponse{},
+			wantErr:  true,
+			mockFunc: func(mock mockFields, arg args) {},
+		},
 		{
 			name: "err get profile by user ids",
 			args: args{
-				ctx: appcontext.SetUserId(context.Background(), 1),
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.MatchListParam{Limit: 1},
 			},
-			want:    nil,
+			want:    entity.MatchListResponse{},
 			wantErr: true,
 			mockFunc: func(mock mockFields, arg args) {
-				mock.matchMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return([]entity.Match{{UserId1: 1, UserId2: 2}}, nil)
-				mock.profileMock.EXPECT().GetByUserIds(arg.ctx, []string{"2"}).Return([]entity.Profile{}, assert.AnError)
+				mock.matchMock.EXPECT().GetPage(arg.ctx, firstPage).Return([]entity.Match{match}, nil)
+				mock.profileMock.EXPECT().GetByUserIds(arg.ctx, int64(1), []string{"2"}).Return([]entity.Profile{}, assert.AnError)
 			},
 		},
 		{
 			name: "all  goods",
 			args: args{
-				ctx: appcontext.SetUserId(context.Background(), 1),
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.MatchListParam{Limit: 1},
 			},
 			want:    allGoods,
 			wantErr: false,
 			mockFunc: func(mock mockFields, arg args) {
-				mock.matchMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return([]entity.Match{{UserId1: 1, UserId2: 2}}, nil)
-				mock.profileMock.EXPECT().GetByUserIds(arg.ctx, []string{"2"}).Return([]entity.Profile{{FullName: "test", Gender: entity.Female}}, nil)
+				mock.matchMock.EXPECT().GetPage(arg.ctx, firstPage).Return([]entity.Match{match}, nil)
+				mock.profileMock.EXPECT().GetByUserIds(arg.ctx, int64(1), []string{"2"}).Return([]entity.Profile{{UserId: 2, FullName: "test", Gender: entity.Female}}, nil)
+			},
+		},
+		{
+			name: "next page by activity",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.MatchListParam{Limit: 1, Order: entity.MatchOrderActivity, Cursor: cursor.Encode(activeAt.Add(time.Hour), 9)},
+			},
+			want: entity.MatchListResponse{
+				Matches:    allGoods.Matches,
+				NextCursor: cursor.Encode(activeAt, 7),
+			},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.matchMock.EXPECT().GetPage(arg.ctx, entity.MatchPageParam{UserId: 1, Order: entity.MatchOrderActivity, BeforeAt: activeAt.Add(time.Hour), BeforeId: 9, Limit: 2}).Return([]entity.Match{match, {ID: 3, UserId1: 1, UserId2: 4}}, nil)
+				mock.profileMock.EXPECT().GetByUserIds(arg.ctx, int64(1), []string{"2"}).Return([]entity.Profile{{UserId: 2, FullName: "test", Gender: entity.Female}}, nil)
 			},
 		},
 	}
@@ -87,8 +136,8 @@ func TestGetList(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockFunc(mocks, tt.args)
 
-			d := Init(log, matchMock, profileMock)
-			got, err := d.GetList(tt.args.ctx)
+			d := Init(log, config.Match{}, matchMock, profileMock, outboxMock, atomicMock)
+			got, err := d.GetList(tt.args.ctx, tt.args.param)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetList error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -98,3 +147,349 @@ func TestGetList(t *testing.T) {
 		})
 	}
 }
+
+func TestUnmatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	matchMock := mock_match.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
+
+	type args struct {
+		ctx     context.Context
+		matchId int64
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		wantErr  error
+	}{
+		{
+			name: "err invalid user id",
+			args: args{
+				ctx:     context.Background(),
+				matchId: 5,
+			},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "err match not found",
+			args: args{
+				ctx:     appcontext.SetUserId(context.Background(), 1),
+				matchId: 5,
+			},
+			wantErr: appErr.ErrMatchNotFound,
+			mockFunc: func(arg args) {
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				matchMock.EXPECT().GetForUpdate(gomock.Any(), int64(5)).Return(entity.Match{}, sql.ErrNoRows)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "err match of other users",
+			args: args{
+				ctx:     appcontext.SetUserId(context.Background(), 1),
+				matchId: 5,
+			},
+			wantErr: appErr.ErrMatchNotFound,
+			mockFunc: func(arg args) {
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				matchMock.EXPECT().GetForUpdate(gomock.Any(), int64(5)).Return(entity.Match{ID: 5, UserId1: 2, UserId2: 3}, nil)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "all goods",
+			args: args{
+				ctx:     appcontext.SetUserId(context.Background(), 1),
+				matchId: 5,
+			},
+			wantErr: nil,
+			mockFunc: func(arg args) {
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				matchMock.EXPECT().GetForUpdate(gomock.Any(), int64(5)).Return(entity.Match{ID: 5, UserId1: 2, UserId2: 1}, nil)
+				matchMock.EXPECT().Delete(gomock.Any(), entity.Match{ID: 5, UserId1: 2, UserId2: 1, DeletedBy: sql.NullInt64{Int64: 1, Valid: true}}).Return(nil)
+				sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			d := Init(log, config.Match{}, matchMock, profileMock, outboxMock, atomicMock)
+			err := d.Unmatch(tt.args.ctx, tt.args.matchId)
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestExtend(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	matchMock := mock_match.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
+
+	now := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	Now = func() time.Time { return now }
+	defer func() { Now = time.Now }()
+
+	cfg := config.Match{ExpireAfter: 72 * time.Hour, ExtendBy: 24 * time.Hour}
+	matchedAt := now.Add(-48 * time.Hour)
+	match := entity.Match{ID: 5, UserId1: 2, UserId2: 1, CreatedAt: sql.NullTime{Time: matchedAt, Valid: true}}
+
+	type args struct {
+		ctx     context.Context
+		matchId int64
+	}
+
+	tests := []struct {
+		name     string
+		cfg      config.Match
+		mockFunc func(arg args)
+		args     args
+		want     entity.MatchExtendResponse
+		wantErr  error
+	}{
+		{
+			name: "err invalid user id",
+			cfg:  cfg,
+			args: args{
+				ctx:     context.Background(),
+				matchId: 5,
+			},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "err expiry disabled",
+			cfg:  config.Match{ExtendBy: 24 * time.Hour},
+			args: args{
+				ctx:     appcontext.SetUserId(context.Background(), 1),
+				matchId: 5,
+			},
+			wantErr:  appErr.ErrMatchNotExpiring,
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "err match of other users",
+			cfg:  cfg,
+			args: args{
+				ctx:     appcontext.SetUserId(context.Background(), 1),
+				matchId: 5,
+			},
+			wantErr: appErr.ErrMatchNotFound,
+			mockFunc: func(arg args) {
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				matchMock.EXPECT().GetForUpdate(gomock.Any(), int64(5)).Return(entity.Match{ID: 5, UserId1: 2, UserId2: 3}, nil)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "err match already engaged",
+			cfg:  cfg,
+			args: args{
+				ctx:     appcontext.SetUserId(context.Background(), 1),
+				matchId: 5,
+			},
+			wantErr: appErr.ErrMatchNotExpiring,
+			mockFunc: func(arg args) {
+				engaged := match
+				engaged.EngagedAt = sql.NullTime{Time: now, Valid: true}
+
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				matchMock.EXPECT().GetForUpdate(gomock.Any(), int64(5)).Return(engaged, nil)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "err already extended",
+			cfg:  cfg,
+			args: args{
+				ctx:     appcontext.SetUserId(context.Background(), 1),
+				matchId: 5,
+			},
+			wantErr: appErr.ErrMatchAlreadyExtended,
+			mockFunc: func(arg args) {
+				extended := match
+				extended.ExpiresAt = sql.NullTime{Time: now.Add(48 * time.Hour), Valid: true}
+				extended.ExtendedAt = sql.NullTime{Time: now, Valid: true}
+
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				matchMock.EXPECT().GetForUpdate(gomock.Any(), int64(5)).Return(extended, nil)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "all goods",
+			cfg:  cfg,
+			args: args{
+				ctx:     appcontext.SetUserId(context.Background(), 1),
+				matchId: 5,
+			},
+			want:    entity.MatchExtendResponse{ID: 5, ExpiresAt: matchedAt.Add(96 * time.Hour)},
+			wantErr: nil,
+			mockFunc: func(arg args) {
+				extended := match
+				extended.ExpiresAt = sql.NullTime{Time: matchedAt.Add(96 * time.Hour), Valid: true}
+
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				matchMock.EXPECT().GetForUpdate(gomock.Any(), int64(5)).Return(match, nil)
+				matchMock.EXPECT().Extend(gomock.Any(), extended).Return(nil)
+				sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			d := Init(log, tt.cfg, matchMock, profileMock, outboxMock, atomicMock)
+			got, err := d.Extend(tt.args.ctx, tt.args.matchId)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSweep(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	matchMock := mock_match.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
+
+	cfg := config.Match{ExpireAfter: 72 * time.Hour, ExpiryWarning: 12 * time.Hour, ExtendBy: 24 * time.Hour}
+	param := entity.MatchExpiryParam{ExpireAfter: 72 * time.Hour, WarnBefore: 12 * time.Hour, Limit: 10}
+
+	matchedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	extendedTo := matchedAt.Add(96 * time.Hour)
+	stale := entity.Match{ID: 5, UserId1: 2, UserId2: 1, CreatedAt: sql.NullTime{Time: matchedAt, Valid: true}}
+	expiring := entity.Match{ID: 6, UserId1: 3, UserId2: 1, CreatedAt: sql.NullTime{Time: matchedAt, Valid: true}, ExpiresAt: sql.NullTime{Time: extendedTo, Valid: true}}
+
+	type args struct {
+		ctx   context.Context
+		limit int
+	}
+
+	tests := []struct {
+		name     string
+		cfg      config.Match
+		mockFunc func(arg args)
+		args     args
+		want     int
+		wantErr  bool
+	}{
+		{
+			name: "expiry disabled",
+			cfg:  config.Match{ExtendBy: 24 * time.Hour},
+			args: args{
+				ctx:   context.Background(),
+				limit: 10,
+			},
+			want:     0,
+			wantErr:  false,
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "err get expired",
+			cfg:  cfg,
+			args: args{
+				ctx:   context.Background(),
+				limit: 10,
+			},
+			want:    0,
+			wantErr: true,
+			mockFunc: func(arg args) {
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				matchMock.EXPECT().GetExpired(gomock.Any(), param).Return(nil, assert.AnError)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "err emit expiring rolls back the warning",
+			cfg:  cfg,
+			args: args{
+				ctx:   context.Background(),
+				limit: 10,
+			},
+			want:    1,
+			wantErr: true,
+			mockFunc: func(arg args) {
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				matchMock.EXPECT().GetExpired(gomock.Any(), param).Return([]entity.Match{stale}, nil)
+				matchMock.EXPECT().Delete(gomock.Any(), stale).Return(nil)
+				outboxMock.EXPECT().Emit(gomock.Any(), entity.EventMatchExpired, int64(2), entity.MatchExpiryPayload{MatchId: 5, UserId1: 2, UserId2: 1, ExpiresAt: matchedAt.Add(72 * time.Hour)}).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				matchMock.EXPECT().GetExpiring(gomock.Any(), param).Return([]entity.Match{expiring}, nil)
+				matchMock.EXPECT().MarkExpiryNotified(gomock.Any(), expiring).Return(nil)
+				outboxMock.EXPECT().Emit(gomock.Any(), entity.EventMatchExpiring, int64(3), entity.MatchExpiryPayload{MatchId: 6, UserId1: 3, UserId2: 1, ExpiresAt: extendedTo}).Return(int64(0), assert.AnError)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "all goods",
+			cfg:  cfg,
+			args: args{
+				ctx:   context.Background(),
+				limit: 10,
+			},
+			want:    2,
+			wantErr: false,
+			mockFunc: func(arg args) {
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				matchMock.EXPECT().GetExpired(gomock.Any(), param).Return([]entity.Match{stale}, nil)
+				matchMock.EXPECT().Delete(gomock.Any(), stale).Return(nil)
+				outboxMock.EXPECT().Emit(gomock.Any(), entity.EventMatchExpired, int64(2), entity.MatchExpiryPayload{MatchId: 5, UserId1: 2, UserId2: 1, ExpiresAt: matchedAt.Add(72 * time.Hour)}).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				matchMock.EXPECT().GetExpiring(gomock.Any(), param).Return([]entity.Match{expiring}, nil)
+				matchMock.EXPECT().MarkExpiryNotified(gomock.Any(), expiring).Return(nil)
+				outboxMock.EXPECT().Emit(gomock.Any(), entity.EventMatchExpiring, int64(3), entity.MatchExpiryPayload{MatchId: 6, UserId1: 3, UserId2: 1, ExpiresAt: extendedTo}).Return(int64(2), nil)
+				sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			d := Init(log, tt.cfg, matchMock, profileMock, outboxMock, atomicMock)
+			got, err := d.Sweep(tt.args.ctx, tt.args.limit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Sweep error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,1072 @@
+package payment
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
+	"loverly/lib/log"
+	"loverly/src/business/domain/audit"
+	"loverly/src/business/domain/boost"
+	"loverly/src/business/domain/coupon"
+	"loverly/src/business/domain/outbox"
+	"loverly/src/business/domain/payment"
+	"loverly/src/business/domain/plan"
+	"loverly/src/business/domain/subscription"
+	"loverly/src/business/entity"
+	"loverly/src/config"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	gateway "loverly/lib/payment"
+	appErr "loverly/src/errors"
+)
+
+// Interface sells plans, a checkout is handed off to the provider and the subscription only starts once the
+// provider confirms the payment through a signed webhook
+type Interface interface {
+	// Checkout buys a plan for a user without a subscription, the ones with one change its plan instead. A
+	// coupon applies to the first period, one that leaves nothing to pay starts the subscription right away
+	Checkout(ctx context.Context, param entity.SubscriptionParam) (entity.PaymentResponse, error)
+	// Quote is what Checkout would charge, it fails on a coupon the way Checkout would
+	Quote(ctx context.Context, param entity.SubscriptionParam) (entity.QuoteResponse, error)
+	// ChangePlan upgrades right away for the price less what's left of the period, downgrades wait for the renewal
+	ChangePlan(ctx context.Context, param entity.SubscriptionParam) (entity.PlanChangeResponse, error)
+	// StartTrial gives a user without a subscription the trial of the plan, once per plan family. With a card on
+	// file required the trial starts once a checkout of nothing is paid and converts at its end, without one it
+	// starts right away and expires at its end
+	StartTrial(ctx context.Context, param entity.SubscriptionParam) (entity.TrialResponse, error)
+	// BuyBoosts checks out a pack of boosts, they're credited once the payment is settled
+	BuyBoosts(ctx context.Context, param entity.BoostPurchaseParam) (entity.PaymentResponse, error)
+	Get(ctx context.Context, id int64) (entity.PaymentResponse, error)
+	// Settle applies a provider webhook, an event delivered more than once is only applied the first time
+	Settle(ctx context.Context, provider string, header http.Header, body []byte) error
+	// Refund gives back the payments the provider captured for something that couldn't be given anymore
+	Refund(ctx context.Context, limit int) (int, error)
+}
+
+var Now = time.Now
+
+type payments struct {
+	log          log.Interface
+	cfg          config.Payment
+	gateway      gateway.Interface
+	payment      payment.Interface
+	plan         plan.Interface
+	subscription subscription.Interface
+	coupon       coupon.Interface
+	boost        boost.Interface
+	outbox       outbox.Interface
+	audit        audit.Interface
+	atomic       atomic.AtomicSessionProvider
+}
+
+func Init(log log.Interface, cfg config.Payment, g gateway.Interface, p payment.Interface, pl plan.Interface, s subscription.Interface, c coupon.Interface, b boost.Interface, o outbox.Interface, au audit.Interface, a atomic.AtomicSessionProvider) Interface {
+	return &payments{
+		log:          log,
+		cfg:          cfg,
+		gateway:      g,
+		payment:      p,
+		plan:         pl,
+		subscription: s,
+		coupon:       c,
+		boost:        b,
+		outbox:       o,
+		audit:        au,
+		atomic:       a,
+	}
+}
+
+func (p *payments) Checkout(ctx context.Context, param entity.SubscriptionParam) (entity.PaymentResponse, error) {
+	var result entity.PaymentResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	pl, err := p.plan.GetByCode(ctx, param.Plan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, appErr.ErrInvalidPlan
+		}
+		return result, err
+	}
+
+	// the unique index on the current subscription is what holds, this only spares a checkout bound to fail
+	sub, err := p.subscription.GetByUserId(ctx, int64(userId))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return result, err
+	}
+
+	if sub.ID > 0 && sub.Status != entity.SubscriptionExpired {
+		return result, appErr.ErrSubscriptionExists
+	}
+
+	pay := entity.Payment{
+		UserId:   int64(userId),
+		Plan:     pl.Code,
+		Amount:   pl.Price,
+		Currency: pl.Currency,
+		Provider: p.gateway.Name(),
+		Status:   entity.PaymentPending,
+	}
+
+	if param.Coupon == "" {
+		pay.ID, err = p.payment.Create(ctx, pay)
+	} else {
+		// the coupon stays locked from its count until the redemption holding it is created
+		err = atomic.Atomic(ctx, p.atomic, p.log, func(ctx context.Context) error {
+			c, err := p.coupon.GetByCodeForUpdate(ctx, param.Coupon)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return appErr.ErrCouponNotFound
+				}
+				return err
+			}
+
+			if err := p.redeemable(ctx, c, pay.UserId, pl); err != nil {
+				return err
+			}
+
+			pay.CouponId = sql.NullInt64{Int64: c.ID, Valid: true}
+			pay.Discount, pay.ExtraDays = discount(c, pl)
+			pay.Amount -= pay.Discount
+
+			pay.ID, err = p.payment.Create(ctx, pay)
+			if err != nil {
+				return err
+			}
+
+			_, err = p.coupon.CreateRedemption(ctx, entity.CouponRedemption{
+				CouponId:  c.ID,
+				UserId:    pay.UserId,
+				PaymentId: pay.ID,
+				Status:    entity.CouponRedemptionReserved,
+				Discount:  pay.Discount,
+				ExtraDays: pay.ExtraDays,
+			})
+			if err != nil {
+				return err
+			}
+
+			// nothing to send the user to the provider for
+			if pay.Amount == 0 {
+				pay, err = p.activate(ctx, pay)
+			}
+
+			return err
+		})
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if pay.Status == entity.PaymentPaid {
+		return toResponse(pay), nil
+	}
+
+	pay, err = p.startCheckout(ctx, pay)
+	if err != nil {
+		return result, err
+	}
+
+	return toResponse(pay), nil
+}
+
+func (p *payments) Quote(ctx context.Context, param entity.SubscriptionParam) (entity.QuoteResponse, error) {
+	var result entity.QuoteResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	pl, err := p.plan.GetByCode(ctx, param.Plan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, appErr.ErrInvalidPlan
+		}
+		return result, err
+	}
+
+	result = entity.QuoteResponse{
+		Plan:         pl.Code,
+		Price:        pl.Price,
+		Amount:       pl.Price,
+		Currency:     pl.Currency,
+		DurationDays: pl.DurationDays,
+	}
+
+	if param.Coupon == "" {
+		return result, nil
+	}
+
+	c, err := p.coupon.GetByCode(ctx, param.Coupon)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.QuoteResponse{}, appErr.ErrCouponNotFound
+		}
+		return entity.QuoteResponse{}, err
+	}
+
+	if err := p.redeemable(ctx, c, int64(userId), pl); err != nil {
+		return entity.QuoteResponse{}, err
+	}
+
+	result.Coupon = c.Code
+	result.Discount, result.ExtraDays = discount(c, pl)
+	result.Amount -= result.Discount
+
+	return result, nil
+}
+
+func (p *payments) ChangePlan(ctx context.Context, param entity.SubscriptionParam) (entity.PlanChangeResponse, error) {
+	var result entity.PlanChangeResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	// coupons are for the first period of a subscription
+	if param.Coupon != "" {
+		return result, appErr.ErrCouponNotApplicable
+	}
+
+	to, err := p.plan.GetByCode(ctx, param.Plan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, appErr.ErrInvalidPlan
+		}
+		return result, err
+	}
+
+	var pay entity.Payment
+	err = atomic.Atomic(ctx, p.atomic, p.log, func(ctx context.Context) error {
+		cur, err := p.subscription.GetCurrentForUpdate(ctx, int64(userId))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return appErr.ErrSubscriptionNotFound
+			}
+			return err
+		}
+
+		result = entity.PlanChangeResponse{FromPlan: cur.Plan, ToPlan: to.Code, Currency: to.Currency, EffectiveAt: Now()}
+
+		if cur.Plan == to.Code {
+			// staying on the plan calls off the downgrade
+			if cur.PendingPlan.Valid {
+				result.Kind = entity.SubscriptionChangeKeep
+				cur.PendingPlan = sql.NullString{}
+				return p.subscription.Update(ctx, cur)
+			}
+
+			if !trialing(cur) {
+				return appErr.ErrSubscriptionPlanUnchanged
+			}
+
+			// paying for the plan during its trial ends the trial now instead of at its end, see switchPlan
+			result.Kind = entity.SubscriptionChangeConvert
+		} else {
+			// a retired plan has no price to credit, moving off it starts the new plan right away
+			from, err := p.plan.GetByCode(ctx, cur.Plan)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+
+			if from.ID > 0 && from.Currency != to.Currency {
+				return appErr.ErrInvalidPlanChange
+			}
+
+			// plans are compared by their price per day, whatever their duration
+			if from.ID > 0 && to.Price*int64(from.DurationDays) <= from.Price*int64(to.DurationDays) {
+				// a cancelled subscription isn't renewed, there is nothing to downgrade
+				if cur.Status == entity.SubscriptionCancelled {
+					return appErr.ErrSubscriptionAlreadyCancelled
+				}
+
+				result.Kind = entity.SubscriptionChangeDowngrade
+				result.Amount = to.Price
+				result.EffectiveAt = cur.EndDate
+				cur.PendingPlan = sql.NullString{String: to.Code, Valid: true}
+				return p.subscription.Update(ctx, cur)
+			}
+
+			result.Kind = entity.SubscriptionChangeUpgrade
+			// a trial was free, there is nothing to credit
+			if from.ID > 0 && !trialing(cur) {
+				result.Credit = prorate(cur)
+			}
+		}
+
+		// one upgrade at a time, the checkout still pending would be priced on a period this one ends
+		if err := p.payment.FailPendingChange(ctx, cur.ID); err != nil {
+			return err
+		}
+
+		result.Amount = to.Price - result.Credit
+		if result.Amount <= 0 {
+			// the credit covers it, nothing goes through the provider
+			result.Amount = 0
+			return p.switchPlan(ctx, cur, to, entity.Payment{Credit: result.Credit})
+		}
+
+		pay = entity.Payment{
+			UserId:         int64(userId),
+			Plan:           to.Code,
+			Amount:         result.Amount,
+			Credit:         result.Credit,
+			Currency:       to.Currency,
+			Provider:       p.gateway.Name(),
+			Status:         entity.PaymentPending,
+			SubscriptionId: sql.NullInt64{Int64: cur.ID, Valid: true},
+			FromPlan:       sql.NullString{String: cur.Plan, Valid: true},
+			PeriodEnd:      sql.NullTime{Time: cur.EndDate, Valid: true},
+		}
+
+		pay.ID, err = p.payment.Create(ctx, pay)
+		return err
+	})
+	if err != nil {
+		return entity.PlanChangeResponse{}, err
+	}
+
+	// the upgrade is applied once the payment is settled, see activate
+	if pay.ID > 0 {
+		pay, err = p.startCheckout(ctx, pay)
+		if err != nil {
+			return entity.PlanChangeResponse{}, err
+		}
+
+		resp := toResponse(pay)
+		result.Payment = &resp
+	}
+
+	return result, nil
+}
+
+func (p *payments) StartTrial(ctx context.Context, param entity.SubscriptionParam) (entity.TrialResponse, error) {
+	var result entity.TrialResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	if param.Coupon != "" {
+		return result, appErr.ErrCouponNotApplicable
+	}
+
+	pl, err := p.plan.GetByCode(ctx, param.Plan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, appErr.ErrInvalidPlan
+		}
+		return result, err
+	}
+
+	if pl.TrialDays < 1 {
+		return result, appErr.ErrTrialNotOffered
+	}
+
+	// both are held by unique indexes once the trial starts, these only spare a checkout bound to fail
+	taken, err := p.subscription.HasTrial(ctx, int64(userId), pl.Family)
+	if err != nil {
+		return result, err
+	}
+
+	if taken {
+		return result, appErr.ErrTrialUsed
+	}
+
+	sub, err := p.subscription.GetByUserId(ctx, int64(userId))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return result, err
+	}
+
+	if sub.ID > 0 && sub.Status != entity.SubscriptionExpired {
+		return result, appErr.ErrSubscriptionExists
+	}
+
+	result = entity.TrialResponse{Plan: pl.Code, TrialDays: pl.TrialDays}
+	pay := entity.Payment{UserId: int64(userId), Plan: pl.Code, Currency: pl.Currency, TrialDays: pl.TrialDays}
+
+	if !p.cfg.TrialCard {
+		err = atomic.Atomic(ctx, p.atomic, p.log, func(ctx context.Context) error {
+			sub, err = p.start(ctx, pl, pay)
+			return err
+		})
+		if err != nil {
+			return entity.TrialResponse{}, err
+		}
+
+		result.SubscriptionId = sub.ID
+		result.EndsAt = &sub.EndDate
+
+		return result, nil
+	}
+
+	// the trial starts once the card is on file, see activate
+	pay.Provider = p.gateway.Name()
+	pay.Status = entity.PaymentPending
+	pay.ID, err = p.payment.Create(ctx, pay)
+	if err != nil {
+		return entity.TrialResponse{}, err
+	}
+
+	pay, err = p.startCheckout(ctx, pay)
+	if err != nil {
+		return entity.TrialResponse{}, err
+	}
+
+	resp := toResponse(pay)
+	result.Payment = &resp
+
+	return result, nil
+}
+
+func (p *payments) BuyBoosts(ctx context.Context, param entity.BoostPurchaseParam) (entity.PaymentResponse, error) {
+	var result entity.PaymentResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	if p.cfg.BoostPrice < 1 {
+		return result, appErr.ErrBoostsNotForSale
+	}
+
+	pay := entity.Payment{
+		UserId:   int64(userId),
+		Boosts:   param.Quantity,
+		Amount:   p.cfg.BoostPrice * int64(param.Quantity),
+		Currency: p.cfg.BoostCurrency,
+		Provider: p.gateway.Name(),
+		Status:   entity.PaymentPending,
+	}
+
+	var err error
+	pay.ID, err = p.payment.Create(ctx, pay)
+	if err != nil {
+		return result, err
+	}
+
+	pay, err = p.startCheckout(ctx, pay)
+	if err != nil {
+		return result, err
+	}
+
+	return toResponse(pay), nil
+}
+
+// startCheckout hands a created payment to the provider. The provider is called outside of any transaction,
+// the payment row is there first so the reference the provider echoes back always points at something
+func (p *payments) startCheckout(ctx context.Context, pay entity.Payment) (entity.Payment, error) {
+	session, err := p.gateway.CreateCheckout(ctx, gateway.Checkout{
+		Reference:   strconv.FormatInt(pay.ID, 10),
+		Amount:      pay.Amount,
+		Currency:    pay.Currency,
+		Description: description(pay),
+		SaveMethod:  pay.TrialDays > 0,
+	})
+	if err != nil {
+		p.log.Error(ctx, fmt.Sprintf("CreateCheckout err: %v", err))
+
+		pay.Status = entity.PaymentFailed
+		if err := p.payment.Update(ctx, pay); err != nil {
+			return pay, err
+		}
+
+		if pay.CouponId.Valid {
+			if err := p.coupon.SetRedemptionStatus(ctx, pay.ID, entity.CouponRedemptionReleased); err != nil {
+				return pay, err
+			}
+		}
+
+		return pay, appErr.ErrPaymentProviderFailed
+	}
+
+	pay.ProviderRef = sql.NullString{String: session.ProviderRef, Valid: true}
+	pay.CheckoutURL = session.CheckoutURL
+	if err := p.payment.Update(ctx, pay); err != nil {
+		return pay, err
+	}
+
+	return pay, nil
+}
+
+func (p *payments) Get(ctx context.Context, id int64) (entity.PaymentResponse, error) {
+	var result entity.PaymentResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	pay, err := p.payment.Get(ctx, id, int64(userId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, appErr.ErrPaymentNotFound
+		}
+		return result, err
+	}
+
+	return toResponse(pay), nil
+}
+
+func (p *payments) Settle(ctx context.Context, provider string, header http.Header, body []byte) error {
+	if provider != p.gateway.Name() {
+		return appErr.ErrUnknownPaymentProvider
+	}
+
+	event, err := p.gateway.ParseWebhook(header, body)
+	if err != nil {
+		p.log.Error(ctx, fmt.Sprintf("ParseWebhook err: %v", err))
+		if errors.Is(err, gateway.ErrInvalidSignature) {
+			return appErr.ErrInvalidPaymentSignature
+		}
+		return appErr.ErrInvalidPaymentEvent
+	}
+
+	return atomic.Atomic(ctx, p.atomic, p.log, func(ctx context.Context) error {
+		pay, err := p.payment.GetByProviderRefForUpdate(ctx, provider, event.ProviderRef)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return appErr.ErrPaymentNotFound
+			}
+			return err
+		}
+
+		if event.Reference != strconv.FormatInt(pay.ID, 10) {
+			return appErr.ErrInvalidPaymentEvent
+		}
+
+		eventId, err := p.payment.RecordEvent(ctx, entity.PaymentEvent{
+			Provider:  provider,
+			EventId:   event.ID,
+			Type:      event.Type,
+			PaymentId: sql.NullInt64{Int64: pay.ID, Valid: true},
+			Payload:   body,
+		})
+		if err != nil {
+			return err
+		}
+
+		// redelivered, it was applied the first time
+		if eventId == 0 {
+			return nil
+		}
+
+		switch event.Type {
+		case gateway.EventFailed:
+			// a late failure doesn't undo a payment already settled
+			if pay.Status != entity.PaymentPending {
+				return nil
+			}
+
+			pay.Status = entity.PaymentFailed
+			if err := p.payment.Update(ctx, pay); err != nil {
+				return err
+			}
+
+			// the coupon goes back to the ones left
+			if pay.CouponId.Valid {
+				return p.coupon.SetRedemptionStatus(ctx, pay.ID, entity.CouponRedemptionReleased)
+			}
+
+			return nil
+		case gateway.EventPaid:
+			if pay.Status == entity.PaymentPaid || pay.Status == entity.PaymentRefundDue || pay.Status == entity.PaymentRefunded {
+				return nil
+			}
+
+			if event.Amount != pay.Amount || event.Currency != pay.Currency {
+				return appErr.ErrInvalidPaymentEvent
+			}
+
+			// a trial checkout charges nothing, all it's good for is the card the provider verified
+			if pay.TrialDays > 0 && event.PaymentMethod == "" {
+				p.log.Error(ctx, fmt.Sprintf("payment %d paid for a trial without a verified card, it's failed", pay.ID))
+
+				pay.Status = entity.PaymentFailed
+				return p.payment.Update(ctx, pay)
+			}
+
+			_, err = p.activate(ctx, pay)
+			return err
+		}
+
+		return nil
+	})
+}
+
+// activate starts the subscription the payment bought, or switches the current one to it, or credits the boosts
+// it bought. It runs inside the settling atomic session
+func (p *payments) activate(ctx context.Context, pay entity.Payment) (entity.Payment, error) {
+	if pay.Boosts > 0 {
+		return p.creditBoosts(ctx, pay)
+	}
+
+	// the reservation of a checkout stops counting once its hold lapsed, others may have used the coupon up since
+	if pay.CouponId.Valid && p.cfg.CouponHold > 0 && pay.CreatedAt.Valid && !pay.CreatedAt.Time.After(Now().Add(-p.cfg.CouponHold)) {
+		usedUp, err := p.couponUsedUp(ctx, pay)
+		if err != nil {
+			return pay, err
+		}
+
+		if usedUp {
+			p.log.Error(ctx, fmt.Sprintf("payment %d paid with coupon %d after it was used up, it's refunded", pay.ID, pay.CouponId.Int64))
+
+			pay, err = p.refundDue(ctx, pay)
+			if err != nil {
+				return pay, err
+			}
+
+			return pay, p.coupon.SetRedemptionStatus(ctx, pay.ID, entity.CouponRedemptionReleased)
+		}
+	}
+
+	pl, err := p.plan.GetByCode(ctx, pay.Plan)
+	if err != nil {
+		return pay, err
+	}
+
+	// the user may have subscribed again between the checkout and the payment, there is still one subscription
+	cur, err := p.subscription.GetCurrentForUpdate(ctx, pay.UserId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return pay, err
+	}
+
+	// the credit of an upgrade was taken from the period it was checked out on, once the subscription moved on
+	// from it, e.g. renewed or upgraded by another payment, the price paid no longer holds
+	if pay.FromPlan.Valid && (err != nil || cur.ID != pay.SubscriptionId.Int64 || cur.Plan != pay.FromPlan.String ||
+		!cur.EndDate.Equal(pay.PeriodEnd.Time)) {
+		p.log.Error(ctx, fmt.Sprintf("payment %d paid an upgrade of a period that's over, it's refunded", pay.ID))
+
+		return p.refundDue(ctx, pay)
+	}
+
+	switch {
+	case err == nil && pay.TrialDays > 0:
+		// a card put on file for a trial has nothing to add to a subscription
+		pay.SubscriptionId = sql.NullInt64{Int64: cur.ID, Valid: true}
+	case err == nil:
+		err = p.switchPlan(ctx, cur, pl, pay)
+		pay.SubscriptionId = sql.NullInt64{Int64: cur.ID, Valid: true}
+	case errors.Is(err, sql.ErrNoRows):
+		var sub entity.Subscription
+		sub, err = p.start(ctx, pl, pay)
+		// the family was tried since the checkout, there is no trial for the card to go with
+		if errors.Is(err, appErr.ErrTrialUsed) {
+			p.log.Error(ctx, fmt.Sprintf("payment %d put a card on file for a trial already taken, it's failed", pay.ID))
+
+			pay.Status = entity.PaymentFailed
+			return pay, p.payment.Update(ctx, pay)
+		}
+		pay.SubscriptionId = sql.NullInt64{Int64: sub.ID, Valid: sub.ID > 0}
+	}
+	if err != nil {
+		return pay, err
+	}
+
+	pay.Status = entity.PaymentPaid
+	pay.PaidAt = sql.NullTime{Time: Now(), Valid: true}
+	if err := p.payment.Update(ctx, pay); err != nil {
+		return pay, err
+	}
+
+	// a payment settled after its hold lapsed was recounted above
+	if pay.CouponId.Valid {
+		if err := p.coupon.SetRedemptionStatus(ctx, pay.ID, entity.CouponRedemptionRedeemed); err != nil {
+			return pay, err
+		}
+	}
+
+	return pay, nil
+}
+
+// refundDue leaves the payment the provider captured to the refund worker, it runs inside the settling atomic session
+func (p *payments) refundDue(ctx context.Context, pay entity.Payment) (entity.Payment, error) {
+	pay.Status = entity.PaymentRefundDue
+	pay.PaidAt = sql.NullTime{Time: Now(), Valid: true}
+	return pay, p.payment.Update(ctx, pay)
+}
+
+func (p *payments) Refund(ctx context.Context, limit int) (int, error) {
+	// each payment is refunded in a transaction of its own, one failing doesn't roll back the others
+	due, err := p.payment.GetRefundDue(ctx, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	refunded := 0
+	var refundErr error
+	for _, pay := range due {
+		done, err := p.refund(ctx, pay)
+		if err != nil {
+			if refundErr == nil {
+				refundErr = err
+			}
+			continue
+		}
+
+		if done {
+			refunded++
+		}
+	}
+
+	return refunded, refundErr
+}
+
+// refund makes the refund before the payment is locked, it carries the same reference every time so one repeated
+// after a failure or by another worker isn't refunded twice. It returns false when another worker got there first
+func (p *payments) refund(ctx context.Context, pay entity.Payment) (bool, error) {
+	ref, err := p.gateway.Refund(ctx, gateway.Refund{
+		ProviderRef: pay.ProviderRef.String,
+		Reference:   fmt.Sprintf("refund:%d", pay.ID),
+		Amount:      pay.Amount,
+		Currency:    pay.Currency,
+		Reason:      description(pay),
+	})
+	if err != nil {
+		p.log.Error(ctx, fmt.Sprintf("refund payment %d err: %v", pay.ID, err))
+		return false, err
+	}
+
+	refunded := false
+	err = atomic.Atomic(ctx, p.atomic, p.log, func(ctx context.Context) error {
+		locked, err := p.payment.GetByIdForUpdate(ctx, pay.ID)
+		if err != nil {
+			return err
+		}
+
+		if locked.Status != entity.PaymentRefundDue {
+			return nil
+		}
+
+		refunded = true
+
+		locked.Status = entity.PaymentRefunded
+		locked.RefundRef = sql.NullString{String: ref, Valid: true}
+		return p.payment.Update(ctx, locked)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return refunded, nil
+}
+
+// creditBoosts adds the boosts of a paid pack to the user's credits, the payment is locked by the settlement
+func (p *payments) creditBoosts(ctx context.Context, pay entity.Payment) (entity.Payment, error) {
+	_, err := p.boost.CreateCredit(ctx, entity.BoostCredit{
+		UserId:    pay.UserId,
+		Amount:    int64(pay.Boosts),
+		Reason:    fmt.Sprintf("payment:%d", pay.ID),
+		PaymentId: sql.NullInt64{Int64: pay.ID, Valid: true},
+	})
+	if err != nil {
+		return pay, err
+	}
+
+	pay.Status = entity.PaymentPaid
+	pay.PaidAt = sql.NullTime{Time: Now(), Valid: true}
+	if err := p.payment.Update(ctx, pay); err != nil {
+		return pay, err
+	}
+
+	return pay, nil
+}
+
+// start creates the subscription, a payment with trial days starts it with a trial of the plan family instead of
+// a paid period. pay has no ID when the trial needs no card on file
+func (p *payments) start(ctx context.Context, pl entity.Plan, pay entity.Payment) (entity.Subscription, error) {
+	sub := entity.Subscription{
+		UserId:    pay.UserId,
+		Plan:      pl.Code,
+		StartDate: Now(),
+		EndDate:   Now().AddDate(0, 0, pl.DurationDays+pay.ExtraDays),
+		// what the user paid after the coupon, nothing for a trial
+		PeriodAmount: pay.Amount,
+	}
+
+	if pay.TrialDays > 0 {
+		// with a card on file the renewal at the end of the trial converts it
+		sub.EndDate = sub.StartDate.AddDate(0, 0, pay.TrialDays)
+		sub.TrialEndsAt = sql.NullTime{Time: sub.EndDate, Valid: true}
+		sub.TrialConverts = pay.ID > 0
+
+		trialId, err := p.subscription.ClaimTrial(ctx, entity.Trial{
+			UserId:    sub.UserId,
+			Family:    pl.Family,
+			Plan:      pl.Code,
+			PaymentId: sql.NullInt64{Int64: pay.ID, Valid: pay.ID > 0},
+			StartedAt: sub.StartDate,
+			EndsAt:    sub.EndDate,
+		})
+		if err != nil {
+			return entity.Subscription{}, err
+		}
+
+		if trialId == 0 {
+			return entity.Subscription{}, appErr.ErrTrialUsed
+		}
+	}
+
+	subId, err := p.subscription.Create(ctx, sub)
+	if err != nil {
+		return entity.Subscription{}, err
+	}
+	sub.ID = subId
+
+	_, err = p.subscription.RecordChange(ctx, entity.SubscriptionChange{
+		SubscriptionId: subId,
+		UserId:         sub.UserId,
+		ToPlan:         sub.Plan,
+		Kind:           entity.SubscriptionChangeStart,
+		Amount:         pay.Amount,
+		Currency:       pay.Currency,
+		PaymentId:      sql.NullInt64{Int64: pay.ID, Valid: pay.ID > 0},
+		EffectiveAt:    sub.StartDate,
+	})
+	if err != nil {
+		return entity.Subscription{}, err
+	}
+
+	payload := entity.SubscriptionStartedPayload{
+		SubscriptionId: subId,
+		UserId:         sub.UserId,
+		Plan:           sub.Plan,
+		StartDate:      sub.StartDate,
+		EndDate:        sub.EndDate,
+	}
+
+	if sub.TrialEndsAt.Valid {
+		payload.TrialEndsAt = &sub.TrialEndsAt.Time
+	}
+
+	if _, err = p.outbox.Emit(ctx, entity.EventSubscriptionStarted, sub.UserId, payload); err != nil {
+		return entity.Subscription{}, err
+	}
+
+	_, err = p.audit.Record(ctx, entity.AuditLog{
+		ActorId:    sql.NullInt64{Int64: sub.UserId, Valid: true},
+		Action:     entity.AuditSubscriptionCreated,
+		TargetType: entity.AuditTargetSubscription,
+		TargetId:   sql.NullInt64{Int64: subId, Valid: true},
+	}, entity.AuditSubscriptionDetails{Plan: sub.Plan, StartDate: sub.StartDate, EndDate: sub.EndDate, PaymentId: pay.ID, Trial: sub.TrialEndsAt.Valid})
+	if err != nil {
+		return entity.Subscription{}, err
+	}
+
+	return sub, nil
+}
+
+// switchPlan moves the locked subscription to the plan, a new period starts now since the unused part of the
+// old one was credited. pay is empty but for the credit when the credit covered the whole price
+func (p *payments) switchPlan(ctx context.Context, sub entity.Subscription, pl entity.Plan, pay entity.Payment) error {
+	change := entity.SubscriptionChange{
+		SubscriptionId: sub.ID,
+		UserId:         sub.UserId,
+		FromPlan:       sql.NullString{String: sub.Plan, Valid: true},
+		ToPlan:         pl.Code,
+		Kind:           entity.SubscriptionChangeUpgrade,
+		Credit:         pay.Credit,
+		Amount:         pay.Amount,
+		Currency:       pl.Currency,
+		PaymentId:      sql.NullInt64{Int64: pay.ID, Valid: pay.ID > 0},
+		EffectiveAt:    Now(),
+	}
+
+	if sub.Plan == pl.Code && !trialing(sub) {
+		// paid twice for the same plan, the second period follows the first
+		change.Kind = entity.SubscriptionChangeExtend
+		if sub.EndDate.Before(Now()) {
+			sub.EndDate = Now()
+		}
+		sub.EndDate = sub.EndDate.AddDate(0, 0, pl.DurationDays+pay.ExtraDays)
+		sub.PeriodAmount += pay.Amount
+	} else {
+		// the paid period takes over from the trial, on the same plan it's the trial converting early
+		if trialing(sub) {
+			sub.TrialEndsAt.Time = Now()
+			if sub.Plan == pl.Code {
+				change.Kind = entity.SubscriptionChangeConvert
+			}
+		}
+		sub.StartDate = Now()
+		sub.EndDate = Now().AddDate(0, 0, pl.DurationDays+pay.ExtraDays)
+		// the credit went into the new period, up to its price when it covered it all
+		sub.PeriodAmount = pay.Amount + min(pay.Credit, pl.Price)
+	}
+
+	// paying again takes it out of cancelled or grace
+	sub.Plan = pl.Code
+	sub.Status = entity.SubscriptionActive
+	sub.CancelledAt = sql.NullTime{}
+	sub.GraceUntil = sql.NullTime{}
+	sub.RenewalAttempts = 0
+	sub.NextRenewalAt = sql.NullTime{}
+	sub.ReminderSentAt = sql.NullTime{}
+	sub.PendingPlan = sql.NullString{}
+	if err := p.subscription.Update(ctx, sub); err != nil {
+		return err
+	}
+
+	if _, err := p.subscription.RecordChange(ctx, change); err != nil {
+		return err
+	}
+
+	_, err := p.outbox.Emit(ctx, entity.EventSubscriptionChanged, sub.UserId, entity.SubscriptionChangedPayload{
+		SubscriptionId: sub.ID,
+		UserId:         sub.UserId,
+		Kind:           change.Kind,
+		FromPlan:       change.FromPlan.String,
+		ToPlan:         sub.Plan,
+		EndDate:        sub.EndDate,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = p.audit.Record(ctx, entity.AuditLog{
+		ActorId:    sql.NullInt64{Int64: sub.UserId, Valid: true},
+		Action:     entity.AuditSubscriptionChanged,
+		TargetType: entity.AuditTargetSubscription,
+		TargetId:   sql.NullInt64{Int64: sub.ID, Valid: true},
+	}, entity.AuditSubscriptionDetails{Plan: sub.Plan, StartDate: sub.StartDate, EndDate: sub.EndDate, PaymentId: pay.ID, FromPlan: change.FromPlan.String})
+
+	return err
+}
+
+func toResponse(pay entity.Payment) entity.PaymentResponse {
+	resp := entity.PaymentResponse{
+		ID:             pay.ID,
+		Plan:           pay.Plan,
+		Boosts:         pay.Boosts,
+		Amount:         pay.Amount,
+		Credit:         pay.Credit,
+		Discount:       pay.Discount,
+		ExtraDays:      pay.ExtraDays,
+		TrialDays:      pay.TrialDays,
+		Currency:       pay.Currency,
+		Status:         pay.Status,
+		SubscriptionId: pay.SubscriptionId.Int64,
+	}
+
+	// nothing left to pay once it's settled
+	if pay.Status == entity.PaymentPending {
+		resp.CheckoutURL = pay.CheckoutURL
+	}
+
+	if pay.PaidAt.Valid {
+		resp.PaidAt = &pay.PaidAt.Time
+	}
+
+	return resp
+}
+
+// description is what the provider shows the user on the checkout page
+func description(pay entity.Payment) string {
+	if pay.Boosts > 0 {
+		return fmt.Sprintf("%d boosts", pay.Boosts)
+	}
+
+	return pay.Plan
+}
+
+// redeemable checks the user can use the coupon on the plan now, the usage counted inside an atomic session
+// is only reliable once the coupon is locked
+func (p *payments) redeemable(ctx context.Context, c entity.Coupon, userId int64, pl entity.Plan) error {
+	if (c.StartsAt.Valid && Now().Before(c.StartsAt.Time)) || (c.EndsAt.Valid && !Now().Before(c.EndsAt.Time)) {
+		return appErr.ErrCouponExpired
+	}
+
+	var plans []string
+	if len(c.Plans) > 0 {
+		if err := json.Unmarshal(c.Plans, &plans); err != nil {
+			return err
+		}
+	}
+
+	if len(plans) > 0 && !slices.Contains(plans, pl.Code) {
+		return appErr.ErrCouponNotApplicable
+	}
+
+	if c.Kind == entity.CouponFixed && c.Currency.String != pl.Currency {
+		return appErr.ErrCouponNotApplicable
+	}
+
+	usage, err := p.coupon.GetUsage(ctx, c.ID, userId, p.cfg.CouponHold)
+	if err != nil {
+		return err
+	}
+
+	if c.MaxRedemptions.Valid && int64(usage.Total) >= c.MaxRedemptions.Int64 {
+		return appErr.ErrCouponExhausted
+	}
+
+	if usage.ByUser >= c.MaxPerUser {
+		return appErr.ErrCouponAlreadyRedeemed
+	}
+
+	return nil
+}
+
+// couponUsedUp recounts the limits of the coupon of a payment whose reservation lapsed, it isn't counted any
+// more. The coupon is locked so payments settling together are counted one after the other
+func (p *payments) couponUsedUp(ctx context.Context, pay entity.Payment) (bool, error) {
+	c, err := p.coupon.GetByIdForUpdate(ctx, pay.CouponId.Int64)
+	if err != nil {
+		return false, err
+	}
+
+	usage, err := p.coupon.GetUsage(ctx, c.ID, pay.UserId, p.cfg.CouponHold)
+	if err != nil {
+		return false, err
+	}
+
+	return (c.MaxRedemptions.Valid && int64(usage.Total) >= c.MaxRedemptions.Int64) || usage.ByUser >= c.MaxPerUser, nil
+}
+
+// discount is what the coupon takes off the price of the plan, or the days it adds to the period
+func discount(c entity.Coupon, pl entity.Plan) (int64, int) {
+	switch c.Kind {
+	case entity.CouponPercent:
+		return pl.Price * c.Value / 100, 0
+	case entity.CouponFixed:
+		return min(c.Value, pl.Price), 0
+	case entity.CouponExtraDays:
+		return 0, int(c.Value)
+	}
+
+	return 0, 0
+}
+
+// trialing tells whether the subscription is still in the trial it started with
+func trialing(sub entity.Subscription) bool {
+	return sub.TrialEndsAt.Valid && sub.TrialEndsAt.Time.After(Now())
+}
+
+// prorate is the part of what was paid for the current period left unused, nothing once the period is over,
+// e.g. in grace. The price of the plan may have changed since it was paid
+func prorate(sub entity.Subscription) int64 {
+	period := int64(sub.EndDate.Sub(sub.StartDate) / time.Second)
+	left := int64(sub.EndDate.Sub(Now()) / time.Second)
+	if period <= 0 || left <= 0 {
+		return 0
+	}
+
+	if left > period {
+		left = period
+	}
+
+	return sub.PeriodAmount * left / period
+}
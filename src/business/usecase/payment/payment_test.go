@@ -0,0 +1,1680 @@
+package payment
+
+import (
+	"context"
+	"database/sql"
+	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
+	mock_atomic "loverly/lib/atomic/mock"
+	mock_log "loverly/lib/log/mock"
+	mock_gateway "loverly/lib/payment/mock"
+	mock_audit "loverly/src/business/domain/mock/audit"
+	mock_boost "loverly/src/business/domain/mock/boost"
+	mock_coupon "loverly/src/business/domain/mock/coupon"
+	mock_outbox "loverly/src/business/domain/mock/outbox"
+	mock_payment "loverly/src/business/domain/mock/payment"
+	mock_plan "loverly/src/business/domain/mock/plan"
+	mock_subscription "loverly/src/business/domain/mock/subscription"
+	"loverly/src/business/entity"
+	"loverly/src/config"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	gateway "loverly/lib/payment"
+	appErr "loverly/src/errors"
+)
+
+type mockFields struct {
+	gatewayMock *mock_gateway.MockInterface
+	paymentMock *mock_payment.MockInterface
+	planMock    *mock_plan.MockInterface
+	subsMock    *mock_subscription.MockInterface
+	couponMock  *mock_coupon.MockInterface
+	boostMock   *mock_boost.MockInterface
+	outboxMock  *mock_outbox.MockInterface
+	auditMock   *mock_audit.MockInterface
+	atomicMock  *mock_atomic.MockAtomicSessionProvider
+	sessionMock *mock_atomic.MockAtomicSession
+}
+
+func newMocks(ctrl *gomock.Controller) mockFields {
+	return mockFields{
+		gatewayMock: mock_gateway.NewMockInterface(ctrl),
+		paymentMock: mock_payment.NewMockInterface(ctrl),
+		planMock:    mock_plan.NewMockInterface(ctrl),
+		subsMock:    mock_subscription.NewMockInterface(ctrl),
+		couponMock:  mock_coupon.NewMockInterface(ctrl),
+		boostMock:   mock_boost.NewMockInterface(ctrl),
+		outboxMock:  mock_outbox.NewMockInterface(ctrl),
+		auditMock:   mock_audit.NewMockInterface(ctrl),
+		atomicMock:  mock_atomic.NewMockAtomicSessionProvider(ctrl),
+		sessionMock: mock_atomic.NewMockAtomicSession(ctrl),
+	}
+}
+
+func TestCheckout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	mocks := newMocks(ctrl)
+
+	mockTime := time.Now()
+	Now = func() time.Time {
+		return mockTime
+	}
+	defer func() {
+		Now = time.Now
+	}()
+
+	type args struct {
+		ctx   context.Context
+		param entity.SubscriptionParam
+	}
+
+	planMock := entity.Plan{ID: 1, Code: entity.UnlimitedPlan, Price: 99000, Currency: "IDR", DurationDays: 30}
+	pending := entity.Payment{ID: 7, UserId: 1, Plan: entity.UnlimitedPlan, Amount: 99000, Currency: "IDR", Provider: gateway.KindMock, Status: entity.PaymentPending}
+	percent := entity.Coupon{ID: 5, Code: "SPRING20", Kind: entity.CouponPercent, Value: 20, MaxRedemptions: sql.NullInt64{Int64: 100, Valid: true}, MaxPerUser: 1, Active: true}
+	free := entity.Coupon{ID: 6, Code: "FREEMONTH", Kind: entity.CouponFixed, Value: 150000, Currency: sql.NullString{String: "IDR", Valid: true}, MaxPerUser: 1, Active: true}
+
+	begin := func(mock mockFields, ctx context.Context) {
+		mock.atomicMock.EXPECT().BeginSession(ctx).Return(atomic.NewAtomicSessionContext(ctx, mock.sessionMock), nil)
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(mock mockFields, arg args)
+		args     args
+		want     entity.PaymentResponse
+		wantErr  error
+	}{
+		{
+			name: "err invalid user",
+			args: args{
+				ctx:   context.Background(),
+				param: entity.SubscriptionParam{Plan: entity.UnlimitedPlan},
+			},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(mock mockFields, arg args) {},
+		},
+		{
+			name: "err unknown plan",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.SubscriptionParam{Plan: "lifetime"},
+			},
+			wantErr: appErr.ErrInvalidPlan,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.planMock.EXPECT().GetByCode(arg.ctx, "lifetime").Return(entity.Plan{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name: "err already subscribed",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.SubscriptionParam{Plan: entity.UnlimitedPlan},
+			},
+			wantErr: appErr.ErrSubscriptionExists,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.planMock.EXPECT().GetByCode(arg.ctx, entity.UnlimitedPlan).Return(planMock, nil)
+				mock.subsMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Subscription{ID: 3, UserId: 1, Plan: entity.VerifiedPlan, Status: entity.SubscriptionCancelled}, nil)
+			},
+		},
+		{
+			name: "err create payment",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.SubscriptionParam{Plan: entity.UnlimitedPlan},
+			},
+			wantErr: assert.AnError,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.planMock.EXPECT().GetByCode(arg.ctx, entity.UnlimitedPlan).Return(planMock, nil)
+				mock.subsMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Subscription{}, sql.ErrNoRows)
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.paymentMock.EXPECT().Create(arg.ctx, entity.Payment{UserId: 1, Plan: entity.UnlimitedPlan, Amount: 99000, Currency: "IDR", Provider: gateway.KindMock, Status: entity.PaymentPending}).Return(int64(0), assert.AnError)
+			},
+		},
+		{
+			name: "err provider, payment failed",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.SubscriptionParam{Plan: entity.UnlimitedPlan},
+			},
+			wantErr: appErr.ErrPaymentProviderFailed,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.planMock.EXPECT().GetByCode(arg.ctx, entity.UnlimitedPlan).Return(planMock, nil)
+				mock.subsMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Subscription{}, sql.ErrNoRows)
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.paymentMock.EXPECT().Create(arg.ctx, gomock.Any()).Return(int64(7), nil)
+				mock.gatewayMock.EXPECT().CreateCheckout(arg.ctx, gateway.Checkout{Reference: "7", Amount: 99000, Currency: "IDR", Description: entity.UnlimitedPlan}).Return(gateway.Session{}, assert.AnError)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+
+				failed := pending
+				failed.Status = entity.PaymentFailed
+				mock.paymentMock.EXPECT().Update(arg.ctx, failed).Return(nil)
+			},
+		},
+		{
+			name: "err coupon used up",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.SubscriptionParam{Plan: entity.UnlimitedPlan, Coupon: "spring20"},
+			},
+			wantErr: appErr.ErrCouponExhausted,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.planMock.EXPECT().GetByCode(arg.ctx, entity.UnlimitedPlan).Return(planMock, nil)
+				mock.subsMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Subscription{}, sql.ErrNoRows)
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				begin(mock, arg.ctx)
+				mock.couponMock.EXPECT().GetByCodeForUpdate(gomock.Any(), "spring20").Return(percent, nil)
+				mock.couponMock.EXPECT().GetUsage(gomock.Any(), int64(5), int64(1), time.Duration(0)).Return(entity.CouponUsage{Total: 100}, nil)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				mock.sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "coupon is taken off the price",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.SubscriptionParam{Plan: entity.UnlimitedPlan, Coupon: "spring20"},
+			},
+			want: entity.PaymentResponse{ID: 7, Plan: entity.UnlimitedPlan, Amount: 79200, Discount: 19800, Currency: "IDR", Status: entity.PaymentPending, CheckoutURL: "http://mockpay/checkouts/chk_1"},
+			mockFunc: func(mock mockFields, arg args) {
+				mock.planMock.EXPECT().GetByCode(arg.ctx, entity.UnlimitedPlan).Return(planMock, nil)
+				mock.subsMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Subscription{}, sql.ErrNoRows)
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				begin(mock, arg.ctx)
+				mock.couponMock.EXPECT().GetByCodeForUpdate(gomock.Any(), "spring20").Return(percent, nil)
+				mock.couponMock.EXPECT().GetUsage(gomock.Any(), int64(5), int64(1), time.Duration(0)).Return(entity.CouponUsage{Total: 99}, nil)
+
+				discounted := entity.Payment{UserId: 1, Plan: entity.UnlimitedPlan, Amount: 79200, CouponId: sql.NullInt64{Int64: 5, Valid: true}, Discount: 19800, Currency: "IDR", Provider: gateway.KindMock, Status: entity.PaymentPending}
+				mock.paymentMock.EXPECT().Create(gomock.Any(), discounted).Return(int64(7), nil)
+				mock.couponMock.EXPECT().CreateRedemption(gomock.Any(), entity.CouponRedemption{CouponId: 5, UserId: 1, PaymentId: 7, Status: entity.CouponRedemptionReserved, Discount: 19800}).Return(int64(1), nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+
+				mock.gatewayMock.EXPECT().CreateCheckout(arg.ctx, gateway.Checkout{Reference: "7", Amount: 79200, Currency: "IDR", Description: entity.UnlimitedPlan}).Return(gateway.Session{ProviderRef: "chk_1", CheckoutURL: "http://mockpay/checkouts/chk_1"}, nil)
+				mock.paymentMock.EXPECT().Update(arg.ctx, gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name: "nothing left to pay starts the subscription",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.SubscriptionParam{Plan: entity.UnlimitedPlan, Coupon: "FREEMONTH"},
+			},
+			want: entity.PaymentResponse{ID: 7, Plan: entity.UnlimitedPlan, Discount: 99000, Currency: "IDR", Status: entity.PaymentPaid, SubscriptionId: 3, PaidAt: &mockTime},
+			mockFunc: func(mock mockFields, arg args) {
+				mock.planMock.EXPECT().GetByCode(arg.ctx, entity.UnlimitedPlan).Return(planMock, nil)
+				mock.subsMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Subscription{}, sql.ErrNoRows)
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				begin(mock, arg.ctx)
+				mock.couponMock.EXPECT().GetByCodeForUpdate(gomock.Any(), "FREEMONTH").Return(free, nil)
+				mock.couponMock.EXPECT().GetUsage(gomock.Any(), int64(6), int64(1), time.Duration(0)).Return(entity.CouponUsage{}, nil)
+				mock.paymentMock.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(7), nil)
+				mock.couponMock.EXPECT().CreateRedemption(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+
+				mock.planMock.EXPECT().GetByCode(gomock.Any(), entity.UnlimitedPlan).Return(planMock, nil)
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(entity.Subscription{}, sql.ErrNoRows)
+				mock.subsMock.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(3), nil)
+				mock.subsMock.EXPECT().RecordChange(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionStarted, int64(1), gomock.Any()).Return(int64(1), nil)
+				mock.auditMock.EXPECT().Record(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.paymentMock.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+				mock.couponMock.EXPECT().SetRedemptionStatus(gomock.Any(), int64(7), entity.CouponRedemptionRedeemed).Return(nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "all goods, an expired subscription doesn't count",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.SubscriptionParam{Plan: entity.UnlimitedPlan},
+			},
+			want: entity.PaymentResponse{ID: 7, Plan: entity.UnlimitedPlan, Amount: 99000, Currency: "IDR", Status: entity.PaymentPending, CheckoutURL: "http://mockpay/checkouts/chk_1"},
+			mockFunc: func(mock mockFields, arg args) {
+				mock.planMock.EXPECT().GetByCode(arg.ctx, entity.UnlimitedPlan).Return(planMock, nil)
+				mock.subsMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Subscription{ID: 3, UserId: 1, Plan: entity.VerifiedPlan, Status: entity.SubscriptionExpired}, nil)
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.paymentMock.EXPECT().Create(arg.ctx, gomock.Any()).Return(int64(7), nil)
+				mock.gatewayMock.EXPECT().CreateCheckout(arg.ctx, gomock.Any()).Return(gateway.Session{ProviderRef: "chk_1", CheckoutURL: "http://mockpay/checkouts/chk_1"}, nil)
+
+				started := pending
+				started.ProviderRef = sql.NullString{String: "chk_1", Valid: true}
+				started.CheckoutURL = "http://mockpay/checkouts/chk_1"
+				mock.paymentMock.EXPECT().Update(arg.ctx, started).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks, tt.args)
+
+			p := Init(log, config.Payment{}, mocks.gatewayMock, mocks.paymentMock, mocks.planMock, mocks.subsMock, mocks.couponMock, mocks.boostMock, mocks.outboxMock, mocks.auditMock, mocks.atomicMock)
+			got, err := p.Checkout(tt.args.ctx, tt.args.param)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	mocks := newMocks(ctrl)
+
+	paidAt := time.Now()
+
+	tests := []struct {
+		name     string
+		mockFunc func(mock mockFields, ctx context.Context)
+		ctx      context.Context
+		want     entity.PaymentResponse
+		wantErr  error
+	}{
+		{
+			name:     "err invalid user",
+			ctx:      context.Background(),
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(mock mockFields, ctx context.Context) {},
+		},
+		{
+			name:    "err not found",
+			ctx:     appcontext.SetUserId(context.Background(), 1),
+			wantErr: appErr.ErrPaymentNotFound,
+			mockFunc: func(mock mockFields, ctx context.Context) {
+				mock.paymentMock.EXPECT().Get(ctx, int64(7), int64(1)).Return(entity.Payment{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name: "paid hides the checkout url",
+			ctx:  appcontext.SetUserId(context.Background(), 1),
+			want: entity.PaymentResponse{ID: 7, Plan: entity.UnlimitedPlan, Amount: 99000, Currency: "IDR", Status: entity.PaymentPaid, SubscriptionId: 3, PaidAt: &paidAt},
+			mockFunc: func(mock mockFields, ctx context.Context) {
+				mock.paymentMock.EXPECT().Get(ctx, int64(7), int64(1)).Return(entity.Payment{
+					ID:             7,
+					UserId:         1,
+					Plan:           entity.UnlimitedPlan,
+					Amount:         99000,
+					Currency:       "IDR",
+					CheckoutURL:    "http://mockpay/checkouts/chk_1",
+					Status:         entity.PaymentPaid,
+					SubscriptionId: sql.NullInt64{Int64: 3, Valid: true},
+					PaidAt:         sql.NullTime{Time: paidAt, Valid: true},
+				}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks, tt.ctx)
+
+			p := Init(log, config.Payment{}, mocks.gatewayMock, mocks.paymentMock, mocks.planMock, mocks.subsMock, mocks.couponMock, mocks.boostMock, mocks.outboxMock, mocks.auditMock, mocks.atomicMock)
+			got, err := p.Get(tt.ctx, 7)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSettle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	mocks := newMocks(ctrl)
+
+	mockTime := time.Now()
+	Now = func() time.Time {
+		return mockTime
+	}
+	defer func() {
+		Now = time.Now
+	}()
+
+	ctx := context.Background()
+	header := http.Header{gateway.MockpaySignatureHeader: []string{"t=1,v1=abc"}}
+	body := []byte(`{"id":"evt_1"}`)
+
+	pending := entity.Payment{ID: 7, UserId: 1, Plan: entity.UnlimitedPlan, Amount: 99000, Currency: "IDR", Provider: gateway.KindMock,
+		ProviderRef: sql.NullString{String: "chk_1", Valid: true}, Status: entity.PaymentPending}
+	paid := gateway.Event{ID: "evt_1", Type: gateway.EventPaid, ProviderRef: "chk_1", Reference: "7", Amount: 99000, Currency: "IDR"}
+	failed := gateway.Event{ID: "evt_2", Type: gateway.EventFailed, ProviderRef: "chk_1", Reference: "7", Amount: 99000, Currency: "IDR"}
+	planMock := entity.Plan{ID: 1, Code: entity.UnlimitedPlan, Price: 99000, Currency: "IDR", DurationDays: 30}
+	trialPlan := entity.Plan{ID: 1, Code: entity.UnlimitedPlan, Price: 99000, Currency: "IDR", DurationDays: 30, Family: entity.UnlimitedPlan, TrialDays: 7}
+	cardOnFile := entity.Payment{ID: 7, UserId: 1, Plan: entity.UnlimitedPlan, TrialDays: 7, Currency: "IDR", Provider: gateway.KindMock,
+		ProviderRef: sql.NullString{String: "chk_1", Valid: true}, Status: entity.PaymentPending}
+
+	begin := func(mock mockFields) {
+		mock.atomicMock.EXPECT().BeginSession(ctx).Return(atomic.NewAtomicSessionContext(ctx, mock.sessionMock), nil)
+	}
+
+	rollback := func(mock mockFields) {
+		log.EXPECT().Error(ctx, gomock.Any())
+		mock.sessionMock.EXPECT().Rollback(ctx).Return(nil)
+	}
+
+	tests := []struct {
+		name     string
+		provider string
+		cfg      config.Payment
+		mockFunc func(mock mockFields)
+		wantErr  error
+	}{
+		{
+			name:     "err unknown provider",
+			provider: "stripe",
+			wantErr:  appErr.ErrUnknownPaymentProvider,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+			},
+		},
+		{
+			name:     "err invalid signature",
+			provider: gateway.KindMock,
+			wantErr:  appErr.ErrInvalidPaymentSignature,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(gateway.Event{}, gateway.ErrInvalidSignature)
+				log.EXPECT().Error(ctx, gomock.Any())
+			},
+		},
+		{
+			name:     "err payment not found",
+			provider: gateway.KindMock,
+			wantErr:  appErr.ErrPaymentNotFound,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(paid, nil)
+				begin(mock)
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(entity.Payment{}, sql.ErrNoRows)
+				rollback(mock)
+			},
+		},
+		{
+			name:     "err reference of another payment",
+			provider: gateway.KindMock,
+			wantErr:  appErr.ErrInvalidPaymentEvent,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(gateway.Event{ID: "evt_1", Type: gateway.EventPaid, ProviderRef: "chk_1", Reference: "8"}, nil)
+				begin(mock)
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(pending, nil)
+				rollback(mock)
+			},
+		},
+		{
+			name:     "redelivered event is acknowledged only",
+			provider: gateway.KindMock,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(paid, nil)
+				begin(mock)
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(pending, nil)
+				mock.paymentMock.EXPECT().RecordEvent(gomock.Any(), entity.PaymentEvent{
+					Provider:  gateway.KindMock,
+					EventId:   "evt_1",
+					Type:      gateway.EventPaid,
+					PaymentId: sql.NullInt64{Int64: 7, Valid: true},
+					Payload:   body,
+				}).Return(int64(0), nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name:     "err amount mismatch",
+			provider: gateway.KindMock,
+			wantErr:  appErr.ErrInvalidPaymentEvent,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mismatch := paid
+				mismatch.Amount = 1000
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(mismatch, nil)
+				begin(mock)
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(pending, nil)
+				mock.paymentMock.EXPECT().RecordEvent(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				rollback(mock)
+			},
+		},
+		{
+			name:     "failed payment",
+			provider: gateway.KindMock,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(failed, nil)
+				begin(mock)
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(pending, nil)
+				mock.paymentMock.EXPECT().RecordEvent(gomock.Any(), gomock.Any()).Return(int64(2), nil)
+				update := pending
+				update.Status = entity.PaymentFailed
+				mock.paymentMock.EXPECT().Update(gomock.Any(), update).Return(nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name:     "failed payment releases its coupon",
+			provider: gateway.KindMock,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(failed, nil)
+				begin(mock)
+				discounted := pending
+				discounted.CouponId = sql.NullInt64{Int64: 5, Valid: true}
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(discounted, nil)
+				mock.paymentMock.EXPECT().RecordEvent(gomock.Any(), gomock.Any()).Return(int64(2), nil)
+				mock.paymentMock.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+				mock.couponMock.EXPECT().SetRedemptionStatus(gomock.Any(), int64(7), entity.CouponRedemptionReleased).Return(nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name:     "late failure keeps the payment paid",
+			provider: gateway.KindMock,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(failed, nil)
+				begin(mock)
+				settled := pending
+				settled.Status = entity.PaymentPaid
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(settled, nil)
+				mock.paymentMock.EXPECT().RecordEvent(gomock.Any(), gomock.Any()).Return(int64(2), nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name:     "err create subscription",
+			provider: gateway.KindMock,
+			wantErr:  assert.AnError,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(paid, nil)
+				begin(mock)
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(pending, nil)
+				mock.paymentMock.EXPECT().RecordEvent(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.planMock.EXPECT().GetByCode(gomock.Any(), entity.UnlimitedPlan).Return(planMock, nil)
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(entity.Subscription{}, sql.ErrNoRows)
+				mock.subsMock.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(0), assert.AnError)
+				rollback(mock)
+			},
+		},
+		{
+			name:     "paid starts the subscription",
+			provider: gateway.KindMock,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(paid, nil)
+				begin(mock)
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(pending, nil)
+				mock.paymentMock.EXPECT().RecordEvent(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.planMock.EXPECT().GetByCode(gomock.Any(), entity.UnlimitedPlan).Return(planMock, nil)
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(entity.Subscription{}, sql.ErrNoRows)
+				mock.subsMock.EXPECT().Create(gomock.Any(), entity.Subscription{UserId: 1, Plan: entity.UnlimitedPlan, StartDate: Now(), EndDate: Now().AddDate(0, 0, 30), PeriodAmount: 99000}).Return(int64(3), nil)
+				mock.subsMock.EXPECT().RecordChange(gomock.Any(), entity.SubscriptionChange{
+					SubscriptionId: 3,
+					UserId:         1,
+					ToPlan:         entity.UnlimitedPlan,
+					Kind:           entity.SubscriptionChangeStart,
+					Amount:         99000,
+					Currency:       "IDR",
+					PaymentId:      sql.NullInt64{Int64: 7, Valid: true},
+					EffectiveAt:    Now(),
+				}).Return(int64(1), nil)
+
+				settled := pending
+				settled.Status = entity.PaymentPaid
+				settled.SubscriptionId = sql.NullInt64{Int64: 3, Valid: true}
+				settled.PaidAt = sql.NullTime{Time: Now(), Valid: true}
+				mock.paymentMock.EXPECT().Update(gomock.Any(), settled).Return(nil)
+				mock.outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionStarted, int64(1), entity.SubscriptionStartedPayload{
+					SubscriptionId: 3,
+					UserId:         1,
+					Plan:           entity.UnlimitedPlan,
+					StartDate:      Now(),
+					EndDate:        Now().AddDate(0, 0, 30),
+				}).Return(int64(1), nil)
+				mock.auditMock.EXPECT().Record(gomock.Any(), entity.AuditLog{
+					ActorId:    sql.NullInt64{Int64: 1, Valid: true},
+					Action:     entity.AuditSubscriptionCreated,
+					TargetType: entity.AuditTargetSubscription,
+					TargetId:   sql.NullInt64{Int64: 3, Valid: true},
+				}, entity.AuditSubscriptionDetails{Plan: entity.UnlimitedPlan, StartDate: Now(), EndDate: Now().AddDate(0, 0, 30), PaymentId: 7}).Return(int64(1), nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name:     "paid upgrade switches the current subscription",
+			provider: gateway.KindMock,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				upgrade := paid
+				upgrade.Amount = 74500
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(upgrade, nil)
+				begin(mock)
+				upgrading := pending
+				upgrading.Amount = 74500
+				upgrading.Credit = 24500
+				upgrading.SubscriptionId = sql.NullInt64{Int64: 3, Valid: true}
+				upgrading.FromPlan = sql.NullString{String: entity.VerifiedPlan, Valid: true}
+				upgrading.PeriodEnd = sql.NullTime{Time: Now().AddDate(0, 0, 15), Valid: true}
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(upgrading, nil)
+				mock.paymentMock.EXPECT().RecordEvent(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.planMock.EXPECT().GetByCode(gomock.Any(), entity.UnlimitedPlan).Return(planMock, nil)
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(entity.Subscription{
+					ID:          3,
+					UserId:      1,
+					Plan:        entity.VerifiedPlan,
+					StartDate:   Now().AddDate(0, 0, -15),
+					EndDate:     Now().AddDate(0, 0, 15),
+					Status:      entity.SubscriptionCancelled,
+					CancelledAt: sql.NullTime{Time: Now(), Valid: true},
+				}, nil)
+				mock.subsMock.EXPECT().Update(gomock.Any(), entity.Subscription{
+					ID:           3,
+					UserId:       1,
+					Plan:         entity.UnlimitedPlan,
+					StartDate:    Now(),
+					EndDate:      Now().AddDate(0, 0, 30),
+					Status:       entity.SubscriptionActive,
+					PeriodAmount: 99000,
+				}).Return(nil)
+				mock.subsMock.EXPECT().RecordChange(gomock.Any(), entity.SubscriptionChange{
+					SubscriptionId: 3,
+					UserId:         1,
+					FromPlan:       sql.NullString{String: entity.VerifiedPlan, Valid: true},
+					ToPlan:         entity.UnlimitedPlan,
+					Kind:           entity.SubscriptionChangeUpgrade,
+					Credit:         24500,
+					Amount:         74500,
+					Currency:       "IDR",
+					PaymentId:      sql.NullInt64{Int64: 7, Valid: true},
+					EffectiveAt:    Now(),
+				}).Return(int64(2), nil)
+				mock.outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionChanged, int64(1), entity.SubscriptionChangedPayload{
+					SubscriptionId: 3,
+					UserId:         1,
+					Kind:           entity.SubscriptionChangeUpgrade,
+					FromPlan:       entity.VerifiedPlan,
+					ToPlan:         entity.UnlimitedPlan,
+					EndDate:        Now().AddDate(0, 0, 30),
+				}).Return(int64(1), nil)
+				mock.auditMock.EXPECT().Record(gomock.Any(), gomock.Any(), entity.AuditSubscriptionDetails{Plan: entity.UnlimitedPlan, StartDate: Now(), EndDate: Now().AddDate(0, 0, 30), PaymentId: 7, FromPlan: entity.VerifiedPlan}).Return(int64(1), nil)
+
+				settled := upgrading
+				settled.Status = entity.PaymentPaid
+				settled.SubscriptionId = sql.NullInt64{Int64: 3, Valid: true}
+				settled.PaidAt = sql.NullTime{Time: Now(), Valid: true}
+				mock.paymentMock.EXPECT().Update(gomock.Any(), settled).Return(nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name:     "paid conversion ends the trial now",
+			provider: gateway.KindMock,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(paid, nil)
+				begin(mock)
+				converting := pending
+				converting.SubscriptionId = sql.NullInt64{Int64: 3, Valid: true}
+				converting.FromPlan = sql.NullString{String: entity.UnlimitedPlan, Valid: true}
+				converting.PeriodEnd = sql.NullTime{Time: Now().AddDate(0, 0, 3), Valid: true}
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(converting, nil)
+				mock.paymentMock.EXPECT().RecordEvent(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.planMock.EXPECT().GetByCode(gomock.Any(), entity.UnlimitedPlan).Return(trialPlan, nil)
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(entity.Subscription{
+					ID:          3,
+					UserId:      1,
+					Plan:        entity.UnlimitedPlan,
+					StartDate:   Now().AddDate(0, 0, -4),
+					EndDate:     Now().AddDate(0, 0, 3),
+					Status:      entity.SubscriptionActive,
+					TrialEndsAt: sql.NullTime{Time: Now().AddDate(0, 0, 3), Valid: true},
+				}, nil)
+				mock.subsMock.EXPECT().Update(gomock.Any(), entity.Subscription{
+					ID:           3,
+					UserId:       1,
+					Plan:         entity.UnlimitedPlan,
+					StartDate:    Now(),
+					EndDate:      Now().AddDate(0, 0, 30),
+					Status:       entity.SubscriptionActive,
+					TrialEndsAt:  sql.NullTime{Time: Now(), Valid: true},
+					PeriodAmount: 99000,
+				}).Return(nil)
+				mock.subsMock.EXPECT().RecordChange(gomock.Any(), entity.SubscriptionChange{
+					SubscriptionId: 3,
+					UserId:         1,
+					FromPlan:       sql.NullString{String: entity.UnlimitedPlan, Valid: true},
+					ToPlan:         entity.UnlimitedPlan,
+					Kind:           entity.SubscriptionChangeConvert,
+					Amount:         99000,
+					Currency:       "IDR",
+					PaymentId:      sql.NullInt64{Int64: 7, Valid: true},
+					EffectiveAt:    Now(),
+				}).Return(int64(2), nil)
+				mock.outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionChanged, int64(1), gomock.Any()).Return(int64(1), nil)
+				mock.auditMock.EXPECT().Record(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(1), nil)
+
+				settled := converting
+				settled.Status = entity.PaymentPaid
+				settled.PaidAt = sql.NullTime{Time: Now(), Valid: true}
+				mock.paymentMock.EXPECT().Update(gomock.Any(), settled).Return(nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name:     "paid upgrade of a period renewed since is due a refund",
+			provider: gateway.KindMock,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				upgrade := paid
+				upgrade.Amount = 74500
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(upgrade, nil)
+				begin(mock)
+				upgrading := pending
+				upgrading.Amount = 74500
+				upgrading.Credit = 24500
+				upgrading.SubscriptionId = sql.NullInt64{Int64: 3, Valid: true}
+				upgrading.FromPlan = sql.NullString{String: entity.VerifiedPlan, Valid: true}
+				upgrading.PeriodEnd = sql.NullTime{Time: Now().AddDate(0, 0, -1), Valid: true}
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(upgrading, nil)
+				mock.paymentMock.EXPECT().RecordEvent(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.planMock.EXPECT().GetByCode(gomock.Any(), entity.UnlimitedPlan).Return(planMock, nil)
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(entity.Subscription{
+					ID:        3,
+					UserId:    1,
+					Plan:      entity.VerifiedPlan,
+					StartDate: Now().AddDate(0, 0, -1),
+					EndDate:   Now().AddDate(0, 0, 29),
+					Status:    entity.SubscriptionActive,
+				}, nil)
+				log.EXPECT().Error(gomock.Any(), gomock.Any())
+
+				settled := upgrading
+				settled.Status = entity.PaymentRefundDue
+				settled.PaidAt = sql.NullTime{Time: Now(), Valid: true}
+				mock.paymentMock.EXPECT().Update(gomock.Any(), settled).Return(nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name:     "paid upgrade of a subscription expired since is due a refund",
+			provider: gateway.KindMock,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				upgrade := paid
+				upgrade.Amount = 74500
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(upgrade, nil)
+				begin(mock)
+				upgrading := pending
+				upgrading.Amount = 74500
+				upgrading.Credit = 24500
+				upgrading.SubscriptionId = sql.NullInt64{Int64: 3, Valid: true}
+				upgrading.FromPlan = sql.NullString{String: entity.VerifiedPlan, Valid: true}
+				upgrading.PeriodEnd = sql.NullTime{Time: Now().AddDate(0, 0, -1), Valid: true}
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(upgrading, nil)
+				mock.paymentMock.EXPECT().RecordEvent(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.planMock.EXPECT().GetByCode(gomock.Any(), entity.UnlimitedPlan).Return(planMock, nil)
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(entity.Subscription{}, sql.ErrNoRows)
+				log.EXPECT().Error(gomock.Any(), gomock.Any())
+
+				settled := upgrading
+				settled.Status = entity.PaymentRefundDue
+				settled.PaidAt = sql.NullTime{Time: Now(), Valid: true}
+				mock.paymentMock.EXPECT().Update(gomock.Any(), settled).Return(nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name:     "paid after the coupon hold lapsed and it was used up is due a refund",
+			provider: gateway.KindMock,
+			cfg:      config.Payment{CouponHold: 30 * time.Minute},
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(paid, nil)
+				begin(mock)
+				lapsed := pending
+				lapsed.CouponId = sql.NullInt64{Int64: 5, Valid: true}
+				lapsed.CreatedAt = sql.NullTime{Time: Now().Add(-time.Hour), Valid: true}
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(lapsed, nil)
+				mock.paymentMock.EXPECT().RecordEvent(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.couponMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(5)).Return(entity.Coupon{ID: 5, MaxRedemptions: sql.NullInt64{Int64: 100, Valid: true}, MaxPerUser: 1}, nil)
+				mock.couponMock.EXPECT().GetUsage(gomock.Any(), int64(5), int64(1), 30*time.Minute).Return(entity.CouponUsage{Total: 100}, nil)
+				log.EXPECT().Error(gomock.Any(), gomock.Any())
+
+				settled := lapsed
+				settled.Status = entity.PaymentRefundDue
+				settled.PaidAt = sql.NullTime{Time: Now(), Valid: true}
+				mock.paymentMock.EXPECT().Update(gomock.Any(), settled).Return(nil)
+				mock.couponMock.EXPECT().SetRedemptionStatus(gomock.Any(), int64(7), entity.CouponRedemptionReleased).Return(nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name:     "paid after the coupon hold lapsed with redemptions left redeems it",
+			provider: gateway.KindMock,
+			cfg:      config.Payment{CouponHold: 30 * time.Minute},
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(paid, nil)
+				begin(mock)
+				lapsed := pending
+				lapsed.CouponId = sql.NullInt64{Int64: 5, Valid: true}
+				lapsed.CreatedAt = sql.NullTime{Time: Now().Add(-time.Hour), Valid: true}
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(lapsed, nil)
+				mock.paymentMock.EXPECT().RecordEvent(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.couponMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(5)).Return(entity.Coupon{ID: 5, MaxRedemptions: sql.NullInt64{Int64: 100, Valid: true}, MaxPerUser: 1}, nil)
+				mock.couponMock.EXPECT().GetUsage(gomock.Any(), int64(5), int64(1), 30*time.Minute).Return(entity.CouponUsage{Total: 99}, nil)
+				mock.planMock.EXPECT().GetByCode(gomock.Any(), entity.UnlimitedPlan).Return(planMock, nil)
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(entity.Subscription{}, sql.ErrNoRows)
+				mock.subsMock.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(3), nil)
+				mock.subsMock.EXPECT().RecordChange(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionStarted, int64(1), gomock.Any()).Return(int64(1), nil)
+				mock.auditMock.EXPECT().Record(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.paymentMock.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+				mock.couponMock.EXPECT().SetRedemptionStatus(gomock.Any(), int64(7), entity.CouponRedemptionRedeemed).Return(nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name:     "paid card on file starts the trial",
+			provider: gateway.KindMock,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(gateway.Event{ID: "evt_1", Type: gateway.EventPaid, ProviderRef: "chk_1", Reference: "7", Currency: "IDR", PaymentMethod: "pm_1"}, nil)
+				begin(mock)
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(cardOnFile, nil)
+				mock.paymentMock.EXPECT().RecordEvent(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.planMock.EXPECT().GetByCode(gomock.Any(), entity.UnlimitedPlan).Return(trialPlan, nil)
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(entity.Subscription{}, sql.ErrNoRows)
+				mock.subsMock.EXPECT().ClaimTrial(gomock.Any(), entity.Trial{
+					UserId:    1,
+					Family:    entity.UnlimitedPlan,
+					Plan:      entity.UnlimitedPlan,
+					PaymentId: sql.NullInt64{Int64: 7, Valid: true},
+					StartedAt: Now(),
+					EndsAt:    Now().AddDate(0, 0, 7),
+				}).Return(int64(1), nil)
+				mock.subsMock.EXPECT().Create(gomock.Any(), entity.Subscription{
+					UserId:        1,
+					Plan:          entity.UnlimitedPlan,
+					StartDate:     Now(),
+					EndDate:       Now().AddDate(0, 0, 7),
+					TrialEndsAt:   sql.NullTime{Time: Now().AddDate(0, 0, 7), Valid: true},
+					TrialConverts: true,
+				}).Return(int64(3), nil)
+				mock.subsMock.EXPECT().RecordChange(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionStarted, int64(1), gomock.Any()).Return(int64(1), nil)
+				mock.auditMock.EXPECT().Record(gomock.Any(), gomock.Any(), entity.AuditSubscriptionDetails{Plan: entity.UnlimitedPlan, StartDate: Now(), EndDate: Now().AddDate(0, 0, 7), PaymentId: 7, Trial: true}).Return(int64(1), nil)
+
+				settled := cardOnFile
+				settled.Status = entity.PaymentPaid
+				settled.SubscriptionId = sql.NullInt64{Int64: 3, Valid: true}
+				settled.PaidAt = sql.NullTime{Time: Now(), Valid: true}
+				mock.paymentMock.EXPECT().Update(gomock.Any(), settled).Return(nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name:     "trial taken since the checkout fails the payment",
+			provider: gateway.KindMock,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(gateway.Event{ID: "evt_1", Type: gateway.EventPaid, ProviderRef: "chk_1", Reference: "7", Currency: "IDR", PaymentMethod: "pm_1"}, nil)
+				begin(mock)
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(cardOnFile, nil)
+				mock.paymentMock.EXPECT().RecordEvent(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.planMock.EXPECT().GetByCode(gomock.Any(), entity.UnlimitedPlan).Return(trialPlan, nil)
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(entity.Subscription{}, sql.ErrNoRows)
+				mock.subsMock.EXPECT().ClaimTrial(gomock.Any(), gomock.Any()).Return(int64(0), nil)
+				log.EXPECT().Error(gomock.Any(), gomock.Any())
+
+				settled := cardOnFile
+				settled.Status = entity.PaymentFailed
+				mock.paymentMock.EXPECT().Update(gomock.Any(), settled).Return(nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name:     "trial checkout without a verified card fails the payment",
+			provider: gateway.KindMock,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(gateway.Event{ID: "evt_1", Type: gateway.EventPaid, ProviderRef: "chk_1", Reference: "7", Currency: "IDR"}, nil)
+				begin(mock)
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(cardOnFile, nil)
+				mock.paymentMock.EXPECT().RecordEvent(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				log.EXPECT().Error(gomock.Any(), gomock.Any())
+
+				settled := cardOnFile
+				settled.Status = entity.PaymentFailed
+				mock.paymentMock.EXPECT().Update(gomock.Any(), settled).Return(nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name:     "paid pack of boosts is credited",
+			provider: gateway.KindMock,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.gatewayMock.EXPECT().ParseWebhook(header, body).Return(gateway.Event{ID: "evt_1", Type: gateway.EventPaid, ProviderRef: "chk_1", Reference: "7", Amount: 45000, Currency: "IDR"}, nil)
+				begin(mock)
+				pack := entity.Payment{ID: 7, UserId: 1, Boosts: 3, Amount: 45000, Currency: "IDR", Provider: gateway.KindMock,
+					ProviderRef: sql.NullString{String: "chk_1", Valid: true}, Status: entity.PaymentPending}
+				mock.paymentMock.EXPECT().GetByProviderRefForUpdate(gomock.Any(), gateway.KindMock, "chk_1").Return(pack, nil)
+				mock.paymentMock.EXPECT().RecordEvent(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.boostMock.EXPECT().CreateCredit(gomock.Any(), entity.BoostCredit{UserId: 1, Amount: 3, Reason: "payment:7", PaymentId: sql.NullInt64{Int64: 7, Valid: true}}).Return(int64(1), nil)
+
+				settled := pack
+				settled.Status = entity.PaymentPaid
+				settled.PaidAt = sql.NullTime{Time: Now(), Valid: true}
+				mock.paymentMock.EXPECT().Update(gomock.Any(), settled).Return(nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks)
+
+			p := Init(log, tt.cfg, mocks.gatewayMock, mocks.paymentMock, mocks.planMock, mocks.subsMock, mocks.couponMock, mocks.boostMock, mocks.outboxMock, mocks.auditMock, mocks.atomicMock)
+			err := p.Settle(ctx, tt.provider, header, body)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestRefund(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	mocks := newMocks(ctrl)
+
+	ctx := context.Background()
+
+	due := entity.Payment{ID: 7, UserId: 1, Plan: entity.UnlimitedPlan, Amount: 74500, Currency: "IDR", Provider: gateway.KindMock,
+		ProviderRef: sql.NullString{String: "chk_1", Valid: true}, Status: entity.PaymentRefundDue}
+	refund := gateway.Refund{ProviderRef: "chk_1", Reference: "refund:7", Amount: 74500, Currency: "IDR", Reason: entity.UnlimitedPlan}
+
+	begin := func(mock mockFields) {
+		mock.atomicMock.EXPECT().BeginSession(ctx).Return(atomic.NewAtomicSessionContext(ctx, mock.sessionMock), nil)
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(mock mockFields)
+		want     int
+		wantErr  error
+	}{
+		{
+			name:    "err get refund due",
+			wantErr: assert.AnError,
+			mockFunc: func(mock mockFields) {
+				mock.paymentMock.EXPECT().GetRefundDue(ctx, 10).Return(nil, assert.AnError)
+			},
+		},
+		{
+			name: "refunds and records the refund",
+			want: 1,
+			mockFunc: func(mock mockFields) {
+				mock.paymentMock.EXPECT().GetRefundDue(ctx, 10).Return([]entity.Payment{due}, nil)
+				mock.gatewayMock.EXPECT().Refund(ctx, refund).Return("ref_1", nil)
+				begin(mock)
+				mock.paymentMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(7)).Return(due, nil)
+
+				refunded := due
+				refunded.Status = entity.PaymentRefunded
+				refunded.RefundRef = sql.NullString{String: "ref_1", Valid: true}
+				mock.paymentMock.EXPECT().Update(gomock.Any(), refunded).Return(nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name: "refunded by another worker since it was read",
+			mockFunc: func(mock mockFields) {
+				mock.paymentMock.EXPECT().GetRefundDue(ctx, 10).Return([]entity.Payment{due}, nil)
+				mock.gatewayMock.EXPECT().Refund(ctx, refund).Return("ref_1", nil)
+				begin(mock)
+				refunded := due
+				refunded.Status = entity.PaymentRefunded
+				mock.paymentMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(7)).Return(refunded, nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name:    "err refund keeps it due and goes on with the others",
+			want:    1,
+			wantErr: assert.AnError,
+			mockFunc: func(mock mockFields) {
+				other := due
+				other.ID = 8
+				other.ProviderRef = sql.NullString{String: "chk_2", Valid: true}
+				mock.paymentMock.EXPECT().GetRefundDue(ctx, 10).Return([]entity.Payment{due, other}, nil)
+				mock.gatewayMock.EXPECT().Refund(ctx, refund).Return("", assert.AnError)
+				log.EXPECT().Error(ctx, gomock.Any())
+				mock.gatewayMock.EXPECT().Refund(ctx, gateway.Refund{ProviderRef: "chk_2", Reference: "refund:8", Amount: 74500, Currency: "IDR", Reason: entity.UnlimitedPlan}).Return("ref_2", nil)
+				begin(mock)
+				mock.paymentMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(8)).Return(other, nil)
+				mock.paymentMock.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks)
+
+			p := Init(log, config.Payment{}, mocks.gatewayMock, mocks.paymentMock, mocks.planMock, mocks.subsMock, mocks.couponMock, mocks.boostMock, mocks.outboxMock, mocks.auditMock, mocks.atomicMock)
+			got, err := p.Refund(ctx, 10)
+			assert.Equal(t, tt.want, got)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestChangePlan(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	mocks := newMocks(ctrl)
+
+	mockTime := time.Now()
+	Now = func() time.Time {
+		return mockTime
+	}
+	defer func() {
+		Now = time.Now
+	}()
+
+	ctx := appcontext.SetUserId(context.Background(), 1)
+
+	unlimited := entity.Plan{ID: 1, Code: entity.UnlimitedPlan, Price: 99000, Currency: "IDR", DurationDays: 30}
+	verified := entity.Plan{ID: 2, Code: entity.VerifiedPlan, Price: 49000, Currency: "IDR", DurationDays: 30}
+	weekly := entity.Plan{ID: 3, Code: "weekly", Price: 30000, Currency: "IDR", DurationDays: 7}
+
+	// half of the period is left, it was paid at the price of the plan
+	paid := map[string]int64{entity.UnlimitedPlan: 99000, entity.VerifiedPlan: 49000}
+	current := func(plan string) entity.Subscription {
+		return entity.Subscription{
+			ID:           3,
+			UserId:       1,
+			Plan:         plan,
+			StartDate:    Now().AddDate(0, 0, -15),
+			EndDate:      Now().AddDate(0, 0, 15),
+			Status:       entity.SubscriptionActive,
+			PeriodAmount: paid[plan],
+		}
+	}
+
+	changing := func(pay entity.Payment, plan string) entity.Payment {
+		pay.SubscriptionId = sql.NullInt64{Int64: 3, Valid: true}
+		pay.FromPlan = sql.NullString{String: plan, Valid: true}
+		pay.PeriodEnd = sql.NullTime{Time: Now().AddDate(0, 0, 15), Valid: true}
+		return pay
+	}
+
+	begin := func(mock mockFields) {
+		mock.atomicMock.EXPECT().BeginSession(ctx).Return(atomic.NewAtomicSessionContext(ctx, mock.sessionMock), nil)
+	}
+
+	rollback := func(mock mockFields) {
+		log.EXPECT().Error(ctx, gomock.Any())
+		mock.sessionMock.EXPECT().Rollback(ctx).Return(nil)
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		plan     string
+		mockFunc func(mock mockFields)
+		want     entity.PlanChangeResponse
+		wantErr  error
+	}{
+		{
+			name:     "err invalid user",
+			ctx:      context.Background(),
+			plan:     entity.UnlimitedPlan,
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(mock mockFields) {},
+		},
+		{
+			name:    "err unknown plan",
+			ctx:     ctx,
+			plan:    "lifetime",
+			wantErr: appErr.ErrInvalidPlan,
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, "lifetime").Return(entity.Plan{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name:    "err no subscription",
+			ctx:     ctx,
+			plan:    entity.UnlimitedPlan,
+			wantErr: appErr.ErrSubscriptionNotFound,
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(unlimited, nil)
+				begin(mock)
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(entity.Subscription{}, sql.ErrNoRows)
+				rollback(mock)
+			},
+		},
+		{
+			name:    "err same plan",
+			ctx:     ctx,
+			plan:    entity.UnlimitedPlan,
+			wantErr: appErr.ErrSubscriptionPlanUnchanged,
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(unlimited, nil)
+				begin(mock)
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(current(entity.UnlimitedPlan), nil)
+				rollback(mock)
+			},
+		},
+		{
+			name: "same plan calls off the downgrade",
+			ctx:  ctx,
+			plan: entity.UnlimitedPlan,
+			want: entity.PlanChangeResponse{Kind: entity.SubscriptionChangeKeep, FromPlan: entity.UnlimitedPlan, ToPlan: entity.UnlimitedPlan, Currency: "IDR", EffectiveAt: Now()},
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(unlimited, nil)
+				begin(mock)
+				sub := current(entity.UnlimitedPlan)
+				sub.PendingPlan = sql.NullString{String: entity.VerifiedPlan, Valid: true}
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(sub, nil)
+				mock.subsMock.EXPECT().Update(gomock.Any(), current(entity.UnlimitedPlan)).Return(nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name:    "err downgrade of a cancelled subscription",
+			ctx:     ctx,
+			plan:    entity.VerifiedPlan,
+			wantErr: appErr.ErrSubscriptionAlreadyCancelled,
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, entity.VerifiedPlan).Return(verified, nil)
+				begin(mock)
+				sub := current(entity.UnlimitedPlan)
+				sub.Status = entity.SubscriptionCancelled
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(sub, nil)
+				mock.planMock.EXPECT().GetByCode(gomock.Any(), entity.UnlimitedPlan).Return(unlimited, nil)
+				rollback(mock)
+			},
+		},
+		{
+			name: "downgrade waits for the end of the period",
+			ctx:  ctx,
+			plan: entity.VerifiedPlan,
+			want: entity.PlanChangeResponse{Kind: entity.SubscriptionChangeDowngrade, FromPlan: entity.UnlimitedPlan, ToPlan: entity.VerifiedPlan, Amount: 49000, Currency: "IDR", EffectiveAt: Now().AddDate(0, 0, 15)},
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, entity.VerifiedPlan).Return(verified, nil)
+				begin(mock)
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(current(entity.UnlimitedPlan), nil)
+				mock.planMock.EXPECT().GetByCode(gomock.Any(), entity.UnlimitedPlan).Return(unlimited, nil)
+				sub := current(entity.UnlimitedPlan)
+				sub.PendingPlan = sql.NullString{String: entity.VerifiedPlan, Valid: true}
+				mock.subsMock.EXPECT().Update(gomock.Any(), sub).Return(nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name: "upgrade is paid less the credit",
+			ctx:  ctx,
+			plan: entity.UnlimitedPlan,
+			want: entity.PlanChangeResponse{
+				Kind:        entity.SubscriptionChangeUpgrade,
+				FromPlan:    entity.VerifiedPlan,
+				ToPlan:      entity.UnlimitedPlan,
+				Credit:      24500,
+				Amount:      74500,
+				Currency:    "IDR",
+				EffectiveAt: Now(),
+				Payment:     &entity.PaymentResponse{ID: 7, Plan: entity.UnlimitedPlan, Amount: 74500, Credit: 24500, Currency: "IDR", Status: entity.PaymentPending, CheckoutURL: "http://mockpay/checkouts/chk_1", SubscriptionId: 3},
+			},
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(unlimited, nil)
+				begin(mock)
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(current(entity.VerifiedPlan), nil)
+				mock.planMock.EXPECT().GetByCode(gomock.Any(), entity.VerifiedPlan).Return(verified, nil)
+				mock.paymentMock.EXPECT().FailPendingChange(gomock.Any(), int64(3)).Return(nil)
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				pending := changing(entity.Payment{UserId: 1, Plan: entity.UnlimitedPlan, Amount: 74500, Credit: 24500, Currency: "IDR", Provider: gateway.KindMock, Status: entity.PaymentPending}, entity.VerifiedPlan)
+				mock.paymentMock.EXPECT().Create(gomock.Any(), pending).Return(int64(7), nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+
+				mock.gatewayMock.EXPECT().CreateCheckout(ctx, gateway.Checkout{Reference: "7", Amount: 74500, Currency: "IDR", Description: entity.UnlimitedPlan}).Return(gateway.Session{ProviderRef: "chk_1", CheckoutURL: "http://mockpay/checkouts/chk_1"}, nil)
+				pending.ID = 7
+				pending.ProviderRef = sql.NullString{String: "chk_1", Valid: true}
+				pending.CheckoutURL = "http://mockpay/checkouts/chk_1"
+				mock.paymentMock.EXPECT().Update(ctx, pending).Return(nil)
+			},
+		},
+		{
+			name: "upgrade credits what was paid, not the price of the plan",
+			ctx:  ctx,
+			plan: entity.UnlimitedPlan,
+			want: entity.PlanChangeResponse{
+				Kind:        entity.SubscriptionChangeUpgrade,
+				FromPlan:    entity.VerifiedPlan,
+				ToPlan:      entity.UnlimitedPlan,
+				Credit:      19500,
+				Amount:      79500,
+				Currency:    "IDR",
+				EffectiveAt: Now(),
+				Payment:     &entity.PaymentResponse{ID: 7, Plan: entity.UnlimitedPlan, Amount: 79500, Credit: 19500, Currency: "IDR", Status: entity.PaymentPending, CheckoutURL: "http://mockpay/checkouts/chk_1", SubscriptionId: 3},
+			},
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(unlimited, nil)
+				begin(mock)
+				discounted := current(entity.VerifiedPlan)
+				discounted.PeriodAmount = 39000
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(discounted, nil)
+				mock.planMock.EXPECT().GetByCode(gomock.Any(), entity.VerifiedPlan).Return(verified, nil)
+				mock.paymentMock.EXPECT().FailPendingChange(gomock.Any(), int64(3)).Return(nil)
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.paymentMock.EXPECT().Create(gomock.Any(), changing(entity.Payment{UserId: 1, Plan: entity.UnlimitedPlan, Amount: 79500, Credit: 19500, Currency: "IDR", Provider: gateway.KindMock, Status: entity.PaymentPending}, entity.VerifiedPlan)).Return(int64(7), nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+
+				mock.gatewayMock.EXPECT().CreateCheckout(ctx, gomock.Any()).Return(gateway.Session{ProviderRef: "chk_1", CheckoutURL: "http://mockpay/checkouts/chk_1"}, nil)
+				mock.paymentMock.EXPECT().Update(ctx, gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name: "upgrade during a trial has no credit",
+			ctx:  ctx,
+			plan: entity.UnlimitedPlan,
+			want: entity.PlanChangeResponse{
+				Kind:        entity.SubscriptionChangeUpgrade,
+				FromPlan:    entity.VerifiedPlan,
+				ToPlan:      entity.UnlimitedPlan,
+				Amount:      99000,
+				Currency:    "IDR",
+				EffectiveAt: Now(),
+				Payment:     &entity.PaymentResponse{ID: 7, Plan: entity.UnlimitedPlan, Amount: 99000, Currency: "IDR", Status: entity.PaymentPending, CheckoutURL: "http://mockpay/checkouts/chk_1", SubscriptionId: 3},
+			},
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(unlimited, nil)
+				begin(mock)
+				trial := current(entity.VerifiedPlan)
+				trial.TrialEndsAt = sql.NullTime{Time: trial.EndDate, Valid: true}
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(trial, nil)
+				mock.planMock.EXPECT().GetByCode(gomock.Any(), entity.VerifiedPlan).Return(verified, nil)
+				mock.paymentMock.EXPECT().FailPendingChange(gomock.Any(), int64(3)).Return(nil)
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				pending := entity.Payment{UserId: 1, Plan: entity.UnlimitedPlan, Amount: 99000, Currency: "IDR", Provider: gateway.KindMock, Status: entity.PaymentPending}
+				pending.SubscriptionId = sql.NullInt64{Int64: 3, Valid: true}
+				pending.FromPlan = sql.NullString{String: entity.VerifiedPlan, Valid: true}
+				pending.PeriodEnd = sql.NullTime{Time: trial.EndDate, Valid: true}
+				mock.paymentMock.EXPECT().Create(gomock.Any(), pending).Return(int64(7), nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+
+				mock.gatewayMock.EXPECT().CreateCheckout(ctx, gomock.Any()).Return(gateway.Session{ProviderRef: "chk_1", CheckoutURL: "http://mockpay/checkouts/chk_1"}, nil)
+				mock.paymentMock.EXPECT().Update(ctx, gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name: "same plan during a trial converts it now",
+			ctx:  ctx,
+			plan: entity.UnlimitedPlan,
+			want: entity.PlanChangeResponse{
+				Kind:        entity.SubscriptionChangeConvert,
+				FromPlan:    entity.UnlimitedPlan,
+				ToPlan:      entity.UnlimitedPlan,
+				Amount:      99000,
+				Currency:    "IDR",
+				EffectiveAt: Now(),
+				Payment:     &entity.PaymentResponse{ID: 7, Plan: entity.UnlimitedPlan, Amount: 99000, Currency: "IDR", Status: entity.PaymentPending, CheckoutURL: "http://mockpay/checkouts/chk_1", SubscriptionId: 3},
+			},
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(unlimited, nil)
+				begin(mock)
+				trial := current(entity.UnlimitedPlan)
+				trial.PeriodAmount = 0
+				trial.TrialEndsAt = sql.NullTime{Time: trial.EndDate, Valid: true}
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(trial, nil)
+				mock.paymentMock.EXPECT().FailPendingChange(gomock.Any(), int64(3)).Return(nil)
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				pending := changing(entity.Payment{UserId: 1, Plan: entity.UnlimitedPlan, Amount: 99000, Currency: "IDR", Provider: gateway.KindMock, Status: entity.PaymentPending}, entity.UnlimitedPlan)
+				mock.paymentMock.EXPECT().Create(gomock.Any(), pending).Return(int64(7), nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+
+				mock.gatewayMock.EXPECT().CreateCheckout(ctx, gateway.Checkout{Reference: "7", Amount: 99000, Currency: "IDR", Description: entity.UnlimitedPlan}).Return(gateway.Session{ProviderRef: "chk_1", CheckoutURL: "http://mockpay/checkouts/chk_1"}, nil)
+				mock.paymentMock.EXPECT().Update(ctx, gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name: "upgrade covered by the credit applies right away",
+			ctx:  ctx,
+			plan: "weekly",
+			want: entity.PlanChangeResponse{Kind: entity.SubscriptionChangeUpgrade, FromPlan: entity.UnlimitedPlan, ToPlan: "weekly", Credit: 49500, Currency: "IDR", EffectiveAt: Now()},
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, "weekly").Return(weekly, nil)
+				begin(mock)
+				mock.subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(current(entity.UnlimitedPlan), nil)
+				mock.planMock.EXPECT().GetByCode(gomock.Any(), entity.UnlimitedPlan).Return(unlimited, nil)
+				mock.paymentMock.EXPECT().FailPendingChange(gomock.Any(), int64(3)).Return(nil)
+				mock.subsMock.EXPECT().Update(gomock.Any(), entity.Subscription{
+					ID:           3,
+					UserId:       1,
+					Plan:         "weekly",
+					StartDate:    Now(),
+					EndDate:      Now().AddDate(0, 0, 7),
+					Status:       entity.SubscriptionActive,
+					PeriodAmount: 30000,
+				}).Return(nil)
+				mock.subsMock.EXPECT().RecordChange(gomock.Any(), entity.SubscriptionChange{
+					SubscriptionId: 3,
+					UserId:         1,
+					FromPlan:       sql.NullString{String: entity.UnlimitedPlan, Valid: true},
+					ToPlan:         "weekly",
+					Kind:           entity.SubscriptionChangeUpgrade,
+					Credit:         49500,
+					Currency:       "IDR",
+					EffectiveAt:    Now(),
+				}).Return(int64(2), nil)
+				mock.outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionChanged, int64(1), gomock.Any()).Return(int64(1), nil)
+				mock.auditMock.EXPECT().Record(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks)
+
+			p := Init(log, config.Payment{}, mocks.gatewayMock, mocks.paymentMock, mocks.planMock, mocks.subsMock, mocks.couponMock, mocks.boostMock, mocks.outboxMock, mocks.auditMock, mocks.atomicMock)
+			got, err := p.ChangePlan(tt.ctx, entity.SubscriptionParam{Plan: tt.plan})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestQuote(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	mocks := newMocks(ctrl)
+
+	mockTime := time.Now()
+	Now = func() time.Time {
+		return mockTime
+	}
+	defer func() {
+		Now = time.Now
+	}()
+
+	ctx := appcontext.SetUserId(context.Background(), 1)
+	cfg := config.Payment{CouponHold: 30 * time.Minute}
+	planMock := entity.Plan{ID: 1, Code: entity.UnlimitedPlan, Price: 99000, Currency: "IDR", DurationDays: 30}
+	extraDays := entity.Coupon{ID: 5, Code: "WEEKFREE", Kind: entity.CouponExtraDays, Value: 7, Plans: []byte(`["unlimited"]`), MaxPerUser: 1, Active: true}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		param    entity.SubscriptionParam
+		mockFunc func(mock mockFields)
+		want     entity.QuoteResponse
+		wantErr  error
+	}{
+		{
+			name:     "err invalid user",
+			ctx:      context.Background(),
+			param:    entity.SubscriptionParam{Plan: entity.UnlimitedPlan, Coupon: "WEEKFREE"},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(mock mockFields) {},
+		},
+		{
+			name:    "err unknown coupon",
+			ctx:     ctx,
+			param:   entity.SubscriptionParam{Plan: entity.UnlimitedPlan, Coupon: "NOPE"},
+			wantErr: appErr.ErrCouponNotFound,
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(planMock, nil)
+				mock.couponMock.EXPECT().GetByCode(ctx, "NOPE").Return(entity.Coupon{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name:    "err campaign over",
+			ctx:     ctx,
+			param:   entity.SubscriptionParam{Plan: entity.UnlimitedPlan, Coupon: "WEEKFREE"},
+			wantErr: appErr.ErrCouponExpired,
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(planMock, nil)
+				over := extraDays
+				over.EndsAt = sql.NullTime{Time: mockTime, Valid: true}
+				mock.couponMock.EXPECT().GetByCode(ctx, "WEEKFREE").Return(over, nil)
+			},
+		},
+		{
+			name:    "err plan not covered",
+			ctx:     ctx,
+			param:   entity.SubscriptionParam{Plan: entity.VerifiedPlan, Coupon: "WEEKFREE"},
+			wantErr: appErr.ErrCouponNotApplicable,
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, entity.VerifiedPlan).Return(entity.Plan{ID: 2, Code: entity.VerifiedPlan, Price: 49000, Currency: "IDR", DurationDays: 30}, nil)
+				mock.couponMock.EXPECT().GetByCode(ctx, "WEEKFREE").Return(extraDays, nil)
+			},
+		},
+		{
+			name:    "err already redeemed by the user",
+			ctx:     ctx,
+			param:   entity.SubscriptionParam{Plan: entity.UnlimitedPlan, Coupon: "WEEKFREE"},
+			wantErr: appErr.ErrCouponAlreadyRedeemed,
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(planMock, nil)
+				mock.couponMock.EXPECT().GetByCode(ctx, "WEEKFREE").Return(extraDays, nil)
+				mock.couponMock.EXPECT().GetUsage(ctx, int64(5), int64(1), 30*time.Minute).Return(entity.CouponUsage{Total: 3, ByUser: 1}, nil)
+			},
+		},
+		{
+			name:  "without a coupon is the plan price",
+			ctx:   ctx,
+			param: entity.SubscriptionParam{Plan: entity.UnlimitedPlan},
+			want:  entity.QuoteResponse{Plan: entity.UnlimitedPlan, Price: 99000, Amount: 99000, Currency: "IDR", DurationDays: 30},
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(planMock, nil)
+			},
+		},
+		{
+			name:  "extra days at full price",
+			ctx:   ctx,
+			param: entity.SubscriptionParam{Plan: entity.UnlimitedPlan, Coupon: "weekfree"},
+			want:  entity.QuoteResponse{Plan: entity.UnlimitedPlan, Coupon: "WEEKFREE", Price: 99000, Amount: 99000, Currency: "IDR", DurationDays: 30, ExtraDays: 7},
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(planMock, nil)
+				mock.couponMock.EXPECT().GetByCode(ctx, "weekfree").Return(extraDays, nil)
+				mock.couponMock.EXPECT().GetUsage(ctx, int64(5), int64(1), 30*time.Minute).Return(entity.CouponUsage{Total: 3}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks)
+
+			p := Init(log, cfg, mocks.gatewayMock, mocks.paymentMock, mocks.planMock, mocks.subsMock, mocks.couponMock, mocks.boostMock, mocks.outboxMock, mocks.auditMock, mocks.atomicMock)
+			got, err := p.Quote(tt.ctx, tt.param)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStartTrial(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	mocks := newMocks(ctrl)
+
+	mockTime := time.Now()
+	Now = func() time.Time {
+		return mockTime
+	}
+	defer func() {
+		Now = time.Now
+	}()
+
+	ctx := appcontext.SetUserId(context.Background(), 1)
+	trialPlan := entity.Plan{ID: 1, Code: entity.UnlimitedPlan, Price: 99000, Currency: "IDR", DurationDays: 30, Family: entity.UnlimitedPlan, TrialDays: 7}
+	endsAt := mockTime.AddDate(0, 0, 7)
+
+	begin := func(mock mockFields) {
+		mock.atomicMock.EXPECT().BeginSession(ctx).Return(atomic.NewAtomicSessionContext(ctx, mock.sessionMock), nil)
+	}
+
+	eligible := func(mock mockFields) {
+		mock.planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(trialPlan, nil)
+		mock.subsMock.EXPECT().HasTrial(ctx, int64(1), entity.UnlimitedPlan).Return(false, nil)
+		mock.subsMock.EXPECT().GetByUserId(ctx, int64(1)).Return(entity.Subscription{}, sql.ErrNoRows)
+	}
+
+	tests := []struct {
+		name     string
+		cfg      config.Payment
+		ctx      context.Context
+		param    entity.SubscriptionParam
+		mockFunc func(mock mockFields)
+		want     entity.TrialResponse
+		wantErr  error
+	}{
+		{
+			name:     "err invalid user",
+			ctx:      context.Background(),
+			param:    entity.SubscriptionParam{Plan: entity.UnlimitedPlan},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(mock mockFields) {},
+		},
+		{
+			name:     "err coupon",
+			ctx:      ctx,
+			param:    entity.SubscriptionParam{Plan: entity.UnlimitedPlan, Coupon: "SPRING20"},
+			wantErr:  appErr.ErrCouponNotApplicable,
+			mockFunc: func(mock mockFields) {},
+		},
+		{
+			name:    "err plan without a trial",
+			ctx:     ctx,
+			param:   entity.SubscriptionParam{Plan: entity.VerifiedPlan},
+			wantErr: appErr.ErrTrialNotOffered,
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, entity.VerifiedPlan).Return(entity.Plan{ID: 2, Code: entity.VerifiedPlan, Family: entity.VerifiedPlan}, nil)
+			},
+		},
+		{
+			name:    "err family already tried",
+			ctx:     ctx,
+			param:   entity.SubscriptionParam{Plan: entity.UnlimitedPlan},
+			wantErr: appErr.ErrTrialUsed,
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(trialPlan, nil)
+				mock.subsMock.EXPECT().HasTrial(ctx, int64(1), entity.UnlimitedPlan).Return(true, nil)
+			},
+		},
+		{
+			name:    "err already subscribed",
+			ctx:     ctx,
+			param:   entity.SubscriptionParam{Plan: entity.UnlimitedPlan},
+			wantErr: appErr.ErrSubscriptionExists,
+			mockFunc: func(mock mockFields) {
+				mock.planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(trialPlan, nil)
+				mock.subsMock.EXPECT().HasTrial(ctx, int64(1), entity.UnlimitedPlan).Return(false, nil)
+				mock.subsMock.EXPECT().GetByUserId(ctx, int64(1)).Return(entity.Subscription{ID: 3, Status: entity.SubscriptionActive}, nil)
+			},
+		},
+		{
+			name:    "err tried concurrently",
+			ctx:     ctx,
+			param:   entity.SubscriptionParam{Plan: entity.UnlimitedPlan},
+			wantErr: appErr.ErrTrialUsed,
+			mockFunc: func(mock mockFields) {
+				eligible(mock)
+				begin(mock)
+				mock.subsMock.EXPECT().ClaimTrial(gomock.Any(), gomock.Any()).Return(int64(0), nil)
+				log.EXPECT().Error(ctx, gomock.Any())
+				mock.sessionMock.EXPECT().Rollback(ctx).Return(nil)
+			},
+		},
+		{
+			name:  "without a card the trial starts right away",
+			ctx:   ctx,
+			param: entity.SubscriptionParam{Plan: entity.UnlimitedPlan},
+			want:  entity.TrialResponse{Plan: entity.UnlimitedPlan, TrialDays: 7, SubscriptionId: 3, EndsAt: &endsAt},
+			mockFunc: func(mock mockFields) {
+				eligible(mock)
+				begin(mock)
+				mock.subsMock.EXPECT().ClaimTrial(gomock.Any(), entity.Trial{UserId: 1, Family: entity.UnlimitedPlan, Plan: entity.UnlimitedPlan, StartedAt: Now(), EndsAt: endsAt}).Return(int64(1), nil)
+				mock.subsMock.EXPECT().Create(gomock.Any(), entity.Subscription{
+					UserId:      1,
+					Plan:        entity.UnlimitedPlan,
+					StartDate:   Now(),
+					EndDate:     endsAt,
+					TrialEndsAt: sql.NullTime{Time: endsAt, Valid: true},
+				}).Return(int64(3), nil)
+				mock.subsMock.EXPECT().RecordChange(gomock.Any(), entity.SubscriptionChange{
+					SubscriptionId: 3,
+					UserId:         1,
+					ToPlan:         entity.UnlimitedPlan,
+					Kind:           entity.SubscriptionChangeStart,
+					Currency:       "IDR",
+					EffectiveAt:    Now(),
+				}).Return(int64(1), nil)
+				mock.outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionStarted, int64(1), entity.SubscriptionStartedPayload{
+					SubscriptionId: 3,
+					UserId:         1,
+					Plan:           entity.UnlimitedPlan,
+					StartDate:      Now(),
+					EndDate:        endsAt,
+					TrialEndsAt:    &endsAt,
+				}).Return(int64(1), nil)
+				mock.auditMock.EXPECT().Record(gomock.Any(), gomock.Any(), entity.AuditSubscriptionDetails{Plan: entity.UnlimitedPlan, StartDate: Now(), EndDate: endsAt, Trial: true}).Return(int64(1), nil)
+				mock.sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name:  "with a card the trial waits for the checkout",
+			cfg:   config.Payment{TrialCard: true},
+			ctx:   ctx,
+			param: entity.SubscriptionParam{Plan: entity.UnlimitedPlan},
+			want: entity.TrialResponse{
+				Plan:      entity.UnlimitedPlan,
+				TrialDays: 7,
+				Payment:   &entity.PaymentResponse{ID: 7, Plan: entity.UnlimitedPlan, TrialDays: 7, Currency: "IDR", Status: entity.PaymentPending, CheckoutURL: "http://mockpay/checkouts/chk_1"},
+			},
+			mockFunc: func(mock mockFields) {
+				eligible(mock)
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				pending := entity.Payment{UserId: 1, Plan: entity.UnlimitedPlan, TrialDays: 7, Currency: "IDR", Provider: gateway.KindMock, Status: entity.PaymentPending}
+				mock.paymentMock.EXPECT().Create(ctx, pending).Return(int64(7), nil)
+				mock.gatewayMock.EXPECT().CreateCheckout(ctx, gateway.Checkout{Reference: "7", Currency: "IDR", Description: entity.UnlimitedPlan, SaveMethod: true}).Return(gateway.Session{ProviderRef: "chk_1", CheckoutURL: "http://mockpay/checkouts/chk_1"}, nil)
+				pending.ID = 7
+				pending.ProviderRef = sql.NullString{String: "chk_1", Valid: true}
+				pending.CheckoutURL = "http://mockpay/checkouts/chk_1"
+				mock.paymentMock.EXPECT().Update(ctx, pending).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks)
+
+			p := Init(log, tt.cfg, mocks.gatewayMock, mocks.paymentMock, mocks.planMock, mocks.subsMock, mocks.couponMock, mocks.boostMock, mocks.outboxMock, mocks.auditMock, mocks.atomicMock)
+			got, err := p.StartTrial(tt.ctx, tt.param)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBuyBoosts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	mocks := newMocks(ctrl)
+	cfg := config.Payment{BoostPrice: 15000, BoostCurrency: "IDR"}
+
+	ctx := appcontext.SetUserId(context.Background(), 1)
+	pending := entity.Payment{UserId: 1, Boosts: 3, Amount: 45000, Currency: "IDR", Provider: gateway.KindMock, Status: entity.PaymentPending}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		cfg      config.Payment
+		mockFunc func(mock mockFields)
+		want     entity.PaymentResponse
+		wantErr  error
+	}{
+		{
+			name:     "err invalid user id",
+			ctx:      context.Background(),
+			cfg:      cfg,
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(mock mockFields) {},
+		},
+		{
+			name:     "err boosts not for sale",
+			ctx:      ctx,
+			cfg:      config.Payment{},
+			wantErr:  appErr.ErrBoostsNotForSale,
+			mockFunc: func(mock mockFields) {},
+		},
+		{
+			name:    "err create payment",
+			ctx:     ctx,
+			cfg:     cfg,
+			wantErr: assert.AnError,
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.paymentMock.EXPECT().Create(ctx, pending).Return(int64(0), assert.AnError)
+			},
+		},
+		{
+			name: "checkout of the pack",
+			ctx:  ctx,
+			cfg:  cfg,
+			want: entity.PaymentResponse{ID: 7, Boosts: 3, Amount: 45000, Currency: "IDR", Status: entity.PaymentPending, CheckoutURL: "http://pay/chk_1"},
+			mockFunc: func(mock mockFields) {
+				mock.gatewayMock.EXPECT().Name().Return(gateway.KindMock)
+				mock.paymentMock.EXPECT().Create(ctx, pending).Return(int64(7), nil)
+				mock.gatewayMock.EXPECT().CreateCheckout(ctx, gateway.Checkout{Reference: "7", Amount: 45000, Currency: "IDR", Description: "3 boosts"}).
+					Return(gateway.Session{ProviderRef: "chk_1", CheckoutURL: "http://pay/chk_1"}, nil)
+				mock.paymentMock.EXPECT().Update(ctx, gomock.Any()).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks)
+
+			p := Init(log, tt.cfg, mocks.gatewayMock, mocks.paymentMock, mocks.planMock, mocks.subsMock, mocks.couponMock, mocks.boostMock, mocks.outboxMock, mocks.auditMock, mocks.atomicMock)
+			got, err := p.BuyBoosts(tt.ctx, entity.BoostPurchaseParam{Quantity: 3})
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"loverly/lib/appcontext"
+	"loverly/lib/language"
+	"loverly/lib/log"
+	"loverly/src/business/domain/plan"
+	"loverly/src/business/entity"
+	"strings"
+)
+
+type Interface interface {
+	GetList(ctx context.Context) ([]entity.PlanResponse, error)
+}
+
+type plans struct {
+	log  log.Interface
+	plan plan.Interface
+}
+
+func Init(log log.Interface, p plan.Interface) Interface {
+	return &plans{
+		log:  log,
+		plan: p,
+	}
+}
+
+// GetList returns the catalog with names in the language of the request
+func (p *plans) GetList(ctx context.Context) ([]entity.PlanResponse, error) {
+	results := []entity.PlanResponse{}
+
+	catalog, err := p.plan.GetList(ctx)
+	if err != nil {
+		return results, err
+	}
+
+	lang := appcontext.GetAcceptLanguage(ctx)
+	for _, pl := range catalog {
+		var names map[string]string
+		if err := json.Unmarshal(pl.Name, &names); err != nil {
+			p.log.Error(ctx, fmt.Sprintf("unmarshal name of plan %s err: %v", pl.Code, err))
+		}
+
+		features := []string{}
+		if err := json.Unmarshal(pl.Features, &features); err != nil {
+			p.log.Error(ctx, fmt.Sprintf("unmarshal features of plan %s err: %v", pl.Code, err))
+		}
+
+		results = append(results, entity.PlanResponse{
+			Code:         pl.Code,
+			Name:         localize(names, lang, pl.Code),
+			Price:        pl.Price,
+			Currency:     pl.Currency,
+			DurationDays: pl.DurationDays,
+			TrialDays:    pl.TrialDays,
+			Features:     features,
+		})
+	}
+
+	return results, nil
+}
+
+// localize picks the name for the primary language of an Accept-Language value ("id-ID,en;q=0.8" is "id"),
+// falling back to english and then to the plan code
+func localize(names map[string]string, lang, fallback string) string {
+	primary := strings.ToLower(lang)
+	if i := strings.IndexAny(primary, ",;-_"); i >= 0 {
+		primary = primary[:i]
+	}
+	primary = strings.TrimSpace(primary)
+
+	for _, l := range []string{primary, language.English} {
+		if name, ok := names[l]; ok && name != "" {
+			return name
+		}
+	}
+
+	return fallback
+}
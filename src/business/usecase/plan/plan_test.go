@@ -0,0 +1,91 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"loverly/lib/appcontext"
+	mock_log "loverly/lib/log/mock"
+	mock_plan "loverly/src/business/domain/mock/plan"
+	"loverly/src/business/entity"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestGetList(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	planMock := mock_plan.NewMockInterface(ctrl)
+
+	catalog := []entity.Plan{
+		{
+			ID: 1, Code: entity.UnlimitedPlan, Name: json.RawMessage(`{"en": "Unlimited", "id": "Tanpa Batas"}`), Price: 99000, Currency: "IDR", DurationDays: 30,
+			Features: json.RawMessage(`["unlimited_swipes", "boosts"]`),
+		},
+		{ID: 2, Code: "weekly", Name: json.RawMessage(`{}`), Price: 29000, Currency: "IDR", DurationDays: 7, Features: json.RawMessage(`[]`), TrialDays: 3},
+	}
+
+	type args struct {
+		ctx context.Context
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		want     []entity.PlanResponse
+		wantErr  error
+	}{
+		{
+			name: "err get list",
+			args: args{
+				ctx: context.Background(),
+			},
+			want:    []entity.PlanResponse{},
+			wantErr: assert.AnError,
+			mockFunc: func(arg args) {
+				planMock.EXPECT().GetList(arg.ctx).Return(nil, assert.AnError)
+			},
+		},
+		{
+			name: "indonesian names",
+			args: args{
+				ctx: appcontext.SetAcceptLanguage(context.Background(), "id-ID,en;q=0.8"),
+			},
+			want: []entity.PlanResponse{
+				{Code: entity.UnlimitedPlan, Name: "Tanpa Batas", Price: 99000, Currency: "IDR", DurationDays: 30, Features: []string{entity.FeatureUnlimitedSwipes, entity.FeatureBoosts}},
+				{Code: "weekly", Name: "weekly", Price: 29000, Currency: "IDR", DurationDays: 7, TrialDays: 3, Features: []string{}},
+			},
+			mockFunc: func(arg args) {
+				planMock.EXPECT().GetList(arg.ctx).Return(catalog, nil)
+			},
+		},
+		{
+			name: "unknown language falls back to english",
+			args: args{
+				ctx: appcontext.SetAcceptLanguage(context.Background(), "jp"),
+			},
+			want: []entity.PlanResponse{
+				{Code: entity.UnlimitedPlan, Name: "Unlimited", Price: 99000, Currency: "IDR", DurationDays: 30, Features: []string{entity.FeatureUnlimitedSwipes, entity.FeatureBoosts}},
+				{Code: "weekly", Name: "weekly", Price: 29000, Currency: "IDR", DurationDays: 7, TrialDays: 3, Features: []string{}},
+			},
+			mockFunc: func(arg args) {
+				planMock.EXPECT().GetList(arg.ctx).Return(catalog, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			p := Init(log, planMock)
+			got, err := p.GetList(tt.args.ctx)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
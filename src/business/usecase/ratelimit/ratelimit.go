@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"loverly/lib/log"
+	"loverly/src/business/domain/ratelimit"
+	"loverly/src/business/entity"
+	"loverly/src/config"
+	"math"
+	"time"
+)
+
+// Interface throttles requests with a sliding window, the previous fixed window weighs in by how much of it the sliding one still covers
+type Interface interface {
+	Allow(ctx context.Context, policy, key string) (entity.RateLimitResult, error)
+}
+
+var Now = time.Now
+
+type limiter struct {
+	log       log.Interface
+	policies  map[string]entity.RateLimitPolicy
+	ratelimit ratelimit.Interface
+}
+
+func Init(log log.Interface, cfg config.RateLimit, rl ratelimit.Interface) Interface {
+	return &limiter{
+		log: log,
+		policies: map[string]entity.RateLimitPolicy{
+			entity.RateLimitAuth:    {Limit: cfg.AuthLimit, Window: cfg.AuthWindow},
+			entity.RateLimitDefault: {Limit: cfg.DefaultLimit, Window: cfg.DefaultWindow},
+			entity.RateLimitSwipe:   {Limit: cfg.SwipeLimit, Window: cfg.SwipeWindow},
+			entity.RateLimitMessage: {Limit: cfg.MessageLimit, Window: cfg.MessageWindow},
+			entity.RateLimitReport:  {Limit: cfg.ReportLimit, Window: cfg.ReportWindow},
+		},
+		ratelimit: rl,
+	}
+}
+
+// Allow counts the request against the policy, rejected requests count too so hammering doesn't pay off
+func (l *limiter) Allow(ctx context.Context, policy, key string) (entity.RateLimitResult, error) {
+	p := l.policies[policy]
+	if p.Limit < 1 || p.Window <= 0 {
+		return entity.RateLimitResult{Allowed: true}, nil
+	}
+
+	now := Now()
+	count, err := l.ratelimit.Hit(ctx, policy, key, p.Window, now)
+	if err != nil {
+		return entity.RateLimitResult{}, err
+	}
+
+	elapsed := now.Sub(count.WindowStart)
+	reset := p.Window - elapsed
+	weight := 1 - float64(elapsed)/float64(p.Window)
+	estimate := float64(count.Previous)*weight + float64(count.Current)
+
+	result := entity.RateLimitResult{
+		Allowed:   estimate <= float64(p.Limit),
+		Limit:     p.Limit,
+		Remaining: max(0, p.Limit-int(math.Ceil(estimate))),
+		Reset:     reset,
+	}
+	if result.Allowed {
+		return result, nil
+	}
+
+	// wait until the estimate is back under the limit, within this window if only the previous one is to blame,
+	// rounded up so the client never comes back too early
+	limit, window := float64(p.Limit), float64(p.Window)
+	if float64(count.Current) > limit {
+		result.RetryAfter = reset + time.Duration(math.Ceil(window*(1-limit/float64(count.Current))))
+	} else {
+		result.RetryAfter = time.Duration(math.Ceil(window*(1-(limit-float64(count.Current))/float64(count.Previous)))) - elapsed
+	}
+
+	return result, nil
+}
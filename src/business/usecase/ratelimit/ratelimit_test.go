@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"context"
+	mock_log "loverly/lib/log/mock"
+	mock_ratelimit "loverly/src/business/domain/mock/ratelimit"
+	"loverly/src/business/entity"
+	"loverly/src/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAllow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	ratelimitMock := mock_ratelimit.NewMockInterface(ctrl)
+
+	cfg := config.RateLimit{SwipeLimit: 10, SwipeWindow: time.Minute}
+
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	now := start.Add(15 * time.Second)
+	Now = func() time.Time { return now }
+	defer func() { Now = time.Now }()
+
+	type args struct {
+		ctx    context.Context
+		policy string
+		key    string
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		want     entity.RateLimitResult
+		wantErr  error
+	}{
+		{
+			name: "policy disabled",
+			args: args{
+				ctx:    context.Background(),
+				policy: entity.RateLimitReport,
+				key:    "user:1",
+			},
+			want:     entity.RateLimitResult{Allowed: true},
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "err hit",
+			args: args{
+				ctx:    context.Background(),
+				policy: entity.RateLimitSwipe,
+				key:    "user:1",
+			},
+			wantErr: assert.AnError,
+			mockFunc: func(arg args) {
+				ratelimitMock.EXPECT().Hit(arg.ctx, entity.RateLimitSwipe, "user:1", time.Minute, now).Return(entity.RateLimitCount{}, assert.AnError)
+			},
+		},
+		{
+			name: "allowed",
+			args: args{
+				ctx:    context.Background(),
+				policy: entity.RateLimitSwipe,
+				key:    "user:1",
+			},
+			want: entity.RateLimitResult{Allowed: true, Limit: 10, Remaining: 4, Reset: 45 * time.Second},
+			mockFunc: func(arg args) {
+				ratelimitMock.EXPECT().Hit(arg.ctx, entity.RateLimitSwipe, "user:1", time.Minute, now).Return(entity.RateLimitCount{Current: 3, Previous: 4, WindowStart: start}, nil)
+			},
+		},
+		{
+			name: "rejected by the previous window",
+			args: args{
+				ctx:    context.Background(),
+				policy: entity.RateLimitSwipe,
+				key:    "ip:10.0.0.1",
+			},
+			want: entity.RateLimitResult{Allowed: false, Limit: 10, Remaining: 0, Reset: 45 * time.Second, RetryAfter: 7500 * time.Millisecond},
+			mockFunc: func(arg args) {
+				ratelimitMock.EXPECT().Hit(arg.ctx, entity.RateLimitSwipe, "ip:10.0.0.1", time.Minute, now).Return(entity.RateLimitCount{Current: 5, Previous: 8, WindowStart: start}, nil)
+			},
+		},
+		{
+			name: "rejected by the current window",
+			args: args{
+				ctx:    context.Background(),
+				policy: entity.RateLimitSwipe,
+				key:    "user:1",
+			},
+			want: entity.RateLimitResult{Allowed: false, Limit: 10, Remaining: 0, Reset: 45 * time.Second, RetryAfter: 55 * time.Second},
+			mockFunc: func(arg args) {
+				ratelimitMock.EXPECT().Hit(arg.ctx, entity.RateLimitSwipe, "user:1", time.Minute, now).Return(entity.RateLimitCount{Current: 12, WindowStart: start}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			l := Init(log, cfg, ratelimitMock)
+			got, err := l.Allow(tt.args.ctx, tt.args.policy, tt.args.key)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
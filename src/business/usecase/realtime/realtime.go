@@ -0,0 +1,144 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"loverly/lib/appcontext"
+	"loverly/lib/log"
+	"loverly/src/business/domain/notification"
+	"loverly/src/business/entity"
+	"loverly/src/config"
+	appErr "loverly/src/errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	Now       = time.Now
+	NewTicket = uuid.NewString
+)
+
+type Interface interface {
+	// Push sends a notification of any type to the connected devices of the user, data is sent as JSON
+	Push(ctx context.Context, userId int64, notificationType string, data interface{}) error
+	Broadcast(ctx context.Context, notificationType string, data interface{}) error
+
+	// PushOnce is Push for handlers that may run again, a notification already pushed under key is skipped
+	PushOnce(ctx context.Context, key string, userId int64, notificationType string, data interface{}) error
+
+	// Listen streams what every instance pushes, see handler/realtime
+	Listen(ctx context.Context) (<-chan entity.Notification, error)
+
+	// Ticket lets the user open the websocket without putting the access token in the URL, Redeem returns whose it was
+	Ticket(ctx context.Context) (entity.RealtimeTicket, error)
+	Redeem(ctx context.Context, ticket string) (int64, error)
+}
+
+type realtime struct {
+	log          log.Interface
+	cfg          config.Realtime
+	notification notification.Interface
+}
+
+func Init(log log.Interface, cfg config.Realtime, n notification.Interface) Interface {
+	return &realtime{
+		log:          log,
+		cfg:          cfg,
+		notification: n,
+	}
+}
+
+func (r *realtime) Push(ctx context.Context, userId int64, notificationType string, data interface{}) error {
+	if userId < 1 {
+		return fmt.Errorf("invalid notification user id %d", userId)
+	}
+
+	return r.publish(ctx, "", userId, notificationType, data)
+}
+
+func (r *realtime) PushOnce(ctx context.Context, key string, userId int64, notificationType string, data interface{}) error {
+	if userId < 1 {
+		return fmt.Errorf("invalid notification user id %d", userId)
+	}
+
+	claimed, err := r.notification.Claim(ctx, key, r.cfg.DedupeWindow)
+	if err != nil || !claimed {
+		return err
+	}
+
+	if err := r.publish(ctx, key, userId, notificationType, data); err != nil {
+		// let the retry push it
+		if err := r.notification.Release(ctx, key); err != nil {
+			r.log.Error(ctx, fmt.Sprintf("release notification %s err: %v", key, err))
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *realtime) Broadcast(ctx context.Context, notificationType string, data interface{}) error {
+	return r.publish(ctx, "", entity.BroadcastUserId, notificationType, data)
+}
+
+func (r *realtime) Listen(ctx context.Context) (<-chan entity.Notification, error) {
+	return r.notification.Subscribe(ctx)
+}
+
+func (r *realtime) Ticket(ctx context.Context) (entity.RealtimeTicket, error) {
+	var result entity.RealtimeTicket
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	ticket := NewTicket()
+	if err := r.notification.CreateTicket(ctx, ticket, int64(userId), r.cfg.TicketTTL); err != nil {
+		return result, err
+	}
+
+	result.Ticket = ticket
+	result.ExpiresAt = Now().Add(r.cfg.TicketTTL)
+
+	return result, nil
+}
+
+func (r *realtime) Redeem(ctx context.Context, ticket string) (int64, error) {
+	if ticket == "" {
+		return 0, appErr.ErrInvalidTicket
+	}
+
+	userId, err := r.notification.RedeemTicket(ctx, ticket)
+	if err != nil {
+		return 0, err
+	}
+
+	if userId < 1 {
+		return 0, appErr.ErrInvalidTicket
+	}
+
+	return userId, nil
+}
+
+func (r *realtime) publish(ctx context.Context, id string, userId int64, notificationType string, data interface{}) error {
+	param := entity.Notification{
+		Id:     id,
+		UserId: userId,
+		Type:   notificationType,
+		SentAt: Now(),
+	}
+
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			r.log.Error(ctx, fmt.Sprintf("error when marshal notification data: %v", err))
+			return err
+		}
+		param.Data = raw
+	}
+
+	return r.notification.Publish(ctx, param)
+}
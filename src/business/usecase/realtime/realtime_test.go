@@ -0,0 +1,392 @@
+package realtime
+
+import (
+	"context"
+	"loverly/lib/appcontext"
+	mock_log "loverly/lib/log/mock"
+	mock_notification "loverly/src/business/domain/mock/notification"
+	"loverly/src/business/entity"
+	"loverly/src/config"
+	appErr "loverly/src/errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestPush(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	notificationMock := mock_notification.NewMockInterface(ctrl)
+	cfg := config.Realtime{TicketTTL: 30 * time.Second, DedupeWindow: 24 * time.Hour}
+
+	mockTime := time.Now()
+	Now = func() time.Time {
+		return mockTime
+	}
+
+	restoreAll := func() {
+		Now = time.Now
+	}
+	defer restoreAll()
+
+	type mockFields struct {
+		notificationMock *mock_notification.MockInterface
+	}
+
+	mocks := mockFields{
+		notificationMock: notificationMock,
+	}
+
+	type args struct {
+		ctx              context.Context
+		userId           int64
+		notificationType string
+		data             interface{}
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(mock mockFields, arg args)
+		args     args
+		wantErr  bool
+	}{
+		{
+			name: "err broadcast user id",
+			args: args{
+				ctx:              context.Background(),
+				userId:           entity.BroadcastUserId,
+				notificationType: entity.NotificationLikeReceived,
+			},
+			wantErr:  true,
+			mockFunc: func(mock mockFields, arg args) {},
+		},
+		{
+			name: "err publish",
+			args: args{
+				ctx:              context.Background(),
+				userId:           1,
+				notificationType: entity.NotificationLikeReceived,
+			},
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.notificationMock.EXPECT().Publish(arg.ctx, entity.Notification{UserId: 1, Type: entity.NotificationLikeReceived, SentAt: mockTime}).Return(assert.AnError)
+			},
+		},
+		{
+			name: "all goods",
+			args: args{
+				ctx:              context.Background(),
+				userId:           1,
+				notificationType: "custom.event",
+				data:             map[string]int64{"match_id": 7},
+			},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.notificationMock.EXPECT().Publish(arg.ctx, entity.Notification{UserId: 1, Type: "custom.event", Data: []byte(`{"match_id":7}`), SentAt: mockTime}).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks, tt.args)
+
+			r := Init(log, cfg, notificationMock)
+			err := r.Push(tt.args.ctx, tt.args.userId, tt.args.notificationType, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Push error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPushOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	notificationMock := mock_notification.NewMockInterface(ctrl)
+	cfg := config.Realtime{TicketTTL: 30 * time.Second, DedupeWindow: 24 * time.Hour}
+
+	mockTime := time.Now()
+	Now = func() time.Time {
+		return mockTime
+	}
+
+	restoreAll := func() {
+		Now = time.Now
+	}
+	defer restoreAll()
+
+	type mockFields struct {
+		notificationMock *mock_notification.MockInterface
+	}
+
+	mocks := mockFields{
+		notificationMock: notificationMock,
+	}
+
+	type args struct {
+		ctx              context.Context
+		key              string
+		userId           int64
+		notificationType string
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(mock mockFields, arg args)
+		args     args
+		wantErr  bool
+	}{
+		{
+			name: "err broadcast user id",
+			args: args{
+				ctx:              context.Background(),
+				key:              "7:1",
+				userId:           entity.BroadcastUserId,
+				notificationType: entity.NotificationMatchCreated,
+			},
+			wantErr:  true,
+			mockFunc: func(mock mockFields, arg args) {},
+		},
+		{
+			name: "err claim",
+			args: args{
+				ctx:              context.Background(),
+				key:              "7:1",
+				userId:           1,
+				notificationType: entity.NotificationMatchCreated,
+			},
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.notificationMock.EXPECT().Claim(arg.ctx, "7:1", 24*time.Hour).Return(false, assert.AnError)
+			},
+		},
+		{
+			name: "already pushed is skipped",
+			args: args{
+				ctx:              context.Background(),
+				key:              "7:1",
+				userId:           1,
+				notificationType: entity.NotificationMatchCreated,
+			},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.notificationMock.EXPECT().Claim(arg.ctx, "7:1", 24*time.Hour).Return(false, nil)
+			},
+		},
+		{
+			name: "err publish releases the claim",
+			args: args{
+				ctx:              context.Background(),
+				key:              "7:1",
+				userId:           1,
+				notificationType: entity.NotificationMatchCreated,
+			},
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.notificationMock.EXPECT().Claim(arg.ctx, "7:1", 24*time.Hour).Return(true, nil)
+				mock.notificationMock.EXPECT().Publish(arg.ctx, entity.Notification{Id: "7:1", UserId: 1, Type: entity.NotificationMatchCreated, SentAt: mockTime}).Return(assert.AnError)
+				mock.notificationMock.EXPECT().Release(arg.ctx, "7:1").Return(nil)
+			},
+		},
+		{
+			name: "all goods",
+			args: args{
+				ctx:              context.Background(),
+				key:              "7:1",
+				userId:           1,
+				notificationType: entity.NotificationMatchCreated,
+			},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.notificationMock.EXPECT().Claim(arg.ctx, "7:1", 24*time.Hour).Return(true, nil)
+				mock.notificationMock.EXPECT().Publish(arg.ctx, entity.Notification{Id: "7:1", UserId: 1, Type: entity.NotificationMatchCreated, SentAt: mockTime}).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks, tt.args)
+
+			r := Init(log, cfg, notificationMock)
+			err := r.PushOnce(tt.args.ctx, tt.args.key, tt.args.userId, tt.args.notificationType, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PushOnce error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTicket(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	notificationMock := mock_notification.NewMockInterface(ctrl)
+	cfg := config.Realtime{TicketTTL: 30 * time.Second, DedupeWindow: 24 * time.Hour}
+
+	mockTime := time.Now()
+	Now = func() time.Time {
+		return mockTime
+	}
+	NewTicket = func() string {
+		return "ticket"
+	}
+
+	restoreAll := func() {
+		Now = time.Now
+		NewTicket = uuid.NewString
+	}
+	defer restoreAll()
+
+	type mockFields struct {
+		notificationMock *mock_notification.MockInterface
+	}
+
+	mocks := mockFields{
+		notificationMock: notificationMock,
+	}
+
+	type args struct {
+		ctx context.Context
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(mock mockFields, arg args)
+		args     args
+		want     entity.RealtimeTicket
+		wantErr  error
+	}{
+		{
+			name: "err invalid user id",
+			args: args{
+				ctx: context.Background(),
+			},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(mock mockFields, arg args) {},
+		},
+		{
+			name: "err create ticket",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			wantErr: assert.AnError,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.notificationMock.EXPECT().CreateTicket(arg.ctx, "ticket", int64(1), 30*time.Second).Return(assert.AnError)
+			},
+		},
+		{
+			name: "all goods",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want: entity.RealtimeTicket{Ticket: "ticket", ExpiresAt: mockTime.Add(30 * time.Second)},
+			mockFunc: func(mock mockFields, arg args) {
+				mock.notificationMock.EXPECT().CreateTicket(arg.ctx, "ticket", int64(1), 30*time.Second).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks, tt.args)
+
+			r := Init(log, cfg, notificationMock)
+			got, err := r.Ticket(tt.args.ctx)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRedeem(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	notificationMock := mock_notification.NewMockInterface(ctrl)
+	cfg := config.Realtime{TicketTTL: 30 * time.Second, DedupeWindow: 24 * time.Hour}
+
+	type mockFields struct {
+		notificationMock *mock_notification.MockInterface
+	}
+
+	mocks := mockFields{
+		notificationMock: notificationMock,
+	}
+
+	type args struct {
+		ctx    context.Context
+		ticket string
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(mock mockFields, arg args)
+		args     args
+		want     int64
+		wantErr  error
+	}{
+		{
+			name: "err missing ticket",
+			args: args{
+				ctx: context.Background(),
+			},
+			wantErr:  appErr.ErrInvalidTicket,
+			mockFunc: func(mock mockFields, arg args) {},
+		},
+		{
+			name: "err redeem ticket",
+			args: args{
+				ctx:    context.Background(),
+				ticket: "ticket",
+			},
+			wantErr: assert.AnError,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.notificationMock.EXPECT().RedeemTicket(arg.ctx, "ticket").Return(int64(0), assert.AnError)
+			},
+		},
+		{
+			name: "err unknown or used ticket",
+			args: args{
+				ctx:    context.Background(),
+				ticket: "ticket",
+			},
+			wantErr: appErr.ErrInvalidTicket,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.notificationMock.EXPECT().RedeemTicket(arg.ctx, "ticket").Return(int64(0), nil)
+			},
+		},
+		{
+			name: "all goods",
+			args: args{
+				ctx:    context.Background(),
+				ticket: "ticket",
+			},
+			want: 1,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.notificationMock.EXPECT().RedeemTicket(arg.ctx, "ticket").Return(int64(1), nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks, tt.args)
+
+			r := Init(log, cfg, notificationMock)
+			got, err := r.Redeem(tt.args.ctx, tt.args.ticket)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package recommendation
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"loverly/lib/atomic"
+	"loverly/lib/log"
+	"loverly/src/business/domain/recommendation"
+	"loverly/src/business/domain/swipe"
+	"loverly/src/business/entity"
+	"loverly/src/config"
+	"math"
+	"slices"
+	"time"
+)
+
+var Now = time.Now
+
+type Interface interface {
+	Build(ctx context.Context) (int, error)
+}
+
+type recommendations struct {
+	log            log.Interface
+	cfg            config.Recommender
+	swipe          swipe.Interface
+	recommendation recommendation.Interface
+	atomic         atomic.AtomicSessionProvider
+}
+
+func Init(log log.Interface, cfg config.Recommender, sw swipe.Interface, r recommendation.Interface, a atomic.AtomicSessionProvider) Interface {
+	return &recommendations{
+		log:            log,
+		cfg:            cfg,
+		swipe:          sw,
+		recommendation: r,
+		atomic:         a,
+	}
+}
+
+// Build recomputes the candidate list of every user with swipe history and returns how many were written, the
+// lists of users without any left in the lookback are dropped along with the candidates they may point at
+func (r *recommendations) Build(ctx context.Context) (int, error) {
+	history, err := r.swipe.GetHistory(ctx, Now().Add(-r.cfg.Lookback))
+	if err != nil {
+		return 0, err
+	}
+
+	g := newGraph(history)
+
+	// cold-start users fall back to whoever gets liked the most
+	if err := r.replace(ctx, entity.ColdStartUserId, g.popular(r.cfg.Candidates)); err != nil {
+		return 0, err
+	}
+
+	var written int
+	users := g.users()
+	for _, userId := range users {
+		if err := r.replace(ctx, userId, g.recommend(userId, r.cfg.Candidates)); err != nil {
+			r.log.Error(ctx, fmt.Sprintf("replace recommendations for user %d err: %v", userId, err))
+			continue
+		}
+		written++
+	}
+
+	stale, err := r.recommendation.DeleteExcept(ctx, append(users, entity.ColdStartUserId))
+	if err != nil {
+		return written, err
+	}
+	r.recommendation.Invalidate(ctx, stale...)
+
+	return written, nil
+}
+
+func (r *recommendations) replace(ctx context.Context, userId int64, recs []entity.Recommendation) error {
+	err := atomic.Atomic(ctx, r.atomic, r.log, func(ctx context.Context) error {
+		return r.recommendation.Replace(ctx, userId, recs)
+	})
+	if err != nil {
+		return err
+	}
+
+	// only now, a read before the commit would cache the old list again
+	r.recommendation.Invalidate(ctx, userId)
+
+	return nil
+}
+
+// graph is the like/pass history as an implicit-feedback matrix, candidates are scored with
+// item-item cosine similarity over the sets of users who liked them
+type graph struct {
+	liked  map[int64]map[int64]bool
+	passed map[int64]map[int64]bool
+	likers map[int64]map[int64]bool
+	sims   map[int64]map[int64]float64
+}
+
+func newGraph(history []entity.Swipe) *graph {
+	g := &graph{
+		liked:  make(map[int64]map[int64]bool),
+		passed: make(map[int64]map[int64]bool),
+		likers: make(map[int64]map[int64]bool),
+		sims:   make(map[int64]map[int64]float64),
+	}
+
+	for _, s := range history {
+		if s.Direction == entity.Like {
+			add(g.liked, s.SwiperId, s.SwipedId)
+			add(g.likers, s.SwipedId, s.SwiperId)
+			continue
+		}
+		add(g.passed, s.SwiperId, s.SwipedId)
+	}
+
+	return g
+}
+
+func add(m map[int64]map[int64]bool, key, val int64) {
+	if m[key] == nil {
+		m[key] = make(map[int64]bool)
+	}
+	m[key][val] = true
+}
+
+// users returns everyone who swiped at least once, in a stable order
+func (g *graph) users() []int64 {
+	var users []int64
+	for id := range g.liked {
+		users = append(users, id)
+	}
+	for id := range g.passed {
+		if g.liked[id] == nil {
+			users = append(users, id)
+		}
+	}
+	slices.Sort(users)
+
+	return users
+}
+
+// similar returns the users liked by the same people as the given one, memoized per run
+func (g *graph) similar(item int64) map[int64]float64 {
+	if sims, ok := g.sims[item]; ok {
+		return sims
+	}
+
+	common := make(map[int64]int)
+	for liker := range g.likers[item] {
+		for other := range g.liked[liker] {
+			if other != item {
+				common[other]++
+			}
+		}
+	}
+
+	sims := make(map[int64]float64, len(common))
+	for other, n := range common {
+		sims[other] = float64(n) / math.Sqrt(float64(len(g.likers[item])*len(g.likers[other])))
+	}
+	g.sims[item] = sims
+
+	return sims
+}
+
+// recommend scores candidates by how similar they are to the user's likes, minus how similar they are to the passes
+func (g *graph) recommend(userId int64, limit int) []entity.Recommendation {
+	scores := make(map[int64]float64)
+	for item := range g.liked[userId] {
+		for other, sim := range g.similar(item) {
+			scores[other] += sim
+		}
+	}
+	for item := range g.passed[userId] {
+		for other, sim := range g.similar(item) {
+			scores[other] -= sim
+		}
+	}
+
+	var recs []entity.Recommendation
+	for candidate, score := range scores {
+		if candidate == userId || g.liked[userId][candidate] || g.passed[userId][candidate] || score <= 0 {
+			continue
+		}
+		recs = append(recs, entity.Recommendation{UserId: userId, CandidateId: candidate, Score: score})
+	}
+
+	return top(recs, limit)
+}
+
+func (g *graph) popular(limit int) []entity.Recommendation {
+	var recs []entity.Recommendation
+	for item, likers := range g.likers {
+		recs = append(recs, entity.Recommendation{UserId: entity.ColdStartUserId, CandidateId: item, Score: float64(len(likers))})
+	}
+
+	return top(recs, limit)
+}
+
+// top keeps the best scored recommendations, ties go to the lower candidate id so runs are reproducible
+func top(recs []entity.Recommendation, limit int) []entity.Recommendation {
+	slices.SortFunc(recs, func(a, b entity.Recommendation) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.CandidateId, b.CandidateId)
+	})
+
+	return recs[:min(len(recs), limit)]
+}
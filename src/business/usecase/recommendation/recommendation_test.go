@@ -0,0 +1,132 @@
+package recommendation
+
+import (
+	"context"
+	"loverly/lib/atomic"
+	mock_atomic "loverly/lib/atomic/mock"
+	mock_log "loverly/lib/log/mock"
+	mock_recommendation "loverly/src/business/domain/mock/recommendation"
+	mock_swipe "loverly/src/business/domain/mock/swipe"
+	"loverly/src/business/entity"
+	"loverly/src/config"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestBuild(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	swipeMock := mock_swipe.NewMockInterface(ctrl)
+	recMock := mock_recommendation.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
+	cfg := config.Recommender{Lookback: 24 * time.Hour, Candidates: 10}
+
+	mockTime := time.Now()
+	Now = func() time.Time {
+		return mockTime
+	}
+	defer func() {
+		Now = time.Now
+	}()
+
+	type args struct {
+		ctx context.Context
+	}
+
+	// users 1 and 2 both liked 10, user 1 also liked 11 and user 3 only passed
+	history := []entity.Swipe{
+		{SwiperId: 1, SwipedId: 10, Direction: entity.Like},
+		{SwiperId: 2, SwipedId: 10, Direction: entity.Like},
+		{SwiperId: 1, SwipedId: 11, Direction: entity.Like},
+		{SwiperId: 3, SwipedId: 10, Direction: entity.Pass},
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		want     int
+		wantErr  bool
+	}{
+		{
+			name: "err get history",
+			args: args{
+				ctx: context.Background(),
+			},
+			want:    0,
+			wantErr: true,
+			mockFunc: func(arg args) {
+				swipeMock.EXPECT().GetHistory(arg.ctx, mockTime.Add(-cfg.Lookback)).Return(nil, assert.AnError)
+			},
+		},
+		{
+			name: "all goods",
+			args: args{
+				ctx: context.Background(),
+			},
+			want:    3,
+			wantErr: false,
+			mockFunc: func(arg args) {
+				swipeMock.EXPECT().GetHistory(arg.ctx, mockTime.Add(-cfg.Lookback)).Return(history, nil)
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil).Times(4)
+				sessionMock.EXPECT().Commit(arg.ctx).Return(nil).Times(4)
+
+				recMock.EXPECT().Replace(gomock.Any(), int64(entity.ColdStartUserId), []entity.Recommendation{
+					{UserId: entity.ColdStartUserId, CandidateId: 10, Score: 2},
+					{UserId: entity.ColdStartUserId, CandidateId: 11, Score: 1},
+				}).Return(nil)
+				recMock.EXPECT().Replace(gomock.Any(), int64(1), gomock.Len(0)).Return(nil)
+				recMock.EXPECT().Replace(gomock.Any(), int64(2), []entity.Recommendation{
+					{UserId: 2, CandidateId: 11, Score: 1 / math.Sqrt(2)},
+				}).Return(nil)
+				// passing on someone liked together with 11 makes 11 a bad candidate
+				recMock.EXPECT().Replace(gomock.Any(), int64(3), gomock.Len(0)).Return(nil)
+				for _, userId := range []int64{entity.ColdStartUserId, 1, 2, 3} {
+					recMock.EXPECT().Invalidate(arg.ctx, userId)
+				}
+
+				// user 4 stopped swiping, their list may point at users gone since
+				recMock.EXPECT().DeleteExcept(arg.ctx, []int64{1, 2, 3, entity.ColdStartUserId}).Return([]int64{4}, nil)
+				recMock.EXPECT().Invalidate(arg.ctx, int64(4))
+			},
+		},
+		{
+			name: "err drop stale lists",
+			args: args{
+				ctx: context.Background(),
+			},
+			want:    3,
+			wantErr: true,
+			mockFunc: func(arg args) {
+				swipeMock.EXPECT().GetHistory(arg.ctx, mockTime.Add(-cfg.Lookback)).Return(history, nil)
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil).Times(4)
+				sessionMock.EXPECT().Commit(arg.ctx).Return(nil).Times(4)
+				recMock.EXPECT().Replace(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(4)
+				recMock.EXPECT().Invalidate(arg.ctx, gomock.Any()).Times(4)
+				recMock.EXPECT().DeleteExcept(arg.ctx, gomock.Any()).Return(nil, assert.AnError)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			r := Init(log, cfg, swipeMock, recMock, atomicMock)
+			got, err := r.Build(tt.args.ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Build error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
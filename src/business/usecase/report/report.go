@@ -0,0 +1,353 @@
+package report
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
+	"loverly/lib/cursor"
+	"loverly/lib/log"
+	"loverly/lib/operator"
+	"loverly/src/business/domain/audit"
+	"loverly/src/business/domain/message"
+	"loverly/src/business/domain/profile"
+	"loverly/src/business/domain/report"
+	"loverly/src/business/domain/user"
+	"loverly/src/business/entity"
+	appErr "loverly/src/errors"
+	"slices"
+	"time"
+)
+
+// Interface lets users report each other and moderators work through the queue, see handler for the role checks
+type Interface interface {
+	Create(ctx context.Context, param entity.ReportParam) (entity.ReportResponse, error)
+	GetList(ctx context.Context, param entity.ReportListParam) (entity.ReportListResponse, error)
+	Assign(ctx context.Context, reportId int64, param entity.ReportAssignParam) (entity.ReportResponse, error)
+	Resolve(ctx context.Context, reportId int64, param entity.ReportResolveParam) (entity.ReportResponse, error)
+}
+
+const defaultLimit = 20
+
+var Now = time.Now
+
+type reports struct {
+	log     log.Interface
+	report  report.Interface
+	user    user.Interface
+	profile profile.Interface
+	message message.Interface
+	audit   audit.Interface
+	atomic  atomic.AtomicSessionProvider
+}
+
+func Init(log log.Interface, r report.Interface, u user.Interface, p profile.Interface, msg message.Interface, au audit.Interface, a atomic.AtomicSessionProvider) Interface {
+	return &reports{
+		log:     log,
+		report:  r,
+		user:    u,
+		profile: p,
+		message: msg,
+		audit:   au,
+		atomic:  a,
+	}
+}
+
+func (r *reports) Create(ctx context.Context, param entity.ReportParam) (entity.ReportResponse, error) {
+	var result entity.ReportResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	rp := entity.Report{
+		ReporterId: int64(userId),
+		ReportedId: param.UserId,
+		TargetType: param.TargetType,
+		Reason:     param.Reason,
+		Details:    param.Details,
+		Status:     entity.ReportStatusOpen,
+	}
+
+	// a message is reported by its id, only by the users of its match
+	if param.TargetType == entity.ReportTargetMessage {
+		msg, err := r.message.GetForParticipant(ctx, param.MessageId, int64(userId))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return result, appErr.ErrInvalidReportTarget
+			}
+			return result, err
+		}
+
+		rp.ReportedId = msg.SenderId
+		rp.TargetId = sql.NullInt64{Int64: msg.ID, Valid: true}
+	} else if _, err := r.profile.GetByUserId(ctx, param.UserId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, appErr.ErrInvalidReportTarget
+		}
+		return result, err
+	}
+
+	if rp.ReportedId == int64(userId) {
+		return result, appErr.ErrInvalidReportTarget
+	}
+
+	id, err := r.report.Create(ctx, rp)
+	if err != nil {
+		return result, err
+	}
+
+	rp.ID = id
+	rp.CreatedAt = sql.NullTime{Time: Now(), Valid: true}
+
+	return toResponse(rp), nil
+}
+
+// GetList pages the queue oldest first, open reports unless another status is asked for
+func (r *reports) GetList(ctx context.Context, param entity.ReportListParam) (entity.ReportListResponse, error) {
+	var result entity.ReportListResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	limit := operator.Ternary(param.Limit < 1, defaultLimit, param.Limit)
+	page := entity.ReportPageParam{
+		Status:     operator.Ternary(param.Status == "", entity.ReportStatusOpen, param.Status),
+		AssigneeId: param.AssigneeId,
+		Limit:      limit + 1, // one extra row tells whether there is a next page
+	}
+
+	if param.Cursor != "" {
+		var err error
+		page.AfterAt, page.AfterId, err = cursor.Decode(param.Cursor)
+		if err != nil {
+			return result, appErr.ErrInvalidCursor
+		}
+	}
+
+	reports, err := r.report.GetPage(ctx, page)
+	if err != nil {
+		return result, err
+	}
+
+	if len(reports) > limit {
+		reports = reports[:limit]
+		last := reports[len(reports)-1]
+		result.NextCursor = cursor.Encode(last.CreatedAt.Time, last.ID)
+	}
+
+	for _, rp := range reports {
+		result.Reports = append(result.Reports, toResponse(rp))
+	}
+
+	return result, nil
+}
+
+// Assign hands an open report to a moderator, the moderator making the request when none is given
+func (r *reports) Assign(ctx context.Context, reportId int64, param entity.ReportAssignParam) (entity.ReportResponse, error) {
+	var result entity.ReportResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	assigneeId := operator.Ternary(param.AssigneeId < 1, int64(userId), param.AssigneeId)
+	if assigneeId != int64(userId) {
+		assignee, err := r.user.GetById(ctx, assigneeId)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return result, err
+		}
+
+		if !slices.Contains([]string{entity.RoleModerator, entity.RoleAdmin}, assignee.Role) {
+			return result, appErr.ErrInvalidAssignee
+		}
+	}
+
+	err := atomic.Atomic(ctx, r.atomic, r.log, func(ctx context.Context) error {
+		current, err := r.getOpen(ctx, reportId)
+		if err != nil {
+			return err
+		}
+
+		current.AssigneeId = sql.NullInt64{Int64: assigneeId, Valid: true}
+		if err := r.report.Assign(ctx, current); err != nil {
+			return err
+		}
+
+		if _, err := r.report.CreateAction(ctx, entity.ModerationAction{
+			ReportId:    sql.NullInt64{Int64: current.ID, Valid: true},
+			ModeratorId: int64(userId),
+			UserId:      assigneeId,
+			Action:      entity.ModerationAssign,
+		}); err != nil {
+			return err
+		}
+
+		if _, err := r.audit.Record(ctx, entity.AuditLog{
+			ActorId:    sql.NullInt64{Int64: int64(userId), Valid: true},
+			Action:     entity.AuditReportAssigned,
+			TargetType: entity.AuditTargetReport,
+			TargetId:   sql.NullInt64{Int64: current.ID, Valid: true},
+		}, entity.AuditModerationDetails{UserId: assigneeId, Action: entity.ModerationAssign}); err != nil {
+			return err
+		}
+
+		result = toResponse(current)
+
+		return nil
+	})
+	if err != nil {
+		return entity.ReportResponse{}, err
+	}
+
+	return result, nil
+}
+
+// Resolve closes an open report, every action but a dismissal is taken against the reported user
+func (r *reports) Resolve(ctx context.Context, reportId int64, param entity.ReportResolveParam) (entity.ReportResponse, error) {
+	var result entity.ReportResponse
+
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return result, appErr.ErrInvalidUserId
+	}
+
+	restricted := false
+	err := atomic.Atomic(ctx, r.atomic, r.log, func(ctx context.Context) error {
+		current, err := r.getOpen(ctx, reportId)
+		if err != nil {
+			return err
+		}
+
+		now := Now()
+		current.Status = operator.Ternary(param.Action == entity.ModerationDismiss, entity.ReportStatusDismissed, entity.ReportStatusActioned)
+		current.AssigneeId = sql.NullInt64{Int64: int64(userId), Valid: true}
+		current.ResolvedAt = sql.NullTime{Time: now, Valid: true}
+		if err := r.report.Resolve(ctx, current); err != nil {
+			return err
+		}
+
+		action := entity.ModerationAction{
+			ReportId:    sql.NullInt64{Int64: current.ID, Valid: true},
+			ModeratorId: int64(userId),
+			UserId:      current.ReportedId,
+			Action:      param.Action,
+			Note:        param.Note,
+		}
+		if param.Action == entity.ModerationSuspend {
+			action.ExpiresAt = sql.NullTime{Time: now.AddDate(0, 0, param.SuspendDays), Valid: true}
+		}
+
+		if _, err := r.report.CreateAction(ctx, action); err != nil {
+			return err
+		}
+
+		// only suspensions and bans restrict the account, a warning is just on record
+		if param.Action == entity.ModerationSuspend || param.Action == entity.ModerationBan {
+			if restricted, err = r.restrict(ctx, current, action); err != nil {
+				return err
+			}
+		}
+
+		details := entity.AuditModerationDetails{UserId: current.ReportedId, Action: param.Action, Note: param.Note}
+		if action.ExpiresAt.Valid {
+			details.ExpiresAt = &action.ExpiresAt.Time
+		}
+
+		if _, err := r.audit.Record(ctx, entity.AuditLog{
+			ActorId:    sql.NullInt64{Int64: int64(userId), Valid: true},
+			Action:     entity.AuditReportResolved,
+			TargetType: entity.AuditTargetReport,
+			TargetId:   sql.NullInt64{Int64: current.ID, Valid: true},
+		}, details); err != nil {
+			return err
+		}
+
+		result = toResponse(current)
+
+		return nil
+	})
+	if err != nil {
+		return entity.ReportResponse{}, err
+	}
+
+	// only now, a read before the commit would cache the account as it was
+	if restricted {
+		r.user.InvalidateStatus(ctx)
+	}
+
+	return result, nil
+}
+
+// restrict applies the suspension or ban of the action to the account locked as it is now, reports resolved one
+// after the other only ever make it stricter: a ban isn't turned into a suspension and a suspension isn't cut
+// short. It returns false when the account was left as it was
+func (r *reports) restrict(ctx context.Context, current entity.Report, action entity.ModerationAction) (bool, error) {
+	u, err := r.user.GetByIdForUpdate(ctx, current.ReportedId)
+	if err != nil {
+		return false, err
+	}
+
+	if u.Status == entity.UserStatusBanned {
+		return false, nil
+	}
+
+	if action.Action == entity.ModerationSuspend && u.Status == entity.UserStatusSuspended && u.SuspendedUntil.Valid &&
+		u.SuspendedUntil.Time.After(action.ExpiresAt.Time) {
+		return false, nil
+	}
+
+	if err := r.user.UpdateStatus(ctx, entity.User{
+		ID:             current.ReportedId,
+		Status:         operator.Ternary(action.Action == entity.ModerationBan, entity.UserStatusBanned, entity.UserStatusSuspended),
+		SuspendedUntil: action.ExpiresAt,
+		StatusReason:   operator.Ternary(action.Note == "", current.Reason, action.Note),
+	}); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// getOpen locks the report, a resolved one can't be assigned or resolved again
+func (r *reports) getOpen(ctx context.Context, reportId int64) (entity.Report, error) {
+	current, err := r.report.GetForUpdate(ctx, reportId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return current, appErr.ErrReportNotFound
+		}
+		return current, err
+	}
+
+	if current.Status != entity.ReportStatusOpen {
+		return current, appErr.ErrReportAlreadyResolved
+	}
+
+	return current, nil
+}
+
+func toResponse(rp entity.Report) entity.ReportResponse {
+	resp := entity.ReportResponse{
+		ID:         rp.ID,
+		ReporterId: rp.ReporterId,
+		ReportedId: rp.ReportedId,
+		TargetType: rp.TargetType,
+		MessageId:  rp.TargetId.Int64,
+		Reason:     rp.Reason,
+		Details:    rp.Details,
+		Status:     rp.Status,
+		AssigneeId: rp.AssigneeId.Int64,
+		CreatedAt:  rp.CreatedAt.Time,
+	}
+
+	if rp.ResolvedAt.Valid {
+		resp.ResolvedAt = &rp.ResolvedAt.Time
+	}
+
+	return resp
+}
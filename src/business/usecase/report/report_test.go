@@ -0,0 +1,602 @@
+package report
+
+import (
+	"context"
+	"database/sql"
+	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
+	mock_atomic "loverly/lib/atomic/mock"
+	"loverly/lib/cursor"
+	mock_log "loverly/lib/log/mock"
+	mock_audit "loverly/src/business/domain/mock/audit"
+	mock_message "loverly/src/business/domain/mock/message"
+	mock_profile "loverly/src/business/domain/mock/profile"
+	mock_report "loverly/src/business/domain/mock/report"
+	mock_user "loverly/src/business/domain/mock/user"
+	"loverly/src/business/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	appErr "loverly/src/errors"
+)
+
+func TestCreate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	reportMock := mock_report.NewMockInterface(ctrl)
+	userMock := mock_user.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	messageMock := mock_message.NewMockInterface(ctrl)
+	auditMock := mock_audit.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	Now = func() time.Time { return now }
+	defer func() { Now = time.Now }()
+
+	type args struct {
+		ctx   context.Context
+		param entity.ReportParam
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		want     entity.ReportResponse
+		wantErr  error
+	}{
+		{
+			name: "err invalid user id",
+			args: args{
+				ctx:   context.Background(),
+				param: entity.ReportParam{TargetType: entity.ReportTargetProfile, UserId: 2, Reason: "spam"},
+			},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "err unknown user",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.ReportParam{TargetType: entity.ReportTargetProfile, UserId: 2, Reason: "spam"},
+			},
+			wantErr: appErr.ErrInvalidReportTarget,
+			mockFunc: func(arg args) {
+				profileMock.EXPECT().GetByUserId(arg.ctx, int64(2)).Return(entity.Profile{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name: "err report yourself",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.ReportParam{TargetType: entity.ReportTargetPhoto, UserId: 1, Reason: "spam"},
+			},
+			wantErr: appErr.ErrInvalidReportTarget,
+			mockFunc: func(arg args) {
+				profileMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Profile{UserId: 1}, nil)
+			},
+		},
+		{
+			name: "err message outside your matches",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.ReportParam{TargetType: entity.ReportTargetMessage, MessageId: 7, Reason: "harassment"},
+			},
+			wantErr: appErr.ErrInvalidReportTarget,
+			mockFunc: func(arg args) {
+				messageMock.EXPECT().GetForParticipant(arg.ctx, int64(7), int64(1)).Return(entity.Message{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name: "err report your own message",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.ReportParam{TargetType: entity.ReportTargetMessage, MessageId: 7, Reason: "harassment"},
+			},
+			wantErr: appErr.ErrInvalidReportTarget,
+			mockFunc: func(arg args) {
+				messageMock.EXPECT().GetForParticipant(arg.ctx, int64(7), int64(1)).Return(entity.Message{ID: 7, SenderId: 1}, nil)
+			},
+		},
+		{
+			name: "err create report",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.ReportParam{TargetType: entity.ReportTargetProfile, UserId: 2, Reason: "fake"},
+			},
+			wantErr: assert.AnError,
+			mockFunc: func(arg args) {
+				profileMock.EXPECT().GetByUserId(arg.ctx, int64(2)).Return(entity.Profile{UserId: 2}, nil)
+				reportMock.EXPECT().Create(arg.ctx, entity.Report{ReporterId: 1, ReportedId: 2, TargetType: entity.ReportTargetProfile, Reason: "fake", Status: entity.ReportStatusOpen}).Return(int64(0), assert.AnError)
+			},
+		},
+		{
+			name: "report a profile",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.ReportParam{TargetType: entity.ReportTargetProfile, UserId: 2, Reason: "fake", Details: "stock photos"},
+			},
+			want: entity.ReportResponse{ID: 3, ReporterId: 1, ReportedId: 2, TargetType: entity.ReportTargetProfile, Reason: "fake", Details: "stock photos", Status: entity.ReportStatusOpen, CreatedAt: now},
+			mockFunc: func(arg args) {
+				profileMock.EXPECT().GetByUserId(arg.ctx, int64(2)).Return(entity.Profile{UserId: 2}, nil)
+				reportMock.EXPECT().Create(arg.ctx, entity.Report{ReporterId: 1, ReportedId: 2, TargetType: entity.ReportTargetProfile, Reason: "fake", Details: "stock photos", Status: entity.ReportStatusOpen}).Return(int64(3), nil)
+			},
+		},
+		{
+			name: "report a message",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.ReportParam{TargetType: entity.ReportTargetMessage, MessageId: 7, Reason: "harassment"},
+			},
+			want: entity.ReportResponse{ID: 3, ReporterId: 1, ReportedId: 2, TargetType: entity.ReportTargetMessage, MessageId: 7, Reason: "harassment", Status: entity.ReportStatusOpen, CreatedAt: now},
+			mockFunc: func(arg args) {
+				messageMock.EXPECT().GetForParticipant(arg.ctx, int64(7), int64(1)).Return(entity.Message{ID: 7, SenderId: 2}, nil)
+				reportMock.EXPECT().Create(arg.ctx, entity.Report{ReporterId: 1, ReportedId: 2, TargetType: entity.ReportTargetMessage, TargetId: sql.NullInt64{Int64: 7, Valid: true}, Reason: "harassment", Status: entity.ReportStatusOpen}).Return(int64(3), nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			r := Init(log, reportMock, userMock, profileMock, messageMock, auditMock, atomicMock)
+			got, err := r.Create(tt.args.ctx, tt.args.param)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetList(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	reportMock := mock_report.NewMockInterface(ctrl)
+	userMock := mock_user.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	messageMock := mock_message.NewMockInterface(ctrl)
+	auditMock := mock_audit.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	type args struct {
+		ctx   context.Context
+		param entity.ReportListParam
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		want     entity.ReportListResponse
+		wantErr  error
+	}{
+		{
+			name: "err invalid user id",
+			args: args{
+				ctx: context.Background(),
+			},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "err invalid cursor",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.ReportListParam{Cursor: "bm9wZQ"},
+			},
+			wantErr:  appErr.ErrInvalidCursor,
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "err get page",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			wantErr: assert.AnError,
+			mockFunc: func(arg args) {
+				reportMock.EXPECT().GetPage(arg.ctx, entity.ReportPageParam{Status: entity.ReportStatusOpen, Limit: defaultLimit + 1}).Return(nil, assert.AnError)
+			},
+		},
+		{
+			name: "first page with more to come",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.ReportListParam{AssigneeId: 1, Limit: 1},
+			},
+			want: entity.ReportListResponse{
+				Reports:    []entity.ReportResponse{{ID: 3, ReporterId: 4, ReportedId: 5, Status: entity.ReportStatusOpen, AssigneeId: 1, CreatedAt: createdAt}},
+				NextCursor: cursor.Encode(createdAt, 3),
+			},
+			mockFunc: func(arg args) {
+				reportMock.EXPECT().GetPage(arg.ctx, entity.ReportPageParam{Status: entity.ReportStatusOpen, AssigneeId: 1, Limit: 2}).Return([]entity.Report{
+					{ID: 3, ReporterId: 4, ReportedId: 5, Status: entity.ReportStatusOpen, AssigneeId: sql.NullInt64{Int64: 1, Valid: true}, CreatedAt: sql.NullTime{Time: createdAt, Valid: true}},
+					{ID: 6, ReporterId: 4, ReportedId: 5, Status: entity.ReportStatusOpen, AssigneeId: sql.NullInt64{Int64: 1, Valid: true}, CreatedAt: sql.NullTime{Time: createdAt, Valid: true}},
+				}, nil)
+			},
+		},
+		{
+			name: "last page",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.ReportListParam{Status: entity.ReportStatusDismissed, Cursor: cursor.Encode(createdAt, 3)},
+			},
+			want: entity.ReportListResponse{
+				Reports: []entity.ReportResponse{{ID: 6, ReporterId: 4, ReportedId: 5, Status: entity.ReportStatusDismissed, ResolvedAt: &createdAt, CreatedAt: createdAt}},
+			},
+			mockFunc: func(arg args) {
+				reportMock.EXPECT().GetPage(arg.ctx, entity.ReportPageParam{Status: entity.ReportStatusDismissed, AfterAt: createdAt, AfterId: 3, Limit: defaultLimit + 1}).Return([]entity.Report{
+					{ID: 6, ReporterId: 4, ReportedId: 5, Status: entity.ReportStatusDismissed, ResolvedAt: sql.NullTime{Time: createdAt, Valid: true}, CreatedAt: sql.NullTime{Time: createdAt, Valid: true}},
+				}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			r := Init(log, reportMock, userMock, profileMock, messageMock, auditMock, atomicMock)
+			got, err := r.GetList(tt.args.ctx, tt.args.param)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAssign(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	reportMock := mock_report.NewMockInterface(ctrl)
+	userMock := mock_user.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	messageMock := mock_message.NewMockInterface(ctrl)
+	auditMock := mock_audit.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
+
+	open := entity.Report{ID: 3, ReporterId: 4, ReportedId: 5, Status: entity.ReportStatusOpen}
+
+	type args struct {
+		ctx      context.Context
+		reportId int64
+		param    entity.ReportAssignParam
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		want     entity.ReportResponse
+		wantErr  error
+	}{
+		{
+			name: "err invalid user id",
+			args: args{
+				ctx:      context.Background(),
+				reportId: 3,
+			},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "err assignee is not a moderator",
+			args: args{
+				ctx:      appcontext.SetUserId(context.Background(), 1),
+				reportId: 3,
+				param:    entity.ReportAssignParam{AssigneeId: 2},
+			},
+			wantErr: appErr.ErrInvalidAssignee,
+			mockFunc: func(arg args) {
+				userMock.EXPECT().GetById(arg.ctx, int64(2)).Return(entity.User{ID: 2, Role: entity.RoleUser}, nil)
+			},
+		},
+		{
+			name: "err report not found",
+			args: args{
+				ctx:      appcontext.SetUserId(context.Background(), 1),
+				reportId: 3,
+			},
+			wantErr: appErr.ErrReportNotFound,
+			mockFunc: func(arg args) {
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				reportMock.EXPECT().GetForUpdate(gomock.Any(), int64(3)).Return(entity.Report{}, sql.ErrNoRows)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "err report already resolved",
+			args: args{
+				ctx:      appcontext.SetUserId(context.Background(), 1),
+				reportId: 3,
+			},
+			wantErr: appErr.ErrReportAlreadyResolved,
+			mockFunc: func(arg args) {
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				reportMock.EXPECT().GetForUpdate(gomock.Any(), int64(3)).Return(entity.Report{ID: 3, Status: entity.ReportStatusDismissed}, nil)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "assign to yourself",
+			args: args{
+				ctx:      appcontext.SetUserId(context.Background(), 1),
+				reportId: 3,
+			},
+			want: entity.ReportResponse{ID: 3, ReporterId: 4, ReportedId: 5, Status: entity.ReportStatusOpen, AssigneeId: 1},
+			mockFunc: func(arg args) {
+				assigned := open
+				assigned.AssigneeId = sql.NullInt64{Int64: 1, Valid: true}
+
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				reportMock.EXPECT().GetForUpdate(gomock.Any(), int64(3)).Return(open, nil)
+				reportMock.EXPECT().Assign(gomock.Any(), assigned).Return(nil)
+				reportMock.EXPECT().CreateAction(gomock.Any(), entity.ModerationAction{ReportId: sql.NullInt64{Int64: 3, Valid: true}, ModeratorId: 1, UserId: 1, Action: entity.ModerationAssign}).Return(int64(9), nil)
+				auditMock.EXPECT().Record(gomock.Any(), entity.AuditLog{ActorId: sql.NullInt64{Int64: 1, Valid: true}, Action: entity.AuditReportAssigned, TargetType: entity.AuditTargetReport, TargetId: sql.NullInt64{Int64: 3, Valid: true}},
+					entity.AuditModerationDetails{UserId: 1, Action: entity.ModerationAssign}).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "assign to another moderator",
+			args: args{
+				ctx:      appcontext.SetUserId(context.Background(), 1),
+				reportId: 3,
+				param:    entity.ReportAssignParam{AssigneeId: 2},
+			},
+			want: entity.ReportResponse{ID: 3, ReporterId: 4, ReportedId: 5, Status: entity.ReportStatusOpen, AssigneeId: 2},
+			mockFunc: func(arg args) {
+				assigned := open
+				assigned.AssigneeId = sql.NullInt64{Int64: 2, Valid: true}
+
+				userMock.EXPECT().GetById(arg.ctx, int64(2)).Return(entity.User{ID: 2, Role: entity.RoleModerator}, nil)
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				reportMock.EXPECT().GetForUpdate(gomock.Any(), int64(3)).Return(open, nil)
+				reportMock.EXPECT().Assign(gomock.Any(), assigned).Return(nil)
+				reportMock.EXPECT().CreateAction(gomock.Any(), entity.ModerationAction{ReportId: sql.NullInt64{Int64: 3, Valid: true}, ModeratorId: 1, UserId: 2, Action: entity.ModerationAssign}).Return(int64(9), nil)
+				auditMock.EXPECT().Record(gomock.Any(), entity.AuditLog{ActorId: sql.NullInt64{Int64: 1, Valid: true}, Action: entity.AuditReportAssigned, TargetType: entity.AuditTargetReport, TargetId: sql.NullInt64{Int64: 3, Valid: true}},
+					entity.AuditModerationDetails{UserId: 2, Action: entity.ModerationAssign}).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			r := Init(log, reportMock, userMock, profileMock, messageMock, auditMock, atomicMock)
+			got, err := r.Assign(tt.args.ctx, tt.args.reportId, tt.args.param)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	reportMock := mock_report.NewMockInterface(ctrl)
+	userMock := mock_user.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	messageMock := mock_message.NewMockInterface(ctrl)
+	auditMock := mock_audit.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
+
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	Now = func() time.Time { return now }
+	defer func() { Now = time.Now }()
+
+	open := entity.Report{ID: 3, ReporterId: 4, ReportedId: 5, Reason: "spam", Status: entity.ReportStatusOpen}
+	expiresAt := now.AddDate(0, 0, 7)
+
+	type args struct {
+		ctx      context.Context
+		reportId int64
+		param    entity.ReportResolveParam
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		want     entity.ReportResponse
+		wantErr  error
+	}{
+		{
+			name: "err invalid user id",
+			args: args{
+				ctx:      context.Background(),
+				reportId: 3,
+				param:    entity.ReportResolveParam{Action: entity.ModerationDismiss},
+			},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "err report already resolved",
+			args: args{
+				ctx:      appcontext.SetUserId(context.Background(), 1),
+				reportId: 3,
+				param:    entity.ReportResolveParam{Action: entity.ModerationWarn},
+			},
+			wantErr: appErr.ErrReportAlreadyResolved,
+			mockFunc: func(arg args) {
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				reportMock.EXPECT().GetForUpdate(gomock.Any(), int64(3)).Return(entity.Report{ID: 3, Status: entity.ReportStatusActioned}, nil)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "err create action",
+			args: args{
+				ctx:      appcontext.SetUserId(context.Background(), 1),
+				reportId: 3,
+				param:    entity.ReportResolveParam{Action: entity.ModerationBan},
+			},
+			wantErr: assert.AnError,
+			mockFunc: func(arg args) {
+				resolved := open
+				resolved.Status = entity.ReportStatusActioned
+				resolved.AssigneeId = sql.NullInt64{Int64: 1, Valid: true}
+				resolved.ResolvedAt = sql.NullTime{Time: now, Valid: true}
+
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				reportMock.EXPECT().GetForUpdate(gomock.Any(), int64(3)).Return(open, nil)
+				reportMock.EXPECT().Resolve(gomock.Any(), resolved).Return(nil)
+				reportMock.EXPECT().CreateAction(gomock.Any(), entity.ModerationAction{ReportId: sql.NullInt64{Int64: 3, Valid: true}, ModeratorId: 1, UserId: 5, Action: entity.ModerationBan}).Return(int64(0), assert.AnError)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "err ban user",
+			args: args{
+				ctx:      appcontext.SetUserId(context.Background(), 1),
+				reportId: 3,
+				param:    entity.ReportResolveParam{Action: entity.ModerationBan, Note: "repeat offender"},
+			},
+			wantErr: assert.AnError,
+			mockFunc: func(arg args) {
+				resolved := open
+				resolved.Status = entity.ReportStatusActioned
+				resolved.AssigneeId = sql.NullInt64{Int64: 1, Valid: true}
+				resolved.ResolvedAt = sql.NullTime{Time: now, Valid: true}
+
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				reportMock.EXPECT().GetForUpdate(gomock.Any(), int64(3)).Return(open, nil)
+				reportMock.EXPECT().Resolve(gomock.Any(), resolved).Return(nil)
+				reportMock.EXPECT().CreateAction(gomock.Any(), entity.ModerationAction{ReportId: sql.NullInt64{Int64: 3, Valid: true}, ModeratorId: 1, UserId: 5, Action: entity.ModerationBan, Note: "repeat offender"}).Return(int64(9), nil)
+				userMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(5)).Return(entity.User{ID: 5, Status: entity.UserStatusActive}, nil)
+				userMock.EXPECT().UpdateStatus(gomock.Any(), entity.User{ID: 5, Status: entity.UserStatusBanned, StatusReason: "repeat offender"}).Return(assert.AnError)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "dismiss",
+			args: args{
+				ctx:      appcontext.SetUserId(context.Background(), 1),
+				reportId: 3,
+				param:    entity.ReportResolveParam{Action: entity.ModerationDismiss, Note: "not a violation"},
+			},
+			want: entity.ReportResponse{ID: 3, ReporterId: 4, ReportedId: 5, Reason: "spam", Status: entity.ReportStatusDismissed, AssigneeId: 1, ResolvedAt: &now},
+			mockFunc: func(arg args) {
+				resolved := open
+				resolved.Status = entity.ReportStatusDismissed
+				resolved.AssigneeId = sql.NullInt64{Int64: 1, Valid: true}
+				resolved.ResolvedAt = sql.NullTime{Time: now, Valid: true}
+
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				reportMock.EXPECT().GetForUpdate(gomock.Any(), int64(3)).Return(open, nil)
+				reportMock.EXPECT().Resolve(gomock.Any(), resolved).Return(nil)
+				reportMock.EXPECT().CreateAction(gomock.Any(), entity.ModerationAction{ReportId: sql.NullInt64{Int64: 3, Valid: true}, ModeratorId: 1, UserId: 5, Action: entity.ModerationDismiss, Note: "not a violation"}).Return(int64(9), nil)
+				auditMock.EXPECT().Record(gomock.Any(), entity.AuditLog{ActorId: sql.NullInt64{Int64: 1, Valid: true}, Action: entity.AuditReportResolved, TargetType: entity.AuditTargetReport, TargetId: sql.NullInt64{Int64: 3, Valid: true}},
+					entity.AuditModerationDetails{UserId: 5, Action: entity.ModerationDismiss, Note: "not a violation"}).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "suspend",
+			args: args{
+				ctx:      appcontext.SetUserId(context.Background(), 1),
+				reportId: 3,
+				param:    entity.ReportResolveParam{Action: entity.ModerationSuspend, SuspendDays: 7},
+			},
+			want: entity.ReportResponse{ID: 3, ReporterId: 4, ReportedId: 5, Reason: "spam", Status: entity.ReportStatusActioned, AssigneeId: 1, ResolvedAt: &now},
+			mockFunc: func(arg args) {
+				resolved := open
+				resolved.Status = entity.ReportStatusActioned
+				resolved.AssigneeId = sql.NullInt64{Int64: 1, Valid: true}
+				resolved.ResolvedAt = sql.NullTime{Time: now, Valid: true}
+
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				reportMock.EXPECT().GetForUpdate(gomock.Any(), int64(3)).Return(open, nil)
+				reportMock.EXPECT().Resolve(gomock.Any(), resolved).Return(nil)
+				reportMock.EXPECT().CreateAction(gomock.Any(), entity.ModerationAction{ReportId: sql.NullInt64{Int64: 3, Valid: true}, ModeratorId: 1, UserId: 5, Action: entity.ModerationSuspend, ExpiresAt: sql.NullTime{Time: now.AddDate(0, 0, 7), Valid: true}}).Return(int64(9), nil)
+				userMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(5)).Return(entity.User{ID: 5, Status: entity.UserStatusSuspended, SuspendedUntil: sql.NullTime{Time: now.AddDate(0, 0, 3), Valid: true}}, nil)
+				userMock.EXPECT().UpdateStatus(gomock.Any(), entity.User{ID: 5, Status: entity.UserStatusSuspended, SuspendedUntil: sql.NullTime{Time: now.AddDate(0, 0, 7), Valid: true}, StatusReason: "spam"}).Return(nil)
+				auditMock.EXPECT().Record(gomock.Any(), entity.AuditLog{ActorId: sql.NullInt64{Int64: 1, Valid: true}, Action: entity.AuditReportResolved, TargetType: entity.AuditTargetReport, TargetId: sql.NullInt64{Int64: 3, Valid: true}},
+					entity.AuditModerationDetails{UserId: 5, Action: entity.ModerationSuspend, ExpiresAt: &expiresAt}).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+				userMock.EXPECT().InvalidateStatus(arg.ctx)
+			},
+		},
+		{
+			name: "suspend keeps a ban",
+			args: args{
+				ctx:      appcontext.SetUserId(context.Background(), 1),
+				reportId: 3,
+				param:    entity.ReportResolveParam{Action: entity.ModerationSuspend, SuspendDays: 7},
+			},
+			want: entity.ReportResponse{ID: 3, ReporterId: 4, ReportedId: 5, Reason: "spam", Status: entity.ReportStatusActioned, AssigneeId: 1, ResolvedAt: &now},
+			mockFunc: func(arg args) {
+				resolved := open
+				resolved.Status = entity.ReportStatusActioned
+				resolved.AssigneeId = sql.NullInt64{Int64: 1, Valid: true}
+				resolved.ResolvedAt = sql.NullTime{Time: now, Valid: true}
+
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				reportMock.EXPECT().GetForUpdate(gomock.Any(), int64(3)).Return(open, nil)
+				reportMock.EXPECT().Resolve(gomock.Any(), resolved).Return(nil)
+				reportMock.EXPECT().CreateAction(gomock.Any(), entity.ModerationAction{ReportId: sql.NullInt64{Int64: 3, Valid: true}, ModeratorId: 1, UserId: 5, Action: entity.ModerationSuspend, ExpiresAt: sql.NullTime{Time: now.AddDate(0, 0, 7), Valid: true}}).Return(int64(9), nil)
+				userMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(5)).Return(entity.User{ID: 5, Status: entity.UserStatusBanned}, nil)
+				auditMock.EXPECT().Record(gomock.Any(), entity.AuditLog{ActorId: sql.NullInt64{Int64: 1, Valid: true}, Action: entity.AuditReportResolved, TargetType: entity.AuditTargetReport, TargetId: sql.NullInt64{Int64: 3, Valid: true}},
+					entity.AuditModerationDetails{UserId: 5, Action: entity.ModerationSuspend, ExpiresAt: &expiresAt}).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "suspend keeps a longer suspension",
+			args: args{
+				ctx:      appcontext.SetUserId(context.Background(), 1),
+				reportId: 3,
+				param:    entity.ReportResolveParam{Action: entity.ModerationSuspend, SuspendDays: 7},
+			},
+			want: entity.ReportResponse{ID: 3, ReporterId: 4, ReportedId: 5, Reason: "spam", Status: entity.ReportStatusActioned, AssigneeId: 1, ResolvedAt: &now},
+			mockFunc: func(arg args) {
+				resolved := open
+				resolved.Status = entity.ReportStatusActioned
+				resolved.AssigneeId = sql.NullInt64{Int64: 1, Valid: true}
+				resolved.ResolvedAt = sql.NullTime{Time: now, Valid: true}
+
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				reportMock.EXPECT().GetForUpdate(gomock.Any(), int64(3)).Return(open, nil)
+				reportMock.EXPECT().Resolve(gomock.Any(), resolved).Return(nil)
+				reportMock.EXPECT().CreateAction(gomock.Any(), entity.ModerationAction{ReportId: sql.NullInt64{Int64: 3, Valid: true}, ModeratorId: 1, UserId: 5, Action: entity.ModerationSuspend, ExpiresAt: sql.NullTime{Time: now.AddDate(0, 0, 7), Valid: true}}).Return(int64(9), nil)
+				userMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(5)).Return(entity.User{ID: 5, Status: entity.UserStatusSuspended, SuspendedUntil: sql.NullTime{Time: now.AddDate(0, 0, 30), Valid: true}}, nil)
+				auditMock.EXPECT().Record(gomock.Any(), entity.AuditLog{ActorId: sql.NullInt64{Int64: 1, Valid: true}, Action: entity.AuditReportResolved, TargetType: entity.AuditTargetReport, TargetId: sql.NullInt64{Int64: 3, Valid: true}},
+					entity.AuditModerationDetails{UserId: 5, Action: entity.ModerationSuspend, ExpiresAt: &expiresAt}).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			r := Init(log, reportMock, userMock, profileMock, messageMock, auditMock, atomicMock)
+			got, err := r.Resolve(tt.args.ctx, tt.args.reportId, tt.args.param)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
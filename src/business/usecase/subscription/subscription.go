@@ -4,66 +4,405 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
 	"loverly/lib/log"
+	"loverly/src/business/domain/audit"
+	"loverly/src/business/domain/outbox"
+	"loverly/src/business/domain/plan"
 	"loverly/src/business/domain/subscription"
 	"loverly/src/business/entity"
+	"loverly/src/config"
+	"math"
 	"time"
 
+	gateway "loverly/lib/payment"
 	appErr "loverly/src/errors"
 )
 
-var Now = time.Now
-
+// Interface runs the lifecycle of a subscription, subscriptions are started by settling a payment, see payment
 type Interface interface {
-	Get(ctx context.Context) (*entity.Subscription, error)
-	Create(ctx context.Context, param entity.SubscriptionParam) error
+	// Get returns the current subscription, or the latest one, with where its trial stands
+	Get(ctx context.Context) (*entity.SubscriptionResponse, error)
+	// Cancel stops the renewal, the features stay on until the end of the period
+	Cancel(ctx context.Context) (entity.Subscription, error)
+	// Sweep renews or expires subscriptions past their period and reminds the users of those about to end
+	Sweep(ctx context.Context, limit int) (int, error)
 }
 
+var Now = time.Now
+
 type subs struct {
 	log          log.Interface
+	cfg          config.Subscription
 	subscription subscription.Interface
+	plan         plan.Interface
+	charger      gateway.Charger
+	outbox       outbox.Interface
+	audit        audit.Interface
+	atomic       atomic.AtomicSessionProvider
 }
 
-func Init(log log.Interface, s subscription.Interface) Interface {
+func Init(log log.Interface, cfg config.Subscription, s subscription.Interface, p plan.Interface, c gateway.Charger, o outbox.Interface, au audit.Interface, a atomic.AtomicSessionProvider) Interface {
 	return &subs{
 		log:          log,
+		cfg:          cfg,
 		subscription: s,
+		plan:         p,
+		charger:      c,
+		outbox:       o,
+		audit:        au,
+		atomic:       a,
 	}
 }
 
-func (s *subs) Create(ctx context.Context, param entity.SubscriptionParam) error {
+func (s *subs) Get(ctx context.Context) (*entity.SubscriptionResponse, error) {
+	var results entity.SubscriptionResponse
+
 	userId := appcontext.GetUserId(ctx)
 	if userId < 1 {
-		return appErr.ErrInvalidUserId
+		return &results, appErr.ErrInvalidUserId
 	}
 
-	_, err := s.subscription.Create(ctx, entity.Subscription{
-		UserId:    int64(userId),
-		Plan:      param.Plan,
-		StartDate: Now(),
-		EndDate:   Now().AddDate(0, 0, 30),
-	})
+	sub, err := s.subscription.GetByUserId(ctx, int64(userId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
 
-	return err
+		return &results, err
+	}
+
+	results.Subscription = sub
+	results.Trial = trial(sub)
+
+	return &results, nil
+}
+
+// trial is where the trial the subscription started with stands. Remaining days are counted up so the last
+// hours of a trial still show one day, a trial past its end stays active until the sweep converts or expires it
+func trial(sub entity.Subscription) *entity.SubscriptionTrial {
+	if !sub.TrialEndsAt.Valid {
+		return nil
+	}
+
+	result := &entity.SubscriptionTrial{EndsAt: sub.TrialEndsAt.Time}
+
+	switch {
+	// a paid period follows, from the renewal or a payment made during the trial
+	case sub.EndDate.After(sub.TrialEndsAt.Time):
+		result.Status = entity.TrialConverted
+	case sub.Status == entity.SubscriptionExpired:
+		result.Status = entity.TrialEnded
+	default:
+		result.Status = entity.TrialActive
+		result.RemainingDays = max(0, int(math.Ceil(sub.TrialEndsAt.Time.Sub(Now()).Hours()/24)))
+		result.Converts = sub.TrialConverts && sub.Status != entity.SubscriptionCancelled
+	}
+
+	return result
 }
 
-func (s *subs) Get(ctx context.Context) (*entity.Subscription, error) {
-	var results entity.Subscription
+func (s *subs) Cancel(ctx context.Context) (entity.Subscription, error) {
+	var result entity.Subscription
 
 	userId := appcontext.GetUserId(ctx)
 	if userId < 1 {
-		return &results, appErr.ErrInvalidUserId
+		return result, appErr.ErrInvalidUserId
+	}
+
+	err := atomic.Atomic(ctx, s.atomic, s.log, func(ctx context.Context) error {
+		sub, err := s.subscription.GetCurrentForUpdate(ctx, int64(userId))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return appErr.ErrSubscriptionNotFound
+			}
+			return err
+		}
+
+		if sub.Status == entity.SubscriptionCancelled {
+			return appErr.ErrSubscriptionAlreadyCancelled
+		}
+
+		// cancelling in grace stops the retries, the sweep expires it right after
+		sub.Status = entity.SubscriptionCancelled
+		sub.CancelledAt = sql.NullTime{Time: Now(), Valid: true}
+		sub.NextRenewalAt = sql.NullTime{}
+		sub.PendingPlan = sql.NullString{}
+		if err := s.subscription.Update(ctx, sub); err != nil {
+			return err
+		}
+
+		if err := s.emit(ctx, entity.EventSubscriptionCancelled, sub); err != nil {
+			return err
+		}
+
+		if _, err := s.audit.Record(ctx, entity.AuditLog{
+			ActorId:    sql.NullInt64{Int64: sub.UserId, Valid: true},
+			Action:     entity.AuditSubscriptionCancelled,
+			TargetType: entity.AuditTargetSubscription,
+			TargetId:   sql.NullInt64{Int64: sub.ID, Valid: true},
+		}, entity.AuditSubscriptionDetails{Plan: sub.Plan, StartDate: sub.StartDate, EndDate: sub.EndDate}); err != nil {
+			return err
+		}
+
+		result = sub
+
+		return nil
+	})
+	if err != nil {
+		return entity.Subscription{}, err
 	}
 
-	results, err := s.subscription.GetByUserId(ctx, int64(userId))
+	return result, nil
+}
+
+func (s *subs) Sweep(ctx context.Context, limit int) (int, error) {
+	// each subscription is swept in a transaction of its own, one failing doesn't roll back the others
+	due, err := s.subscription.GetDue(ctx, limit)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
+		return 0, err
+	}
+
+	swept := 0
+	var sweepErr error
+	for _, sub := range due {
+		done, err := s.sweep(ctx, sub)
+		if err != nil {
+			if sweepErr == nil {
+				sweepErr = err
+			}
+			continue
 		}
 
-		return &results, err
+		if done {
+			swept++
+		}
 	}
 
-	return &results, nil
+	if s.cfg.ExpiryReminder <= 0 {
+		return swept, sweepErr
+	}
+
+	reminded := 0
+	err = atomic.Atomic(ctx, s.atomic, s.log, func(ctx context.Context) error {
+		expiring, err := s.subscription.GetExpiring(ctx, s.cfg.ExpiryReminder, limit)
+		if err != nil {
+			return err
+		}
+
+		for _, sub := range expiring {
+			sub.ReminderSentAt = sql.NullTime{Time: Now(), Valid: true}
+			if err := s.subscription.Update(ctx, sub); err != nil {
+				return err
+			}
+
+			if err := s.emit(ctx, entity.EventSubscriptionExpiring, sub); err != nil {
+				return err
+			}
+		}
+
+		reminded = len(expiring)
+
+		return nil
+	})
+	if err != nil {
+		return swept, err
+	}
+
+	return swept + reminded, sweepErr
+}
+
+// sweep renews or expires the subscription, on the downgraded plan if one is pending. The charge is made
+// before the row is locked, it carries the same reference until its outcome is recorded so a charge repeated
+// after a failure or by another worker isn't billed twice. It returns false when another worker got there first
+func (s *subs) sweep(ctx context.Context, sub entity.Subscription) (bool, error) {
+	var (
+		pl        entity.Plan
+		chargeRef string
+		chargeErr error
+	)
+
+	renewing := sub.Status != entity.SubscriptionCancelled &&
+		!(sub.Status == entity.SubscriptionGrace && !sub.GraceUntil.Time.After(Now())) &&
+		// a trial without a card on file has nothing to charge
+		!(sub.TrialEndsAt.Valid && !sub.TrialConverts && !sub.EndDate.After(sub.TrialEndsAt.Time))
+
+	if renewing {
+		code := sub.Plan
+		if sub.PendingPlan.Valid {
+			code = sub.PendingPlan.String
+		}
+
+		var err error
+		pl, err = s.plan.GetByCode(ctx, code)
+		switch {
+		// a retired plan isn't renewed
+		case errors.Is(err, sql.ErrNoRows):
+			renewing = false
+		case err != nil:
+			return false, err
+		}
+	}
+
+	if renewing {
+		chargeRef, chargeErr = s.charger.Charge(ctx, gateway.Charge{
+			Reference:   fmt.Sprintf("subscription:%d:%d:%d", sub.ID, sub.EndDate.Unix(), sub.RenewalAttempts),
+			UserId:      sub.UserId,
+			Amount:      pl.Price,
+			Currency:    pl.Currency,
+			Description: pl.Code,
+		})
+		if chargeErr != nil {
+			s.log.Error(ctx, fmt.Sprintf("renew subscription %d err: %v", sub.ID, chargeErr))
+		}
+	}
+
+	swept := false
+	err := atomic.Atomic(ctx, s.atomic, s.log, func(ctx context.Context) error {
+		locked, err := s.subscription.GetByIdForUpdate(ctx, sub.ID)
+		if err != nil {
+			return err
+		}
+
+		// swept since it was read, a charge made for it had the same reference
+		if locked.Status == entity.SubscriptionExpired || !locked.EndDate.Equal(sub.EndDate) || locked.RenewalAttempts != sub.RenewalAttempts {
+			return nil
+		}
+
+		swept = true
+
+		switch {
+		case !renewing:
+			return s.expire(ctx, locked)
+		case chargeErr != nil:
+			return s.renewalFailed(ctx, locked)
+		default:
+			return s.renew(ctx, locked, pl, chargeRef)
+		}
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return swept, nil
+}
+
+// renewalFailed puts the subscription in grace after a declined charge, or expires it when there is no grace left
+func (s *subs) renewalFailed(ctx context.Context, sub entity.Subscription) error {
+	graceUntil := sub.EndDate.Add(s.cfg.GracePeriod)
+	if sub.GraceUntil.Valid {
+		graceUntil = sub.GraceUntil.Time
+	}
+
+	if !graceUntil.After(Now()) {
+		return s.expire(ctx, sub)
+	}
+
+	sub.Status = entity.SubscriptionGrace
+	sub.GraceUntil = sql.NullTime{Time: graceUntil, Valid: true}
+	sub.RenewalAttempts++
+	sub.NextRenewalAt = sql.NullTime{Time: Now().Add(s.cfg.RenewalRetry), Valid: true}
+	if err := s.subscription.Update(ctx, sub); err != nil {
+		return err
+	}
+
+	return s.emit(ctx, entity.EventSubscriptionRenewalFailed, sub)
+}
+
+// renew starts the period pl was charged for. A subscription cancelled while it was being charged keeps the
+// period it paid for and ends with it
+func (s *subs) renew(ctx context.Context, sub entity.Subscription, pl entity.Plan, chargeRef string) error {
+	// the new period follows the old one, time spent in grace was already used
+	fromPlan := sub.Plan
+	sub.Plan = pl.Code
+	sub.PendingPlan = sql.NullString{}
+	sub.StartDate = sub.EndDate
+	sub.EndDate = sub.EndDate.AddDate(0, 0, pl.DurationDays)
+	sub.PeriodAmount = pl.Price
+	if sub.Status != entity.SubscriptionCancelled {
+		sub.Status = entity.SubscriptionActive
+	}
+	sub.GraceUntil = sql.NullTime{}
+	sub.RenewalAttempts = 0
+	sub.NextRenewalAt = sql.NullTime{}
+	sub.ReminderSentAt = sql.NullTime{}
+	if err := s.subscription.Update(ctx, sub); err != nil {
+		return err
+	}
+
+	if err := s.emit(ctx, entity.EventSubscriptionRenewed, sub); err != nil {
+		return err
+	}
+
+	if fromPlan != sub.Plan {
+		if err := s.downgraded(ctx, sub, fromPlan, pl); err != nil {
+			return err
+		}
+	}
+
+	_, err := s.audit.Record(ctx, entity.AuditLog{
+		Action:     entity.AuditSubscriptionRenewed,
+		TargetType: entity.AuditTargetSubscription,
+		TargetId:   sql.NullInt64{Int64: sub.ID, Valid: true},
+	}, entity.AuditSubscriptionDetails{Plan: sub.Plan, StartDate: sub.StartDate, EndDate: sub.EndDate, ChargeRef: chargeRef})
+
+	return err
+}
+
+// downgraded records the switch to the pending plan the renewal just charged
+func (s *subs) downgraded(ctx context.Context, sub entity.Subscription, fromPlan string, pl entity.Plan) error {
+	_, err := s.subscription.RecordChange(ctx, entity.SubscriptionChange{
+		SubscriptionId: sub.ID,
+		UserId:         sub.UserId,
+		FromPlan:       sql.NullString{String: fromPlan, Valid: true},
+		ToPlan:         sub.Plan,
+		Kind:           entity.SubscriptionChangeDowngrade,
+		Amount:         pl.Price,
+		Currency:       pl.Currency,
+		EffectiveAt:    sub.StartDate,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.outbox.Emit(ctx, entity.EventSubscriptionChanged, sub.UserId, entity.SubscriptionChangedPayload{
+		SubscriptionId: sub.ID,
+		UserId:         sub.UserId,
+		Kind:           entity.SubscriptionChangeDowngrade,
+		FromPlan:       fromPlan,
+		ToPlan:         sub.Plan,
+		EndDate:        sub.EndDate,
+	})
+
+	return err
+}
+
+func (s *subs) expire(ctx context.Context, sub entity.Subscription) error {
+	sub.Status = entity.SubscriptionExpired
+	sub.NextRenewalAt = sql.NullTime{}
+	if err := s.subscription.Update(ctx, sub); err != nil {
+		return err
+	}
+
+	return s.emit(ctx, entity.EventSubscriptionExpired, sub)
+}
+
+func (s *subs) emit(ctx context.Context, eventType string, sub entity.Subscription) error {
+	payload := entity.SubscriptionLifecyclePayload{
+		SubscriptionId: sub.ID,
+		UserId:         sub.UserId,
+		Plan:           sub.Plan,
+		Status:         sub.Status,
+		EndDate:        sub.EndDate,
+	}
+
+	if sub.GraceUntil.Valid {
+		payload.GraceUntil = &sub.GraceUntil.Time
+	}
+
+	_, err := s.outbox.Emit(ctx, eventType, sub.UserId, payload)
+
+	return err
 }
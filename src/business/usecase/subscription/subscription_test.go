@@ -2,15 +2,27 @@ package subscription
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
+	mock_atomic "loverly/lib/atomic/mock"
 	mock_log "loverly/lib/log/mock"
+	mock_gateway "loverly/lib/payment/mock"
+	mock_audit "loverly/src/business/domain/mock/audit"
+	mock_outbox "loverly/src/business/domain/mock/outbox"
+	mock_plan "loverly/src/business/domain/mock/plan"
 	mock_subscription "loverly/src/business/domain/mock/subscription"
 	"loverly/src/business/entity"
+	"loverly/src/config"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
+
+	gateway "loverly/lib/payment"
+	appErr "loverly/src/errors"
 )
 
 func TestGet(t *testing.T) {
@@ -20,6 +32,14 @@ func TestGet(t *testing.T) {
 	log := mock_log.NewMockInterface(ctrl)
 	subsMock := mock_subscription.NewMockInterface(ctrl)
 
+	mockTime := time.Now()
+	Now = func() time.Time {
+		return mockTime
+	}
+	defer func() {
+		Now = time.Now
+	}()
+
 	type mockFields struct {
 		subsMock *mock_subscription.MockInterface
 	}
@@ -32,13 +52,27 @@ func TestGet(t *testing.T) {
 		ctx context.Context
 	}
 
-	allGoods := &entity.Subscription{ID: 1, UserId: 1, Plan: entity.UnlimitedPlan}
+	allGoods := &entity.SubscriptionResponse{Subscription: entity.Subscription{ID: 1, UserId: 1, Plan: entity.UnlimitedPlan}}
+
+	trialEnd := mockTime.Add(36 * time.Hour)
+	trialing := entity.Subscription{
+		ID: 1, UserId: 1, Plan: entity.UnlimitedPlan, Status: entity.SubscriptionActive, EndDate: trialEnd,
+		TrialEndsAt: sql.NullTime{Time: trialEnd, Valid: true}, TrialConverts: true,
+	}
+	cancelledTrial := trialing
+	cancelledTrial.Status = entity.SubscriptionCancelled
+	converted := trialing
+	converted.TrialEndsAt.Time = mockTime.AddDate(0, 0, -3)
+	converted.EndDate = mockTime.AddDate(0, 0, 27)
+	ended := trialing
+	ended.Status = entity.SubscriptionExpired
+	ended.TrialConverts = false
 
 	tests := []struct {
 		name     string
 		mockFunc func(mock mockFields, arg args)
 		args     args
-		want     *entity.Subscription
+		want     *entity.SubscriptionResponse
 		wantErr  bool
 	}{
 		{
@@ -46,7 +80,7 @@ func TestGet(t *testing.T) {
 			args: args{
 				ctx: appcontext.SetUserId(context.Background(), 1),
 			},
-			want:    &entity.Subscription{},
+			want:    &entity.SubscriptionResponse{},
 			wantErr: true,
 			mockFunc: func(mock mockFields, arg args) {
 				mock.subsMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Subscription{}, assert.AnError)
@@ -63,13 +97,65 @@ func TestGet(t *testing.T) {
 				mock.subsMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(entity.Subscription{ID: 1, UserId: 1, Plan: entity.UnlimitedPlan}, nil)
 			},
 		},
+		{
+			name: "trial with the remaining days counted up",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want: &entity.SubscriptionResponse{
+				Subscription: trialing,
+				Trial:        &entity.SubscriptionTrial{Status: entity.TrialActive, EndsAt: trialEnd, RemainingDays: 2, Converts: true},
+			},
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(trialing, nil)
+			},
+		},
+		{
+			name: "cancelled trial doesn't convert",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want: &entity.SubscriptionResponse{
+				Subscription: cancelledTrial,
+				Trial:        &entity.SubscriptionTrial{Status: entity.TrialActive, EndsAt: trialEnd, RemainingDays: 2},
+			},
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(cancelledTrial, nil)
+			},
+		},
+		{
+			name: "converted trial",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want: &entity.SubscriptionResponse{
+				Subscription: converted,
+				Trial:        &entity.SubscriptionTrial{Status: entity.TrialConverted, EndsAt: converted.TrialEndsAt.Time},
+			},
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(converted, nil)
+			},
+		},
+		{
+			name: "ended trial",
+			args: args{
+				ctx: appcontext.SetUserId(context.Background(), 1),
+			},
+			want: &entity.SubscriptionResponse{
+				Subscription: ended,
+				Trial:        &entity.SubscriptionTrial{Status: entity.TrialEnded, EndsAt: trialEnd},
+			},
+			mockFunc: func(mock mockFields, arg args) {
+				mock.subsMock.EXPECT().GetByUserId(arg.ctx, int64(1)).Return(ended, nil)
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockFunc(mocks, tt.args)
 
-			d := Init(log, subsMock)
+			d := Init(log, config.Subscription{}, subsMock, nil, nil, nil, nil, nil)
 			got, err := d.Get(tt.args.ctx)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Get error = %v, wantErr %v", err, tt.wantErr)
@@ -81,83 +167,481 @@ func TestGet(t *testing.T) {
 	}
 }
 
-func TestCreate(t *testing.T) {
+func TestCancel(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	log := mock_log.NewMockInterface(ctrl)
 	subsMock := mock_subscription.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	auditMock := mock_audit.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
 
 	mockTime := time.Now()
 	Now = func() time.Time {
 		return mockTime
 	}
-
-	restoreAll := func() {
+	defer func() {
 		Now = time.Now
-	}
-	defer restoreAll()
+	}()
 
-	type mockFields struct {
-		subsMock *mock_subscription.MockInterface
+	ctx := appcontext.SetUserId(context.Background(), 1)
+	current := entity.Subscription{ID: 2, UserId: 1, Plan: entity.UnlimitedPlan, Status: entity.SubscriptionActive, EndDate: mockTime.AddDate(0, 0, 10)}
+	cancelled := current
+	cancelled.Status = entity.SubscriptionCancelled
+	cancelled.CancelledAt = sql.NullTime{Time: mockTime, Valid: true}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		mockFunc func()
+		want     entity.Subscription
+		wantErr  error
+	}{
+		{
+			name:     "err invalid user",
+			ctx:      context.Background(),
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func() {},
+		},
+		{
+			name:    "err no subscription",
+			ctx:     ctx,
+			wantErr: appErr.ErrSubscriptionNotFound,
+			mockFunc: func() {
+				atomicMock.EXPECT().BeginSession(ctx).Return(atomic.NewAtomicSessionContext(ctx, sessionMock), nil)
+				subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(entity.Subscription{}, sql.ErrNoRows)
+				log.EXPECT().Error(ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(ctx).Return(nil)
+			},
+		},
+		{
+			name:    "err already cancelled",
+			ctx:     ctx,
+			wantErr: appErr.ErrSubscriptionAlreadyCancelled,
+			mockFunc: func() {
+				atomicMock.EXPECT().BeginSession(ctx).Return(atomic.NewAtomicSessionContext(ctx, sessionMock), nil)
+				subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(cancelled, nil)
+				log.EXPECT().Error(ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(ctx).Return(nil)
+			},
+		},
+		{
+			name:    "err update",
+			ctx:     ctx,
+			wantErr: assert.AnError,
+			mockFunc: func() {
+				atomicMock.EXPECT().BeginSession(ctx).Return(atomic.NewAtomicSessionContext(ctx, sessionMock), nil)
+				subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(current, nil)
+				subsMock.EXPECT().Update(gomock.Any(), cancelled).Return(assert.AnError)
+				log.EXPECT().Error(ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(ctx).Return(nil)
+			},
+		},
+		{
+			name: "all goods",
+			ctx:  ctx,
+			want: cancelled,
+			mockFunc: func() {
+				atomicMock.EXPECT().BeginSession(ctx).Return(atomic.NewAtomicSessionContext(ctx, sessionMock), nil)
+				subsMock.EXPECT().GetCurrentForUpdate(gomock.Any(), int64(1)).Return(current, nil)
+				subsMock.EXPECT().Update(gomock.Any(), cancelled).Return(nil)
+				outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionCancelled, int64(1), entity.SubscriptionLifecyclePayload{
+					SubscriptionId: 2,
+					UserId:         1,
+					Plan:           entity.UnlimitedPlan,
+					Status:         entity.SubscriptionCancelled,
+					EndDate:        current.EndDate,
+				}).Return(int64(1), nil)
+				auditMock.EXPECT().Record(gomock.Any(), entity.AuditLog{
+					ActorId:    sql.NullInt64{Int64: 1, Valid: true},
+					Action:     entity.AuditSubscriptionCancelled,
+					TargetType: entity.AuditTargetSubscription,
+					TargetId:   sql.NullInt64{Int64: 2, Valid: true},
+				}, gomock.Any()).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
 	}
 
-	mocks := mockFields{
-		subsMock: subsMock,
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc()
+
+			s := Init(log, config.Subscription{}, subsMock, nil, nil, outboxMock, auditMock, atomicMock)
+			got, err := s.Cancel(tt.ctx)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
 	}
+}
 
-	type args struct {
-		ctx   context.Context
-		param entity.SubscriptionParam
+func TestSweep(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	subsMock := mock_subscription.NewMockInterface(ctrl)
+	planMock := mock_plan.NewMockInterface(ctrl)
+	chargerMock := mock_gateway.NewMockCharger(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	auditMock := mock_audit.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
+
+	mockTime := time.Now()
+	Now = func() time.Time {
+		return mockTime
 	}
+	defer func() {
+		Now = time.Now
+	}()
 
-	paramMock := entity.SubscriptionParam{Plan: entity.UnlimitedPlan}
+	ctx := context.Background()
+	cfg := config.Subscription{GracePeriod: 72 * time.Hour, RenewalRetry: 12 * time.Hour}
+	planMock30 := entity.Plan{ID: 1, Code: entity.UnlimitedPlan, Price: 99000, Currency: "IDR", DurationDays: 30}
+	endDate := mockTime.Add(-time.Hour)
+	due := entity.Subscription{ID: 2, UserId: 1, Plan: entity.UnlimitedPlan, Status: entity.SubscriptionActive, EndDate: endDate}
+
+	begin := func() {
+		atomicMock.EXPECT().BeginSession(ctx).Return(atomic.NewAtomicSessionContext(ctx, sessionMock), nil)
+	}
 
 	tests := []struct {
 		name     string
-		mockFunc func(mock mockFields, arg args)
-		args     args
-		want     error
+		cfg      config.Subscription
+		mockFunc func()
+		want     int
 		wantErr  bool
 	}{
 		{
-			name: "err insert subscription",
-			args: args{
-				ctx:   appcontext.SetUserId(context.Background(), 1),
-				param: paramMock,
+			name: "err get due",
+			cfg:  cfg,
+			mockFunc: func() {
+				subsMock.EXPECT().GetDue(ctx, 10).Return(nil, assert.AnError)
 			},
-			want:    nil,
 			wantErr: true,
-			mockFunc: func(mock mockFields, arg args) {
-				mock.subsMock.EXPECT().Create(arg.ctx, entity.Subscription{UserId: int64(1), Plan: entity.UnlimitedPlan, StartDate: Now(), EndDate: Now().AddDate(0, 0, 30)}).Return(int64(0), assert.AnError)
+		},
+		{
+			name: "renewed",
+			cfg:  cfg,
+			want: 1,
+			mockFunc: func() {
+				subsMock.EXPECT().GetDue(ctx, 10).Return([]entity.Subscription{due}, nil)
+				planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(planMock30, nil)
+				chargerMock.EXPECT().Charge(ctx, gateway.Charge{
+					Reference:   fmt.Sprintf("subscription:2:%d:0", endDate.Unix()),
+					UserId:      1,
+					Amount:      99000,
+					Currency:    "IDR",
+					Description: entity.UnlimitedPlan,
+				}).Return("ch_1", nil)
+
+				begin()
+				subsMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(2)).Return(due, nil)
+				renewed := due
+				renewed.StartDate = endDate
+				renewed.EndDate = endDate.AddDate(0, 0, 30)
+				renewed.PeriodAmount = 99000
+				subsMock.EXPECT().Update(gomock.Any(), renewed).Return(nil)
+				outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionRenewed, int64(1), gomock.Any()).Return(int64(1), nil)
+				auditMock.EXPECT().Record(gomock.Any(), gomock.Any(), entity.AuditSubscriptionDetails{Plan: entity.UnlimitedPlan, StartDate: endDate, EndDate: endDate.AddDate(0, 0, 30), ChargeRef: "ch_1"}).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(ctx).Return(nil)
 			},
 		},
 		{
-			name: "all goods",
-			args: args{
-				ctx:   appcontext.SetUserId(context.Background(), 1),
-				param: paramMock,
+			name: "renewed on the scheduled downgrade",
+			cfg:  cfg,
+			want: 1,
+			mockFunc: func() {
+				downgrading := due
+				downgrading.PendingPlan = sql.NullString{String: entity.VerifiedPlan, Valid: true}
+				subsMock.EXPECT().GetDue(ctx, 10).Return([]entity.Subscription{downgrading}, nil)
+				planMock.EXPECT().GetByCode(ctx, entity.VerifiedPlan).Return(entity.Plan{ID: 2, Code: entity.VerifiedPlan, Price: 49000, Currency: "IDR", DurationDays: 30}, nil)
+				chargerMock.EXPECT().Charge(ctx, gateway.Charge{
+					Reference:   fmt.Sprintf("subscription:2:%d:0", endDate.Unix()),
+					UserId:      1,
+					Amount:      49000,
+					Currency:    "IDR",
+					Description: entity.VerifiedPlan,
+				}).Return("ch_1", nil)
+
+				begin()
+				subsMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(2)).Return(downgrading, nil)
+				renewed := due
+				renewed.Plan = entity.VerifiedPlan
+				renewed.StartDate = endDate
+				renewed.EndDate = endDate.AddDate(0, 0, 30)
+				renewed.PeriodAmount = 49000
+				subsMock.EXPECT().Update(gomock.Any(), renewed).Return(nil)
+				outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionRenewed, int64(1), gomock.Any()).Return(int64(1), nil)
+				subsMock.EXPECT().RecordChange(gomock.Any(), entity.SubscriptionChange{
+					SubscriptionId: 2,
+					UserId:         1,
+					FromPlan:       sql.NullString{String: entity.UnlimitedPlan, Valid: true},
+					ToPlan:         entity.VerifiedPlan,
+					Kind:           entity.SubscriptionChangeDowngrade,
+					Amount:         49000,
+					Currency:       "IDR",
+					EffectiveAt:    endDate,
+				}).Return(int64(1), nil)
+				outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionChanged, int64(1), entity.SubscriptionChangedPayload{
+					SubscriptionId: 2,
+					UserId:         1,
+					Kind:           entity.SubscriptionChangeDowngrade,
+					FromPlan:       entity.UnlimitedPlan,
+					ToPlan:         entity.VerifiedPlan,
+					EndDate:        endDate.AddDate(0, 0, 30),
+				}).Return(int64(2), nil)
+				auditMock.EXPECT().Record(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(ctx).Return(nil)
 			},
-			want:    nil,
-			wantErr: false,
-			mockFunc: func(mock mockFields, arg args) {
-				mock.subsMock.EXPECT().Create(arg.ctx, entity.Subscription{UserId: int64(1), Plan: entity.UnlimitedPlan, StartDate: Now(), EndDate: Now().AddDate(0, 0, 30)}).Return(int64(0), nil)
+		},
+		{
+			name: "cancelled while charged keeps the period it paid for",
+			cfg:  cfg,
+			want: 1,
+			mockFunc: func() {
+				subsMock.EXPECT().GetDue(ctx, 10).Return([]entity.Subscription{due}, nil)
+				planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(planMock30, nil)
+				chargerMock.EXPECT().Charge(ctx, gomock.Any()).Return("ch_1", nil)
+
+				begin()
+				cancelled := due
+				cancelled.Status = entity.SubscriptionCancelled
+				cancelled.CancelledAt = sql.NullTime{Time: mockTime, Valid: true}
+				subsMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(2)).Return(cancelled, nil)
+				renewed := cancelled
+				renewed.StartDate = endDate
+				renewed.EndDate = endDate.AddDate(0, 0, 30)
+				renewed.PeriodAmount = 99000
+				subsMock.EXPECT().Update(gomock.Any(), renewed).Return(nil)
+				outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionRenewed, int64(1), gomock.Any()).Return(int64(1), nil)
+				auditMock.EXPECT().Record(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name: "renewed by another worker meanwhile is skipped",
+			cfg:  cfg,
+			want: 0,
+			mockFunc: func() {
+				subsMock.EXPECT().GetDue(ctx, 10).Return([]entity.Subscription{due}, nil)
+				planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(planMock30, nil)
+				chargerMock.EXPECT().Charge(ctx, gomock.Any()).Return("ch_1", nil)
+
+				begin()
+				renewed := due
+				renewed.StartDate = endDate
+				renewed.EndDate = endDate.AddDate(0, 0, 30)
+				subsMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(2)).Return(renewed, nil)
+				sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name:    "one failing doesn't hold back the others",
+			cfg:     cfg,
+			want:    1,
+			wantErr: true,
+			mockFunc: func() {
+				cancelled := due
+				cancelled.Status = entity.SubscriptionCancelled
+				other := cancelled
+				other.ID = 3
+				subsMock.EXPECT().GetDue(ctx, 10).Return([]entity.Subscription{cancelled, other}, nil)
+
+				begin()
+				subsMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(2)).Return(cancelled, nil)
+				subsMock.EXPECT().Update(gomock.Any(), gomock.Any()).Return(assert.AnError)
+				log.EXPECT().Error(ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(ctx).Return(nil)
+
+				begin()
+				subsMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(3)).Return(other, nil)
+				subsMock.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+				outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionExpired, int64(1), gomock.Any()).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name: "trial with a card on file converts",
+			cfg:  cfg,
+			want: 1,
+			mockFunc: func() {
+				trial := due
+				trial.TrialEndsAt = sql.NullTime{Time: endDate, Valid: true}
+				trial.TrialConverts = true
+				subsMock.EXPECT().GetDue(ctx, 10).Return([]entity.Subscription{trial}, nil)
+				planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(planMock30, nil)
+				chargerMock.EXPECT().Charge(ctx, gomock.Any()).Return("ch_1", nil)
+
+				begin()
+				subsMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(2)).Return(trial, nil)
+				converted := trial
+				converted.StartDate = endDate
+				converted.EndDate = endDate.AddDate(0, 0, 30)
+				converted.PeriodAmount = 99000
+				subsMock.EXPECT().Update(gomock.Any(), converted).Return(nil)
+				outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionRenewed, int64(1), gomock.Any()).Return(int64(1), nil)
+				auditMock.EXPECT().Record(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name: "trial without a card expires",
+			cfg:  cfg,
+			want: 1,
+			mockFunc: func() {
+				trial := due
+				trial.TrialEndsAt = sql.NullTime{Time: endDate, Valid: true}
+				subsMock.EXPECT().GetDue(ctx, 10).Return([]entity.Subscription{trial}, nil)
+
+				begin()
+				subsMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(2)).Return(trial, nil)
+				expired := trial
+				expired.Status = entity.SubscriptionExpired
+				subsMock.EXPECT().Update(gomock.Any(), expired).Return(nil)
+				outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionExpired, int64(1), gomock.Any()).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name: "declined renewal enters grace",
+			cfg:  cfg,
+			want: 1,
+			mockFunc: func() {
+				subsMock.EXPECT().GetDue(ctx, 10).Return([]entity.Subscription{due}, nil)
+				planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(planMock30, nil)
+				chargerMock.EXPECT().Charge(ctx, gomock.Any()).Return("", gateway.ErrChargeDeclined)
+				log.EXPECT().Error(ctx, gomock.Any())
+
+				begin()
+				subsMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(2)).Return(due, nil)
+				grace := due
+				grace.Status = entity.SubscriptionGrace
+				grace.GraceUntil = sql.NullTime{Time: endDate.Add(72 * time.Hour), Valid: true}
+				grace.RenewalAttempts = 1
+				grace.NextRenewalAt = sql.NullTime{Time: mockTime.Add(12 * time.Hour), Valid: true}
+				subsMock.EXPECT().Update(gomock.Any(), grace).Return(nil)
+				outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionRenewalFailed, int64(1), gomock.Any()).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name: "retry in grace is charged with a new reference",
+			cfg:  cfg,
+			want: 1,
+			mockFunc: func() {
+				grace := due
+				grace.Status = entity.SubscriptionGrace
+				grace.GraceUntil = sql.NullTime{Time: endDate.Add(72 * time.Hour), Valid: true}
+				grace.RenewalAttempts = 1
+				grace.NextRenewalAt = sql.NullTime{Time: mockTime.Add(-time.Minute), Valid: true}
+				subsMock.EXPECT().GetDue(ctx, 10).Return([]entity.Subscription{grace}, nil)
+				planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(planMock30, nil)
+				chargerMock.EXPECT().Charge(ctx, gateway.Charge{
+					Reference:   fmt.Sprintf("subscription:2:%d:1", endDate.Unix()),
+					UserId:      1,
+					Amount:      99000,
+					Currency:    "IDR",
+					Description: entity.UnlimitedPlan,
+				}).Return("ch_2", nil)
+
+				begin()
+				subsMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(2)).Return(grace, nil)
+				subsMock.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, sub entity.Subscription) error {
+					assert.Equal(t, entity.SubscriptionActive, sub.Status)
+					assert.Equal(t, 0, sub.RenewalAttempts)
+					return nil
+				})
+				outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionRenewed, int64(1), gomock.Any()).Return(int64(1), nil)
+				auditMock.EXPECT().Record(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name: "declined renewal without grace expires",
+			cfg:  config.Subscription{},
+			want: 1,
+			mockFunc: func() {
+				subsMock.EXPECT().GetDue(ctx, 10).Return([]entity.Subscription{due}, nil)
+				planMock.EXPECT().GetByCode(ctx, entity.UnlimitedPlan).Return(planMock30, nil)
+				chargerMock.EXPECT().Charge(ctx, gomock.Any()).Return("", gateway.ErrChargeDeclined)
+				log.EXPECT().Error(ctx, gomock.Any())
+
+				begin()
+				subsMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(2)).Return(due, nil)
+				expired := due
+				expired.Status = entity.SubscriptionExpired
+				subsMock.EXPECT().Update(gomock.Any(), expired).Return(nil)
+				outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionExpired, int64(1), gomock.Any()).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(ctx).Return(nil)
+			},
+		},
+		{
+			name: "cancelled and grace over expire without charging",
+			cfg:  cfg,
+			want: 2,
+			mockFunc: func() {
+				cancelled := due
+				cancelled.Status = entity.SubscriptionCancelled
+				graceOver := due
+				graceOver.ID = 3
+				graceOver.Status = entity.SubscriptionGrace
+				graceOver.GraceUntil = sql.NullTime{Time: mockTime.Add(-time.Minute), Valid: true}
+				subsMock.EXPECT().GetDue(ctx, 10).Return([]entity.Subscription{cancelled, graceOver}, nil)
+
+				begin()
+				subsMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(2)).Return(cancelled, nil)
+				begin()
+				subsMock.EXPECT().GetByIdForUpdate(gomock.Any(), int64(3)).Return(graceOver, nil)
+				subsMock.EXPECT().Update(gomock.Any(), gomock.Any()).Times(2).DoAndReturn(func(ctx context.Context, sub entity.Subscription) error {
+					assert.Equal(t, entity.SubscriptionExpired, sub.Status)
+					return nil
+				})
+				outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionExpired, int64(1), gomock.Any()).Times(2).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(ctx).Times(2).Return(nil)
+			},
+		},
+		{
+			name: "reminded before expiry",
+			cfg:  config.Subscription{ExpiryReminder: 72 * time.Hour},
+			want: 1,
+			mockFunc: func() {
+				subsMock.EXPECT().GetDue(ctx, 10).Return(nil, nil)
+
+				begin()
+				expiring := due
+				expiring.EndDate = mockTime.Add(24 * time.Hour)
+				subsMock.EXPECT().GetExpiring(gomock.Any(), 72*time.Hour, 10).Return([]entity.Subscription{expiring}, nil)
+
+				reminded := expiring
+				reminded.ReminderSentAt = sql.NullTime{Time: mockTime, Valid: true}
+				subsMock.EXPECT().Update(gomock.Any(), reminded).Return(nil)
+				outboxMock.EXPECT().Emit(gomock.Any(), entity.EventSubscriptionExpiring, int64(1), gomock.Any()).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(ctx).Return(nil)
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tt.mockFunc(mocks, tt.args)
+			tt.mockFunc()
 
-			d := Init(log, subsMock)
-			err := d.Create(tt.args.ctx, tt.args.param)
+			s := Init(log, tt.cfg, subsMock, planMock, chargerMock, outboxMock, auditMock, atomicMock)
+			got, err := s.Sweep(ctx, 10)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("Get error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("Sweep error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 
-			// assert.Equal(t, tt.want, err)
+			assert.Equal(t, tt.want, got)
 		})
 	}
 }
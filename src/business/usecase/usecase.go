@@ -1,17 +1,35 @@
 package usecase
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"loverly/lib/broker"
 	"loverly/lib/jwt"
 	"loverly/lib/log"
 	"loverly/src/business/domain"
+	"loverly/src/business/entity"
+	"loverly/src/business/usecase/abuse"
+	"loverly/src/business/usecase/audit"
+	"loverly/src/business/usecase/block"
+	"loverly/src/business/usecase/boost"
+	"loverly/src/business/usecase/chat"
 	"loverly/src/business/usecase/dating"
+	"loverly/src/business/usecase/entitlement"
+	"loverly/src/business/usecase/event"
 	"loverly/src/business/usecase/match"
+	"loverly/src/business/usecase/payment"
+	"loverly/src/business/usecase/plan"
 	"loverly/src/business/usecase/profile"
+	"loverly/src/business/usecase/ratelimit"
+	"loverly/src/business/usecase/realtime"
+	"loverly/src/business/usecase/report"
 	"loverly/src/business/usecase/subscription"
 	"loverly/src/business/usecase/user"
 	"loverly/src/config"
 
 	"loverly/lib/atomic"
+	gateway "loverly/lib/payment"
 
 	"go.opentelemetry.io/otel/trace"
 )
@@ -22,14 +40,115 @@ type Usecases struct {
 	Subscription subscription.Interface
 	Match        match.Interface
 	Profile      profile.Interface
+	Boost        boost.Interface
+	Event        event.Interface
+	Chat         chat.Interface
+	Realtime     realtime.Interface
+	Block        block.Interface
+	Report       report.Interface
+	RateLimit    ratelimit.Interface
+	Abuse        abuse.Interface
+	Audit        audit.Interface
+	Plan         plan.Interface
+	Payment      payment.Interface
+	Entitlement  entitlement.Interface
 }
 
-func Init(log log.Interface, cfg config.Configuration, jwt jwt.TokenProvider, dom domain.Domains, atomic atomic.AtomicSessionProvider, tr trace.Tracer) *Usecases {
-	return &Usecases{
-		User:         user.Init(log, &jwt, dom.User, dom.Profile, atomic),
-		Dating:       dating.Init(log, dom.Subscription, dom.Profile, dom.Swipe, dom.Match),
-		Subscription: subscription.Init(log, dom.Subscription),
-		Match:        match.Init(log, dom.Match, dom.Profile),
+func Init(log log.Interface, cfg config.Configuration, jwt jwt.TokenProvider, dom domain.Domains, atomic atomic.AtomicSessionProvider, br broker.Interface, pg gateway.Interface, ch gateway.Charger, tr trace.Tracer) *Usecases {
+	uc := &Usecases{
+		User:         user.Init(log, &jwt, dom.User, dom.Profile, dom.Outbox, dom.Audit, atomic),
+		Dating:       dating.Init(log, cfg.Discovery, cfg.Abuse, dom.Subscription, dom.Profile, dom.Swipe, dom.Match, dom.Boost, dom.Deck, dom.Recommendation, dom.Abuse, dom.Outbox, atomic),
+		Subscription: subscription.Init(log, cfg.Subscription, dom.Subscription, dom.Plan, ch, dom.Outbox, dom.Audit, atomic),
+		Match:        match.Init(log, cfg.Match, dom.Match, dom.Profile, dom.Outbox, atomic),
 		Profile:      profile.Init(log, dom.Profile),
+		Boost:        boost.Init(log, cfg.Boost, dom.Boost, dom.Profile, dom.Subscription, atomic),
+		Event:        event.Init(log, cfg.Event, dom.Outbox, br, atomic),
+		Chat:         chat.Init(log, cfg.Match, dom.Match, dom.Message, dom.Profile, dom.Outbox, atomic),
+		Realtime:     realtime.Init(log, cfg.Realtime, dom.Notification),
+		Block:        block.Init(log, dom.Block, dom.Match, dom.Profile, dom.Deck, atomic),
+		Report:       report.Init(log, dom.Report, dom.User, dom.Profile, dom.Message, dom.Audit, atomic),
+		RateLimit:    ratelimit.Init(log, cfg.RateLimit, dom.RateLimit),
+		Abuse:        abuse.Init(log, dom.Abuse, dom.Swipe, dom.Audit, atomic),
+		Audit:        audit.Init(log, dom.Audit),
+		Plan:         plan.Init(log, dom.Plan),
+		Payment:      payment.Init(log, cfg.Payment, pg, dom.Payment, dom.Plan, dom.Subscription, dom.Coupon, dom.Boost, dom.Outbox, dom.Audit, atomic),
+		Entitlement:  entitlement.Init(log, dom.Subscription),
 	}
+
+	subscribe(uc)
+
+	return uc
+}
+
+// subscribe wires the in-process reactions to domain events
+func subscribe(uc *Usecases) {
+	// new users get their first deck before they open discovery
+	uc.Event.Subscribe(entity.EventUserRegistered, func(ctx context.Context, ev entity.Event) error {
+		return uc.Dating.RefillDeck(ctx, ev.UserId)
+	})
+
+	// the bot detector looks at swipes after the fact, a swipe never waits for it
+	uc.Event.Subscribe(entity.EventSwipeRecorded, func(ctx context.Context, ev entity.Event) error {
+		var payload entity.SwipeRecordedPayload
+		if err := json.Unmarshal(ev.Payload, &payload); err != nil {
+			return err
+		}
+
+		return uc.Dating.Inspect(ctx, payload)
+	})
+
+	// real-time notifications, the payload of the event is passed on as is. a failed handler or
+	// broker publish redelivers the event to every handler, so a push is done once per event and user
+	push := func(ctx context.Context, ev entity.Event, userId int64, notificationType string) error {
+		key := fmt.Sprintf("%d:%d", ev.ID, userId)
+		return uc.Realtime.PushOnce(ctx, key, userId, notificationType, ev.Payload)
+	}
+
+	uc.Event.Subscribe(entity.EventMatchCreated, func(ctx context.Context, ev entity.Event) error {
+		var payload entity.MatchCreatedPayload
+		if err := json.Unmarshal(ev.Payload, &payload); err != nil {
+			return err
+		}
+
+		for _, userId := range []int64{payload.UserId1, payload.UserId2} {
+			if err := push(ctx, ev, userId, entity.NotificationMatchCreated); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	pushMatchExpiry := func(notificationType string) event.Handler {
+		return func(ctx context.Context, ev entity.Event) error {
+			var payload entity.MatchExpiryPayload
+			if err := json.Unmarshal(ev.Payload, &payload); err != nil {
+				return err
+			}
+
+			for _, userId := range []int64{payload.UserId1, payload.UserId2} {
+				if err := push(ctx, ev, userId, notificationType); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+	}
+	uc.Event.Subscribe(entity.EventMatchExpiring, pushMatchExpiry(entity.NotificationMatchExpiring))
+	uc.Event.Subscribe(entity.EventMatchExpired, pushMatchExpiry(entity.NotificationMatchExpired))
+
+	pushUser := func(notificationType string) event.Handler {
+		return func(ctx context.Context, ev entity.Event) error {
+			return push(ctx, ev, ev.UserId, notificationType)
+		}
+	}
+	uc.Event.Subscribe(entity.EventLikeReceived, pushUser(entity.NotificationLikeReceived))
+	uc.Event.Subscribe(entity.EventMessageSent, pushUser(entity.NotificationMessageReceived))
+	uc.Event.Subscribe(entity.EventSubscriptionStarted, pushUser(entity.NotificationSubscriptionActivated))
+	uc.Event.Subscribe(entity.EventSubscriptionExpiring, pushUser(entity.NotificationSubscriptionExpiring))
+	uc.Event.Subscribe(entity.EventSubscriptionRenewed, pushUser(entity.NotificationSubscriptionRenewed))
+	uc.Event.Subscribe(entity.EventSubscriptionRenewalFailed, pushUser(entity.NotificationSubscriptionFailed))
+	uc.Event.Subscribe(entity.EventSubscriptionExpired, pushUser(entity.NotificationSubscriptionExpired))
+	uc.Event.Subscribe(entity.EventSubscriptionChanged, pushUser(entity.NotificationSubscriptionChanged))
 }
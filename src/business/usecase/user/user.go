@@ -2,13 +2,19 @@ package user
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"loverly/lib/appcontext"
 	"loverly/lib/atomic"
 	"loverly/lib/jwt"
 	"loverly/lib/log"
+	"loverly/src/business/domain/audit"
+	"loverly/src/business/domain/outbox"
 	"loverly/src/business/domain/profile"
 	"loverly/src/business/domain/user"
 	"loverly/src/business/entity"
 	appErr "loverly/src/errors"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -16,21 +22,30 @@ import (
 type Interface interface {
 	SignIn(ctx context.Context, params entity.SignInParam) (*entity.SignInResponse, error)
 	SignUp(ctx context.Context, params entity.SignUpParam) (*entity.SignUpResponse, error)
+	ChangePassword(ctx context.Context, params entity.ChangePasswordParam) error
+	CheckStatus(ctx context.Context, userId int64) error
+	CheckToken(ctx context.Context, userId int64, version int) error
 }
 
+var Now = time.Now
+
 type customer struct {
 	log     log.Interface
 	user    user.Interface
 	profile profile.Interface
+	outbox  outbox.Interface
+	audit   audit.Interface
 	jwt     *jwt.TokenProvider
 	atomic  atomic.AtomicSessionProvider
 }
 
-func Init(log log.Interface, jwt *jwt.TokenProvider, u user.Interface, p profile.Interface, a atomic.AtomicSessionProvider) Interface {
+func Init(log log.Interface, jwt *jwt.TokenProvider, u user.Interface, p profile.Interface, o outbox.Interface, au audit.Interface, a atomic.AtomicSessionProvider) Interface {
 	return &customer{
 		log:     log,
 		user:    u,
 		profile: p,
+		outbox:  o,
+		audit:   au,
 		jwt:     jwt,
 		atomic:  a,
 	}
@@ -41,15 +56,32 @@ func (c *customer) SignIn(ctx context.Context, params entity.SignInParam) (*enti
 
 	user, err := c.user.GetByEmail(ctx, params.Email)
 	if err != nil {
+		c.loginFailed(ctx, user.ID, params.Email, appErr.ErrInvalidEmailOrPassword)
 		return resp, appErr.ErrInvalidEmailOrPassword
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(params.Password))
 	if err != nil {
+		c.loginFailed(ctx, user.ID, params.Email, appErr.ErrPasswordNotMatch)
 		return resp, appErr.ErrPasswordNotMatch
 	}
 
-	token, err := c.jwt.NewAccessToken(ctx, user.ID, []string{}, jwt.AccessTypeOnline)
+	if err := checkStatus(user); err != nil {
+		c.loginFailed(ctx, user.ID, params.Email, err)
+		return resp, err
+	}
+
+	// nobody gets a token without it being on record
+	if _, err := c.audit.Record(ctx, entity.AuditLog{
+		ActorId:    sql.NullInt64{Int64: user.ID, Valid: true},
+		Action:     entity.AuditLogin,
+		TargetType: entity.AuditTargetUser,
+		TargetId:   sql.NullInt64{Int64: user.ID, Valid: true},
+	}, entity.AuditLoginDetails{Email: user.Email}); err != nil {
+		return resp, err
+	}
+
+	token, err := c.jwt.NewAccessToken(ctx, user.ID, user.TokenVersion, user.Role, []string{}, jwt.AccessTypeOnline)
 	if err != nil {
 		return resp, err
 	}
@@ -84,6 +116,14 @@ func (c *customer) SignUp(ctx context.Context, params entity.SignUpParam) (*enti
 			FullName: params.FullName,
 			Gender:   params.Gender,
 		})
+		if err != nil {
+			return err
+		}
+
+		_, err = c.outbox.Emit(ctx, entity.EventUserRegistered, userId, entity.UserRegisteredPayload{
+			UserId: userId,
+			Email:  params.Email,
+		})
 
 		return err
 	})
@@ -95,3 +135,101 @@ func (c *customer) SignUp(ctx context.Context, params entity.SignUpParam) (*enti
 		NextState: entity.NextStateLogin,
 	}, nil
 }
+
+// ChangePassword replaces the password of the user making the request once the old one is confirmed
+func (c *customer) ChangePassword(ctx context.Context, params entity.ChangePasswordParam) error {
+	userId := appcontext.GetUserId(ctx)
+	if userId < 1 {
+		return appErr.ErrInvalidUserId
+	}
+
+	user, err := c.user.GetById(ctx, int64(userId))
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(params.OldPassword)); err != nil {
+		return appErr.ErrPasswordNotMatch
+	}
+
+	hashPassword, err := bcrypt.GenerateFromPassword([]byte(params.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	// bumping the token version revokes every token issued before, this one included
+	err = atomic.Atomic(ctx, c.atomic, c.log, func(ctx context.Context) error {
+		if err := c.user.UpdatePassword(ctx, entity.User{ID: user.ID, Password: string(hashPassword)}); err != nil {
+			return err
+		}
+
+		_, err := c.audit.Record(ctx, entity.AuditLog{
+			ActorId:    sql.NullInt64{Int64: user.ID, Valid: true},
+			Action:     entity.AuditPasswordChanged,
+			TargetType: entity.AuditTargetUser,
+			TargetId:   sql.NullInt64{Int64: user.ID, Valid: true},
+		}, nil)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	c.user.Invalidate(ctx)
+
+	return nil
+}
+
+// CheckStatus rejects tokens of users banned or suspended after they logged in
+func (c *customer) CheckStatus(ctx context.Context, userId int64) error {
+	user, err := c.user.GetById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	return checkStatus(user)
+}
+
+// CheckToken rejects tokens issued before the last password change on top of CheckStatus
+func (c *customer) CheckToken(ctx context.Context, userId int64, version int) error {
+	user, err := c.user.GetById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if user.TokenVersion != version {
+		return appErr.ErrTokenRevoked
+	}
+
+	return checkStatus(user)
+}
+
+// checkStatus lets a suspended user back in once the suspension is over
+func checkStatus(user entity.User) error {
+	switch user.Status {
+	case entity.UserStatusBanned:
+		return appErr.ErrAccountBanned
+	case entity.UserStatusSuspended:
+		if user.SuspendedUntil.Valid && user.SuspendedUntil.Time.After(Now()) {
+			return appErr.ErrAccountSuspended
+		}
+	}
+
+	return nil
+}
+
+// loginFailed records a rejected login, the attempt is rejected anyway when recording fails
+func (c *customer) loginFailed(ctx context.Context, userId int64, email string, reason error) {
+	entry := entity.AuditLog{
+		Action:     entity.AuditLoginFailed,
+		TargetType: entity.AuditTargetUser,
+	}
+	if userId > 0 {
+		entry.TargetId = sql.NullInt64{Int64: userId, Valid: true}
+	}
+
+	if _, err := c.audit.Record(ctx, entry, entity.AuditLoginDetails{Email: email, Reason: reason.Error()}); err != nil {
+		c.log.Error(ctx, fmt.Sprintf("loginFailed err: %v", err))
+	}
+}
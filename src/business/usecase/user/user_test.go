@@ -2,18 +2,26 @@ package user
 
 import (
 	"context"
+	"database/sql"
 	"loverly/lib/appcontext"
+	"loverly/lib/atomic"
+	mock_atomic "loverly/lib/atomic/mock"
 	mock_log "loverly/lib/log/mock"
+	mock_audit "loverly/src/business/domain/mock/audit"
+	mock_outbox "loverly/src/business/domain/mock/outbox"
 	mock_profile "loverly/src/business/domain/mock/profile"
 	mock_user "loverly/src/business/domain/mock/user"
 	"loverly/src/business/entity"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.opentelemetry.io/otel"
 	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
 
 	atomicSQLX "loverly/lib/atomic/sqlx"
+	appErr "loverly/src/errors"
 )
 
 func TestSignIn(t *testing.T) {
@@ -23,16 +31,24 @@ func TestSignIn(t *testing.T) {
 	log := mock_log.NewMockInterface(ctrl)
 	userMock := mock_user.NewMockInterface(ctrl)
 	profileMock := mock_profile.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	auditMock := mock_audit.NewMockInterface(ctrl)
 
 	tracer := otel.Tracer("test")
 	atomicSessionProvider := atomicSQLX.NewSqlxAtomicSessionProvider(nil, tracer, log)
 
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+
 	type mockFields struct {
-		userMock *mock_user.MockInterface
+		log       *mock_log.MockInterface
+		userMock  *mock_user.MockInterface
+		auditMock *mock_audit.MockInterface
 	}
 
 	mocks := mockFields{
-		userMock: userMock,
+		log:       log,
+		userMock:  userMock,
+		auditMock: auditMock,
 	}
 
 	type args struct {
@@ -61,6 +77,70 @@ func TestSignIn(t *testing.T) {
 			wantErr: true,
 			mockFunc: func(mock mockFields, arg args) {
 				mock.userMock.EXPECT().GetByEmail(arg.ctx, arg.param.Email).Return(entity.User{}, assert.AnError)
+				mock.auditMock.EXPECT().Record(arg.ctx, entity.AuditLog{Action: entity.AuditLoginFailed, TargetType: entity.AuditTargetUser},
+					entity.AuditLoginDetails{Email: "test", Reason: appErr.ErrInvalidEmailOrPassword.Error()}).Return(int64(1), nil)
+			},
+		},
+		{
+			name: "err banned",
+			args: args{
+				ctx:   context.Background(),
+				param: entity.SignInParam{Email: "test", Password: "password"},
+			},
+			want:    &entity.SignInResponse{},
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.userMock.EXPECT().GetByEmail(arg.ctx, arg.param.Email).Return(entity.User{ID: 1, Password: string(hash), Status: entity.UserStatusBanned}, nil)
+				mock.auditMock.EXPECT().Record(arg.ctx, entity.AuditLog{Action: entity.AuditLoginFailed, TargetType: entity.AuditTargetUser, TargetId: sql.NullInt64{Int64: 1, Valid: true}},
+					entity.AuditLoginDetails{Email: "test", Reason: appErr.ErrAccountBanned.Error()}).Return(int64(1), nil)
+			},
+		},
+		{
+			name: "err still suspended",
+			args: args{
+				ctx:   context.Background(),
+				param: entity.SignInParam{Email: "test", Password: "password"},
+			},
+			want:    &entity.SignInResponse{},
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.userMock.EXPECT().GetByEmail(arg.ctx, arg.param.Email).Return(entity.User{
+					ID: 1, Password: string(hash), Status: entity.UserStatusSuspended, SuspendedUntil: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+				}, nil)
+				mock.auditMock.EXPECT().Record(arg.ctx, gomock.Any(), entity.AuditLoginDetails{Email: "test", Reason: appErr.ErrAccountSuspended.Error()}).Return(int64(1), nil)
+			},
+		},
+		{
+			name: "err wrong password is recorded even when recording fails",
+			args: args{
+				ctx:   context.Background(),
+				param: entity.SignInParam{Email: "test", Password: "wrong password"},
+			},
+			want:    &entity.SignInResponse{},
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.userMock.EXPECT().GetByEmail(arg.ctx, arg.param.Email).Return(entity.User{ID: 1, Password: string(hash)}, nil)
+				mock.auditMock.EXPECT().Record(arg.ctx, entity.AuditLog{Action: entity.AuditLoginFailed, TargetType: entity.AuditTargetUser, TargetId: sql.NullInt64{Int64: 1, Valid: true}},
+					entity.AuditLoginDetails{Email: "test", Reason: appErr.ErrPasswordNotMatch.Error()}).Return(int64(0), assert.AnError)
+				mock.log.EXPECT().Error(arg.ctx, gomock.Any())
+			},
+		},
+		{
+			name: "err record login",
+			args: args{
+				ctx:   context.Background(),
+				param: entity.SignInParam{Email: "test", Password: "password"},
+			},
+			want:    &entity.SignInResponse{},
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.userMock.EXPECT().GetByEmail(arg.ctx, arg.param.Email).Return(entity.User{ID: 1, Email: "test", Password: string(hash)}, nil)
+				mock.auditMock.EXPECT().Record(arg.ctx, entity.AuditLog{
+					ActorId:    sql.NullInt64{Int64: 1, Valid: true},
+					Action:     entity.AuditLogin,
+					TargetType: entity.AuditTargetUser,
+					TargetId:   sql.NullInt64{Int64: 1, Valid: true},
+				}, entity.AuditLoginDetails{Email: "test"}).Return(int64(0), assert.AnError)
 			},
 		},
 	}
@@ -69,7 +149,7 @@ func TestSignIn(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockFunc(mocks, tt.args)
 
-			d := Init(log, nil, userMock, profileMock, atomicSessionProvider)
+			d := Init(log, nil, userMock, profileMock, outboxMock, auditMock, atomicSessionProvider)
 			got, err := d.SignIn(tt.args.ctx, tt.args.param)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("SignIn error = %v, wantErr %v", err, tt.wantErr)
@@ -80,3 +160,417 @@ func TestSignIn(t *testing.T) {
 		})
 	}
 }
+
+func TestSignUp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	userMock := mock_user.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	auditMock := mock_audit.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
+
+	type mockFields struct {
+		userMock    *mock_user.MockInterface
+		profileMock *mock_profile.MockInterface
+		outboxMock  *mock_outbox.MockInterface
+		atomicMock  *mock_atomic.MockAtomicSessionProvider
+		sessionMock *mock_atomic.MockAtomicSession
+	}
+
+	mocks := mockFields{
+		userMock:    userMock,
+		profileMock: profileMock,
+		outboxMock:  outboxMock,
+		atomicMock:  atomicMock,
+		sessionMock: sessionMock,
+	}
+
+	type args struct {
+		ctx   context.Context
+		param entity.SignUpParam
+	}
+
+	paramMock := entity.SignUpParam{Email: "test@mail.com", Password: "test", FullName: "test", Gender: entity.Female}
+
+	tests := []struct {
+		name     string
+		mockFunc func(mock mockFields, arg args)
+		args     args
+		want     *entity.SignUpResponse
+		wantErr  bool
+	}{
+		{
+			name: "err emit user registered",
+			args: args{
+				ctx:   context.Background(),
+				param: paramMock,
+			},
+			want:    &entity.SignUpResponse{},
+			wantErr: true,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.userMock.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.profileMock.EXPECT().Create(gomock.Any(), entity.Profile{UserId: 1, FullName: "test", Gender: entity.Female}).Return(int64(1), nil)
+				mock.outboxMock.EXPECT().Emit(gomock.Any(), entity.EventUserRegistered, int64(1), entity.UserRegisteredPayload{UserId: 1, Email: "test@mail.com"}).Return(int64(0), assert.AnError)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				mock.sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "all goods",
+			args: args{
+				ctx:   context.Background(),
+				param: paramMock,
+			},
+			want:    &entity.SignUpResponse{NextState: entity.NextStateLogin},
+			wantErr: false,
+			mockFunc: func(mock mockFields, arg args) {
+				mock.atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, mock.sessionMock), nil)
+				mock.userMock.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mock.profileMock.EXPECT().Create(gomock.Any(), entity.Profile{UserId: 1, FullName: "test", Gender: entity.Female}).Return(int64(1), nil)
+				mock.outboxMock.EXPECT().Emit(gomock.Any(), entity.EventUserRegistered, int64(1), entity.UserRegisteredPayload{UserId: 1, Email: "test@mail.com"}).Return(int64(1), nil)
+				mock.sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(mocks, tt.args)
+
+			d := Init(log, nil, userMock, profileMock, outboxMock, auditMock, atomicMock)
+			got, err := d.SignUp(tt.args.ctx, tt.args.param)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SignUp error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	userMock := mock_user.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	auditMock := mock_audit.NewMockInterface(ctrl)
+	atomicMock := mock_atomic.NewMockAtomicSessionProvider(ctrl)
+	sessionMock := mock_atomic.NewMockAtomicSession(ctrl)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+
+	// the new hash is salted, only the password it was made from can be checked
+	newPassword := gomock.Cond(func(x any) bool {
+		u := x.(entity.User)
+		return u.ID == 1 && bcrypt.CompareHashAndPassword([]byte(u.Password), []byte("new password")) == nil
+	})
+
+	type args struct {
+		ctx   context.Context
+		param entity.ChangePasswordParam
+	}
+
+	paramMock := entity.ChangePasswordParam{OldPassword: "password", Password: "new password", ConfirmPassword: "new password"}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		wantErr  error
+	}{
+		{
+			name: "err invalid user id",
+			args: args{
+				ctx:   context.Background(),
+				param: paramMock,
+			},
+			wantErr:  appErr.ErrInvalidUserId,
+			mockFunc: func(arg args) {},
+		},
+		{
+			name: "err wrong old password",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: entity.ChangePasswordParam{OldPassword: "wrong", Password: "new password", ConfirmPassword: "new password"},
+			},
+			wantErr: appErr.ErrPasswordNotMatch,
+			mockFunc: func(arg args) {
+				userMock.EXPECT().GetById(arg.ctx, int64(1)).Return(entity.User{ID: 1, Password: string(hash)}, nil)
+			},
+		},
+		{
+			name: "err record password change",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: paramMock,
+			},
+			wantErr: assert.AnError,
+			mockFunc: func(arg args) {
+				userMock.EXPECT().GetById(arg.ctx, int64(1)).Return(entity.User{ID: 1, Password: string(hash)}, nil)
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				userMock.EXPECT().UpdatePassword(gomock.Any(), newPassword).Return(nil)
+				auditMock.EXPECT().Record(gomock.Any(), gomock.Any(), nil).Return(int64(0), assert.AnError)
+				log.EXPECT().Error(arg.ctx, gomock.Any())
+				sessionMock.EXPECT().Rollback(arg.ctx).Return(nil)
+			},
+		},
+		{
+			name: "all goods",
+			args: args{
+				ctx:   appcontext.SetUserId(context.Background(), 1),
+				param: paramMock,
+			},
+			wantErr: nil,
+			mockFunc: func(arg args) {
+				userMock.EXPECT().GetById(arg.ctx, int64(1)).Return(entity.User{ID: 1, Password: string(hash)}, nil)
+				atomicMock.EXPECT().BeginSession(arg.ctx).Return(atomic.NewAtomicSessionContext(arg.ctx, sessionMock), nil)
+				userMock.EXPECT().UpdatePassword(gomock.Any(), newPassword).Return(nil)
+				auditMock.EXPECT().Record(gomock.Any(), entity.AuditLog{
+					ActorId:    sql.NullInt64{Int64: 1, Valid: true},
+					Action:     entity.AuditPasswordChanged,
+					TargetType: entity.AuditTargetUser,
+					TargetId:   sql.NullInt64{Int64: 1, Valid: true},
+				}, nil).Return(int64(1), nil)
+				sessionMock.EXPECT().Commit(arg.ctx).Return(nil)
+				userMock.EXPECT().Invalidate(arg.ctx)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			d := Init(log, nil, userMock, profileMock, outboxMock, auditMock, atomicMock)
+			err := d.ChangePassword(tt.args.ctx, tt.args.param)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCheckStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	userMock := mock_user.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	auditMock := mock_audit.NewMockInterface(ctrl)
+
+	tracer := otel.Tracer("test")
+	atomicSessionProvider := atomicSQLX.NewSqlxAtomicSessionProvider(nil, tracer, log)
+
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	Now = func() time.Time { return now }
+	defer func() { Now = time.Now }()
+
+	type args struct {
+		ctx    context.Context
+		userId int64
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		wantErr  error
+	}{
+		{
+			name: "err get user",
+			args: args{
+				ctx:    context.Background(),
+				userId: 1,
+			},
+			wantErr: sql.ErrNoRows,
+			mockFunc: func(arg args) {
+				userMock.EXPECT().GetById(arg.ctx, int64(1)).Return(entity.User{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name: "err banned",
+			args: args{
+				ctx:    context.Background(),
+				userId: 1,
+			},
+			wantErr: appErr.ErrAccountBanned,
+			mockFunc: func(arg args) {
+				userMock.EXPECT().GetById(arg.ctx, int64(1)).Return(entity.User{ID: 1, Status: entity.UserStatusBanned}, nil)
+			},
+		},
+		{
+			name: "err suspended",
+			args: args{
+				ctx:    context.Background(),
+				userId: 1,
+			},
+			wantErr: appErr.ErrAccountSuspended,
+			mockFunc: func(arg args) {
+				userMock.EXPECT().GetById(arg.ctx, int64(1)).Return(entity.User{
+					ID: 1, Status: entity.UserStatusSuspended, SuspendedUntil: sql.NullTime{Time: now.Add(time.Minute), Valid: true},
+				}, nil)
+			},
+		},
+		{
+			name: "suspension is over",
+			args: args{
+				ctx:    context.Background(),
+				userId: 1,
+			},
+			wantErr: nil,
+			mockFunc: func(arg args) {
+				userMock.EXPECT().GetById(arg.ctx, int64(1)).Return(entity.User{
+					ID: 1, Status: entity.UserStatusSuspended, SuspendedUntil: sql.NullTime{Time: now, Valid: true},
+				}, nil)
+			},
+		},
+		{
+			name: "active",
+			args: args{
+				ctx:    context.Background(),
+				userId: 1,
+			},
+			wantErr: nil,
+			mockFunc: func(arg args) {
+				userMock.EXPECT().GetById(arg.ctx, int64(1)).Return(entity.User{ID: 1, Status: entity.UserStatusActive}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			d := Init(log, nil, userMock, profileMock, outboxMock, auditMock, atomicSessionProvider)
+			err := d.CheckStatus(tt.args.ctx, tt.args.userId)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCheckToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	log := mock_log.NewMockInterface(ctrl)
+	userMock := mock_user.NewMockInterface(ctrl)
+	profileMock := mock_profile.NewMockInterface(ctrl)
+	outboxMock := mock_outbox.NewMockInterface(ctrl)
+	auditMock := mock_audit.NewMockInterface(ctrl)
+
+	tracer := otel.Tracer("test")
+	atomicSessionProvider := atomicSQLX.NewSqlxAtomicSessionProvider(nil, tracer, log)
+
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	Now = func() time.Time { return now }
+	defer func() { Now = time.Now }()
+
+	type args struct {
+		ctx     context.Context
+		userId  int64
+		version int
+	}
+
+	tests := []struct {
+		name     string
+		mockFunc func(arg args)
+		args     args
+		wantErr  error
+	}{
+		{
+			name: "err get user",
+			args: args{
+				ctx:     context.Background(),
+				userId:  1,
+				version: 1,
+			},
+			wantErr: sql.ErrNoRows,
+			mockFunc: func(arg args) {
+				userMock.EXPECT().GetById(arg.ctx, int64(1)).Return(entity.User{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name: "err token issued before the password changed",
+			args: args{
+				ctx:     context.Background(),
+				userId:  1,
+				version: 1,
+			},
+			wantErr: appErr.ErrTokenRevoked,
+			mockFunc: func(arg args) {
+				userMock.EXPECT().GetById(arg.ctx, int64(1)).Return(entity.User{ID: 1, Status: entity.UserStatusActive, TokenVersion: 2}, nil)
+			},
+		},
+		{
+			name: "err banned",
+			args: args{
+				ctx:     context.Background(),
+				userId:  1,
+				version: 1,
+			},
+			wantErr: appErr.ErrAccountBanned,
+			mockFunc: func(arg args) {
+				userMock.EXPECT().GetById(arg.ctx, int64(1)).Return(entity.User{ID: 1, Status: entity.UserStatusBanned, TokenVersion: 1}, nil)
+			},
+		},
+		{
+			name: "err suspended",
+			args: args{
+				ctx:     context.Background(),
+				userId:  1,
+				version: 1,
+			},
+			wantErr: appErr.ErrAccountSuspended,
+			mockFunc: func(arg args) {
+				userMock.EXPECT().GetById(arg.ctx, int64(1)).Return(entity.User{
+					ID: 1, Status: entity.UserStatusSuspended, TokenVersion: 1, SuspendedUntil: sql.NullTime{Time: now.Add(time.Minute), Valid: true},
+				}, nil)
+			},
+		},
+		{
+			name: "suspension is over",
+			args: args{
+				ctx:     context.Background(),
+				userId:  1,
+				version: 1,
+			},
+			wantErr: nil,
+			mockFunc: func(arg args) {
+				userMock.EXPECT().GetById(arg.ctx, int64(1)).Return(entity.User{
+					ID: 1, Status: entity.UserStatusSuspended, TokenVersion: 1, SuspendedUntil: sql.NullTime{Time: now, Valid: true},
+				}, nil)
+			},
+		},
+		{
+			name: "active",
+			args: args{
+				ctx:     context.Background(),
+				userId:  1,
+				version: 1,
+			},
+			wantErr: nil,
+			mockFunc: func(arg args) {
+				userMock.EXPECT().GetById(arg.ctx, int64(1)).Return(entity.User{ID: 1, Status: entity.UserStatusActive, TokenVersion: 1}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockFunc(tt.args)
+
+			d := Init(log, nil, userMock, profileMock, outboxMock, auditMock, atomicSessionProvider)
+			err := d.CheckToken(tt.args.ctx, tt.args.userId, tt.args.version)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
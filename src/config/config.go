@@ -53,6 +53,114 @@ type (
 		VerifyKey string `mapstructure:"ACCESS_TOKEN_RSA256_PUBLIC_KEY" validate:"required"`  //RSA Public Key in PEM
 	}
 
+	Discovery struct {
+		PassCoolOff      time.Duration `mapstructure:"DISCOVERY_PASS_COOL_OFF" validate:"required"` //How long a passed profile stays hidden before resurfacing
+		PageSize         int           `mapstructure:"DISCOVERY_PAGE_SIZE" validate:"required"`     //Profiles popped from the deck per request
+		DeckSize         int           `mapstructure:"DISCOVERY_DECK_SIZE" validate:"required"`     //Profiles precomputed per user
+		DeckTTL          time.Duration `mapstructure:"DISCOVERY_DECK_TTL" validate:"required"`
+		RefillThreshold  int64         `mapstructure:"DISCOVERY_DECK_REFILL_THRESHOLD"`                //Optional, refill once the deck drops below it, default to 0
+		ActiveWindow     time.Duration `mapstructure:"DISCOVERY_ACTIVE_WINDOW" validate:"required"`    //Users that opened discovery within it keep a deck
+		RecommendedShare int           `mapstructure:"DISCOVERY_RECOMMENDED_SHARE" validate:"max=100"` //Optional, percentage of deck slots given to recommendations, default to 0
+	}
+
+	Recommender struct {
+		Lookback   time.Duration `mapstructure:"RECOMMENDER_LOOKBACK" validate:"required"`   //Swipes older than this are ignored
+		Candidates int           `mapstructure:"RECOMMENDER_CANDIDATES" validate:"required"` //Candidates kept per user
+	}
+
+	Worker struct {
+		DeckInterval          time.Duration `mapstructure:"WORKER_DECK_INTERVAL" validate:"required"`
+		DeckBatchSize         int64         `mapstructure:"WORKER_DECK_BATCH_SIZE" validate:"required"` //Refill requests drained per tick
+		OutboxInterval        time.Duration `mapstructure:"WORKER_OUTBOX_INTERVAL" validate:"required"`
+		QuotaResetAt          time.Duration `mapstructure:"WORKER_QUOTA_RESET_AT"` //Optional, offset from local midnight where swipe quotas reset, default to 0
+		MatchInterval         time.Duration `mapstructure:"WORKER_MATCH_INTERVAL" validate:"required"`
+		MatchBatchSize        int           `mapstructure:"WORKER_MATCH_BATCH_SIZE" validate:"required"` //Matches expired or warned per tick
+		SubscriptionInterval  time.Duration `mapstructure:"WORKER_SUBSCRIPTION_INTERVAL" validate:"required"`
+		SubscriptionBatchSize int           `mapstructure:"WORKER_SUBSCRIPTION_BATCH_SIZE" validate:"required"` //Subscriptions renewed, expired or reminded per tick
+		BoostInterval         time.Duration `mapstructure:"WORKER_BOOST_INTERVAL" validate:"required"`
+		BoostBatchSize        int           `mapstructure:"WORKER_BOOST_BATCH_SIZE" validate:"required"` //Boosts over summarized per tick
+		RefundInterval        time.Duration `mapstructure:"WORKER_REFUND_INTERVAL" validate:"required"`
+		RefundBatchSize       int           `mapstructure:"WORKER_REFUND_BATCH_SIZE" validate:"required"` //Payments refunded per tick
+	}
+
+	Realtime struct {
+		PingInterval time.Duration `mapstructure:"REALTIME_PING_INTERVAL" validate:"required"`
+		PongWait     time.Duration `mapstructure:"REALTIME_PONG_WAIT" validate:"required,gtfield=PingInterval"` //Connections silent for longer are dropped
+		WriteWait    time.Duration `mapstructure:"REALTIME_WRITE_WAIT" validate:"required"`
+		SendBuffer   int           `mapstructure:"REALTIME_SEND_BUFFER" validate:"required"`   //Notifications queued per connection before it's dropped as too slow
+		TicketTTL    time.Duration `mapstructure:"REALTIME_TICKET_TTL" validate:"required"`    //Time to open the websocket with a ticket, it's single-use
+		DedupeWindow time.Duration `mapstructure:"REALTIME_DEDUPE_WINDOW" validate:"required"` //A notification of a redelivered event isn't pushed again for this long
+	}
+
+	Event struct {
+		Broker         string `mapstructure:"EVENT_BROKER" validate:"required,oneof=redis none"`
+		StreamPrefix   string `mapstructure:"EVENT_STREAM_PREFIX"`                        //Optional, prepended to the event type to name the redis stream, default to ""
+		StreamMaxLen   int64  `mapstructure:"EVENT_STREAM_MAX_LEN"`                       //Optional, approximate entries kept per stream, default to 0 (unbounded)
+		RelayBatchSize int    `mapstructure:"EVENT_RELAY_BATCH_SIZE" validate:"required"` //Outbox events delivered per tick
+		MaxAttempts    int    `mapstructure:"EVENT_MAX_ATTEMPTS" validate:"required"`     //Failed deliveries before an event is left for inspection
+	}
+
+	Match struct {
+		ExpireAfter   time.Duration `mapstructure:"MATCH_EXPIRE_AFTER"`                                                         //Optional, matches nobody engages with are removed after it, default to 0 (never)
+		ExpiryWarning time.Duration `mapstructure:"MATCH_EXPIRY_WARNING"`                                                       //Optional, both users are warned this long before expiry, default to 0 (no warning)
+		ExtendBy      time.Duration `mapstructure:"MATCH_EXTEND_BY" validate:"required"`                                        //Extra time a premium user buys an expiring match
+		FirstMove     string        `mapstructure:"MATCH_FIRST_MOVE" validate:"omitempty,oneof=anyone female male first_liker"` //Optional, who may send the first message, default to "" (anyone)
+	}
+
+	// a zero threshold turns its signal off
+	Abuse struct {
+		SwipeWindow       time.Duration `mapstructure:"ABUSE_SWIPE_WINDOW" validate:"required_with=MaxSwipes"`
+		MaxSwipes         int           `mapstructure:"ABUSE_MAX_SWIPES"` //Optional, swipes within ABUSE_SWIPE_WINDOW allowed, default to 0 (off)
+		RatioWindow       time.Duration `mapstructure:"ABUSE_RATIO_WINDOW" validate:"required_with=MaxLikeRatio"`
+		MinRatioSwipes    int           `mapstructure:"ABUSE_MIN_RATIO_SWIPES"`                  //Optional, swipes within ABUSE_RATIO_WINDOW before the like ratio is judged, default to 0
+		MaxLikeRatio      int           `mapstructure:"ABUSE_MAX_LIKE_RATIO" validate:"max=100"` //Optional, percentage of likes allowed, default to 0 (off)
+		MaxSharedBio      int           `mapstructure:"ABUSE_MAX_SHARED_BIO"`                    //Optional, other accounts with the same bio allowed, default to 0 (off)
+		FingerprintWindow time.Duration `mapstructure:"ABUSE_FINGERPRINT_WINDOW" validate:"required_with=MaxSharedIP MaxSharedDevice"`
+		MaxSharedIP       int           `mapstructure:"ABUSE_MAX_SHARED_IP"`     //Optional, other accounts seen from the same IP allowed, default to 0 (off)
+		MaxSharedDevice   int           `mapstructure:"ABUSE_MAX_SHARED_DEVICE"` //Optional, other accounts seen from the same device allowed, default to 0 (off)
+	}
+
+	// a policy with a zero limit is off, see entity.RateLimitAuth and friends for what each one covers
+	RateLimit struct {
+		AuthLimit     int           `mapstructure:"RATE_LIMIT_AUTH_LIMIT"` //Optional, requests per window to login and register per IP, default to 0 (unlimited)
+		AuthWindow    time.Duration `mapstructure:"RATE_LIMIT_AUTH_WINDOW" validate:"required_with=AuthLimit"`
+		DefaultLimit  int           `mapstructure:"RATE_LIMIT_DEFAULT_LIMIT"` //Optional, requests per window to any authenticated route per user, default to 0 (unlimited)
+		DefaultWindow time.Duration `mapstructure:"RATE_LIMIT_DEFAULT_WINDOW" validate:"required_with=DefaultLimit"`
+		SwipeLimit    int           `mapstructure:"RATE_LIMIT_SWIPE_LIMIT"` //Optional, swipe requests per window per user, default to 0 (unlimited)
+		SwipeWindow   time.Duration `mapstructure:"RATE_LIMIT_SWIPE_WINDOW" validate:"required_with=SwipeLimit"`
+		MessageLimit  int           `mapstructure:"RATE_LIMIT_MESSAGE_LIMIT"` //Optional, messages sent per window per user, default to 0 (unlimited)
+		MessageWindow time.Duration `mapstructure:"RATE_LIMIT_MESSAGE_WINDOW" validate:"required_with=MessageLimit"`
+		ReportLimit   int           `mapstructure:"RATE_LIMIT_REPORT_LIMIT"` //Optional, reports filed per window per user, default to 0 (unlimited)
+		ReportWindow  time.Duration `mapstructure:"RATE_LIMIT_REPORT_WINDOW" validate:"required_with=ReportLimit"`
+	}
+
+	Boost struct {
+		Duration      time.Duration `mapstructure:"BOOST_DURATION" validate:"required"`
+		PlanAllowance int64         `mapstructure:"BOOST_PLAN_ALLOWANCE"` //Optional, boosts included per subscription period, default to 0
+	}
+
+	Payment struct {
+		Provider      string        `mapstructure:"PAYMENT_PROVIDER" validate:"required,oneof=mock"`
+		BaseURL       string        `mapstructure:"PAYMENT_BASE_URL" validate:"required,url"`    //Provider API, the mock provider is started with make mockpay
+		WebhookSecret string        `mapstructure:"PAYMENT_WEBHOOK_SECRET" validate:"required"`  //Shared with the provider, webhooks not signed with it are refused
+		NotifyURL     string        `mapstructure:"PAYMENT_NOTIFY_URL" validate:"required,url"`  //Where the provider posts webhooks, /v1/payments/webhook/{provider} of this service
+		ReturnURL     string        `mapstructure:"PAYMENT_RETURN_URL" validate:"omitempty,url"` //Optional, where the user lands after checkout, default to "" (provider page)
+		Timeout       time.Duration `mapstructure:"PAYMENT_TIMEOUT" validate:"required"`
+		Charger       string        `mapstructure:"PAYMENT_CHARGER" validate:"required,oneof=fake"` //Bills the payment method on file for renewals
+		FakeDecline   bool          `mapstructure:"PAYMENT_FAKE_DECLINE"`                           //Optional, the fake charger declines every renewal, default to false
+		CouponHold    time.Duration `mapstructure:"PAYMENT_COUPON_HOLD"`                            //Optional, how long an unpaid checkout counts against the limits of its coupon, default to 0 (until it's settled)
+		TrialCard     bool          `mapstructure:"PAYMENT_TRIAL_CARD"`                             //Optional, trials need a card on file and are charged at their end, default to false (no payment, the trial expires at its end)
+		BoostPrice    int64         `mapstructure:"PAYMENT_BOOST_PRICE"`                            //Optional, of one boost in the smallest unit of PAYMENT_BOOST_CURRENCY, default to 0 (boosts aren't sold)
+		BoostCurrency string        `mapstructure:"PAYMENT_BOOST_CURRENCY" validate:"required_with=BoostPrice"`
+	}
+
+	// a subscription whose renewal fails keeps its features for GracePeriod while the renewal is retried
+	Subscription struct {
+		GracePeriod    time.Duration `mapstructure:"SUBSCRIPTION_GRACE_PERIOD"` //Optional, default to 0 (expire on the first failed renewal)
+		RenewalRetry   time.Duration `mapstructure:"SUBSCRIPTION_RENEWAL_RETRY" validate:"required_with=GracePeriod"`
+		ExpiryReminder time.Duration `mapstructure:"SUBSCRIPTION_EXPIRY_REMINDER"` //Optional, users are reminded this long before their period ends, default to 0 (no reminder)
+	}
+
 	Configuration struct {
 		ServiceName          string         `mapstructure:"SERVICE_NAME"`
 		TraceEndpoint        string         `mapstructure:"TRACE_ENDPOINT"`
@@ -61,6 +169,17 @@ type (
 		PostgresReader       PostgresReader `mapstructure:",squash"`
 		Translation          Translation    `mapstructure:",squash"`
 		Redis                Redis          `mapstructure:",squash"`
+		Discovery            Discovery      `mapstructure:",squash"`
+		Boost                Boost          `mapstructure:",squash"`
+		Match                Match          `mapstructure:",squash"`
+		Worker               Worker         `mapstructure:",squash"`
+		Recommender          Recommender    `mapstructure:",squash"`
+		Event                Event          `mapstructure:",squash"`
+		Realtime             Realtime       `mapstructure:",squash"`
+		RateLimit            RateLimit      `mapstructure:",squash"`
+		Abuse                Abuse          `mapstructure:",squash"`
+		Payment              Payment        `mapstructure:",squash"`
+		Subscription         Subscription   `mapstructure:",squash"`
 		TokenIssuer          string         `mapstructure:"TOKEN_ISSUER" validate:"required"`
 		IatLeeway            time.Duration  `mapstructure:"IAT_LEEWAY" validate:"required"` //Leeway time for iat to accommodate server time discrepancy
 		JWTKey               JWTKey         `mapstructure:",squash"`
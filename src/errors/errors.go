@@ -11,4 +11,78 @@ var (
 	ErrPasswordNotMatch       = i18n_err.NewI18nError("err_password_not_match")
 	ErrInvalidEmailFormat     = i18n_err.NewI18nError("err_invalid_email_format")
 	ErrInvalidUserId          = i18n_err.NewI18nError("err_invalid_user_id")
+	ErrForbidden              = i18n_err.NewI18nError("err_forbidden")
+	ErrAccountSuspended       = i18n_err.NewI18nError("err_account_suspended")
+	ErrAccountBanned          = i18n_err.NewI18nError("err_account_banned")
+	ErrTokenRevoked           = i18n_err.NewI18nError("err_token_revoked")
+	ErrTooManyRequests        = i18n_err.NewI18nError("err_too_many_requests")
+
+	// Realtime
+	ErrInvalidTicket = i18n_err.NewI18nError("err_invalid_ticket")
+
+	// Dating
+	ErrInvalidSwipeTarget = i18n_err.NewI18nError("err_invalid_swipe_target")
+	ErrSwipeQuotaExceeded = i18n_err.NewI18nError("err_swipe_quota_exceeded")
+	ErrLikeNotFound       = i18n_err.NewI18nError("err_like_not_found")
+	ErrNothingToRewind    = i18n_err.NewI18nError("err_nothing_to_rewind")
+
+	// Match
+	ErrInvalidMatchId = i18n_err.NewI18nError("err_invalid_match_id")
+	ErrMatchNotFound  = i18n_err.NewI18nError("err_match_not_found")
+	ErrInvalidCursor  = i18n_err.NewI18nError("err_invalid_cursor")
+
+	ErrMatchAlreadyExtended = i18n_err.NewI18nError("err_match_already_extended")
+	ErrMatchNotExpiring     = i18n_err.NewI18nError("err_match_not_expiring")
+
+	// Chat
+	ErrFirstMoveNotAllowed = i18n_err.NewI18nError("err_first_move_not_allowed")
+
+	// Block
+	ErrInvalidBlockTarget = i18n_err.NewI18nError("err_invalid_block_target")
+	ErrInvalidBlockUserId = i18n_err.NewI18nError("err_invalid_block_user_id")
+
+	// Report
+	ErrInvalidReportTarget   = i18n_err.NewI18nError("err_invalid_report_target")
+	ErrInvalidReportId       = i18n_err.NewI18nError("err_invalid_report_id")
+	ErrReportNotFound        = i18n_err.NewI18nError("err_report_not_found")
+	ErrReportAlreadyResolved = i18n_err.NewI18nError("err_report_already_resolved")
+	ErrInvalidAssignee       = i18n_err.NewI18nError("err_invalid_assignee")
+
+	// Abuse
+	ErrInvalidAbuseFlagId       = i18n_err.NewI18nError("err_invalid_abuse_flag_id")
+	ErrAbuseFlagNotFound        = i18n_err.NewI18nError("err_abuse_flag_not_found")
+	ErrAbuseFlagAlreadyReviewed = i18n_err.NewI18nError("err_abuse_flag_already_reviewed")
+
+	// Subscription
+	ErrInvalidPlan                  = i18n_err.NewI18nError("err_invalid_plan")
+	ErrSubscriptionNotFound         = i18n_err.NewI18nError("err_subscription_not_found")
+	ErrSubscriptionAlreadyCancelled = i18n_err.NewI18nError("err_subscription_already_cancelled")
+	ErrSubscriptionExists           = i18n_err.NewI18nError("err_subscription_exists")
+	ErrSubscriptionPlanUnchanged    = i18n_err.NewI18nError("err_subscription_plan_unchanged")
+	ErrInvalidPlanChange            = i18n_err.NewI18nError("err_invalid_plan_change")
+	ErrTrialNotOffered              = i18n_err.NewI18nError("err_trial_not_offered")
+	ErrTrialUsed                    = i18n_err.NewI18nError("err_trial_used")
+
+	// Payment
+	ErrInvalidPaymentId        = i18n_err.NewI18nError("err_invalid_payment_id")
+	ErrPaymentNotFound         = i18n_err.NewI18nError("err_payment_not_found")
+	ErrPaymentProviderFailed   = i18n_err.NewI18nError("err_payment_provider_failed")
+	ErrUnknownPaymentProvider  = i18n_err.NewI18nError("err_unknown_payment_provider")
+	ErrInvalidPaymentSignature = i18n_err.NewI18nError("err_invalid_payment_signature")
+	ErrInvalidPaymentEvent     = i18n_err.NewI18nError("err_invalid_payment_event")
+
+	// Coupon
+	ErrCouponNotFound        = i18n_err.NewI18nError("err_coupon_not_found")
+	ErrCouponExpired         = i18n_err.NewI18nError("err_coupon_expired")
+	ErrCouponNotApplicable   = i18n_err.NewI18nError("err_coupon_not_applicable")
+	ErrCouponExhausted       = i18n_err.NewI18nError("err_coupon_exhausted")
+	ErrCouponAlreadyRedeemed = i18n_err.NewI18nError("err_coupon_already_redeemed")
+
+	// Entitlement
+	ErrFeatureNotEntitled = i18n_err.NewI18nError("err_feature_not_entitled")
+
+	// Boost
+	ErrBoostActive      = i18n_err.NewI18nError("err_boost_active")
+	ErrNoBoostAvailable = i18n_err.NewI18nError("err_no_boost_available")
+	ErrBoostsNotForSale = i18n_err.NewI18nError("err_boosts_not_for_sale")
 )
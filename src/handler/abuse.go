@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"errors"
+	"loverly/src/business/usecase"
+	"loverly/src/handler/verifier"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	appErr "loverly/src/errors"
+)
+
+func GetAbuseFlags(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		param, err := verifier.BuildAndValidateAbuseFlagListRequest(r, Log, Verify)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		res, err := uc.Abuse.GetList(r.Context(), param)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusBadRequest, err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, res)
+	}
+}
+
+func ReviewAbuseFlag(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flagId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || flagId < 1 {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, appErr.ErrInvalidAbuseFlagId)
+			return
+		}
+
+		payload, err := verifier.BuildAndValidateAbuseFlagReviewRequest(r, Log, Verify)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		res, err := uc.Abuse.Review(r.Context(), flagId, payload)
+		if err != nil {
+			JSONError(r.Context(), w, abuseErrorCode(err), err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, res)
+	}
+}
+
+func abuseErrorCode(err error) int {
+	switch {
+	case errors.Is(err, appErr.ErrAbuseFlagNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, appErr.ErrAbuseFlagAlreadyReviewed):
+		return http.StatusConflict
+	}
+
+	return http.StatusBadRequest
+}
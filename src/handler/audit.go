@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"loverly/src/business/usecase"
+	"loverly/src/handler/verifier"
+	"net/http"
+)
+
+func GetAuditLogs(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		param, err := verifier.BuildAndValidateAuditLogListRequest(r, Log, Verify)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		res, err := uc.Audit.GetList(r.Context(), param)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusBadRequest, err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, res)
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"loverly/src/business/usecase"
+	"loverly/src/handler/verifier"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	appErr "loverly/src/errors"
+)
+
+func BlockUser(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, err := verifier.BuildAndValidateBlockRequest(r, Log, Verify)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		if err := uc.Block.Block(r.Context(), payload); err != nil {
+			JSONError(r.Context(), w, http.StatusBadRequest, err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusCreated, nil)
+	}
+}
+
+func UnblockUser(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		blockedId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || blockedId < 1 {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, appErr.ErrInvalidBlockUserId)
+			return
+		}
+
+		if err := uc.Block.Unblock(r.Context(), blockedId); err != nil {
+			JSONError(r.Context(), w, http.StatusBadRequest, err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, nil)
+	}
+}
+
+func GetBlocks(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, err := uc.Block.GetList(r.Context())
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusBadRequest, err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, res)
+	}
+}
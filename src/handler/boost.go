@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"loverly/src/business/usecase"
+	"loverly/src/handler/verifier"
+	"net/http"
+)
+
+func ActivateBoost(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, err := uc.Boost.Activate(r.Context())
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusBadRequest, err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusCreated, res)
+	}
+}
+
+// BuyBoosts checks out a pack of boosts, the client sends the user to checkout_url
+func BuyBoosts(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, err := verifier.BuildAndValidateBoostPurchaseRequest(r, Log, Verify)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		res, err := uc.Payment.BuyBoosts(r.Context(), payload)
+		if err != nil {
+			JSONError(r.Context(), w, paymentErrorCode(err), err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusCreated, res)
+	}
+}
+
+func GetBoost(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, err := uc.Boost.GetLatest(r.Context())
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusBadRequest, err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, res)
+	}
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"loverly/src/business/usecase"
+	"loverly/src/handler/verifier"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	appErr "loverly/src/errors"
+)
+
+func SendMessage(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		matchId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || matchId < 1 {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, appErr.ErrInvalidMatchId)
+			return
+		}
+
+		payload, err := verifier.BuildAndValidateMessageRequest(r, Log, Verify)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		res, err := uc.Chat.Send(r.Context(), matchId, payload)
+		if err != nil {
+			JSONError(r.Context(), w, chatErrorCode(err), err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusCreated, res)
+	}
+}
+
+func GetMessages(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		matchId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || matchId < 1 {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, appErr.ErrInvalidMatchId)
+			return
+		}
+
+		payload, err := verifier.BuildAndValidateMessageListRequest(r, Log, Verify)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		res, err := uc.Chat.GetHistory(r.Context(), matchId, payload)
+		if err != nil {
+			JSONError(r.Context(), w, chatErrorCode(err), err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, res)
+	}
+}
+
+func GetConversations(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, err := verifier.BuildAndValidateMessageListRequest(r, Log, Verify)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		res, err := uc.Chat.GetConversations(r.Context(), payload)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusBadRequest, err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, res)
+	}
+}
+
+func chatErrorCode(err error) int {
+	switch {
+	case errors.Is(err, appErr.ErrMatchNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, appErr.ErrFirstMoveNotAllowed):
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
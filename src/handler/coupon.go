@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"loverly/src/business/entity"
+	"loverly/src/business/usecase"
+	"loverly/src/handler/verifier"
+	"net/http"
+)
+
+// ValidateCoupon previews the price of a plan with a coupon, nothing is redeemed
+func ValidateCoupon(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, err := verifier.BuildAndValidateCouponRequest(r, Log, Verify)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		res, err := uc.Payment.Quote(r.Context(), entity.SubscriptionParam{Plan: payload.Plan, Coupon: payload.Coupon})
+		if err != nil {
+			JSONError(r.Context(), w, paymentErrorCode(err), err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, res)
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"loverly/src/business/entity"
 	"loverly/src/business/usecase"
 	"loverly/src/handler/verifier"
 	"net/http"
@@ -35,3 +36,59 @@ func Swipe(uc *usecase.Usecases) http.HandlerFunc {
 		JSONSuccess(r.Context(), w, http.StatusOK, res)
 	}
 }
+
+// swipeBatchResult carries the error of an item the way JSONError writes it
+type swipeBatchResult struct {
+	entity.SwipeBatchResult
+	Error *Error `json:"error,omitempty"`
+}
+
+func SwipeBatch(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, err := verifier.BuildAndValidateSwipeBatchRequest(r, Log, Verify)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		res, err := uc.Dating.SwipeBatch(r.Context(), payload)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusBadRequest, err)
+			return
+		}
+
+		results := make([]swipeBatchResult, len(res))
+		for i, item := range res {
+			results[i] = swipeBatchResult{SwipeBatchResult: item}
+			if item.Err != nil {
+				results[i].Error = translate(r.Context(), item.Err)
+			}
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, results)
+	}
+}
+
+func Rewind(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, err := uc.Dating.Rewind(r.Context())
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusBadRequest, err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, res)
+	}
+}
+
+func ReceivedLikes(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, err := uc.Dating.ReceivedLikes(r.Context())
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusBadRequest, err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, res)
+	}
+}
@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"loverly/src/business/usecase"
+	"net/http"
+)
+
+// GetEntitlements returns the features the subscription of the user turns on, clients drive their UI by it
+func GetEntitlements(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, err := uc.Entitlement.Get(r.Context())
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusBadRequest, err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, res)
+	}
+}
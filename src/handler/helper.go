@@ -9,14 +9,22 @@ import (
 	"loverly/lib/i18n"
 	"loverly/lib/jwt"
 	"loverly/lib/log"
+	"loverly/src/business/usecase"
+	"math"
+	"net"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
 
 	"loverly/lib/header"
 	i18n_err "loverly/lib/i18n/errors"
+	appErr "loverly/src/errors"
 )
 
 type Response struct {
@@ -61,14 +69,8 @@ func JSONError(ctx context.Context, w http.ResponseWriter, code int, err i18n_er
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 
-	lang := appcontext.GetAcceptLanguage(ctx)
 	resp := Response{
-		Error: &Error{
-			Code:     err.Error(),
-			Title:    i18n.Title(lang, err.Error()),
-			Message:  i18n.Message(lang, err.Error()),
-			Severity: "error",
-		},
+		Error: translate(ctx, err),
 		Metadata: Meta{
 			RequestId: appcontext.GetRequestId(ctx),
 		},
@@ -77,6 +79,17 @@ func JSONError(ctx context.Context, w http.ResponseWriter, code int, err i18n_er
 	json.NewEncoder(w).Encode(resp)
 }
 
+// translate puts the error in the language of the request, for errors returned inside a successful response too
+func translate(ctx context.Context, err i18n_err.I18nError) *Error {
+	lang := appcontext.GetAcceptLanguage(ctx)
+	return &Error{
+		Code:     err.Error(),
+		Title:    i18n.Title(lang, err.Error()),
+		Message:  i18n.Message(lang, err.Error()),
+		Severity: "error",
+	}
+}
+
 func addFieldsToContext(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		reqId := r.Header.Get(header.KeyRequestID)
@@ -89,6 +102,8 @@ func addFieldsToContext(next http.Handler) http.Handler {
 		c = appcontext.SetUserAgent(c, r.Header.Get(header.KeyUserAgent))
 		c = appcontext.SetAcceptLanguage(c, r.Header.Get(header.KeyAcceptLanguage))
 		c = appcontext.SetDeviceType(c, r.Header.Get(header.KeyDeviceType))
+		c = appcontext.SetDeviceId(c, r.Header.Get(header.KeyDeviceId))
+		c = appcontext.SetRequestIP(c, clientIP(r))
 		c = appcontext.SetCacheControl(c, r.Header.Get(header.KeyCacheControl))
 		c = appcontext.SetServiceName(c, r.Header.Get(header.KeyServiceName))
 
@@ -96,11 +111,31 @@ func addFieldsToContext(next http.Handler) http.Handler {
 	})
 }
 
-func authentication(jwt *jwt.TokenProvider, log log.Interface) func(http.Handler) http.Handler {
+// clientIP is the address RealIP resolved, without the port
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return ip
+}
+
+// statusCode answers a failed account status check, a banned or suspended account is known but not allowed
+func statusCode(err error) int {
+	if errors.Is(err, appErr.ErrAccountSuspended) || errors.Is(err, appErr.ErrAccountBanned) {
+		return http.StatusForbidden
+	}
+
+	return http.StatusUnauthorized
+}
+
+func authentication(jwt *jwt.TokenProvider, uc *usecase.Usecases, log log.Interface) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
 			token := r.Header.Get(header.KeyAuthorization)
+
 			if token == "" {
 				JSONError(ctx, w, http.StatusUnauthorized, errors.New("missing authorization"))
 				return
@@ -118,12 +153,110 @@ func authentication(jwt *jwt.TokenProvider, log log.Interface) func(http.Handler
 				return
 			}
 
+			// a token outlives a password change, a ban or a suspension issued after login
+			if err := uc.User.CheckToken(ctx, verify.Data.UserId, verify.Data.Version); err != nil {
+				JSONError(ctx, w, statusCode(err), err)
+				return
+			}
+
 			ctx = appcontext.SetUserId(ctx, int(verify.Data.UserId))
+			ctx = appcontext.SetUserRole(ctx, verify.Roles)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// authorization lets only the given roles through, it goes after authentication
+func authorization(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !slices.Contains(roles, appcontext.GetUserRole(r.Context())) {
+				JSONError(r.Context(), w, http.StatusForbidden, appErr.ErrForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireEntitlement lets only users whose plan has every given feature through, it goes after authentication
+func RequireEntitlement(uc *usecase.Usecases, features ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			ok, err := uc.Entitlement.Has(ctx, features...)
+			if err != nil {
+				JSONError(ctx, w, http.StatusBadRequest, err)
+				return
+			}
+
+			if !ok {
+				JSONError(ctx, w, http.StatusForbidden, appErr.ErrFeatureNotEntitled)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimit throttles by user once authenticated, by IP before, a limiter failure lets the request through
+func rateLimit(uc *usecase.Usecases, policy string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			key := fmt.Sprintf("user:%d", appcontext.GetUserId(ctx))
+			if appcontext.GetUserId(ctx) < 1 {
+				key = fmt.Sprintf("ip:%s", appcontext.GetRequestIP(ctx))
+			}
+
+			res, err := uc.RateLimit.Allow(ctx, policy, key)
+			if err != nil {
+				Log.Error(ctx, fmt.Sprintf("rate limit %s err: %v", policy, err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if res.Limit > 0 {
+				w.Header().Set(header.KeyRateLimitLimit, strconv.Itoa(res.Limit))
+				w.Header().Set(header.KeyRateLimitRemaining, strconv.Itoa(res.Remaining))
+				w.Header().Set(header.KeyRateLimitReset, strconv.Itoa(seconds(res.Reset)))
+			}
+
+			if !res.Allowed {
+				w.Header().Set(header.KeyRetryAfter, strconv.Itoa(seconds(res.RetryAfter)))
+				JSONError(ctx, w, http.StatusTooManyRequests, appErr.ErrTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// seconds rounds up, a client waiting the truncated value would come back too early
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// timeout bounds every request but websocket upgrades, those last as long as the connection
+func timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		limited := chimiddleware.Timeout(d)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if websocket.IsWebSocketUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
+
 func bodyLogger(log log.Interface) func(http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
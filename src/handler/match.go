@@ -1,13 +1,26 @@
 package handler
 
 import (
+	"errors"
 	"loverly/src/business/usecase"
+	"loverly/src/handler/verifier"
 	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	appErr "loverly/src/errors"
 )
 
 func Match(uc *usecase.Usecases) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		matchs, err := uc.Match.GetList(r.Context())
+		payload, err := verifier.BuildAndValidateMatchListRequest(r, Log, Verify)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		matchs, err := uc.Match.GetList(r.Context(), payload)
 		if err != nil {
 			JSONError(r.Context(), w, http.StatusBadRequest, err)
 			return
@@ -16,3 +29,49 @@ func Match(uc *usecase.Usecases) http.HandlerFunc {
 		JSONSuccess(r.Context(), w, http.StatusOK, matchs)
 	}
 }
+
+func Unmatch(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		matchId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || matchId < 1 {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, appErr.ErrInvalidMatchId)
+			return
+		}
+
+		if err := uc.Match.Unmatch(r.Context(), matchId); err != nil {
+			code := http.StatusBadRequest
+			if errors.Is(err, appErr.ErrMatchNotFound) {
+				code = http.StatusNotFound
+			}
+
+			JSONError(r.Context(), w, code, err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, nil)
+	}
+}
+
+func ExtendMatch(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		matchId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || matchId < 1 {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, appErr.ErrInvalidMatchId)
+			return
+		}
+
+		res, err := uc.Match.Extend(r.Context(), matchId)
+		if err != nil {
+			code := http.StatusBadRequest
+			switch {
+			case errors.Is(err, appErr.ErrMatchNotFound):
+				code = http.StatusNotFound
+			}
+
+			JSONError(r.Context(), w, code, err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, res)
+	}
+}
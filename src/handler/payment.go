@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"loverly/src/business/usecase"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	appErr "loverly/src/errors"
+)
+
+// webhook bodies larger than this are refused before they're verified
+const maxWebhookBody = 1 << 20
+
+func GetPayment(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		paymentId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || paymentId < 1 {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, appErr.ErrInvalidPaymentId)
+			return
+		}
+
+		res, err := uc.Payment.Get(r.Context(), paymentId)
+		if err != nil {
+			JSONError(r.Context(), w, paymentErrorCode(err), err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, res)
+	}
+}
+
+// PaymentWebhook is called by the provider, the signature of the raw body is what authenticates it
+func PaymentWebhook(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusBadRequest, appErr.ErrInvalidPaymentEvent)
+			return
+		}
+
+		err = uc.Payment.Settle(r.Context(), chi.URLParam(r, "provider"), r.Header, body)
+		if err != nil {
+			JSONError(r.Context(), w, paymentErrorCode(err), err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, nil)
+	}
+}
+
+func paymentErrorCode(err error) int {
+	switch {
+	case errors.Is(err, appErr.ErrPaymentNotFound), errors.Is(err, appErr.ErrUnknownPaymentProvider),
+		errors.Is(err, appErr.ErrSubscriptionNotFound), errors.Is(err, appErr.ErrCouponNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, appErr.ErrSubscriptionExists), errors.Is(err, appErr.ErrSubscriptionPlanUnchanged),
+		errors.Is(err, appErr.ErrSubscriptionAlreadyCancelled), errors.Is(err, appErr.ErrCouponExhausted),
+		errors.Is(err, appErr.ErrCouponAlreadyRedeemed), errors.Is(err, appErr.ErrTrialUsed):
+		return http.StatusConflict
+	case errors.Is(err, appErr.ErrInvalidPaymentSignature):
+		return http.StatusUnauthorized
+	case errors.Is(err, appErr.ErrPaymentProviderFailed):
+		return http.StatusBadGateway
+	}
+
+	return http.StatusBadRequest
+}
@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"loverly/src/business/usecase"
+	"net/http"
+)
+
+func GetPlans(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		plans, err := uc.Plan.GetList(r.Context())
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusBadRequest, err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, plans)
+	}
+}
@@ -0,0 +1,88 @@
+package realtime
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// client is one websocket connection, only writePump writes to it and only readPump reads from it
+type client struct {
+	hub    *Hub
+	userId int64
+	conn   *websocket.Conn
+	send   chan []byte
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// deliver never blocks the hub, a client that can't keep up is dropped and has to reconnect
+func (c *client) deliver(msg []byte) {
+	select {
+	case c.send <- msg:
+	case <-c.done:
+	default:
+		c.stop()
+	}
+}
+
+// stop makes writePump say goodbye and close the connection
+func (c *client) stop() {
+	c.stopOnce.Do(func() { close(c.done) })
+}
+
+func (c *client) readPump() {
+	defer func() {
+		c.hub.unregister(c)
+		c.stop()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(c.hub.cfg.PongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(c.hub.cfg.PongWait))
+	})
+
+	// nothing is expected from the client, reading only drives pongs and close frames
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func (c *client) writePump() {
+	ticker := time.NewTicker(c.hub.cfg.PingInterval)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			c.closeWith(websocket.CloseGoingAway, "")
+			return
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.cfg.WriteWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				c.stop()
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.cfg.WriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.stop()
+				return
+			}
+		}
+	}
+}
+
+func (c *client) closeWith(code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(c.hub.cfg.WriteWait))
+	c.conn.Close()
+}
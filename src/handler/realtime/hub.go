@@ -0,0 +1,173 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"loverly/lib/log"
+	"loverly/src/business/entity"
+	"loverly/src/config"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// clients only listen, anything bigger than a control frame is a misbehaving client
+	maxMessageSize = 512
+
+	// pause before listening again after the notification stream broke
+	relistenWait = time.Second
+)
+
+// Hub keeps the websocket connections of this instance and delivers the notifications addressed to them
+type Hub struct {
+	log      log.Interface
+	cfg      config.Realtime
+	upgrader websocket.Upgrader
+
+	mu      sync.RWMutex
+	clients map[int64]map[*client]bool
+	closed  bool
+}
+
+// frame is what the client receives, the recipient is implied by the connection
+type frame struct {
+	Id     string          `json:"id,omitempty"`
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	SentAt time.Time       `json:"sent_at"`
+}
+
+func NewHub(log log.Interface, cfg config.Realtime) *Hub {
+	return &Hub{
+		log: log,
+		cfg: cfg,
+		upgrader: websocket.Upgrader{
+			// authentication is by ticket, not cookies, so any origin is fine
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		clients: make(map[int64]map[*client]bool),
+	}
+}
+
+// Run delivers notifications until ctx is done, the stream is opened again whenever it fails or ends early
+func (h *Hub) Run(ctx context.Context, listen func(ctx context.Context) (<-chan entity.Notification, error)) {
+	for {
+		notifications, err := listen(ctx)
+		if err != nil {
+			h.log.Error(ctx, fmt.Sprintf("listen notifications err: %v", err))
+		} else {
+			h.deliver(ctx, notifications)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(relistenWait):
+			h.log.Info(ctx, "listening to notifications again")
+		}
+	}
+}
+
+func (h *Hub) deliver(ctx context.Context, notifications <-chan entity.Notification) {
+	for n := range notifications {
+		msg, err := json.Marshal(frame{Id: n.Id, Type: n.Type, Data: n.Data, SentAt: n.SentAt})
+		if err != nil {
+			h.log.Error(ctx, fmt.Sprintf("error when marshal notification frame: %v", err))
+			continue
+		}
+
+		for _, c := range h.recipients(n.UserId) {
+			c.deliver(msg)
+		}
+	}
+}
+
+// Serve upgrades the request and keeps the connection until either side closes it
+func (h *Hub) Serve(w http.ResponseWriter, r *http.Request, userId int64) error {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+
+	c := &client{
+		hub:    h,
+		userId: userId,
+		conn:   conn,
+		send:   make(chan []byte, h.cfg.SendBuffer),
+		done:   make(chan struct{}),
+	}
+
+	if !h.register(c) {
+		c.closeWith(websocket.CloseGoingAway, "server shutting down")
+		return nil
+	}
+
+	go c.writePump()
+	c.readPump()
+
+	return nil
+}
+
+// Shutdown tells every connected client to reconnect elsewhere, http.Server.Shutdown doesn't see hijacked connections
+func (h *Hub) Shutdown() {
+	h.mu.Lock()
+	h.closed = true
+	var all []*client
+	for _, cs := range h.clients {
+		for c := range cs {
+			all = append(all, c)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, c := range all {
+		c.stop()
+	}
+}
+
+func (h *Hub) register(c *client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return false
+	}
+
+	if h.clients[c.userId] == nil {
+		h.clients[c.userId] = make(map[*client]bool)
+	}
+	h.clients[c.userId][c] = true
+
+	return true
+}
+
+func (h *Hub) unregister(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.clients[c.userId], c)
+	if len(h.clients[c.userId]) == 0 {
+		delete(h.clients, c.userId)
+	}
+}
+
+func (h *Hub) recipients(userId int64) []*client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var cs []*client
+	for id, conns := range h.clients {
+		if userId != entity.BroadcastUserId && id != userId {
+			continue
+		}
+		for c := range conns {
+			cs = append(cs, c)
+		}
+	}
+
+	return cs
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"errors"
+	"loverly/src/business/usecase"
+	"loverly/src/handler/verifier"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	appErr "loverly/src/errors"
+)
+
+func CreateReport(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, err := verifier.BuildAndValidateReportRequest(r, Log, Verify)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		res, err := uc.Report.Create(r.Context(), payload)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusBadRequest, err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusCreated, res)
+	}
+}
+
+func GetReports(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		param, err := verifier.BuildAndValidateReportListRequest(r, Log, Verify)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		res, err := uc.Report.GetList(r.Context(), param)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusBadRequest, err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, res)
+	}
+}
+
+func AssignReport(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reportId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || reportId < 1 {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, appErr.ErrInvalidReportId)
+			return
+		}
+
+		payload, err := verifier.BuildAndValidateReportAssignRequest(r, Log, Verify)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		res, err := uc.Report.Assign(r.Context(), reportId, payload)
+		if err != nil {
+			JSONError(r.Context(), w, reportErrorCode(err), err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, res)
+	}
+}
+
+func ResolveReport(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reportId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || reportId < 1 {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, appErr.ErrInvalidReportId)
+			return
+		}
+
+		payload, err := verifier.BuildAndValidateReportResolveRequest(r, Log, Verify)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		res, err := uc.Report.Resolve(r.Context(), reportId, payload)
+		if err != nil {
+			JSONError(r.Context(), w, reportErrorCode(err), err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, res)
+	}
+}
+
+func reportErrorCode(err error) int {
+	switch {
+	case errors.Is(err, appErr.ErrReportNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, appErr.ErrReportAlreadyResolved):
+		return http.StatusConflict
+	}
+
+	return http.StatusBadRequest
+}
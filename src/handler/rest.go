@@ -2,10 +2,13 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"loverly/lib/jwt"
+	"loverly/src/business/entity"
 	"loverly/src/business/usecase"
 	"loverly/src/config"
+	"loverly/src/handler/realtime"
 	"net/http"
 	"sync"
 	"time"
@@ -35,54 +38,128 @@ func Init(ctx context.Context, log log.Interface, cfg config.Configuration, uc *
 		r.Use(cors.Handler(cors.Options{
 			AllowedOrigins:   []string{"https://*", "http://*"},
 			AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-			ExposedHeaders:   []string{"Link"},
+			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Device-Id"},
+			ExposedHeaders:   []string{"Link", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"},
 			AllowCredentials: false,
 			MaxAge:           300, // Maximum value not ignored by any of major browsers
 		}))
 		r.Use(chimiddleware.Logger)
 		r.Use(chimiddleware.RealIP)
-		r.Use(chimiddleware.Timeout(60 * time.Second))
+		r.Use(timeout(60 * time.Second))
 		r.Use(addFieldsToContext)
 		r.Use(bodyLogger(log))
 
+		// Initialize real-time notifications
+		hub := realtime.NewHub(log, cfg.Realtime)
+		go hub.Run(ctx, uc.Realtime.Listen)
+
 		// Initialize routes
-		Router(r, uc, jwt)
+		Router(r, uc, jwt, hub)
 
 		// Initalize Log
 		Log = log
 
-		err := http.ListenAndServe(address, r)
-		if err != nil {
+		srv := &http.Server{Addr: address, Handler: r}
+		idle := make(chan struct{})
+		go func() {
+			defer close(idle)
+
+			<-ctx.Done()
+			log.Info(context.Background(), "Shutting down loverly service")
+
+			hub.Shutdown()
+
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				log.Error(shutdownCtx, fmt.Sprintf("Shutdown err %s", err))
+			}
+		}()
+
+		err := srv.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Error(ctx, fmt.Sprintf("ListenAndServe err %s", err))
+			return
 		}
+
+		// wait for in-flight requests to drain
+		<-idle
 	})
 }
 
-func Router(r *chi.Mux, usecase *usecase.Usecases, jwt *jwt.TokenProvider) {
+func Router(r *chi.Mux, usecase *usecase.Usecases, jwt *jwt.TokenProvider, hub *realtime.Hub) {
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("ok"))
 	})
 
 	r.Route("/v1", func(v1 chi.Router) {
 		// Authentication
-		v1.Post("/login", SignIn(usecase))
-		v1.Post("/register", SignUp(usecase))
+		public := v1.With(rateLimit(usecase, entity.RateLimitAuth))
+		public.Post("/login", SignIn(usecase))
+		public.Post("/register", SignUp(usecase))
+
+		// payment provider callbacks, authenticated by their signature
+		v1.Post("/payments/webhook/{provider}", PaymentWebhook(usecase))
 
-		auth := v1.With(authentication(jwt, Log))
+		auth := v1.With(authentication(jwt, usecase, Log), rateLimit(usecase, entity.RateLimitDefault))
+
+		// real-time notifications, the websocket is opened with a ticket instead of the access token
+		v1.Get("/ws", Realtime(usecase, hub))
+		auth.Post("/ws/ticket", RealtimeTicket(usecase))
 
 		// dating in action
 		auth.Get("/discovery", Discovery(usecase))
 		auth.Get("/match", Match(usecase))
-		auth.Post("/swipe", Swipe(usecase))
+		auth.Delete("/matches/{id}", Unmatch(usecase))
+		auth.With(RequireEntitlement(usecase, entity.FeatureExtendMatch)).Post("/matches/{id}/extend", ExtendMatch(usecase))
+		auth.With(rateLimit(usecase, entity.RateLimitSwipe)).Post("/swipe", Swipe(usecase))
+		auth.With(rateLimit(usecase, entity.RateLimitSwipe)).Post("/swipes:batch", SwipeBatch(usecase))
+		auth.Get("/likes/received", ReceivedLikes(usecase))
+		auth.With(RequireEntitlement(usecase, entity.FeatureRewind)).Post("/swipes:rewind", Rewind(usecase))
+
+		// chat
+		auth.Get("/conversations", GetConversations(usecase))
+		auth.Get("/matches/{id}/messages", GetMessages(usecase))
+		auth.With(rateLimit(usecase, entity.RateLimitMessage)).Post("/matches/{id}/messages", SendMessage(usecase))
+
+		// blocks
+		auth.Get("/blocks", GetBlocks(usecase))
+		auth.Post("/blocks", BlockUser(usecase))
+		auth.Delete("/blocks/{id}", UnblockUser(usecase))
+
+		// reports
+		auth.With(rateLimit(usecase, entity.RateLimitReport)).Post("/reports", CreateReport(usecase))
+
+		// boost
+		auth.Post("/boost", ActivateBoost(usecase))
+		auth.Get("/boost", GetBoost(usecase))
+		auth.Post("/boosts/purchase", BuyBoosts(usecase))
 
 		// profile
 		auth.Get("/profile", GetProfile(usecase))
+		auth.Put("/password", ChangePassword(usecase))
 
 		// subscription
+		auth.Get("/plans", GetPlans(usecase))
 		auth.Post("/subscription", Subscribe(usecase))
 		auth.Get("/subscription", GetSubscribe(usecase))
-
+		auth.Post("/subscription/cancel", CancelSubscription(usecase))
+		auth.Post("/subscription/change", ChangeSubscriptionPlan(usecase))
+		auth.Post("/subscription/trial", StartTrial(usecase))
+		auth.Post("/coupons/validate", ValidateCoupon(usecase))
+		auth.Get("/entitlements", GetEntitlements(usecase))
+		auth.Get("/payments/{id}", GetPayment(usecase))
+
+		// moderation
+		admin := auth.With(authorization(entity.RoleModerator, entity.RoleAdmin))
+		admin.Get("/admin/reports", GetReports(usecase))
+		admin.Post("/admin/reports/{id}/assign", AssignReport(usecase))
+		admin.Post("/admin/reports/{id}/resolve", ResolveReport(usecase))
+		admin.Get("/admin/abuse-flags", GetAbuseFlags(usecase))
+		admin.Post("/admin/abuse-flags/{id}/review", ReviewAbuseFlag(usecase))
+
+		// audit, admins only
+		auth.With(authorization(entity.RoleAdmin)).Get("/admin/audit-logs", GetAuditLogs(usecase))
 	})
 
 }
@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"errors"
 	"loverly/src/business/usecase"
 	"loverly/src/handler/verifier"
 	"net/http"
+
+	appErr "loverly/src/errors"
 )
 
 func Subscribe(uc *usecase.Usecases) http.HandlerFunc {
@@ -15,13 +18,53 @@ func Subscribe(uc *usecase.Usecases) http.HandlerFunc {
 			return
 		}
 
-		err = uc.Subscription.Create(r.Context(), payload)
+		// the subscription starts once the payment is settled, the client sends the user to checkout_url
+		res, err := uc.Payment.Checkout(r.Context(), payload)
 		if err != nil {
-			JSONError(r.Context(), w, http.StatusBadRequest, err)
+			JSONError(r.Context(), w, paymentErrorCode(err), err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusCreated, res)
+	}
+}
+
+// ChangeSubscriptionPlan switches the plan of the current subscription, an upgrade comes with a payment to
+// check out before it applies
+func ChangeSubscriptionPlan(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, err := verifier.BuildAndValidateSubscriptionRequest(r, Log, Verify)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		res, err := uc.Payment.ChangePlan(r.Context(), payload)
+		if err != nil {
+			JSONError(r.Context(), w, paymentErrorCode(err), err)
 			return
 		}
 
-		JSONSuccess(r.Context(), w, http.StatusCreated, nil)
+		JSONSuccess(r.Context(), w, http.StatusOK, res)
+	}
+}
+
+// StartTrial starts the free trial of a plan, or returns the payment that puts a card on file for it first
+func StartTrial(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, err := verifier.BuildAndValidateSubscriptionRequest(r, Log, Verify)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		res, err := uc.Payment.StartTrial(r.Context(), payload)
+		if err != nil {
+			JSONError(r.Context(), w, paymentErrorCode(err), err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusCreated, res)
 	}
 }
 
@@ -36,3 +79,26 @@ func GetSubscribe(uc *usecase.Usecases) http.HandlerFunc {
 		JSONSuccess(r.Context(), w, http.StatusOK, subs)
 	}
 }
+
+func CancelSubscription(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		subs, err := uc.Subscription.Cancel(r.Context())
+		if err != nil {
+			JSONError(r.Context(), w, subscriptionErrorCode(err), err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, subs)
+	}
+}
+
+func subscriptionErrorCode(err error) int {
+	switch {
+	case errors.Is(err, appErr.ErrSubscriptionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, appErr.ErrSubscriptionAlreadyCancelled):
+		return http.StatusConflict
+	}
+
+	return http.StatusBadRequest
+}
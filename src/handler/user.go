@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"errors"
 	"loverly/src/business/usecase"
 	"loverly/src/handler/verifier"
 	"net/http"
+
+	appErr "loverly/src/errors"
 )
 
 func SignIn(uc *usecase.Usecases) http.HandlerFunc {
@@ -18,7 +21,12 @@ func SignIn(uc *usecase.Usecases) http.HandlerFunc {
 		// service to authenticate user
 		res, err := uc.User.SignIn(r.Context(), payload)
 		if err != nil {
-			JSONError(r.Context(), w, http.StatusUnauthorized, err)
+			code := http.StatusUnauthorized
+			if errors.Is(err, appErr.ErrAccountSuspended) || errors.Is(err, appErr.ErrAccountBanned) {
+				code = http.StatusForbidden
+			}
+
+			JSONError(r.Context(), w, code, err)
 			return
 		}
 
@@ -44,3 +52,20 @@ func SignUp(uc *usecase.Usecases) http.HandlerFunc {
 		JSONSuccess(r.Context(), w, http.StatusCreated, res)
 	}
 }
+
+func ChangePassword(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload, err := verifier.BuildAndValidateChangePasswordRequest(r, Log, Verify)
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		if err := uc.User.ChangePassword(r.Context(), payload); err != nil {
+			JSONError(r.Context(), w, http.StatusBadRequest, err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, nil)
+	}
+}
@@ -0,0 +1,66 @@
+package verifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"loverly/lib/log"
+	"loverly/src/business/entity"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func BuildAndValidateAbuseFlagListRequest(r *http.Request, log log.Interface, validate *validator.Validate) (entity.AbuseFlagListParam, error) {
+	query := r.URL.Query()
+	param := entity.AbuseFlagListParam{
+		Status: query.Get("status"),
+		Cursor: query.Get("cursor"),
+	}
+
+	if userId := query.Get("user_id"); userId != "" {
+		var err error
+		if param.UserId, err = strconv.ParseInt(userId, 10, 64); err != nil {
+			log.Error(r.Context(), fmt.Sprintf("parse user_id query err: %v", err))
+			return param, err
+		}
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		if param.Limit, err = strconv.Atoi(limit); err != nil {
+			log.Error(r.Context(), fmt.Sprintf("parse limit query err: %v", err))
+			return param, err
+		}
+	}
+
+	if err := validate.Struct(param); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("validate request query err: %v", err))
+		return param, err
+	}
+
+	return param, nil
+}
+
+func BuildAndValidateAbuseFlagReviewRequest(r *http.Request, log log.Interface, validate *validator.Validate) (entity.AbuseFlagReviewParam, error) {
+	var review entity.AbuseFlagReviewParam
+
+	bodyByte, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Error(r.Context(), fmt.Sprintf("read request body err: %v", err))
+		return review, err
+	}
+
+	if err := json.Unmarshal(bodyByte, &review); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("unmarshal request body err: %v", err))
+		return review, err
+	}
+
+	if err := validate.Struct(review); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("validate request body err: %v", err))
+		return review, err
+	}
+
+	return review, nil
+}
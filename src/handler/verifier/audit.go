@@ -0,0 +1,57 @@
+package verifier
+
+import (
+	"fmt"
+	"loverly/lib/log"
+	"loverly/src/business/entity"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func BuildAndValidateAuditLogListRequest(r *http.Request, log log.Interface, validate *validator.Validate) (entity.AuditLogListParam, error) {
+	query := r.URL.Query()
+	param := entity.AuditLogListParam{
+		Action:     query.Get("action"),
+		TargetType: query.Get("target_type"),
+		Cursor:     query.Get("cursor"),
+	}
+
+	for key, id := range map[string]*int64{"actor_id": &param.ActorId, "target_id": &param.TargetId} {
+		if value := query.Get(key); value != "" {
+			var err error
+			if *id, err = strconv.ParseInt(value, 10, 64); err != nil {
+				log.Error(r.Context(), fmt.Sprintf("parse %s query err: %v", key, err))
+				return param, err
+			}
+		}
+	}
+
+	// bounds are RFC 3339 timestamps
+	for key, at := range map[string]*time.Time{"from": &param.From, "to": &param.To} {
+		if value := query.Get(key); value != "" {
+			var err error
+			if *at, err = time.Parse(time.RFC3339, value); err != nil {
+				log.Error(r.Context(), fmt.Sprintf("parse %s query err: %v", key, err))
+				return param, err
+			}
+		}
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		if param.Limit, err = strconv.Atoi(limit); err != nil {
+			log.Error(r.Context(), fmt.Sprintf("parse limit query err: %v", err))
+			return param, err
+		}
+	}
+
+	if err := validate.Struct(param); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("validate request query err: %v", err))
+		return param, err
+	}
+
+	return param, nil
+}
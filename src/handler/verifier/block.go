@@ -0,0 +1,34 @@
+package verifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"loverly/lib/log"
+	"loverly/src/business/entity"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func BuildAndValidateBlockRequest(r *http.Request, log log.Interface, validate *validator.Validate) (entity.BlockParam, error) {
+	var block entity.BlockParam
+
+	bodyByte, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Error(r.Context(), fmt.Sprintf("read request body err: %v", err))
+		return block, err
+	}
+
+	if err := json.Unmarshal(bodyByte, &block); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("unmarshal request body err: %v", err))
+		return block, err
+	}
+
+	if err := validate.Struct(block); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("validate request body err: %v", err))
+		return block, err
+	}
+
+	return block, nil
+}
@@ -0,0 +1,34 @@
+package verifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"loverly/lib/log"
+	"loverly/src/business/entity"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func BuildAndValidateBoostPurchaseRequest(r *http.Request, log log.Interface, validate *validator.Validate) (entity.BoostPurchaseParam, error) {
+	var purchase entity.BoostPurchaseParam
+
+	bodyByte, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Error(r.Context(), fmt.Sprintf("read request body err: %v", err))
+		return purchase, err
+	}
+
+	if err := json.Unmarshal(bodyByte, &purchase); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("unmarshal request body err: %v", err))
+		return purchase, err
+	}
+
+	if err := validate.Struct(purchase); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("validate request body err: %v", err))
+		return purchase, err
+	}
+
+	return purchase, nil
+}
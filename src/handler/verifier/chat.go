@@ -0,0 +1,57 @@
+package verifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"loverly/lib/log"
+	"loverly/src/business/entity"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func BuildAndValidateMessageRequest(r *http.Request, log log.Interface, validate *validator.Validate) (entity.MessageParam, error) {
+	var message entity.MessageParam
+
+	bodyByte, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Error(r.Context(), fmt.Sprintf("read request body err: %v", err))
+		return message, err
+	}
+
+	if err := json.Unmarshal(bodyByte, &message); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("unmarshal request body err: %v", err))
+		return message, err
+	}
+
+	if err := validate.Struct(message); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("validate request body err: %v", err))
+		return message, err
+	}
+
+	return message, nil
+}
+
+func BuildAndValidateMessageListRequest(r *http.Request, log log.Interface, validate *validator.Validate) (entity.MessageListParam, error) {
+	query := r.URL.Query()
+	param := entity.MessageListParam{
+		Cursor: query.Get("cursor"),
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		if param.Limit, err = strconv.Atoi(limit); err != nil {
+			log.Error(r.Context(), fmt.Sprintf("parse limit query err: %v", err))
+			return param, err
+		}
+	}
+
+	if err := validate.Struct(param); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("validate request query err: %v", err))
+		return param, err
+	}
+
+	return param, nil
+}
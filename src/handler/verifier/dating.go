@@ -32,3 +32,25 @@ func BuildAndValidateSwipeRequest(r *http.Request, log log.Interface, validate *
 
 	return swipe, nil
 }
+
+func BuildAndValidateSwipeBatchRequest(r *http.Request, log log.Interface, validate *validator.Validate) (entity.SwipeBatchParam, error) {
+	var batch entity.SwipeBatchParam
+
+	bodyByte, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Error(r.Context(), fmt.Sprintf("read request body err: %v", err))
+		return batch, err
+	}
+
+	if err := json.Unmarshal(bodyByte, &batch); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("unmarshal request body err: %v", err))
+		return batch, err
+	}
+
+	if err := validate.Struct(batch); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("validate request body err: %v", err))
+		return batch, err
+	}
+
+	return batch, nil
+}
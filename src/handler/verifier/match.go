@@ -0,0 +1,34 @@
+package verifier
+
+import (
+	"fmt"
+	"loverly/lib/log"
+	"loverly/src/business/entity"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func BuildAndValidateMatchListRequest(r *http.Request, log log.Interface, validate *validator.Validate) (entity.MatchListParam, error) {
+	query := r.URL.Query()
+	param := entity.MatchListParam{
+		Cursor: query.Get("cursor"),
+		Order:  query.Get("order"),
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		if param.Limit, err = strconv.Atoi(limit); err != nil {
+			log.Error(r.Context(), fmt.Sprintf("parse limit query err: %v", err))
+			return param, err
+		}
+	}
+
+	if err := validate.Struct(param); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("validate request query err: %v", err))
+		return param, err
+	}
+
+	return param, nil
+}
@@ -0,0 +1,113 @@
+package verifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"loverly/lib/log"
+	"loverly/src/business/entity"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func BuildAndValidateReportRequest(r *http.Request, log log.Interface, validate *validator.Validate) (entity.ReportParam, error) {
+	var report entity.ReportParam
+
+	bodyByte, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Error(r.Context(), fmt.Sprintf("read request body err: %v", err))
+		return report, err
+	}
+
+	if err := json.Unmarshal(bodyByte, &report); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("unmarshal request body err: %v", err))
+		return report, err
+	}
+
+	if err := validate.Struct(report); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("validate request body err: %v", err))
+		return report, err
+	}
+
+	return report, nil
+}
+
+func BuildAndValidateReportListRequest(r *http.Request, log log.Interface, validate *validator.Validate) (entity.ReportListParam, error) {
+	query := r.URL.Query()
+	param := entity.ReportListParam{
+		Status: query.Get("status"),
+		Cursor: query.Get("cursor"),
+	}
+
+	if assignee := query.Get("assignee_id"); assignee != "" {
+		var err error
+		if param.AssigneeId, err = strconv.ParseInt(assignee, 10, 64); err != nil {
+			log.Error(r.Context(), fmt.Sprintf("parse assignee_id query err: %v", err))
+			return param, err
+		}
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		if param.Limit, err = strconv.Atoi(limit); err != nil {
+			log.Error(r.Context(), fmt.Sprintf("parse limit query err: %v", err))
+			return param, err
+		}
+	}
+
+	if err := validate.Struct(param); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("validate request query err: %v", err))
+		return param, err
+	}
+
+	return param, nil
+}
+
+// BuildAndValidateReportAssignRequest accepts an empty body, the report then goes to the moderator making the request
+func BuildAndValidateReportAssignRequest(r *http.Request, log log.Interface, validate *validator.Validate) (entity.ReportAssignParam, error) {
+	var assign entity.ReportAssignParam
+
+	bodyByte, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Error(r.Context(), fmt.Sprintf("read request body err: %v", err))
+		return assign, err
+	}
+
+	if len(bodyByte) > 0 {
+		if err := json.Unmarshal(bodyByte, &assign); err != nil {
+			log.Error(r.Context(), fmt.Sprintf("unmarshal request body err: %v", err))
+			return assign, err
+		}
+	}
+
+	if err := validate.Struct(assign); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("validate request body err: %v", err))
+		return assign, err
+	}
+
+	return assign, nil
+}
+
+func BuildAndValidateReportResolveRequest(r *http.Request, log log.Interface, validate *validator.Validate) (entity.ReportResolveParam, error) {
+	var resolve entity.ReportResolveParam
+
+	bodyByte, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Error(r.Context(), fmt.Sprintf("read request body err: %v", err))
+		return resolve, err
+	}
+
+	if err := json.Unmarshal(bodyByte, &resolve); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("unmarshal request body err: %v", err))
+		return resolve, err
+	}
+
+	if err := validate.Struct(resolve); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("validate request body err: %v", err))
+		return resolve, err
+	}
+
+	return resolve, nil
+}
@@ -32,3 +32,25 @@ func BuildAndValidateSubscriptionRequest(r *http.Request, log log.Interface, val
 
 	return subs, nil
 }
+
+func BuildAndValidateCouponRequest(r *http.Request, log log.Interface, validate *validator.Validate) (entity.CouponParam, error) {
+	var coupon entity.CouponParam
+
+	bodyByte, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Error(r.Context(), fmt.Sprintf("read request body err: %v", err))
+		return coupon, err
+	}
+
+	if err := json.Unmarshal(bodyByte, &coupon); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("unmarshal request body err: %v", err))
+		return coupon, err
+	}
+
+	if err := validate.Struct(coupon); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("validate request body err: %v", err))
+		return coupon, err
+	}
+
+	return coupon, nil
+}
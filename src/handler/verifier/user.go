@@ -80,3 +80,25 @@ func BuildAndValidateRegisterRequest(r *http.Request, log log.Interface, validat
 
 	return signUp, nil
 }
+
+func BuildAndValidateChangePasswordRequest(r *http.Request, log log.Interface, validate *validator.Validate) (entity.ChangePasswordParam, error) {
+	var change entity.ChangePasswordParam
+
+	bodyByte, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Error(r.Context(), fmt.Sprintf("read request body err: %v", err))
+		return change, err
+	}
+
+	if err := json.Unmarshal(bodyByte, &change); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("unmarshal request body err: %v", err))
+		return change, err
+	}
+
+	if err := validate.Struct(change); err != nil {
+		log.Error(r.Context(), fmt.Sprintf("validate request body err: %v", err))
+		return change, err
+	}
+
+	return change, nil
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"loverly/src/business/usecase"
+	"loverly/src/handler/realtime"
+	"net/http"
+
+	appErr "loverly/src/errors"
+)
+
+// RealtimeTicket hands out the single-use ticket to open the websocket with, browsers can't set headers on the handshake
+func RealtimeTicket(uc *usecase.Usecases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, err := uc.Realtime.Ticket(r.Context())
+		if err != nil {
+			JSONError(r.Context(), w, http.StatusBadRequest, err)
+			return
+		}
+
+		JSONSuccess(r.Context(), w, http.StatusOK, res)
+	}
+}
+
+// Realtime is authenticated by a ticket in the query, it's burnt on use so access logs don't leak anything usable
+func Realtime(uc *usecase.Usecases, hub *realtime.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userId, err := uc.Realtime.Redeem(ctx, r.URL.Query().Get("ticket"))
+		if err != nil {
+			code := http.StatusBadRequest
+			if errors.Is(err, appErr.ErrInvalidTicket) {
+				code = http.StatusUnauthorized
+			}
+
+			JSONError(ctx, w, code, err)
+			return
+		}
+
+		// a ban or suspension issued after the ticket
+		if err := uc.User.CheckStatus(ctx, userId); err != nil {
+			JSONError(ctx, w, statusCode(err), err)
+			return
+		}
+
+		// a failed upgrade has already been answered by the upgrader
+		if err := hub.Serve(w, r, userId); err != nil {
+			Log.Error(ctx, fmt.Sprintf("websocket upgrade err: %v", err))
+		}
+	}
+}
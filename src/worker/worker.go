@@ -0,0 +1,148 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"loverly/lib/log"
+	"loverly/src/business/entity"
+	"loverly/src/business/usecase"
+	"loverly/src/config"
+	"time"
+)
+
+// Init starts the background jobs, they stop once ctx is done
+func Init(ctx context.Context, log log.Interface, cfg config.Configuration, uc *usecase.Usecases) {
+	go run(ctx, log, "deck", cfg.Worker.DeckInterval, func(ctx context.Context) error {
+		refilled, err := uc.Dating.RefillDecks(ctx, cfg.Worker.DeckBatchSize)
+		if err != nil {
+			return err
+		}
+
+		if refilled > 0 {
+			log.Debug(ctx, fmt.Sprintf("refilled %d discovery decks", refilled))
+		}
+
+		return nil
+	})
+
+	go run(ctx, log, "outbox", cfg.Worker.OutboxInterval, func(ctx context.Context) error {
+		published, err := uc.Event.Relay(ctx)
+		if err != nil {
+			return err
+		}
+
+		if published > 0 {
+			log.Debug(ctx, fmt.Sprintf("published %d domain events", published))
+		}
+
+		return nil
+	})
+
+	go run(ctx, log, "match", cfg.Worker.MatchInterval, func(ctx context.Context) error {
+		swept, err := uc.Match.Sweep(ctx, cfg.Worker.MatchBatchSize)
+		if err != nil {
+			return err
+		}
+
+		if swept > 0 {
+			log.Debug(ctx, fmt.Sprintf("expired or warned %d matches", swept))
+		}
+
+		return nil
+	})
+
+	go run(ctx, log, "subscription", cfg.Worker.SubscriptionInterval, func(ctx context.Context) error {
+		swept, err := uc.Subscription.Sweep(ctx, cfg.Worker.SubscriptionBatchSize)
+		if err != nil {
+			return err
+		}
+
+		if swept > 0 {
+			log.Debug(ctx, fmt.Sprintf("renewed, expired or reminded %d subscriptions", swept))
+		}
+
+		return nil
+	})
+
+	go run(ctx, log, "boost", cfg.Worker.BoostInterval, func(ctx context.Context) error {
+		summarized, err := uc.Boost.Summarize(ctx, cfg.Worker.BoostBatchSize)
+		if err != nil {
+			return err
+		}
+
+		if summarized > 0 {
+			log.Debug(ctx, fmt.Sprintf("summarized %d boosts", summarized))
+		}
+
+		return nil
+	})
+
+	go run(ctx, log, "refund", cfg.Worker.RefundInterval, func(ctx context.Context) error {
+		refunded, err := uc.Payment.Refund(ctx, cfg.Worker.RefundBatchSize)
+		if err != nil {
+			return err
+		}
+
+		if refunded > 0 {
+			log.Debug(ctx, fmt.Sprintf("refunded %d payments", refunded))
+		}
+
+		return nil
+	})
+
+	// swipe quotas are counted per day, let connected clients know a new one started
+	go daily(ctx, log, "quota", cfg.Worker.QuotaResetAt, func(ctx context.Context) error {
+		return uc.Realtime.Broadcast(ctx, entity.NotificationQuotaReset, nil)
+	})
+}
+
+// run calls job every interval, a failing tick is logged and retried on the next one
+func run(ctx context.Context, log log.Interface, name string, interval time.Duration, job func(ctx context.Context) error) {
+	log.Info(ctx, fmt.Sprintf("Starting %s worker every %s", name, interval))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info(ctx, fmt.Sprintf("Stopping %s worker", name))
+			return
+		case <-ticker.C:
+			if err := job(ctx); err != nil {
+				log.Error(ctx, fmt.Sprintf("%s worker err: %v", name, err))
+			}
+		}
+	}
+}
+
+// daily calls job once a day at the given offset from local midnight
+func daily(ctx context.Context, log log.Interface, name string, at time.Duration, job func(ctx context.Context) error) {
+	log.Info(ctx, fmt.Sprintf("Starting %s worker daily at %s", name, at))
+
+	for {
+		timer := time.NewTimer(time.Until(nextDaily(time.Now(), at)))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Info(ctx, fmt.Sprintf("Stopping %s worker", name))
+			return
+		case <-timer.C:
+			if err := job(ctx); err != nil {
+				log.Error(ctx, fmt.Sprintf("%s worker err: %v", name, err))
+			}
+		}
+	}
+}
+
+func nextDaily(now time.Time, at time.Duration) time.Time {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	next := midnight.Add(at)
+	for !next.After(now) {
+		midnight = midnight.AddDate(0, 0, 1)
+		next = midnight.Add(at)
+	}
+
+	return next
+}